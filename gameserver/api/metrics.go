@@ -0,0 +1,53 @@
+package api
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/lib/metrics"
+)
+
+// RegisterMetrics registers the host's metrics, server-wide and per table,
+// in reg. Tables are labeled by ID, so reg should not be exposed to
+// players.
+func RegisterMetrics(reg *metrics.Registry, h *host.Host) {
+	reg.GaugeFunc("gameserver_tables", "Tables open now.", func() float64 { return float64(h.Len()) })
+	reg.GaugeFunc("gameserver_capacity", "Most tables the server runs at once.", func() float64 { return float64(h.Capacity()) })
+	reg.CounterFunc("gameserver_table_crashes_total", "Tables closed because they panicked.", func() float64 { return float64(h.Crashes()) })
+	reg.Collect(func(w io.Writer) { writeTableMetrics(w, h.Stats()) })
+}
+
+// tableMetric is one per-table metric.
+type tableMetric struct {
+	name, kind, help string
+	value            func(host.Stats) float64
+}
+
+var tableMetrics = []tableMetric{
+	{"gameserver_table_connections", metrics.KindGauge, "Open player connections to the table.",
+		func(s host.Stats) float64 { return float64(s.Connections) }},
+	{"gameserver_table_spectators", metrics.KindGauge, "Spectators watching the table.",
+		func(s host.Stats) float64 { return float64(s.Spectators) }},
+	{"gameserver_table_queued_messages", metrics.KindGauge, "Messages waiting in the table's mailbox.",
+		func(s host.Stats) float64 { return float64(s.Queued) }},
+	{"gameserver_table_messages_total", metrics.KindCounter, "Messages the table has handled.",
+		func(s host.Stats) float64 { return float64(s.Handled) }},
+	{"gameserver_table_rejected_messages_total", metrics.KindCounter, "Messages turned away because the table's mailbox was full.",
+		func(s host.Stats) float64 { return float64(s.Rejected) }},
+	{"gameserver_table_busy_seconds_total", metrics.KindCounter, "Time the table has spent handling messages.",
+		func(s host.Stats) float64 { return s.Busy.Seconds() }},
+	{"gameserver_table_events_total", metrics.KindCounter, "Events sent to the table's connections.",
+		func(s host.Stats) float64 { return float64(s.Events) }},
+	{"gameserver_table_dropped_connections_total", metrics.KindCounter, "Connections dropped for falling too far behind.",
+		func(s host.Stats) float64 { return float64(s.Dropped) }},
+}
+
+func writeTableMetrics(w io.Writer, stats []host.Stats) {
+	for _, m := range tableMetrics {
+		metrics.WriteHeader(w, m.name, m.kind, m.help)
+		for _, s := range stats {
+			fmt.Fprintf(w, "%s{table=%q,game_mode=%q} %g\n", m.name, s.TableID, s.GameMode, m.value(s))
+		}
+	}
+}
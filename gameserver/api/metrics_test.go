@@ -0,0 +1,44 @@
+package api
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/lib/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	h := host.New(10, host.WithMetrics(reg))
+	RegisterMetrics(reg, h)
+	table, err := h.Assign(host.Assignment{MatchID: "m1", GameMode: "holdem", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+	_, leave, err := table.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	AssertThat(t, table.StartHand(), Nil())
+	AssertThat(t, table.StartTurn("alice", func() {}), Nil())
+	AssertThat(t, table.EndTurn("alice"), Nil())
+	table.EndHand()
+
+	srv := httptest.NewServer(reg)
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL)
+	AssertThat(t, err, Nil())
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	AssertThat(t, err, Nil())
+
+	lines := strings.Split(string(body), "\n")
+	ExpectThat(t, lines, Contains("gameserver_tables 1"))
+	ExpectThat(t, lines, Contains("gameserver_capacity 10"))
+	ExpectThat(t, lines, Contains(`gameserver_table_connections{table="m1",game_mode="holdem"} 1`))
+	ExpectThat(t, lines, Contains("# TYPE gameserver_table_messages_total counter"))
+	ExpectThat(t, lines, Contains(`gameserver_hands_total{game_mode="holdem"} 1`))
+	ExpectThat(t, lines, Contains(`gameserver_action_seconds_count{game_mode="holdem"} 1`))
+}
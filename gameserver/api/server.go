@@ -0,0 +1,166 @@
+// Package api implements the game server's interface for players.
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/lib/table"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+
+	"github.com/jfmatt/snapfold/lib/debug"
+	"github.com/jfmatt/snapfold/lib/idempotency"
+	"github.com/jfmatt/snapfold/lib/protocol"
+	"github.com/jfmatt/snapfold/lib/tracing"
+)
+
+// Config holds the dependencies of a Server.
+type Config struct {
+	Host *host.Host
+
+	// Checks players' access tokens. It must share the matchmaker's signing
+	// key, so that tokens it issues are accepted here.
+	Sessions *session.Store
+
+	// Remembers the idempotency keys of requests to buy chips, so that they
+	// can be retried safely. If nil, keys are remembered in memory, which
+	// suits game servers since each table is hosted by only one.
+	Idempotency *idempotency.Keeper
+
+	// The bearer token of the game server's admins, who may read its
+	// profiles and runtime statistics under /debug/. They aren't served if
+	// it is empty.
+	AdminToken string
+}
+
+// Server serves the tables running on a game server.
+type Server struct {
+	host        *host.Host
+	sessions    *session.Store
+	idempotency *idempotency.Keeper
+	adminToken  string
+	mux         *http.ServeMux
+}
+
+// NewServer returns a Server built from cfg.
+func NewServer(cfg Config) *Server {
+	s := &Server{
+		host:        cfg.Host,
+		sessions:    cfg.Sessions,
+		idempotency: cfg.Idempotency,
+		adminToken:  cfg.AdminToken,
+		mux:         http.NewServeMux(),
+	}
+	if s.idempotency == nil {
+		s.idempotency = idempotency.New(idempotency.NewMemStore(), idempotency.DefaultTTL)
+	}
+	s.mux.HandleFunc("GET /v1/tables/{id}/events", s.authenticated(s.handleTableEvents))
+	s.mux.HandleFunc("GET /v1/tables/{id}/watch", s.authenticated(s.handleWatch))
+	s.mux.HandleFunc("POST /v1/tables/{id}/sit-out", s.authenticated(s.handleSitOut))
+	s.mux.HandleFunc("POST /v1/tables/{id}/return", s.authenticated(s.handleReturn))
+	s.mux.HandleFunc("POST /v1/tables/{id}/top-up", s.authenticated(s.idempotent(s.handleTopUp)))
+	s.mux.HandleFunc("POST /v1/tables/{id}/rebuy", s.authenticated(s.idempotent(s.handleRebuy)))
+	s.mux.HandleFunc("POST /v1/tables/{id}/add-on", s.authenticated(s.idempotent(s.handleAddOn)))
+	s.mux.HandleFunc("POST /v1/tables/{id}/chat", s.authenticated(s.handleChat))
+	s.mux.HandleFunc("PUT /v1/tables/{id}/mutes/{player}", s.authenticated(s.handleMute))
+	s.mux.HandleFunc("DELETE /v1/tables/{id}/mutes/{player}", s.authenticated(s.handleUnmute))
+	if s.adminToken != "" {
+		s.mux.HandleFunc("/debug/", s.admin(debug.Handler("/debug/").ServeHTTP))
+	}
+	return s
+}
+
+// ServeHTTP serves a request, once its client's protocol version is known
+// to be supported.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	protocol.Handler(tracing.Handler(s.mux)).ServeHTTP(w, r)
+}
+
+// authenticated wraps a handler so that it only runs for requests carrying a
+// valid bearer token issued by the matchmaker. The player ID is available to
+// the handler via session.PlayerFrom.
+//
+// Browsers cannot set headers on WebSocket handshakes, so those may carry the
+// token in an access_token query parameter instead.
+func (s *Server) authenticated(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := session.BearerToken(r.Header.Get("Authorization"))
+		if !ok && websocket.IsWebSocketUpgrade(r) {
+			token = r.URL.Query().Get("access_token")
+			ok = token != ""
+		}
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		ctx, ok := s.sessions.Authenticate(r.Context(), token)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "invalid session")
+			return
+		}
+		h(w, r.WithContext(ctx))
+	}
+}
+
+// admin wraps a handler so that it only runs for requests carrying the
+// admin token.
+func (s *Server) admin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := session.BearerToken(r.Header.Get("Authorization"))
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			writeError(w, http.StatusForbidden, "not the admin token")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// idempotent wraps an authenticated handler so that a request carrying an
+// Idempotency-Key header is only handled once per key and player; retries
+// get the first response.
+func (s *Server) idempotent(h http.HandlerFunc) http.HandlerFunc {
+	return s.idempotency.Handler(func(r *http.Request) string {
+		playerID, _ := session.PlayerFrom(r.Context())
+		return playerID
+	}, h).ServeHTTP
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// writeErr replies with an error from the host package, choosing a status
+// code based on its cause.
+func writeErr(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, host.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, host.ErrClosed):
+		status = http.StatusConflict
+	case errors.Is(err, host.ErrNotSeated), errors.Is(err, host.ErrNoSpectators), errors.Is(err, host.ErrChatDisabled):
+		status = http.StatusForbidden
+	case errors.Is(err, host.ErrBadChat):
+		status = http.StatusBadRequest
+	case errors.Is(err, table.ErrBuyinNotAllowed):
+		status = http.StatusConflict
+	case errors.Is(err, host.ErrPayment):
+		status = http.StatusPaymentRequired
+	}
+	writeError(w, status, err.Error())
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func TestDebug(t *testing.T) {
+	sessions := session.NewStore()
+	get := func(s *Server, path, token string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	s := NewServer(Config{Host: host.New(1), Sessions: sessions, AdminToken: "s3cret"})
+	ExpectEq(t, get(s, "/debug/vars", ""), http.StatusUnauthorized)
+	ExpectEq(t, get(s, "/debug/vars", sessions.Create("alice")), http.StatusForbidden)
+	ExpectEq(t, get(s, "/debug/vars", "s3cret"), http.StatusOK)
+	ExpectEq(t, get(s, "/debug/pprof/goroutine", "s3cret"), http.StatusOK)
+
+	// Without an admin token, nobody can read them.
+	s = NewServer(Config{Host: host.New(1), Sessions: sessions})
+	ExpectEq(t, get(s, "/debug/vars", ""), http.StatusNotFound)
+}
@@ -0,0 +1,396 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/lib/frame"
+	"github.com/jfmatt/snapfold/lib/protocol"
+	"github.com/jfmatt/snapfold/lib/table"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+const (
+	// How long to wait for a write to a table socket before giving up.
+	tableWriteTimeout = 10 * time.Second
+
+	// Interval between keepalive pings on table sockets.
+	tablePingInterval = 30 * time.Second
+
+	// The most events sent in one WebSocket message to clients that take
+	// frames.
+	maxTableBatch = 64
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// handleTableEvents streams TableEvent messages from a table the caller is
+// seated at, starting with a snapshot, until the table closes. The stream
+// is cut short if the caller falls too far behind, and they should
+// reconnect for a fresh snapshot.
+//
+// Requests to upgrade get the events on a WebSocket, which is closed
+// abnormally if cut short, and on which the caller sends the table their
+// actions. Others, such as over HTTP/3, where WebSockets
+// aren't available, get them as a response body of frames, which ends
+// without a TableClosed event if cut short. Either ends once the caller's
+// session is revoked.
+func (s *Server) handleTableEvents(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	t, err := s.host.Table(r.PathValue("id"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	events, leave, err := t.Connect(playerID)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	defer leave()
+	s.stream(w, r, events, func(ctx context.Context, a *pb.TableAction) error {
+		action, to, err := table.ActionFor(a)
+		if err != nil {
+			return err
+		}
+		return t.Act(ctx, playerID, action, to)
+	})
+}
+
+// handleWatch is handleTableEvents for spectators: anyone signed in may
+// watch a table, unless its game mode doesn't allow it, but never sees
+// players' hole cards.
+func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
+	t, err := s.host.Table(r.PathValue("id"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	events, leave, err := t.Watch()
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	defer leave()
+	s.stream(w, r, events, func(context.Context, *pb.TableAction) error { return host.ErrNotSeated })
+}
+
+// stream writes each event to the client until the events end or the
+// caller's session is revoked, on a WebSocket or in the response body.
+// Actions sent on a WebSocket are passed to act.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, events <-chan *pb.TableEvent, act actFunc) {
+	ctx, stop := s.sessions.Watch(r.Context())
+	defer stop()
+	r = r.WithContext(ctx)
+	if websocket.IsWebSocketUpgrade(r) {
+		streamSocket(w, r, events, act)
+	} else {
+		streamBody(w, r, events)
+	}
+}
+
+// actFunc does what a player sent on a table WebSocket.
+type actFunc func(context.Context, *pb.TableAction) error
+
+// streamSocket upgrades the connection to a WebSocket and writes each event
+// to it until the events end, passing each action the client sends to act.
+// Actions that fail are answered with an ActionRejected event.
+func streamSocket(w http.ResponseWriter, r *http.Request, events <-chan *pb.TableEvent, act actFunc) {
+	framed := protocol.FromContext(r.Context()).Framed()
+	conn, err := upgrader.Upgrade(w, r, protocol.UpgradeHeader())
+	if err != nil {
+		// The upgrader has already replied to the client.
+		return
+	}
+	defer conn.Close()
+
+	// The client sends its actions, and reading is also required to process
+	// control frames and to notice when it goes away. Only the loop below
+	// writes, so rejections are passed back to it.
+	closed := make(chan struct{})
+	rejected := make(chan *pb.TableEvent)
+	go func() {
+		defer close(closed)
+		for {
+			kind, b, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			actions, err := readActions(kind, b, framed)
+			for _, a := range actions {
+				if err = act(r.Context(), a); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				continue
+			}
+			select {
+			case rejected <- actionRejected(err):
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(tablePingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, session.ErrRevoked.Error())
+			conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(tableWriteTimeout))
+			return
+		case <-ping.C:
+			deadline := time.Now().Add(tableWriteTimeout)
+			if err := conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				return
+			}
+		case ev := <-rejected:
+			if err := writeEvents(conn, framed, ev); err != nil {
+				return
+			}
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			batch := []*pb.TableEvent{ev}
+			if framed {
+				// Events come in bursts, such as the end of a hand, and
+				// are cheaper to send together.
+				batch = pending(batch, events)
+				ev = batch[len(batch)-1]
+			}
+			if err := writeEvents(conn, framed, batch...); err != nil {
+				return
+			}
+			if ev.HasTableClosed() {
+				msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+				conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(tableWriteTimeout))
+				return
+			}
+		}
+	}
+}
+
+// writeEvents writes events to a table socket in one message: framed, or
+// for clients that don't take frames, a single bare event.
+func writeEvents(conn *websocket.Conn, framed bool, events ...*pb.TableEvent) error {
+	var b []byte
+	var err error
+	if framed {
+		b, err = frame.Marshal(events...)
+	} else {
+		b, err = proto.Marshal(events[0])
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetWriteDeadline(time.Now().Add(tableWriteTimeout))
+	return conn.WriteMessage(websocket.BinaryMessage, b)
+}
+
+// readActions decodes the actions in a message from a table socket: one in
+// JSON in a text message, and otherwise as many as it has frames, or a
+// single bare one for clients that don't frame.
+func readActions(kind int, b []byte, framed bool) ([]*pb.TableAction, error) {
+	if kind == websocket.TextMessage {
+		a := &pb.TableAction{}
+		if err := protojson.Unmarshal(b, a); err != nil {
+			return nil, fmt.Errorf("%w: %v", table.ErrInvalidAction, err)
+		}
+		return []*pb.TableAction{a}, nil
+	}
+	payloads := [][]byte{b}
+	if framed {
+		var err error
+		if payloads, err = frame.Split(b); err != nil {
+			return nil, fmt.Errorf("%w: %v", table.ErrInvalidAction, err)
+		}
+	}
+	actions := make([]*pb.TableAction, len(payloads))
+	for i, p := range payloads {
+		actions[i] = &pb.TableAction{}
+		if err := proto.Unmarshal(p, actions[i]); err != nil {
+			return nil, fmt.Errorf("%w: %v", table.ErrInvalidAction, err)
+		}
+	}
+	return actions, nil
+}
+
+// actionRejected returns the event telling a player why their action
+// failed.
+func actionRejected(err error) *pb.TableEvent {
+	return pb.TableEvent_builder{
+		ActionRejected: pb.ActionRejected_builder{Reason: proto.String(err.Error())}.Build(),
+	}.Build()
+}
+
+// streamBody writes each event to the response body as a frame, flushing
+// as events arrive, until the events end or the client goes away. Bodies
+// are always framed, since only clients that take frames ask for them.
+func streamBody(w http.ResponseWriter, r *http.Request, events <-chan *pb.TableEvent) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", frame.ContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			batch := pending([]*pb.TableEvent{ev}, events)
+			b, err := frame.Marshal(batch...)
+			if err != nil {
+				return
+			}
+			rc.SetWriteDeadline(time.Now().Add(tableWriteTimeout))
+			if _, err := w.Write(b); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil || batch[len(batch)-1].HasTableClosed() {
+				return
+			}
+		}
+	}
+}
+
+// pending appends to batch the events already waiting, up to
+// maxTableBatch, stopping after the table closes.
+func pending(batch []*pb.TableEvent, events <-chan *pb.TableEvent) []*pb.TableEvent {
+	for len(batch) < maxTableBatch && !batch[len(batch)-1].HasTableClosed() {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, ev)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// handleSitOut sits the caller out at a table they are seated at.
+func (s *Server) handleSitOut(w http.ResponseWriter, r *http.Request) {
+	s.asPlayer(w, r, (*host.Table).SitOut)
+}
+
+// handleReturn brings the caller back into play at a table they sat out
+// at.
+func (s *Server) handleReturn(w http.ResponseWriter, r *http.Request) {
+	s.asPlayer(w, r, (*host.Table).Return)
+}
+
+// asPlayer does something at a table as the caller, replying with no content
+// if it works.
+func (s *Server) asPlayer(w http.ResponseWriter, r *http.Request, do func(*host.Table, string) error) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	t, err := s.host.Table(r.PathValue("id"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := do(t, playerID); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type chatRequest struct {
+	Text string `json:"text"`
+}
+
+// handleChat sends a message from the caller to everyone at a table.
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	s.asPlayer(w, r, func(t *host.Table, playerID string) error { return t.Chat(playerID, req.Text) })
+}
+
+// handleMute stops the caller getting another player's chat at a table.
+func (s *Server) handleMute(w http.ResponseWriter, r *http.Request) {
+	s.asPlayer(w, r, func(t *host.Table, playerID string) error { return t.Mute(playerID, r.PathValue("player")) })
+}
+
+// handleUnmute lets the caller get a player's chat at a table again.
+func (s *Server) handleUnmute(w http.ResponseWriter, r *http.Request) {
+	s.asPlayer(w, r, func(t *host.Table, playerID string) error { return t.Unmute(playerID, r.PathValue("player")) })
+}
+
+type topUpRequest struct {
+	Chips int64 `json:"chips"`
+}
+
+type buyResponse struct {
+	// The caller's chips after buying, not counting chips bought during a
+	// hand, which are added once it ends.
+	Stack int64 `json:"stack"`
+}
+
+// handleTopUp buys the caller more chips at a cash table, paid for from
+// their wallet.
+func (s *Server) handleTopUp(w http.ResponseWriter, r *http.Request) {
+	var req topUpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	s.buy(w, r, func(t *host.Table, playerID string) (int64, error) {
+		return t.TopUp(r.Context(), playerID, req.Chips)
+	})
+}
+
+// handleRebuy buys the caller a tournament rebuy.
+func (s *Server) handleRebuy(w http.ResponseWriter, r *http.Request) {
+	s.buy(w, r, func(t *host.Table, playerID string) (int64, error) {
+		return t.Rebuy(r.Context(), playerID)
+	})
+}
+
+// handleAddOn buys the caller a tournament add-on.
+func (s *Server) handleAddOn(w http.ResponseWriter, r *http.Request) {
+	s.buy(w, r, func(t *host.Table, playerID string) (int64, error) {
+		return t.AddOn(r.Context(), playerID)
+	})
+}
+
+func (s *Server) buy(w http.ResponseWriter, r *http.Request, buy func(*host.Table, string) (int64, error)) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	t, err := s.host.Table(r.PathValue("id"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	stack, err := buy(t, playerID)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, buyResponse{Stack: stack})
+}
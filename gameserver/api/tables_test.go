@@ -0,0 +1,474 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/lib/frame"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/lib/idempotency"
+	"github.com/jfmatt/snapfold/lib/protocol"
+	"github.com/jfmatt/snapfold/lib/table"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+var ctx = context.Background()
+
+func readEvent(t *testing.T, conn *websocket.Conn) *pb.TableEvent {
+	t.Helper()
+	kind, b, err := conn.ReadMessage()
+	AssertThat(t, err, Nil())
+	AssertEq(t, kind, websocket.BinaryMessage)
+	ev := &pb.TableEvent{}
+	AssertThat(t, proto.Unmarshal(b, ev), Nil())
+	return ev
+}
+
+func TestTableEvents(t *testing.T) {
+	h := host.New(1)
+	table, err := h.Assign(host.Assignment{MatchID: "m1", GameMode: "holdem", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	key := []byte("shared with the matchmaker")
+	srv := httptest.NewServer(NewServer(Config{Host: h, Sessions: session.NewStore(session.WithKey(key))}))
+	defer srv.Close()
+
+	// Tokens from the matchmaker are accepted.
+	matchmaker := session.NewStore(session.WithKey(key))
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/tables/m1/events?access_token=" + matchmaker.Create("alice")
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	AssertThat(t, err, Nil())
+	defer conn.Close()
+
+	ev := readEvent(t, conn)
+	AssertEq(t, ev.HasSnapshot(), true)
+	ExpectEq(t, ev.GetSnapshot().GetGameMode(), "holdem")
+	ExpectThat(t, ev.GetSnapshot().GetConnectedPlayerIds(), ElementsAre("alice"))
+
+	_, leave, err := table.Connect("bob")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, readEvent(t, conn).GetPlayerConnected().GetPlayerId(), "bob")
+	leave()
+	ExpectEq(t, readEvent(t, conn).GetPlayerDisconnected().GetPlayerId(), "bob")
+
+	AssertThat(t, table.Close(ctx, "game over"), Nil())
+	ExpectEq(t, readEvent(t, conn).GetTableClosed().GetReason(), "game over")
+	_, _, err = conn.ReadMessage()
+	ExpectEq(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), true)
+}
+
+func TestTableEvents_Revoked(t *testing.T) {
+	h := host.New(1)
+	_, err := h.Assign(host.Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	key := []byte("shared with the matchmaker")
+	matchmaker := session.NewStore(session.WithKey(key))
+	sessions := session.NewStore(session.WithKey(key), session.WithRevocations(matchmaker))
+	srv := httptest.NewServer(NewServer(Config{Host: h, Sessions: sessions}))
+	defer srv.Close()
+
+	tokens, err := matchmaker.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/tables/m1/events?access_token=" + tokens.Access
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	AssertThat(t, err, Nil())
+	defer conn.Close()
+	ExpectEq(t, readEvent(t, conn).HasSnapshot(), true)
+
+	// The stream ends once the server learns the session was revoked, and
+	// the token no longer works.
+	AssertThat(t, matchmaker.Logout(ctx, tokens.Refresh), Nil())
+	AssertThat(t, sessions.Sync(ctx), Nil())
+	_, _, err = conn.ReadMessage()
+	ExpectEq(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), true)
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	ExpectThat(t, err, Not(Nil()))
+	AssertThat(t, resp, Not(Nil()))
+	ExpectEq(t, resp.StatusCode, http.StatusUnauthorized)
+}
+
+func TestTableEvents_Framed(t *testing.T) {
+	h := host.New(1)
+	table, err := h.Assign(host.Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewServer(Config{Host: h, Sessions: sessions}))
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/tables/m1/events?access_token=" + sessions.Create("alice")
+	conn, resp, err := websocket.DefaultDialer.Dial(url, http.Header{protocol.Header: {protocol.Current.String()}})
+	AssertThat(t, err, Nil())
+	defer conn.Close()
+	ExpectEq(t, resp.Header.Get(protocol.Header), strconv.Itoa(protocol.Version))
+
+	_, leave, err := table.Connect("bob")
+	AssertThat(t, err, Nil())
+	leave()
+	AssertThat(t, table.Close(ctx, "game over"), Nil())
+
+	// However the events were batched, they all arrive in order.
+	var events []*pb.TableEvent
+	for len(events) == 0 || !events[len(events)-1].HasTableClosed() {
+		kind, b, err := conn.ReadMessage()
+		AssertThat(t, err, Nil())
+		AssertEq(t, kind, websocket.BinaryMessage)
+		payloads, err := frame.Split(b)
+		AssertThat(t, err, Nil())
+		for _, p := range payloads {
+			ev := &pb.TableEvent{}
+			AssertThat(t, proto.Unmarshal(p, ev), Nil())
+			events = append(events, ev)
+		}
+	}
+	AssertThat(t, events, Len(4))
+	ExpectEq(t, events[0].HasSnapshot(), true)
+	ExpectEq(t, events[1].GetPlayerConnected().GetPlayerId(), "bob")
+	ExpectEq(t, events[2].GetPlayerDisconnected().GetPlayerId(), "bob")
+}
+
+func TestTableEvents_Stream(t *testing.T) {
+	h := host.New(1)
+	table, err := h.Assign(host.Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewServer(Config{Host: h, Sessions: sessions}))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/tables/m1/events", nil)
+	AssertThat(t, err, Nil())
+	req.Header.Set("Authorization", "Bearer "+sessions.Create("alice"))
+	resp, err := srv.Client().Do(req)
+	AssertThat(t, err, Nil())
+	defer resp.Body.Close()
+	AssertEq(t, resp.StatusCode, http.StatusOK)
+	ExpectEq(t, resp.Header.Get("Content-Type"), frame.ContentType)
+
+	frames := frame.NewReader(resp.Body)
+	next := func() *pb.TableEvent {
+		t.Helper()
+		b, err := frames.Next()
+		AssertThat(t, err, Nil())
+		ev := &pb.TableEvent{}
+		AssertThat(t, proto.Unmarshal(b, ev), Nil())
+		return ev
+	}
+	ExpectEq(t, next().HasSnapshot(), true)
+	_, leave, err := table.Connect("bob")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, next().GetPlayerConnected().GetPlayerId(), "bob")
+	leave()
+	ExpectEq(t, next().GetPlayerDisconnected().GetPlayerId(), "bob")
+
+	// The body ends once the table closes.
+	AssertThat(t, table.Close(ctx, "game over"), Nil())
+	ExpectEq(t, next().GetTableClosed().GetReason(), "game over")
+	_, err = frames.Next()
+	ExpectThat(t, err, ErrorIs(io.EOF))
+}
+
+func TestTableEvents_Rejected(t *testing.T) {
+	h := host.New(1)
+	_, err := h.Assign(host.Assignment{MatchID: "m1", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewServer(Config{Host: h, Sessions: sessions}))
+	defer srv.Close()
+	base := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/tables/"
+
+	for _, tc := range []struct {
+		path, token string
+		want        int
+	}{
+		{"m1", "", http.StatusUnauthorized},
+		{"m1", session.NewStore().Create("alice"), http.StatusUnauthorized},
+		{"m1", sessions.Create("carol"), http.StatusForbidden},
+		{"m2", sessions.Create("alice"), http.StatusNotFound},
+	} {
+		header := http.Header{}
+		if tc.token != "" {
+			header.Set("Authorization", "Bearer "+tc.token)
+		}
+		_, resp, err := websocket.DefaultDialer.Dial(base+tc.path+"/events", header)
+		ExpectThat(t, err, Not(Nil()))
+		AssertThat(t, resp, Not(Nil()))
+		ExpectEq(t, resp.StatusCode, tc.want)
+	}
+}
+
+// events reads the events sent on a table socket, in frames or not.
+type events struct {
+	conn    *websocket.Conn
+	framed  bool
+	pending [][]byte
+}
+
+func (e *events) next(t *testing.T) *pb.TableEvent {
+	t.Helper()
+	if !e.framed {
+		return readEvent(t, e.conn)
+	}
+	for len(e.pending) == 0 {
+		_, b, err := e.conn.ReadMessage()
+		AssertThat(t, err, Nil())
+		e.pending, err = frame.Split(b)
+		AssertThat(t, err, Nil())
+	}
+	ev := &pb.TableEvent{}
+	AssertThat(t, proto.Unmarshal(e.pending[0], ev), Nil())
+	e.pending = e.pending[1:]
+	return ev
+}
+
+// until reads events up to and including the first that matches.
+func (e *events) until(t *testing.T, match func(*pb.TableEvent) bool) *pb.TableEvent {
+	t.Helper()
+	for {
+		if ev := e.next(t); match(ev) {
+			return ev
+		}
+	}
+}
+
+func TestTableEvents_Act(t *testing.T) {
+	cfg, err := gamedefio.LoadPreset("heads-up")
+	AssertThat(t, err, Nil())
+	for _, framed := range []bool{false, true} {
+		h := host.New(1)
+		stacks := map[string]int64{"alice": 10000, "bob": 10000}
+		tbl, err := h.Assign(host.Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}, Stacks: stacks, Config: cfg})
+		AssertThat(t, err, Nil())
+		sessions := session.NewStore()
+		srv := httptest.NewServer(NewServer(Config{Host: h, Sessions: sessions}))
+		defer srv.Close()
+
+		header := http.Header{"Authorization": {"Bearer " + sessions.Create("alice")}}
+		if framed {
+			header.Set(protocol.Header, protocol.Current.String())
+		}
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/tables/m1/events", header)
+		AssertThat(t, err, Nil())
+		defer conn.Close()
+		alice := &events{conn: conn, framed: framed}
+		AssertEq(t, alice.next(t).HasSnapshot(), true)
+		send := func(a *pb.TableAction) {
+			t.Helper()
+			var b []byte
+			if framed {
+				b, err = frame.Marshal(a)
+			} else {
+				b, err = proto.Marshal(a)
+			}
+			AssertThat(t, err, Nil())
+			AssertThat(t, conn.WriteMessage(websocket.BinaryMessage, b), Nil())
+		}
+		rejected := func() string {
+			t.Helper()
+			return alice.until(t, (*pb.TableEvent).HasActionRejected).GetActionRejected().GetReason()
+		}
+
+		// Once bob connects, alice's actions reach the table on her turns,
+		// in frames or in JSON, and are rejected on bob's.
+		_, leave, err := tbl.Connect("bob")
+		AssertThat(t, err, Nil())
+		defer leave()
+		var opts *pb.TurnOptions
+		for turn := ""; turn != "bob"; {
+			ev := alice.until(t, func(ev *pb.TableEvent) bool { return ev.HasTurnOptions() || ev.HasTurnTimer() })
+			if ev.HasTurnOptions() {
+				opts = ev.GetTurnOptions()
+				continue
+			}
+			if turn = ev.GetTurnTimer().GetPlayerId(); turn != "alice" {
+				continue
+			}
+			kind := pb.TableAction_CHECK
+			if opts.GetCall() > 0 {
+				kind = pb.TableAction_CALL
+			}
+			a := pb.TableAction_builder{Kind: kind.Enum()}.Build()
+			if framed {
+				send(a)
+			} else {
+				b, err := protojson.Marshal(a)
+				AssertThat(t, err, Nil())
+				AssertThat(t, conn.WriteMessage(websocket.TextMessage, b), Nil())
+			}
+			acted := alice.until(t, (*pb.TableEvent).HasPlayerActed).GetPlayerActed()
+			ExpectEq(t, acted.GetPlayerId(), "alice")
+		}
+		send(pb.TableAction_builder{Kind: pb.TableAction_FOLD.Enum()}.Build())
+		ExpectEq(t, rejected(), host.ErrNotTurn.Error())
+
+		// Actions that aren't valid are rejected whenever they are sent.
+		AssertThat(t, conn.WriteMessage(websocket.BinaryMessage, []byte("not an action")), Nil())
+		ExpectThat(t, rejected(), StartsWith("invalid action"))
+		send(pb.TableAction_builder{Kind: pb.TableAction_BET.Enum()}.Build())
+		ExpectThat(t, rejected(), StartsWith("invalid action"))
+
+		AssertThat(t, tbl.Act(ctx, "bob", table.Fold, 0), Nil())
+		ExpectEq(t, alice.until(t, (*pb.TableEvent).HasPlayerActed).GetPlayerActed().GetPlayerId(), "bob")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	h := host.New(2)
+	_, err := h.Assign(host.Assignment{MatchID: "m1", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+	_, err = h.Assign(host.Assignment{
+		MatchID:   "m2",
+		PlayerIDs: []string{"alice"},
+		Config:    pb.TableConfig_builder{NoSpectators: proto.Bool(true)}.Build(),
+	})
+	AssertThat(t, err, Nil())
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewServer(Config{Host: h, Sessions: sessions}))
+	defer srv.Close()
+	base := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/tables/"
+	header := http.Header{"Authorization": {"Bearer " + sessions.Create("carol")}}
+
+	conn, _, err := websocket.DefaultDialer.Dial(base+"m1/watch", header)
+	AssertThat(t, err, Nil())
+	defer conn.Close()
+	ExpectThat(t, readEvent(t, conn).GetSnapshot().GetPlayerIds(), ElementsAre("alice"))
+	b, err := proto.Marshal(pb.TableAction_builder{Kind: pb.TableAction_FOLD.Enum()}.Build())
+	AssertThat(t, err, Nil())
+	AssertThat(t, conn.WriteMessage(websocket.BinaryMessage, b), Nil())
+	ExpectEq(t, readEvent(t, conn).GetActionRejected().GetReason(), host.ErrNotSeated.Error())
+
+	_, resp, err := websocket.DefaultDialer.Dial(base+"m2/watch", header)
+	ExpectThat(t, err, Not(Nil()))
+	AssertThat(t, resp, Not(Nil()))
+	ExpectEq(t, resp.StatusCode, http.StatusForbidden)
+}
+
+func TestSitOut(t *testing.T) {
+	h := host.New(1)
+	table, err := h.Assign(host.Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewServer(Config{Host: h, Sessions: sessions}))
+	defer srv.Close()
+
+	post := func(path, token string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, srv.URL+path, nil)
+		AssertThat(t, err, Nil())
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := srv.Client().Do(req)
+		AssertThat(t, err, Nil())
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	alice := sessions.Create("alice")
+	ExpectEq(t, post("/v1/tables/m1/sit-out", alice), http.StatusNoContent)
+	ExpectThat(t, table.SittingOut(), ElementsAre("alice"))
+	ExpectEq(t, post("/v1/tables/m1/return", alice), http.StatusNoContent)
+	ExpectThat(t, table.SittingOut(), Empty())
+
+	ExpectEq(t, post("/v1/tables/m1/sit-out", sessions.Create("carol")), http.StatusForbidden)
+	ExpectEq(t, post("/v1/tables/m2/sit-out", alice), http.StatusNotFound)
+}
+
+func TestTopUp(t *testing.T) {
+	h := host.New(1, host.WithBuyin(table.BuyinRules{Max: 20000}), host.WithPlayMoney())
+	_, err := h.Assign(host.Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}, Stacks: map[string]int64{"alice": 5000}})
+	AssertThat(t, err, Nil())
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewServer(Config{Host: h, Sessions: sessions}))
+	defer srv.Close()
+
+	post := func(path, token, body string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+		AssertThat(t, err, Nil())
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := srv.Client().Do(req)
+		AssertThat(t, err, Nil())
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		AssertThat(t, err, Nil())
+		return resp.StatusCode, strings.TrimSpace(string(b))
+	}
+	alice := sessions.Create("alice")
+	status, body := post("/v1/tables/m1/top-up", alice, `{"chips": 10000}`)
+	ExpectEq(t, status, http.StatusOK)
+	ExpectEq(t, body, `{"stack":15000}`)
+
+	status, _ = post("/v1/tables/m1/top-up", alice, `{"chips": 10000}`)
+	ExpectEq(t, status, http.StatusConflict)
+
+	// A retried top-up isn't bought twice.
+	for range 2 {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/tables/m1/top-up", strings.NewReader(`{"chips": 1000}`))
+		AssertThat(t, err, Nil())
+		req.Header.Set("Authorization", "Bearer "+alice)
+		req.Header.Set(idempotency.Header, "k1")
+		resp, err := srv.Client().Do(req)
+		AssertThat(t, err, Nil())
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		AssertThat(t, err, Nil())
+		ExpectEq(t, resp.StatusCode, http.StatusOK)
+		ExpectEq(t, strings.TrimSpace(string(b)), `{"stack":16000}`)
+	}
+	status, _ = post("/v1/tables/m1/top-up", alice, `chips`)
+	ExpectEq(t, status, http.StatusBadRequest)
+	status, _ = post("/v1/tables/m1/rebuy", alice, ``)
+	ExpectEq(t, status, http.StatusConflict)
+}
+
+func TestChat(t *testing.T) {
+	h := host.New(2)
+	table, err := h.Assign(host.Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	_, err = h.Assign(host.Assignment{
+		MatchID:   "m2",
+		PlayerIDs: []string{"alice"},
+		Config:    pb.TableConfig_builder{NoChat: proto.Bool(true)}.Build(),
+	})
+	AssertThat(t, err, Nil())
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewServer(Config{Host: h, Sessions: sessions}))
+	defer srv.Close()
+
+	do := func(method, path, token, body string) int {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		AssertThat(t, err, Nil())
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := srv.Client().Do(req)
+		AssertThat(t, err, Nil())
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	alice, bob := sessions.Create("alice"), sessions.Create("bob")
+	events, leave, err := table.Connect("bob")
+	AssertThat(t, err, Nil())
+	defer leave()
+	<-events
+
+	ExpectEq(t, do("POST", "/v1/tables/m1/chat", alice, `{"text": "good luck"}`), http.StatusNoContent)
+	ExpectEq(t, (<-events).GetChatMessage().GetText(), "good luck")
+
+	ExpectEq(t, do("PUT", "/v1/tables/m1/mutes/alice", bob, ``), http.StatusNoContent)
+	ExpectThat(t, table.Muted("bob"), ElementsAre("alice"))
+	ExpectEq(t, do("DELETE", "/v1/tables/m1/mutes/alice", bob, ``), http.StatusNoContent)
+	ExpectThat(t, table.Muted("bob"), Empty())
+
+	ExpectEq(t, do("POST", "/v1/tables/m1/chat", alice, `{"text": ""}`), http.StatusBadRequest)
+	ExpectEq(t, do("POST", "/v1/tables/m1/chat", alice, `text`), http.StatusBadRequest)
+	ExpectEq(t, do("POST", "/v1/tables/m1/chat", sessions.Create("carol"), `{"text": "hi"}`), http.StatusForbidden)
+	ExpectEq(t, do("POST", "/v1/tables/m2/chat", alice, `{"text": "hi"}`), http.StatusForbidden)
+	ExpectEq(t, do("PUT", "/v1/tables/m1/mutes/carol", bob, ``), http.StatusForbidden)
+}
@@ -0,0 +1,167 @@
+// Package bot has strategies for the bots that fill empty seats at tables,
+// so that matches can be backfilled, servers soak tested, and players
+// practice on their own.
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/lib/handeval"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+var ErrUnknownStrategy = errors.New("unknown bot strategy")
+
+var evaluator = handeval.MustNew(handeval.Standard)
+
+// Parse returns the strategy a spec names: "basic" or "caller", followed
+// for basic by its settings as in "basic:raise=0.8,margin=0.05".
+func Parse(spec string) (host.BotStrategy, error) {
+	name, settings, _ := strings.Cut(spec, ":")
+	switch name {
+	case "caller":
+		if settings != "" {
+			return nil, fmt.Errorf("%w: caller has no settings", ErrUnknownStrategy)
+		}
+		return Caller{}, nil
+	case "basic":
+		var b Basic
+		for _, kv := range strings.Split(settings, ",") {
+			if kv == "" {
+				continue
+			}
+			k, v, _ := strings.Cut(kv, "=")
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrUnknownStrategy, kv, err)
+			}
+			switch k {
+			case "raise":
+				b.Raise = f
+			case "margin":
+				b.Margin = f
+			default:
+				return nil, fmt.Errorf("%w: basic has no setting %q", ErrUnknownStrategy, k)
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownStrategy, name)
+}
+
+// Basic is a simple rule-based strategy. It rates its hand from 0 to 1,
+// bets or raises strong ones, calls when its hand is good enough for the
+// price, and otherwise checks or folds. It always does the same thing in
+// the same spot, so it is easy to play against and to test with.
+type Basic struct {
+	// Hands rated at least this strong are bet or raised. Zero means 0.75.
+	Raise float64
+
+	// How much better than the pot odds a hand must be rated to call. Zero
+	// means 0.1.
+	Margin float64
+}
+
+func (b Basic) Act(ctx context.Context, turn host.BotTurn) (host.BotAction, error) {
+	s, err := Strength(turn.Hole, turn.Board)
+	if err != nil {
+		return host.BotAction{}, err
+	}
+	opts := turn.Options
+	if opts.MaxTo > 0 && s >= or(b.Raise, 0.75) {
+		// Two thirds of the pot on top of the bet.
+		to := min(max(turn.Bet+turn.Pot*2/3, opts.MinTo), opts.MaxTo)
+		if turn.Bet == 0 {
+			return host.BotAction{Action: table.Bet, To: to}, nil
+		}
+		return host.BotAction{Action: table.Raise, To: to}, nil
+	}
+	if opts.Call == 0 {
+		return host.BotAction{Action: table.Check}, nil
+	}
+	odds := float64(opts.Call) / float64(turn.Pot+opts.Call)
+	if s >= odds+or(b.Margin, 0.1) {
+		return host.BotAction{Action: table.Call}, nil
+	}
+	return host.BotAction{Action: table.Fold}, nil
+}
+
+// Caller never bets or folds: it checks when it can and calls otherwise.
+// Tables of callers see every hand to showdown.
+type Caller struct{}
+
+func (Caller) Act(ctx context.Context, turn host.BotTurn) (host.BotAction, error) {
+	if turn.Options.Call == 0 {
+		return host.BotAction{Action: table.Check}, nil
+	}
+	return host.BotAction{Action: table.Call}, nil
+}
+
+func or(v, def float64) float64 {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// categories rates each kind of made hand.
+var categories = map[handeval.Category]float64{
+	handeval.HighCard:      0.1,
+	handeval.Pair:          0.4,
+	handeval.TwoPair:       0.6,
+	handeval.ThreeOfAKind:  0.7,
+	handeval.Straight:      0.8,
+	handeval.Flush:         0.85,
+	handeval.FullHouse:     0.9,
+	handeval.FourOfAKind:   0.95,
+	handeval.StraightFlush: 1,
+}
+
+// Strength rates a hand from 0 to 1 by the best two of its hole cards:
+// before the flop by their ranks, and after it by what they make with the
+// board.
+func Strength(hole, board []handeval.Card) (float64, error) {
+	var best float64
+	for i := range hole {
+		for j := i + 1; j < len(hole); j++ {
+			pair := []handeval.Card{hole[i], hole[j]}
+			if len(board) == 0 {
+				best = max(best, preflop(pair[0], pair[1]))
+				continue
+			}
+			h, err := evaluator.Evaluate(slices.Concat(pair, board))
+			if err != nil {
+				return 0, err
+			}
+			best = max(best, categories[h.Category])
+		}
+	}
+	return best, nil
+}
+
+// preflop rates two hole cards: pairs from 0.5 up, and other hands by
+// their high and low card, with a little more for suited and connected
+// cards.
+func preflop(a, b handeval.Card) float64 {
+	rank := func(r handeval.Rank) float64 {
+		return float64(r-handeval.Two) / float64(handeval.Ace-handeval.Two)
+	}
+	if a.Rank == b.Rank {
+		return 0.5 + 0.5*rank(a.Rank)
+	}
+	hi, lo := max(a.Rank, b.Rank), min(a.Rank, b.Rank)
+	s := 0.4*rank(hi) + 0.15*rank(lo)
+	if a.Suit == b.Suit {
+		s += 0.1
+	}
+	if hi-lo == 1 {
+		s += 0.05
+	}
+	return s
+}
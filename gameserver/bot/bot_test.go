@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/lib/handeval"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+var ctx = context.Background()
+
+func cards(t *testing.T, s string) []handeval.Card {
+	t.Helper()
+	if s == "" {
+		return nil
+	}
+	c, err := handeval.ParseCards(s)
+	AssertThat(t, err, Nil())
+	return c
+}
+
+func TestStrength(t *testing.T) {
+	for _, tc := range []struct {
+		hole, board string
+		want        float64
+	}{
+		{"As Ad", "", 1},
+		{"2s 2d", "", 0.5},
+		{"Ks As", "", 0.4 + 0.15*11/12 + 0.1 + 0.05},
+		{"7c 2d", "", 0.4 * (5.0 / 12)},
+		{"As Ad", "Ac 7h 2d", 0.7},
+		{"Kh Qh", "2h 7h 9h", 0.85},
+		{"7c 2d", "As Kd Qh", 0.1},
+		// Omaha hands are rated by their best two cards.
+		{"7c 2d Ah Ad", "As Kd Qh", 0.7},
+	} {
+		got, err := Strength(cards(t, tc.hole), cards(t, tc.board))
+		AssertThat(t, err, Nil())
+		ExpectEq(t, math.Abs(got-tc.want) < 1e-9, true)
+	}
+	_, err := Strength(cards(t, "As Ad"), cards(t, "As 7h 2d"))
+	ExpectThat(t, err, ErrorIs(handeval.ErrDuplicateCard))
+}
+
+func TestBasic(t *testing.T) {
+	facing := table.Options{Seat: 1, Call: 100, MinTo: 200, MaxTo: 1000}
+	for _, tc := range []struct {
+		name        string
+		hole, board string
+		opts        table.Options
+		bet, pot    int64
+		want        host.BotAction
+	}{
+		{"raises aces", "As Ad", "", facing, 100, 150, host.BotAction{Action: table.Raise, To: 200}},
+		{"calls a good price", "5s 5d", "", facing, 100, 150, host.BotAction{Action: table.Call}},
+		{"folds junk", "7c 2d", "", facing, 100, 150, host.BotAction{Action: table.Fold}},
+		{"checks junk", "7c 2d", "As Kd Qh", table.Options{Seat: 1, MinTo: 100, MaxTo: 1000}, 0, 600, host.BotAction{Action: table.Check}},
+		{"bets a flush", "Kh Qh", "2h 7h 9h", table.Options{Seat: 1, MinTo: 100, MaxTo: 1000}, 0, 600, host.BotAction{Action: table.Bet, To: 400}},
+		{"bets what it has", "Kh Qh", "2h 7h 9h", table.Options{Seat: 1, MinTo: 100, MaxTo: 300}, 0, 600, host.BotAction{Action: table.Bet, To: 300}},
+		{"calls when it can't raise", "Kh Qh", "2h 7h 9h", table.Options{Seat: 1, Call: 300}, 300, 900, host.BotAction{Action: table.Call}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Basic{}.Act(ctx, host.BotTurn{
+				BotID:   "bot-1",
+				Hole:    cards(t, tc.hole),
+				Board:   cards(t, tc.board),
+				Options: tc.opts,
+				Bet:     tc.bet,
+				Pot:     tc.pot,
+			})
+			AssertThat(t, err, Nil())
+			ExpectEq(t, got, tc.want)
+		})
+	}
+}
+
+func TestCaller(t *testing.T) {
+	got, err := Caller{}.Act(ctx, host.BotTurn{Options: table.Options{Seat: 1, Call: 100, MinTo: 200, MaxTo: 1000}})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got, host.BotAction{Action: table.Call})
+	got, err = Caller{}.Act(ctx, host.BotTurn{Options: table.Options{Seat: 1, MinTo: 100, MaxTo: 1000}})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got, host.BotAction{Action: table.Check})
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		spec string
+		want host.BotStrategy
+	}{
+		{"basic", Basic{}},
+		{"basic:raise=0.8,margin=0.05", Basic{Raise: 0.8, Margin: 0.05}},
+		{"caller", Caller{}},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			got, err := Parse(tc.spec)
+			AssertThat(t, err, Nil())
+			ExpectEq(t, got, tc.want)
+		})
+	}
+	for _, spec := range []string{"", "shark", "basic:bluff=0.2", "basic:raise=high", "caller:raise=1"} {
+		_, err := Parse(spec)
+		ExpectThat(t, err, ErrorIs(ErrUnknownStrategy))
+	}
+}
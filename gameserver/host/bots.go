@@ -0,0 +1,122 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jfmatt/snapfold/lib/handeval"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+// BotStrategy decides what a bot does on its turn. The game asks it through
+// PlayBot whenever it is a bot's turn to act.
+type BotStrategy interface {
+	Act(ctx context.Context, turn BotTurn) (BotAction, error)
+}
+
+// BotTurn is what a bot knows when it is its turn to act: no more than a
+// player in its seat would.
+type BotTurn struct {
+	BotID   string
+	Variant table.Variant
+
+	Hole  []handeval.Card
+	Board []handeval.Card
+
+	// What the bot may do.
+	Options table.Options
+
+	// The largest bet this round, which the bot must raise rather than
+	// bet over. Zero if nobody has bet.
+	Bet int64
+
+	// Chips in the pot, including this round's bets, and the bot's chips
+	// behind.
+	Pot   int64
+	Stack int64
+
+	BigBlind int64
+
+	// Players still in the hand, counting the bot.
+	Players int
+}
+
+// BotAction is what a bot does. For Bet and Raise, To is its total bet for
+// the round afterward.
+type BotAction struct {
+	Action table.Action
+	To     int64
+}
+
+// BotID returns the ID of the nth bot at a table, from 1. Bots are seated
+// under these IDs, which hand histories show.
+func BotID(n int) string {
+	return fmt.Sprintf("bot-%d", n)
+}
+
+// BotIDs returns the IDs of the table's bots.
+func (t *Table) BotIDs() []string {
+	ids := make([]string, t.Bots)
+	for i := range ids {
+		ids[i] = BotID(i + 1)
+	}
+	return ids
+}
+
+// PlayBot returns what one of the table's bots does on its turn, as the
+// host's bot strategy decides. If the strategy fails, or picks something
+// the bot may not do, the bot checks if it can and folds if not; the
+// strategy's error is passed to the reporter's onError.
+func (t *Table) PlayBot(ctx context.Context, turn BotTurn) (BotAction, error) {
+	if !slices.Contains(t.BotIDs(), turn.BotID) {
+		return BotAction{}, fmt.Errorf("%w: %s is not a bot", ErrNotSeated, turn.BotID)
+	}
+	t.mu.Lock()
+	closed := t.closed
+	t.mu.Unlock()
+	if closed {
+		return BotAction{}, ErrClosed
+	}
+	if t.host.bots == nil {
+		return passive(turn), nil
+	}
+	a, err := t.host.bots.Act(ctx, turn)
+	if err == nil && !allowed(turn, a) {
+		err = fmt.Errorf("%w: %s to %d", table.ErrInvalidAction, a.Action, a.To)
+	}
+	if err != nil {
+		if t.host.onError != nil {
+			t.host.onError(fmt.Errorf("bot %s at table %s: %w", turn.BotID, t.ID, err))
+		}
+		return passive(turn), nil
+	}
+	return a, nil
+}
+
+// passive checks if it can, and folds if not.
+func passive(turn BotTurn) BotAction {
+	if turn.Options.Call == 0 {
+		return BotAction{Action: table.Check}
+	}
+	return BotAction{Action: table.Fold}
+}
+
+// allowed reports whether a bot may take an action on its turn.
+func allowed(turn BotTurn, a BotAction) bool {
+	opts := turn.Options
+	switch a.Action {
+	case table.Fold:
+		return true
+	case table.Check:
+		return opts.Call == 0
+	case table.Call:
+		return opts.Call > 0
+	case table.Bet, table.Raise:
+		if (a.Action == table.Bet) != (turn.Bet == 0) {
+			return false
+		}
+		return opts.MaxTo > 0 && a.To >= opts.MinTo && a.To <= opts.MaxTo
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+type fixedBot struct {
+	action BotAction
+	err    error
+}
+
+func (b fixedBot) Act(ctx context.Context, turn BotTurn) (BotAction, error) {
+	return b.action, b.err
+}
+
+func TestPlayBot(t *testing.T) {
+	var mu sync.Mutex
+	var errs []error
+	onError := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
+	facing := BotTurn{BotID: "bot-2", Options: table.Options{Call: 100, MinTo: 200, MaxTo: 1000}, Bet: 100}
+	checked := BotTurn{BotID: "bot-1", Options: table.Options{MinTo: 100, MaxTo: 1000}}
+
+	for _, tc := range []struct {
+		name     string
+		strategy BotStrategy
+		turn     BotTurn
+		want     BotAction
+		failed   bool
+	}{
+		{"passive by default", nil, facing, BotAction{Action: table.Fold}, false},
+		{"passive checks", nil, checked, BotAction{Action: table.Check}, false},
+		{"strategy", fixedBot{action: BotAction{Action: table.Raise, To: 300}}, facing, BotAction{Action: table.Raise, To: 300}, false},
+		{"strategy fails", fixedBot{err: errors.New("boom")}, checked, BotAction{Action: table.Check}, true},
+		{"raise too small", fixedBot{action: BotAction{Action: table.Raise, To: 150}}, facing, BotAction{Action: table.Fold}, true},
+		{"bet facing a bet", fixedBot{action: BotAction{Action: table.Bet, To: 300}}, facing, BotAction{Action: table.Fold}, true},
+		{"check facing a bet", fixedBot{action: BotAction{Action: table.Check}}, facing, BotAction{Action: table.Fold}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			errs = nil
+			opts := []Option{WithReporter(&fakeReporter{}, onError)}
+			if tc.strategy != nil {
+				opts = append(opts, WithBots(tc.strategy))
+			}
+			h := New(1, opts...)
+			tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice"}, Bots: 2})
+			AssertThat(t, err, Nil())
+			defer tbl.Close(ctx, "done")
+
+			got, err := tbl.PlayBot(ctx, tc.turn)
+			AssertThat(t, err, Nil())
+			ExpectEq(t, got, tc.want)
+			mu.Lock()
+			ExpectEq(t, len(errs) > 0, tc.failed)
+			mu.Unlock()
+		})
+	}
+}
+
+func TestPlayBot_NotABot(t *testing.T) {
+	h := New(1)
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice"}, Bots: 1})
+	AssertThat(t, err, Nil())
+	ExpectThat(t, tbl.BotIDs(), ElementsAre("bot-1"))
+
+	_, err = tbl.PlayBot(ctx, BotTurn{BotID: "alice"})
+	ExpectThat(t, err, ErrorIs(ErrNotSeated))
+	_, err = tbl.PlayBot(ctx, BotTurn{BotID: "bot-2"})
+	ExpectThat(t, err, ErrorIs(ErrNotSeated))
+
+	AssertThat(t, tbl.Close(ctx, "done"), Nil())
+	_, err = tbl.PlayBot(ctx, BotTurn{BotID: "bot-1"})
+	ExpectThat(t, err, ErrorIs(ErrClosed))
+}
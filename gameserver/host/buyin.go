@@ -0,0 +1,223 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+// Wallet holds players' money away from the tables. Chips bought at a table
+// are paid for from it.
+type Wallet interface {
+	// Debit takes amount from a player's wallet, failing if they can't
+	// afford it. ref identifies the purchase, so that a retried debit is
+	// only taken once.
+	Debit(ctx context.Context, playerID string, amount int64, ref string) error
+
+	// Credit gives back a debit whose chips could not be added, such as
+	// because the table closed.
+	Credit(ctx context.Context, playerID string, amount int64, ref string) error
+}
+
+// StartHand marks a hand as being played. Chips bought during it are added
+// once it ends. No hands start once the host is draining.
+func (t *Table) StartHand() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+	if t.inHand {
+		return ErrInHand
+	}
+	if t.host.draining.Load() {
+		return ErrDraining
+	}
+	t.inHand = true
+	t.logLocked(pb.TableLogEntry_builder{HandStarted: &pb.TableLogEntry_HandStarted{}})
+	return nil
+}
+
+// EndHand marks the hand over, and adds the chips bought during it. Players'
+// hole cards are forgotten. A table being drained closes.
+func (t *Table) EndHand() {
+	t.mu.Lock()
+	t.logLocked(pb.TableLogEntry_builder{HandEnded: &pb.TableLogEntry_HandEnded{}})
+	t.endHandLocked()
+	t.host.hands.Inc(t.GameMode)
+	t.mu.Unlock()
+	if reason, ok := t.host.drainReason(); ok {
+		t.close(context.Background(), reason, false)
+	}
+}
+
+// closeAfterHand closes the table now if no hand is being played at it.
+// Otherwise EndHand closes it, as the host is draining.
+func (t *Table) closeAfterHand(ctx context.Context, reason string) {
+	t.mu.Lock()
+	inHand := t.inHand
+	t.mu.Unlock()
+	if !inHand {
+		t.close(ctx, reason, false)
+	}
+}
+
+func (t *Table) endHandLocked() {
+	t.inHand = false
+	clear(t.hole)
+	for _, id := range t.Players {
+		for _, b := range t.pending[id] {
+			t.addChipsLocked(id, b.kind, b.chips)
+		}
+	}
+	clear(t.pending)
+}
+
+// TopUp buys a seated player more chips at a cash table, paying for them
+// from their wallet. They must end up within the host's buy-in limits.
+// Chips bought during a hand are added once it ends. It returns the
+// player's chips after, not counting those still to be added.
+func (t *Table) TopUp(ctx context.Context, playerID string, chips int64) (int64, error) {
+	return t.buy(ctx, playerID, pb.ChipsBought_TOP_UP, func(stack int64) (int64, int64, error) {
+		if t.host.rebuys.Period > 0 {
+			return 0, 0, fmt.Errorf("%w: tournament players rebuy rather than top up", table.ErrBuyinNotAllowed)
+		}
+		var largest int64
+		for _, s := range t.stacks {
+			largest = max(largest, s)
+		}
+		return chips, chips, t.host.buyin.TopUp(stack, largest, chips)
+	})
+}
+
+// Rebuy buys a seated tournament player another rebuy's worth of chips,
+// paying for it from their wallet.
+func (t *Table) Rebuy(ctx context.Context, playerID string) (int64, error) {
+	r := t.host.rebuys
+	return t.buy(ctx, playerID, pb.ChipsBought_REBUY, func(stack int64) (int64, int64, error) {
+		return r.Chips, r.Cost, r.Rebuy(time.Since(t.opened), t.rebuys[playerID], stack)
+	})
+}
+
+// AddOn buys a seated tournament player the add-on, paying for it from
+// their wallet.
+func (t *Table) AddOn(ctx context.Context, playerID string) (int64, error) {
+	r := t.host.rebuys
+	return t.buy(ctx, playerID, pb.ChipsBought_ADD_ON, func(int64) (int64, int64, error) {
+		return r.AddOnChips, r.AddOnCost, r.AddOn(time.Since(t.opened), t.addOns[playerID])
+	})
+}
+
+// purchase is chips bought during a hand, waiting for it to end.
+type purchase struct {
+	kind  pb.ChipsBought_Kind
+	chips int64
+}
+
+// buy charges a player for chips and adds them. check is called with the
+// player's chips, counting those waiting to be added, and returns what to
+// add and what it costs, or why the player may not buy them. Each player
+// makes one purchase at a time.
+func (t *Table) buy(ctx context.Context, playerID string, kind pb.ChipsBought_Kind, check func(stack int64) (chips, cost int64, err error)) (int64, error) {
+	if !slices.Contains(t.Players, playerID) {
+		return 0, ErrNotSeated
+	}
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return 0, ErrClosed
+	}
+	if t.buying[playerID] {
+		t.mu.Unlock()
+		return 0, fmt.Errorf("%w: already buying chips", table.ErrBuyinNotAllowed)
+	}
+	stack := t.stacks[playerID]
+	for _, b := range t.pending[playerID] {
+		stack += b.chips
+	}
+	chips, cost, err := check(stack)
+	if err != nil {
+		t.mu.Unlock()
+		return 0, err
+	}
+	w := t.host.wallet
+	if t.host.free {
+		w, cost = nil, 0
+	} else if w == nil && cost > 0 {
+		t.mu.Unlock()
+		return 0, fmt.Errorf("%w: no wallet to pay from", ErrPayment)
+	}
+	t.buying[playerID] = true
+	t.purchases++
+	ref := fmt.Sprintf("%s/%d", t.ID, t.purchases)
+	t.logLocked(pb.TableLogEntry_builder{
+		PurchaseStarted: pb.TableLogEntry_PurchaseStarted_builder{Number: proto.Int32(int32(t.purchases))}.Build(),
+	})
+	t.mu.Unlock()
+
+	if cost > 0 {
+		if err := w.Debit(ctx, playerID, cost, ref); err != nil {
+			t.mu.Lock()
+			delete(t.buying, playerID)
+			t.mu.Unlock()
+			return 0, fmt.Errorf("%w: %w", ErrPayment, err)
+		}
+	}
+
+	t.mu.Lock()
+	delete(t.buying, playerID)
+	if t.closed {
+		t.mu.Unlock()
+		if cost > 0 {
+			if err := w.Credit(context.WithoutCancel(ctx), playerID, cost, ref); err != nil && t.host.onError != nil {
+				t.host.onError(err)
+			}
+		}
+		return 0, ErrClosed
+	}
+	defer t.mu.Unlock()
+	t.logLocked(pb.TableLogEntry_builder{
+		ChipsBought: pb.ChipsBought_builder{
+			PlayerId: proto.String(playerID),
+			Kind:     kind.Enum(),
+			Chips:    proto.Int64(chips),
+		}.Build(),
+	})
+	t.boughtLocked(playerID, kind, chips)
+	return t.stacks[playerID], nil
+}
+
+// boughtLocked adds chips a player has paid for, or holds them until the
+// hand ends.
+func (t *Table) boughtLocked(playerID string, kind pb.ChipsBought_Kind, chips int64) {
+	switch kind {
+	case pb.ChipsBought_REBUY:
+		t.rebuys[playerID]++
+	case pb.ChipsBought_ADD_ON:
+		t.addOns[playerID] = true
+	}
+	if t.inHand {
+		t.pending[playerID] = append(t.pending[playerID], purchase{kind, chips})
+	} else {
+		t.addChipsLocked(playerID, kind, chips)
+	}
+}
+
+func (t *Table) addChipsLocked(playerID string, kind pb.ChipsBought_Kind, chips int64) {
+	t.stacks[playerID] += chips
+	t.wakeUp()
+	t.broadcastLocked(pb.TableEvent_builder{
+		ChipsBought: pb.ChipsBought_builder{
+			PlayerId: proto.String(playerID),
+			Kind:     kind.Enum(),
+			Chips:    proto.Int64(chips),
+			Stack:    proto.Int64(t.stacks[playerID]),
+		}.Build(),
+	}.Build())
+}
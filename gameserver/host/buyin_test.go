@@ -0,0 +1,200 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+var errBroke = errors.New("not enough money")
+
+// fakeWallet gives each player a balance, and records the refs of debits.
+type fakeWallet struct {
+	mu       sync.Mutex
+	balances map[string]int64
+	refs     []string
+
+	// If set, debits wait for it to close.
+	hold chan struct{}
+}
+
+func (w *fakeWallet) Debit(ctx context.Context, playerID string, amount int64, ref string) error {
+	if w.hold != nil {
+		<-w.hold
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.balances[playerID] < amount {
+		return errBroke
+	}
+	w.balances[playerID] -= amount
+	w.refs = append(w.refs, ref)
+	return nil
+}
+
+func (w *fakeWallet) Credit(ctx context.Context, playerID string, amount int64, ref string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.balances[playerID] += amount
+	return nil
+}
+
+func (w *fakeWallet) balance(playerID string) int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.balances[playerID]
+}
+
+func TestTopUp(t *testing.T) {
+	w := &fakeWallet{balances: map[string]int64{"alice": 50000}}
+	h := New(1, WithBuyin(table.BuyinRules{Min: 4000, Max: 20000}), WithWallet(w))
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}, Stacks: map[string]int64{"alice": 5000}})
+	AssertThat(t, err, Nil())
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, alice)
+
+	stack, err := tbl.TopUp(ctx, "alice", 15000)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, stack, int64(20000))
+	ExpectEq(t, w.balance("alice"), int64(35000))
+	ev := next(t, alice).GetChipsBought()
+	ExpectEq(t, ev.GetPlayerId(), "alice")
+	ExpectEq(t, ev.GetKind(), pb.ChipsBought_TOP_UP)
+	ExpectEq(t, ev.GetChips(), int64(15000))
+	ExpectEq(t, ev.GetStack(), int64(20000))
+	ExpectThat(t, w.refs, ElementsAre("m1/1"))
+
+	_, err = tbl.TopUp(ctx, "alice", 1)
+	ExpectThat(t, err, ErrorIs(table.ErrBuyinNotAllowed))
+	_, err = tbl.TopUp(ctx, "bob", 10000)
+	ExpectThat(t, err, ErrorIs(ErrPayment))
+	ExpectThat(t, err, ErrorIs(errBroke))
+	_, err = tbl.TopUp(ctx, "carol", 10000)
+	ExpectThat(t, err, ErrorIs(ErrNotSeated))
+	ExpectEq(t, w.balance("alice"), int64(35000))
+}
+
+func TestTopUp_DuringHand(t *testing.T) {
+	h := New(1, WithPlayMoney())
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}, Stacks: map[string]int64{"alice": 5000}})
+	AssertThat(t, err, Nil())
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, alice)
+
+	AssertThat(t, tbl.StartHand(), Nil())
+	ExpectThat(t, tbl.StartHand(), ErrorIs(ErrInHand))
+	stack, err := tbl.TopUp(ctx, "alice", 1000)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, stack, int64(5000))
+	ExpectThat(t, tbl.Stacks(), ElementsAre(Stack{"alice", 5000}, Stack{"bob", 0}))
+
+	tbl.EndHand()
+	ExpectEq(t, next(t, alice).GetChipsBought().GetStack(), int64(6000))
+	ExpectThat(t, tbl.Stacks(), ElementsAre(Stack{"alice", 6000}, Stack{"bob", 0}))
+}
+
+func TestTopUp_NoWallet(t *testing.T) {
+	h := New(1)
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}, Stacks: map[string]int64{"alice": 5000}})
+	AssertThat(t, err, Nil())
+
+	// Chips that cost money can't be bought with nothing to pay from.
+	_, err = tbl.TopUp(ctx, "alice", 1000)
+	ExpectThat(t, err, ErrorIs(ErrPayment))
+	ExpectThat(t, tbl.Stacks(), ElementsAre(Stack{"alice", 5000}, Stack{"bob", 0}))
+
+	// At play-money tables they're free, even with a wallet.
+	w := &fakeWallet{balances: map[string]int64{}}
+	h = New(1, WithWallet(w), WithPlayMoney())
+	tbl, err = h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}, Stacks: map[string]int64{"alice": 5000}})
+	AssertThat(t, err, Nil())
+	stack, err := tbl.TopUp(ctx, "alice", 1000)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, stack, int64(6000))
+	ExpectThat(t, w.refs, Empty())
+}
+
+func TestTopUp_Closed(t *testing.T) {
+	w := &fakeWallet{balances: map[string]int64{"alice": 5000}, hold: make(chan struct{})}
+	h := New(1, WithWallet(w))
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+
+	done := make(chan error)
+	go func() {
+		_, err := tbl.TopUp(ctx, "alice", 1000)
+		done <- err
+	}()
+	// The table closes while the wallet is being debited, so the debit is
+	// given back.
+	time.Sleep(10 * time.Millisecond)
+	AssertThat(t, tbl.Close(ctx, "done"), Nil())
+	close(w.hold)
+	ExpectThat(t, <-done, ErrorIs(ErrClosed))
+	ExpectEq(t, w.balance("alice"), int64(5000))
+}
+
+func TestRebuy(t *testing.T) {
+	w := &fakeWallet{balances: map[string]int64{"alice": 10000}}
+	h := New(1, WithWallet(w), WithRebuys(table.RebuyRules{
+		Period:      time.Hour,
+		MaxRebuys:   1,
+		Cost:        1000,
+		Chips:       1500,
+		AddOnCost:   2000,
+		AddOnChips:  3000,
+		AddOnWindow: time.Minute,
+	}))
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, alice)
+
+	stack, err := tbl.Rebuy(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, stack, int64(1500))
+	ExpectEq(t, w.balance("alice"), int64(9000))
+	ExpectEq(t, next(t, alice).GetChipsBought().GetKind(), pb.ChipsBought_REBUY)
+
+	_, err = tbl.Rebuy(ctx, "alice")
+	ExpectThat(t, err, ErrorIs(table.ErrBuyinNotAllowed))
+	_, err = tbl.TopUp(ctx, "alice", 1000)
+	ExpectThat(t, err, ErrorIs(table.ErrBuyinNotAllowed))
+	// Rebuys have not ended.
+	_, err = tbl.AddOn(ctx, "alice")
+	ExpectThat(t, err, ErrorIs(table.ErrBuyinNotAllowed))
+	ExpectEq(t, w.balance("alice"), int64(9000))
+}
+
+func TestAddOn(t *testing.T) {
+	h := New(1, WithRebuys(table.RebuyRules{
+		Period:      time.Millisecond,
+		Chips:       1500,
+		AddOnChips:  3000,
+		AddOnWindow: time.Hour,
+	}))
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	time.Sleep(10 * time.Millisecond)
+
+	_, err = tbl.Rebuy(ctx, "alice")
+	ExpectThat(t, err, ErrorIs(table.ErrBuyinNotAllowed))
+	stack, err := tbl.AddOn(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, stack, int64(3000))
+	_, err = tbl.AddOn(ctx, "alice")
+	ExpectThat(t, err, ErrorIs(table.ErrBuyinNotAllowed))
+}
@@ -0,0 +1,88 @@
+package host
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+// MaxChatLength is the most characters a chat message may have.
+const MaxChatLength = 200
+
+// Chat sends a seated player's message to everyone watching the table,
+// except players who muted them. Leading and trailing space is trimmed.
+// Chat isn't logged, so it is lost if the server restarts.
+func (t *Table) Chat(playerID, text string) error {
+	if !slices.Contains(t.Players, playerID) {
+		return ErrNotSeated
+	}
+	if t.Config.GetNoChat() {
+		return ErrChatDisabled
+	}
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > MaxChatLength {
+		return ErrBadChat
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+	ev := pb.TableEvent_builder{
+		ChatMessage: pb.ChatMessage_builder{PlayerId: proto.String(playerID), Text: proto.String(text)}.Build(),
+	}.Build()
+	for ch, id := range t.subs {
+		if !t.mutes[id][playerID] {
+			t.sendLocked(ch, ev)
+		}
+	}
+	return nil
+}
+
+// Mute stops a seated player getting another's chat at the table. Muting
+// someone already muted does nothing.
+func (t *Table) Mute(playerID, target string) error {
+	return t.setMuted(playerID, target, true)
+}
+
+// Unmute lets a seated player get the chat of someone they muted again.
+func (t *Table) Unmute(playerID, target string) error {
+	return t.setMuted(playerID, target, false)
+}
+
+// Muted returns the players a seated player has muted, in seat order.
+func (t *Table) Muted(playerID string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var ids []string
+	for _, id := range t.Players {
+		if t.mutes[playerID][id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (t *Table) setMuted(playerID, target string, muted bool) error {
+	if !slices.Contains(t.Players, playerID) || !slices.Contains(t.Players, target) {
+		return ErrNotSeated
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+	if !muted {
+		delete(t.mutes[playerID], target)
+		return nil
+	}
+	if t.mutes[playerID] == nil {
+		t.mutes[playerID] = map[string]bool{}
+	}
+	t.mutes[playerID][target] = true
+	return nil
+}
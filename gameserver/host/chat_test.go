@@ -0,0 +1,81 @@
+package host
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+func TestChat(t *testing.T) {
+	h := New(1)
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob", "carol"}})
+	AssertThat(t, err, Nil())
+	alice, leaveAlice, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leaveAlice()
+	next(t, alice)
+	bob, leaveBob, err := tbl.Connect("bob")
+	AssertThat(t, err, Nil())
+	defer leaveBob()
+	next(t, bob)
+	next(t, alice) // bob connected
+	spectator, leave, err := tbl.Watch()
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, spectator)
+
+	AssertThat(t, tbl.Chat("alice", "  nice hand  "), Nil())
+	for _, ch := range []<-chan *pb.TableEvent{alice, bob, spectator} {
+		msg := next(t, ch).GetChatMessage()
+		ExpectEq(t, msg.GetPlayerId(), "alice")
+		ExpectEq(t, msg.GetText(), "nice hand")
+	}
+
+	// Bob mutes carol, so only alice and the spectator hear her.
+	AssertThat(t, tbl.Mute("bob", "carol"), Nil())
+	ExpectThat(t, tbl.Muted("bob"), ElementsAre("carol"))
+	AssertThat(t, tbl.Chat("carol", "gg"), Nil())
+	AssertThat(t, tbl.Chat("alice", "thanks"), Nil())
+	ExpectEq(t, next(t, alice).GetChatMessage().GetText(), "gg")
+	ExpectEq(t, next(t, spectator).GetChatMessage().GetText(), "gg")
+	ExpectEq(t, next(t, bob).GetChatMessage().GetText(), "thanks")
+
+	AssertThat(t, tbl.Unmute("bob", "carol"), Nil())
+	ExpectThat(t, tbl.Muted("bob"), Empty())
+
+	ExpectThat(t, tbl.Chat("alice", ""), ErrorIs(ErrBadChat))
+	ExpectThat(t, tbl.Chat("alice", strings.Repeat("é", MaxChatLength)), Nil())
+	ExpectThat(t, tbl.Chat("alice", strings.Repeat("é", MaxChatLength+1)), ErrorIs(ErrBadChat))
+	ExpectThat(t, tbl.Chat("dave", "hi"), ErrorIs(ErrNotSeated))
+	ExpectThat(t, tbl.Mute("alice", "dave"), ErrorIs(ErrNotSeated))
+}
+
+func TestChat_Disabled(t *testing.T) {
+	h := New(1)
+	tbl, err := h.Assign(Assignment{
+		MatchID:   "m1",
+		PlayerIDs: []string{"alice"},
+		Config:    pb.TableConfig_builder{NoChat: proto.Bool(true)}.Build(),
+	})
+	AssertThat(t, err, Nil())
+	ExpectThat(t, tbl.Chat("alice", "hi"), ErrorIs(ErrChatDisabled))
+}
+
+func TestChat_FeatureFlag(t *testing.T) {
+	cfg := pb.TableConfig_builder{
+		Features: pb.FeatureFlags_builder{
+			Environments: map[string]*pb.FeatureFlags{"staging": pb.FeatureFlags_builder{Chat: proto.Bool(false)}.Build()},
+		}.Build(),
+	}.Build()
+	staging, prod := New(1, WithEnvironment("staging")), New(1)
+	a, err := staging.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice"}, Config: cfg})
+	AssertThat(t, err, Nil())
+	ExpectThat(t, a.Chat("alice", "hi"), ErrorIs(ErrChatDisabled))
+	b, err := prod.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice"}, Config: cfg})
+	AssertThat(t, err, Nil())
+	ExpectThat(t, b.Chat("alice", "hi"), Nil())
+}
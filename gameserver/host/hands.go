@@ -0,0 +1,98 @@
+package host
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/handeval"
+	"github.com/jfmatt/snapfold/lib/handhistory"
+)
+
+// PlayedHand is a hand played at a table, with each seated player's history
+// of it.
+type PlayedHand struct {
+	TableID  string
+	Number   uint64
+	PlayedAt time.Time
+
+	// Each player's history of the hand, by player ID, showing only their
+	// own hole cards and those shown down.
+	Histories map[string]string
+
+	// Chips the house took from the pots.
+	Rake int64
+}
+
+// Deal gives seated players their hole cards for the hand being played,
+// sending each player's only to their own connections. Players who connect
+// during the hand get theirs in the snapshot. The cards are forgotten once
+// the hand ends.
+func (t *Table) Deal(hole map[string][]handeval.Card) error {
+	for id := range hole {
+		if !slices.Contains(t.Players, id) {
+			return ErrNotSeated
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+	if !t.inHand {
+		return ErrNoHand
+	}
+	for _, id := range t.Players {
+		cards, ok := hole[id]
+		if !ok {
+			continue
+		}
+		strs := make([]string, len(cards))
+		for i, c := range cards {
+			strs[i] = c.String()
+		}
+		t.hole[id] = strs
+		ev := pb.TableEvent_builder{
+			HoleCards: pb.HoleCards_builder{PlayerId: proto.String(id), Cards: strs}.Build(),
+		}.Build()
+		t.logLocked(pb.TableLogEntry_builder{Event: ev})
+		t.sendPrivateLocked(id, ev)
+	}
+	return nil
+}
+
+// RecordHand writes each seated player's history of a hand played at the
+// table, and reports it so that players can download their histories
+// later. Players in the hand are named by their player IDs. Reporting
+// happens in the background, so as not to hold up the next hand.
+func (t *Table) RecordHand(h handhistory.Hand) error {
+	t.mu.Lock()
+	closed := t.closed
+	t.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	if h.Table == "" {
+		h.Table = t.ID
+	}
+	played := PlayedHand{TableID: t.ID, Number: h.Number, PlayedAt: h.PlayedAt, Histories: map[string]string{}, Rake: h.Rake}
+	bots := t.BotIDs()
+	h.Seats = slices.Clone(h.Seats)
+	for i, s := range h.Seats {
+		h.Seats[i].Bot = slices.Contains(bots, s.Player)
+	}
+	for _, s := range h.Seats {
+		if slices.Contains(t.Players, s.Player) {
+			played.Histories[s.Player] = handhistory.Format(h, s.Player)
+		}
+	}
+	t.host.reports.Add(1)
+	go func() {
+		defer t.host.reports.Done()
+		t.host.report(func(r Reporter) error { return r.HandPlayed(context.Background(), played) })
+	}()
+	return nil
+}
@@ -0,0 +1,69 @@
+package host
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/handeval"
+	"github.com/jfmatt/snapfold/lib/handhistory"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+func TestRecordHand(t *testing.T) {
+	reporter := &fakeReporter{}
+	h := New(1, WithReporter(reporter, nil))
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}, Bots: 1})
+	AssertThat(t, err, Nil())
+
+	hole := func(s string) []handeval.Card {
+		c, err := handeval.ParseCards(s)
+		AssertThat(t, err, Nil())
+		return c
+	}
+	hand := handhistory.Hand{
+		Number:   7,
+		MaxSeats: 3,
+		Game:     "Hold'em No Limit",
+		Blinds:   []int64{50, 100},
+		PlayedAt: time.Unix(1000, 0),
+		Seats: []handhistory.Seat{
+			{Seat: 1, Player: "alice", Stack: 1000},
+			{Seat: 2, Player: "bob", Stack: 1000},
+			{Seat: 3, Player: "bot-1", Stack: 1000},
+		},
+		Hole: map[int][]handeval.Card{1: hole("As Ad"), 2: hole("7c 2d"), 3: hole("Kh Kd")},
+		Events: []table.Event{
+			{Type: table.EventBlind, Seat: 2, Amount: 50, Total: 50},
+			{Type: table.EventBlind, Seat: 3, Amount: 100, Total: 100},
+			{Type: table.EventFold, Seat: 1},
+			{Type: table.EventFold, Seat: 2},
+			{Type: table.EventWin, Seat: 3, Amount: 140},
+		},
+		UncalledSeat: -1,
+		Rake:         10,
+	}
+	AssertThat(t, tbl.RecordHand(hand), Nil())
+
+	deadline := time.Now().Add(time.Second)
+	for len(reporter.Hands()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	AssertThat(t, reporter.Hands(), Len(1))
+	played := reporter.Hands()[0]
+	ExpectEq(t, played.TableID, "m1")
+	ExpectEq(t, played.Number, uint64(7))
+	ExpectEq(t, played.Rake, int64(10))
+	// Bots have no histories, and each player sees only their own cards.
+	AssertThat(t, played.Histories, Len(2))
+	ExpectEq(t, strings.Contains(played.Histories["alice"], "Dealt to alice [As Ad]"), true)
+	ExpectEq(t, strings.Contains(played.Histories["alice"], "7c 2d"), false)
+	ExpectEq(t, strings.Contains(played.Histories["bob"], "Table 'm1'"), true)
+	ExpectEq(t, strings.Contains(played.Histories["bob"], "Seat 3: bot-1 (1000 in chips) is a bot"), true)
+	ExpectEq(t, hand.Seats[2].Bot, false)
+
+	AssertThat(t, tbl.Close(ctx, "done"), Nil())
+	ExpectThat(t, tbl.RecordHand(hand), ErrorIs(ErrClosed))
+}
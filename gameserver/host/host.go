@@ -0,0 +1,404 @@
+// Package host runs the tables the matchmaker assigns to a game server and
+// fans each table's events out to the players connected to it.
+package host
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/metrics"
+	"github.com/jfmatt/snapfold/lib/shuffle"
+	"github.com/jfmatt/snapfold/lib/table"
+	"github.com/jfmatt/snapfold/lib/tracing"
+)
+
+var (
+	ErrNotFound  = errors.New("table not found")
+	ErrNotSeated = errors.New("player is not seated at the table")
+	ErrClosed    = errors.New("table is closed")
+	ErrFull      = errors.New("server is running as many tables as it can")
+	ErrNotTurn   = errors.New("not the player's turn")
+	ErrBusy      = errors.New("table is too busy")
+	ErrPaused    = errors.New("table is paused")
+	ErrInHand    = errors.New("a hand is being played")
+	ErrPayment   = errors.New("payment failed")
+	ErrBadLog    = errors.New("table log is invalid")
+	ErrNoHand    = errors.New("no hand is being played")
+	ErrDraining  = errors.New("server is shutting down")
+
+	ErrNoSpectators = errors.New("table does not allow spectators")
+	ErrChatDisabled = errors.New("table does not allow chat")
+	ErrBadChat      = errors.New("chat message is empty or too long")
+)
+
+// DefaultIdleTimeout is how long a table stays open with no players
+// connected, unless set by WithIdleTimeout.
+const DefaultIdleTimeout = 5 * time.Minute
+
+// Assignment is a match the matchmaker has placed on this server.
+type Assignment struct {
+	MatchID   string
+	GameMode  string
+	PlayerIDs []string
+
+	// Number of seats to fill with bots.
+	Bots int
+
+	// Chips each player brings to the table, by player ID.
+	Stacks map[string]int64
+
+	// The configuration of the match's game mode. Nil for the defaults.
+	Config *pb.TableConfig
+
+	// The W3C traceparent of the span the table is opened for, if it was
+	// traced, such as the matchmaker's allocation of the match.
+	Traceparent string
+}
+
+// Reporter tells the matchmaker what happens at tables, so that it can
+// hold or free players' seats and keep their hand histories.
+type Reporter interface {
+	// Disconnected reports that a seated player's last connection dropped.
+	Disconnected(ctx context.Context, tableID, playerID string) error
+
+	// TableClosed reports that a table has finished.
+	TableClosed(ctx context.Context, tableID string) error
+
+	// HandPlayed reports a hand played at a table, so that players' hand
+	// histories can be stored.
+	HandPlayed(ctx context.Context, h PlayedHand) error
+}
+
+// Host runs a server's tables. It is safe for concurrent use.
+type Host struct {
+	capacity int
+	idle     time.Duration
+	clock    table.ClockRules
+	buyin    table.BuyinRules
+	rebuys   table.RebuyRules
+	wallet   Wallet
+	free     bool
+	log      Log
+	bots     BotStrategy
+	shuffler *shuffle.Shuffler
+	reporter Reporter
+	onError  func(error)
+	onPanic  func(tableID string, v any, stack []byte)
+	env      string
+	crashes  atomic.Uint64
+
+	// Set once the host stops opening tables and starting hands, with the
+	// reason its tables close.
+	draining atomic.Bool
+	drained  string
+
+	// Reports being sent in the background.
+	reports sync.WaitGroup
+
+	// Hands played, and how long players took to act, by game mode.
+	hands   *metrics.Counter
+	actions *metrics.Histogram
+
+	mu     sync.Mutex
+	tables map[string]*Table
+}
+
+// Option configures a Host.
+type Option func(*Host)
+
+// WithIdleTimeout sets how long a table stays open with no players
+// connected, including while waiting for the first to connect. A
+// non-positive timeout keeps DefaultIdleTimeout.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(h *Host) {
+		if d > 0 {
+			h.idle = d
+		}
+	}
+}
+
+// WithClock sets how long players may take to act. By default, turns have
+// no time limit.
+func WithClock(r table.ClockRules) Option {
+	return func(h *Host) { h.clock = r }
+}
+
+// WithBuyin sets the limits on chips players may top up to at cash tables.
+// By default, there are none.
+func WithBuyin(r table.BuyinRules) Option {
+	return func(h *Host) { h.buyin = r }
+}
+
+// WithRebuys makes the host's tables tournament tables, where players buy
+// chips with rebuys and add-ons rather than topping up.
+func WithRebuys(r table.RebuyRules) Option {
+	return func(h *Host) { h.rebuys = r }
+}
+
+// WithWallet sets where players pay for the chips they buy. Without one,
+// chips that cost anything can't be bought, unless the host is made
+// WithPlayMoney.
+func WithWallet(w Wallet) Option {
+	return func(h *Host) { h.wallet = w }
+}
+
+// WithPlayMoney makes the chips players buy free, as at play-money tables,
+// rather than paid for from a wallet.
+func WithPlayMoney() Option {
+	return func(h *Host) { h.free = true }
+}
+
+// WithLog sets where tables log their changes, so that they can be rebuilt
+// with Recover if the server crashes. Errors logging are passed to the
+// reporter's onError. By default, tables are not logged.
+func WithLog(l Log) Option {
+	return func(h *Host) { h.log = l }
+}
+
+// WithBots sets how the host's bots play. By default, bots check when they
+// can and fold when they can't.
+func WithBots(s BotStrategy) Option {
+	return func(h *Host) { h.bots = s }
+}
+
+// WithShuffler sets how the host's tables shuffle their decks. By default,
+// every shuffle is seeded from crypto/rand; pass shuffle.NewSeeded to
+// repeat the same hands.
+func WithShuffler(s *shuffle.Shuffler) Option {
+	return func(h *Host) { h.shuffler = s }
+}
+
+// WithReporter sets where to report disconnects and closed tables. Errors
+// reporting are passed to onError.
+func WithReporter(r Reporter, onError func(error)) Option {
+	return func(h *Host) {
+		h.reporter = r
+		h.onError = onError
+	}
+}
+
+// WithPanicHandler sets a function called with the value and stack trace
+// whenever a table panics. The table closes, and the server carries on.
+func WithPanicHandler(f func(tableID string, v any, stack []byte)) Option {
+	return func(h *Host) { h.onPanic = f }
+}
+
+// WithEnvironment sets the name of the environment the server runs in,
+// which chooses the feature flags that apply to its tables' configs.
+func WithEnvironment(env string) Option {
+	return func(h *Host) { h.env = env }
+}
+
+// WithMetrics registers the hands played at the host's tables, and how
+// long players take to act on their turns, in reg.
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(h *Host) {
+		h.hands = reg.Counter("gameserver_hands_total", "Hands played.", "game_mode")
+		h.actions = reg.Histogram("gameserver_action_seconds", "Time from the start of a player's turn until they acted; turns that timed out are left out.",
+			[]float64{.25, .5, 1, 2, 5, 10, 20, 30, 60, 120}, "game_mode")
+	}
+}
+
+// New returns a Host that runs at most capacity tables at once.
+func New(capacity int, opts ...Option) *Host {
+	h := &Host{capacity: capacity, idle: DefaultIdleTimeout, shuffler: shuffle.New(), tables: map[string]*Table{}}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Capacity returns the most tables the host runs at once.
+func (h *Host) Capacity() int {
+	return h.capacity
+}
+
+// Len returns the number of tables open now.
+func (h *Host) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.tables)
+}
+
+// Assign opens a table for a match. The table has the match's ID. Assigning
+// a match that already has a table returns that table.
+func (h *Host) Assign(a Assignment) (_ *Table, err error) {
+	_, span := otel.Tracer("github.com/jfmatt/snapfold/gameserver/host").Start(
+		tracing.Continue(context.Background(), a.Traceparent), "host.Assign",
+		trace.WithAttributes(attribute.String("snapfold.match_id", a.MatchID), attribute.String("snapfold.game_mode", a.GameMode)))
+	defer func() { tracing.End(span, err) }()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if t, ok := h.tables[a.MatchID]; ok {
+		return t, nil
+	}
+	if h.draining.Load() {
+		return nil, ErrDraining
+	}
+	if len(h.tables) >= h.capacity {
+		return nil, fmt.Errorf("%w: %d tables", ErrFull, h.capacity)
+	}
+	t, err := newTable(h, a, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := t.logOpened(); err != nil {
+		return nil, err
+	}
+	h.tables[t.ID] = t
+	t.start()
+	return t, nil
+}
+
+// Table returns an open table.
+func (h *Host) Table(id string) (*Table, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t, ok := h.tables[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return t, nil
+}
+
+// Close closes a table, disconnecting its players, and reports it closed.
+func (h *Host) Close(ctx context.Context, id, reason string) error {
+	t, err := h.Table(id)
+	if err != nil {
+		return err
+	}
+	return t.Close(ctx, reason)
+}
+
+// CloseAll closes every table, such as when the server shuts down.
+func (h *Host) CloseAll(ctx context.Context, reason string) error {
+	h.mu.Lock()
+	tables := make([]*Table, 0, len(h.tables))
+	for _, t := range h.tables {
+		tables = append(tables, t)
+	}
+	h.mu.Unlock()
+	var errs []error
+	for _, t := range tables {
+		if err := t.Close(ctx, reason); err != nil && !errors.Is(err, ErrClosed) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Drain closes the host's tables without cutting hands short, such as when
+// the server shuts down. The host opens no more tables and no new hands
+// start; each table closes once the hand being played at it ends, and those
+// still in a hand when ctx ends are closed at once. Drain returns once every
+// table has closed and the hands played have been reported.
+func (h *Host) Drain(ctx context.Context, reason string) error {
+	h.mu.Lock()
+	h.drained = reason
+	h.draining.Store(true)
+	tables := make([]*Table, 0, len(h.tables))
+	for _, t := range h.tables {
+		tables = append(tables, t)
+	}
+	h.mu.Unlock()
+
+	// Closing reports the tables closed, which should happen even once
+	// ctx ends.
+	closeCtx := context.WithoutCancel(ctx)
+	for _, t := range tables {
+		t.closeAfterHand(closeCtx, reason)
+	}
+	var errs []error
+	for _, t := range tables {
+		select {
+		case <-t.done:
+		case <-ctx.Done():
+			if err := t.Close(closeCtx, reason); err != nil && !errors.Is(err, ErrClosed) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	h.reports.Wait()
+	return errors.Join(errs...)
+}
+
+// Draining reports whether the host has stopped opening tables.
+func (h *Host) Draining() bool {
+	return h.draining.Load()
+}
+
+// drainReason returns the reason tables close for if the host is draining.
+func (h *Host) drainReason() (string, bool) {
+	if !h.draining.Load() {
+		return "", false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.drained, true
+}
+
+// Connections returns the number of player connections open to the
+// host's tables now.
+func (h *Host) Connections() int {
+	n := 0
+	for _, s := range h.Stats() {
+		n += s.Connections
+	}
+	return n
+}
+
+// Stats returns the running totals of every open table, ordered by table
+// ID.
+func (h *Host) Stats() []Stats {
+	h.mu.Lock()
+	tables := make([]*Table, 0, len(h.tables))
+	for _, t := range h.tables {
+		tables = append(tables, t)
+	}
+	h.mu.Unlock()
+	stats := make([]Stats, 0, len(tables))
+	for _, t := range tables {
+		stats = append(stats, t.Stats())
+	}
+	slices.SortFunc(stats, func(a, b Stats) int { return strings.Compare(a.TableID, b.TableID) })
+	return stats
+}
+
+// Crashes returns how many tables have closed because they panicked.
+func (h *Host) Crashes() uint64 {
+	return h.crashes.Load()
+}
+
+func (h *Host) crashed(tableID string, v any, stack []byte) {
+	h.crashes.Add(1)
+	if h.onPanic != nil {
+		h.onPanic(tableID, v, stack)
+	}
+}
+
+func (h *Host) remove(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.tables, id)
+}
+
+func (h *Host) report(f func(Reporter) error) {
+	if h.reporter == nil {
+		return
+	}
+	if err := f(h.reporter); err != nil && h.onError != nil {
+		h.onError(err)
+	}
+}
@@ -0,0 +1,152 @@
+package host
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/handhistory"
+)
+
+var ctx = context.Background()
+
+// fakeReporter records what is reported to it.
+type fakeReporter struct {
+	mu           sync.Mutex
+	disconnected []string // tableID/playerID
+	closed       []string
+	hands        []PlayedHand
+}
+
+func (r *fakeReporter) Disconnected(ctx context.Context, tableID, playerID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.disconnected = append(r.disconnected, tableID+"/"+playerID)
+	return nil
+}
+
+func (r *fakeReporter) TableClosed(ctx context.Context, tableID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = append(r.closed, tableID)
+	return nil
+}
+
+func (r *fakeReporter) HandPlayed(ctx context.Context, h PlayedHand) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hands = append(r.hands, h)
+	return nil
+}
+
+func (r *fakeReporter) Hands() []PlayedHand {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]PlayedHand(nil), r.hands...)
+}
+
+func (r *fakeReporter) Closed() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.closed...)
+}
+
+func TestAssign(t *testing.T) {
+	reporter := &fakeReporter{}
+	h := New(1, WithReporter(reporter, nil))
+
+	t1, err := h.Assign(Assignment{MatchID: "m1", GameMode: "holdem", PlayerIDs: []string{"alice", "bob"}, Bots: 1})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, t1.ID, "m1")
+	ExpectThat(t, t1.Players, ElementsAre("alice", "bob"))
+	ExpectEq(t, t1.Bots, 1)
+
+	// Assignments are handed off once, but may be retried.
+	again, err := h.Assign(Assignment{MatchID: "m1"})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, again, t1)
+
+	_, err = h.Assign(Assignment{MatchID: "m2"})
+	ExpectThat(t, err, ErrorIs(ErrFull))
+	ExpectEq(t, h.Len(), 1)
+
+	got, err := h.Table("m1")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got, t1)
+	_, err = h.Table("m2")
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+
+	AssertThat(t, h.Close(ctx, "m1", "done"), Nil())
+	ExpectEq(t, h.Len(), 0)
+	ExpectThat(t, reporter.Closed(), ElementsAre("m1"))
+	ExpectThat(t, h.Close(ctx, "m1", "done"), ErrorIs(ErrNotFound))
+	ExpectThat(t, t1.Close(ctx, "done"), ErrorIs(ErrClosed))
+
+	_, err = h.Assign(Assignment{MatchID: "m2"})
+	ExpectThat(t, err, Nil())
+}
+
+func TestConnections(t *testing.T) {
+	h := New(2)
+	for _, id := range []string{"m1", "m2"} {
+		tbl, err := h.Assign(Assignment{MatchID: id, PlayerIDs: []string{"alice", "bob"}})
+		AssertThat(t, err, Nil())
+		_, leave, err := tbl.Connect("alice")
+		AssertThat(t, err, Nil())
+		defer leave()
+	}
+	ExpectEq(t, h.Connections(), 2)
+}
+
+func TestCloseAll(t *testing.T) {
+	reporter := &fakeReporter{}
+	h := New(10, WithReporter(reporter, nil))
+	for _, id := range []string{"m1", "m2", "m3"} {
+		_, err := h.Assign(Assignment{MatchID: id})
+		AssertThat(t, err, Nil())
+	}
+	AssertThat(t, h.CloseAll(ctx, "shutting down"), Nil())
+	ExpectEq(t, h.Len(), 0)
+	ExpectThat(t, reporter.Closed(), Len(3))
+}
+
+func TestDrain(t *testing.T) {
+	reporter := &fakeReporter{}
+	h := New(10, WithReporter(reporter, nil))
+	idle, err := h.Assign(Assignment{MatchID: "m1"})
+	AssertThat(t, err, Nil())
+	playing, err := h.Assign(Assignment{MatchID: "m2", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+	AssertThat(t, playing.StartHand(), Nil())
+
+	drained := make(chan error)
+	go func() { drained <- h.Drain(ctx, "shutting down") }()
+	<-idle.done
+	ExpectEq(t, h.Draining(), true)
+	_, err = h.Assign(Assignment{MatchID: "m3"})
+	ExpectThat(t, err, ErrorIs(ErrDraining))
+
+	// The hand being played finishes, and is reported.
+	AssertThat(t, playing.RecordHand(handhistory.Hand{Number: 1, Seats: []handhistory.Seat{{Player: "alice"}}}), Nil())
+	playing.EndHand()
+	AssertThat(t, <-drained, Nil())
+	ExpectEq(t, h.Len(), 0)
+	ExpectThat(t, reporter.Closed(), ElementsAreUnordered("m1", "m2"))
+	ExpectThat(t, reporter.Hands(), Len(1))
+}
+
+func TestDrain_Deadline(t *testing.T) {
+	h := New(10)
+	tbl, err := h.Assign(Assignment{MatchID: "m1"})
+	AssertThat(t, err, Nil())
+	AssertThat(t, tbl.StartHand(), Nil())
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	AssertThat(t, h.Drain(ctx, "shutting down"), Nil())
+	ExpectEq(t, h.Len(), 0)
+	ExpectThat(t, tbl.StartHand(), ErrorIs(ErrClosed))
+}
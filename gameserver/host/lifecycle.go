@@ -0,0 +1,151 @@
+package host
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+// Stack is a player's chips at a table.
+type Stack struct {
+	PlayerID string
+	Chips    int64
+}
+
+// Pause stops play at the table and tells every connection why: no new
+// turns start, and the clock on the turn being played stops. Pausing a
+// paused table does nothing.
+func (t *Table) Pause(reason string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+	if t.pause != nil {
+		return nil
+	}
+	t.pause = pb.TablePaused_builder{Reason: proto.String(reason)}.Build()
+	if t.turn != nil {
+		if t.turn.timer != nil {
+			t.turn.timer.Stop()
+		}
+		t.turn.pausedAt = time.Now()
+	}
+	t.broadcastLocked(pb.TableEvent_builder{TablePaused: t.pause}.Build())
+	return nil
+}
+
+// Resume restarts play at a paused table, giving back whatever time was
+// left on the turn being played. Resuming a table that isn't paused does
+// nothing.
+func (t *Table) Resume() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+	if t.pause == nil {
+		return nil
+	}
+	t.pause = nil
+	t.broadcastLocked(pb.TableEvent_builder{TableResumed: &pb.TableResumed{}}.Build())
+	t.wakeUp()
+	if tn := t.turn; tn != nil {
+		now := time.Now()
+		tn.clock = shift(tn.clock, now.Sub(tn.pausedAt))
+		tn.pausedAt = time.Time{}
+		t.startTimerLocked(now)
+		t.broadcastLocked(pb.TableEvent_builder{TurnTimer: t.turnTimerLocked()}.Build())
+	}
+	return nil
+}
+
+// Paused reports whether play at the table is stopped.
+func (t *Table) Paused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pause != nil
+}
+
+// SetStack sets a seated player's chips.
+func (t *Table) SetStack(playerID string, chips int64) error {
+	if !slices.Contains(t.Players, playerID) {
+		return ErrNotSeated
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+	t.stacks[playerID] = chips
+	t.wakeUp()
+	t.logLocked(pb.TableLogEntry_builder{
+		StackSet: pb.Stack_builder{PlayerId: proto.String(playerID), Chips: proto.Int64(chips)}.Build(),
+	})
+	return nil
+}
+
+// Stacks returns each seated player's chips, in seat order.
+func (t *Table) Stacks() []Stack {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stacksLocked()
+}
+
+// CloseGracefully closes the table once every message already queued for it
+// has run, and returns the chips returned to each player. If ctx ends
+// first, the table is left open.
+func (t *Table) CloseGracefully(ctx context.Context, reason string) ([]Stack, error) {
+	type result struct {
+		returned []Stack
+		err      error
+	}
+	started, done := make(chan struct{}), make(chan result, 1)
+	closeTable := func() {
+		close(started)
+		if err := ctx.Err(); err != nil {
+			done <- result{err: err}
+			return
+		}
+		returned, err := t.close(ctx, reason, false)
+		done <- result{returned, err}
+	}
+	if err := t.Submit(closeTable); err != nil {
+		return nil, err
+	}
+	select {
+	case r := <-done:
+		return r.returned, r.err
+	case <-t.done:
+		select {
+		case <-started:
+			r := <-done
+			return r.returned, r.err
+		default:
+			// Closed by someone else first.
+			return nil, ErrClosed
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *Table) stacksLocked() []Stack {
+	stacks := make([]Stack, 0, len(t.Players))
+	for _, id := range t.Players {
+		stacks = append(stacks, Stack{PlayerID: id, Chips: t.stacks[id]})
+	}
+	return stacks
+}
+
+func stackProtos(stacks []Stack) []*pb.Stack {
+	out := make([]*pb.Stack, 0, len(stacks))
+	for _, s := range stacks {
+		out = append(out, pb.Stack_builder{PlayerId: proto.String(s.PlayerID), Chips: proto.Int64(s.Chips)}.Build())
+	}
+	return out
+}
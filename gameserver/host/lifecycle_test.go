@@ -0,0 +1,91 @@
+package host
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+func TestPause(t *testing.T) {
+	h := New(1, WithClock(table.ClockRules{Action: 50 * time.Millisecond}))
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, alice)
+
+	timedOut := make(chan struct{})
+	AssertThat(t, tbl.StartTurn("bob", func() { close(timedOut) }), Nil())
+	deadline := next(t, alice).GetTurnTimer().GetDeadline().AsTime()
+
+	AssertThat(t, tbl.Pause("maintenance"), Nil())
+	AssertThat(t, tbl.Pause("again"), Nil())
+	ExpectEq(t, next(t, alice).GetTablePaused().GetReason(), "maintenance")
+	ExpectEq(t, tbl.Paused(), true)
+	ExpectThat(t, tbl.StartTurn("alice", nil), ErrorIs(ErrPaused))
+
+	// The clock stops while the table is paused.
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-timedOut:
+		t.Fatal("timed out while paused")
+	default:
+	}
+	_, leave2, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	leave2()
+
+	AssertThat(t, tbl.Resume(), Nil())
+	ExpectEq(t, next(t, alice).HasTableResumed(), true)
+	resumed := next(t, alice).GetTurnTimer().GetDeadline().AsTime()
+	ExpectEq(t, resumed.Sub(deadline) >= 100*time.Millisecond, true)
+	select {
+	case <-timedOut:
+	case <-time.After(time.Second):
+		t.Fatal("no timeout after resuming")
+	}
+}
+
+func TestCloseGracefully(t *testing.T) {
+	h := New(1)
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}, Stacks: map[string]int64{"alice": 1000}})
+	AssertThat(t, err, Nil())
+	AssertThat(t, tbl.SetStack("bob", 250), Nil())
+	ExpectThat(t, tbl.SetStack("carol", 1), ErrorIs(ErrNotSeated))
+
+	// Work already queued finishes first.
+	ran := false
+	AssertThat(t, tbl.Submit(func() {
+		time.Sleep(10 * time.Millisecond)
+		ran = true
+		tbl.SetStack("alice", 1200)
+	}), Nil())
+	returned, err := tbl.CloseGracefully(ctx, "game over")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, ran, true)
+	ExpectThat(t, returned, ElementsAre(Stack{"alice", 1200}, Stack{"bob", 250}))
+
+	_, err = tbl.CloseGracefully(ctx, "game over")
+	ExpectThat(t, err, ErrorIs(ErrClosed))
+}
+
+func TestCloseGracefully_Canceled(t *testing.T) {
+	h := New(1)
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+	release := make(chan struct{})
+	AssertThat(t, tbl.Submit(func() { <-release }), Nil())
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = tbl.CloseGracefully(ctx, "game over")
+	ExpectThat(t, err, ErrorIs(context.DeadlineExceeded))
+	close(release)
+	wait(t, tbl)
+	ExpectEq(t, h.Len(), 1)
+}
@@ -0,0 +1,184 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+// Log keeps an append-only log of each table's changes, so that a server
+// that crashes can rebuild its tables when it restarts. Tables' logs are
+// removed once they close.
+type Log interface {
+	// Append adds an entry to the end of a table's log, starting the log if
+	// need be.
+	Append(ctx context.Context, tableID string, e *pb.TableLogEntry) error
+
+	// Read returns a table's entries in order.
+	Read(ctx context.Context, tableID string) ([]*pb.TableLogEntry, error)
+
+	// Tables returns the IDs of the tables with logs.
+	Tables(ctx context.Context) ([]string, error)
+
+	// Remove deletes a table's log.
+	Remove(ctx context.Context, tableID string) error
+}
+
+// Recover rebuilds the tables that were open when the server last stopped
+// from the host's log, and opens them again, even past the host's capacity.
+// It should be called before any tables are assigned.
+//
+// Tables come back as they were: their stacks, sit-outs, purchases, time
+// banks, whether they were paused, and the hand being played and players'
+// hole cards in it.
+// Their players must connect again, and a table that nobody connects to
+// closes once idle. The turn being played is not restored; the game
+// restarts it. A hand the table was dealing itself is called off, and its
+// players keep the chips they had before it. Tables whose logs can't be
+// replayed are skipped, and the errors returned.
+func (h *Host) Recover(ctx context.Context) ([]*Table, error) {
+	if h.log == nil {
+		return nil, nil
+	}
+	ids, err := h.log.Tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tables []*Table
+	var errs []error
+	for _, id := range ids {
+		t, err := h.recover(ctx, id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recovering table %s: %w", id, err))
+			continue
+		}
+		if t == nil {
+			continue
+		}
+		h.mu.Lock()
+		h.tables[t.ID] = t
+		h.mu.Unlock()
+		t.start()
+		tables = append(tables, t)
+	}
+	return tables, errors.Join(errs...)
+}
+
+// recover replays a table's log. It returns nil if the table closed, and
+// removes the log.
+func (h *Host) recover(ctx context.Context, id string) (*Table, error) {
+	entries, err := h.log.Read(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 || !entries[0].HasOpened() {
+		return nil, fmt.Errorf("%w: does not start with the table opening", ErrBadLog)
+	}
+	if last := entries[len(entries)-1]; last.GetEvent().HasTableClosed() {
+		return nil, h.log.Remove(ctx, id)
+	}
+	opened := entries[0].GetOpened()
+	if opened.GetTableId() != id {
+		return nil, fmt.Errorf("%w: opens table %q", ErrBadLog, opened.GetTableId())
+	}
+	t, err := newTable(h, Assignment{
+		MatchID:   opened.GetTableId(),
+		GameMode:  opened.GetGameMode(),
+		PlayerIDs: opened.GetPlayerIds(),
+		Bots:      int(opened.GetBots()),
+		Stacks:    opened.GetStacks(),
+		Config:    opened.GetConfig(),
+	}, entries[0].GetTime().AsTime())
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, e := range entries[1:] {
+		t.applyLocked(e)
+	}
+	return t, nil
+}
+
+// applyLocked replays a log entry, changing the table as it changed when
+// the entry was logged.
+func (t *Table) applyLocked(e *pb.TableLogEntry) {
+	switch e.WhichEntry() {
+	case pb.TableLogEntry_Event_case:
+		ev := e.GetEvent()
+		switch ev.WhichEvent() {
+		case pb.TableEvent_TablePaused_case:
+			t.pause = ev.GetTablePaused()
+		case pb.TableEvent_TableResumed_case:
+			t.pause = nil
+		case pb.TableEvent_PlayerSatOut_case:
+			t.out[ev.GetPlayerSatOut().GetPlayerId()] = true
+		case pb.TableEvent_PlayerReturned_case:
+			delete(t.out, ev.GetPlayerReturned().GetPlayerId())
+		case pb.TableEvent_HoleCards_case:
+			h := ev.GetHoleCards()
+			t.hole[h.GetPlayerId()] = h.GetCards()
+		}
+	case pb.TableLogEntry_StackSet_case:
+		s := e.GetStackSet()
+		t.stacks[s.GetPlayerId()] = s.GetChips()
+	case pb.TableLogEntry_HandStarted_case:
+		t.inHand = true
+		t.number++
+	case pb.TableLogEntry_HandEnded_case:
+		t.endHandLocked()
+	case pb.TableLogEntry_PurchaseStarted_case:
+		t.purchases = max(t.purchases, int(e.GetPurchaseStarted().GetNumber()))
+	case pb.TableLogEntry_ChipsBought_case:
+		b := e.GetChipsBought()
+		t.boughtLocked(b.GetPlayerId(), b.GetKind(), b.GetChips())
+	case pb.TableLogEntry_TurnEnded_case:
+		te := e.GetTurnEnded()
+		if seat := slices.Index(t.Players, te.GetPlayerId()); seat >= 0 {
+			t.clock.Spend(seat, te.GetTimeBankUsed().AsDuration())
+		}
+	}
+}
+
+// logOpened starts a new table's log. Unlike later entries, failing to log
+// it fails opening the table, as the table could not be recovered.
+func (t *Table) logOpened() error {
+	if t.host.log == nil {
+		return nil
+	}
+	e := pb.TableLogEntry_builder{
+		Time: timestamppb.New(t.opened),
+		Opened: pb.CreateTableRequest_builder{
+			TableId:   proto.String(t.ID),
+			GameMode:  proto.String(t.GameMode),
+			PlayerIds: t.Players,
+			Bots:      proto.Int32(int32(t.Bots)),
+			Stacks:    maps.Clone(t.stacks),
+			Config:    t.Config,
+		}.Build(),
+	}.Build()
+	if err := t.host.log.Append(context.Background(), t.ID, e); err != nil {
+		return fmt.Errorf("logging table %s: %w", t.ID, err)
+	}
+	return nil
+}
+
+// logLocked appends an entry to the table's log, passing any error to the
+// host's onError.
+func (t *Table) logLocked(b pb.TableLogEntry_builder) {
+	h := t.host
+	if h.log == nil {
+		return
+	}
+	b.Time = timestamppb.Now()
+	if err := h.log.Append(context.Background(), t.ID, b.Build()); err != nil && h.onError != nil {
+		h.onError(fmt.Errorf("logging table %s: %w", t.ID, err))
+	}
+}
@@ -0,0 +1,169 @@
+package host
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/handeval"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+// memLog keeps table logs in memory, as they would be on disk.
+type memLog struct {
+	mu      sync.Mutex
+	entries map[string][][]byte
+}
+
+func (l *memLog) Append(ctx context.Context, tableID string, e *pb.TableLogEntry) error {
+	b, err := proto.Marshal(e)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.entries == nil {
+		l.entries = map[string][][]byte{}
+	}
+	l.entries[tableID] = append(l.entries[tableID], b)
+	return nil
+}
+
+func (l *memLog) Read(ctx context.Context, tableID string) ([]*pb.TableLogEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var entries []*pb.TableLogEntry
+	for _, b := range l.entries[tableID] {
+		e := &pb.TableLogEntry{}
+		if err := proto.Unmarshal(b, e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (l *memLog) Tables(ctx context.Context) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var ids []string
+	for id := range l.entries {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (l *memLog) Remove(ctx context.Context, tableID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, tableID)
+	return nil
+}
+
+func TestRecover(t *testing.T) {
+	log := &memLog{}
+	w := &fakeWallet{balances: map[string]int64{"alice": 50000}}
+	clock := table.ClockRules{Action: time.Millisecond, TimeBank: time.Minute}
+	h := New(2, WithLog(log), WithWallet(w), WithClock(clock))
+	tbl, err := h.Assign(Assignment{
+		MatchID:   "m1",
+		GameMode:  "holdem",
+		PlayerIDs: []string{"alice", "bob"},
+		Bots:      1,
+		Config:    pb.TableConfig_builder{NoSpectators: proto.Bool(true)}.Build(),
+	})
+	AssertThat(t, err, Nil())
+	AssertThat(t, tbl.SetStack("alice", 5000), Nil())
+	AssertThat(t, tbl.SetStack("bob", 8000), Nil())
+	_, err = tbl.TopUp(ctx, "alice", 1000)
+	AssertThat(t, err, Nil())
+	AssertThat(t, tbl.SitOut("bob"), Nil())
+
+	AssertThat(t, tbl.StartTurn("alice", nil), Nil())
+	time.Sleep(20 * time.Millisecond)
+	AssertThat(t, tbl.EndTurn("alice"), Nil())
+	bank, err := tbl.TimeBank("alice")
+	AssertThat(t, err, Nil())
+
+	AssertThat(t, tbl.StartHand(), Nil())
+	hole, err := handeval.ParseCards("As Kd")
+	AssertThat(t, err, Nil())
+	AssertThat(t, tbl.Deal(map[string][]handeval.Card{"alice": hole}), Nil())
+	_, err = tbl.TopUp(ctx, "alice", 2000)
+	AssertThat(t, err, Nil())
+	AssertThat(t, tbl.Pause("maintenance"), Nil())
+
+	closed, err := h.Assign(Assignment{MatchID: "m2", PlayerIDs: []string{"carol"}})
+	AssertThat(t, err, Nil())
+	AssertThat(t, closed.Close(ctx, "done"), Nil())
+
+	// The server crashes, and a new one starts with the same log.
+	h = New(1, WithLog(log), WithWallet(w), WithClock(clock))
+	tables, err := h.Recover(ctx)
+	AssertThat(t, err, Nil())
+	AssertThat(t, tables, Len(1))
+	tbl = tables[0]
+	ExpectEq(t, tbl.ID, "m1")
+	ExpectEq(t, tbl.GameMode, "holdem")
+	ExpectThat(t, tbl.Players, ElementsAre("alice", "bob"))
+	ExpectEq(t, tbl.Bots, 1)
+	ExpectEq(t, tbl.Config.GetNoSpectators(), true)
+	ExpectEq(t, h.Len(), 1)
+	got, err := h.Table("m1")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got, tbl)
+
+	ExpectThat(t, tbl.Stacks(), ElementsAre(Stack{"alice", 6000}, Stack{"bob", 8000}))
+	ExpectThat(t, tbl.SittingOut(), ElementsAre("bob"))
+	ExpectEq(t, tbl.Paused(), true)
+	ExpectThat(t, bank, Not(Eq(time.Minute)))
+	recovered, err := tbl.TimeBank("alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, recovered, bank)
+	// The hand is still being played, so the chips bought during it are
+	// added once it ends.
+	ExpectThat(t, tbl.StartHand(), ErrorIs(ErrInHand))
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, next(t, alice).GetSnapshot().GetHoleCards(), ElementsAre("As", "Kd"))
+	leave()
+	tbl.EndHand()
+	ExpectThat(t, tbl.Stacks(), ElementsAre(Stack{"alice", 8000}, Stack{"bob", 8000}))
+
+	// Purchases carry on from where they were, so wallet refs aren't reused.
+	_, err = tbl.TopUp(ctx, "alice", 1000)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, w.refs, ElementsAre("m1/1", "m1/2", "m1/3"))
+
+	// The closed table's log is gone, and this one's goes once it closes.
+	ids, err := log.Tables(ctx)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, ids, ElementsAre("m1"))
+	AssertThat(t, tbl.Close(ctx, "done"), Nil())
+	ids, err = log.Tables(ctx)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, ids, Empty())
+}
+
+func TestRecover_BadLog(t *testing.T) {
+	log := &memLog{}
+	AssertThat(t, log.Append(ctx, "m1", pb.TableLogEntry_builder{
+		StackSet: pb.Stack_builder{PlayerId: proto.String("alice"), Chips: proto.Int64(100)}.Build(),
+	}.Build()), Nil())
+	h := New(1, WithLog(log))
+	h2 := New(1, WithLog(log))
+	_, err := h2.Assign(Assignment{MatchID: "m2", PlayerIDs: []string{"bob"}})
+	AssertThat(t, err, Nil())
+
+	tables, err := h.Recover(ctx)
+	ExpectThat(t, err, ErrorIs(ErrBadLog))
+	AssertThat(t, tables, Len(1))
+	ExpectEq(t, tables[0].ID, "m2")
+}
@@ -0,0 +1,143 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// mailboxSize is how many messages may wait for a table before Submit
+// starts turning them away.
+const mailboxSize = 256
+
+// Stats are a table's running totals, for monitoring.
+type Stats struct {
+	TableID  string
+	GameMode string
+	Opened   time.Time
+
+	// Open player connections, spectators watching, and messages waiting
+	// in the mailbox, now.
+	Connections int
+	Spectators  int
+	Queued      int
+
+	// Messages handled, turned away because the mailbox was full, and
+	// that panicked.
+	Handled  uint64
+	Rejected uint64
+	Panics   uint64
+
+	// Total time spent handling messages.
+	Busy time.Duration
+
+	// Events sent to connections, and connections dropped for falling
+	// behind.
+	Events  uint64
+	Dropped uint64
+}
+
+// counters are the Stats a table updates as it goes.
+type counters struct {
+	handled  atomic.Uint64
+	rejected atomic.Uint64
+	panics   atomic.Uint64
+	busy     atomic.Int64 // nanoseconds
+	events   atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+// Submit queues f to run on the table's goroutine, after every message
+// queued before it. Messages run one at a time, so state that only they
+// touch needs no locking. It fails with ErrBusy if too many messages are
+// waiting.
+//
+// If f panics, the table closes, but the server and its other tables carry
+// on.
+func (t *Table) Submit(f func()) error {
+	t.mu.Lock()
+	closed := t.closed
+	t.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	select {
+	case t.mailbox <- f:
+		return nil
+	default:
+		t.counters.rejected.Add(1)
+		return fmt.Errorf("%w: %d messages waiting", ErrBusy, mailboxSize)
+	}
+}
+
+// post queues f like Submit, but waits for room in the mailbox rather than
+// failing, for messages the table must not lose. It gives up if the table
+// closes.
+func (t *Table) post(f func()) {
+	select {
+	case t.mailbox <- f:
+	case <-t.done:
+	}
+}
+
+// run handles the table's messages until it closes.
+func (t *Table) run() {
+	for {
+		select {
+		case f := <-t.mailbox:
+			if !t.handle(f) {
+				return
+			}
+		case <-t.wake:
+			if !t.handle(t.play) {
+				return
+			}
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// handle runs one message, and reports whether the table survived it.
+func (t *Table) handle(f func()) (ok bool) {
+	start := time.Now()
+	defer func() {
+		t.counters.handled.Add(1)
+		t.counters.busy.Add(int64(time.Since(start)))
+		if v := recover(); v != nil {
+			t.counters.panics.Add(1)
+			t.host.crashed(t.ID, v, debug.Stack())
+			t.Close(context.Background(), "internal error")
+			ok = false
+		}
+	}()
+	f()
+	return true
+}
+
+// Stats returns the table's running totals.
+func (t *Table) Stats() Stats {
+	t.mu.Lock()
+	conns := 0
+	for _, n := range t.conns {
+		conns += n
+	}
+	spectators := t.spectatorsLocked()
+	t.mu.Unlock()
+	return Stats{
+		TableID:     t.ID,
+		GameMode:    t.GameMode,
+		Opened:      t.opened,
+		Connections: conns,
+		Spectators:  spectators,
+		Queued:      len(t.mailbox),
+		Handled:     t.counters.handled.Load(),
+		Rejected:    t.counters.rejected.Load(),
+		Panics:      t.counters.panics.Load(),
+		Busy:        time.Duration(t.counters.busy.Load()),
+		Events:      t.counters.events.Load(),
+		Dropped:     t.counters.dropped.Load(),
+	}
+}
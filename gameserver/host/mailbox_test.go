@@ -0,0 +1,131 @@
+package host
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+// wait submits an empty message and waits for it to run, so that every
+// message submitted before it has run.
+func wait(t *testing.T, table *Table) {
+	t.Helper()
+	done := make(chan struct{})
+	err := table.Submit(func() { close(done) })
+	for errors.Is(err, ErrBusy) {
+		time.Sleep(time.Millisecond)
+		err = table.Submit(func() { close(done) })
+	}
+	AssertThat(t, err, Nil())
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("table stuck")
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	h := New(1)
+	table, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+
+	var got []int
+	for i := range 10 {
+		AssertThat(t, table.Submit(func() { got = append(got, i) }), Nil())
+	}
+	wait(t, table)
+	ExpectThat(t, got, ElementsAre(0, 1, 2, 3, 4, 5, 6, 7, 8, 9))
+	ExpectEq(t, table.Stats().Handled, uint64(11))
+
+	AssertThat(t, table.Close(ctx, "game over"), Nil())
+	ExpectThat(t, table.Submit(func() {}), ErrorIs(ErrClosed))
+}
+
+func TestSubmit_Busy(t *testing.T) {
+	h := New(1)
+	table, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+
+	release := make(chan struct{})
+	AssertThat(t, table.Submit(func() { <-release }), Nil())
+	var busy error
+	for range mailboxSize + 2 {
+		if busy = table.Submit(func() {}); busy != nil {
+			break
+		}
+	}
+	ExpectThat(t, busy, ErrorIs(ErrBusy))
+	ExpectEq(t, table.Stats().Rejected, uint64(1))
+	close(release)
+	wait(t, table)
+}
+
+func TestSubmit_PanicIsolated(t *testing.T) {
+	reporter := &fakeReporter{}
+	var mu sync.Mutex
+	var panicked []string
+	h := New(2, WithReporter(reporter, nil), WithPanicHandler(func(tableID string, v any, stack []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		panicked = append(panicked, fmt.Sprintf("%s: %v", tableID, v))
+	}))
+	bad, err := h.Assign(Assignment{MatchID: "bad", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+	good, err := h.Assign(Assignment{MatchID: "good", PlayerIDs: []string{"bob"}})
+	AssertThat(t, err, Nil())
+	events, leave, err := bad.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, events)
+
+	AssertThat(t, bad.Submit(func() { panic("boom") }), Nil())
+	ExpectEq(t, next(t, events).GetTableClosed().GetReason(), "internal error")
+	ExpectEq(t, h.Crashes(), uint64(1))
+	mu.Lock()
+	ExpectThat(t, panicked, ElementsAre("bad: boom"))
+	mu.Unlock()
+	ExpectThat(t, reporter.Closed(), ElementsAre("bad"))
+	_, err = h.Table("bad")
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+
+	// The other table carries on.
+	wait(t, good)
+	ExpectEq(t, h.Len(), 1)
+}
+
+func TestManyTables(t *testing.T) {
+	const tables, messages = 300, 100
+	h := New(tables)
+	counts := make([]int, tables)
+	var wg sync.WaitGroup
+	for i := range tables {
+		table, err := h.Assign(Assignment{MatchID: fmt.Sprintf("m%03d", i), PlayerIDs: []string{"alice"}})
+		AssertThat(t, err, Nil())
+		wg.Add(1)
+		go func() {
+			submit := func(f func()) {
+				for table.Submit(f) != nil {
+					time.Sleep(time.Millisecond)
+				}
+			}
+			for range messages {
+				// Tables don't lock state that only their messages touch.
+				submit(func() { counts[i]++ })
+			}
+			submit(wg.Done)
+		}()
+	}
+	wg.Wait()
+	ExpectThat(t, slices.Compact(counts), ElementsAre(messages))
+
+	stats := h.Stats()
+	AssertThat(t, stats, Len(tables))
+	ExpectEq(t, stats[0].TableID, "m000")
+	ExpectEq(t, stats[0].Handled, uint64(messages+1))
+	AssertThat(t, h.CloseAll(ctx, "done"), Nil())
+}
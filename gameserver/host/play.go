@@ -0,0 +1,666 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/handeval"
+	"github.com/jfmatt/snapfold/lib/handhistory"
+	"github.com/jfmatt/snapfold/lib/shuffle"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+// botBigBlinds is the chips, in big blinds, bots sit down with, and are
+// given again whenever they go broke.
+const botBigBlinds = 100
+
+// streets are the board cards dealt after each betting round but the last:
+// the flop, turn and river.
+var streets = []int{3, 1, 1}
+
+// limitNames are betting structures as hand histories write them.
+var limitNames = map[table.Limit]string{
+	table.NoLimit:    "No Limit",
+	table.PotLimit:   "Pot Limit",
+	table.FixedLimit: "Limit",
+}
+
+// rules are how a table deals and bets its hands.
+type rules struct {
+	variant table.Variant
+	blinds  table.BlindRules
+	betting table.BettingRules
+	rake    table.RakeRules
+	runout  table.RunoutRules
+}
+
+// rulesFor returns the rules for dealing hands at a table with cfg, or an
+// error if cfg lacks what hands need, such as its blinds.
+func rulesFor(cfg *pb.TableConfig) (*rules, error) {
+	r := &rules{runout: table.RunoutRulesFor(cfg)}
+	var err error
+	if r.variant, err = table.VariantFor(cfg); err != nil {
+		return nil, err
+	}
+	if r.blinds, err = table.BlindRulesFor(cfg); err != nil {
+		return nil, err
+	}
+	if r.betting, err = table.RulesFor(cfg, nil); err != nil {
+		return nil, err
+	}
+	if r.rake, err = table.RakeRulesFor(cfg); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rules) bigBlind() int64 {
+	return r.blinds.Blinds[len(r.blinds.Blinds)-1]
+}
+
+// hand is the hand being dealt at a table. Only the table's goroutine
+// touches it.
+type hand struct {
+	deal  table.Deal
+	ids   map[int]string // seat -> player or bot
+	hole  map[int][]handeval.Card
+	deck  []handeval.Card
+	board []handeval.Card
+
+	// Seats dealt in, in seat order, and the chips each started with.
+	order  []int
+	stacks map[int]int64
+
+	// The players as they stood when the last betting round ended, and the
+	// chips bet before this one.
+	players []table.Player
+	pot     int64
+
+	// Chips each seat put in: dead posts now, and each round's bets as it
+	// ends.
+	put map[int]int64
+
+	round   int
+	betting *table.Betting
+	sent    int // betting events sent to connections
+
+	// Whether the clock is running on the turn of the player to act.
+	waiting bool
+
+	history handhistory.Hand
+}
+
+// Act takes an action for a seated player on their turn in the hand being
+// dealt. For table.Bet and table.Raise, to is their total bet for the round
+// afterward; it is ignored otherwise. It fails with ErrNotTurn if it is not
+// their turn, including if they ran out of time, and with
+// table.ErrInvalidAction if they may not take the action, in which case
+// their turn goes on.
+func (t *Table) Act(ctx context.Context, playerID string, a table.Action, to int64) error {
+	if !slices.Contains(t.Players, playerID) {
+		return ErrNotSeated
+	}
+	done := make(chan error, 1)
+	if err := t.Submit(func() { done <- t.act(playerID, a, to) }); err != nil {
+		return err
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-t.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Table) act(playerID string, a table.Action, to int64) error {
+	h := t.hand
+	if h == nil {
+		return ErrNoHand
+	}
+	opts, ok := h.betting.Options()
+	if !ok || !h.waiting || h.ids[opts.Seat] != playerID {
+		return ErrNotTurn
+	}
+	t.mu.Lock()
+	paused := t.pause != nil
+	timing := t.turn != nil && t.turn.playerID == playerID
+	t.mu.Unlock()
+	if paused {
+		return ErrPaused
+	}
+	if !timing {
+		// They ran out of time, and the table is about to act for them.
+		return ErrNotTurn
+	}
+	if err := h.betting.Act(opts.Seat, a, to); err != nil {
+		return err
+	}
+	t.EndTurn(playerID)
+	h.waiting = false
+	t.sendBetting(h)
+	t.play()
+	return nil
+}
+
+// wakeUp asks the table's goroutine to play, once it has handled the
+// messages before it, such as when a player connects or returns, or the
+// table resumes. It never blocks, so it may be called holding t.mu.
+func (t *Table) wakeUp() {
+	if t.rules == nil {
+		return
+	}
+	select {
+	case t.wake <- struct{}{}:
+	default:
+		// Already asked.
+	}
+}
+
+// play moves the table's game on as far as it can without waiting for a
+// player: dealing a hand if none is being dealt, playing for bots and for
+// players sitting out, and starting the clock on the next player's turn.
+// It runs on the table's goroutine.
+//
+// Hands are dealt to the players who have chips and aren't sitting out,
+// and the table's bots, so long as there are two of them and one is a
+// connected player. No hands are dealt, and nobody acts, while the table is
+// paused. Tables whose config can't be dealt, such as one without blinds,
+// deal nothing; their hands are played by calling StartHand and EndHand.
+func (t *Table) play() {
+	if t.hand == nil && !t.dealHand() {
+		return
+	}
+	if err := t.satOutOnTurn(t.hand); err != nil {
+		t.callOff(t.hand, err)
+		return
+	}
+	for h := t.hand; t.hand == h && !h.waiting && !t.Paused(); {
+		if err := t.step(h); err != nil {
+			t.callOff(h, err)
+			return
+		}
+	}
+}
+
+// dealHand starts the next hand, if one can be dealt, and reports whether
+// it did.
+func (t *Table) dealHand() bool {
+	r := t.rules
+	t.mu.Lock()
+	if t.closed || t.pause != nil {
+		t.mu.Unlock()
+		return false
+	}
+	recovered := t.inHand
+	if t.seats == nil {
+		for i := range t.Players {
+			t.seats = append(t.seats, table.Seat{Seat: i + 1})
+		}
+		for range t.Bots {
+			t.seats = append(t.seats, table.Seat{Seat: len(t.seats) + 1, Stack: botBigBlinds * r.bigBlind()})
+		}
+	}
+	connected := false
+	for i, id := range t.Players {
+		s := &t.seats[i]
+		s.Stack = t.stacks[id]
+		s.SittingOut = t.out[id] || s.Stack == 0
+		connected = connected || (!s.SittingOut && t.conns[id] > 0)
+	}
+	t.mu.Unlock()
+
+	if recovered {
+		// The server crashed in the middle of a hand, which can't be dealt
+		// on. Nobody's chips changed, as they only do once a hand ends.
+		t.EndHand()
+	}
+	if !connected {
+		return false
+	}
+	deal, seats, err := table.NextHand(r.blinds, t.rot, t.seats)
+	if errors.Is(err, table.ErrNotEnoughPlayers) {
+		return false
+	}
+	if err != nil {
+		t.reportError(fmt.Errorf("dealing at table %s: %w", t.ID, err))
+		return false
+	}
+	if err := t.StartHand(); err != nil {
+		return false
+	}
+	before := t.seats
+	t.seats, t.rot = seats, deal.Rotation
+	t.number++
+
+	h := &hand{
+		deal:    deal,
+		ids:     t.seatIDs(),
+		stacks:  map[int]int64{},
+		players: deal.Players,
+		pot:     deal.Pot,
+		put:     map[int]int64{},
+		history: handhistory.Hand{
+			Number:       t.number,
+			Table:        t.ID,
+			MaxSeats:     len(t.seats),
+			Game:         r.variant.Name() + " " + limitNames[r.betting.Limit],
+			Blinds:       r.blinds.Blinds,
+			PlayedAt:     time.Now(),
+			Button:       deal.Rotation.Button,
+			UncalledSeat: -1,
+			Events:       slices.Clone(deal.Events),
+		},
+	}
+	t.hand = h
+	for _, s := range before {
+		if !slices.ContainsFunc(deal.Players, func(p table.Player) bool { return p.Seat == s.Seat }) {
+			continue
+		}
+		h.order = append(h.order, s.Seat)
+		h.stacks[s.Seat] = s.Stack
+		h.history.Seats = append(h.history.Seats, handhistory.Seat{Seat: s.Seat, Player: h.ids[s.Seat], Stack: s.Stack})
+	}
+	for _, p := range deal.Posts {
+		if p.Dead {
+			h.put[p.Seat] += p.Amount
+		}
+	}
+	if err := t.dealCards(h); err != nil {
+		t.callOff(h, err)
+		return false
+	}
+	return true
+}
+
+// dealCards tells every connection the hand has started, deals the hole
+// cards, and starts the first betting round.
+func (t *Table) dealCards(h *hand) error {
+	shuffled, err := t.host.shuffler.Shuffle(shuffle.Deck(handeval.Two))
+	if err != nil {
+		return err
+	}
+	if h.hole, h.deck, err = table.DealHoleCards(t.rules.variant, shuffled.Deck, h.order); err != nil {
+		return err
+	}
+	h.history.Hole = h.hole
+	t.broadcastEvents(h, h.deal.Events)
+	hole := map[string][]handeval.Card{}
+	for seat, cards := range h.hole {
+		if id := h.ids[seat]; slices.Contains(t.Players, id) {
+			hole[id] = cards
+		}
+	}
+	if err := t.Deal(hole); err != nil {
+		return err
+	}
+	return t.startRound(h)
+}
+
+// startRound starts the hand's next betting round.
+func (t *Table) startRound(h *hand) error {
+	rules := t.rules.betting
+	pos := table.Position{Seats: h.order, Button: h.deal.Rotation.Button, LastBlind: -1}
+	ordered := h.players
+	var first int
+	var err error
+	if h.round == 0 {
+		pos.LastBlind = h.deal.LastBlind
+		rules.MinBet = max(rules.MinBet, h.deal.Straddle)
+		if first, err = table.FirstToAct(pb.Phase_BettingRound_FOLLOW_BLINDS, pos); err != nil {
+			return err
+		}
+		if h.deal.ButtonStraddled {
+			ordered, first = table.ButtonStraddleOrder(h.players, first, h.deal.Rotation.Button), 0
+		}
+	} else if first, err = table.FirstToAct(pb.Phase_BettingRound_LEFT_OF_DEALER, pos); err != nil {
+		return err
+	}
+	h.betting, err = table.NewBetting(rules, ordered, first, h.pot)
+	h.sent = 0
+	return err
+}
+
+// step plays the next turn of the hand, or ends the betting round if
+// nobody is left to act. Bots, and players sitting out, act at once; for
+// anyone else, the clock starts, and the hand waits for them.
+func (t *Table) step(h *hand) error {
+	b := h.betting
+	opts, ok := b.Options()
+	if !ok {
+		return t.endRound(h)
+	}
+	id := h.ids[opts.Seat]
+	if slices.Contains(t.BotIDs(), id) {
+		if err := t.playBot(h, opts); err != nil {
+			return err
+		}
+		t.sendBetting(h)
+		return nil
+	}
+	t.mu.Lock()
+	out := t.out[id]
+	if !out {
+		msg := table.OptionsMessage(opts, h.ids)
+		t.opts = msg.GetTurnOptions()
+		t.sendPrivateLocked(id, msg)
+	}
+	t.mu.Unlock()
+	if out {
+		if err := b.TimeOut(opts.Seat); err != nil {
+			return err
+		}
+		t.sendBetting(h)
+		return nil
+	}
+	switch err := t.StartTurn(id, func() { t.timedOut(h, opts.Seat) }); {
+	case errors.Is(err, ErrPaused):
+		// Resuming wakes the table up to start it.
+		return nil
+	case err != nil:
+		return err
+	}
+	h.waiting = true
+	return nil
+}
+
+// satOutOnTurn acts for the player whose clock is running if they have sat
+// out since it started, as step does for players already sitting out.
+func (t *Table) satOutOnTurn(h *hand) error {
+	opts, ok := h.betting.Options()
+	if !ok || !h.waiting {
+		return nil
+	}
+	id := h.ids[opts.Seat]
+	t.mu.Lock()
+	out := t.out[id]
+	t.mu.Unlock()
+	if !out || t.EndTurn(id) != nil {
+		// They're playing, or ran out of time, and the table is about to
+		// act for them.
+		return nil
+	}
+	h.waiting = false
+	if err := h.betting.TimeOut(opts.Seat); err != nil {
+		return err
+	}
+	t.sendBetting(h)
+	return nil
+}
+
+// timedOut acts for a player who ran out of time.
+func (t *Table) timedOut(h *hand, seat int) {
+	if t.hand != h || !h.waiting {
+		return
+	}
+	if opts, ok := h.betting.Options(); !ok || opts.Seat != seat {
+		return
+	}
+	h.waiting = false
+	if err := h.betting.TimeOut(seat); err != nil {
+		t.callOff(h, err)
+		return
+	}
+	t.sendBetting(h)
+	t.play()
+}
+
+// playBot acts for the bot whose turn it is, as the host's bot strategy
+// decides.
+func (t *Table) playBot(h *hand, opts table.Options) error {
+	b := h.betting
+	turn := BotTurn{
+		BotID:    h.ids[opts.Seat],
+		Variant:  t.rules.variant,
+		Hole:     h.hole[opts.Seat],
+		Board:    h.board,
+		Options:  opts,
+		Pot:      b.Pot(),
+		BigBlind: t.rules.bigBlind(),
+	}
+	for _, p := range b.Players() {
+		turn.Bet = max(turn.Bet, p.Bet)
+		if p.Seat == opts.Seat {
+			turn.Stack = p.Stack
+		}
+		if !p.Folded {
+			turn.Players++
+		}
+	}
+	a, err := t.PlayBot(context.Background(), turn)
+	if err != nil {
+		return err
+	}
+	if err := b.Act(opts.Seat, a.Action, a.To); err != nil {
+		return b.Act(opts.Seat, table.TimeoutAction(opts), 0)
+	}
+	return nil
+}
+
+// endRound collects the bets of the betting round just over, and deals the
+// next street, or ends the hand if no more betting can happen.
+func (t *Table) endRound(h *hand) error {
+	b := h.betting
+	h.history.Events = append(h.history.Events, b.Events()...)
+	h.pot = b.Pot()
+	h.players = b.Players()
+	slices.SortFunc(h.players, func(a, b table.Player) int { return a.Seat - b.Seat })
+	for i := range h.players {
+		h.put[h.players[i].Seat] += h.players[i].Bet
+		h.players[i].Bet = 0
+	}
+	pots, _, _ := table.Pots(h.contributions())
+	t.broadcast(table.PotsMessage(pots, h.ids))
+
+	if live(h.players) < 2 || h.round == len(streets) || canBet(h.players) < 2 {
+		return t.showdown(h)
+	}
+	n := streets[h.round]
+	ev := table.Event{Type: table.EventBoard, Seat: -1, Cards: slices.Clone(h.deck[:n])}
+	h.board = append(h.board, h.deck[:n]...)
+	h.deck = h.deck[n:]
+	h.history.Events = append(h.history.Events, ev)
+	t.broadcastEvents(h, []table.Event{ev})
+	h.round++
+	return t.startRound(h)
+}
+
+// showdown deals the rest of the board if the hand needs it, awards the
+// pots, and ends the hand.
+func (t *Table) showdown(h *hand) error {
+	r := t.rules
+	boards := [][]handeval.Card{h.board}
+	if live(h.players) > 1 && len(h.board) < table.BoardSize {
+		// Nobody can bet any more: deal the rest of the board. Players
+		// always agree to run it twice where the table allows it.
+		agreed := map[int]bool{}
+		for _, p := range h.players {
+			agreed[p.Seat] = true
+		}
+		runs := r.runout.Runs(h.players, agreed, table.BoardSize-len(h.board))
+		ro, _, err := table.DealRunout(h.deck, h.board, table.BoardSize, runs)
+		if err != nil {
+			return err
+		}
+		boards = ro.Boards
+		h.history.Events = append(h.history.Events, ro.Events...)
+		t.broadcastEvents(h, ro.Events)
+	}
+
+	pots, uncalledSeat, uncalled := table.Pots(h.contributions())
+	won := map[int]int64{}
+	if uncalledSeat >= 0 {
+		won[uncalledSeat] += uncalled
+	}
+	h.history.UncalledSeat, h.history.Uncalled = uncalledSeat, uncalled
+	pots, h.history.Rake = r.rake.Take(pots, len(boards[0]) >= streets[0])
+
+	showing := map[int][]handeval.Card{}
+	for _, p := range h.players {
+		if !p.Folded {
+			showing[p.Seat] = h.hole[p.Seat]
+		}
+	}
+	var winners [][][]int
+	if len(showing) == 1 {
+		// Everyone else folded: the last player takes every pot unseen.
+		var only []int
+		for seat := range showing {
+			only = []int{seat}
+		}
+		run := make([][]int, len(pots))
+		for i := range run {
+			run[i] = only
+		}
+		winners = append(winners, run)
+	} else {
+		made := map[int]handeval.Hand{}
+		if len(boards) == 1 {
+			for seat, cards := range showing {
+				if best, err := r.variant.Best(cards, boards[0]); err == nil {
+					made[seat] = best
+				}
+			}
+		}
+		h.history.Shown = showing
+		t.broadcast(table.ShowdownMessage(showing, made, h.ids))
+		for _, board := range boards {
+			run, err := table.Showdown(r.variant, pots, showing, board)
+			if err != nil {
+				return err
+			}
+			winners = append(winners, run)
+		}
+	}
+	events, err := table.AwardRuns(pots, winners, h.deal.Rotation.Button)
+	if err != nil {
+		return err
+	}
+	h.history.Events = append(h.history.Events, events...)
+	t.broadcastEvents(h, events)
+	for seat, amount := range table.Winnings(events) {
+		won[seat] += amount
+	}
+
+	t.hand = nil
+	for i, s := range t.seats {
+		chips, ok := h.stacks[s.Seat]
+		if !ok {
+			continue
+		}
+		chips += won[s.Seat] - h.put[s.Seat]
+		if i < len(t.Players) {
+			t.SetStack(t.Players[i], chips)
+		} else if chips == 0 {
+			chips = botBigBlinds * r.bigBlind()
+		}
+		t.seats[i].Stack = chips
+	}
+	if err := t.RecordHand(h.history); err != nil && !errors.Is(err, ErrClosed) {
+		t.reportError(err)
+	}
+	t.EndHand()
+	t.wakeUp()
+	return nil
+}
+
+// callOff abandons a hand that can't be dealt on, leaving everyone's chips
+// as they were before it.
+func (t *Table) callOff(h *hand, err error) {
+	if t.hand == h {
+		t.hand = nil
+	}
+	t.mu.Lock()
+	closed := t.closed
+	t.endTurnLocked(time.Now())
+	t.mu.Unlock()
+	if closed {
+		return
+	}
+	t.reportError(fmt.Errorf("calling off hand %d at table %s: %w", h.history.Number, t.ID, err))
+	t.EndHand()
+	t.wakeUp()
+}
+
+// sendBetting sends every connection what has happened in the betting
+// round since it was last sent.
+func (t *Table) sendBetting(h *hand) {
+	events := h.betting.Events()
+	t.broadcastEvents(h, events[h.sent:])
+	h.sent = len(events)
+}
+
+// broadcastEvents sends every connection the messages for events of the
+// hand that have them.
+func (t *Table) broadcastEvents(h *hand, events []table.Event) {
+	var msgs []*pb.TableEvent
+	for _, e := range events {
+		if msg, ok := table.EventMessage(e, h.ids); ok {
+			msgs = append(msgs, msg)
+		}
+	}
+	t.broadcast(msgs...)
+}
+
+func (t *Table) broadcast(evs ...*pb.TableEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	for _, ev := range evs {
+		t.broadcastLocked(ev)
+	}
+}
+
+// seatIDs returns who sits in each of the table's seats: its players, in
+// order from seat 1, and then its bots.
+func (t *Table) seatIDs() map[int]string {
+	ids := map[int]string{}
+	for i, id := range slices.Concat(t.Players, t.BotIDs()) {
+		ids[i+1] = id
+	}
+	return ids
+}
+
+func (t *Table) reportError(err error) {
+	if t.host.onError != nil {
+		t.host.onError(err)
+	}
+}
+
+// contributions returns what each player dealt in has put in so far.
+func (h *hand) contributions() []table.Contribution {
+	var contribs []table.Contribution
+	for _, p := range h.players {
+		contribs = append(contribs, table.Contribution{Seat: p.Seat, Amount: h.put[p.Seat], Folded: p.Folded})
+	}
+	return contribs
+}
+
+// live counts the players who haven't folded.
+func live(players []table.Player) int {
+	n := 0
+	for _, p := range players {
+		if !p.Folded {
+			n++
+		}
+	}
+	return n
+}
+
+// canBet counts the players who haven't folded and have chips behind.
+func canBet(players []table.Player) int {
+	n := 0
+	for _, p := range players {
+		if !p.Folded && !p.AllIn() {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,216 @@
+package host
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/lib/shuffle"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+// caller calls when it is bet into, and checks otherwise, as PlayBot falls
+// back to when it can't call.
+var caller = fixedBot{action: BotAction{Action: table.Call}}
+
+// headsUp assigns a heads-up table, at blinds of 50/100, to players with
+// stacks.
+func headsUp(t *testing.T, h *Host, players []string, bots int, stacks map[string]int64) *Table {
+	t.Helper()
+	cfg, err := gamedefio.LoadPreset("heads-up")
+	AssertThat(t, err, Nil())
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: players, Bots: bots, Stacks: stacks, Config: cfg})
+	AssertThat(t, err, Nil())
+	return tbl
+}
+
+// until returns the events up to and including the first that matches.
+func until(t *testing.T, events <-chan *pb.TableEvent, match func(*pb.TableEvent) bool) []*pb.TableEvent {
+	t.Helper()
+	var got []*pb.TableEvent
+	for {
+		ev := next(t, events)
+		got = append(got, ev)
+		if match(ev) {
+			return got
+		}
+	}
+}
+
+func turnOf(playerID string) func(*pb.TableEvent) bool {
+	return func(ev *pb.TableEvent) bool { return ev.GetTurnOptions().GetPlayerId() == playerID }
+}
+
+func TestPlay(t *testing.T) {
+	reporter := &fakeReporter{}
+	h := New(1, WithBots(caller), WithShuffler(shuffle.NewSeeded(shuffle.Seed{1})), WithReporter(reporter, nil))
+	tbl := headsUp(t, h, []string{"alice"}, 1, map[string]int64{"alice": 10000})
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, alice)
+
+	// Hands are dealt once a player connects, and the bot plays its turns
+	// until it is alice's.
+	events := until(t, alice, turnOf("alice"))
+	ExpectEq(t, events[0].HasHandDealt(), true)
+	var blind int64
+	var hole []string
+	for _, ev := range events {
+		if a := ev.GetPlayerActed(); a.GetPlayerId() == "alice" && a.GetKind() == pb.PlayerActed_BLIND {
+			blind = a.GetAmount()
+		}
+		if ev.HasHoleCards() {
+			hole = ev.GetHoleCards().GetCards()
+		}
+	}
+	ExpectThat(t, []int64{50, 100}, Contains(blind))
+	ExpectThat(t, hole, Len(2))
+	AssertThat(t, tbl.Act(ctx, "alice", table.Fold, 0), Nil())
+
+	events = until(t, alice, func(ev *pb.TableEvent) bool { return ev.HasPlayerActed() })
+	ExpectEq(t, events[len(events)-1].GetPlayerActed().GetKind(), pb.PlayerActed_FOLD)
+	events = until(t, alice, func(ev *pb.TableEvent) bool { return ev.HasPotAwarded() })
+	ExpectEq(t, events[len(events)-1].GetPotAwarded().GetPlayerId(), "bot-1")
+
+	// The next hand is dealt, and waits for alice.
+	until(t, alice, turnOf("alice"))
+	ExpectThat(t, tbl.Stacks(), ElementsAre(Stack{"alice", 10000 - blind}))
+
+	// Draining waits for the hand, until its deadline.
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	AssertThat(t, h.Drain(ctx, "shutting down"), Nil())
+	hands := reporter.Hands()
+	AssertThat(t, hands, Len(1))
+	history := hands[0].Histories["alice"]
+	ExpectThat(t, history, StartsWith("PokerStars Hand #1: Hold'em No Limit (50/100) - "))
+	ExpectThat(t, history, HasSubstr("Dealt to alice ["))
+	ExpectThat(t, history, HasSubstr("alice: folds"))
+	ExpectThat(t, history, HasSubstr("Seat 2: bot-1 (10000 in chips) is a bot"))
+}
+
+func TestAct(t *testing.T) {
+	h := New(1)
+	tbl := headsUp(t, h, []string{"alice", "bob"}, 0, map[string]int64{"alice": 10000, "bob": 10000})
+	ExpectThat(t, tbl.Act(ctx, "alice", table.Check, 0), ErrorIs(ErrNoHand))
+	ExpectThat(t, tbl.Act(ctx, "carol", table.Check, 0), ErrorIs(ErrNotSeated))
+
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	bob, leaveBob, err := tbl.Connect("bob")
+	AssertThat(t, err, Nil())
+	defer leaveBob()
+	next(t, alice)
+	next(t, bob)
+
+	// Whoever is on the button posts the small blind and acts first.
+	first, second, events := "alice", "bob", alice
+	var opts *pb.TurnOptions
+	for opts == nil {
+		select {
+		case ev := <-alice:
+			opts = ev.GetTurnOptions()
+		case ev := <-bob:
+			if opts = ev.GetTurnOptions(); opts != nil {
+				first, second, events = "bob", "alice", bob
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no turn")
+		}
+	}
+	ExpectEq(t, opts.GetPlayerId(), first)
+	ExpectEq(t, opts.GetCall(), int64(50))
+	ExpectEq(t, opts.GetMinTo(), int64(200))
+	ExpectEq(t, opts.GetMaxTo(), int64(10000))
+
+	ExpectThat(t, tbl.Act(ctx, second, table.Check, 0), ErrorIs(ErrNotTurn))
+	ExpectThat(t, tbl.Act(ctx, first, table.Raise, 150), ErrorIs(table.ErrInvalidAction))
+	AssertThat(t, tbl.Act(ctx, first, table.Raise, 300), Nil())
+	acted := until(t, events, func(ev *pb.TableEvent) bool { return ev.HasPlayerActed() })
+	ExpectEq(t, acted[len(acted)-1].GetPlayerActed().GetTotal(), int64(300))
+	ExpectThat(t, tbl.Act(ctx, first, table.Fold, 0), ErrorIs(ErrNotTurn))
+}
+
+func TestPlay_Reconnect(t *testing.T) {
+	h := New(1, WithBots(caller))
+	tbl := headsUp(t, h, []string{"alice"}, 1, map[string]int64{"alice": 10000})
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	next(t, alice)
+	events := until(t, alice, turnOf("alice"))
+	opts := events[len(events)-1].GetTurnOptions()
+	leave()
+
+	// Players who reconnect on their turn are sent their options again.
+	alice, leave, err = tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	snapshot := next(t, alice).GetSnapshot()
+	ExpectThat(t, snapshot.GetHoleCards(), Len(2))
+	ExpectEq(t, snapshot.GetTurnOptions().GetCall(), opts.GetCall())
+	ExpectEq(t, snapshot.GetTurnOptions().GetMaxTo(), opts.GetMaxTo())
+
+	// Spectators aren't sent them.
+	watcher, stop, err := tbl.Watch()
+	AssertThat(t, err, Nil())
+	defer stop()
+	ExpectEq(t, next(t, watcher).GetSnapshot().HasTurnOptions(), false)
+}
+
+func TestPlay_TimeOut(t *testing.T) {
+	h := New(1, WithBots(caller), WithClock(table.ClockRules{Action: 10 * time.Millisecond, TimeBank: 10 * time.Millisecond}))
+	tbl := headsUp(t, h, []string{"alice"}, 1, map[string]int64{"alice": 10000})
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, alice)
+
+	// Players who run out of time check if they can, and fold otherwise.
+	until(t, alice, func(ev *pb.TableEvent) bool { return ev.GetTurnTimedOut().GetPlayerId() == "alice" })
+	acted := next(t, alice).GetPlayerActed()
+	ExpectEq(t, acted.GetPlayerId(), "alice")
+	ExpectThat(t, []pb.PlayerActed_Kind{pb.PlayerActed_CHECK, pb.PlayerActed_FOLD}, Contains(acted.GetKind()))
+}
+
+func TestPlay_NoChips(t *testing.T) {
+	h := New(1, WithBots(caller), WithPlayMoney())
+	tbl := headsUp(t, h, []string{"alice"}, 1, nil)
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, alice)
+
+	// Players aren't dealt in until they have chips.
+	wait(t, tbl)
+	ExpectThat(t, tbl.Act(ctx, "alice", table.Fold, 0), ErrorIs(ErrNoHand))
+	_, err = tbl.TopUp(ctx, "alice", 5000)
+	AssertThat(t, err, Nil())
+	until(t, alice, turnOf("alice"))
+
+	// Nor while they sit out, though the hand being dealt goes on for them.
+	AssertThat(t, tbl.SitOut("alice"), Nil())
+	until(t, alice, func(ev *pb.TableEvent) bool { return ev.HasPotAwarded() })
+	wait(t, tbl)
+	ExpectThat(t, tbl.Act(ctx, "alice", table.Fold, 0), ErrorIs(ErrNoHand))
+}
+
+func TestPlay_Paused(t *testing.T) {
+	h := New(1, WithBots(caller))
+	tbl := headsUp(t, h, []string{"alice"}, 1, map[string]int64{"alice": 10000})
+	AssertThat(t, tbl.Pause("maintenance"), Nil())
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, alice)
+
+	wait(t, tbl)
+	ExpectThat(t, tbl.Act(ctx, "alice", table.Fold, 0), ErrorIs(ErrNoHand))
+	AssertThat(t, tbl.Resume(), Nil())
+	until(t, alice, turnOf("alice"))
+}
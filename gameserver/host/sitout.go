@@ -0,0 +1,66 @@
+package host
+
+import (
+	"slices"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+// SitOut marks a seated player as sitting out, and tells every connection.
+// They keep their seat and chips, but are dealt out of hands until they
+// return. Sitting out again does nothing.
+func (t *Table) SitOut(playerID string) error {
+	return t.setSittingOut(playerID, true)
+}
+
+// Return brings a player who sat out back into play, and tells every
+// connection. Returning while not sitting out does nothing.
+func (t *Table) Return(playerID string) error {
+	return t.setSittingOut(playerID, false)
+}
+
+// SittingOut returns the seated players who are sitting out, in seat order.
+func (t *Table) SittingOut() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sittingOutLocked()
+}
+
+func (t *Table) setSittingOut(playerID string, out bool) error {
+	if !slices.Contains(t.Players, playerID) {
+		return ErrNotSeated
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+	if t.out[playerID] == out {
+		return nil
+	}
+	if out {
+		t.out[playerID] = true
+		t.broadcastLocked(pb.TableEvent_builder{
+			PlayerSatOut: pb.PlayerSatOut_builder{PlayerId: proto.String(playerID)}.Build(),
+		}.Build())
+	} else {
+		delete(t.out, playerID)
+		t.broadcastLocked(pb.TableEvent_builder{
+			PlayerReturned: pb.PlayerReturned_builder{PlayerId: proto.String(playerID)}.Build(),
+		}.Build())
+	}
+	t.wakeUp()
+	return nil
+}
+
+func (t *Table) sittingOutLocked() []string {
+	var ids []string
+	for _, id := range t.Players {
+		if t.out[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,34 @@
+package host
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestSitOut(t *testing.T) {
+	h := New(1)
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, alice)
+
+	ExpectThat(t, tbl.SitOut("carol"), ErrorIs(ErrNotSeated))
+	AssertThat(t, tbl.SitOut("bob"), Nil())
+	AssertThat(t, tbl.SitOut("bob"), Nil())
+	ExpectEq(t, next(t, alice).GetPlayerSatOut().GetPlayerId(), "bob")
+	ExpectThat(t, tbl.SittingOut(), ElementsAre("bob"))
+
+	// Players who reconnect see who is sitting out.
+	bob, leaveBob, err := tbl.Connect("bob")
+	AssertThat(t, err, Nil())
+	defer leaveBob()
+	ExpectThat(t, next(t, bob).GetSnapshot().GetSittingOutPlayerIds(), ElementsAre("bob"))
+	next(t, alice)
+
+	AssertThat(t, tbl.Return("bob"), Nil())
+	ExpectEq(t, next(t, alice).GetPlayerReturned().GetPlayerId(), "bob")
+	ExpectThat(t, tbl.SittingOut(), Empty())
+}
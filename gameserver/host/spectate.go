@@ -0,0 +1,52 @@
+package host
+
+import (
+	"sync"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+// Watch subscribes a spectator to the table's events, starting with a
+// snapshot, as Connect does for seated players. Spectators see everything
+// but players' hole cards, and don't keep an idle table open. It fails
+// with ErrNoSpectators if the table's game mode doesn't allow them. Call
+// leave when the connection ends.
+func (t *Table) Watch() (events <-chan *pb.TableEvent, leave func(), err error) {
+	if t.Config.GetNoSpectators() {
+		return nil, nil, ErrNoSpectators
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, nil, ErrClosed
+	}
+	ch := make(chan *pb.TableEvent, subscriberBuffer)
+	ch <- t.snapshotLocked("")
+	t.subs[ch] = ""
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.unsubscribeLocked(ch)
+		})
+	}, nil
+}
+
+// Spectators returns how many spectators are watching now.
+func (t *Table) Spectators() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.spectatorsLocked()
+}
+
+func (t *Table) spectatorsLocked() int {
+	n := 0
+	for _, id := range t.subs {
+		if id == "" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,100 @@
+package host
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/handeval"
+)
+
+func TestWatch(t *testing.T) {
+	h := New(1)
+	tbl, err := h.Assign(Assignment{MatchID: "m1", GameMode: "holdem", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	alice, leaveAlice, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leaveAlice()
+	next(t, alice)
+
+	spectator, leave, err := tbl.Watch()
+	AssertThat(t, err, Nil())
+	snap := next(t, spectator).GetSnapshot()
+	ExpectEq(t, snap.GetTableId(), "m1")
+	ExpectThat(t, snap.GetConnectedPlayerIds(), ElementsAre("alice"))
+	ExpectEq(t, tbl.Spectators(), 1)
+	ExpectEq(t, tbl.Stats().Spectators, 1)
+	// Spectators aren't players connecting.
+	ExpectThat(t, tbl.Connected(), ElementsAre("alice"))
+
+	hole, err := handeval.ParseCards("As Kd 7c 7h")
+	AssertThat(t, err, Nil())
+	AssertThat(t, tbl.StartHand(), Nil())
+	AssertThat(t, tbl.Deal(map[string][]handeval.Card{"alice": hole[:2], "bob": hole[2:]}), Nil())
+	AssertThat(t, tbl.SitOut("bob"), Nil())
+
+	// Alice sees her own cards, and nobody else's; the spectator sees none.
+	ev := next(t, alice).GetHoleCards()
+	ExpectEq(t, ev.GetPlayerId(), "alice")
+	ExpectThat(t, ev.GetCards(), ElementsAre("As", "Kd"))
+	ExpectEq(t, next(t, alice).GetPlayerSatOut().GetPlayerId(), "bob")
+	ExpectEq(t, next(t, spectator).GetPlayerSatOut().GetPlayerId(), "bob")
+
+	// Reconnecting players get their cards back; spectators don't.
+	bob, leaveBob, err := tbl.Connect("bob")
+	AssertThat(t, err, Nil())
+	defer leaveBob()
+	ExpectThat(t, next(t, bob).GetSnapshot().GetHoleCards(), ElementsAre("7c", "7h"))
+	ExpectEq(t, next(t, spectator).GetPlayerConnected().GetPlayerId(), "bob")
+	late, leaveLate, err := tbl.Watch()
+	AssertThat(t, err, Nil())
+	defer leaveLate()
+	ExpectThat(t, next(t, late).GetSnapshot().GetHoleCards(), Empty())
+
+	// The cards are forgotten once the hand ends.
+	tbl.EndHand()
+	bob2, leaveBob2, err := tbl.Connect("bob")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, next(t, bob2).GetSnapshot().GetHoleCards(), Empty())
+	leaveBob2()
+
+	leave()
+	leave() // Leaving twice is harmless.
+	ExpectEq(t, tbl.Spectators(), 1)
+	_, ok := <-spectator
+	ExpectEq(t, ok, false)
+}
+
+func TestWatch_NoSpectators(t *testing.T) {
+	h := New(1)
+	tbl, err := h.Assign(Assignment{
+		MatchID:   "m1",
+		PlayerIDs: []string{"alice"},
+		Config:    pb.TableConfig_builder{NoSpectators: proto.Bool(true)}.Build(),
+	})
+	AssertThat(t, err, Nil())
+	_, _, err = tbl.Watch()
+	ExpectThat(t, err, ErrorIs(ErrNoSpectators))
+
+	AssertThat(t, tbl.Close(ctx, "done"), Nil())
+	tbl, err = h.Assign(Assignment{MatchID: "m2", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+	AssertThat(t, tbl.Close(ctx, "done"), Nil())
+	_, _, err = tbl.Watch()
+	ExpectThat(t, err, ErrorIs(ErrClosed))
+}
+
+func TestDeal(t *testing.T) {
+	h := New(1)
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+	hole, err := handeval.ParseCards("As Kd")
+	AssertThat(t, err, Nil())
+
+	ExpectThat(t, tbl.Deal(map[string][]handeval.Card{"alice": hole}), ErrorIs(ErrNoHand))
+	AssertThat(t, tbl.StartHand(), Nil())
+	ExpectThat(t, tbl.Deal(map[string][]handeval.Card{"carol": hole}), ErrorIs(ErrNotSeated))
+	ExpectThat(t, tbl.Deal(map[string][]handeval.Card{"alice": hole}), Nil())
+}
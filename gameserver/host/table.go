@@ -0,0 +1,322 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+// subscriberBuffer is how many events may wait for a slow connection before
+// it is dropped, so that one slow player cannot hold up the table.
+const subscriberBuffer = 64
+
+// Table is one live table. Each table runs on its own goroutine, which
+// handles the messages sent to it with Submit. It is safe for concurrent
+// use.
+type Table struct {
+	ID       string
+	GameMode string
+
+	// The configuration of the table's game mode, with the feature flags for
+	// the host's environment applied. Nil for the defaults.
+	Config *pb.TableConfig
+
+	// Players seated at the table, in seat order.
+	Players []string
+	Bots    int
+
+	host     *Host
+	opened   time.Time
+	mailbox  chan func()
+	wake     chan struct{} // asks the table's goroutine to play
+	done     chan struct{} // closed when the table closes
+	counters counters
+
+	// How the table deals its hands, or nil if its config can't be dealt.
+	rules *rules
+
+	// Only the table's goroutine touches these, once it has started: its
+	// seats as they were after the last hand, where the button and blinds
+	// were, the number of hands started, and the hand being dealt.
+	seats  []table.Seat
+	rot    table.Rotation
+	number uint64
+	hand   *hand
+
+	mu     sync.Mutex
+	closed bool
+	conns  map[string]int                 // player ID -> open connections
+	subs   map[chan *pb.TableEvent]string // -> player it's for; empty for spectators
+	idle   *time.Timer
+	clock  *table.Clock
+	turn   *turn
+	opts   *pb.TurnOptions // last sent to a player on their turn
+	pause  *pb.TablePaused // nil unless paused
+	stacks map[string]int64
+	out    map[string]bool // players sitting out
+	hole   map[string][]string
+	mutes  map[string]map[string]bool // player -> players they muted
+
+	inHand    bool
+	buying    map[string]bool       // players paying for chips
+	pending   map[string][]purchase // chips bought during the hand
+	purchases int
+	rebuys    map[string]int
+	addOns    map[string]bool
+}
+
+// newTable returns a table for an assignment, opened at the given time.
+// Call start to start it.
+func newTable(h *Host, a Assignment, opened time.Time) (*Table, error) {
+	clock, err := table.NewClock(h.clock)
+	if err != nil {
+		return nil, err
+	}
+	t := &Table{
+		ID:       a.MatchID,
+		GameMode: a.GameMode,
+		Config:   gamedefio.Resolve(a.Config, h.env),
+		Players:  slices.Clone(a.PlayerIDs),
+		Bots:     a.Bots,
+		host:     h,
+		opened:   opened,
+		mailbox:  make(chan func(), mailboxSize),
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		conns:    map[string]int{},
+		subs:     map[chan *pb.TableEvent]string{},
+		clock:    clock,
+		stacks:   maps.Clone(a.Stacks),
+		out:      map[string]bool{},
+		hole:     map[string][]string{},
+		mutes:    map[string]map[string]bool{},
+		buying:   map[string]bool{},
+		pending:  map[string][]purchase{},
+		rebuys:   map[string]int{},
+		addOns:   map[string]bool{},
+		rot:      table.NoRotation,
+	}
+	if r, err := rulesFor(t.Config); err == nil {
+		t.rules = r
+	}
+	if t.stacks == nil {
+		t.stacks = map[string]int64{}
+	}
+	return t, nil
+}
+
+// start starts the table's goroutine, and its idle timer, as no players are
+// connected yet.
+func (t *Table) start() {
+	// The timer may fire before AfterFunc returns.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.idle = time.AfterFunc(t.host.idle, t.closeIdle)
+	t.wakeUp()
+	go t.run()
+}
+
+// Connect subscribes a seated player to the table's events, starting with a
+// snapshot of the table. The channel is closed once the table closes, or if
+// the connection falls too far behind. Call leave when the connection ends.
+func (t *Table) Connect(playerID string) (events <-chan *pb.TableEvent, leave func(), err error) {
+	if !slices.Contains(t.Players, playerID) {
+		return nil, nil, ErrNotSeated
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, nil, ErrClosed
+	}
+	t.conns[playerID]++
+	if t.conns[playerID] == 1 {
+		t.idle.Stop()
+		t.wakeUp()
+		t.broadcastLocked(pb.TableEvent_builder{
+			PlayerConnected: pb.PlayerConnected_builder{PlayerId: proto.String(playerID)}.Build(),
+		}.Build())
+	}
+	ch := make(chan *pb.TableEvent, subscriberBuffer)
+	ch <- t.snapshotLocked(playerID)
+	t.subs[ch] = playerID
+
+	var once sync.Once
+	return ch, func() { once.Do(func() { t.leave(playerID, ch) }) }, nil
+}
+
+// Connected returns the seated players who are connected now, in seat
+// order.
+func (t *Table) Connected() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.connectedLocked()
+}
+
+// Close ends the table at once, sending a TableClosed event to every
+// connection and reporting it closed. Messages still queued for the table
+// are dropped. Players' chips are returned to them; see CloseGracefully.
+func (t *Table) Close(ctx context.Context, reason string) error {
+	_, err := t.close(ctx, reason, false)
+	return err
+}
+
+// close closes the table, unless idle is set and a player has connected
+// since it went idle. It returns the chips returned to players.
+func (t *Table) close(ctx context.Context, reason string, idle bool) ([]Stack, error) {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if idle && len(t.conns) > 0 {
+		t.mu.Unlock()
+		return nil, nil
+	}
+	t.closed = true
+	close(t.done)
+	t.idle.Stop()
+	t.endTurnLocked(time.Now())
+	for id, bought := range t.pending {
+		for _, b := range bought {
+			t.stacks[id] += b.chips
+		}
+	}
+	clear(t.pending)
+	returned := t.stacksLocked()
+	t.broadcastLocked(pb.TableEvent_builder{
+		TableClosed: pb.TableClosed_builder{
+			TableId:  proto.String(t.ID),
+			Reason:   proto.String(reason),
+			Returned: stackProtos(returned),
+		}.Build(),
+	}.Build())
+	for ch := range t.subs {
+		close(ch)
+	}
+	clear(t.subs)
+	t.mu.Unlock()
+
+	t.host.remove(t.ID)
+	if l := t.host.log; l != nil {
+		if err := l.Remove(context.WithoutCancel(ctx), t.ID); err != nil && t.host.onError != nil {
+			t.host.onError(fmt.Errorf("removing table %s log: %w", t.ID, err))
+		}
+	}
+	t.host.report(func(r Reporter) error { return r.TableClosed(ctx, t.ID) })
+	return returned, nil
+}
+
+func (t *Table) leave(playerID string, ch chan *pb.TableEvent) {
+	t.mu.Lock()
+	t.unsubscribeLocked(ch)
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.conns[playerID]--
+	gone := t.conns[playerID] == 0
+	if gone {
+		delete(t.conns, playerID)
+		t.broadcastLocked(pb.TableEvent_builder{
+			PlayerDisconnected: pb.PlayerDisconnected_builder{PlayerId: proto.String(playerID)}.Build(),
+		}.Build())
+		if len(t.conns) == 0 {
+			t.idle.Reset(t.host.idle)
+		}
+	}
+	t.mu.Unlock()
+
+	if gone {
+		t.host.report(func(r Reporter) error { return r.Disconnected(context.Background(), t.ID, playerID) })
+	}
+}
+
+func (t *Table) closeIdle() {
+	t.close(context.Background(), "no players connected", true)
+}
+
+func (t *Table) unsubscribeLocked(ch chan *pb.TableEvent) {
+	if _, ok := t.subs[ch]; ok {
+		delete(t.subs, ch)
+		close(ch)
+	}
+}
+
+// broadcastLocked sends an event to every connection, players' and
+// spectators', dropping those that have fallen too far behind.
+func (t *Table) broadcastLocked(ev *pb.TableEvent) {
+	switch ev.WhichEvent() {
+	case pb.TableEvent_TablePaused_case, pb.TableEvent_TableResumed_case, pb.TableEvent_PlayerSatOut_case,
+		pb.TableEvent_PlayerReturned_case, pb.TableEvent_TableClosed_case:
+		t.logLocked(pb.TableLogEntry_builder{Event: ev})
+	}
+	for ch := range t.subs {
+		t.sendLocked(ch, ev)
+	}
+}
+
+// sendPrivateLocked sends an event only to a player's own connections.
+func (t *Table) sendPrivateLocked(playerID string, ev *pb.TableEvent) {
+	for ch, id := range t.subs {
+		if id == playerID {
+			t.sendLocked(ch, ev)
+		}
+	}
+}
+
+func (t *Table) sendLocked(ch chan *pb.TableEvent, ev *pb.TableEvent) {
+	select {
+	case ch <- ev:
+		t.counters.events.Add(1)
+	default:
+		delete(t.subs, ch)
+		close(ch)
+		t.counters.dropped.Add(1)
+	}
+}
+
+// snapshotLocked returns the table as a player sees it, or a spectator if
+// playerID is empty.
+func (t *Table) snapshotLocked(playerID string) *pb.TableEvent {
+	var hole []string
+	var opts *pb.TurnOptions
+	if playerID != "" {
+		hole = t.hole[playerID]
+		if t.turn != nil && t.turn.playerID == playerID && t.opts.GetPlayerId() == playerID {
+			opts = t.opts
+		}
+	}
+	return pb.TableEvent_builder{
+		Snapshot: pb.TableSnapshot_builder{
+			TableId:             proto.String(t.ID),
+			GameMode:            proto.String(t.GameMode),
+			PlayerIds:           t.Players,
+			Bots:                proto.Int32(int32(t.Bots)),
+			ConnectedPlayerIds:  t.connectedLocked(),
+			TurnTimer:           t.turnTimerLocked(),
+			Paused:              t.pause,
+			SittingOutPlayerIds: t.sittingOutLocked(),
+			HoleCards:           hole,
+			TurnOptions:         opts,
+		}.Build(),
+	}.Build()
+}
+
+func (t *Table) connectedLocked() []string {
+	var ids []string
+	for _, id := range t.Players {
+		if t.conns[id] > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
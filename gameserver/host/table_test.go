@@ -0,0 +1,115 @@
+package host
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+func next(t *testing.T, events <-chan *pb.TableEvent) *pb.TableEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		AssertEq(t, ok, true)
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+		return nil
+	}
+}
+
+func TestConnect(t *testing.T) {
+	reporter := &fakeReporter{}
+	h := New(1, WithReporter(reporter, nil))
+	table, err := h.Assign(Assignment{MatchID: "m1", GameMode: "holdem", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+
+	_, _, err = table.Connect("carol")
+	ExpectThat(t, err, ErrorIs(ErrNotSeated))
+
+	alice, leaveAlice, err := table.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leaveAlice()
+	ev := next(t, alice)
+	AssertEq(t, ev.HasSnapshot(), true)
+	ExpectEq(t, ev.GetSnapshot().GetTableId(), "m1")
+	ExpectThat(t, ev.GetSnapshot().GetPlayerIds(), ElementsAre("alice", "bob"))
+	ExpectThat(t, ev.GetSnapshot().GetConnectedPlayerIds(), ElementsAre("alice"))
+
+	bob, leaveBob, err := table.Connect("bob")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, next(t, alice).GetPlayerConnected().GetPlayerId(), "bob")
+	ExpectThat(t, next(t, bob).GetSnapshot().GetConnectedPlayerIds(), ElementsAre("alice", "bob"))
+
+	// A second connection from the same player is not news.
+	_, leaveBob2, err := table.Connect("bob")
+	AssertThat(t, err, Nil())
+	leaveBob()
+	leaveBob() // Leaving twice is harmless.
+	ExpectThat(t, table.Connected(), ElementsAre("alice", "bob"))
+	leaveBob2()
+	ExpectEq(t, next(t, alice).GetPlayerDisconnected().GetPlayerId(), "bob")
+	ExpectThat(t, table.Connected(), ElementsAre("alice"))
+	ExpectThat(t, reporter.disconnected, ElementsAre("m1/bob"))
+
+	AssertThat(t, table.Close(ctx, "game over"), Nil())
+	ev = next(t, alice)
+	ExpectEq(t, ev.GetTableClosed().GetReason(), "game over")
+	_, ok := <-alice
+	ExpectEq(t, ok, false)
+	_, _, err = table.Connect("alice")
+	ExpectThat(t, err, ErrorIs(ErrClosed))
+}
+
+func TestConnect_SlowConnectionDropped(t *testing.T) {
+	h := New(1)
+	table, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	alice, leave, err := table.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+
+	for range subscriberBuffer {
+		_, leaveBob, err := table.Connect("bob")
+		AssertThat(t, err, Nil())
+		leaveBob()
+	}
+	n := 0
+	for range alice {
+		n++
+	}
+	ExpectEq(t, n, subscriberBuffer)
+}
+
+func TestIdleTimeout(t *testing.T) {
+	reporter := &fakeReporter{}
+	h := New(2, WithIdleTimeout(10*time.Millisecond), WithReporter(reporter, nil))
+	_, err := h.Assign(Assignment{MatchID: "empty", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+	busy, err := h.Assign(Assignment{MatchID: "busy", PlayerIDs: []string{"bob"}})
+	AssertThat(t, err, Nil())
+	_, leave, err := busy.Connect("bob")
+	AssertThat(t, err, Nil())
+
+	deadline := time.Now().Add(time.Second)
+	for h.Len() > 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	ExpectThat(t, reporter.Closed(), ElementsAre("empty"))
+	_, err = h.Table("busy")
+	ExpectThat(t, err, Nil())
+
+	// Tables go idle again once everyone leaves.
+	leave()
+	for h.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	ExpectThat(t, reporter.Closed(), ElementsAre("empty", "busy"))
+}
+
+func TestIdleTimeout_Unset(t *testing.T) {
+	ExpectEq(t, New(1, WithIdleTimeout(0)).idle, DefaultIdleTimeout)
+}
@@ -0,0 +1,173 @@
+package host
+
+import (
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+// turn is the turn being played at a table.
+type turn struct {
+	playerID string
+	clock    table.Turn
+
+	// Fires when the player runs out of time, and queues timeout. Nil if
+	// turns have no time limit.
+	timer   *time.Timer
+	timeout func()
+
+	// When the table was paused, stopping the clock. Zero unless paused.
+	pausedAt time.Time
+}
+
+// StartTurn starts the clock on a seated player's turn, and tells every
+// connection. If the player has not acted by the deadline, every connection
+// is told that they timed out, the turn ends, and timeout is queued to run
+// on the table's goroutine; it should act for them, as table.TimeoutAction
+// does.
+//
+// Starting a turn ends the one before it, as EndTurn does. Turns may not
+// start while the table is paused.
+func (t *Table) StartTurn(playerID string, timeout func()) error {
+	seat := slices.Index(t.Players, playerID)
+	if seat < 0 {
+		return ErrNotSeated
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+	if t.pause != nil {
+		return ErrPaused
+	}
+	now := time.Now()
+	t.endTurnLocked(now)
+	tn := &turn{playerID: playerID, clock: t.clock.Start(seat, now), timeout: timeout}
+	t.turn = tn
+	t.startTimerLocked(now)
+	t.broadcastLocked(pb.TableEvent_builder{TurnTimer: t.turnTimerLocked()}.Build())
+	return nil
+}
+
+// EndTurn stops the clock once a player acts, charging any time they took
+// past their action time to their time bank. It fails with ErrNotTurn if it
+// is not their turn, including if they have run out of time.
+func (t *Table) EndTurn(playerID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.turn == nil || t.turn.playerID != playerID {
+		return ErrNotTurn
+	}
+	now := time.Now()
+	t.host.actions.Observe(now.Sub(t.turn.clock.Started).Seconds(), t.GameMode)
+	t.endTurnLocked(now)
+	return nil
+}
+
+// TimeBank returns what is left in a seated player's time bank.
+func (t *Table) TimeBank(playerID string) (time.Duration, error) {
+	seat := slices.Index(t.Players, playerID)
+	if seat < 0 {
+		return 0, ErrNotSeated
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.clock.TimeBank(seat), nil
+}
+
+func (t *Table) endTurnLocked(now time.Time) {
+	if t.turn == nil {
+		return
+	}
+	if t.turn.timer != nil {
+		t.turn.timer.Stop()
+	}
+	if !t.turn.pausedAt.IsZero() {
+		// The clock stopped when the table paused.
+		now = t.turn.pausedAt
+	}
+	t.stopClockLocked(t.turn, now)
+	t.turn = nil
+}
+
+// stopClockLocked stops the clock on a turn, logging what the player drew
+// from their time bank.
+func (t *Table) stopClockLocked(tn *turn, now time.Time) {
+	seat := tn.clock.Seat
+	bank := t.clock.TimeBank(seat)
+	t.clock.Stop(tn.clock, now)
+	if used := bank - t.clock.TimeBank(seat); used > 0 {
+		t.logLocked(pb.TableLogEntry_builder{
+			TurnEnded: pb.TableLogEntry_TurnEnded_builder{
+				PlayerId:     proto.String(tn.playerID),
+				TimeBankUsed: durationpb.New(used),
+			}.Build(),
+		})
+	}
+}
+
+// startTimerLocked starts the timer on the current turn, if it has a
+// deadline.
+func (t *Table) startTimerLocked(now time.Time) {
+	tn := t.turn
+	if tn.clock.Deadline.IsZero() {
+		return
+	}
+	tn.timer = time.AfterFunc(tn.clock.Deadline.Sub(now), func() { t.timeOut(tn) })
+}
+
+func (t *Table) timeOut(tn *turn) {
+	t.mu.Lock()
+	if t.turn != tn || !tn.pausedAt.IsZero() || time.Now().Before(tn.clock.Deadline) {
+		// The player acted, the table closed or paused, or the deadline
+		// moved, as the timer fired.
+		t.mu.Unlock()
+		return
+	}
+	t.stopClockLocked(tn, tn.clock.Deadline)
+	t.turn = nil
+	t.broadcastLocked(pb.TableEvent_builder{
+		TurnTimedOut: pb.TurnTimedOut_builder{PlayerId: proto.String(tn.playerID)}.Build(),
+	}.Build())
+	t.mu.Unlock()
+
+	if tn.timeout != nil {
+		t.post(tn.timeout)
+	}
+}
+
+func (t *Table) turnTimerLocked() *pb.TurnTimer {
+	if t.turn == nil {
+		return nil
+	}
+	c := t.turn.clock
+	if !t.turn.pausedAt.IsZero() {
+		c = shift(c, time.Since(t.turn.pausedAt))
+	}
+	b := pb.TurnTimer_builder{
+		PlayerId: proto.String(t.turn.playerID),
+		TimeBank: durationpb.New(c.TimeBank),
+	}
+	if !c.Deadline.IsZero() {
+		b.BankStarts = timestamppb.New(c.BankStarts)
+		b.Deadline = timestamppb.New(c.Deadline)
+	}
+	return b.Build()
+}
+
+// shift moves a turn's times later by d.
+func shift(c table.Turn, d time.Duration) table.Turn {
+	c.Started = c.Started.Add(d)
+	if !c.Deadline.IsZero() {
+		c.BankStarts = c.BankStarts.Add(d)
+		c.Deadline = c.Deadline.Add(d)
+	}
+	return c
+}
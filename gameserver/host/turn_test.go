@@ -0,0 +1,87 @@
+package host
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+func TestTurn(t *testing.T) {
+	h := New(1, WithClock(table.ClockRules{Action: time.Minute, TimeBank: time.Minute}))
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, alice)
+
+	ExpectThat(t, tbl.StartTurn("carol", nil), ErrorIs(ErrNotSeated))
+	AssertThat(t, tbl.StartTurn("bob", nil), Nil())
+	timer := next(t, alice).GetTurnTimer()
+	ExpectEq(t, timer.GetPlayerId(), "bob")
+	ExpectEq(t, timer.GetTimeBank().AsDuration(), time.Minute)
+	ExpectEq(t, timer.GetDeadline().AsTime().Sub(timer.GetBankStarts().AsTime()), time.Minute)
+
+	// Players who reconnect see the clock.
+	bob, leaveBob, err := tbl.Connect("bob")
+	AssertThat(t, err, Nil())
+	defer leaveBob()
+	ExpectEq(t, next(t, bob).GetSnapshot().GetTurnTimer().GetPlayerId(), "bob")
+
+	ExpectThat(t, tbl.EndTurn("alice"), ErrorIs(ErrNotTurn))
+	AssertThat(t, tbl.EndTurn("bob"), Nil())
+	ExpectThat(t, tbl.EndTurn("bob"), ErrorIs(ErrNotTurn))
+	bank, err := tbl.TimeBank("bob")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, bank, time.Minute)
+}
+
+func TestTurn_TimeOut(t *testing.T) {
+	h := New(1, WithClock(table.ClockRules{Action: 10 * time.Millisecond, TimeBank: 20 * time.Millisecond}))
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, alice)
+
+	timedOut := make(chan struct{})
+	AssertThat(t, tbl.StartTurn("bob", func() { close(timedOut) }), Nil())
+	ExpectEq(t, next(t, alice).GetTurnTimer().GetPlayerId(), "bob")
+	ExpectEq(t, next(t, alice).GetTurnTimedOut().GetPlayerId(), "bob")
+	select {
+	case <-timedOut:
+	case <-time.After(time.Second):
+		t.Fatal("timeout not called")
+	}
+
+	// The turn is over, and so is bob's time bank.
+	ExpectThat(t, tbl.EndTurn("bob"), ErrorIs(ErrNotTurn))
+	bank, err := tbl.TimeBank("bob")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, bank, time.Duration(0))
+}
+
+func TestTurn_NoLimit(t *testing.T) {
+	h := New(1)
+	tbl, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+	alice, leave, err := tbl.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	next(t, alice)
+
+	AssertThat(t, tbl.StartTurn("alice", nil), Nil())
+	timer := next(t, alice).GetTurnTimer()
+	ExpectEq(t, timer.HasDeadline(), false)
+	AssertThat(t, tbl.Close(ctx, "game over"), Nil())
+}
+
+func TestAssign_InvalidClock(t *testing.T) {
+	h := New(1, WithClock(table.ClockRules{Action: -time.Second}))
+	_, err := h.Assign(Assignment{MatchID: "m1", PlayerIDs: []string{"alice"}})
+	ExpectThat(t, err, ErrorIs(table.ErrInvalidConfig))
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/jfmatt/flagr"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/jfmatt/snapfold/gameserver/api"
+	"github.com/jfmatt/snapfold/gameserver/bot"
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/gameserver/matchmaker"
+	"github.com/jfmatt/snapfold/gameserver/rpc"
+	"github.com/jfmatt/snapfold/gameserver/tablelog"
+	"github.com/jfmatt/snapfold/lib/config"
+	"github.com/jfmatt/snapfold/lib/health"
+	"github.com/jfmatt/snapfold/lib/log"
+	"github.com/jfmatt/snapfold/lib/metrics"
+	"github.com/jfmatt/snapfold/lib/table"
+	"github.com/jfmatt/snapfold/lib/tracing"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func main() {
+	c := &cobra.Command{
+		Use:   "gameserver",
+		Short: "snapfold game server",
+	}
+
+	c.AddCommand(ServerCommand())
+	c.AddCommand(TablesCommand())
+	config.Register(c, "GAMESERVER")
+
+	if err := c.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+type ServeArgs struct {
+	Host string `flag:"host,default=0.0.0.0,help=Address to bind the HTTP server to"`
+	Port int    `flag:"port,default=7000,help=Port for the HTTP server"`
+
+	HTTP3   bool   `flag:"http3,help=Experimental: also serve the HTTP API over HTTP/3 (QUIC) on UDP at --port, so that players may stream table events over it; needs --tls-cert and --tls-key"`
+	TLSCert string `flag:"tls-cert,help=Certificate file for HTTP/3"`
+	TLSKey  string `flag:"tls-key,help=Private key file for HTTP/3"`
+
+	ServerID string `flag:"server-id,help=Identifies this server to the matchmaker across restarts; defaults to the hostname"`
+	Address  string `flag:"address,help=host:port that players connect to; defaults to the hostname and port"`
+	Region   string `flag:"region,help=Region the server runs in"`
+	Capacity int    `flag:"capacity,default=500,help=Most tables to run at once"`
+
+	Environment string `flag:"environment,help=Environment the server runs in, such as staging, which chooses the feature flags that apply to table configs"`
+
+	Matchmaker        string        `flag:"matchmaker,required,help=Address of the matchmaker's gRPC server, as host:port"`
+	MatchmakerURL     string        `flag:"matchmaker-url,help=Base URL of the matchmaker's HTTP API, for reporting disconnects and closed tables; nothing is reported if unset"`
+	APIKey            string        `flag:"api-key,required,help=Matchmaker API key with the fleet and tables scopes, and configs to fetch game modes from its config registry, or its internal token"`
+	HeartbeatInterval time.Duration `flag:"heartbeat-interval,default=5s,help=How often to renew the server's registration and pick up new matches; must be shorter than the matchmaker's heartbeat timeout"`
+	SessionKey        string        `flag:"session-key,required,help=Secret the matchmaker signs access tokens with, so that players' tokens are accepted here"`
+	AccessTTL         time.Duration `flag:"access-ttl,default=15m,help=How long the matchmaker's access tokens last, as its session.access-ttl, and so how long to keep rejecting those of revoked sessions"`
+	RevocationSync    time.Duration `flag:"revocation-sync,default=5s,help=How often to ask the matchmaker for revoked sessions, whose tokens are then rejected and streams ended; needs matchmaker-url"`
+	IdleTimeout       time.Duration `flag:"idle-timeout,default=5m,help=How long a table stays open with no players connected"`
+	ActionTime        time.Duration `flag:"action-time,default=20s,help=Time players have to act on each turn; 0 for no limit"`
+	TimeBank          time.Duration `flag:"time-bank,default=60s,help=Extra time each player may draw on at a table once their action time runs out"`
+	MinBuyin          int64         `flag:"min-buyin,help=Fewest chips players may top up to; 0 for no minimum"`
+	MaxBuyin          int64         `flag:"max-buyin,help=Most chips players may top up to; 0 for no maximum"`
+	PlayMoney         bool          `flag:"play-money,help=Give players the chips they buy rather than charging their wallets at the matchmaker, which needs matchmaker-url"`
+	Bots              string        `flag:"bots,default=basic,help=How bots play: basic, a simple rule-based strategy, or passive, which checks or folds"`
+	LogDir            string        `flag:"log-dir,help=Directory to log each table's changes in, so that open tables are rebuilt if the server crashes and restarts; tables are not logged if unset"`
+
+	GRPCPort   int    `flag:"grpc-port,default=7001,help=Port for the gRPC table and table event services"`
+	AdminToken string `flag:"admin-token,help=Bearer token the matchmaker and admin tooling must present to manage tables over gRPC and read profiles under /debug/; neither is served if unset"`
+
+	MetricsPort int `flag:"metrics-port,default=9090,help=Port to serve Prometheus metrics on at /metrics, for the server and each of its tables, and the /healthz and /readyz checks, as well as the main port; 0 to not serve them"`
+
+	OTLP OTLPArgs `flag:"otlp"`
+
+	LogLevel  string `flag:"log-level,default=info,help=Least severe records to log: debug, info, warn or error"`
+	LogFormat string `flag:"log-format,default=text,help=Format to log records in: text or json"`
+
+	ReloadInterval time.Duration `flag:"reload-interval,default=30s,help=How often to check the config file for a new log level, which applies without a restart; 0 to not check"`
+
+	DrainTimeout    time.Duration `flag:"drain-timeout,default=2m,help=How long to let the hands being played finish on shutdown before closing their tables anyway; the orchestrator's grace period should cover it and shutdown-timeout"`
+	ShutdownTimeout time.Duration `flag:"shutdown-timeout,default=10s,help=How long to wait for in-flight requests on shutdown"`
+}
+
+type OTLPArgs struct {
+	Endpoint    string  `flag:"endpoint,help=host:port of an OTLP/gRPC collector to export traces to; traces are not exported if unset, though their context is still passed on"`
+	Insecure    bool    `flag:"insecure,help=Connect to the collector without TLS"`
+	SampleRatio float64 `flag:"sample-ratio,default=1,help=Fraction of traces started here to export, from 0 to 1; traces continued from the matchmaker or a player are exported if theirs are"`
+}
+
+func ServerCommand() *cobra.Command {
+	return serverCommand(Serve)
+}
+
+// serverCommand returns the serve command, which runs serve with its flags.
+func serverCommand(serve func(*ServeArgs, *cobra.Command, []string) error) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "serve",
+		Short: "Host the tables the matchmaker assigns to this server",
+	}
+	c.RunE = flagr.Run(c, serve)
+	return c
+}
+
+func Serve(flags *ServeArgs, cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	level, err := log.ParseLevel(flags.LogLevel)
+	if err != nil {
+		return err
+	}
+	var logLevel slog.LevelVar
+	logLevel.Set(level)
+	logger, err := log.New(cmd.ErrOrStderr(), &logLevel, flags.LogFormat)
+	if err != nil {
+		return err
+	}
+	// Changes to settings are audited whatever the log level.
+	auditLog, err := log.New(cmd.ErrOrStderr(), slog.LevelInfo, flags.LogFormat)
+	if err != nil {
+		return err
+	}
+	auditLog = log.Component(auditLog, "audit")
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Service:     "gameserver",
+		Endpoint:    flags.OTLP.Endpoint,
+		Insecure:    flags.OTLP.Insecure,
+		SampleRatio: flags.OTLP.SampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("exporting traces", log.Err(err))
+		}
+	}()
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	server := matchmaker.Server{
+		ID:      cmp.Or(flags.ServerID, hostname),
+		Address: cmp.Or(flags.Address, net.JoinHostPort(hostname, strconv.Itoa(flags.Port))),
+		Region:  flags.Region,
+	}
+
+	conn, err := grpc.NewClient(flags.Matchmaker,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(tracing.ClientHandler()),
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := matchmaker.New(conn, flags.MatchmakerURL, flags.APIKey, &http.Client{
+		Transport: tracing.Transport(nil),
+		Timeout:   10 * time.Second,
+	})
+
+	clock := table.ClockRules{Action: flags.ActionTime, TimeBank: flags.TimeBank}
+	if clock.Action == 0 {
+		clock.TimeBank = 0
+	}
+	if err := clock.Validate(); err != nil {
+		return err
+	}
+	if flags.MaxBuyin > 0 && flags.MinBuyin > flags.MaxBuyin {
+		return fmt.Errorf("--min-buyin %d is over --max-buyin %d", flags.MinBuyin, flags.MaxBuyin)
+	}
+	reg := metrics.NewRegistry()
+	tableLog := log.Component(logger, "tables")
+	mmLog := log.Component(logger, "matchmaker")
+	opts := []host.Option{
+		host.WithIdleTimeout(flags.IdleTimeout),
+		host.WithMetrics(reg),
+		host.WithClock(clock),
+		host.WithBuyin(table.BuyinRules{Min: flags.MinBuyin, Max: flags.MaxBuyin}),
+		host.WithEnvironment(flags.Environment),
+		host.WithReporter(client, func(err error) {
+			mmLog.Error("reporting to matchmaker", log.Err(err))
+		}),
+		host.WithPanicHandler(func(tableID string, v any, stack []byte) {
+			tableLog.Error("table panicked", log.TableID(tableID), slog.Any("panic", v), slog.String("stack", string(stack)))
+		}),
+	}
+	if flags.PlayMoney {
+		opts = append(opts, host.WithPlayMoney())
+	} else {
+		opts = append(opts, host.WithWallet(client))
+	}
+	switch flags.Bots {
+	case "basic":
+		opts = append(opts, host.WithBots(bot.Basic{}))
+	case "passive":
+	default:
+		return fmt.Errorf("unknown --bots strategy %q", flags.Bots)
+	}
+	if flags.LogDir != "" {
+		logs, err := tablelog.Open(flags.LogDir)
+		if err != nil {
+			return err
+		}
+		defer logs.Close()
+		opts = append(opts, host.WithLog(logs))
+	}
+	h := host.New(flags.Capacity, opts...)
+	api.RegisterMetrics(reg, h)
+	// Rebuild the tables open when the server last crashed before the
+	// matchmaker assigns any, so that it finds them here.
+	recovered, err := h.Recover(ctx)
+	if err != nil {
+		tableLog.Error("recovering tables", log.Err(err))
+	}
+	if len(recovered) > 0 {
+		tableLog.Info("recovered tables", slog.Int("tables", len(recovered)))
+	}
+	// Sessions are revoked at the matchmaker, which tells the server.
+	sessions := session.NewStore(
+		session.WithKey([]byte(flags.SessionKey)),
+		session.WithTTLs(flags.AccessTTL, 0),
+		session.WithRevocations(client),
+	)
+	// The server is ready once the matchmaker has it registered, so that
+	// it is being assigned matches.
+	checker := health.New()
+	checker.Add("matchmaker", func(context.Context) error {
+		return client.Registered(3 * cmp.Or(flags.HeartbeatInterval, matchmaker.DefaultHeartbeatInterval))
+	})
+	apiMux := http.NewServeMux()
+	apiMux.Handle("/", log.Handler(api.NewServer(api.Config{Host: h, Sessions: sessions, AdminToken: flags.AdminToken})))
+	checker.Register(apiMux)
+	var handler http.Handler = apiMux
+	srv := &http.Server{
+		Addr:    net.JoinHostPort(flags.Host, strconv.Itoa(flags.Port)),
+		Handler: handler,
+	}
+	var h3 *http3.Server
+	if flags.HTTP3 {
+		if flags.TLSCert == "" || flags.TLSKey == "" {
+			return errors.New("--http3 needs --tls-cert and --tls-key")
+		}
+		h3 = &http3.Server{
+			Addr:    srv.Addr,
+			Handler: handler,
+			QUICConfig: &quic.Config{
+				// Keeps mobile networks' NAT bindings open between events.
+				KeepAlivePeriod: 15 * time.Second,
+			},
+		}
+		// Tell HTTP clients that they may switch.
+		srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h3.SetQUICHeaders(w.Header())
+			handler.ServeHTTP(w, r)
+		})
+	}
+
+	if flags.ReloadInterval > 0 {
+		watcher, err := config.NewWatcher(cmd.Flags(), "log-level")
+		if err != nil {
+			return err
+		}
+		go reloadLogLevel(ctx, flags.ReloadInterval, watcher, &logLevel, logger, auditLog)
+	}
+
+	if flags.MatchmakerURL != "" {
+		go sessions.Run(ctx, cmp.Or(flags.RevocationSync, 5*time.Second), func(err error) {
+			mmLog.Error("checking for revoked sessions", log.Err(err))
+		})
+	}
+
+	heartbeatCtx, stopHeartbeats := context.WithCancel(ctx)
+	defer stopHeartbeats()
+	heartbeatsDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatsDone)
+		client.Run(heartbeatCtx, flags.HeartbeatInterval, server, h, func(err error) {
+			mmLog.Error("taking matches", log.Err(err))
+		})
+	}()
+
+	errc := make(chan error, 2)
+	go func() {
+		logger.Info("listening", slog.String("server_id", server.ID), slog.String("addr", srv.Addr))
+		errc <- srv.ListenAndServe()
+	}()
+	if h3 != nil {
+		defer h3.Close()
+		go func() {
+			logger.Info("serving HTTP/3", slog.String("addr", h3.Addr), slog.String("network", "udp"))
+			if err := h3.ListenAndServeTLS(flags.TLSCert, flags.TLSKey); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errc <- fmt.Errorf("serving HTTP/3: %w", err)
+			}
+		}()
+	}
+	if flags.AdminToken != "" {
+		lis, err := net.Listen("tcp", net.JoinHostPort(flags.Host, strconv.Itoa(flags.GRPCPort)))
+		if err != nil {
+			return err
+		}
+		// Changes made with the admin token go in the matchmaker's audit log.
+		grpcSrv := rpc.NewServer(h, flags.AdminToken, sessions,
+			rpc.WithAuditor(client, "admin token on "+server.ID))
+		defer grpcSrv.Stop()
+		go grpcSrv.Serve(lis)
+	}
+	if flags.MetricsPort != 0 {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", reg)
+		checker.Register(mux)
+		metricsSrv := &http.Server{
+			Addr:    net.JoinHostPort(flags.Host, strconv.Itoa(flags.MetricsPort)),
+			Handler: mux,
+		}
+		defer metricsSrv.Close()
+		go func() {
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("serving metrics", log.Err(err))
+			}
+		}()
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Tell the matchmaker to stop placing matches here, then let the hands
+	// being played finish before closing the tables, so that players aren't
+	// dropped mid-hand.
+	checker.Drain()
+	stopHeartbeats()
+	<-heartbeatsDone
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cmp.Or(flags.DrainTimeout, 2*time.Minute))
+	defer cancelDrain()
+	if err := client.Drain(drainCtx, server, h, func(err error) {
+		mmLog.Error("taking matches", log.Err(err))
+	}); err != nil {
+		mmLog.Error("telling the matchmaker the server is draining", log.Err(err))
+	}
+	logger.Info("draining tables", slog.Int("tables", h.Len()))
+	if err := h.Drain(drainCtx, "server shutting down"); err != nil {
+		tableLog.Error("closing tables", log.Err(err))
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cmp.Or(flags.ShutdownTimeout, 10*time.Second))
+	defer cancel()
+	if h3 != nil {
+		if err := h3.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// reloadLogLevel sets level from the config file whenever it changes there,
+// until ctx is done, recording each change in audit. Other settings changed
+// in the file need a restart.
+func reloadLogLevel(ctx context.Context, interval time.Duration, w *config.Watcher, level *slog.LevelVar, logger, audit *slog.Logger) {
+	reloadLog := log.Component(logger, "reload")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		applied, restart, err := w.Reload()
+		if err != nil {
+			reloadLog.Error("rereading config file", log.Err(err))
+		}
+		for _, c := range restart {
+			reloadLog.Warn("setting changed in the config file takes effect on restart", slog.String("setting", c.Flag),
+				slog.String("old", c.Old), slog.String("new", c.New))
+		}
+		for _, c := range applied {
+			l, err := log.ParseLevel(c.New)
+			if err != nil {
+				reloadLog.Error("applying setting", slog.String("setting", c.Flag), log.Err(err))
+				continue
+			}
+			level.Set(l)
+			audit.Info("applied setting", slog.String("setting", c.Flag), slog.String("old", c.Old),
+				slog.String("new", c.New), slog.String("source", w.Path()))
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"github.com/spf13/cobra"
+
+	"github.com/jfmatt/snapfold/lib/config"
+)
+
+// flagr leaves time.Duration flags that aren't given at 0, which once
+// panicked the heartbeat loop and closed tables as they opened.
+func TestServerCommand_DefaultDurations(t *testing.T) {
+	var got *ServeArgs
+	root := &cobra.Command{Use: "gameserver"}
+	root.AddCommand(serverCommand(func(flags *ServeArgs, _ *cobra.Command, _ []string) error {
+		got = flags
+		return nil
+	}))
+	config.Register(root, "GAMESERVER_TEST")
+	root.SetArgs([]string{"serve", "--matchmaker=localhost:7000", "--api-key=secret", "--session-key=key", "--time-bank=0s"})
+	AssertThat(t, root.Execute(), Nil())
+
+	ExpectEq(t, got.HeartbeatInterval, 5*time.Second)
+	ExpectEq(t, got.IdleTimeout, 5*time.Minute)
+	ExpectEq(t, got.ActionTime, 20*time.Second)
+	ExpectEq(t, got.TimeBank, time.Duration(0))
+	ExpectEq(t, got.ReloadInterval, 30*time.Second)
+	ExpectEq(t, got.DrainTimeout, 2*time.Minute)
+	ExpectEq(t, got.ShutdownTimeout, 10*time.Second)
+}
@@ -0,0 +1,293 @@
+// Package matchmaker connects a game server to the matchmaker: it registers
+// the server through the fleet service, picks up the matches assigned to it,
+// and reports back what happens at its tables.
+package matchmaker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/lib/tracing"
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+)
+
+// Server describes this game server to the matchmaker.
+type Server struct {
+	// Identifies the server across heartbeats.
+	ID string
+
+	// host:port that players connect to.
+	Address string
+	Region  string
+}
+
+// Client talks to the matchmaker on behalf of a game server. It implements
+// host.Reporter.
+type Client struct {
+	fleet   pb.FleetServiceClient
+	baseURL string
+	token   string
+	http    *http.Client
+
+	mu      sync.Mutex
+	configs map[string]cachedConfig // by name
+
+	// When the last heartbeat reached the matchmaker, and the error of the
+	// last heartbeat if it didn't.
+	lastBeat time.Time
+	beatErr  error
+}
+
+var _ host.Reporter = (*Client)(nil)
+
+// New returns a Client that sends heartbeats over conn and reports to the
+// matchmaker's HTTP API at baseURL, authenticating with token: either the
+// matchmaker's internal token or an API key with the fleet and tables
+// scopes, and the configs scope to fetch configs. If baseURL is empty,
+// nothing is reported.
+func New(conn grpc.ClientConnInterface, baseURL, token string, client *http.Client) *Client {
+	return &Client{
+		fleet:   pb.NewFleetServiceClient(conn),
+		baseURL: baseURL,
+		token:   token,
+		http:    client,
+		configs: map[string]cachedConfig{},
+	}
+}
+
+// Heartbeat registers the server, or renews its registration, and returns
+// the matches assigned to it since the last heartbeat.
+func (c *Client) Heartbeat(ctx context.Context, s Server, capacity, tables, players int) ([]host.Assignment, error) {
+	return c.heartbeat(ctx, s, capacity, tables, players, false)
+}
+
+func (c *Client) heartbeat(ctx context.Context, s Server, capacity, tables, players int, draining bool) ([]host.Assignment, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.token)
+	req := pb.HeartbeatRequest_builder{
+		ServerId: proto.String(s.ID),
+		Address:  proto.String(s.Address),
+		Region:   proto.String(s.Region),
+		Capacity: proto.Int32(int32(capacity)),
+		Tables:   proto.Int32(int32(tables)),
+		Players:  proto.Int32(int32(players)),
+	}
+	if draining {
+		req.Draining = proto.Bool(true)
+	}
+	resp, err := c.fleet.Heartbeat(ctx, req.Build())
+	c.mu.Lock()
+	c.beatErr = err
+	if err == nil {
+		c.lastBeat = time.Now()
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("heartbeat: %w", err)
+	}
+	assignments := make([]host.Assignment, 0, len(resp.GetAssignments()))
+	for _, a := range resp.GetAssignments() {
+		assignments = append(assignments, host.Assignment{
+			MatchID:     a.GetMatchId(),
+			GameMode:    a.GetGameMode(),
+			PlayerIDs:   a.GetPlayerIds(),
+			Bots:        int(a.GetBots()),
+			Config:      a.GetConfig(),
+			Traceparent: a.GetTraceparent(),
+		})
+	}
+	return assignments, nil
+}
+
+// ErrNotRegistered is returned by Registered when no heartbeat has reached
+// the matchmaker recently.
+var ErrNotRegistered = errors.New("not registered with the matchmaker")
+
+// Registered returns nil if a heartbeat reached the matchmaker within
+// maxAge, so that the server is registered and is being assigned matches.
+func (c *Client) Registered(maxAge time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.lastBeat.IsZero() && time.Since(c.lastBeat) <= maxAge {
+		return nil
+	}
+	if c.beatErr != nil {
+		return fmt.Errorf("%w: %w", ErrNotRegistered, c.beatErr)
+	}
+	return ErrNotRegistered
+}
+
+type disconnectRequest struct {
+	PlayerID string `json:"player_id"`
+}
+
+// Disconnected reports that a player's connection to a table dropped, so
+// that the matchmaker holds their seat for them to reconnect.
+func (c *Client) Disconnected(ctx context.Context, tableID, playerID string) error {
+	body, err := json.Marshal(disconnectRequest{PlayerID: playerID})
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, "/v1/tables/"+url.PathEscape(tableID)+"/disconnects", body, nil)
+}
+
+// TableClosed reports that a table has finished, freeing its players'
+// seats.
+func (c *Client) TableClosed(ctx context.Context, tableID string) error {
+	return c.do(ctx, http.MethodDelete, "/v1/tables/"+url.PathEscape(tableID), nil, nil)
+}
+
+type handRequest struct {
+	Number    uint64            `json:"number"`
+	PlayedAt  time.Time         `json:"played_at"`
+	Histories map[string]string `json:"histories"`
+	Rake      int64             `json:"rake,omitempty"`
+}
+
+// HandPlayed reports a hand played at a table, so that the matchmaker keeps
+// each player's history of it for them to download.
+func (c *Client) HandPlayed(ctx context.Context, h host.PlayedHand) error {
+	body, err := json.Marshal(handRequest{Number: h.Number, PlayedAt: h.PlayedAt, Histories: h.Histories, Rake: h.Rake})
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, "/v1/tables/"+url.PathEscape(h.TableID)+"/hands", body, nil)
+}
+
+type tableActionRequest struct {
+	Actor  string       `json:"actor"`
+	Action audit.Action `json:"action"`
+	Before any          `json:"before,omitempty"`
+	After  any          `json:"after,omitempty"`
+}
+
+// TableAction reports a change made to a table through the game server's
+// admin API, for the matchmaker's audit log.
+func (c *Client) TableAction(ctx context.Context, tableID, actor string, action audit.Action, before, after any) error {
+	body, err := json.Marshal(tableActionRequest{Actor: actor, Action: action, Before: before, After: after})
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, "/v1/tables/"+url.PathEscape(tableID)+"/audit", body, nil)
+}
+
+// do sends a request to the matchmaker's HTTP API, and decodes the JSON
+// response into out if it isn't nil. It does nothing if the client has no
+// base URL.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	if c.baseURL == "" {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return &statusError{method: method, path: path, code: resp.StatusCode, status: resp.Status}
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// statusError is returned by do when the matchmaker replies with an error.
+type statusError struct {
+	method, path string
+	code         int
+	status       string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.method, e.path, e.status)
+}
+
+// DefaultHeartbeatInterval is how often Run sends heartbeats if given no
+// interval.
+const DefaultHeartbeatInterval = 5 * time.Second
+
+// Run sends a heartbeat every interval until ctx is done, opening a table
+// on h for each match assigned to the server. A non-positive interval means
+// DefaultHeartbeatInterval. Matches assigned without a config get their
+// game mode's from the config registry, if it has one, or else the built-in
+// preset of its name. Errors are passed to onError, and do not stop the
+// loop.
+func (c *Client) Run(ctx context.Context, interval time.Duration, s Server, h *host.Host, onError func(error)) {
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+	beat := func() {
+		assignments, err := c.Heartbeat(ctx, s, h.Capacity(), h.Len(), h.Connections())
+		if err != nil {
+			onError(err)
+			return
+		}
+		c.assign(ctx, h, assignments, onError)
+	}
+	beat()
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			beat()
+		}
+	}
+}
+
+// Drain tells the matchmaker that the server is shutting down, so that it
+// assigns the server no more matches, and opens tables on h for those it
+// had assigned since the last heartbeat, before h is drained. Call it once
+// Run has returned, as a later heartbeat would register the server again.
+// Errors opening tables are passed to onError.
+func (c *Client) Drain(ctx context.Context, s Server, h *host.Host, onError func(error)) error {
+	assignments, err := c.heartbeat(ctx, s, h.Capacity(), h.Len(), h.Connections(), true)
+	if err != nil {
+		return err
+	}
+	c.assign(ctx, h, assignments, onError)
+	return nil
+}
+
+// assign opens a table on h for each assignment.
+func (c *Client) assign(ctx context.Context, h *host.Host, assignments []host.Assignment, onError func(error)) {
+	for _, a := range assignments {
+		if a.Config == nil && a.GameMode != "" {
+			cfg, err := c.TableConfig(tracing.Continue(ctx, a.Traceparent), a.GameMode)
+			if errors.Is(err, ErrNoConfig) {
+				// Modes the registry doesn't have may be built-in presets.
+				cfg, _ = gamedefio.LoadPreset(a.GameMode)
+			} else if err != nil {
+				onError(fmt.Errorf("match %s: %w", a.MatchID, err))
+			}
+			a.Config = cfg
+		}
+		if _, err := h.Assign(a); err != nil {
+			onError(fmt.Errorf("match %s: %w", a.MatchID, err))
+		}
+	}
+}
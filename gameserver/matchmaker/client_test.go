@@ -0,0 +1,249 @@
+package matchmaker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+)
+
+var ctx = context.Background()
+
+// fakeFleet hands out each queued assignment on the next heartbeat.
+type fakeFleet struct {
+	pb.UnimplementedFleetServiceServer
+
+	mu       sync.Mutex
+	last     *pb.HeartbeatRequest
+	assigned []*pb.MatchAssignment
+}
+
+func (f *fakeFleet) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if auth := md.Get("authorization"); len(auth) != 1 || auth[0] != "Bearer secret" {
+		return nil, status.Error(codes.Unauthenticated, "bad token")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.last = req
+	resp := pb.HeartbeatResponse_builder{Assignments: f.assigned}.Build()
+	f.assigned = nil
+	return resp, nil
+}
+
+func (f *fakeFleet) Last() *pb.HeartbeatRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.last
+}
+
+func dialFleet(t *testing.T, f *fakeFleet) grpc.ClientConnInterface {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterFleetServiceServer(srv, f)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	AssertThat(t, err, Nil())
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestHeartbeat(t *testing.T) {
+	f := &fakeFleet{assigned: []*pb.MatchAssignment{pb.MatchAssignment_builder{
+		MatchId:   proto.String("m1"),
+		GameMode:  proto.String("holdem"),
+		PlayerIds: []string{"alice", "bob"},
+		Bots:      proto.Int32(2),
+		Config:    pb.TableConfig_builder{NoSpectators: proto.Bool(true)}.Build(),
+	}.Build()}}
+	conn := dialFleet(t, f)
+	s := Server{ID: "s1", Address: "10.0.0.1:7000", Region: "us-east"}
+
+	_, err := New(conn, "", "wrong", nil).Heartbeat(ctx, s, 4, 0, 0)
+	ExpectEq(t, status.Code(err), codes.Unauthenticated)
+
+	got, err := New(conn, "", "secret", nil).Heartbeat(ctx, s, 4, 1, 3)
+	AssertThat(t, err, Nil())
+	AssertThat(t, got, Len(1))
+	ExpectEq(t, got[0].Config.GetNoSpectators(), true)
+	got[0].Config = nil
+	ExpectThat(t, got, ElementsAre(host.Assignment{MatchID: "m1", GameMode: "holdem", PlayerIDs: []string{"alice", "bob"}, Bots: 2}))
+	ExpectEq(t, f.Last().GetServerId(), "s1")
+	ExpectEq(t, f.Last().GetAddress(), "10.0.0.1:7000")
+	ExpectEq(t, f.Last().GetRegion(), "us-east")
+	ExpectEq(t, f.Last().GetCapacity(), int32(4))
+	ExpectEq(t, f.Last().GetTables(), int32(1))
+	ExpectEq(t, f.Last().GetPlayers(), int32(3))
+}
+
+func TestRegistered(t *testing.T) {
+	conn := dialFleet(t, &fakeFleet{})
+	s := Server{ID: "s1", Address: "10.0.0.1:7000"}
+	c := New(conn, "", "secret", nil)
+	ExpectThat(t, c.Registered(time.Minute), ErrorIs(ErrNotRegistered))
+
+	_, err := c.Heartbeat(ctx, s, 4, 0, 0)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, c.Registered(time.Minute), Nil())
+	ExpectThat(t, c.Registered(0), ErrorIs(ErrNotRegistered))
+
+	c = New(conn, "", "wrong", nil)
+	_, err = c.Heartbeat(ctx, s, 4, 0, 0)
+	AssertThat(t, err, Not(Nil()))
+	err = c.Registered(time.Minute)
+	ExpectThat(t, err, ErrorIs(ErrNotRegistered))
+	ExpectEq(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestDrain(t *testing.T) {
+	f := &fakeFleet{assigned: []*pb.MatchAssignment{pb.MatchAssignment_builder{MatchId: proto.String("m1")}.Build()}}
+	c := New(dialFleet(t, f), "", "secret", nil)
+	h := host.New(4)
+
+	// Matches assigned before the server drained still get tables.
+	AssertThat(t, c.Drain(ctx, Server{ID: "s1", Address: "10.0.0.1:7000"}, h, func(err error) { t.Error(err) }), Nil())
+	ExpectEq(t, f.Last().GetDraining(), true)
+	ExpectEq(t, h.Len(), 1)
+}
+
+func TestRun(t *testing.T) {
+	f := &fakeFleet{assigned: []*pb.MatchAssignment{
+		pb.MatchAssignment_builder{MatchId: proto.String("m1")}.Build(),
+		pb.MatchAssignment_builder{MatchId: proto.String("m2")}.Build(),
+	}}
+	c := New(dialFleet(t, f), "", "secret", nil)
+	h := host.New(1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, 10)
+	go c.Run(ctx, 10*time.Millisecond, Server{ID: "s1"}, h, func(err error) { errs <- err })
+
+	// The server only has room for one of the two matches.
+	select {
+	case err := <-errs:
+		ExpectThat(t, err, ErrorIs(host.ErrFull))
+	case <-time.After(time.Second):
+		t.Fatal("no error")
+	}
+	_, err := h.Table("m1")
+	ExpectThat(t, err, Nil())
+
+	// Later heartbeats report the table.
+	deadline := time.Now().Add(time.Second)
+	for f.Last().GetTables() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	ExpectEq(t, f.Last().GetTables(), int32(1))
+	ExpectEq(t, f.Last().GetCapacity(), int32(1))
+}
+
+func TestReport(t *testing.T) {
+	type call struct {
+		Method, Path, Auth, PlayerID string
+	}
+	var calls []call
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req disconnectRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		calls = append(calls, call{r.Method, r.URL.Path, r.Header.Get("Authorization"), req.PlayerID})
+		if r.URL.Path == "/v1/tables/gone" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	c := New(nil, srv.URL, "secret", srv.Client())
+
+	AssertThat(t, c.Disconnected(ctx, "m1", "alice"), Nil())
+	AssertThat(t, c.TableClosed(ctx, "m1"), Nil())
+	ExpectThat(t, c.TableClosed(ctx, "gone"), Not(Nil()))
+	AssertThat(t, c.HandPlayed(ctx, host.PlayedHand{TableID: "m1", Number: 7, Histories: map[string]string{"alice": "text"}}), Nil())
+	ExpectThat(t, calls, ElementsAre(
+		call{http.MethodPost, "/v1/tables/m1/disconnects", "Bearer secret", "alice"},
+		call{http.MethodDelete, "/v1/tables/m1", "Bearer secret", ""},
+		call{http.MethodDelete, "/v1/tables/gone", "Bearer secret", ""},
+		call{http.MethodPost, "/v1/tables/m1/hands", "Bearer secret", ""},
+	))
+
+	// Without a URL there is nowhere to report to.
+	ExpectThat(t, New(nil, "", "secret", nil).TableClosed(ctx, "m1"), Nil())
+}
+
+func TestTableAction(t *testing.T) {
+	var path string
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	c := New(nil, srv.URL, "secret", srv.Client())
+
+	AssertThat(t, c.TableAction(ctx, "m1", "admin token on gs-1", audit.TableClosed, nil, map[string]string{"reason": "maintenance"}), Nil())
+	ExpectEq(t, path, "/v1/tables/m1/audit")
+	ExpectEq(t, got["actor"], "admin token on gs-1")
+	ExpectEq(t, got["action"], "table.close")
+	ExpectThat(t, got["after"], Not(Nil()))
+	_, ok := got["before"]
+	ExpectEq(t, ok, false)
+}
+
+func TestTableConfig(t *testing.T) {
+	var fetches, unchanged int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.URL.Path != "/v1/configs/table-configs/holdem" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fetches++
+		w.Header().Set("ETag", `"3"`)
+		if r.Header.Get("If-None-Match") == `"3"` {
+			unchanged++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`{"schemaVersion": 1, "standardGameId": "holdem", "someFutureField": true}`))
+	}))
+	defer srv.Close()
+	c := New(nil, srv.URL, "secret", srv.Client())
+
+	cfg, err := c.TableConfig(ctx, "holdem")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, cfg.GetStandardGameId(), "holdem")
+	again, err := c.TableConfig(ctx, "holdem")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, again, cfg)
+	ExpectEq(t, fetches, 2)
+	ExpectEq(t, unchanged, 1)
+
+	_, err = c.TableConfig(ctx, "omaha")
+	ExpectThat(t, err, ErrorIs(ErrNoConfig))
+	_, err = New(nil, "", "secret", nil).TableConfig(ctx, "holdem")
+	ExpectThat(t, err, ErrorIs(ErrNoConfig))
+}
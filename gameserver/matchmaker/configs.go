@@ -0,0 +1,79 @@
+package matchmaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+)
+
+var ErrNoConfig = errors.New("no such config in the registry")
+
+// cachedConfig is a config fetched from the registry, and the ETag of the
+// revision fetched.
+type cachedConfig struct {
+	etag string
+	cfg  *pb.TableConfig
+}
+
+// TableConfig fetches the TableConfig with the name from the matchmaker's
+// config registry, or returns ErrNoConfig if there is none. Configs are
+// cached, and only fetched again if they have changed.
+func (c *Client) TableConfig(ctx context.Context, name string) (*pb.TableConfig, error) {
+	if c.baseURL == "" {
+		return nil, ErrNoConfig
+	}
+	path := "/v1/configs/table-configs/" + url.PathEscape(name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	c.mu.Lock()
+	cached, ok := c.configs[name]
+	c.mu.Unlock()
+	if ok {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if ok {
+			return cached.cfg, nil
+		}
+		fallthrough
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrNoConfig, name)
+	default:
+		return nil, fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = gamedefio.UpgradeJSON(b); err != nil {
+		return nil, fmt.Errorf("table config %s: %w", name, err)
+	}
+	cfg := &pb.TableConfig{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("table config %s: %w", name, err)
+	}
+	c.mu.Lock()
+	c.configs[name] = cachedConfig{etag: resp.Header.Get("ETag"), cfg: cfg}
+	c.mu.Unlock()
+	return cfg, nil
+}
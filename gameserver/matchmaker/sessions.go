@@ -0,0 +1,28 @@
+package matchmaker
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+var _ session.Revocations = (*Client)(nil)
+
+type revokedSessionsResponse struct {
+	SessionIDs []string `json:"session_ids"`
+}
+
+// RevokedSessions returns the IDs of the sessions the matchmaker has revoked
+// at or after since, so that a session store made WithRevocations stops
+// accepting their access tokens. None are if the client has no base URL.
+func (c *Client) RevokedSessions(ctx context.Context, since time.Time) ([]string, error) {
+	var resp revokedSessionsResponse
+	path := "/v1/sessions/revoked?since=" + url.QueryEscape(since.UTC().Format(time.RFC3339Nano))
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.SessionIDs, nil
+}
@@ -0,0 +1,39 @@
+package matchmaker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestRevokedSessions(t *testing.T) {
+	since := time.Unix(1_800_000_000, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.URL.Path != "/v1/sessions/revoked" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		got, err := time.Parse(time.RFC3339Nano, r.URL.Query().Get("since"))
+		if err != nil || !got.Equal(since) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"session_ids": ["s1", "s2"]}`))
+	}))
+	defer srv.Close()
+
+	ids, err := New(nil, srv.URL, "secret", srv.Client()).RevokedSessions(ctx, since)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, ids, ElementsAre("s1", "s2"))
+
+	_, err = New(nil, srv.URL, "wrong", srv.Client()).RevokedSessions(ctx, since)
+	ExpectThat(t, err, ErrorMessage(HasSubstr("401")))
+
+	// Without the HTTP API, nothing is revoked.
+	ids, err = New(nil, "", "secret", nil).RevokedSessions(ctx, since)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, ids, Empty())
+}
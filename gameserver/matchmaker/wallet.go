@@ -0,0 +1,57 @@
+package matchmaker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/jfmatt/snapfold/gameserver/host"
+)
+
+var (
+	// ErrInsufficientFunds is returned by Debit when the player can't
+	// afford the chips.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+
+	// ErrNoWallet is returned by Debit and Credit when the client has no
+	// base URL to reach players' wallets at.
+	ErrNoWallet = errors.New("no matchmaker URL to reach wallets at")
+)
+
+var _ host.Wallet = (*Client)(nil)
+
+type transactionRequest struct {
+	Amount int64  `json:"amount"`
+	Ref    string `json:"ref"`
+}
+
+// Debit takes the cost of chips from a player's wallet at the matchmaker,
+// so that the client can be a host's wallet. A retried debit with the same
+// ref is only taken once.
+func (c *Client) Debit(ctx context.Context, playerID string, amount int64, ref string) error {
+	err := c.transact(ctx, playerID, "debits", amount, ref)
+	var status *statusError
+	if errors.As(err, &status) && status.code == http.StatusPaymentRequired {
+		return fmt.Errorf("%w: %w", ErrInsufficientFunds, err)
+	}
+	return err
+}
+
+// Credit gives back the debit with ref.
+func (c *Client) Credit(ctx context.Context, playerID string, amount int64, ref string) error {
+	return c.transact(ctx, playerID, "credits", amount, ref)
+}
+
+func (c *Client) transact(ctx context.Context, playerID, kind string, amount int64, ref string) error {
+	if c.baseURL == "" {
+		return ErrNoWallet
+	}
+	body, err := json.Marshal(transactionRequest{Amount: amount, Ref: ref})
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, "/v1/wallets/"+url.PathEscape(playerID)+"/"+kind, body, nil)
+}
@@ -0,0 +1,48 @@
+package matchmaker
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/api"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+	"github.com/jfmatt/snapfold/matchmaker/wallet"
+)
+
+func TestWallet(t *testing.T) {
+	wallets := wallet.New(wallet.NewMemStore())
+	_, err := wallets.Deposit(ctx, "alice", 5000, "d1")
+	AssertThat(t, err, Nil())
+	srv := httptest.NewServer(api.NewServer(api.Config{
+		Lobby:         lobby.New(queue.New(), party.NewManager(), nil),
+		Sessions:      session.NewStore(),
+		Wallets:       wallets,
+		InternalToken: "secret",
+	}))
+	defer srv.Close()
+	c := New(nil, srv.URL, "secret", srv.Client())
+	balance := func() int64 {
+		t.Helper()
+		b, err := wallets.Balance(ctx, "alice")
+		AssertThat(t, err, Nil())
+		return b
+	}
+
+	// A retried debit is only taken once.
+	for range 2 {
+		AssertThat(t, c.Debit(ctx, "alice", 2000, "m1/1"), Nil())
+	}
+	ExpectEq(t, balance(), int64(3000))
+	ExpectThat(t, c.Debit(ctx, "alice", 4000, "m1/2"), ErrorIs(ErrInsufficientFunds))
+	AssertThat(t, c.Credit(ctx, "alice", 2000, "m1/1"), Nil())
+	ExpectEq(t, balance(), int64(5000))
+
+	ExpectThat(t, New(nil, srv.URL, "wrong", srv.Client()).Debit(ctx, "alice", 1, "m1/3"), ErrorMessage(HasSubstr("401")))
+	ExpectThat(t, New(nil, "", "secret", nil).Debit(ctx, "alice", 1, "m1/3"), ErrorIs(ErrNoWallet))
+	ExpectEq(t, balance(), int64(5000))
+}
@@ -0,0 +1,63 @@
+package rpc
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+// EventService implements pb.TableEventServiceServer on top of a host.
+type EventService struct {
+	pb.UnimplementedTableEventServiceServer
+
+	host *host.Host
+}
+
+// NewEventService returns an EventService that streams h's tables' events.
+func NewEventService(h *host.Host) *EventService {
+	return &EventService{host: h}
+}
+
+func (s *EventService) StreamTableEvents(req *pb.StreamTableEventsRequest, stream grpc.ServerStreamingServer[pb.TableEvent]) error {
+	ctx := stream.Context()
+	t, err := s.host.Table(req.GetTableId())
+	if err != nil {
+		return statusError(err)
+	}
+	var events <-chan *pb.TableEvent
+	var leave func()
+	playerID, isPlayer := session.PlayerFrom(ctx)
+	switch {
+	case req.GetSpectate():
+		events, leave, err = t.Watch()
+	case isPlayer:
+		events, leave, err = t.Connect(playerID)
+	default:
+		return status.Error(codes.InvalidArgument, "the admin token may only spectate")
+	}
+	if err != nil {
+		return statusError(err)
+	}
+	defer leave()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case ev, ok := <-events:
+			if !ok {
+				return status.Error(codes.Aborted, "fell too far behind the table; stream again for a fresh snapshot")
+			}
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+			if ev.HasTableClosed() {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func TestStreamTableEvents(t *testing.T) {
+	h := host.New(1)
+	table, err := h.Assign(host.Assignment{MatchID: "t1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	sessions := session.NewStore()
+	client := pb.NewTableEventServiceClient(dial(t, h, sessions))
+	as := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+	open := func(ctx context.Context, spectate bool) pb.TableEventService_StreamTableEventsClient {
+		t.Helper()
+		stream, err := client.StreamTableEvents(ctx, pb.StreamTableEventsRequest_builder{
+			TableId:  proto.String("t1"),
+			Spectate: proto.Bool(spectate),
+		}.Build())
+		AssertThat(t, err, Nil())
+		return stream
+	}
+
+	alice := open(as(sessions.Create("alice")), false)
+	ev, err := alice.Recv()
+	AssertThat(t, err, Nil())
+	ExpectThat(t, ev.GetSnapshot().GetConnectedPlayerIds(), ElementsAre("alice"))
+
+	// Spectators, including admin tooling, don't count as connected.
+	admin := open(ctx, true)
+	ev, err = admin.Recv()
+	AssertThat(t, err, Nil())
+	ExpectThat(t, ev.GetSnapshot().GetConnectedPlayerIds(), ElementsAre("alice"))
+
+	AssertThat(t, table.Close(ctx, "game over"), Nil())
+	for _, stream := range []pb.TableEventService_StreamTableEventsClient{alice, admin} {
+		ev, err := stream.Recv()
+		AssertThat(t, err, Nil())
+		ExpectEq(t, ev.GetTableClosed().GetReason(), "game over")
+		_, err = stream.Recv()
+		ExpectThat(t, err, ErrorIs(io.EOF))
+	}
+}
+
+func TestStreamTableEvents_Revoked(t *testing.T) {
+	h := host.New(1)
+	_, err := h.Assign(host.Assignment{MatchID: "t1", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+	key := []byte("shared with the matchmaker")
+	matchmaker := session.NewStore(session.WithKey(key))
+	sessions := session.NewStore(session.WithKey(key), session.WithRevocations(matchmaker))
+	client := pb.NewTableEventServiceClient(dial(t, h, sessions))
+
+	tokens, err := matchmaker.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	authed := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+tokens.Access)
+	req := pb.StreamTableEventsRequest_builder{TableId: proto.String("t1")}.Build()
+	stream, err := client.StreamTableEvents(authed, req)
+	AssertThat(t, err, Nil())
+	_, err = stream.Recv()
+	AssertThat(t, err, Nil())
+
+	AssertThat(t, matchmaker.Logout(ctx, tokens.Refresh), Nil())
+	AssertThat(t, sessions.Sync(ctx), Nil())
+	_, err = stream.Recv()
+	ExpectEq(t, status.Code(err), codes.Unauthenticated)
+
+	stream, err = client.StreamTableEvents(authed, req)
+	AssertThat(t, err, Nil())
+	_, err = stream.Recv()
+	ExpectEq(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestStreamTableEvents_Rejected(t *testing.T) {
+	h := host.New(1)
+	_, err := h.Assign(host.Assignment{MatchID: "t1", PlayerIDs: []string{"alice"}})
+	AssertThat(t, err, Nil())
+	sessions := session.NewStore()
+	client := pb.NewTableEventServiceClient(dial(t, h, sessions))
+
+	for _, tc := range []struct {
+		token    string
+		table    string
+		spectate bool
+		want     codes.Code
+	}{
+		{"wrong", "t1", true, codes.Unauthenticated},
+		{"secret", "t1", false, codes.InvalidArgument},
+		{sessions.Create("carol"), "t1", false, codes.PermissionDenied},
+		{sessions.Create("alice"), "t2", false, codes.NotFound},
+	} {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+tc.token)
+		stream, err := client.StreamTableEvents(ctx, pb.StreamTableEventsRequest_builder{
+			TableId:  proto.String(tc.table),
+			Spectate: proto.Bool(tc.spectate),
+		}.Build())
+		AssertThat(t, err, Nil())
+		_, err = stream.Recv()
+		ExpectEq(t, status.Code(err), tc.want)
+	}
+
+	// Player tokens don't manage tables.
+	_, err = pb.NewTableServiceClient(dial(t, h, sessions)).ListTables(
+		metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+sessions.Create("alice")),
+		&pb.ListTablesRequest{})
+	ExpectEq(t, status.Code(err), codes.Unauthenticated)
+}
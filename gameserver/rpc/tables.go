@@ -0,0 +1,343 @@
+// Package rpc implements the game server's gRPC interface, through which the
+// matchmaker and admin tooling manage its tables, and native clients follow
+// them.
+package rpc
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"maps"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/lib/tracing"
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+// TableService implements pb.TableServiceServer on top of a host.
+type TableService struct {
+	pb.UnimplementedTableServiceServer
+
+	host    *host.Host
+	auditor Auditor
+	actor   string
+}
+
+// Auditor records the changes made to tables through the table service,
+// such as in the matchmaker's audit log. before and after are what the
+// table was like around the change, as JSON.
+type Auditor interface {
+	TableAction(ctx context.Context, tableID, actor string, action audit.Action, before, after any) error
+}
+
+// Option configures a TableService.
+type Option func(*TableService)
+
+// WithAuditor has each table created, paused, resumed or closed recorded by
+// a, as done by actor, such as "admin token on gs-1". Changes are recorded
+// before they are made, and not made if recording fails.
+func WithAuditor(a Auditor, actor string) Option {
+	return func(s *TableService) {
+		s.auditor, s.actor = a, actor
+	}
+}
+
+// NewTableService returns a TableService that manages h's tables.
+func NewTableService(h *host.Host, opts ...Option) *TableService {
+	s := &TableService{host: h}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// NewServer returns a gRPC server with the table and table event services
+// registered. Every call must carry adminToken as a bearer token, except
+// that calls to the table event service may instead carry a player's access
+// token, checked by sessions.
+func NewServer(h *host.Host, adminToken string, sessions *session.Store, opts ...Option) *grpc.Server {
+	auth := authenticator{token: adminToken, sessions: sessions}
+	srv := grpc.NewServer(
+		grpc.StatsHandler(tracing.ServerHandler()),
+		grpc.UnaryInterceptor(auth.unary),
+		grpc.StreamInterceptor(auth.stream),
+	)
+	pb.RegisterTableServiceServer(srv, NewTableService(h, opts...))
+	pb.RegisterTableEventServiceServer(srv, NewEventService(h))
+	return srv
+}
+
+func (s *TableService) CreateTable(ctx context.Context, req *pb.CreateTableRequest) (*pb.TableInfo, error) {
+	if req.GetTableId() == "" || len(req.GetPlayerIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "table_id and player_ids are required")
+	}
+	if req.GetBots() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "bots must not be negative")
+	}
+	for _, chips := range req.GetStacks() {
+		if chips < 0 {
+			return nil, status.Error(codes.InvalidArgument, "stacks must not be negative")
+		}
+	}
+	if _, err := s.host.Table(req.GetTableId()); err == nil {
+		return nil, status.Errorf(codes.AlreadyExists, "table %s is already open", req.GetTableId())
+	}
+	if err := s.record(ctx, audit.TableCreated, req.GetTableId(), nil, tableState{Request: req}); err != nil {
+		return nil, err
+	}
+	t, err := s.host.Assign(host.Assignment{
+		MatchID:     req.GetTableId(),
+		GameMode:    req.GetGameMode(),
+		PlayerIDs:   req.GetPlayerIds(),
+		Bots:        int(req.GetBots()),
+		Stacks:      maps.Clone(req.GetStacks()),
+		Config:      req.GetConfig(),
+		Traceparent: tracing.Traceparent(ctx),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return tableProto(t), nil
+}
+
+func (s *TableService) GetTable(ctx context.Context, req *pb.GetTableRequest) (*pb.TableInfo, error) {
+	t, err := s.host.Table(req.GetTableId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return tableProto(t), nil
+}
+
+func (s *TableService) ListTables(ctx context.Context, req *pb.ListTablesRequest) (*pb.ListTablesResponse, error) {
+	var tables []*pb.TableInfo
+	for _, st := range s.host.Stats() {
+		// Tables may close between listing and looking them up.
+		if t, err := s.host.Table(st.TableID); err == nil {
+			tables = append(tables, tableProto(t))
+		}
+	}
+	return pb.ListTablesResponse_builder{Tables: tables}.Build(), nil
+}
+
+func (s *TableService) PauseTable(ctx context.Context, req *pb.PauseTableRequest) (*pb.TableInfo, error) {
+	t, err := s.host.Table(req.GetTableId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	before := tableProto(t)
+	if before.GetPaused() {
+		return before, nil
+	}
+	reason := cmp.Or(req.GetReason(), "paused by an administrator")
+	if err := s.record(ctx, audit.TablePaused, t.ID, tableState{Table: before}, tableState{Reason: reason}); err != nil {
+		return nil, err
+	}
+	if err := t.Pause(reason); err != nil {
+		return nil, statusError(err)
+	}
+	return tableProto(t), nil
+}
+
+func (s *TableService) ResumeTable(ctx context.Context, req *pb.ResumeTableRequest) (*pb.TableInfo, error) {
+	t, err := s.host.Table(req.GetTableId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	before := tableProto(t)
+	if !before.GetPaused() {
+		return before, nil
+	}
+	if err := s.record(ctx, audit.TableResumed, t.ID, tableState{Table: before}, nil); err != nil {
+		return nil, err
+	}
+	if err := t.Resume(); err != nil {
+		return nil, statusError(err)
+	}
+	return tableProto(t), nil
+}
+
+func (s *TableService) CloseTable(ctx context.Context, req *pb.CloseTableRequest) (*pb.CloseTableResponse, error) {
+	t, err := s.host.Table(req.GetTableId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	reason := cmp.Or(req.GetReason(), "closed by an administrator")
+	if err := s.record(ctx, audit.TableClosed, t.ID, tableState{Table: tableProto(t)}, tableState{Reason: reason}); err != nil {
+		return nil, err
+	}
+	returned, err := t.CloseGracefully(ctx, reason)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return pb.CloseTableResponse_builder{Returned: stackProtos(returned)}.Build(), nil
+}
+
+// tableState is how the auditor is shown a table around a change.
+type tableState struct {
+	Table *pb.TableInfo `json:"-"`
+
+	// The request a table was created with.
+	Request *pb.CreateTableRequest `json:"-"`
+
+	// Why the table was paused or closed.
+	Reason string `json:"reason,omitempty"`
+}
+
+func (ts tableState) MarshalJSON() ([]byte, error) {
+	type plain tableState
+	v := struct {
+		Table   json.RawMessage `json:"table,omitempty"`
+		Request json.RawMessage `json:"request,omitempty"`
+		plain
+	}{plain: plain(ts)}
+	var err error
+	if ts.Table != nil {
+		if v.Table, err = protojson.Marshal(ts.Table); err != nil {
+			return nil, err
+		}
+	}
+	if ts.Request != nil {
+		if v.Request, err = protojson.Marshal(ts.Request); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(v)
+}
+
+// record tells the auditor, if there is one, about a change to a table
+// before it is made. The change must not be made if recording fails, so
+// that none is missing from the audit log.
+func (s *TableService) record(ctx context.Context, action audit.Action, tableID string, before, after any) error {
+	if s.auditor == nil {
+		return nil
+	}
+	if err := s.auditor.TableAction(ctx, tableID, s.actor, action, before, after); err != nil {
+		return status.Errorf(codes.Unavailable, "recording %s of table %s in the audit log: %v", action, tableID, err)
+	}
+	return nil
+}
+
+// statusError converts an error from the host package to a gRPC status
+// error with a matching code.
+func statusError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, host.ErrNotFound),
+		errors.Is(err, host.ErrClosed):
+		code = codes.NotFound
+	case errors.Is(err, host.ErrNotSeated),
+		errors.Is(err, host.ErrNoSpectators):
+		code = codes.PermissionDenied
+	case errors.Is(err, host.ErrFull),
+		errors.Is(err, host.ErrBusy):
+		code = codes.ResourceExhausted
+	case errors.Is(err, host.ErrDraining):
+		code = codes.Unavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	}
+	return status.Error(code, err.Error())
+}
+
+func tableProto(t *host.Table) *pb.TableInfo {
+	return pb.TableInfo_builder{
+		TableId:             proto.String(t.ID),
+		GameMode:            proto.String(t.GameMode),
+		PlayerIds:           t.Players,
+		Bots:                proto.Int32(int32(t.Bots)),
+		ConnectedPlayerIds:  t.Connected(),
+		Paused:              proto.Bool(t.Paused()),
+		Stacks:              stackProtos(t.Stacks()),
+		SittingOutPlayerIds: t.SittingOut(),
+	}.Build()
+}
+
+func stackProtos(stacks []host.Stack) []*pb.Stack {
+	out := make([]*pb.Stack, 0, len(stacks))
+	for _, s := range stacks {
+		out = append(out, pb.Stack_builder{PlayerId: proto.String(s.PlayerID), Chips: proto.Int64(s.Chips)}.Build())
+	}
+	return out
+}
+
+// authenticator checks that every call carries the admin token, or, for
+// the table event service, a player's access token.
+type authenticator struct {
+	token    string
+	sessions *session.Store
+}
+
+// authenticate returns the call's context, with the player's session in it
+// if the call carries an access token rather than the admin token.
+func (a authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, header := range md.Get("authorization") {
+		token, ok := session.BearerToken(header)
+		if !ok {
+			continue
+		}
+		if a.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1 {
+			return ctx, nil
+		}
+		if a.sessions != nil && strings.HasPrefix(method, "/"+pb.TableEventService_ServiceDesc.ServiceName+"/") {
+			if ctx, ok := a.sessions.Authenticate(ctx, token); ok {
+				return ctx, nil
+			}
+			return nil, status.Error(codes.Unauthenticated, "invalid session")
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid admin token")
+	}
+	return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+}
+
+func (a authenticator) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// stream authenticates a streaming call and ends it if its session is
+// revoked.
+func (a authenticator) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	if a.sessions != nil {
+		var stop context.CancelFunc
+		ctx, stop = a.sessions.Watch(ctx)
+		defer stop()
+	}
+	err = handler(srv, &authedStream{ServerStream: ss, ctx: ctx})
+	if errors.Is(context.Cause(ctx), session.ErrRevoked) {
+		return status.Error(codes.Unauthenticated, session.ErrRevoked.Error())
+	}
+	return err
+}
+
+// authedStream overrides the context of a server stream with one carrying
+// the authenticated player, if any.
+type authedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,181 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+var ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+
+func startServer(t *testing.T, h *host.Host) pb.TableServiceClient {
+	t.Helper()
+	return pb.NewTableServiceClient(dial(t, h, session.NewStore()))
+}
+
+// dial serves h with "secret" as the admin token, and returns a connection
+// to it.
+func dial(t *testing.T, h *host.Host, sessions *session.Store) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := NewServer(h, "secret", sessions)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	AssertThat(t, err, Nil())
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestAuthentication(t *testing.T) {
+	client := startServer(t, host.New(1))
+	_, err := client.ListTables(context.Background(), &pb.ListTablesRequest{})
+	ExpectEq(t, status.Code(err), codes.Unauthenticated)
+	wrong := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	_, err = client.ListTables(wrong, &pb.ListTablesRequest{})
+	ExpectEq(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestTableLifecycle(t *testing.T) {
+	h := host.New(1)
+	client := startServer(t, h)
+
+	_, err := client.CreateTable(ctx, &pb.CreateTableRequest{})
+	ExpectEq(t, status.Code(err), codes.InvalidArgument)
+	info, err := client.CreateTable(ctx, pb.CreateTableRequest_builder{
+		TableId:   proto.String("t1"),
+		GameMode:  proto.String("holdem"),
+		PlayerIds: []string{"alice", "bob"},
+		Stacks:    map[string]int64{"alice": 1000, "bob": 500},
+		Config:    pb.TableConfig_builder{NoSpectators: proto.Bool(true)}.Build(),
+	}.Build())
+	AssertThat(t, err, Nil())
+	ExpectEq(t, info.GetTableId(), "t1")
+	ExpectEq(t, info.GetPaused(), false)
+	AssertThat(t, info.GetStacks(), Len(2))
+	ExpectEq(t, info.GetStacks()[1].GetChips(), int64(500))
+
+	_, err = client.CreateTable(ctx, pb.CreateTableRequest_builder{
+		TableId:   proto.String("t2"),
+		PlayerIds: []string{"carol"},
+	}.Build())
+	ExpectEq(t, status.Code(err), codes.ResourceExhausted)
+
+	table, err := h.Table("t1")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, table.Config.GetNoSpectators(), true)
+	events, leave, err := table.Connect("alice")
+	AssertThat(t, err, Nil())
+	defer leave()
+	<-events
+
+	info, err = client.PauseTable(ctx, pb.PauseTableRequest_builder{TableId: proto.String("t1"), Reason: proto.String("maintenance")}.Build())
+	AssertThat(t, err, Nil())
+	ExpectEq(t, info.GetPaused(), true)
+	ExpectEq(t, (<-events).GetTablePaused().GetReason(), "maintenance")
+
+	info, err = client.ResumeTable(ctx, pb.ResumeTableRequest_builder{TableId: proto.String("t1")}.Build())
+	AssertThat(t, err, Nil())
+	ExpectEq(t, info.GetPaused(), false)
+	ExpectEq(t, (<-events).HasTableResumed(), true)
+
+	list, err := client.ListTables(ctx, &pb.ListTablesRequest{})
+	AssertThat(t, err, Nil())
+	AssertThat(t, list.GetTables(), Len(1))
+	ExpectThat(t, list.GetTables()[0].GetConnectedPlayerIds(), ElementsAre("alice"))
+
+	resp, err := client.CloseTable(ctx, pb.CloseTableRequest_builder{TableId: proto.String("t1")}.Build())
+	AssertThat(t, err, Nil())
+	AssertThat(t, resp.GetReturned(), Len(2))
+	ExpectEq(t, resp.GetReturned()[0].GetPlayerId(), "alice")
+	ExpectEq(t, resp.GetReturned()[0].GetChips(), int64(1000))
+	closed := (<-events).GetTableClosed()
+	ExpectEq(t, closed.GetReason(), "closed by an administrator")
+	ExpectThat(t, closed.GetReturned(), Len(2))
+
+	_, err = client.GetTable(ctx, pb.GetTableRequest_builder{TableId: proto.String("t1")}.Build())
+	ExpectEq(t, status.Code(err), codes.NotFound)
+	_, err = client.CloseTable(ctx, pb.CloseTableRequest_builder{TableId: proto.String("t1")}.Build())
+	ExpectEq(t, status.Code(err), codes.NotFound)
+}
+
+type fakeAuditor struct {
+	actions []string
+	afters  []string
+	err     error
+}
+
+func (a *fakeAuditor) TableAction(ctx context.Context, tableID, actor string, action audit.Action, before, after any) error {
+	b, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+	a.actions = append(a.actions, actor+" "+string(action)+" "+tableID)
+	a.afters = append(a.afters, string(b))
+	return a.err
+}
+
+func TestTableService_Audit(t *testing.T) {
+	auditor := &fakeAuditor{}
+	s := NewTableService(host.New(1), WithAuditor(auditor, "admin token on gs-1"))
+
+	_, err := s.CreateTable(ctx, pb.CreateTableRequest_builder{TableId: proto.String("t1"), PlayerIds: []string{"alice"}}.Build())
+	AssertThat(t, err, Nil())
+	_, err = s.PauseTable(ctx, pb.PauseTableRequest_builder{TableId: proto.String("t1"), Reason: proto.String("maintenance")}.Build())
+	AssertThat(t, err, Nil())
+	// Pausing a paused table changes nothing, so isn't recorded.
+	_, err = s.PauseTable(ctx, pb.PauseTableRequest_builder{TableId: proto.String("t1")}.Build())
+	AssertThat(t, err, Nil())
+	_, err = s.ResumeTable(ctx, pb.ResumeTableRequest_builder{TableId: proto.String("t1")}.Build())
+	AssertThat(t, err, Nil())
+	// Failed changes aren't recorded.
+	_, err = s.ResumeTable(ctx, pb.ResumeTableRequest_builder{TableId: proto.String("t2")}.Build())
+	ExpectEq(t, status.Code(err), codes.NotFound)
+	_, err = s.CreateTable(ctx, pb.CreateTableRequest_builder{TableId: proto.String("t1"), PlayerIds: []string{"bob"}}.Build())
+	ExpectEq(t, status.Code(err), codes.AlreadyExists)
+
+	// Tables aren't closed if closing them can't be recorded.
+	auditor.err = errors.New("matchmaker unavailable")
+	_, err = s.CloseTable(ctx, pb.CloseTableRequest_builder{TableId: proto.String("t1")}.Build())
+	ExpectEq(t, status.Code(err), codes.Unavailable)
+	ExpectThat(t, err, ErrorMessage(HasSubstr("matchmaker unavailable")))
+	_, err = s.GetTable(ctx, pb.GetTableRequest_builder{TableId: proto.String("t1")}.Build())
+	AssertThat(t, err, Nil())
+
+	auditor.err = nil
+	_, err = s.CloseTable(ctx, pb.CloseTableRequest_builder{TableId: proto.String("t1")}.Build())
+	AssertThat(t, err, Nil())
+
+	ExpectThat(t, auditor.actions, ElementsAre(
+		"admin token on gs-1 table.create t1",
+		"admin token on gs-1 table.pause t1",
+		"admin token on gs-1 table.resume t1",
+		"admin token on gs-1 table.close t1",
+		"admin token on gs-1 table.close t1",
+	))
+	ExpectThat(t, auditor.afters[0], HasSubstr(`"request":{"tableId":"t1"`))
+	ExpectThat(t, auditor.afters[1], HasSubstr(`"reason":"maintenance"`))
+	ExpectThat(t, auditor.afters[4], HasSubstr(`"reason":"closed by an administrator"`))
+}
@@ -0,0 +1,371 @@
+// Package sim plays hands between bots entirely in-process, with no game
+// server or players, and totals what happened, so that a table's rules can
+// be tried out before anyone plays them.
+package sim
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/lib/handeval"
+	"github.com/jfmatt/snapfold/lib/shuffle"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+// streets are the board cards dealt after each betting round but the last:
+// the flop, turn and river.
+var streets = []int{3, 1, 1}
+
+// Config is what to simulate.
+type Config struct {
+	Table *pb.TableConfig
+
+	// How the bot in each seat plays, from seat 0. At least two.
+	Bots []host.BotStrategy
+
+	// Chips every bot starts each hand with. Zero means 100 big blinds.
+	Stack int64
+
+	Hands int
+
+	// Shuffles each hand's deck. Nil seeds every shuffle from crypto/rand;
+	// pass shuffle.NewSeeded to repeat a simulation.
+	Shuffler *shuffle.Shuffler
+}
+
+// Stats totals the hands simulated.
+type Stats struct {
+	Hands    int
+	BigBlind int64
+
+	// Hands that reached the flop, that two or more players showed down,
+	// that had a player all in, and whose board was run twice.
+	Flops, Showdowns, AllIns, RanTwice int
+
+	// Each hand's pots before rake, its number of actions, not counting
+	// blinds and antes, and how long it took to play, in the order the
+	// hands were played.
+	Pots      []int64
+	Actions   []int
+	Durations []time.Duration
+
+	Rake int64
+
+	// Chips each seat won less what it put in, over every hand.
+	Net []int64
+
+	// Times a bot's strategy failed or picked something it may not do, and
+	// the bot checked or folded instead.
+	Fallbacks int
+}
+
+// Run plays cfg.Hands hands of the table with the bots, moving the button
+// on each hand, and returns their stats. The table's variant is dealt with
+// the blinds, limits, rake and run it twice rules of its config, and four
+// betting rounds around a flop, turn and river. Bots always agree to run
+// it twice where the config allows it.
+//
+// If ctx is canceled, Run returns the stats of the hands played so far
+// with ctx's error.
+func Run(ctx context.Context, cfg Config) (Stats, error) {
+	if len(cfg.Bots) < 2 {
+		return Stats{}, fmt.Errorf("%w: %d bots", table.ErrNotEnoughPlayers, len(cfg.Bots))
+	}
+	s := &simulator{bots: cfg.Bots, shuffler: cfg.Shuffler}
+	var err error
+	if s.variant, err = table.VariantFor(cfg.Table); err != nil {
+		return Stats{}, err
+	}
+	if s.blinds, err = table.BlindRulesFor(cfg.Table); err != nil {
+		return Stats{}, err
+	}
+	if s.betting, err = table.RulesFor(cfg.Table, nil); err != nil {
+		return Stats{}, err
+	}
+	if s.rake, err = table.RakeRulesFor(cfg.Table); err != nil {
+		return Stats{}, err
+	}
+	s.runout = table.RunoutRulesFor(cfg.Table)
+	s.bigBlind = s.blinds.Blinds[len(s.blinds.Blinds)-1]
+	s.stack = cmp.Or(cfg.Stack, 100*s.bigBlind)
+	if s.shuffler == nil {
+		s.shuffler = shuffle.New()
+	}
+
+	stats := Stats{BigBlind: s.bigBlind, Net: make([]int64, len(cfg.Bots))}
+	rot := table.NoRotation
+	for range cfg.Hands {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		start := time.Now()
+		h, err := s.play(ctx, rot)
+		if err != nil {
+			return stats, fmt.Errorf("hand %d: %w", stats.Hands+1, err)
+		}
+		rot = h.rotation
+		stats.add(h, time.Since(start))
+	}
+	return stats, nil
+}
+
+func (s *Stats) add(h hand, d time.Duration) {
+	s.Hands++
+	if h.flopped {
+		s.Flops++
+	}
+	if h.showdown {
+		s.Showdowns++
+	}
+	if h.allIn {
+		s.AllIns++
+	}
+	if h.ranTwice {
+		s.RanTwice++
+	}
+	s.Pots = append(s.Pots, h.pot)
+	s.Actions = append(s.Actions, h.actions)
+	s.Durations = append(s.Durations, d)
+	s.Rake += h.rake
+	for seat, net := range h.net {
+		s.Net[seat] += net
+	}
+	s.Fallbacks += h.fallbacks
+}
+
+type simulator struct {
+	bots     []host.BotStrategy
+	shuffler *shuffle.Shuffler
+
+	variant  table.Variant
+	blinds   table.BlindRules
+	betting  table.BettingRules
+	rake     table.RakeRules
+	runout   table.RunoutRules
+	bigBlind int64
+	stack    int64
+}
+
+// hand is how one hand went.
+type hand struct {
+	rotation table.Rotation
+
+	pot, rake int64
+	actions   int
+	fallbacks int
+
+	flopped, showdown, allIn, ranTwice bool
+
+	// Chips each seat won less what it put in.
+	net map[int]int64
+}
+
+// play deals and plays one hand, the button moving on from rot.
+func (s *simulator) play(ctx context.Context, rot table.Rotation) (hand, error) {
+	seats := make([]table.Seat, len(s.bots))
+	for i := range seats {
+		seats[i] = table.Seat{Seat: i, Stack: s.stack}
+	}
+	deal, _, err := table.NextHand(s.blinds, rot, seats)
+	if err != nil {
+		return hand{}, err
+	}
+	h := hand{rotation: deal.Rotation, net: map[int]int64{}}
+
+	shuffled, err := s.shuffler.Shuffle(shuffle.Deck(handeval.Two))
+	if err != nil {
+		return hand{}, err
+	}
+	order := make([]int, len(deal.Players))
+	for i, p := range deal.Players {
+		order[i] = p.Seat
+	}
+	hole, deck, err := table.DealHoleCards(s.variant, shuffled.Deck, order)
+	if err != nil {
+		return hand{}, err
+	}
+
+	// Chips each seat put in: posts now, and each round's bets as it ends.
+	put := map[int]int64{}
+	for _, p := range deal.Posts {
+		if p.Dead {
+			put[p.Seat] += p.Amount
+		}
+	}
+	players := deal.Players
+	pot := deal.Pot
+	var board []handeval.Card
+	for round := 0; ; round++ {
+		rules := s.betting
+		pos := table.Position{Seats: order, Button: deal.Rotation.Button, LastBlind: -1}
+		first := 0
+		ordered := players
+		if round == 0 {
+			pos.LastBlind = deal.LastBlind
+			rules.MinBet = max(rules.MinBet, deal.Straddle)
+			if first, err = table.FirstToAct(pb.Phase_BettingRound_FOLLOW_BLINDS, pos); err != nil {
+				return hand{}, err
+			}
+			if deal.ButtonStraddled {
+				ordered, first = table.ButtonStraddleOrder(players, first, deal.Rotation.Button), 0
+			}
+		} else if first, err = table.FirstToAct(pb.Phase_BettingRound_LEFT_OF_DEALER, pos); err != nil {
+			return hand{}, err
+		}
+
+		b, err := table.NewBetting(rules, ordered, first, pot)
+		if err != nil {
+			return hand{}, err
+		}
+		for !b.Over() {
+			if err := s.act(ctx, b, hole, board, &h); err != nil {
+				return hand{}, err
+			}
+		}
+		pot = b.Pot()
+		players = b.Players()
+		slices.SortFunc(players, func(a, b table.Player) int { return a.Seat - b.Seat })
+		for i := range players {
+			put[players[i].Seat] += players[i].Bet
+			players[i].Bet = 0
+		}
+
+		if live(players) < 2 || round == len(streets) || canBet(players) < 2 {
+			break
+		}
+		n := streets[round]
+		board = append(board, deck[:n]...)
+		deck = deck[n:]
+	}
+
+	boards := [][]handeval.Card{board}
+	if live(players) > 1 && len(board) < table.BoardSize {
+		// Nobody can bet any more: deal the rest of the board.
+		agreed := map[int]bool{}
+		for _, p := range players {
+			agreed[p.Seat] = true
+		}
+		runs := s.runout.Runs(players, agreed, table.BoardSize-len(board))
+		ro, _, err := table.DealRunout(deck, board, table.BoardSize, runs)
+		if err != nil {
+			return hand{}, err
+		}
+		boards = ro.Boards
+		h.ranTwice = runs > 1
+	}
+	h.flopped = len(boards[0]) >= streets[0]
+
+	var contribs []table.Contribution
+	for _, p := range players {
+		contribs = append(contribs, table.Contribution{Seat: p.Seat, Amount: put[p.Seat], Folded: p.Folded})
+		h.allIn = h.allIn || p.AllIn()
+	}
+	pots, uncalledSeat, uncalled := table.Pots(contribs)
+	won := map[int]int64{}
+	if uncalledSeat >= 0 {
+		won[uncalledSeat] += uncalled
+	}
+	for _, p := range pots {
+		h.pot += p.Amount
+	}
+	pots, h.rake = s.rake.Take(pots, h.flopped)
+
+	showing := map[int][]handeval.Card{}
+	for _, p := range players {
+		if !p.Folded {
+			showing[p.Seat] = hole[p.Seat]
+		}
+	}
+	var winners [][][]int
+	if len(showing) == 1 {
+		// Everyone else folded: the last player takes every pot unseen.
+		var only []int
+		for seat := range showing {
+			only = []int{seat}
+		}
+		run := make([][]int, len(pots))
+		for i := range run {
+			run[i] = only
+		}
+		winners = append(winners, run)
+	} else {
+		h.showdown = true
+		for _, board := range boards {
+			run, err := table.Showdown(s.variant, pots, showing, board)
+			if err != nil {
+				return hand{}, err
+			}
+			winners = append(winners, run)
+		}
+	}
+	events, err := table.AwardRuns(pots, winners, deal.Rotation.Button)
+	if err != nil {
+		return hand{}, err
+	}
+	for seat, amount := range table.Winnings(events) {
+		won[seat] += amount
+	}
+	for _, p := range players {
+		h.net[p.Seat] = won[p.Seat] - put[p.Seat]
+	}
+	return h, nil
+}
+
+// act asks the bot whose turn it is what it does, and does it. A bot whose
+// strategy fails, or picks something it may not do, checks if it can and
+// folds otherwise, as it would at a real table.
+func (s *simulator) act(ctx context.Context, b *table.Betting, hole map[int][]handeval.Card, board []handeval.Card, h *hand) error {
+	opts, _ := b.Options()
+	turn := host.BotTurn{
+		BotID:    host.BotID(opts.Seat + 1),
+		Variant:  s.variant,
+		Hole:     hole[opts.Seat],
+		Board:    board,
+		Options:  opts,
+		Pot:      b.Pot(),
+		BigBlind: s.bigBlind,
+	}
+	for _, p := range b.Players() {
+		turn.Bet = max(turn.Bet, p.Bet)
+		if p.Seat == opts.Seat {
+			turn.Stack = p.Stack
+		}
+		if !p.Folded {
+			turn.Players++
+		}
+	}
+	h.actions++
+	a, err := s.bots[opts.Seat].Act(ctx, turn)
+	if err == nil && b.Act(opts.Seat, a.Action, a.To) == nil {
+		return nil
+	}
+	h.fallbacks++
+	return b.Act(opts.Seat, table.TimeoutAction(opts), 0)
+}
+
+// live counts the players who haven't folded.
+func live(players []table.Player) int {
+	n := 0
+	for _, p := range players {
+		if !p.Folded {
+			n++
+		}
+	}
+	return n
+}
+
+// canBet counts the players who haven't folded and have chips behind.
+func canBet(players []table.Player) int {
+	n := 0
+	for _, p := range players {
+		if !p.Folded && !p.AllIn() {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,100 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/gameserver/bot"
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/lib/shuffle"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+var ctx = context.Background()
+
+func run(t *testing.T, preset string, bots ...host.BotStrategy) Stats {
+	t.Helper()
+	cfg, err := gamedefio.LoadPreset(preset)
+	AssertThat(t, err, Nil())
+	stats, err := Run(ctx, Config{
+		Table:    cfg,
+		Bots:     bots,
+		Hands:    200,
+		Shuffler: shuffle.NewSeeded(shuffle.Seed{1}),
+	})
+	AssertThat(t, err, Nil())
+	return stats
+}
+
+// total is what the table won, which is what the house raked, lost.
+func total(s Stats) int64 {
+	var sum int64
+	for _, n := range s.Net {
+		sum += n
+	}
+	return sum
+}
+
+func TestRun_Callers(t *testing.T) {
+	stats := run(t, "heads-up", bot.Caller{}, bot.Caller{})
+	ExpectEq(t, stats.Hands, 200)
+	ExpectEq(t, stats.BigBlind, int64(100))
+	// Nobody folds, so every hand is shown down over the full board.
+	ExpectEq(t, stats.Flops, 200)
+	ExpectEq(t, stats.Showdowns, 200)
+	ExpectEq(t, stats.AllIns, 0)
+	ExpectEq(t, stats.Fallbacks, 0)
+	ExpectThat(t, stats.Pots, Len(200))
+	for _, p := range stats.Pots {
+		// Each player calls the big blind and checks it down.
+		ExpectEq(t, p, int64(200))
+	}
+	ExpectEq(t, total(stats), -stats.Rake)
+}
+
+func TestRun_Basic(t *testing.T) {
+	bots := []host.BotStrategy{bot.Basic{}, bot.Basic{Raise: 0.6}, bot.Caller{}, bot.Basic{}, bot.Basic{Margin: 0.3}, bot.Basic{Raise: 0.9}}
+	stats := run(t, "6-max", bots...)
+	ExpectEq(t, stats.Hands, 200)
+	ExpectEq(t, stats.Fallbacks, 0)
+	ExpectThat(t, stats.Net, Len(6))
+	ExpectEq(t, total(stats), -stats.Rake)
+	ExpectEq(t, stats.Showdowns > 0 && stats.Showdowns < 200, true)
+
+	// The same seed plays the same hands.
+	again := run(t, "6-max", bots...)
+	ExpectThat(t, again.Net, ElementsAre(stats.Net[0], stats.Net[1], stats.Net[2], stats.Net[3], stats.Net[4], stats.Net[5]))
+	ExpectEq(t, again.Rake, stats.Rake)
+}
+
+type failing struct{}
+
+func (failing) Act(context.Context, host.BotTurn) (host.BotAction, error) {
+	return host.BotAction{}, errors.New("no idea")
+}
+
+func TestRun_Fallback(t *testing.T) {
+	stats := run(t, "heads-up", failing{}, bot.Caller{})
+	ExpectEq(t, stats.Hands, 200)
+	ExpectEq(t, stats.Fallbacks > 0, true)
+	// The failing bot folds whenever it faces a bet, so it loses.
+	ExpectEq(t, stats.Net[0] < 0, true)
+	ExpectEq(t, total(stats), -stats.Rake)
+}
+
+func TestRun_Errors(t *testing.T) {
+	cfg, err := gamedefio.LoadPreset("heads-up")
+	AssertThat(t, err, Nil())
+	_, err = Run(ctx, Config{Table: cfg, Bots: []host.BotStrategy{bot.Caller{}}, Hands: 1})
+	ExpectThat(t, err, ErrorIs(table.ErrNotEnoughPlayers))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	stats, err := Run(canceled, Config{Table: cfg, Bots: []host.BotStrategy{bot.Caller{}, bot.Caller{}}, Hands: 10})
+	ExpectThat(t, err, ErrorIs(context.Canceled))
+	ExpectEq(t, stats.Hands, 0)
+}
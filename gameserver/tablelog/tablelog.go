@@ -0,0 +1,148 @@
+// Package tablelog keeps game server tables' logs in files, so that a
+// server that crashes can rebuild its tables when it restarts.
+package tablelog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+const ext = ".log"
+
+// Dir keeps table logs in a directory, one file per table. Each entry is
+// written with its length before it. It is safe for concurrent use.
+type Dir struct {
+	path string
+
+	mu    sync.Mutex
+	files map[string]*os.File // table ID -> open log
+}
+
+// Open returns the logs in the directory at path, creating it if need be.
+func Open(path string) (*Dir, error) {
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return nil, err
+	}
+	return &Dir{path: path, files: map[string]*os.File{}}, nil
+}
+
+// Append adds an entry to the end of a table's log, starting the log if
+// need be. Entries are written straight to the file rather than buffered,
+// so that they survive the process crashing.
+func (d *Dir) Append(ctx context.Context, tableID string, e *pb.TableLogEntry) error {
+	var b bytes.Buffer
+	if _, err := protodelim.MarshalTo(&b, e); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f, ok := d.files[tableID]
+	if !ok {
+		var err error
+		f, err = os.OpenFile(d.name(tableID), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return err
+		}
+		d.files[tableID] = f
+	}
+	_, err := f.Write(b.Bytes())
+	return err
+}
+
+// Read returns a table's entries in order, or none if it has no log. An
+// entry left half written by a crash is dropped, and cut from the file so
+// that later entries follow the last whole one.
+func (d *Dir) Read(ctx context.Context, tableID string) ([]*pb.TableLogEntry, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	name := d.name(tableID)
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(data)
+	var entries []*pb.TableLogEntry
+	for {
+		// Where the entry starts, and so where the last whole one ends.
+		whole := r.Size() - int64(r.Len())
+		e := &pb.TableLogEntry{}
+		err := protodelim.UnmarshalFrom(r, e)
+		if err == io.EOF {
+			return entries, nil
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return entries, os.Truncate(name, whole)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("table %s log entry %d: %w", tableID, len(entries), err)
+		}
+		entries = append(entries, e)
+	}
+}
+
+// Tables returns the IDs of the tables with logs.
+func (d *Dir) Tables(ctx context.Context) ([]string, error) {
+	files, err := os.ReadDir(d.path)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, f := range files {
+		name, ok := strings.CutSuffix(f.Name(), ext)
+		if !ok || f.IsDir() {
+			continue
+		}
+		id, err := url.PathUnescape(name)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Remove deletes a table's log, once the table has closed.
+func (d *Dir) Remove(ctx context.Context, tableID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if f, ok := d.files[tableID]; ok {
+		f.Close()
+		delete(d.files, tableID)
+	}
+	if err := os.Remove(d.name(tableID)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Close closes the logs' files. Logs stay on disk to be read after.
+func (d *Dir) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var errs []error
+	for id, f := range d.files {
+		errs = append(errs, f.Close())
+		delete(d.files, id)
+	}
+	return errors.Join(errs...)
+}
+
+func (d *Dir) name(tableID string) string {
+	return filepath.Join(d.path, url.PathEscape(tableID)+ext)
+}
@@ -0,0 +1,93 @@
+package tablelog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+var ctx = context.Background()
+
+func stackSet(playerID string, chips int64) *pb.TableLogEntry {
+	return pb.TableLogEntry_builder{
+		StackSet: pb.Stack_builder{PlayerId: proto.String(playerID), Chips: proto.Int64(chips)}.Build(),
+	}.Build()
+}
+
+func TestDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tables")
+	d, err := Open(path)
+	AssertThat(t, err, Nil())
+	defer d.Close()
+
+	AssertThat(t, d.Append(ctx, "m1", stackSet("alice", 100)), Nil())
+	AssertThat(t, d.Append(ctx, "m/2", stackSet("bob", 200)), Nil())
+	AssertThat(t, d.Append(ctx, "m1", stackSet("alice", 300)), Nil())
+
+	tables, err := d.Tables(ctx)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, tables, ElementsAre("m/2", "m1"))
+
+	entries, err := d.Read(ctx, "m1")
+	AssertThat(t, err, Nil())
+	AssertThat(t, entries, Len(2))
+	ExpectEq(t, entries[0].GetStackSet().GetChips(), int64(100))
+	ExpectEq(t, entries[1].GetStackSet().GetChips(), int64(300))
+
+	entries, err = d.Read(ctx, "none")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, entries, Empty())
+
+	AssertThat(t, d.Remove(ctx, "m1"), Nil())
+	AssertThat(t, d.Remove(ctx, "m1"), Nil())
+	tables, err = d.Tables(ctx)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, tables, ElementsAre("m/2"))
+}
+
+func TestDir_Reopen(t *testing.T) {
+	path := t.TempDir()
+	d, err := Open(path)
+	AssertThat(t, err, Nil())
+	AssertThat(t, d.Append(ctx, "m1", stackSet("alice", 100)), Nil())
+	AssertThat(t, d.Close(), Nil())
+
+	d, err = Open(path)
+	AssertThat(t, err, Nil())
+	defer d.Close()
+	AssertThat(t, d.Append(ctx, "m1", stackSet("alice", 200)), Nil())
+	entries, err := d.Read(ctx, "m1")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, entries, Len(2))
+}
+
+func TestDir_HalfWritten(t *testing.T) {
+	path := t.TempDir()
+	d, err := Open(path)
+	AssertThat(t, err, Nil())
+	defer d.Close()
+	AssertThat(t, d.Append(ctx, "m1", stackSet("alice", 100)), Nil())
+
+	// The server crashed partway through writing the second entry.
+	f, err := os.OpenFile(filepath.Join(path, "m1.log"), os.O_WRONLY|os.O_APPEND, 0)
+	AssertThat(t, err, Nil())
+	_, err = f.Write([]byte{20, 1, 2})
+	AssertThat(t, err, Nil())
+	f.Close()
+
+	entries, err := d.Read(ctx, "m1")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, entries, Len(1))
+
+	AssertThat(t, d.Append(ctx, "m1", stackSet("alice", 200)), Nil())
+	entries, err = d.Read(ctx, "m1")
+	AssertThat(t, err, Nil())
+	AssertThat(t, entries, Len(2))
+	ExpectEq(t, entries[1].GetStackSet().GetChips(), int64(200))
+}
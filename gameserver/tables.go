@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jfmatt/flagr"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+func TablesCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "tables",
+		Short: "List, pause, resume and close the tables on a running game server",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the server's open tables",
+	}
+	listCmd.RunE = flagr.Run(listCmd, ListTables)
+	c.AddCommand(listCmd)
+
+	pauseCmd := &cobra.Command{
+		Use:   "pause TABLE_ID",
+		Short: "Stop play at a table",
+		Args:  cobra.ExactArgs(1),
+	}
+	pauseCmd.RunE = flagr.Run(pauseCmd, PauseTable)
+	c.AddCommand(pauseCmd)
+
+	resumeCmd := &cobra.Command{
+		Use:   "resume TABLE_ID",
+		Short: "Restart play at a paused table",
+		Args:  cobra.ExactArgs(1),
+	}
+	resumeCmd.RunE = flagr.Run(resumeCmd, ResumeTable)
+	c.AddCommand(resumeCmd)
+
+	closeCmd := &cobra.Command{
+		Use:   "close TABLE_ID",
+		Short: "Close a table and return its players' chips",
+		Args:  cobra.ExactArgs(1),
+	}
+	closeCmd.RunE = flagr.Run(closeCmd, CloseTable)
+	c.AddCommand(closeCmd)
+
+	return c
+}
+
+// dialTables connects to a game server's table service, returning a context
+// that carries the admin token.
+func dialTables(ctx context.Context, server, adminToken string) (pb.TableServiceClient, context.Context, func(), error) {
+	conn, err := grpc.NewClient(server, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+adminToken)
+	return pb.NewTableServiceClient(conn), ctx, func() { conn.Close() }, nil
+}
+
+type ListTablesArgs struct {
+	Server     string `flag:"server,default=localhost:7001,help=Address of the game server's gRPC table service, as host:port"`
+	AdminToken string `flag:"admin-token,required,help=The game server's admin token"`
+}
+
+func ListTables(flags *ListTablesArgs, cmd *cobra.Command, args []string) error {
+	client, ctx, done, err := dialTables(cmd.Context(), flags.Server, flags.AdminToken)
+	if err != nil {
+		return err
+	}
+	defer done()
+	resp, err := client.ListTables(ctx, &pb.ListTablesRequest{})
+	if err != nil {
+		return err
+	}
+	for _, t := range resp.GetTables() {
+		printTable(cmd.OutOrStdout(), t)
+	}
+	return nil
+}
+
+type PauseTableArgs struct {
+	Server     string `flag:"server,default=localhost:7001,help=Address of the game server's gRPC table service, as host:port"`
+	AdminToken string `flag:"admin-token,required,help=The game server's admin token"`
+	Reason     string `flag:"reason,help=Why the table is paused, shown to its players"`
+}
+
+func PauseTable(flags *PauseTableArgs, cmd *cobra.Command, args []string) error {
+	client, ctx, done, err := dialTables(cmd.Context(), flags.Server, flags.AdminToken)
+	if err != nil {
+		return err
+	}
+	defer done()
+	t, err := client.PauseTable(ctx, pb.PauseTableRequest_builder{
+		TableId: proto.String(args[0]),
+		Reason:  proto.String(flags.Reason),
+	}.Build())
+	if err != nil {
+		return err
+	}
+	printTable(cmd.OutOrStdout(), t)
+	return nil
+}
+
+type ResumeTableArgs struct {
+	Server     string `flag:"server,default=localhost:7001,help=Address of the game server's gRPC table service, as host:port"`
+	AdminToken string `flag:"admin-token,required,help=The game server's admin token"`
+}
+
+func ResumeTable(flags *ResumeTableArgs, cmd *cobra.Command, args []string) error {
+	client, ctx, done, err := dialTables(cmd.Context(), flags.Server, flags.AdminToken)
+	if err != nil {
+		return err
+	}
+	defer done()
+	t, err := client.ResumeTable(ctx, pb.ResumeTableRequest_builder{TableId: proto.String(args[0])}.Build())
+	if err != nil {
+		return err
+	}
+	printTable(cmd.OutOrStdout(), t)
+	return nil
+}
+
+type CloseTableArgs struct {
+	Server     string `flag:"server,default=localhost:7001,help=Address of the game server's gRPC table service, as host:port"`
+	AdminToken string `flag:"admin-token,required,help=The game server's admin token"`
+	Reason     string `flag:"reason,help=Why the table closed, shown to its players"`
+}
+
+func CloseTable(flags *CloseTableArgs, cmd *cobra.Command, args []string) error {
+	client, ctx, done, err := dialTables(cmd.Context(), flags.Server, flags.AdminToken)
+	if err != nil {
+		return err
+	}
+	defer done()
+	resp, err := client.CloseTable(ctx, pb.CloseTableRequest_builder{
+		TableId: proto.String(args[0]),
+		Reason:  proto.String(flags.Reason),
+	}.Build())
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "closed %s\n", args[0])
+	for _, s := range resp.GetReturned() {
+		fmt.Fprintf(out, "  returned %d chips to %s\n", s.GetChips(), s.GetPlayerId())
+	}
+	return nil
+}
+
+func printTable(out io.Writer, t *pb.TableInfo) {
+	state := "playing"
+	if t.GetPaused() {
+		state = "paused"
+	}
+	var stacks []string
+	for _, s := range t.GetStacks() {
+		stacks = append(stacks, fmt.Sprintf("%s=%d", s.GetPlayerId(), s.GetChips()))
+	}
+	fmt.Fprintf(out, "%s  %-10s %-8s %d/%d connected  %s\n", t.GetTableId(), t.GetGameMode(), state,
+		len(t.GetConnectedPlayerIds()), len(t.GetPlayerIds()), strings.Join(stacks, " "))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// auditEntry is the matchmaker's record of an admin action.
+type auditEntry struct {
+	ID     string          `json:"id"`
+	Time   time.Time       `json:"time"`
+	Actor  string          `json:"actor"`
+	Action string          `json:"action"`
+	Target string          `json:"target"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+type auditPage struct {
+	Entries       []auditEntry `json:"entries"`
+	NextPageToken string       `json:"next_page_token"`
+}
+
+func printAuditEntry(out io.Writer, e auditEntry) {
+	fmt.Fprintf(out, "%s  %-16s %-22s %s\n", e.Time.Local().Format(time.DateTime), e.Actor, e.Action, e.Target)
+	if len(e.Before) > 0 {
+		fmt.Fprintf(out, "    before: %s\n", e.Before)
+	}
+	if len(e.After) > 0 {
+		fmt.Fprintf(out, "    after:  %s\n", e.After)
+	}
+}
+
+func auditCmd() *cobra.Command {
+	var (
+		svc         userService
+		actor       string
+		action      string
+		target      string
+		limit       int
+		oldestFirst bool
+	)
+	c := &cobra.Command{
+		Use:   "audit",
+		Short: "Review the log of admin and moderator actions",
+		Long: `Lists the matchmaker's audit log of what admins and moderators have done,
+such as bans, role changes, config changes and tables closed through game
+servers' admin tokens, with what each target was like before and after.
+Newest actions come first unless --oldest-first is given. Only admins may
+read the log; sign in with gocli login, or --username and --password.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if limit <= 0 {
+				return fmt.Errorf("--limit must be positive")
+			}
+			mm, err := signIn(cmd.Context(), strings.TrimSuffix(svc.matchmaker, "/"), svc.username, svc.password)
+			if err != nil {
+				return err
+			}
+			q := url.Values{}
+			for name, v := range map[string]string{"actor": actor, "action": action, "target": target} {
+				if v != "" {
+					q.Set(name, v)
+				}
+			}
+			if oldestFirst {
+				q.Set("sort", "time")
+			}
+			var entries []auditEntry
+			for len(entries) < limit {
+				q.Set("page_size", strconv.Itoa(min(limit-len(entries), 100)))
+				var page auditPage
+				if err := mm.Do(cmd.Context(), http.MethodGet, "/v1/admin/audit?"+q.Encode(), nil, &page); err != nil {
+					return err
+				}
+				entries = append(entries, page.Entries...)
+				if page.NextPageToken == "" {
+					break
+				}
+				q.Set("page_token", page.NextPageToken)
+			}
+			return render(cmd, entries, func(out io.Writer) {
+				for _, e := range entries {
+					printAuditEntry(out, e)
+				}
+			})
+		},
+	}
+	c.Flags().StringVar(&svc.matchmaker, "matchmaker", "http://localhost:8080", "Base URL of the matchmaker's HTTP API")
+	c.Flags().StringVar(&svc.username, "username", "", "Admin to sign in as; the one signed in with gocli login if unset")
+	c.Flags().StringVar(&svc.password, "password", os.Getenv("SNAPFOLD_PASSWORD"), "The account's password; defaults to $SNAPFOLD_PASSWORD")
+	c.Flags().StringVar(&actor, "actor", "", "Only actions taken by this account, or game server admin token")
+	c.Flags().StringVar(&action, "action", "", "Only actions of this kind, such as account.ban or table.close")
+	c.Flags().StringVar(&target, "target", "", "Only actions on this account, config (kind/name), webhook, API key or table")
+	c.Flags().IntVar(&limit, "limit", 50, "Most actions to list")
+	c.Flags().BoolVar(&oldestFirst, "oldest-first", false, "List the oldest actions first")
+	return c
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+)
+
+// completionTimeout bounds how long completions wait on a server, so that
+// a slow or unreachable one doesn't hang the shell.
+const completionTimeout = 2 * time.Second
+
+// completeArg returns a completion function for a command's first
+// argument that offers what list returns.
+func completeArg(list func(ctx context.Context) ([]string, error)) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeNow(cmd, toComplete, list)
+	}
+}
+
+// completeFlag returns a completion function for a flag that offers what
+// list returns.
+func completeFlag(list func(ctx context.Context) ([]string, error)) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return completeNow(cmd, toComplete, list)
+	}
+}
+
+// completeNow offers what list returns that starts with toComplete. list
+// is called with the chosen profile's flags applied, since completions skip
+// the hook that applies them to commands. Errors are only logged for
+// cobra's completion debugging, leaving nothing offered.
+func completeNow(cmd *cobra.Command, toComplete string, list func(ctx context.Context) ([]string, error)) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if err := applyProfile(cmd); err != nil {
+		cobra.CompDebugln(err.Error(), false)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), completionTimeout)
+	defer cancel()
+	all, err := list(ctx)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), false)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var matches []cobra.Completion
+	for _, s := range all {
+		if strings.HasPrefix(s, toComplete) {
+			matches = append(matches, s)
+		}
+	}
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
+
+// tables lists the game server's open tables.
+func (s *tableService) tables(ctx context.Context) ([]*pb.TableInfo, error) {
+	client, ctx, done, err := s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	resp, err := client.ListTables(ctx, &pb.ListTablesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetTables(), nil
+}
+
+// tableIDs lists the IDs of the game server's open tables.
+func (s *tableService) tableIDs(ctx context.Context) ([]string, error) {
+	tables, err := s.tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(tables))
+	for i, t := range tables {
+		ids[i] = t.GetTableId()
+	}
+	return ids, nil
+}
+
+// playerIDs lists the players seated at the game server's tables, who are
+// the ones its operators most often need to name.
+func (s *tableService) playerIDs(ctx context.Context) ([]string, error) {
+	tables, err := s.tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, t := range tables {
+		ids = append(ids, t.GetPlayerIds()...)
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
+
+// presetNames lists the built-in presets.
+func presetNames(context.Context) ([]string, error) {
+	names := make([]string, len(gamedefio.Presets))
+	for i, p := range gamedefio.Presets {
+		names[i] = p.Name
+	}
+	return names, nil
+}
+
+// profileNames lists the profiles in the config file.
+func profileNames(context.Context) ([]string, error) {
+	cfg, _, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(cfg.Profiles)), nil
+}
+
+// completePrefixed completes an argument that is a file, or prefix
+// followed by a name that list returns, such as registry:NAME. Files are
+// left to the shell.
+func completePrefixed(prefix string, list func(ctx context.Context) ([]string, error)) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		name, ok := strings.CutPrefix(toComplete, prefix)
+		if !ok {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		names, directive := completeNow(cmd, name, list)
+		for i, n := range names {
+			names[i] = prefix + n
+		}
+		return names, directive
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/client"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/lib/jsonschema"
+)
+
+const registryPrefix = "registry:"
+
+// gamedefPackage is the proto package of the gamedef messages.
+const gamedefPackage = "snapfold.gamedef"
+
+// configSource reads configs from files, or from the matchmaker's config
+// registry.
+type configSource struct {
+	kind       string
+	matchmaker string
+	token      string
+}
+
+func gamedefCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "gamedef",
+		Short: "Work with TableConfig and MatchRules files",
+	}
+
+	src := &configSource{}
+	diff := &cobra.Command{
+		Use:   "diff OLD NEW",
+		Short: "Show the fields that differ between two configs",
+		Long: `Show the fields that differ between two configs, one per line:
+"+" for fields only NEW sets, "-" for fields only OLD sets, and "~" for
+fields whose value changed.
+
+Each config is a file, or registry:NAME for the config of that name in the
+matchmaker's config registry, whose names the shell completes after
+registry:. TableConfigs are compared with their defaults filled in, as
+servers use them.`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completePrefixed(registryPrefix, src.names),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			old, err := src.load(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			new, err := src.load(cmd.Context(), args[1])
+			if err != nil {
+				return err
+			}
+			changes := gamedefio.Diff(old, new)
+			if changes == nil {
+				changes = []gamedefio.Change{}
+			}
+			return render(cmd, changes, func(out io.Writer) {
+				if len(changes) == 0 {
+					fmt.Fprintln(out, "no changes")
+				}
+				for _, ch := range changes {
+					fmt.Fprintln(out, ch)
+				}
+			})
+		},
+	}
+	diff.Flags().StringVar(&src.kind, "kind", "table-configs", "Kind of config: table-configs or match-rules")
+	diff.Flags().StringVar(&src.matchmaker, "matchmaker", "", "Base URL of the matchmaker, for registry: configs")
+	diff.Flags().StringVar(&src.token, "token", os.Getenv("SNAPFOLD_API_KEY"), "API key with the configs scope, for registry: configs; defaults to $SNAPFOLD_API_KEY")
+	c.AddCommand(diff)
+
+	var out string
+	schema := &cobra.Command{
+		Use:   "schema [MESSAGE]",
+		Short: "Print the JSON Schema of a gamedef message",
+		Long: `Print the JSON Schema of a gamedef message, TableConfig unless another is
+named, such as MatchRules or TournamentStructure. Configs written in JSON or
+YAML can be checked against it before they are loaded.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := "TableConfig"
+			if len(args) > 0 {
+				name = args[0]
+			}
+			if !strings.Contains(name, ".") {
+				name = gamedefPackage + "." + name
+			}
+			d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+			if err != nil {
+				return fmt.Errorf("unknown message %s: %w", name, err)
+			}
+			md, ok := d.(protoreflect.MessageDescriptor)
+			if !ok {
+				return fmt.Errorf("%s is not a message", name)
+			}
+			schema := jsonschema.For(md)
+			b, err := schema.MarshalIndent()
+			if err != nil {
+				return err
+			}
+			// The schema is JSON already, so that is how tables show it.
+			human := func(w io.Writer) { fmt.Fprintf(w, "%s\n", b) }
+			if out == "" {
+				return render(cmd, schema, human)
+			}
+			f, err := os.Create(out)
+			if err != nil {
+				return err
+			}
+			if err := renderTo(cmd, f, schema, human); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		},
+	}
+	schema.Flags().StringVarP(&out, "out", "o", "", "File to write the schema to, instead of stdout")
+	c.AddCommand(schema)
+
+	return c
+}
+
+// newConfig returns an empty config of the source's kind.
+func (s *configSource) newConfig() (proto.Message, error) {
+	switch s.kind {
+	case "table-configs":
+		return &pb.TableConfig{}, nil
+	case "match-rules":
+		return &pb.MatchRules{}, nil
+	}
+	return nil, fmt.Errorf("unknown config kind %q", s.kind)
+}
+
+// load reads the config at a path, or registry:NAME.
+func (s *configSource) load(ctx context.Context, path string) (proto.Message, error) {
+	if name, ok := strings.CutPrefix(path, registryPrefix); ok {
+		return s.fetch(ctx, name)
+	}
+	if s.kind == "table-configs" {
+		f, err := gamedefio.FormatFor(path)
+		if err != nil {
+			return nil, err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg, err := gamedefio.Parse(b, f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		gamedefio.SetDefaults(cfg)
+		return cfg, nil
+	}
+	m, err := s.newConfig()
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := prototext.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// fetch gets the config with the name from the matchmaker's registry.
+func (s *configSource) fetch(ctx context.Context, name string) (proto.Message, error) {
+	m, err := s.newConfig()
+	if err != nil {
+		return nil, err
+	}
+	if s.matchmaker == "" {
+		return nil, fmt.Errorf("--matchmaker is needed to read %s%s", registryPrefix, name)
+	}
+	b, err := client.New(s.matchmaker).Config(ctx, s.token, s.kind, name)
+	if err != nil {
+		return nil, err
+	}
+	cfg, isTable := m.(*pb.TableConfig)
+	if isTable {
+		if b, err = gamedefio.UpgradeJSON(b); err != nil {
+			return nil, fmt.Errorf("%s%s: %w", registryPrefix, name, err)
+		}
+	}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("%s%s: %w", registryPrefix, name, err)
+	}
+	if isTable {
+		gamedefio.SetDefaults(cfg)
+	}
+	return m, nil
+}
+
+// names lists the names of the configs of the source's kind in the
+// matchmaker's registry.
+func (s *configSource) names(ctx context.Context) ([]string, error) {
+	if s.matchmaker == "" {
+		return nil, fmt.Errorf("--matchmaker is needed to list configs")
+	}
+	entries, err := client.New(s.matchmaker).Configs(ctx, s.token, s.kind)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name
+	}
+	return names, nil
+}
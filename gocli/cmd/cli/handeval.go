@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/handeval"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+// handRules are the rules handeval --rules can name.
+var handRules = map[string]handeval.Rules{
+	"standard":                 handeval.Standard,
+	"short-deck":               handeval.ShortDeck,
+	"short-deck-high-straight": handeval.ShortDeckHighStraight,
+	"ace-to-five":              handeval.AceToFive,
+	"ace-to-six":               handeval.AceToSix,
+	"deuce-to-seven":           handeval.DeuceToSeven,
+}
+
+func handevalCmd() *cobra.Command {
+	var (
+		board     string
+		game      string
+		rules     string
+		qualifier string
+		batch     bool
+	)
+	c := &cobra.Command{
+		Use:   "handeval [HAND...]",
+		Short: "Rank poker hands and say which win",
+		Long: `Ranks hands and says which win. The hands are given as arguments, with
+the board as --board, or else read from stdin a line at a time, written as
+hands separated by "|" and then the board after "/":
+
+  As Ks | Qh Qd / Ah 7c 2d 9s 3h
+
+Cards are written as in "As", "Td" or "10♦". Blank lines and lines starting
+with "#" are skipped.
+
+Without --game, each hand is the best five of its cards and the board under
+--rules. With --game, hands are hole cards judged as that variant of the
+table judges them at showdown, against a full board.
+
+--batch is for pipelines: it writes a line for each line read as soon as it
+is judged, the winners and then each hand tab-separated, or JSON Lines with
+--output json. Lines that can't be judged are reported in place and make
+the command fail once every line is read.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			j, err := newHandJudge(game, rules, qualifier)
+			if err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				if batch {
+					return fmt.Errorf("--batch reads hands from stdin, not arguments")
+				}
+				line := strings.Join(args, " | ")
+				if board != "" {
+					line += " / " + board
+				}
+				r, err := j.judge(line)
+				if err != nil {
+					return err
+				}
+				return render(cmd, r, r.print)
+			}
+			if board != "" {
+				return fmt.Errorf("--board is for hands given as arguments; on stdin, put the board after \"/\"")
+			}
+			if batch {
+				return j.batch(cmd)
+			}
+			var results []handResult
+			err = eachHandLine(cmd.InOrStdin(), func(n int, line string) error {
+				r, err := j.judge(line)
+				if err != nil {
+					return fmt.Errorf("line %d: %w", n, err)
+				}
+				results = append(results, r)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			return render(cmd, results, func(out io.Writer) {
+				for i, r := range results {
+					if i > 0 {
+						fmt.Fprintln(out)
+					}
+					r.print(out)
+				}
+			})
+		},
+	}
+	c.Flags().StringVar(&board, "board", "", "Board cards, for hands given as arguments")
+	c.Flags().StringVar(&game, "game", "", "Variant to judge hole cards as: holdem, omaha or snapfold")
+	c.Flags().StringVar(&rules, "rules", "standard", "Hand rankings, without --game: "+strings.Join(slices.Sorted(maps.Keys(handRules)), ", "))
+	c.Flags().StringVar(&qualifier, "qualifier", "", "Highest card a low hand may have to qualify, such as 8; for lowball --rules")
+	c.Flags().BoolVar(&batch, "batch", false, "Read lines from stdin and write a result line for each, for pipelines")
+	return c
+}
+
+// handJudge ranks the hands on a line.
+type handJudge struct {
+	// If set, hands are hole cards that the variant judges. Otherwise each
+	// hand is evaluated with the board by eval.
+	variant table.Variant
+	eval    *handeval.Evaluator
+}
+
+func newHandJudge(game, rules, qualifier string) (handJudge, error) {
+	if game != "" {
+		if rules != "standard" || qualifier != "" {
+			return handJudge{}, fmt.Errorf("--game judges hands by its own rules; --rules and --qualifier are for evaluating hands without one")
+		}
+		v, ok := pb.TableConfig_Variant_value[strings.ToUpper(game)]
+		if !ok || v == int32(pb.TableConfig_GAME_UNKNOWN) {
+			return handJudge{}, fmt.Errorf("unknown game %q", game)
+		}
+		variant, err := table.VariantFor(pb.TableConfig_builder{Variant: pb.TableConfig_Variant(v).Enum()}.Build())
+		if err != nil {
+			return handJudge{}, err
+		}
+		return handJudge{variant: variant}, nil
+	}
+	r, ok := handRules[rules]
+	if !ok {
+		return handJudge{}, fmt.Errorf("unknown rules %q", rules)
+	}
+	if qualifier != "" {
+		q, err := handeval.ParseRank(qualifier)
+		if err != nil {
+			return handJudge{}, err
+		}
+		r.Qualifier = q
+	}
+	eval, err := handeval.New(r)
+	if err != nil {
+		return handJudge{}, err
+	}
+	return handJudge{eval: eval}, nil
+}
+
+// handResult is how the hands on a line ranked.
+type handResult struct {
+	Hands []rankedHand `json:"hands"`
+	Board string       `json:"board,omitempty"`
+
+	// Numbers of the winning hands, from 1: more than one if they tie, and
+	// none if no hand qualifies.
+	Winners []int `json:"winners"`
+}
+
+type rankedHand struct {
+	Cards     string `json:"cards"`
+	Category  string `json:"category"`
+	Best      string `json:"best"`
+	Qualified bool   `json:"qualified"`
+
+	// 1 for the best hand, 2 for the next best and so on. Tied hands share
+	// a place.
+	Place int `json:"place"`
+}
+
+// judge ranks the hands on a line.
+func (j handJudge) judge(line string) (handResult, error) {
+	handsPart, boardPart, _ := strings.Cut(line, "/")
+	board, err := handeval.ParseCards(boardPart)
+	if err != nil {
+		return handResult{}, fmt.Errorf("board: %w", err)
+	}
+	seen := map[handeval.Card]bool{}
+	for _, c := range board {
+		seen[c] = true
+	}
+
+	var r handResult
+	var hands []handeval.Hand
+	for i, s := range strings.Split(handsPart, "|") {
+		cards, err := handeval.ParseCards(s)
+		if err != nil {
+			return handResult{}, fmt.Errorf("hand %d: %w", i+1, err)
+		}
+		if len(cards) == 0 {
+			return handResult{}, fmt.Errorf("hand %d has no cards", i+1)
+		}
+		for _, c := range cards {
+			if seen[c] {
+				return handResult{}, fmt.Errorf("hand %d: %w: %s", i+1, handeval.ErrDuplicateCard, c)
+			}
+			seen[c] = true
+		}
+		var h handeval.Hand
+		if j.variant != nil {
+			h, err = j.variant.Best(cards, board)
+		} else {
+			h, err = j.eval.Evaluate(slices.Concat(cards, board))
+		}
+		if err != nil {
+			return handResult{}, fmt.Errorf("hand %d: %w", i+1, err)
+		}
+		hands = append(hands, h)
+		r.Hands = append(r.Hands, rankedHand{
+			Cards:     handeval.FormatCards(cards),
+			Category:  h.Category.String(),
+			Best:      handeval.FormatCards(h.Cards),
+			Qualified: h.Qualified,
+		})
+	}
+	for i, h := range hands {
+		r.Hands[i].Place = 1
+		for _, o := range hands {
+			if o.Compare(h) > 0 {
+				r.Hands[i].Place++
+			}
+		}
+	}
+	r.Board = handeval.FormatCards(board)
+	r.Winners = []int{}
+	for _, w := range handeval.Winners(hands) {
+		r.Winners = append(r.Winners, w+1)
+	}
+	return r, nil
+}
+
+func (r handResult) print(out io.Writer) {
+	if r.Board != "" {
+		fmt.Fprintf(out, "board: %s\n", r.Board)
+	}
+	for i, h := range r.Hands {
+		note := ""
+		switch {
+		case slices.Contains(r.Winners, i+1):
+			note = "  wins"
+		case !h.Qualified:
+			note = "  doesn't qualify"
+		}
+		fmt.Fprintf(out, "%d  %-16s %-16s %s%s\n", i+1, h.Cards, h.Category, h.Best, note)
+	}
+}
+
+// line writes the result as one tab-separated line: the winners, or "-"
+// if there are none, and then each hand.
+func (r handResult) line() string {
+	winners := make([]string, len(r.Winners))
+	for i, w := range r.Winners {
+		winners[i] = strconv.Itoa(w)
+	}
+	fields := []string{cmp.Or(strings.Join(winners, ","), "-")}
+	for _, h := range r.Hands {
+		fields = append(fields, h.Category+": "+h.Best)
+	}
+	return strings.Join(fields, "\t")
+}
+
+// batch judges each line of stdin and writes a line for it.
+func (j handJudge) batch(cmd *cobra.Command) error {
+	format, _ := cmd.Flags().GetString("output")
+	if format == outputYAML {
+		return fmt.Errorf("--batch writes --output table or json")
+	}
+	// Results are written as each line is judged, so that pipelines can
+	// read them as they come.
+	out := cmd.OutOrStdout()
+	enc := json.NewEncoder(out)
+	failed := 0
+	err := eachHandLine(cmd.InOrStdin(), func(n int, line string) error {
+		r, err := j.judge(line)
+		switch {
+		case format == outputJSON && err != nil:
+			enc.Encode(struct {
+				Line  int    `json:"line"`
+				Error string `json:"error"`
+			}{n, err.Error()})
+		case format == outputJSON:
+			enc.Encode(struct {
+				Line int `json:"line"`
+				handResult
+			}{n, r})
+		case err != nil:
+			fmt.Fprintf(out, "error\tline %d: %v\n", n, err)
+		default:
+			fmt.Fprintln(out, r.line())
+		}
+		if err != nil {
+			failed++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("couldn't judge %d of the lines", failed)
+	}
+	return nil
+}
+
+// eachHandLine calls f with each line of in that isn't blank or a comment,
+// numbered from 1.
+func eachHandLine(in io.Reader, f func(n int, line string) error) error {
+	s := bufio.NewScanner(in)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := f(n, line); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
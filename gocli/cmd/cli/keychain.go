@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+var (
+	// errNoSession is returned by tokenStores that hold no session for a
+	// matchmaker.
+	errNoSession = errors.New("not signed in")
+
+	// errNoKeychain is returned when the OS keychain can't be used, either
+	// because gocli doesn't support the OS's or its tool isn't installed.
+	errNoKeychain = errors.New("no OS keychain")
+)
+
+// keychainService is the service that sessions are filed under in the
+// OS keychain, with the matchmaker's URL as the account.
+const keychainService = "snapfold"
+
+// tokenStore keeps the sessions that gocli login signs in to between
+// commands, one for each matchmaker.
+type tokenStore interface {
+	load(ctx context.Context, matchmaker string) (*loginSession, error)
+	save(ctx context.Context, s *loginSession) error
+	remove(ctx context.Context, matchmaker string) error
+}
+
+// keychainStore keeps sessions in the OS keychain, through the security
+// tool on macOS and libsecret's secret-tool elsewhere.
+type keychainStore struct{}
+
+func (keychainStore) tool() (string, error) {
+	name := "secret-tool"
+	switch runtime.GOOS {
+	case "darwin":
+		name = "security"
+	case "windows":
+		return "", fmt.Errorf("%w: gocli doesn't support the Windows Credential Manager", errNoKeychain)
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errNoKeychain, err)
+	}
+	return path, nil
+}
+
+func (k keychainStore) run(ctx context.Context, stdin string, args ...string) ([]byte, error) {
+	tool, err := k.tool()
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, tool, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", filepath.Base(tool), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func (k keychainStore) load(ctx context.Context, matchmaker string) (*loginSession, error) {
+	var args []string
+	if runtime.GOOS == "darwin" {
+		args = []string{"find-generic-password", "-s", keychainService, "-a", matchmaker, "-w"}
+	} else {
+		args = []string{"lookup", "service", keychainService, "account", matchmaker}
+	}
+	out, err := k.run(ctx, "", args...)
+	if errors.Is(err, errNoKeychain) {
+		return nil, err
+	}
+	// Both tools fail when there is no such item, without a way to tell
+	// that apart from other failures.
+	if err != nil || len(bytes.TrimSpace(out)) == 0 {
+		return nil, errNoSession
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, fmt.Errorf("keychain: %w", err)
+	}
+	s := &loginSession{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("keychain: %w", err)
+	}
+	return s, nil
+}
+
+func (k keychainStore) save(ctx context.Context, s *loginSession) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	// Encoded so that the secret needs no quoting, and passed on stdin so
+	// that it never appears in a command line.
+	secret := base64.StdEncoding.EncodeToString(b)
+	if runtime.GOOS == "darwin" {
+		cmd := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", keychainService, strconv.Quote(s.Matchmaker), secret)
+		_, err = k.run(ctx, cmd, "-i")
+	} else {
+		_, err = k.run(ctx, secret, "store", "--label", "snapfold session for "+s.Matchmaker, "service", keychainService, "account", s.Matchmaker)
+	}
+	return err
+}
+
+func (k keychainStore) remove(ctx context.Context, matchmaker string) error {
+	var err error
+	if runtime.GOOS == "darwin" {
+		_, err = k.run(ctx, "", "delete-generic-password", "-s", keychainService, "-a", matchmaker)
+	} else {
+		_, err = k.run(ctx, "", "clear", "service", keychainService, "account", matchmaker)
+	}
+	return err
+}
+
+// fileStore keeps sessions in plain text in a file only the user can read,
+// for machines without a keychain.
+type fileStore struct {
+	path string
+}
+
+// credentialsFile returns the fileStore next to the config file.
+func credentialsFile() (fileStore, error) {
+	path, err := configPath()
+	if err != nil {
+		return fileStore{}, err
+	}
+	return fileStore{path: filepath.Join(filepath.Dir(path), "credentials.json")}, nil
+}
+
+func (f fileStore) read() (map[string]*loginSession, error) {
+	sessions := map[string]*loginSession{}
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return sessions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &sessions); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.path, err)
+	}
+	return sessions, nil
+}
+
+func (f fileStore) write(sessions map[string]*loginSession) error {
+	if len(sessions) == 0 {
+		err := os.Remove(f.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	b, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, b, 0o600)
+}
+
+func (f fileStore) load(_ context.Context, matchmaker string) (*loginSession, error) {
+	sessions, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	s, ok := sessions[matchmaker]
+	if !ok {
+		return nil, errNoSession
+	}
+	return s, nil
+}
+
+func (f fileStore) save(_ context.Context, s *loginSession) error {
+	sessions, err := f.read()
+	if err != nil {
+		return err
+	}
+	sessions[s.Matchmaker] = s
+	return f.write(sessions)
+}
+
+func (f fileStore) remove(_ context.Context, matchmaker string) error {
+	sessions, err := f.read()
+	if err != nil {
+		return err
+	}
+	delete(sessions, matchmaker)
+	return f.write(sessions)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jfmatt/snapfold/lib/client"
+)
+
+// loginSession is a player's session with a matchmaker, as gocli login keeps
+// it.
+type loginSession struct {
+	Matchmaker string `json:"matchmaker"`
+	client.Session
+
+	// Where refreshed tokens are saved, if the session was stored.
+	store tokenStore
+}
+
+// passwordLogin signs in to a matchmaker with a password.
+func passwordLogin(ctx context.Context, matchmaker, username, password string) (*client.Client, error) {
+	mm := client.New(matchmaker)
+	if _, err := mm.Login(ctx, username, password); err != nil {
+		return nil, err
+	}
+	return mm, nil
+}
+
+// resume returns a client for the session's matchmaker, signed in with it.
+// Each refresh token may be used once, so the tokens the client refreshes
+// it with are kept in its place.
+func (s *loginSession) resume() *client.Client {
+	return client.New(s.Matchmaker, client.WithSession(s.Session), client.OnSession(func(ctx context.Context, refreshed client.Session) error {
+		s.Session = refreshed
+		if s.store == nil {
+			return nil
+		}
+		return s.store.save(ctx, s)
+	}))
+}
+
+// tokenStores returns where sessions may be stored, the OS keychain before
+// the credentials file.
+func tokenStores() ([]tokenStore, error) {
+	f, err := credentialsFile()
+	if err != nil {
+		return nil, err
+	}
+	return []tokenStore{keychainStore{}, f}, nil
+}
+
+// storedSession returns the session gocli login stored for a matchmaker,
+// or errNoSession.
+func storedSession(ctx context.Context, matchmaker string) (*loginSession, error) {
+	stores, err := tokenStores()
+	if err != nil {
+		return nil, err
+	}
+	for _, store := range stores {
+		s, err := store.load(ctx, matchmaker)
+		if errors.Is(err, errNoSession) || errors.Is(err, errNoKeychain) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.store = store
+		return s, nil
+	}
+	return nil, errNoSession
+}
+
+// signIn returns a client signed in with the stored session for a
+// matchmaker if it is the username's, or with any stored session if
+// username is empty; and otherwise signs in with the password for a
+// session that lasts for this command.
+func signIn(ctx context.Context, matchmaker, username, password string) (*client.Client, error) {
+	s, err := storedSession(ctx, matchmaker)
+	if err == nil && (username == "" || username == s.PlayerID) {
+		mm := s.resume()
+		if _, err := mm.Token(ctx); err != nil {
+			return nil, fmt.Errorf("%w; run gocli login again", err)
+		}
+		return mm, nil
+	}
+	if err != nil && !errors.Is(err, errNoSession) {
+		return nil, err
+	}
+	if username == "" {
+		return nil, fmt.Errorf("%w to %s; run gocli login, or pass --username", errNoSession, matchmaker)
+	}
+	return passwordLogin(ctx, matchmaker, username, password)
+}
+
+func loginCmd() *cobra.Command {
+	var (
+		matchmaker string
+		username   string
+		password   string
+		insecure   bool
+	)
+	c := &cobra.Command{
+		Use:   "login",
+		Short: "Sign in to the matchmaker and keep the session for later commands",
+		Long: `Signs in to the matchmaker and keeps the session in the OS keychain, the
+macOS keychain or the Secret Service through secret-tool, so that commands
+like play needn't sign in again. The session is refreshed as it expires.
+
+Without a keychain, --insecure-storage keeps the session in plain text in
+credentials.json next to gocli's config file instead.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			matchmaker = strings.TrimSuffix(matchmaker, "/")
+			if password == "" {
+				var err error
+				if password, err = prompt(cmd.InOrStdin(), cmd.ErrOrStderr(), "password: "); err != nil {
+					return err
+				}
+			}
+			mm, err := passwordLogin(ctx, matchmaker, username, password)
+			if err != nil {
+				return err
+			}
+			s := &loginSession{Matchmaker: matchmaker, Session: mm.Session()}
+			var store tokenStore = keychainStore{}
+			if insecure {
+				if store, err = credentialsFile(); err != nil {
+					return err
+				}
+			}
+			if err := store.save(ctx, s); err != nil {
+				if errors.Is(err, errNoKeychain) {
+					return fmt.Errorf("%w; pass --insecure-storage to keep the session in a file", err)
+				}
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "signed in to %s as %s\n", matchmaker, s.PlayerID)
+			return nil
+		},
+	}
+	c.Flags().StringVar(&matchmaker, "matchmaker", "http://localhost:8080", "Base URL of the matchmaker's HTTP API")
+	c.Flags().StringVar(&username, "username", "", "Account to sign in as")
+	c.Flags().StringVar(&password, "password", os.Getenv("SNAPFOLD_PASSWORD"), "The account's password; defaults to $SNAPFOLD_PASSWORD, or is asked for")
+	c.Flags().BoolVar(&insecure, "insecure-storage", false, "Keep the session in a plain text file rather than the OS keychain")
+	c.MarkFlagRequired("username")
+	return c
+}
+
+func logoutCmd() *cobra.Command {
+	var matchmaker string
+	c := &cobra.Command{
+		Use:   "logout",
+		Short: "End the session gocli login kept and forget it",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			matchmaker = strings.TrimSuffix(matchmaker, "/")
+			s, err := storedSession(ctx, matchmaker)
+			if err != nil {
+				return err
+			}
+			if err := s.resume().Logout(ctx); err != nil {
+				// The session is forgotten regardless, as it may well have
+				// expired already.
+				fmt.Fprintf(cmd.ErrOrStderr(), "ending the session: %v\n", err)
+			}
+			if err := s.store.remove(ctx, matchmaker); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "signed out of %s\n", matchmaker)
+			return nil
+		},
+	}
+	c.Flags().StringVar(&matchmaker, "matchmaker", "http://localhost:8080", "Base URL of the matchmaker's HTTP API")
+	return c
+}
+
+// prompt asks for a line of input. It is echoed, as gocli has no way to
+// turn echo off portably.
+func prompt(in io.Reader, out io.Writer, question string) (string, error) {
+	fmt.Fprint(out, question)
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if errors.Is(err, io.EOF) && line == "" {
+		return "", fmt.Errorf("no answer to %q", strings.TrimSpace(question))
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
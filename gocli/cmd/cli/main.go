@@ -28,7 +28,27 @@ func rootCmd() *cobra.Command {
 			fmt.Printf("proto (%T): %s\n", protoVal, protoVal.String())
 		},
 	}
+	addOutputFlag(c)
+	addProfileFlag(c)
+	c.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFlag(cmd, args); err != nil {
+			return err
+		}
+		return applyProfile(cmd)
+	}
 	c.AddCommand(greeting.NewGreetCommand())
+	c.AddCommand(presetsCmd())
+	c.AddCommand(gamedefCmd())
+	c.AddCommand(tableCmd())
+	c.AddCommand(playCmd())
+	c.AddCommand(watchCmd())
+	c.AddCommand(simulateCmd())
+	c.AddCommand(handevalCmd())
+	c.AddCommand(configCmd())
+	c.AddCommand(loginCmd())
+	c.AddCommand(logoutCmd())
+	c.AddCommand(userCmd())
+	c.AddCommand(auditCmd())
 
 	return c
 }
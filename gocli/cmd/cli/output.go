@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats for the --output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var outputFormats = []string{outputTable, outputJSON, outputYAML}
+
+// addOutputFlag adds the --output flag, which every command under c
+// inherits.
+func addOutputFlag(c *cobra.Command) {
+	c.PersistentFlags().String("output", outputTable, "How to print results: table for people, or json or yaml for scripts")
+}
+
+// checkOutputFlag rejects an --output format that render doesn't know.
+func checkOutputFlag(cmd *cobra.Command, args []string) error {
+	if f, _ := cmd.Flags().GetString("output"); !slices.Contains(outputFormats, f) {
+		return fmt.Errorf("--output must be one of %v, not %q", outputFormats, f)
+	}
+	return nil
+}
+
+// render writes a command's result to its output in the format --output
+// asks for: v as JSON or YAML, or as human writes it for table. Protobuf
+// messages are written in their JSON form, and anything else as
+// encoding/json would.
+func render(cmd *cobra.Command, v any, human func(io.Writer)) error {
+	return renderTo(cmd, cmd.OutOrStdout(), v, human)
+}
+
+// renderTo is render to somewhere other than the command's output.
+func renderTo(cmd *cobra.Command, out io.Writer, v any, human func(io.Writer)) error {
+	format, _ := cmd.Flags().GetString("output")
+	if format == outputTable {
+		human(out)
+		return nil
+	}
+	b, err := marshalJSON(v)
+	if err != nil {
+		return err
+	}
+	if format == outputYAML {
+		if b, err = jsonToYAML(b); err != nil {
+			return err
+		}
+	} else {
+		b = append(b, '\n')
+	}
+	_, err = out.Write(b)
+	return err
+}
+
+func marshalJSON(v any) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		return protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(m)
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// jsonToYAML converts a JSON document to YAML, keeping the order of its
+// object keys.
+func jsonToYAML(b []byte) ([]byte, error) {
+	// JSON is YAML, but in flow style; decoding to nodes keeps the order of
+	// keys, and clearing their styles writes them in block style.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	var block func(n *yaml.Node)
+	block = func(n *yaml.Node) {
+		n.Style = 0
+		for _, c := range n.Content {
+			block(c)
+		}
+	}
+	block(&doc)
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), enc.Close()
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/client"
+)
+
+func playCmd() *cobra.Command {
+	var (
+		matchmaker string
+		username   string
+		password   string
+		gameMode   string
+	)
+	c := &cobra.Command{
+		Use:   "play",
+		Short: "Sign in, join a table and play in the terminal",
+		Long: `Signs in to the matchmaker, returns to the table the player is seated at or
+queues for the game mode, and plays at the table in the terminal. Type
+"help" at the table for its commands.
+
+The session kept by gocli login is used if there is one for the
+matchmaker, and is --username's if that is given; otherwise play signs in
+with --username and --password for as long as it runs.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			status := cmd.ErrOrStderr()
+			mm, err := signIn(ctx, strings.TrimSuffix(matchmaker, "/"), username, password)
+			if err != nil {
+				return err
+			}
+			playerID := mm.Session().PlayerID
+			fmt.Fprintf(status, "signed in as %s\n", playerID)
+
+			st, err := mm.Rejoin(ctx)
+			if errors.Is(err, client.ErrNotFound) {
+				if gameMode == "" {
+					return fmt.Errorf("not seated at a table; --game-mode is needed to queue")
+				}
+				fmt.Fprintf(status, "queueing for %s\n", gameMode)
+				st, err = mm.Queue(ctx, gameMode, func(ev *pb.LobbyEvent) { printLobbyEvent(status, ev) })
+			}
+			if err != nil {
+				return err
+			}
+			if st.ServerAddress == "" {
+				return fmt.Errorf("table %s has no game server address", st.TableID)
+			}
+			return playTable(ctx, mm.GameServer(st.ServerAddress), st.TableID, playerID, cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+	c.Flags().StringVar(&matchmaker, "matchmaker", "http://localhost:8080", "Base URL of the matchmaker's HTTP API")
+	c.Flags().StringVar(&username, "username", "", "Account to sign in as; the one signed in with gocli login if unset")
+	c.Flags().StringVar(&password, "password", os.Getenv("SNAPFOLD_PASSWORD"), "The account's password; defaults to $SNAPFOLD_PASSWORD")
+	c.Flags().StringVar(&gameMode, "game-mode", "", "Game mode to queue for, unless already seated at a table")
+	return c
+}
+
+// printLobbyEvent reports the player's progress through the queue.
+func printLobbyEvent(status io.Writer, ev *pb.LobbyEvent) {
+	switch ev.WhichEvent() {
+	case pb.LobbyEvent_QueueStatus_case:
+		qs := ev.GetQueueStatus()
+		fmt.Fprintf(status, "position %d", qs.GetPosition())
+		if qs.HasEstimatedWait() {
+			fmt.Fprintf(status, ", about %s", qs.GetEstimatedWait().AsDuration().Round(time.Second))
+		}
+		fmt.Fprintln(status)
+	case pb.LobbyEvent_ReadyCheck_case:
+		fmt.Fprintf(status, "match found with %s; accepting\n", strings.Join(ev.GetReadyCheck().GetPlayerIds(), ", "))
+	case pb.LobbyEvent_MatchFound_case:
+		fmt.Fprintf(status, "matched with %s\n", strings.Join(ev.GetMatchFound().GetPlayerIds(), ", "))
+	}
+}
+
+// playTable connects to a table and plays at it, redrawing the table on
+// each event and sending the commands read from in, until the table closes
+// or the player quits.
+func playTable(ctx context.Context, gs *client.Client, tableID, playerID string, in io.Reader, out io.Writer) error {
+	t, err := gs.JoinTable(ctx, tableID)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	events := make(chan *pb.TableEvent)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(events)
+		for {
+			ev, err := t.Recv()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					readErr <- err
+				}
+				return
+			}
+			events <- ev
+		}
+	}()
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		sc := bufio.NewScanner(in)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+	}()
+
+	v := newTableView(tableID, playerID)
+	for {
+		v.render(out)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-events:
+			if !ok {
+				select {
+				case err := <-readErr:
+					return err
+				default:
+					return nil
+				}
+			}
+			v.apply(ev)
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			v.status = ""
+			quit, err := v.command(ctx, line, t, gs)
+			if quit {
+				return nil
+			}
+			if err != nil {
+				v.status = err.Error()
+			}
+		}
+	}
+}
+
+// command carries out a line typed at the table, reporting whether the
+// player quit.
+func (v *tableView) command(ctx context.Context, line string, t *client.Table, gs *client.Client) (quit bool, err error) {
+	word, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+	switch strings.ToLower(word) {
+	case "":
+		return false, nil
+	case "q", "quit":
+		return true, nil
+	case "h", "help", "?":
+		v.status = "f fold, k check, c call, b N bet to N, r N raise to N, a all in, say TEXT, out, back, q quit"
+		return false, nil
+	case "f", "fold":
+		return false, v.act(t, pb.TableAction_FOLD, 0)
+	case "k", "check":
+		return false, v.act(t, pb.TableAction_CHECK, 0)
+	case "c", "call":
+		return false, v.act(t, pb.TableAction_CALL, 0)
+	case "b", "bet", "r", "raise":
+		to, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("%s needs the total to bet to", word)
+		}
+		kind := pb.TableAction_RAISE
+		if strings.HasPrefix(word, "b") {
+			kind = pb.TableAction_BET
+		}
+		return false, v.act(t, kind, to)
+	case "a", "allin":
+		if v.options == nil {
+			return false, fmt.Errorf("not your turn")
+		}
+		kind := pb.TableAction_RAISE
+		if v.options.GetCall() == 0 {
+			kind = pb.TableAction_BET
+		}
+		return false, v.act(t, kind, v.options.GetMaxTo())
+	case "say":
+		return false, gs.Chat(ctx, v.tableID, arg)
+	case "out":
+		return false, gs.SitOut(ctx, v.tableID)
+	case "back":
+		return false, gs.Return(ctx, v.tableID)
+	}
+	return false, fmt.Errorf("unknown command %q; type help for commands", word)
+}
+
+// act sends an action to the table.
+func (v *tableView) act(t *client.Table, kind pb.TableAction_Kind, to int64) error {
+	if v.options == nil {
+		return fmt.Errorf("not your turn")
+	}
+	return t.Act(kind, to)
+}
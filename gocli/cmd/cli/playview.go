@@ -0,0 +1,369 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+const (
+	// How many of the table's latest happenings to show under it.
+	logLines = 8
+
+	clearScreen = "\x1b[H\x1b[2J"
+	bold        = "\x1b[1m"
+	red         = "\x1b[31m"
+	reset       = "\x1b[0m"
+)
+
+// tableView is the table as the player sees it, built up from its events.
+type tableView struct {
+	tableID  string
+	playerID string
+	gameMode string
+
+	players    []string
+	bots       int32
+	connected  []string
+	sittingOut []string
+	paused     *pb.TablePaused
+	closed     *pb.TableClosed
+
+	// The player whose turn it is and when they time out, if known.
+	turn     string
+	deadline time.Time
+
+	// Chips behind, once a purchase or award tells us.
+	stacks map[string]int64
+
+	// The hand being played.
+	button  string
+	hole    []string
+	boards  [][]string
+	pots    []*pb.Pot
+	bets    map[string]int64
+	acted   map[string]string
+	allIn   map[string]bool
+	shown   map[string]*pb.Showdown_Hand
+	options *pb.TurnOptions
+
+	log    []string
+	status string
+}
+
+func newTableView(tableID, playerID string) *tableView {
+	v := &tableView{tableID: tableID, playerID: playerID, stacks: map[string]int64{}}
+	v.newHand()
+	return v
+}
+
+func (v *tableView) newHand() {
+	v.hole = nil
+	v.boards = nil
+	v.pots = nil
+	v.bets = map[string]int64{}
+	v.acted = map[string]string{}
+	v.allIn = map[string]bool{}
+	v.shown = map[string]*pb.Showdown_Hand{}
+	v.options = nil
+}
+
+func (v *tableView) logf(format string, args ...any) {
+	v.log = append(v.log, fmt.Sprintf(format, args...))
+	if len(v.log) > logLines {
+		v.log = v.log[len(v.log)-logLines:]
+	}
+}
+
+// apply updates the view with an event from the table.
+func (v *tableView) apply(ev *pb.TableEvent) {
+	switch ev.WhichEvent() {
+	case pb.TableEvent_Snapshot_case:
+		s := ev.GetSnapshot()
+		v.newHand()
+		v.gameMode = s.GetGameMode()
+		v.players = s.GetPlayerIds()
+		v.bots = s.GetBots()
+		v.connected = s.GetConnectedPlayerIds()
+		v.sittingOut = s.GetSittingOutPlayerIds()
+		v.paused = nil
+		if s.HasPaused() {
+			v.paused = s.GetPaused()
+		}
+		v.turn, v.deadline = "", time.Time{}
+		if s.HasTurnTimer() {
+			v.startTurn(s.GetTurnTimer())
+		}
+		v.hole = s.GetHoleCards()
+		v.options = s.GetTurnOptions()
+	case pb.TableEvent_PlayerConnected_case:
+		id := ev.GetPlayerConnected().GetPlayerId()
+		if !slices.Contains(v.connected, id) {
+			v.connected = append(v.connected, id)
+		}
+		v.logf("%s connected", id)
+	case pb.TableEvent_PlayerDisconnected_case:
+		id := ev.GetPlayerDisconnected().GetPlayerId()
+		v.connected = slices.DeleteFunc(v.connected, func(p string) bool { return p == id })
+		v.logf("%s disconnected", id)
+	case pb.TableEvent_PlayerSatOut_case:
+		id := ev.GetPlayerSatOut().GetPlayerId()
+		if !slices.Contains(v.sittingOut, id) {
+			v.sittingOut = append(v.sittingOut, id)
+		}
+		v.logf("%s sat out", id)
+	case pb.TableEvent_PlayerReturned_case:
+		id := ev.GetPlayerReturned().GetPlayerId()
+		v.sittingOut = slices.DeleteFunc(v.sittingOut, func(p string) bool { return p == id })
+		v.logf("%s is back", id)
+	case pb.TableEvent_TablePaused_case:
+		v.paused = ev.GetTablePaused()
+		v.logf("table paused: %s", v.paused.GetReason())
+	case pb.TableEvent_TableResumed_case:
+		v.paused = nil
+		v.logf("table resumed")
+	case pb.TableEvent_TableClosed_case:
+		v.closed = ev.GetTableClosed()
+		v.turn, v.options = "", nil
+		for _, s := range v.closed.GetReturned() {
+			v.stacks[s.GetPlayerId()] = s.GetChips()
+		}
+		v.logf("table closed: %s", v.closed.GetReason())
+	case pb.TableEvent_TurnTimer_case:
+		v.startTurn(ev.GetTurnTimer())
+	case pb.TableEvent_TurnTimedOut_case:
+		id := ev.GetTurnTimedOut().GetPlayerId()
+		if id == v.playerID {
+			v.options = nil
+		}
+		v.logf("%s timed out", id)
+	case pb.TableEvent_ChipsBought_case:
+		b := ev.GetChipsBought()
+		v.stacks[b.GetPlayerId()] = b.GetStack()
+		v.logf("%s bought %d chips", b.GetPlayerId(), b.GetChips())
+	case pb.TableEvent_HoleCards_case:
+		if h := ev.GetHoleCards(); h.GetPlayerId() == v.playerID {
+			v.hole = h.GetCards()
+		}
+	case pb.TableEvent_ChatMessage_case:
+		m := ev.GetChatMessage()
+		v.logf("<%s> %s", m.GetPlayerId(), m.GetText())
+	case pb.TableEvent_HandDealt_case:
+		v.newHand()
+		v.button = ev.GetHandDealt().GetButtonPlayerId()
+		v.logf("new hand")
+	case pb.TableEvent_PlayerActed_case:
+		a := ev.GetPlayerActed()
+		id := a.GetPlayerId()
+		word := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(a.GetKind().String(), "KIND_"), "_", " "))
+		v.acted[id] = word
+		v.allIn[id] = a.GetAllIn()
+		v.bets[id] = a.GetTotal()
+		if stack, ok := v.stacks[id]; ok {
+			v.stacks[id] = stack - a.GetAmount()
+		}
+		if id == v.playerID {
+			v.options = nil
+		}
+		if a.GetAmount() > 0 {
+			v.logf("%s: %s %d", id, word, a.GetAmount())
+		} else {
+			v.logf("%s: %s", id, word)
+		}
+	case pb.TableEvent_ActionRejected_case:
+		v.status = ev.GetActionRejected().GetReason()
+	case pb.TableEvent_TurnOptions_case:
+		if o := ev.GetTurnOptions(); o.GetPlayerId() == v.playerID {
+			v.options = o
+		}
+	case pb.TableEvent_BoardDealt_case:
+		b := ev.GetBoardDealt()
+		run := max(int(b.GetRun()), 1)
+		for len(v.boards) < run {
+			// A later run starts from the cards the runs share.
+			var shared []string
+			if len(v.boards) > 0 {
+				shared = slices.Clone(v.boards[0][:max(0, len(v.boards[0])-len(b.GetCards()))])
+			}
+			v.boards = append(v.boards, shared)
+		}
+		v.boards[run-1] = append(v.boards[run-1], b.GetCards()...)
+		v.acted = map[string]string{}
+	case pb.TableEvent_PotUpdate_case:
+		v.pots = ev.GetPotUpdate().GetPots()
+		v.bets = map[string]int64{}
+	case pb.TableEvent_Showdown_case:
+		for _, h := range ev.GetShowdown().GetHands() {
+			v.shown[h.GetPlayerId()] = h
+		}
+	case pb.TableEvent_PotAwarded_case:
+		a := ev.GetPotAwarded()
+		if stack, ok := v.stacks[a.GetPlayerId()]; ok {
+			v.stacks[a.GetPlayerId()] = stack + a.GetAmount()
+		}
+		v.logf("%s wins %d", a.GetPlayerId(), a.GetAmount())
+	}
+}
+
+func (v *tableView) startTurn(t *pb.TurnTimer) {
+	v.turn = t.GetPlayerId()
+	v.deadline = time.Time{}
+	if t.HasDeadline() {
+		v.deadline = t.GetDeadline().AsTime()
+	}
+}
+
+// render redraws the whole table.
+func (v *tableView) render(out io.Writer) {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	fmt.Fprintf(&b, "%s%s%s  %s\n", bold, v.tableID, reset, v.gameMode)
+	switch {
+	case v.closed != nil:
+		fmt.Fprintf(&b, "closed: %s\n", v.closed.GetReason())
+	case v.paused != nil:
+		fmt.Fprintf(&b, "paused: %s\n", v.paused.GetReason())
+	}
+	b.WriteString("\n")
+
+	if len(v.boards) == 0 {
+		b.WriteString("board: -\n")
+	}
+	for i, board := range v.boards {
+		label := "board"
+		if len(v.boards) > 1 {
+			label = fmt.Sprintf("run %d", i+1)
+		}
+		fmt.Fprintf(&b, "%s: %s\n", label, cards(board))
+	}
+	for i, p := range v.pots {
+		label := "pot"
+		if i > 0 {
+			label = fmt.Sprintf("side pot %d", i)
+		}
+		fmt.Fprintf(&b, "%s: %d\n", label, p.GetAmount())
+	}
+	b.WriteString("\n")
+
+	for _, id := range v.players {
+		v.renderSeat(&b, id)
+	}
+	if v.bots > 0 {
+		fmt.Fprintf(&b, "  + %d bots\n", v.bots)
+	}
+	b.WriteString("\n")
+
+	if o := v.options; o != nil {
+		fmt.Fprintf(&b, "%syour turn%s  [f]old", bold, reset)
+		if o.GetCall() == 0 {
+			b.WriteString("  [k] check")
+		} else {
+			fmt.Fprintf(&b, "  [c]all %d", o.GetCall())
+		}
+		if o.GetMaxTo() > 0 {
+			verb := "r"
+			if o.GetCall() == 0 {
+				verb = "b"
+			}
+			fmt.Fprintf(&b, "  [%s N] to %d-%d  [a]ll in", verb, o.GetMinTo(), o.GetMaxTo())
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	for _, line := range v.log {
+		fmt.Fprintf(&b, "  %s\n", line)
+	}
+	if v.status != "" {
+		fmt.Fprintf(&b, "%s%s%s\n", red, v.status, reset)
+	}
+	b.WriteString("> ")
+	io.WriteString(out, b.String())
+}
+
+func (v *tableView) renderSeat(b *strings.Builder, id string) {
+	marker := "  "
+	if id == v.turn {
+		marker = "> "
+	}
+	name := id
+	if id == v.playerID {
+		name = bold + id + reset
+	}
+	fmt.Fprintf(b, "%s%-3s %-20s", marker, v.buttonMark(id), name)
+
+	if stack, ok := v.stacks[id]; ok {
+		fmt.Fprintf(b, " %8d", stack)
+	} else {
+		fmt.Fprintf(b, " %8s", "")
+	}
+	if bet := v.bets[id]; bet > 0 {
+		fmt.Fprintf(b, "  bet %-6d", bet)
+	} else {
+		fmt.Fprintf(b, "  %-10s", "")
+	}
+
+	var notes []string
+	switch {
+	case v.shown[id] != nil:
+		h := v.shown[id]
+		notes = append(notes, cards(h.GetCards()))
+		if h.GetDescription() != "" {
+			notes = append(notes, h.GetDescription())
+		}
+	case id == v.playerID && len(v.hole) > 0:
+		notes = append(notes, cards(v.hole))
+	}
+	if a := v.acted[id]; a != "" {
+		notes = append(notes, a)
+	}
+	if v.allIn[id] {
+		notes = append(notes, "all in")
+	}
+	if id == v.turn && !v.deadline.IsZero() {
+		notes = append(notes, fmt.Sprintf("%ds left", max(0, int(time.Until(v.deadline).Seconds()))))
+	}
+	if slices.Contains(v.sittingOut, id) {
+		notes = append(notes, "sitting out")
+	}
+	if !slices.Contains(v.connected, id) {
+		notes = append(notes, "disconnected")
+	}
+	fmt.Fprintf(b, "  %s\n", strings.Join(notes, "  "))
+}
+
+func (v *tableView) buttonMark(id string) string {
+	if id != "" && id == v.button {
+		return "(D)"
+	}
+	return ""
+}
+
+// cards formats cards such as "As" with suit symbols, hearts and diamonds
+// in red.
+func cards(cs []string) string {
+	out := make([]string, len(cs))
+	for i, c := range cs {
+		if len(c) < 2 {
+			out[i] = c
+			continue
+		}
+		rank, suit := c[:len(c)-1], c[len(c)-1:]
+		switch strings.ToLower(suit) {
+		case "s":
+			out[i] = rank + "♠"
+		case "c":
+			out[i] = rank + "♣"
+		case "h":
+			out[i] = red + rank + "♥" + reset
+		case "d":
+			out[i] = red + rank + "♦" + reset
+		default:
+			out[i] = c
+		}
+	}
+	return strings.Join(out, " ")
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+)
+
+func presetsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "presets [NAME]",
+		Short:             "List the built-in table config presets, or print one",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeArg(presetNames),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return render(cmd, gamedefio.Presets, func(out io.Writer) {
+					for _, p := range gamedefio.Presets {
+						fmt.Fprintf(out, "%-10s %d seats\n", p.Name, p.Seats)
+					}
+				})
+			}
+			cfg, err := gamedefio.LoadPreset(args[0])
+			if err != nil {
+				return err
+			}
+			b, err := protojson.MarshalOptions{Multiline: true}.Marshal(cfg)
+			if err != nil {
+				return err
+			}
+			return render(cmd, cfg, func(out io.Writer) { fmt.Fprintln(out, string(b)) })
+		},
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// cliConfig is gocli's config file, which holds the servers and
+// credentials of each environment so that they needn't be passed as flags.
+//
+//	current: dev
+//	profiles:
+//	  dev:
+//	    matchmaker: http://localhost:8080
+//	    server: localhost:7001
+//	    admin_token_env: SNAPFOLD_ADMIN_TOKEN
+//	  prod:
+//	    matchmaker: https://mm.example.com
+//	    server: tables.example.com:7001
+//	    admin_token_env: SNAPFOLD_PROD_ADMIN_TOKEN
+//	    api_key_env: SNAPFOLD_PROD_API_KEY
+type cliConfig struct {
+	// The profile used unless --profile names another.
+	Current string `yaml:"current,omitempty" json:"current,omitempty"`
+
+	Profiles map[string]profile `yaml:"profiles,omitempty" json:"profiles,omitempty"`
+}
+
+// profile is one environment's settings. Credentials are never stored in
+// the file: it names the environment variables that hold them.
+type profile struct {
+	// Base URL of the matchmaker's HTTP API, for --matchmaker.
+	Matchmaker string `yaml:"matchmaker,omitempty" json:"matchmaker,omitempty"`
+
+	// Address of a game server's gRPC table service, for --server.
+	Server string `yaml:"server,omitempty" json:"server,omitempty"`
+
+	// Base URL of a game server's HTTP API, for --game-server.
+	GameServer string `yaml:"game_server,omitempty" json:"game_server,omitempty"`
+
+	// Account to sign in as, for --username.
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+
+	// Variables holding the game server's admin token, for --admin-token;
+	// an API key, for --token; and the account's password, for --password.
+	AdminTokenEnv string `yaml:"admin_token_env,omitempty" json:"admin_token_env,omitempty"`
+	APIKeyEnv     string `yaml:"api_key_env,omitempty" json:"api_key_env,omitempty"`
+	PasswordEnv   string `yaml:"password_env,omitempty" json:"password_env,omitempty"`
+}
+
+// flags returns the value the profile gives each flag it sets.
+func (p profile) flags() map[string]string {
+	env := func(name string) string {
+		if name == "" {
+			return ""
+		}
+		return os.Getenv(name)
+	}
+	return map[string]string{
+		"matchmaker":  p.Matchmaker,
+		"server":      p.Server,
+		"game-server": p.GameServer,
+		"username":    p.Username,
+		"admin-token": env(p.AdminTokenEnv),
+		"token":       env(p.APIKeyEnv),
+		"password":    env(p.PasswordEnv),
+	}
+}
+
+// configPath returns where the config file is: $SNAPFOLD_CONFIG, or
+// snapfold/config.yaml under $XDG_CONFIG_HOME or ~/.config.
+func configPath() (string, error) {
+	if p := os.Getenv("SNAPFOLD_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "snapfold", "config.yaml"), nil
+}
+
+// loadConfig reads the config file. A missing file is an empty config.
+func loadConfig() (*cliConfig, string, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, "", err
+	}
+	cfg := &cliConfig{}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, path, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, path, nil
+}
+
+func (c *cliConfig) save(path string) error {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0o600)
+}
+
+// addProfileFlag adds the --profile flag, which every command under c
+// inherits.
+func addProfileFlag(c *cobra.Command) {
+	c.PersistentFlags().String("profile", os.Getenv("SNAPFOLD_PROFILE"), "Config file profile to take servers and credentials from; defaults to $SNAPFOLD_PROFILE, then the current profile")
+	c.RegisterFlagCompletionFunc("profile", completeFlag(profileNames))
+}
+
+// applyProfile sets the flags of cmd that the user didn't pass from the
+// chosen profile, if there is one.
+func applyProfile(cmd *cobra.Command) error {
+	cfg, path, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	name, _ := cmd.Flags().GetString("profile")
+	if name == "" {
+		name = cfg.Current
+	}
+	if name == "" {
+		return nil
+	}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return fmt.Errorf("no profile %q in %s", name, path)
+	}
+	for flag, v := range p.flags() {
+		f := cmd.Flags().Lookup(flag)
+		if f == nil || f.Changed || v == "" {
+			continue
+		}
+		if err := cmd.Flags().Set(flag, v); err != nil {
+			return fmt.Errorf("profile %s: --%s: %w", name, flag, err)
+		}
+	}
+	return nil
+}
+
+func configCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the profiles in gocli's config file",
+		Long: `Manage the profiles in gocli's config file, $SNAPFOLD_CONFIG or
+~/.config/snapfold/config.yaml. Each profile gives the servers and
+credentials of one environment, which commands use for the flags they
+aren't passed:
+
+  current: dev
+  profiles:
+    dev:
+      matchmaker: http://localhost:8080
+      server: localhost:7001
+      game_server: http://localhost:7000
+      admin_token_env: SNAPFOLD_ADMIN_TOKEN
+    prod:
+      matchmaker: https://mm.example.com
+      server: tables.example.com:7001
+      username: ops
+      admin_token_env: SNAPFOLD_PROD_ADMIN_TOKEN
+      api_key_env: SNAPFOLD_PROD_API_KEY
+      password_env: SNAPFOLD_PROD_PASSWORD
+
+Credentials are never written to the file: profiles name the environment
+variables that hold them.`,
+	}
+	c.AddCommand(&cobra.Command{
+		Use:               "use-profile NAME",
+		Short:             "Make a profile the current one",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeArg(profileNames),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, path, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if _, ok := cfg.Profiles[args[0]]; !ok {
+				return fmt.Errorf("no profile %q in %s", args[0], path)
+			}
+			cfg.Current = args[0]
+			if err := cfg.save(path); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "using profile %s\n", args[0])
+			return nil
+		},
+	})
+	c.AddCommand(&cobra.Command{
+		Use:   "profiles",
+		Short: "List the profiles, marking the current one",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, _, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			return render(cmd, cfg, func(out io.Writer) {
+				for _, name := range slices.Sorted(maps.Keys(cfg.Profiles)) {
+					mark := " "
+					if name == cfg.Current {
+						mark = "*"
+					}
+					p := cfg.Profiles[name]
+					fmt.Fprintf(out, "%s %-12s %-32s %s\n", mark, name, p.Matchmaker, p.Server)
+				}
+			})
+		},
+	})
+	c.AddCommand(&cobra.Command{
+		Use:   "path",
+		Short: "Print where the config file is",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := configPath()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), path)
+			return nil
+		},
+	})
+	return c
+}
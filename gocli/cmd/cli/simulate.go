@@ -0,0 +1,230 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/gameserver/bot"
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/gameserver/sim"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/lib/shuffle"
+)
+
+func simulateCmd() *cobra.Command {
+	var (
+		hands int
+		specs []string
+		seats int
+		stack int64
+		seed  string
+	)
+	c := &cobra.Command{
+		Use:   "simulate CONFIG",
+		Short: "Play hands between bots under a table config and print stats",
+		Long: `Plays hands between bots under a TableConfig, entirely in-process, and
+prints how they went: how often hands see the flop and a showdown, pot
+sizes, and each seat's winnings. CONFIG is a config file in YAML, JSON or
+textproto, or preset:NAME for a built-in preset.
+
+Bots are given as --bot specs, one per seat; a single spec is played in
+every seat. Specs are "basic", optionally with settings as in
+"basic:raise=0.8,margin=0.05", or "caller", which checks and calls every
+hand down.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completePrefixed("preset:", presetNames),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, presetSeats, err := loadSimConfig(args[0])
+			if err != nil {
+				return err
+			}
+			seats = cmp.Or(seats, max(len(specs), presetSeats), 6)
+			if len(specs) == 1 {
+				specs = slices.Repeat(specs, seats)
+			}
+			if len(specs) != seats {
+				return fmt.Errorf("%d --bot specs for %d seats; give one, or one per seat", len(specs), seats)
+			}
+			bots := make([]host.BotStrategy, len(specs))
+			for i, spec := range specs {
+				if bots[i], err = bot.Parse(spec); err != nil {
+					return err
+				}
+			}
+
+			var s shuffle.Seed
+			if seed == "" {
+				if s, err = shuffle.NewSeed(); err != nil {
+					return err
+				}
+			} else if s, err = shuffle.ParseSeed(seed); err != nil {
+				return err
+			}
+
+			stats, err := sim.Run(cmd.Context(), sim.Config{
+				Table:    cfg,
+				Bots:     bots,
+				Stack:    stack,
+				Hands:    hands,
+				Shuffler: shuffle.NewSeeded(s),
+			})
+			if err != nil {
+				return err
+			}
+			sum := summarize(stats, specs, s)
+			return render(cmd, sum, sum.print)
+		},
+	}
+	c.Flags().IntVar(&hands, "hands", 1000, "Number of hands to play")
+	c.Flags().StringArrayVar(&specs, "bot", []string{"basic"}, "Strategy of the bot in each seat; repeat for each seat")
+	c.Flags().IntVar(&seats, "seats", 0, "Number of seats; defaults to the number of --bot specs, the preset's seats, or 6")
+	c.Flags().Int64Var(&stack, "stack", 0, "Chips each bot starts every hand with; 100 big blinds if unset")
+	c.Flags().StringVar(&seed, "seed", "", "Hex seed for the shuffles, to repeat a simulation; random if unset")
+	return c
+}
+
+// loadSimConfig loads a config file, or a preset named as preset:NAME along
+// with its number of seats.
+func loadSimConfig(path string) (*pb.TableConfig, int, error) {
+	name, ok := strings.CutPrefix(path, "preset:")
+	if !ok {
+		cfg, err := gamedefio.Load(path)
+		return cfg, 0, err
+	}
+	p, err := gamedefio.PresetNamed(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	cfg, err := p.Config()
+	return cfg, p.Seats, err
+}
+
+// simSummary is what simulate reports.
+type simSummary struct {
+	Hands int    `json:"hands"`
+	Seed  string `json:"seed"`
+
+	// Shares of hands, from 0 to 1.
+	Flops     float64 `json:"flops"`
+	Showdowns float64 `json:"showdowns"`
+	AllIns    float64 `json:"all_ins"`
+	RanTwice  float64 `json:"ran_twice"`
+
+	// Pot sizes in big blinds.
+	Pots spread[float64] `json:"pots_bb"`
+
+	Actions spread[int] `json:"actions"`
+
+	// Time to play a hand, in seconds.
+	Durations spread[float64] `json:"seconds"`
+
+	// Chips raked in all, and big blinds raked per hand.
+	Rake        int64   `json:"rake"`
+	RakePerHand float64 `json:"rake_bb_per_hand"`
+
+	Fallbacks int `json:"fallbacks"`
+
+	Seats []seatSummary `json:"seats"`
+}
+
+type seatSummary struct {
+	Bot string `json:"bot"`
+
+	// Big blinds won per 100 hands.
+	BBPer100 float64 `json:"bb_per_100"`
+}
+
+// spread summarizes the values of something over every hand.
+type spread[T int | float64] struct {
+	Mean   float64 `json:"mean"`
+	Median T       `json:"median"`
+	P90    T       `json:"p90"`
+	Max    T       `json:"max"`
+}
+
+func spreadOf[T int | float64](values []T) spread[T] {
+	sorted := slices.Sorted(slices.Values(values))
+	var sum T
+	for _, v := range sorted {
+		sum += v
+	}
+	return spread[T]{
+		Mean:   float64(sum) / float64(len(sorted)),
+		Median: quantile(sorted, 0.5),
+		P90:    quantile(sorted, 0.9),
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// quantile returns the value q of the way through sorted, which must not
+// be empty.
+func quantile[T any](sorted []T, q float64) T {
+	return sorted[min(int(q*float64(len(sorted))), len(sorted)-1)]
+}
+
+func summarize(s sim.Stats, specs []string, seed shuffle.Seed) simSummary {
+	sum := simSummary{Hands: s.Hands, Seed: seed.String(), Rake: s.Rake, Fallbacks: s.Fallbacks}
+	if s.Hands == 0 {
+		return sum
+	}
+	share := func(n int) float64 { return float64(n) / float64(s.Hands) }
+	bb := func(chips int64) float64 { return float64(chips) / float64(s.BigBlind) }
+	sum.Flops, sum.Showdowns, sum.AllIns, sum.RanTwice = share(s.Flops), share(s.Showdowns), share(s.AllIns), share(s.RanTwice)
+
+	pots := make([]float64, len(s.Pots))
+	for i, p := range s.Pots {
+		pots[i] = bb(p)
+	}
+	sum.Pots = spreadOf(pots)
+	sum.Actions = spreadOf(s.Actions)
+	secs := make([]float64, len(s.Durations))
+	for i, d := range s.Durations {
+		secs[i] = d.Seconds()
+	}
+	sum.Durations = spreadOf(secs)
+	sum.RakePerHand = bb(s.Rake) / float64(s.Hands)
+	for seat, net := range s.Net {
+		sum.Seats = append(sum.Seats, seatSummary{Bot: specs[seat], BBPer100: 100 * bb(net) / float64(s.Hands)})
+	}
+	return sum
+}
+
+func (s simSummary) print(out io.Writer) {
+	if s.Hands == 0 {
+		fmt.Fprintln(out, "no hands played")
+		return
+	}
+	pct := func(f float64) string { return fmt.Sprintf("%.1f%%", 100*f) }
+	secs := func(f float64) time.Duration { return time.Duration(f * float64(time.Second)) }
+
+	fmt.Fprintf(out, "hands:             %d\n", s.Hands)
+	fmt.Fprintf(out, "saw the flop:      %s\n", pct(s.Flops))
+	fmt.Fprintf(out, "showdown:          %s\n", pct(s.Showdowns))
+	fmt.Fprintf(out, "all in:            %s\n", pct(s.AllIns))
+	if s.RanTwice > 0 {
+		fmt.Fprintf(out, "ran twice:         %s\n", pct(s.RanTwice))
+	}
+	fmt.Fprintf(out, "pot (bb):          mean %.1f  median %.1f  p90 %.1f  max %.1f\n",
+		s.Pots.Mean, s.Pots.Median, s.Pots.P90, s.Pots.Max)
+	fmt.Fprintf(out, "actions per hand:  mean %.1f  median %d  p90 %d  max %d\n",
+		s.Actions.Mean, s.Actions.Median, s.Actions.P90, s.Actions.Max)
+	fmt.Fprintf(out, "time per hand:     mean %s  p90 %s  max %s\n",
+		secs(s.Durations.Mean), secs(s.Durations.P90), secs(s.Durations.Max))
+	fmt.Fprintf(out, "rake:              %d chips, %.2f bb per hand\n", s.Rake, s.RakePerHand)
+	if s.Fallbacks > 0 {
+		fmt.Fprintf(out, "bot fallbacks:     %d\n", s.Fallbacks)
+	}
+
+	fmt.Fprintf(out, "\n%-5s %-32s %12s\n", "seat", "bot", "bb/100 hands")
+	for i, seat := range s.Seats {
+		fmt.Fprintf(out, "%-5d %-32s %+12.1f\n", i+1, seat.Bot, seat.BBPer100)
+	}
+	fmt.Fprintf(out, "\nseed: %s\n", s.Seed)
+}
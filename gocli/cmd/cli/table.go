@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+)
+
+// tableService is how the table commands reach a game server.
+type tableService struct {
+	server     string
+	adminToken string
+}
+
+// dial connects to the game server's table service, returning a context
+// that carries the admin token.
+func (s *tableService) dial(ctx context.Context) (pb.TableServiceClient, context.Context, func(), error) {
+	if s.adminToken == "" {
+		return nil, nil, nil, fmt.Errorf("--admin-token or $SNAPFOLD_ADMIN_TOKEN is required")
+	}
+	conn, err := grpc.NewClient(s.server, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+s.adminToken)
+	return pb.NewTableServiceClient(conn), ctx, func() { conn.Close() }, nil
+}
+
+func tableCmd() *cobra.Command {
+	svc := &tableService{}
+	c := &cobra.Command{
+		Use:   "table",
+		Short: "Create, list, inspect and close tables on a game server",
+	}
+	c.PersistentFlags().StringVar(&svc.server, "server", "localhost:7001", "Address of the game server's gRPC table service, as host:port")
+	c.PersistentFlags().StringVar(&svc.adminToken, "admin-token", os.Getenv("SNAPFOLD_ADMIN_TOKEN"), "The game server's admin token; defaults to $SNAPFOLD_ADMIN_TOKEN")
+
+	c.AddCommand(tableCreateCmd(svc))
+	c.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the server's open tables",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, ctx, done, err := svc.dial(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer done()
+			resp, err := client.ListTables(ctx, &pb.ListTablesRequest{})
+			if err != nil {
+				return err
+			}
+			return render(cmd, resp, func(out io.Writer) {
+				for _, t := range resp.GetTables() {
+					printTableLine(out, t)
+				}
+			})
+		},
+	})
+	c.AddCommand(&cobra.Command{
+		Use:               "inspect TABLE_ID",
+		Short:             "Show a table's seats, stacks and state",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeArg(svc.tableIDs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, ctx, done, err := svc.dial(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer done()
+			t, err := client.GetTable(ctx, pb.GetTableRequest_builder{TableId: proto.String(args[0])}.Build())
+			if err != nil {
+				return err
+			}
+			return render(cmd, t, func(out io.Writer) { printTableDetail(out, t) })
+		},
+	})
+	c.AddCommand(tableCloseCmd(svc))
+	return c
+}
+
+func tableCreateCmd(svc *tableService) *cobra.Command {
+	var (
+		gameMode string
+		players  []string
+		bots     int32
+		stacks   map[string]int64
+		config   string
+	)
+	c := &cobra.Command{
+		Use:               "create TABLE_ID",
+		Short:             "Open a table, seating the players given",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := pb.CreateTableRequest_builder{
+				TableId:   proto.String(args[0]),
+				GameMode:  proto.String(gameMode),
+				PlayerIds: players,
+				Bots:      proto.Int32(bots),
+				Stacks:    stacks,
+			}
+			if config != "" {
+				cfg, err := gamedefio.Load(config)
+				if err != nil {
+					return err
+				}
+				req.Config = cfg
+			}
+			client, ctx, done, err := svc.dial(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer done()
+			t, err := client.CreateTable(ctx, req.Build())
+			if err != nil {
+				return err
+			}
+			return render(cmd, t, func(out io.Writer) { printTableDetail(out, t) })
+		},
+	}
+	c.Flags().StringVar(&gameMode, "game-mode", "", "Name of the table's game mode")
+	c.Flags().StringSliceVar(&players, "player", nil, "Player to seat, in seat order; repeat for each player")
+	c.Flags().Int32Var(&bots, "bots", 0, "Number of seats to fill with bots")
+	c.Flags().StringToInt64Var(&stacks, "stack", nil, "Chips a player brings to the table, as player=chips")
+	c.Flags().StringVar(&config, "config", "", "TableConfig file in YAML, JSON or textproto; the game mode's defaults if unset")
+	c.RegisterFlagCompletionFunc("player", completeFlag(svc.playerIDs))
+	return c
+}
+
+func tableCloseCmd(svc *tableService) *cobra.Command {
+	var reason string
+	c := &cobra.Command{
+		Use:               "close TABLE_ID",
+		Short:             "Close a table and return its players' chips",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeArg(svc.tableIDs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, ctx, done, err := svc.dial(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer done()
+			resp, err := client.CloseTable(ctx, pb.CloseTableRequest_builder{
+				TableId: proto.String(args[0]),
+				Reason:  proto.String(reason),
+			}.Build())
+			if err != nil {
+				return err
+			}
+			return render(cmd, resp, func(out io.Writer) {
+				fmt.Fprintf(out, "closed %s\n", args[0])
+				for _, s := range resp.GetReturned() {
+					fmt.Fprintf(out, "  returned %d chips to %s\n", s.GetChips(), s.GetPlayerId())
+				}
+			})
+		},
+	}
+	c.Flags().StringVar(&reason, "reason", "", "Why the table closed, shown to its players")
+	return c
+}
+
+func tableState(t *pb.TableInfo) string {
+	if t.GetPaused() {
+		return "paused"
+	}
+	return "playing"
+}
+
+// printTableLine writes a table as one line of a listing.
+func printTableLine(out io.Writer, t *pb.TableInfo) {
+	fmt.Fprintf(out, "%-24s %-12s %-8s %d/%d connected, %d bots\n", t.GetTableId(), t.GetGameMode(), tableState(t),
+		len(t.GetConnectedPlayerIds()), len(t.GetPlayerIds()), t.GetBots())
+}
+
+// printTableDetail writes a table with a line for each seat.
+func printTableDetail(out io.Writer, t *pb.TableInfo) {
+	fmt.Fprintf(out, "table:     %s\n", t.GetTableId())
+	fmt.Fprintf(out, "game mode: %s\n", t.GetGameMode())
+	fmt.Fprintf(out, "state:     %s\n", tableState(t))
+	chips := map[string]int64{}
+	for _, s := range t.GetStacks() {
+		chips[s.GetPlayerId()] = s.GetChips()
+	}
+	for i, id := range t.GetPlayerIds() {
+		var notes []string
+		if !slices.Contains(t.GetConnectedPlayerIds(), id) {
+			notes = append(notes, "disconnected")
+		}
+		if slices.Contains(t.GetSittingOutPlayerIds(), id) {
+			notes = append(notes, "sitting out")
+		}
+		line := fmt.Sprintf("seat %d:    %-20s %10d", i+1, id, chips[id])
+		if len(notes) > 0 {
+			line += "  (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Fprintln(out, line)
+	}
+	if t.GetBots() > 0 {
+		fmt.Fprintf(out, "bots:      %d\n", t.GetBots())
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// userService is how the user commands reach the matchmaker's admin API,
+// signed in as a moderator or admin.
+type userService struct {
+	matchmaker string
+	username   string
+	password   string
+}
+
+// do sends a request to an account's admin endpoint.
+func (s *userService) do(ctx context.Context, method, account, path string, in, out any) error {
+	matchmaker := strings.TrimSuffix(s.matchmaker, "/")
+	mm, err := signIn(ctx, matchmaker, s.username, s.password)
+	if err != nil {
+		return err
+	}
+	return mm.Do(ctx, method, "/v1/admin/accounts/"+url.PathEscape(account)+path, in, out)
+}
+
+// change sends a request that changes an account, then renders the account
+// as it is afterwards, written for people as the one line done says.
+func (s *userService) change(cmd *cobra.Command, method, account, path string, in any, done func(a accountInfo) string) error {
+	if err := s.do(cmd.Context(), method, account, path, in, nil); err != nil {
+		return err
+	}
+	var a accountInfo
+	if err := s.do(cmd.Context(), http.MethodGet, account, "", nil, &a); err != nil {
+		return err
+	}
+	return render(cmd, a, func(out io.Writer) { fmt.Fprintln(out, done(a)) })
+}
+
+// resetResult is what user reset-password did.
+type resetResult struct {
+	Username  string `json:"username"`
+	ResetSent bool   `json:"reset_sent"`
+}
+
+// accountInfo is the matchmaker's description of an account.
+type accountInfo struct {
+	Username      string    `json:"username"`
+	Email         string    `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	Guest         bool      `json:"guest"`
+	Role          string    `json:"role"`
+	CreatedAt     time.Time `json:"created_at"`
+	Ban           *struct {
+		Reason string     `json:"reason"`
+		By     string     `json:"by"`
+		At     time.Time  `json:"at"`
+		Until  *time.Time `json:"until,omitempty"`
+	} `json:"ban,omitempty"`
+	Identities []string `json:"identities"`
+	Sessions   int      `json:"sessions"`
+}
+
+func (a accountInfo) print(out io.Writer) {
+	fmt.Fprintf(out, "username:    %s\n", a.Username)
+	email := a.Email
+	switch {
+	case a.Guest:
+		email = "none (guest)"
+	case !a.EmailVerified:
+		email += " (unverified)"
+	}
+	fmt.Fprintf(out, "email:       %s\n", email)
+	fmt.Fprintf(out, "role:        %s\n", a.Role)
+	fmt.Fprintf(out, "created:     %s\n", a.CreatedAt.Local().Format(time.DateTime))
+	if len(a.Identities) > 0 {
+		fmt.Fprintf(out, "identities:  %s\n", strings.Join(a.Identities, ", "))
+	}
+	fmt.Fprintf(out, "sessions:    %d\n", a.Sessions)
+	if b := a.Ban; b != nil {
+		until := "until unbanned"
+		if b.Until != nil {
+			until = "until " + b.Until.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(out, "banned:      by %s on %s, %s: %s\n", b.By, b.At.Local().Format(time.DateTime), until, b.Reason)
+	}
+}
+
+func userCmd() *cobra.Command {
+	svc := &userService{}
+	c := &cobra.Command{
+		Use:   "user",
+		Short: "Look up, ban and manage players' accounts",
+		Long: `Looks up and manages players' accounts through the matchmaker's admin
+API, signed in as a moderator or admin: from gocli login, or --username and
+--password. Moderators may look up, ban and unban players and send them
+password resets; only admins may grant roles.`,
+	}
+	c.PersistentFlags().StringVar(&svc.matchmaker, "matchmaker", "http://localhost:8080", "Base URL of the matchmaker's HTTP API")
+	c.PersistentFlags().StringVar(&svc.username, "username", "", "Moderator or admin to sign in as; the one signed in with gocli login if unset")
+	c.PersistentFlags().StringVar(&svc.password, "password", os.Getenv("SNAPFOLD_PASSWORD"), "The account's password; defaults to $SNAPFOLD_PASSWORD")
+
+	c.AddCommand(&cobra.Command{
+		Use:   "info USERNAME",
+		Short: "Show an account's email, role, sessions and any ban",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var a accountInfo
+			if err := svc.do(cmd.Context(), http.MethodGet, args[0], "", nil, &a); err != nil {
+				return err
+			}
+			return render(cmd, a, a.print)
+		},
+	})
+	c.AddCommand(userBanCmd(svc))
+	c.AddCommand(&cobra.Command{
+		Use:   "unban USERNAME",
+		Short: "Lift an account's ban",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return svc.change(cmd, http.MethodDelete, args[0], "/ban", nil, func(a accountInfo) string {
+				return "unbanned " + a.Username
+			})
+		},
+	})
+	c.AddCommand(&cobra.Command{
+		Use:       "grant-role USERNAME ROLE",
+		Short:     "Make an account a player, moderator or admin",
+		Args:      cobra.ExactArgs(2),
+		ValidArgs: []cobra.Completion{"player", "moderator", "admin"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return svc.change(cmd, http.MethodPut, args[0], "/role", map[string]string{"role": args[1]}, func(a accountInfo) string {
+				return fmt.Sprintf("%s is now a %s", a.Username, a.Role)
+			})
+		},
+	})
+	c.AddCommand(&cobra.Command{
+		Use:   "reset-password USERNAME",
+		Short: "Email an account a link to reset its password",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := svc.do(cmd.Context(), http.MethodPost, args[0], "/password-reset", nil, nil); err != nil {
+				return err
+			}
+			res := resetResult{Username: args[0], ResetSent: true}
+			return render(cmd, res, func(out io.Writer) {
+				fmt.Fprintf(out, "sent %s a password reset link\n", res.Username)
+			})
+		},
+	})
+	return c
+}
+
+func userBanCmd(svc *userService) *cobra.Command {
+	var (
+		reason string
+		length time.Duration
+	)
+	c := &cobra.Command{
+		Use:   "ban USERNAME",
+		Short: "Ban an account and end its sessions",
+		Long: `Bans an account, ending its sessions and refusing it new ones, for --for
+or until it is unbanned. Banning an account that is already banned replaces
+its ban. Moderators can't ban other moderators or admins.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if length < 0 {
+				return fmt.Errorf("--for must be positive")
+			}
+			req := map[string]any{"reason": reason}
+			if length > 0 {
+				req["until"] = time.Now().Add(length)
+			}
+			return svc.change(cmd, http.MethodPut, args[0], "/ban", req, func(a accountInfo) string {
+				return "banned " + a.Username
+			})
+		},
+	}
+	c.Flags().StringVar(&reason, "reason", "", "Why the account is banned, which its player is told when refused")
+	c.Flags().DurationVar(&length, "for", 0, "How long the ban lasts, such as 72h; until unbanned if unset")
+	c.MarkFlagRequired("reason")
+	return c
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/client"
+)
+
+// watchReconnectDelay is how long watch waits to reconnect after the game
+// server drops it for falling behind.
+const watchReconnectDelay = time.Second
+
+func watchCmd() *cobra.Command {
+	var (
+		gameServer string
+		matchmaker string
+		username   string
+		password   string
+	)
+	c := &cobra.Command{
+		Use:   "watch TABLE_ID",
+		Short: "Print a table's events as they happen, as a spectator",
+		Long: `Watches a table as a spectator and prints its events as they happen, a
+line each, until the table closes or the command is interrupted. Spectators
+see everything but players' hole cards, and only tables whose game mode
+allows them can be watched. With --output json, events are written as
+JSON Lines, each a TableEvent in its JSON form.
+
+Watching needs a signed-in player, from gocli login or --username and
+--password as for play.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("output")
+			if format == outputYAML {
+				return fmt.Errorf("watch writes --output table or json")
+			}
+			ctx := cmd.Context()
+			mm, err := signIn(ctx, strings.TrimSuffix(matchmaker, "/"), username, password)
+			if err != nil {
+				return err
+			}
+			gs := mm.GameServer(gameServer)
+			print := printEvent
+			if format == outputJSON {
+				print = printEventJSON
+			}
+			return watchTable(ctx, gs, args[0], cmd.OutOrStdout(), cmd.ErrOrStderr(), print)
+		},
+	}
+	c.Flags().StringVar(&gameServer, "game-server", "http://localhost:7000", "Base URL of the HTTP API of the game server hosting the table")
+	c.Flags().StringVar(&matchmaker, "matchmaker", "http://localhost:8080", "Base URL of the matchmaker's HTTP API, to sign in to")
+	c.Flags().StringVar(&username, "username", "", "Account to sign in as; the one signed in with gocli login if unset")
+	c.Flags().StringVar(&password, "password", os.Getenv("SNAPFOLD_PASSWORD"), "The account's password; defaults to $SNAPFOLD_PASSWORD")
+	return c
+}
+
+// watchTable prints a table's spectator events until it closes,
+// reconnecting for a fresh snapshot whenever the server drops the
+// connection for falling behind.
+func watchTable(ctx context.Context, gs *client.Client, tableID string, out, status io.Writer, print func(io.Writer, time.Time, *pb.TableEvent) error) error {
+	for {
+		t, err := gs.WatchTable(ctx, tableID)
+		if err != nil {
+			return err
+		}
+		closed, err := tailEvents(t, out, print)
+		t.Close()
+		if closed || errors.Is(err, io.EOF) {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, ok := err.(*websocket.CloseError); !ok {
+			return err
+		}
+		fmt.Fprintf(status, "disconnected (%v); reconnecting\n", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watchReconnectDelay):
+		}
+	}
+}
+
+// tailEvents prints the events read from t until it fails, returning
+// whether the table closed.
+func tailEvents(t *client.Table, out io.Writer, print func(io.Writer, time.Time, *pb.TableEvent) error) (bool, error) {
+	for {
+		ev, err := t.Recv()
+		if err != nil {
+			return false, err
+		}
+		if err := print(out, time.Now(), ev); err != nil {
+			return false, err
+		}
+		if ev.HasTableClosed() {
+			return true, nil
+		}
+	}
+}
+
+func printEventJSON(out io.Writer, _ time.Time, ev *pb.TableEvent) error {
+	b, err := protojson.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", b)
+	return err
+}
+
+func printEvent(out io.Writer, at time.Time, ev *pb.TableEvent) error {
+	_, err := fmt.Fprintf(out, "%s  %s\n", at.Format(time.TimeOnly), describeEvent(ev))
+	return err
+}
+
+// describeEvent writes an event as a line of a table's log.
+func describeEvent(ev *pb.TableEvent) string {
+	switch ev.WhichEvent() {
+	case pb.TableEvent_Snapshot_case:
+		s := ev.GetSnapshot()
+		line := fmt.Sprintf("watching %s (%s): %s", s.GetTableId(), s.GetGameMode(), strings.Join(s.GetPlayerIds(), ", "))
+		if s.GetBots() > 0 {
+			line += fmt.Sprintf(" and %d bots", s.GetBots())
+		}
+		if s.HasPaused() {
+			line += "; paused: " + s.GetPaused().GetReason()
+		}
+		return line
+	case pb.TableEvent_PlayerConnected_case:
+		return ev.GetPlayerConnected().GetPlayerId() + " connected"
+	case pb.TableEvent_PlayerDisconnected_case:
+		return ev.GetPlayerDisconnected().GetPlayerId() + " disconnected"
+	case pb.TableEvent_TableClosed_case:
+		c := ev.GetTableClosed()
+		line := "table closed: " + c.GetReason()
+		for _, s := range c.GetReturned() {
+			line += fmt.Sprintf("; %d chips to %s", s.GetChips(), s.GetPlayerId())
+		}
+		return line
+	case pb.TableEvent_TurnTimer_case:
+		t := ev.GetTurnTimer()
+		if !t.HasDeadline() {
+			return t.GetPlayerId() + " to act"
+		}
+		return fmt.Sprintf("%s to act by %s", t.GetPlayerId(), t.GetDeadline().AsTime().Local().Format(time.TimeOnly))
+	case pb.TableEvent_TurnTimedOut_case:
+		return ev.GetTurnTimedOut().GetPlayerId() + " timed out"
+	case pb.TableEvent_TablePaused_case:
+		return "table paused: " + ev.GetTablePaused().GetReason()
+	case pb.TableEvent_TableResumed_case:
+		return "table resumed"
+	case pb.TableEvent_PlayerSatOut_case:
+		return ev.GetPlayerSatOut().GetPlayerId() + " sat out"
+	case pb.TableEvent_PlayerReturned_case:
+		return ev.GetPlayerReturned().GetPlayerId() + " is back"
+	case pb.TableEvent_ChipsBought_case:
+		b := ev.GetChipsBought()
+		return fmt.Sprintf("%s bought %d chips, for a stack of %d", b.GetPlayerId(), b.GetChips(), b.GetStack())
+	case pb.TableEvent_HoleCards_case:
+		return "hole cards dealt to " + ev.GetHoleCards().GetPlayerId()
+	case pb.TableEvent_ChatMessage_case:
+		m := ev.GetChatMessage()
+		return fmt.Sprintf("<%s> %s", m.GetPlayerId(), m.GetText())
+	case pb.TableEvent_HandDealt_case:
+		return "new hand; " + ev.GetHandDealt().GetButtonPlayerId() + " has the button"
+	case pb.TableEvent_PlayerActed_case:
+		a := ev.GetPlayerActed()
+		line := a.GetPlayerId() + ": " + strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(a.GetKind().String(), "KIND_"), "_", " "))
+		if a.GetAmount() > 0 {
+			line += fmt.Sprintf(" %d", a.GetAmount())
+		}
+		if a.GetAllIn() {
+			line += ", all in"
+		}
+		return line
+	case pb.TableEvent_TurnOptions_case:
+		o := ev.GetTurnOptions()
+		return fmt.Sprintf("%s may call %d or raise to %d-%d", o.GetPlayerId(), o.GetCall(), o.GetMinTo(), o.GetMaxTo())
+	case pb.TableEvent_BoardDealt_case:
+		b := ev.GetBoardDealt()
+		line := "board: " + strings.Join(b.GetCards(), " ")
+		if b.GetRun() > 1 {
+			line += fmt.Sprintf(" (run %d)", b.GetRun())
+		}
+		return line
+	case pb.TableEvent_PotUpdate_case:
+		var pots []string
+		for _, p := range ev.GetPotUpdate().GetPots() {
+			pots = append(pots, fmt.Sprint(p.GetAmount()))
+		}
+		return "pots: " + strings.Join(pots, ", ")
+	case pb.TableEvent_Showdown_case:
+		var hands []string
+		for _, h := range ev.GetShowdown().GetHands() {
+			hand := h.GetPlayerId() + " shows " + strings.Join(h.GetCards(), " ")
+			if h.GetDescription() != "" {
+				hand += " (" + h.GetDescription() + ")"
+			}
+			hands = append(hands, hand)
+		}
+		return "showdown: " + strings.Join(hands, "; ")
+	case pb.TableEvent_PotAwarded_case:
+		a := ev.GetPotAwarded()
+		return fmt.Sprintf("%s wins %d", a.GetPlayerId(), a.GetAmount())
+	}
+	return fmt.Sprintf("unknown event %v", ev)
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// refreshMargin is how long before its access token expires that a session
+// is refreshed, so that a request doesn't race the expiry.
+const refreshMargin = time.Minute
+
+// Session is a player's session with a matchmaker, as it replies to signing
+// in or refreshing.
+type Session struct {
+	PlayerID string `json:"player_id"`
+
+	// Access token, presented as a bearer token, and when it expires.
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Exchanged for new tokens before Token expires. Each refresh token may
+	// be used once.
+	RefreshToken string `json:"refresh_token"`
+}
+
+// auth holds a client's session and refreshes it as it expires.
+type auth struct {
+	matchmaker *Client
+	save       func(context.Context, Session) error
+
+	mu      sync.Mutex
+	session Session
+}
+
+// token returns the session's access token, first exchanging its refresh
+// token for new tokens if it is about to expire, or "" if the client isn't
+// signed in.
+func (a *auth) token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.session.Token == "" || time.Until(a.session.ExpiresAt) > refreshMargin {
+		return a.session.Token, nil
+	}
+	if err := a.refreshLocked(ctx); err != nil {
+		return "", err
+	}
+	return a.session.Token, nil
+}
+
+func (a *auth) refreshLocked(ctx context.Context) error {
+	var s Session
+	err := a.matchmaker.do(ctx, http.MethodPost, "/v1/sessions/refresh", "", "", map[string]string{"refresh_token": a.session.RefreshToken}, &s)
+	if err != nil {
+		return fmt.Errorf("refreshing the session: %w", err)
+	}
+	return a.setLocked(ctx, s)
+}
+
+func (a *auth) setLocked(ctx context.Context, s Session) error {
+	a.session = s
+	if a.save != nil {
+		if err := a.save(ctx, s); err != nil {
+			return fmt.Errorf("saving the session: %w", err)
+		}
+	}
+	return nil
+}
+
+// Login signs in with a password. A matchmaker that doesn't check
+// passwords, as in development, accepts any.
+func (c *Client) Login(ctx context.Context, username, password string) (Session, error) {
+	var s Session
+	err := c.auth.matchmaker.do(ctx, http.MethodPost, "/v1/login", "", "", map[string]string{"username": username, "password": password}, &s)
+	if err != nil {
+		return Session{}, err
+	}
+	c.auth.mu.Lock()
+	defer c.auth.mu.Unlock()
+	return s, c.auth.setLocked(ctx, s)
+}
+
+// Session returns the client's session, which is zero if it isn't signed
+// in.
+func (c *Client) Session() Session {
+	c.auth.mu.Lock()
+	defer c.auth.mu.Unlock()
+	return c.auth.session
+}
+
+// Token returns the session's access token, first refreshing the session if
+// it is about to expire, or "" if the client isn't signed in. It is for
+// calls the client doesn't make itself, such as over gRPC.
+func (c *Client) Token(ctx context.Context) (string, error) {
+	return c.auth.token(ctx)
+}
+
+// Refresh exchanges the session's refresh token for new tokens now, rather
+// than as the access token expires.
+func (c *Client) Refresh(ctx context.Context) (Session, error) {
+	c.auth.mu.Lock()
+	defer c.auth.mu.Unlock()
+	if err := c.auth.refreshLocked(ctx); err != nil {
+		return Session{}, err
+	}
+	return c.auth.session, nil
+}
+
+// Logout ends the session and forgets it. It is forgotten even if ending it
+// fails, as it may well have expired already.
+func (c *Client) Logout(ctx context.Context) error {
+	c.auth.mu.Lock()
+	defer c.auth.mu.Unlock()
+	refresh := c.auth.session.RefreshToken
+	c.auth.session = Session{}
+	return c.auth.matchmaker.do(ctx, http.MethodPost, "/v1/logout", "", "", map[string]string{"refresh_token": refresh}, nil)
+}
@@ -0,0 +1,395 @@
+// Package client calls snapfold's matchmaker and game servers as a player:
+// it signs in and keeps the session's tokens fresh, queues for matches, and
+// plays at or watches tables. Requests that fail in ways that can safely be
+// tried again are retried. Requests carry the trace context of any span in
+// their context.
+package client
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+
+	"github.com/jfmatt/snapfold/lib/frame"
+	"github.com/jfmatt/snapfold/lib/idempotency"
+	"github.com/jfmatt/snapfold/lib/protocol"
+	"github.com/jfmatt/snapfold/lib/tracing"
+)
+
+var (
+	// ErrUnauthorized is wrapped by errors for replies with status 401: the
+	// client isn't signed in, or its session has ended.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrNotFound is wrapped by errors for replies with status 404.
+	ErrNotFound = errors.New("not found")
+)
+
+// StatusError is a reply with an error status. A reply with status 426
+// wraps protocol.ErrUnsupported: the server no longer speaks this client's
+// protocol version, and it must be updated.
+type StatusError struct {
+	Method, Path string
+	StatusCode   int
+
+	// The error the server gave, or the status if it gave none.
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Method, e.Path, e.Message)
+}
+
+func (e *StatusError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusUpgradeRequired:
+		return protocol.ErrUnsupported
+	}
+	return nil
+}
+
+const (
+	defaultRetries = 3
+	defaultBackoff = 250 * time.Millisecond
+
+	// The longest wait between attempts, however many have failed.
+	maxBackoff = 10 * time.Second
+
+	// Interval between QUIC keepalives, which keep NAT bindings open while
+	// a table is quiet.
+	quicKeepAlive = 15 * time.Second
+)
+
+// Client calls a snapfold HTTP API: a matchmaker's, or, for a Client
+// returned by GameServer, a game server's. It is safe for concurrent use.
+type Client struct {
+	base    string
+	http    *http.Client
+	dialer  *websocket.Dialer
+	retries int
+	backoff time.Duration
+	hello   protocol.Hello
+
+	// Streams table events from game servers over HTTP/3, if set.
+	h3 *http.Client
+
+	// Shared with the game server clients made from this one.
+	auth *auth
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sends requests with hc rather than http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithRetries retries a failed request up to n times, waiting backoff
+// before the first retry and twice as long before each one after, unless
+// the server says how long to wait. Only requests that failed before the
+// server could act on them, or that are idempotent, are retried. The
+// default is 3 retries after 250ms; 0 never retries.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) { c.retries, c.backoff = n, backoff }
+}
+
+// WithProtocol tells servers h of the client rather than protocol.Current,
+// such as to leave out capabilities that the code using it doesn't handle.
+func WithProtocol(h protocol.Hello) Option {
+	return func(c *Client) { c.hello = h }
+}
+
+// WithHTTP3 streams table events from game servers over HTTP/3 (QUIC)
+// rather than WebSockets, experimentally. A lost packet then only holds up
+// the stream it was on, and connections survive the network changing, as it
+// does for phones. The game servers must serve HTTP/3 on the port of their
+// HTTP API, and events only flow one way: actions can't be sent on such a
+// table. tlsConfig checks the servers' certificates; if nil, they are
+// checked against the system's roots.
+func WithHTTP3(tlsConfig *tls.Config) Option {
+	return func(c *Client) {
+		tlsConfig = tlsConfig.Clone()
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ClientSessionCache == nil {
+			// Resuming TLS sessions makes reconnecting quicker.
+			tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+		}
+		c.h3 = &http.Client{Transport: &http3.Transport{
+			TLSClientConfig: tlsConfig,
+			QUICConfig:      &quic.Config{KeepAlivePeriod: quicKeepAlive},
+		}}
+	}
+}
+
+// WithSession resumes a session, such as one kept from an earlier run.
+func WithSession(s Session) Option {
+	return func(c *Client) { c.auth.session = s }
+}
+
+// OnSession calls save with each session the client signs in to or
+// refreshes, so that it can be kept. Each refresh token may be used once,
+// so a kept session is useless once the client has refreshed it. If save
+// fails, so does the call that got the session.
+func OnSession(save func(context.Context, Session) error) Option {
+	return func(c *Client) { c.auth.save = save }
+}
+
+// New returns a Client for the matchmaker whose HTTP API is at baseURL,
+// such as http://localhost:8080.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		base:    strings.TrimSuffix(baseURL, "/"),
+		http:    http.DefaultClient,
+		dialer:  websocket.DefaultDialer,
+		retries: defaultRetries,
+		backoff: defaultBackoff,
+		hello:   protocol.Current,
+	}
+	c.auth = &auth{matchmaker: c}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// GameServer returns a Client for the HTTP API of a game server, signed in
+// as this client's player. address is a base URL, or host:port as the
+// matchmaker reports it in a Seat.
+func (c *Client) GameServer(address string) *Client {
+	gs := *c
+	gs.base = strings.TrimSuffix(address, "/")
+	if !strings.Contains(gs.base, "://") {
+		gs.base = "http://" + gs.base
+	}
+	return &gs
+}
+
+// Do sends a request to path, with in as its JSON body if it isn't nil,
+// and decodes the JSON reply into out if it isn't nil. It authenticates as
+// the signed-in player, if any. Replies with an error status are returned
+// as a *StatusError.
+func (c *Client) Do(ctx context.Context, method, path string, in, out any) error {
+	token, err := c.auth.token(ctx)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, method, path, token, "", in, out)
+}
+
+// doOnce is Do for a request that must take effect at most once, however
+// often it is retried. It sends an idempotency key with every attempt, which
+// makes retrying safe even when an attempt might have got through.
+func (c *Client) doOnce(ctx context.Context, method, path string, in, out any) error {
+	token, err := c.auth.token(ctx)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, method, path, token, rand.Text(), in, out)
+}
+
+// do is Do with the bearer token to present, if any, and the request's
+// idempotency key, if it has one.
+func (c *Client) do(ctx context.Context, method, path, token, key string, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.base+path, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		if in != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set(protocol.Header, c.hello.String())
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if key != "" {
+			req.Header.Set(idempotency.Header, key)
+		}
+		tracing.Inject(ctx, req.Header)
+		resp, err := c.http.Do(req)
+		if err == nil && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if out == nil {
+				return nil
+			}
+			return json.NewDecoder(resp.Body).Decode(out)
+		}
+		if err == nil {
+			err = replyError(method, path, resp)
+		}
+		if attempt >= c.retries || !retryable(method, key != "", resp, err) {
+			return err
+		}
+		if err := c.wait(ctx, attempt, resp); err != nil {
+			return err
+		}
+	}
+}
+
+// dial opens a WebSocket to path, authenticated as the signed-in player.
+// A refused handshake is returned as a *StatusError.
+func (c *Client) dial(ctx context.Context, path string) (*socket, error) {
+	u, err := url.Parse(c.base + path)
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	token, err := c.auth.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{protocol.Header: {c.hello.String()}}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	tracing.Inject(ctx, header)
+	for attempt := 0; ; attempt++ {
+		conn, resp, err := c.dialer.DialContext(ctx, u.String(), header)
+		if err == nil {
+			// Servers from before versions were negotiated don't say, and
+			// don't frame.
+			server, _ := strconv.Atoi(resp.Header.Get(protocol.Header))
+			framed := protocol.Hello{Version: min(c.hello.Version, server)}.Framed()
+			return &socket{conn: conn, framed: framed}, nil
+		}
+		if resp != nil {
+			err = replyError(http.MethodGet, path, resp)
+		}
+		if attempt >= c.retries || !retryable(http.MethodGet, false, resp, err) {
+			return nil, err
+		}
+		if err := c.wait(ctx, attempt, resp); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// stream requests path as a stream of frames over HTTP/3, authenticated as
+// the signed-in player. The stream lasts until it is closed, even once ctx
+// is done. A refused request is returned as a *StatusError.
+func (c *Client) stream(ctx context.Context, path string) (*bodyStream, error) {
+	u, err := url.Parse(c.base + path)
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = "https"
+	token, err := c.auth.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 0; ; attempt++ {
+		sctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		stop := context.AfterFunc(ctx, cancel)
+		req, err := http.NewRequestWithContext(sctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		req.Header.Set("Accept", frame.ContentType)
+		req.Header.Set(protocol.Header, c.hello.String())
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		tracing.Inject(ctx, req.Header)
+		resp, err := c.h3.Do(req)
+		if !stop() {
+			err = cmp.Or(ctx.Err(), err)
+		}
+		if err == nil && resp.StatusCode < 300 {
+			return &bodyStream{body: resp.Body, frames: frame.NewReader(resp.Body), cancel: cancel}, nil
+		}
+		cancel()
+		if err == nil {
+			err = replyError(http.MethodGet, path, resp)
+		}
+		if attempt >= c.retries || !retryable(http.MethodGet, false, resp, err) {
+			return nil, err
+		}
+		if err := c.wait(ctx, attempt, resp); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// replyError reads the error from a reply with an error status, and closes
+// its body.
+func replyError(method, path string, resp *http.Response) error {
+	defer resp.Body.Close()
+	var e struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&e)
+	return &StatusError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: cmp.Or(e.Error, resp.Status)}
+}
+
+// retryable reports whether a request may be sent again after it got resp,
+// or failed with err before getting a reply. Requests that may have been
+// acted on are only retried if they are idempotent, by their method or
+// because they carry an idempotency key.
+func retryable(method string, keyed bool, resp *http.Response, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	idempotent := keyed || (method != http.MethodPost && method != http.MethodPatch)
+	if resp == nil {
+		return idempotent
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		// The server turned the request away without acting on it.
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	case http.StatusConflict:
+		// An earlier attempt with the same key is still being handled.
+		return keyed
+	}
+	return false
+}
+
+// wait sleeps before retrying a request for the attempt'th time, for as
+// long as resp's Retry-After asks, if it has one.
+func (c *Client) wait(ctx context.Context, attempt int, resp *http.Response) error {
+	d := min(c.backoff<<attempt, maxBackoff)
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			d = time.Duration(secs) * time.Second
+		}
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/idempotency"
+	"github.com/jfmatt/snapfold/lib/protocol"
+)
+
+var ctx = context.Background()
+
+func TestDo_Retries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			json.NewEncoder(w).Encode(map[string]string{"ok": "yes"})
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithRetries(3, time.Millisecond))
+	var out map[string]string
+	AssertThat(t, c.Do(ctx, http.MethodPost, "/v1/tickets", map[string]string{"game_mode": "holdem"}, &out), Nil())
+	ExpectEq(t, out["ok"], "yes")
+	ExpectEq(t, calls.Load(), int32(3))
+}
+
+func TestDo_NoRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "no such thing"})
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	c := New(srv.URL, WithRetries(3, time.Millisecond))
+
+	// A POST that may have reached the matchmaker isn't sent again.
+	err := c.Do(ctx, http.MethodPost, "/v1/tickets", nil, nil)
+	ExpectThat(t, err, ErrorMessage(HasSubstr("502 Bad Gateway")))
+	ExpectEq(t, calls.Load(), int32(1))
+
+	// A GET is.
+	calls.Store(0)
+	ExpectThat(t, c.Do(ctx, http.MethodGet, "/v1/regions", nil, nil), Not(Nil()))
+	ExpectEq(t, calls.Load(), int32(4))
+
+	calls.Store(0)
+	err = c.Do(ctx, http.MethodGet, "/missing", nil, nil)
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+	ExpectEq(t, err.Error(), "GET /missing: no such thing")
+	ExpectEq(t, calls.Load(), int32(1))
+}
+
+func TestDoOnce(t *testing.T) {
+	var calls atomic.Int32
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(idempotency.Header))
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	c := New(srv.URL, WithRetries(3, time.Millisecond))
+
+	// A POST with an idempotency key is safe to send again, with the same
+	// key.
+	AssertThat(t, c.doOnce(ctx, http.MethodPost, "/v1/tickets", nil, nil), Nil())
+	AssertThat(t, keys, Len(2))
+	ExpectEq(t, keys[0] != "", true)
+	ExpectEq(t, keys[1], keys[0])
+}
+
+func TestRefresh(t *testing.T) {
+	var refreshes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sessions/refresh":
+			var req struct {
+				RefreshToken string `json:"refresh_token"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.RefreshToken != "r1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			refreshes.Add(1)
+			json.NewEncoder(w).Encode(Session{PlayerID: "alice", Token: "a2", ExpiresAt: time.Now().Add(time.Hour), RefreshToken: "r2"})
+		default:
+			if r.Header.Get("Authorization") != "Bearer a2" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	var saved []Session
+	c := New(srv.URL,
+		WithSession(Session{PlayerID: "alice", Token: "a1", ExpiresAt: time.Now().Add(time.Second), RefreshToken: "r1"}),
+		OnSession(func(ctx context.Context, s Session) error {
+			saved = append(saved, s)
+			return nil
+		}))
+
+	// The access token is about to expire, so it is refreshed first, once.
+	AssertThat(t, c.Do(ctx, http.MethodGet, "/v1/sessions", nil, nil), Nil())
+	AssertThat(t, c.Do(ctx, http.MethodGet, "/v1/sessions", nil, nil), Nil())
+	ExpectEq(t, refreshes.Load(), int32(1))
+	ExpectEq(t, c.Session().RefreshToken, "r2")
+	AssertThat(t, saved, Len(1))
+	ExpectEq(t, saved[0].Token, "a2")
+
+	// The old refresh token has been used.
+	_, err := New(srv.URL, WithSession(Session{Token: "a1", RefreshToken: "r2"})).Refresh(ctx)
+	ExpectThat(t, err, ErrorIs(ErrUnauthorized))
+}
+
+func TestProtocol(t *testing.T) {
+	srv := httptest.NewServer(protocol.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(protocol.FromContext(r.Context()))
+	})))
+	defer srv.Close()
+
+	var got protocol.Hello
+	AssertThat(t, New(srv.URL).Do(ctx, http.MethodGet, "/", nil, &got), Nil())
+	ExpectEq(t, got, protocol.Current)
+
+	err := New(srv.URL, WithProtocol(protocol.Hello{Version: 0})).Do(ctx, http.MethodGet, "/", nil, nil)
+	ExpectThat(t, err, ErrorIs(protocol.ErrUnsupported))
+	ExpectThat(t, err, ErrorMessage(HasSubstr("please update")))
+}
@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ConfigEntry is a config in the matchmaker's config registry.
+type ConfigEntry struct {
+	Name string `json:"name"`
+
+	// 1 when the config was first stored, and one more for each change.
+	Revision  int64     `json:"revision"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Configs lists the configs of a kind, table-configs or match-rules, in the
+// matchmaker's config registry. The registry is read with apiKey, an API key
+// with the configs scope, rather than as the signed-in player.
+func (c *Client) Configs(ctx context.Context, apiKey, kind string) ([]ConfigEntry, error) {
+	var entries []ConfigEntry
+	err := c.do(ctx, http.MethodGet, "/v1/configs/"+url.PathEscape(kind), apiKey, "", nil, &entries)
+	return entries, err
+}
+
+// Config returns the config of a kind with the name from the matchmaker's
+// config registry, as protojson, read with apiKey as Configs is. It fails
+// with ErrNotFound if there is no such config.
+func (c *Client) Config(ctx context.Context, apiKey, kind, name string) (json.RawMessage, error) {
+	var cfg json.RawMessage
+	err := c.do(ctx, http.MethodGet, "/v1/configs/"+url.PathEscape(kind)+"/"+url.PathEscape(name), apiKey, "", nil, &cfg)
+	return cfg, err
+}
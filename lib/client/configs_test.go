@@ -0,0 +1,47 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/api"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/registry"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func TestConfigs(t *testing.T) {
+	reg := registry.New(registry.NewMemStore())
+	_, err := reg.Put(ctx, registry.KindTableConfig, "holdem", []byte(`{"blinds": {"blindLevels": [{"units": "1"}, {"units": "2"}]}}`), 0)
+	AssertThat(t, err, Nil())
+	srv := httptest.NewServer(api.NewServer(api.Config{
+		Lobby:         lobby.New(queue.New(), party.NewManager(), nil),
+		Sessions:      session.NewStore(),
+		Registry:      reg,
+		InternalToken: "secret",
+	}))
+	defer srv.Close()
+	c := New(srv.URL)
+
+	entries, err := c.Configs(ctx, "secret", "table-configs")
+	AssertThat(t, err, Nil())
+	AssertThat(t, entries, Len(1))
+	ExpectEq(t, entries[0].Name, "holdem")
+	ExpectEq(t, entries[0].Revision, int64(1))
+
+	b, err := c.Config(ctx, "secret", "table-configs", "holdem")
+	AssertThat(t, err, Nil())
+	var cfg map[string]any
+	AssertThat(t, json.Unmarshal(b, &cfg), Nil())
+	ExpectEq(t, cfg["variant"], "HOLDEM")
+
+	_, err = c.Config(ctx, "secret", "table-configs", "omaha")
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+	_, err = c.Configs(ctx, "wrong", "table-configs")
+	ExpectThat(t, err, ErrorIs(ErrUnauthorized))
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+// Ticket is a player's, or their party's, place in the queue.
+type Ticket struct {
+	ID       string   `json:"ticket_id"`
+	GameMode string   `json:"game_mode"`
+	Players  []string `json:"players"`
+	Priority string   `json:"priority"`
+
+	// queued, matched, canceled or expired.
+	State string `json:"state"`
+
+	// Only meaningful while the ticket is queued.
+	Position int `json:"position"`
+
+	// Set once the ticket is matched.
+	MatchID string `json:"match_id,omitempty"`
+}
+
+// Seat is where the matchmaker has seated the player.
+type Seat struct {
+	TableID  string `json:"table_id"`
+	MatchID  string `json:"match_id"`
+	GameMode string `json:"game_mode"`
+	Region   string `json:"region,omitempty"`
+
+	// host:port of the game server hosting the table, if known. Pass it to
+	// GameServer to play at the table.
+	ServerAddress string `json:"server_address,omitempty"`
+
+	// Set while the player is disconnected: when they lose their seat unless
+	// they rejoin.
+	RejoinDeadline *time.Time `json:"rejoin_deadline,omitempty"`
+}
+
+// Enqueue queues the player, and their party if they lead one, for a game
+// mode.
+func (c *Client) Enqueue(ctx context.Context, gameMode string) (Ticket, error) {
+	var t Ticket
+	err := c.doOnce(ctx, http.MethodPost, "/v1/tickets", map[string]string{"game_mode": gameMode}, &t)
+	return t, err
+}
+
+// Ticket returns one of the player's tickets.
+func (c *Client) Ticket(ctx context.Context, id string) (Ticket, error) {
+	var t Ticket
+	err := c.Do(ctx, http.MethodGet, "/v1/tickets/"+url.PathEscape(id), nil, &t)
+	return t, err
+}
+
+// CancelTicket takes one of the player's tickets out of the queue. Canceling
+// a ticket that has already left the queue does nothing.
+func (c *Client) CancelTicket(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodDelete, "/v1/tickets/"+url.PathEscape(id), nil, nil)
+}
+
+// AcceptMatch accepts a match's ready check.
+func (c *Client) AcceptMatch(ctx context.Context, matchID string) error {
+	return c.Do(ctx, http.MethodPost, "/v1/matches/"+url.PathEscape(matchID)+"/accept", nil, nil)
+}
+
+// DeclineMatch declines a match's ready check, which returns the other
+// players to the queue.
+func (c *Client) DeclineMatch(ctx context.Context, matchID string) error {
+	return c.Do(ctx, http.MethodPost, "/v1/matches/"+url.PathEscape(matchID)+"/decline", nil, nil)
+}
+
+// Rejoin returns the seat the player holds, and reconnects them to it if
+// they were disconnected. It fails with ErrNotFound if they aren't seated.
+func (c *Client) Rejoin(ctx context.Context) (Seat, error) {
+	var s Seat
+	err := c.Do(ctx, http.MethodPost, "/v1/rejoin", nil, &s)
+	return s, err
+}
+
+// WaitForSeat polls the matchmaker every interval for the player's seat,
+// which is known once a game server has opened their match's table.
+func (c *Client) WaitForSeat(ctx context.Context, interval time.Duration) (Seat, error) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		s, err := c.Rejoin(ctx)
+		if !errors.Is(err, ErrNotFound) {
+			return s, err
+		}
+		select {
+		case <-ctx.Done():
+			return Seat{}, ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// Lobby is the stream of a queued ticket's events.
+type Lobby struct {
+	socket *socket
+}
+
+// Lobby opens the stream of a queued ticket's events: its position in the
+// queue, ready checks, and how the search ends.
+func (c *Client) Lobby(ctx context.Context, ticketID string) (*Lobby, error) {
+	s, err := c.dial(ctx, "/v1/tickets/"+url.PathEscape(ticketID)+"/lobby")
+	if err != nil {
+		return nil, err
+	}
+	return &Lobby{socket: s}, nil
+}
+
+// Recv returns the next event.
+func (l *Lobby) Recv() (*pb.LobbyEvent, error) {
+	ev := &pb.LobbyEvent{}
+	if err := l.socket.recv(ev); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// Close closes the stream.
+func (l *Lobby) Close() error {
+	return l.socket.close()
+}
+
+// seatPollInterval is how often Queue asks for the player's seat once they
+// are matched.
+const seatPollInterval = time.Second
+
+// Queue queues the player for a game mode, accepts every ready check, and
+// returns their seat once they are matched and the match's table is open.
+// If onEvent isn't nil, it is called with each lobby event, such as to show
+// the player's progress. It fails if the ticket is canceled or expires.
+func (c *Client) Queue(ctx context.Context, gameMode string, onEvent func(*pb.LobbyEvent)) (Seat, error) {
+	t, err := c.Enqueue(ctx, gameMode)
+	if err != nil {
+		return Seat{}, err
+	}
+	lobby, err := c.Lobby(ctx, t.ID)
+	if err != nil {
+		return Seat{}, err
+	}
+	defer lobby.Close()
+	// Reading doesn't watch the context, but fails once the stream is
+	// closed.
+	stop := context.AfterFunc(ctx, func() { lobby.Close() })
+	defer stop()
+	for {
+		ev, err := lobby.Recv()
+		if ctx.Err() != nil {
+			return Seat{}, ctx.Err()
+		}
+		if err != nil {
+			return Seat{}, fmt.Errorf("lobby: %w", err)
+		}
+		if onEvent != nil {
+			onEvent(ev)
+		}
+		switch ev.WhichEvent() {
+		case pb.LobbyEvent_ReadyCheck_case:
+			if err := c.AcceptMatch(ctx, ev.GetReadyCheck().GetMatchId()); err != nil {
+				return Seat{}, err
+			}
+		case pb.LobbyEvent_MatchFound_case:
+			return c.WaitForSeat(ctx, seatPollInterval)
+		case pb.LobbyEvent_TicketCanceled_case:
+			return Seat{}, fmt.Errorf("ticket %s canceled", t.ID)
+		case pb.LobbyEvent_TicketExpired_case:
+			return Seat{}, fmt.Errorf("ticket %s expired", t.ID)
+		}
+	}
+}
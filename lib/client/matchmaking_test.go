@@ -0,0 +1,57 @@
+package client
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/matchmaker/api"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func TestMatchmaking(t *testing.T) {
+	q := queue.New()
+	srv := httptest.NewServer(api.NewServer(api.Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: session.NewStore()}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	s, err := c.Login(ctx, "alice", "")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.PlayerID, "alice")
+
+	_, err = c.Rejoin(ctx)
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+
+	tk, err := c.Enqueue(ctx, "holdem")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, tk.GameMode, "holdem")
+	ExpectEq(t, tk.State, "queued")
+	got, err := c.Ticket(ctx, tk.ID)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, got.Players, ElementsAre("alice"))
+	AssertThat(t, c.CancelTicket(ctx, tk.ID), Nil())
+	ExpectEq(t, q.Len(), 0)
+
+	// Queueing reports each lobby event and fails once the ticket is
+	// canceled.
+	events := make(chan *pb.LobbyEvent, 10)
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Queue(ctx, "holdem", func(ev *pb.LobbyEvent) { events <- ev })
+		done <- err
+	}()
+	ev := <-events
+	AssertEq(t, ev.HasQueueStatus(), true)
+	_, err = q.Cancel(ctx, ev.GetQueueStatus().GetTicketId())
+	AssertThat(t, err, Nil())
+	ExpectThat(t, <-done, ErrorMessage(HasSubstr("canceled")))
+
+	AssertThat(t, c.Logout(ctx), Nil())
+	_, err = c.Enqueue(ctx, "holdem")
+	ExpectThat(t, err, ErrorIs(ErrUnauthorized))
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/jfmatt/snapfold/lib/frame"
+)
+
+// socket is a WebSocket to a server, whose messages are frames or bare
+// protobuf messages, as negotiated with the server.
+type socket struct {
+	conn   *websocket.Conn
+	framed bool
+
+	// The payloads of the last message read that haven't been returned.
+	pending [][]byte
+
+	// Writes to a WebSocket may not be concurrent.
+	mu sync.Mutex
+}
+
+// recv reads the next message into m.
+func (s *socket) recv(m proto.Message) error {
+	for len(s.pending) == 0 {
+		_, b, err := s.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if !s.framed {
+			return proto.Unmarshal(b, m)
+		}
+		if s.pending, err = frame.Split(b); err != nil {
+			return err
+		}
+	}
+	b := s.pending[0]
+	s.pending = s.pending[1:]
+	return proto.Unmarshal(b, m)
+}
+
+// send writes m.
+func (s *socket) send(m proto.Message) error {
+	var b []byte
+	var err error
+	if s.framed {
+		b, err = frame.Marshal(m)
+	} else {
+		b, err = proto.Marshal(m)
+	}
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.WriteMessage(websocket.BinaryMessage, b)
+}
+
+func (s *socket) close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/jfmatt/snapfold/lib/frame"
+)
+
+// bodyStream is a response body of frames from a server. Messages only
+// flow from the server.
+type bodyStream struct {
+	body   io.ReadCloser
+	frames *frame.Reader
+
+	// Ends the request.
+	cancel func()
+}
+
+// recv reads the next message into m.
+func (s *bodyStream) recv(m proto.Message) error {
+	b, err := s.frames.Next()
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, m)
+}
+
+// send fails: the client can't write to a response.
+func (s *bodyStream) send(m proto.Message) error {
+	return fmt.Errorf("sending on an HTTP/3 stream: %w", errors.ErrUnsupported)
+}
+
+func (s *bodyStream) close() error {
+	s.cancel()
+	return s.body.Close()
+}
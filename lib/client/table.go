@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+// Table is a connection to a table on a game server, as a player seated at
+// it or as a spectator.
+type Table struct {
+	conn conn
+
+	// Whether the table has closed.
+	closed bool
+}
+
+// conn carries messages to and from a server: a *socket, or a *bodyStream
+// for clients made WithHTTP3.
+type conn interface {
+	recv(m proto.Message) error
+	send(m proto.Message) error
+	close() error
+}
+
+// JoinTable connects to a table the player is seated at, on a Client
+// returned by GameServer. Its events start with a snapshot of the table.
+func (c *Client) JoinTable(ctx context.Context, tableID string) (*Table, error) {
+	return c.openTable(ctx, "/v1/tables/"+url.PathEscape(tableID)+"/events")
+}
+
+// WatchTable connects to a table as a spectator, on a Client returned by
+// GameServer. Spectators see everything but players' hole cards, and only
+// tables whose game mode allows them can be watched.
+func (c *Client) WatchTable(ctx context.Context, tableID string) (*Table, error) {
+	return c.openTable(ctx, "/v1/tables/"+url.PathEscape(tableID)+"/watch")
+}
+
+func (c *Client) openTable(ctx context.Context, path string) (*Table, error) {
+	if c.h3 != nil {
+		s, err := c.stream(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return &Table{conn: s}, nil
+	}
+	s, err := c.dial(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return &Table{conn: s}, nil
+}
+
+// Recv returns the table's next event, or io.EOF once the table has closed.
+// A game server drops a connection that falls too far behind, which gives a
+// *websocket.CloseError, or io.ErrUnexpectedEOF over HTTP/3; connecting
+// again starts over with a fresh snapshot.
+func (t *Table) Recv() (*pb.TableEvent, error) {
+	ev := &pb.TableEvent{}
+	err := t.conn.recv(ev)
+	switch {
+	case websocket.IsCloseError(err, websocket.CloseNormalClosure):
+		return nil, io.EOF
+	case err == io.EOF && !t.closed:
+		// Streams that are cut short end without the table closing.
+		return nil, io.ErrUnexpectedEOF
+	case err != nil:
+		return nil, err
+	}
+	t.closed = ev.HasTableClosed()
+	return ev, nil
+}
+
+// Act takes an action at the table on the player's turn. to is the total to
+// bet or raise to, and is ignored for other actions. Act returns once the
+// action is sent; the table answers one it doesn't accept, such as one out
+// of turn, with an ActionRejected event. Over HTTP/3, it fails with
+// errors.ErrUnsupported.
+func (t *Table) Act(kind pb.TableAction_Kind, to int64) error {
+	a := pb.TableAction_builder{Kind: kind.Enum()}
+	if to > 0 {
+		a.To = proto.Int64(to)
+	}
+	return t.conn.send(a.Build())
+}
+
+// Close disconnects from the table.
+func (t *Table) Close() error {
+	return t.conn.close()
+}
+
+// Chat sends a message to everyone at a table the player is seated at.
+func (c *Client) Chat(ctx context.Context, tableID, text string) error {
+	return c.Do(ctx, http.MethodPost, "/v1/tables/"+url.PathEscape(tableID)+"/chat", map[string]string{"text": text}, nil)
+}
+
+// SitOut sits the player out at a table: they keep their seat, but are
+// dealt out of hands until they return.
+func (c *Client) SitOut(ctx context.Context, tableID string) error {
+	return c.Do(ctx, http.MethodPost, "/v1/tables/"+url.PathEscape(tableID)+"/sit-out", nil, nil)
+}
+
+// Return brings the player back into play at a table they sat out at.
+func (c *Client) Return(ctx context.Context, tableID string) error {
+	return c.Do(ctx, http.MethodPost, "/v1/tables/"+url.PathEscape(tableID)+"/return", nil, nil)
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"github.com/quic-go/quic-go/http3"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/gameserver/api"
+	"github.com/jfmatt/snapfold/gameserver/host"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/lib/protocol"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func TestJoinTable_HTTP3(t *testing.T) {
+	h := host.New(1)
+	table, err := h.Assign(host.Assignment{MatchID: "m1", PlayerIDs: []string{"alice", "bob"}})
+	AssertThat(t, err, Nil())
+	sessions := session.NewStore()
+	handler := api.NewServer(api.Config{Host: h, Sessions: sessions})
+
+	// Borrow httptest's certificate, and its client's trust in it.
+	certs := httptest.NewTLSServer(handler)
+	defer certs.Close()
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	AssertThat(t, err, Nil())
+	srv := &http3.Server{Handler: handler, TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: certs.TLS.Certificates})}
+	defer srv.Close()
+	go srv.Serve(udp)
+
+	trust := certs.Client().Transport.(*http.Transport).TLSClientConfig
+	session := Session{PlayerID: "alice", Token: sessions.Create("alice"), ExpiresAt: time.Now().Add(time.Hour)}
+	gs := New("http://matchmaker.invalid", WithSession(session), WithHTTP3(trust)).GameServer(udp.LocalAddr().String())
+	tbl, err := gs.JoinTable(ctx, "m1")
+	AssertThat(t, err, Nil())
+	defer tbl.Close()
+
+	ev, err := tbl.Recv()
+	AssertThat(t, err, Nil())
+	ExpectEq(t, ev.HasSnapshot(), true)
+	ExpectThat(t, tbl.Act(pb.TableAction_FOLD, 0), ErrorIs(errors.ErrUnsupported))
+
+	AssertThat(t, table.Close(ctx, "game over"), Nil())
+	ev, err = tbl.Recv()
+	AssertThat(t, err, Nil())
+	ExpectEq(t, ev.GetTableClosed().GetReason(), "game over")
+	_, err = tbl.Recv()
+	ExpectThat(t, err, ErrorIs(io.EOF))
+
+	_, err = gs.JoinTable(ctx, "m2")
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+}
+
+func TestTable_Act(t *testing.T) {
+	cfg, err := gamedefio.LoadPreset("heads-up")
+	AssertThat(t, err, Nil())
+	for _, hello := range []protocol.Hello{protocol.Current, {Version: protocol.MinVersion}} {
+		h := host.New(1)
+		_, err := h.Assign(host.Assignment{MatchID: "m1", PlayerIDs: []string{"alice"}, Bots: 1, Stacks: map[string]int64{"alice": 10000}, Config: cfg})
+		AssertThat(t, err, Nil())
+		sessions := session.NewStore()
+		srv := httptest.NewServer(api.NewServer(api.Config{Host: h, Sessions: sessions}))
+		defer srv.Close()
+
+		session := Session{PlayerID: "alice", Token: sessions.Create("alice"), ExpiresAt: time.Now().Add(time.Hour)}
+		gs := New("http://matchmaker.invalid", WithSession(session), WithProtocol(hello)).GameServer(srv.URL)
+		tbl, err := gs.JoinTable(ctx, "m1")
+		AssertThat(t, err, Nil())
+		defer tbl.Close()
+		until := func(match func(*pb.TableEvent) bool) *pb.TableEvent {
+			t.Helper()
+			for {
+				ev, err := tbl.Recv()
+				AssertThat(t, err, Nil())
+				if match(ev) {
+					return ev
+				}
+			}
+		}
+
+		// Actions the table doesn't accept are answered, and the turn goes
+		// on.
+		opts := until((*pb.TableEvent).HasTurnOptions).GetTurnOptions()
+		AssertThat(t, tbl.Act(pb.TableAction_RAISE, opts.GetMinTo()-1), Nil())
+		ExpectThat(t, until((*pb.TableEvent).HasActionRejected).GetActionRejected().GetReason(), StartsWith("invalid action"))
+
+		kind, want := pb.TableAction_CHECK, pb.PlayerActed_CHECK
+		if opts.GetCall() > 0 {
+			kind, want = pb.TableAction_CALL, pb.PlayerActed_CALL
+		}
+		AssertThat(t, tbl.Act(kind, 0), Nil())
+		acted := until(func(ev *pb.TableEvent) bool {
+			a := ev.GetPlayerActed()
+			return a.GetPlayerId() == "alice" && a.GetKind() != pb.PlayerActed_BLIND
+		})
+		ExpectEq(t, acted.GetPlayerActed().GetKind(), want)
+	}
+}
@@ -0,0 +1,221 @@
+// Package config layers a binary's settings from several sources onto its
+// command-line flags, so that the typed flag structs its commands take can
+// be filled in however it is deployed.
+//
+// Each flag takes the first value given, in this order:
+//
+//  1. the flag on the command line, such as --db.health-interval=30s;
+//  2. the environment variable named after it, such as
+//     MATCHMAKER_DB_HEALTH_INTERVAL=30s;
+//  3. the config file given by --config-file, or its environment variable
+//     such as MATCHMAKER_CONFIG_FILE, as YAML or JSON, such as
+//     "db: {health-interval: 30s}" or "db.health-interval: 30s";
+//  4. the default in the flag's tag.
+//
+// Keys in the config file that name no flag of the command being run are
+// ignored, so that one file can configure each of a binary's commands.
+//
+// A Watcher rereads the config file while the binary runs, for the flags
+// that can change without a restart.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// FileFlag is the flag that gives the config file.
+const FileFlag = "config-file"
+
+// ErrBadFile is returned when the config file can't be read.
+var ErrBadFile = errors.New("invalid config file")
+
+// sourceKey annotates the flags that Apply found on the command line or in
+// the environment, which the config file can't change.
+const sourceKey = "config-source"
+
+// Register adds --config-file to cmd and its subcommands, and has each of
+// them layer their flags with Apply before running. envPrefix starts the
+// names of the binary's environment variables, such as MATCHMAKER.
+func Register(cmd *cobra.Command, envPrefix string) {
+	cmd.PersistentFlags().String(FileFlag, "", fmt.Sprintf("YAML or JSON file of flag values, which flags and %s_* environment variables override", envPrefix))
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return Apply(cmd.Flags(), envPrefix)
+	}
+}
+
+// EnvName returns the name of the environment variable a flag is read from:
+// the prefix and the flag's name in upper case, joined by underscores, with
+// dots and dashes also made underscores.
+func EnvName(prefix, flag string) string {
+	return strings.ToUpper(prefix + "_" + strings.NewReplacer(".", "_", "-", "_").Replace(flag))
+}
+
+// Apply sets each flag in fs that wasn't given on the command line from the
+// environment, or else the config file, or else its default.
+func Apply(fs *pflag.FlagSet, envPrefix string) error {
+	given := map[string]bool{}
+	fs.Visit(func(f *pflag.Flag) {
+		given[f.Name] = true
+		annotate(f, "flag")
+	})
+
+	var errs []error
+	set := func(f *pflag.Flag, value, source string) {
+		if err := fs.Set(f.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: --%s: %w", source, f.Name, err))
+		}
+		given[f.Name] = true
+	}
+	fs.VisitAll(func(f *pflag.Flag) {
+		if given[f.Name] {
+			return
+		}
+		name := EnvName(envPrefix, f.Name)
+		if v, ok := os.LookupEnv(name); ok {
+			set(f, v, name)
+			annotate(f, "env")
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if path, _ := fs.GetString(FileFlag); path != "" {
+		values, err := readFile(path, fs)
+		if err != nil {
+			return err
+		}
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			f := fs.Lookup(name)
+			if f == nil || given[f.Name] {
+				continue
+			}
+			for _, v := range values[name] {
+				set(f, v, path)
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+	}
+
+	// Durations are only read from flags that were set, so their defaults
+	// are set explicitly.
+	fs.VisitAll(func(f *pflag.Flag) {
+		if !given[f.Name] && f.Value.Type() == "duration" && f.DefValue != "0s" {
+			set(f, f.DefValue, "default")
+		}
+	})
+	return errors.Join(errs...)
+}
+
+func annotate(f *pflag.Flag, source string) {
+	if f.Annotations == nil {
+		f.Annotations = map[string][]string{}
+	}
+	f.Annotations[sourceKey] = []string{source}
+}
+
+// fromFile reports whether f takes its value from the config file, or its
+// default, rather than the command line or environment.
+func fromFile(f *pflag.Flag) bool {
+	return len(f.Annotations[sourceKey]) == 0
+}
+
+// readFile reads a config file into the values to set each flag to, by
+// flag name. Nested mappings' keys are joined with dots.
+func readFile(path string, fs *pflag.FlagSet) (map[string][]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrBadFile, path, err)
+	}
+	values := map[string][]string{}
+	if err := flatten(values, "", doc, fs); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrBadFile, path, err)
+	}
+	return values, nil
+}
+
+func flatten(values map[string][]string, prefix string, m map[string]any, fs *pflag.FlagSet) error {
+	for k, v := range m {
+		name := k
+		if prefix != "" {
+			name = prefix + "." + k
+		}
+		f := fs.Lookup(name)
+		if nested, ok := v.(map[string]any); ok && !isMap(f) {
+			if err := flatten(values, name, nested, fs); err != nil {
+				return err
+			}
+			continue
+		}
+		if f == nil {
+			continue
+		}
+		vs, err := flagValues(f, v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		values[name] = vs
+	}
+	return nil
+}
+
+// flagValues formats a value from a config file as f would be given it on
+// the command line: once, or once for each element of a list.
+func flagValues(f *pflag.Flag, v any) ([]string, error) {
+	if list, ok := v.([]any); ok && (strings.HasSuffix(f.Value.Type(), "Slice") || strings.HasSuffix(f.Value.Type(), "Array")) {
+		out := make([]string, len(list))
+		for i, e := range list {
+			s, err := flagValue(e)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = s
+		}
+		return out, nil
+	}
+	if m, ok := v.(map[string]any); ok && isMap(f) {
+		var parts []string
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			parts = append(parts, fmt.Sprintf("%s=%v", k, m[k]))
+		}
+		return []string{strings.Join(parts, ",")}, nil
+	}
+	s, err := flagValue(v)
+	return []string{s}, err
+}
+
+// flagValue formats a scalar, or else JSON for the flags of structs.
+func flagValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []any, map[string]any:
+		b, err := json.Marshal(v)
+		return string(b), err
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// isMap reports whether f takes key=value pairs.
+func isMap(f *pflag.Flag) bool {
+	return f != nil && strings.HasPrefix(f.Value.Type(), "stringTo")
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"github.com/spf13/pflag"
+)
+
+func flags() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.String(FileFlag, "", "")
+	fs.String("host", "0.0.0.0", "")
+	fs.Int("port", 8080, "")
+	fs.Duration("db.health-interval", 10*time.Second, "")
+	fs.Duration("db.health-timeout", 2*time.Second, "")
+	fs.Duration("db.conn-max-lifetime", 0, "")
+	fs.StringSlice("regions", nil, "")
+	fs.StringToString("labels", nil, "")
+	return fs
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	AssertThat(t, os.WriteFile(path, []byte(content), 0o600), Nil())
+	return path
+}
+
+func TestEnvName(t *testing.T) {
+	ExpectEq(t, EnvName("MATCHMAKER", "db.health-interval"), "MATCHMAKER_DB_HEALTH_INTERVAL")
+	ExpectEq(t, EnvName("GAMESERVER", "port"), "GAMESERVER_PORT")
+}
+
+func TestApply(t *testing.T) {
+	path := writeFile(t, `
+host: 10.0.0.1
+port: 9000
+db:
+  health-interval: 1m
+  health-timeout: 5s
+regions: [us-east, eu-west]
+labels: {tier: gold}
+unknown: ignored
+`)
+	t.Setenv("TEST_PORT", "9001")
+	t.Setenv("TEST_DB_HEALTH_TIMEOUT", "3s")
+	fs := flags()
+	AssertThat(t, fs.Parse([]string{"--config-file", path, "--db.health-timeout=4s"}), Nil())
+	AssertThat(t, Apply(fs, "TEST"), Nil())
+
+	host, _ := fs.GetString("host")
+	ExpectEq(t, host, "10.0.0.1") // file
+	port, _ := fs.GetInt("port")
+	ExpectEq(t, port, 9001) // environment over file
+	interval, _ := fs.GetDuration("db.health-interval")
+	ExpectEq(t, interval, time.Minute) // file
+	timeout, _ := fs.GetDuration("db.health-timeout")
+	ExpectEq(t, timeout, 4*time.Second) // flag over environment and file
+	regions, _ := fs.GetStringSlice("regions")
+	ExpectThat(t, regions, ElementsAre("us-east", "eu-west"))
+	labels, _ := fs.GetStringToString("labels")
+	ExpectEq(t, labels["tier"], "gold")
+}
+
+func TestApply_Defaults(t *testing.T) {
+	fs := flags()
+	AssertThat(t, fs.Parse(nil), Nil())
+	AssertThat(t, Apply(fs, "TEST"), Nil())
+
+	// Durations' defaults count as set, so that flagr reads them.
+	ExpectEq(t, fs.Changed("db.health-interval"), true)
+	ExpectEq(t, fs.Changed("db.conn-max-lifetime"), false)
+	ExpectEq(t, fs.Changed("port"), false)
+}
+
+func TestApply_Errors(t *testing.T) {
+	t.Setenv("TEST_PORT", "many")
+	fs := flags()
+	AssertThat(t, fs.Parse(nil), Nil())
+	ExpectThat(t, Apply(fs, "TEST"), ErrorMessage(HasSubstr("TEST_PORT")))
+
+	fs = flags()
+	AssertThat(t, fs.Parse([]string{"--config-file", writeFile(t, "port: [")}), Nil())
+	ExpectThat(t, Apply(fs, "OTHER"), ErrorIs(ErrBadFile))
+
+	fs = flags()
+	AssertThat(t, fs.Parse([]string{"--config-file", writeFile(t, "db: {health-interval: 30}")}), Nil())
+	ExpectThat(t, Apply(fs, "OTHER"), ErrorMessage(HasSubstr("db.health-interval")))
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Change is a flag whose value in the config file changed.
+type Change struct {
+	Flag string
+
+	// The flag's value before and after. Where a flag that needs a restart
+	// was added to or removed from the file, its value there, or empty.
+	Old, New string
+}
+
+// Watcher rereads the config file for changes to flags that were already
+// layered by Apply. It is not safe for concurrent use.
+type Watcher struct {
+	fs   *pflag.FlagSet
+	path string
+	live map[string]bool
+
+	// The file's values as last read.
+	last map[string][]string
+}
+
+// NewWatcher returns a Watcher of the config file given to fs, if any. live
+// names the flags that the binary reads again when they change, which
+// Reload sets; any other flag changed in the file takes effect on restart.
+// Live flags should be ones that a value replaces, such as strings,
+// numbers, durations and slices, rather than maps.
+func NewWatcher(fs *pflag.FlagSet, live ...string) (*Watcher, error) {
+	w := &Watcher{fs: fs, live: map[string]bool{}}
+	for _, name := range live {
+		w.live[name] = true
+	}
+	w.path, _ = fs.GetString(FileFlag)
+	if w.path == "" {
+		return w, nil
+	}
+	var err error
+	w.last, err = readFile(w.path, fs)
+	return w, err
+}
+
+// Path returns the config file being watched, or "" if there is none.
+func (w *Watcher) Path() string {
+	return w.path
+}
+
+// Reload rereads the config file. Each live flag whose value there changed
+// is set to it, or back to its default if it was removed, unless the flag
+// was given on the command line or in the environment, which still take
+// precedence. It returns the live flags it changed, and the other flags
+// changed in the file, which need a restart. Each change is returned once.
+func (w *Watcher) Reload() (applied, restart []Change, err error) {
+	if w.path == "" {
+		return nil, nil, nil
+	}
+	values, err := readFile(w.path, w.fs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var errs []error
+	names := slices.Sorted(maps.Keys(values))
+	for name := range w.last {
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		f := w.fs.Lookup(name)
+		if f == nil || !fromFile(f) || slices.Equal(values[name], w.last[name]) {
+			continue
+		}
+		if !w.live[name] {
+			restart = append(restart, Change{
+				Flag: name,
+				Old:  strings.Join(w.last[name], ","),
+				New:  strings.Join(values[name], ","),
+			})
+			continue
+		}
+		vs, ok := values[name]
+		if !ok {
+			vs = []string{f.DefValue}
+		}
+		old := f.Value.String()
+		if err := w.set(f, vs); err != nil {
+			errs = append(errs, fmt.Errorf("%s: --%s: %w", w.path, name, err))
+			continue
+		}
+		if v := f.Value.String(); v != old {
+			applied = append(applied, Change{Flag: name, Old: old, New: v})
+		}
+	}
+	w.last = values
+	return applied, restart, errors.Join(errs...)
+}
+
+// set replaces f's value, rather than adding to it as setting a slice flag
+// again would.
+func (w *Watcher) set(f *pflag.Flag, values []string) error {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		return sv.Replace(values)
+	}
+	for _, v := range values {
+		if err := w.fs.Set(f.Name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
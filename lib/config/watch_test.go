@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestWatcher(t *testing.T) {
+	path := writeFile(t, `
+host: 10.0.0.1
+port: 9000
+db: {health-interval: 1m}
+regions: [us-east]
+`)
+	t.Setenv("TEST_PORT", "9001")
+	fs := flags()
+	AssertThat(t, fs.Parse([]string{"--config-file", path}), Nil())
+	AssertThat(t, Apply(fs, "TEST"), Nil())
+	w, err := NewWatcher(fs, "port", "db.health-interval", "db.health-timeout", "regions")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, w.Path(), path)
+
+	applied, restart, err := w.Reload()
+	AssertThat(t, err, Nil())
+	ExpectThat(t, applied, Empty())
+	ExpectThat(t, restart, Empty())
+
+	AssertThat(t, os.WriteFile(path, []byte(`
+host: 10.0.0.2
+port: 9002
+db: {health-timeout: 5s}
+regions: [eu-west, us-west]
+`), 0o600), Nil())
+	applied, restart, err = w.Reload()
+	AssertThat(t, err, Nil())
+	// The environment still overrides the port.
+	ExpectThat(t, applied, ElementsAre(
+		Change{Flag: "db.health-timeout", Old: "2s", New: "5s"},
+		Change{Flag: "regions", Old: "[us-east]", New: "[eu-west,us-west]"},
+		Change{Flag: "db.health-interval", Old: "1m0s", New: "10s"},
+	))
+	ExpectThat(t, restart, ElementsAre(Change{Flag: "host", Old: "10.0.0.1", New: "10.0.0.2"}))
+	interval, _ := fs.GetDuration("db.health-interval")
+	ExpectEq(t, interval, 10*time.Second)
+	port, _ := fs.GetInt("port")
+	ExpectEq(t, port, 9001)
+
+	// Changes are only reported once.
+	applied, restart, err = w.Reload()
+	AssertThat(t, err, Nil())
+	ExpectThat(t, applied, Empty())
+	ExpectThat(t, restart, Empty())
+
+	AssertThat(t, os.WriteFile(path, []byte(`db: {health-timeout: soon}`), 0o600), Nil())
+	_, _, err = w.Reload()
+	ExpectThat(t, err, ErrorMessage(HasSubstr("db.health-timeout")))
+}
+
+func TestWatcher_NoFile(t *testing.T) {
+	fs := flags()
+	AssertThat(t, fs.Parse(nil), Nil())
+	w, err := NewWatcher(fs, "port")
+	AssertThat(t, err, Nil())
+	applied, restart, err := w.Reload()
+	AssertThat(t, err, Nil())
+	ExpectThat(t, applied, Empty())
+	ExpectThat(t, restart, Empty())
+}
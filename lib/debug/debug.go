@@ -0,0 +1,68 @@
+// Package debug serves a running server's profiles and runtime statistics,
+// so that performance problems in production can be diagnosed without
+// deploying a debug build.
+//
+// Under the prefix it is mounted at, such as /v1/admin/debug/, it serves:
+//
+//   - pprof/, the index of net/http/pprof's profiles, and each of them, such
+//     as pprof/heap and pprof/profile?seconds=30 for go tool pprof;
+//   - vars, expvar's variables as JSON, including the "runtime" variable
+//     this package publishes.
+//
+// The handler does no authorization of its own; servers mount it behind
+// their admin checks.
+package debug
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+var start = time.Now()
+
+func init() {
+	expvar.Publish("runtime", expvar.Func(runtimeStats))
+}
+
+// Runtime holds the statistics of the "runtime" variable that expvar's
+// memstats don't cover.
+type Runtime struct {
+	GoVersion     string  `json:"go_version"`
+	Goroutines    int     `json:"goroutines"`
+	GOMAXPROCS    int     `json:"gomaxprocs"`
+	NumCPU        int     `json:"num_cpu"`
+	CgoCalls      int64   `json:"cgo_calls"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+}
+
+func runtimeStats() any {
+	return Runtime{
+		GoVersion:     runtime.Version(),
+		Goroutines:    runtime.NumGoroutine(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		NumCPU:        runtime.NumCPU(),
+		CgoCalls:      runtime.NumCgoCall(),
+		UptimeSeconds: time.Since(start).Seconds(),
+	}
+}
+
+// Handler returns the handler of the debug endpoints, for a mux to route
+// the paths under prefix to. prefix must end in a slash.
+func Handler(prefix string) http.Handler {
+	mux := http.NewServeMux()
+	// The index links to each profile relative to itself.
+	mux.HandleFunc("GET "+prefix+"pprof/{$}", pprof.Index)
+	mux.HandleFunc("GET "+prefix+"pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET "+prefix+"pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET "+prefix+"pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("POST "+prefix+"pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET "+prefix+"pprof/trace", pprof.Trace)
+	mux.HandleFunc("GET "+prefix+"pprof/{profile}", func(w http.ResponseWriter, r *http.Request) {
+		pprof.Handler(r.PathValue("profile")).ServeHTTP(w, r)
+	})
+	mux.Handle("GET "+prefix+"vars", expvar.Handler())
+	return mux
+}
@@ -0,0 +1,46 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func get(t *testing.T, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler("/debug/").ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	return rec
+}
+
+func TestHandler_Pprof(t *testing.T) {
+	rec := get(t, "/debug/pprof/")
+	ExpectEq(t, rec.Code, http.StatusOK)
+	ExpectThat(t, rec.Body.String(), HasSubstr("href='goroutine?debug=1'"))
+
+	rec = get(t, "/debug/pprof/goroutine?debug=1")
+	ExpectEq(t, rec.Code, http.StatusOK)
+	ExpectThat(t, rec.Body.String(), HasSubstr("goroutine profile"))
+
+	ExpectEq(t, get(t, "/debug/pprof/cmdline").Code, http.StatusOK)
+	ExpectEq(t, get(t, "/debug/pprof/heap").Code, http.StatusOK)
+	ExpectEq(t, get(t, "/debug/pprof/nonesuch").Code, http.StatusNotFound)
+	ExpectEq(t, get(t, "/other/pprof/").Code, http.StatusNotFound)
+}
+
+func TestHandler_Vars(t *testing.T) {
+	rec := get(t, "/debug/vars")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var vars struct {
+		Runtime  Runtime        `json:"runtime"`
+		MemStats map[string]any `json:"memstats"`
+	}
+	AssertThat(t, json.Unmarshal(rec.Body.Bytes(), &vars), Nil())
+	ExpectThat(t, vars.Runtime.GoVersion, Not(Empty()))
+	ExpectEq(t, vars.Runtime.Goroutines > 0, true)
+	ExpectEq(t, vars.Runtime.NumCPU > 0, true)
+	ExpectThat(t, vars.MemStats, Not(Empty()))
+}
@@ -0,0 +1,174 @@
+// Package frame is the binary framing of protobuf messages on snapfold's
+// WebSockets, for clients that speak protocol version 3 or later, and on
+// its streamed HTTP responses.
+//
+// Each WebSocket message carries one or more frames, so that a server can
+// send a burst of events at once. A streamed response body is a sequence of
+// frames, read one at a time with a Reader. A frame is a flags byte, the length of its
+// payload as a uvarint, and the payload: a protobuf message, compressed with
+// zstd if the flags have Compressed set. Messages are only compressed when
+// that makes them smaller, which small ones rarely are.
+//
+// Older clients get one bare protobuf message per WebSocket message.
+package frame
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// Compressed is the flag of a frame whose payload is compressed.
+	Compressed byte = 1 << 0
+
+	// compressAbove is the size of the smallest message worth trying to
+	// compress.
+	compressAbove = 128
+
+	// MaxPayload is the largest payload a frame may have, before or after
+	// decompressing.
+	MaxPayload = 1 << 20
+
+	// ContentType is the media type of a response body that is a stream of
+	// frames.
+	ContentType = "application/vnd.snapfold.frames"
+)
+
+// ErrMalformed is returned for data that isn't a sequence of frames.
+var ErrMalformed = errors.New("malformed frame")
+
+// The encoder and decoder are safe for concurrent use, and costly to make.
+var (
+	codecsOnce sync.Once
+	encoder    *zstd.Encoder
+	decoder    *zstd.Decoder
+)
+
+func codecs() (*zstd.Encoder, *zstd.Decoder) {
+	codecsOnce.Do(func() {
+		// Servers write the same events to many sockets, so they favour
+		// speed over ratio.
+		encoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1))
+		decoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(MaxPayload))
+	})
+	return encoder, decoder
+}
+
+// Append appends m's frame to b.
+func Append(b []byte, m proto.Message) ([]byte, error) {
+	payload, err := proto.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > MaxPayload {
+		return nil, fmt.Errorf("%w: %d byte message is too large", ErrMalformed, len(payload))
+	}
+	var flags byte
+	if len(payload) > compressAbove {
+		enc, _ := codecs()
+		if z := enc.EncodeAll(payload, nil); len(z) < len(payload) {
+			flags, payload = Compressed, z
+		}
+	}
+	b = append(b, flags)
+	b = binary.AppendUvarint(b, uint64(len(payload)))
+	return append(b, payload...), nil
+}
+
+// Marshal returns the frames of msgs, to send as one WebSocket message.
+func Marshal[M proto.Message](msgs ...M) ([]byte, error) {
+	var b []byte
+	for _, m := range msgs {
+		var err error
+		if b, err = Append(b, m); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Split returns the payloads of the frames in a WebSocket message,
+// decompressed.
+func Split(b []byte) ([][]byte, error) {
+	var payloads [][]byte
+	for len(b) > 0 {
+		flags := b[0]
+		n, size := binary.Uvarint(b[1:])
+		if size <= 0 || n > MaxPayload || n > uint64(len(b)-1-size) {
+			return nil, fmt.Errorf("%w: bad length", ErrMalformed)
+		}
+		b = b[1+size:]
+		payload, err := decode(flags, b[:n:n])
+		if err != nil {
+			return nil, err
+		}
+		b = b[n:]
+		payloads = append(payloads, payload)
+	}
+	return payloads, nil
+}
+
+// Reader reads the frames of a stream, such as a response body, one at a
+// time.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a Reader of the frames in r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next returns the payload of the stream's next frame, decompressed, or
+// io.EOF if the stream ended after the last one. A stream that ends partway
+// through a frame gives io.ErrUnexpectedEOF.
+func (r *Reader) Next() ([]byte, error) {
+	flags, err := r.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+	if n > MaxPayload {
+		return nil, fmt.Errorf("%w: bad length", ErrMalformed)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r.r, payload); err != nil {
+		return nil, unexpected(err)
+	}
+	return decode(flags, payload)
+}
+
+// unexpected turns the end of a stream partway through a frame into
+// io.ErrUnexpectedEOF.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// decode checks a frame's flags and returns its payload, decompressed.
+func decode(flags byte, payload []byte) ([]byte, error) {
+	if flags&^Compressed != 0 {
+		return nil, fmt.Errorf("%w: unknown flags %#x", ErrMalformed, flags)
+	}
+	if flags&Compressed == 0 {
+		return payload, nil
+	}
+	_, dec := codecs()
+	payload, err := dec.DecodeAll(payload, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return payload, nil
+}
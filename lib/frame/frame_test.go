@@ -0,0 +1,74 @@
+package frame
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMarshal(t *testing.T) {
+	small := wrapperspb.String("check")
+	large := wrapperspb.String(strings.Repeat("all in! ", 100))
+	b, err := Marshal(small, large, small)
+	AssertThat(t, err, Nil())
+
+	// The small message is left alone, and the large one compressed.
+	ExpectEq(t, b[0], byte(0))
+	ExpectEq(t, b[9], Compressed)
+	ExpectEq(t, len(b) < proto.Size(large), true)
+
+	payloads, err := Split(b)
+	AssertThat(t, err, Nil())
+	AssertThat(t, payloads, Len(3))
+	for i, want := range []*wrapperspb.StringValue{small, large, small} {
+		got := &wrapperspb.StringValue{}
+		AssertThat(t, proto.Unmarshal(payloads[i], got), Nil())
+		ExpectEq(t, got.GetValue(), want.GetValue())
+	}
+}
+
+func TestSplit_Malformed(t *testing.T) {
+	for _, b := range [][]byte{
+		{0},          // No length.
+		{0, 5, 1, 2}, // Too short for its length.
+		{2, 1, 0},    // Unknown flag.
+		{Compressed, 3, 1, 2, 3},
+	} {
+		_, err := Split(b)
+		ExpectThat(t, err, ErrorIs(ErrMalformed))
+	}
+}
+
+func TestReader(t *testing.T) {
+	small := wrapperspb.String("fold")
+	large := wrapperspb.String(strings.Repeat("showdown ", 100))
+	b, err := Marshal(small, large)
+	AssertThat(t, err, Nil())
+	more, err := Marshal(small)
+	AssertThat(t, err, Nil())
+
+	r := NewReader(bytes.NewReader(append(b, more...)))
+	for _, want := range []*wrapperspb.StringValue{small, large, small} {
+		p, err := r.Next()
+		AssertThat(t, err, Nil())
+		got := &wrapperspb.StringValue{}
+		AssertThat(t, proto.Unmarshal(p, got), Nil())
+		ExpectEq(t, got.GetValue(), want.GetValue())
+	}
+	_, err = r.Next()
+	ExpectThat(t, err, ErrorIs(io.EOF))
+
+	// A stream cut off partway through a frame isn't mistaken for its end.
+	r = NewReader(bytes.NewReader(b[:len(b)-1]))
+	_, err = r.Next()
+	AssertThat(t, err, Nil())
+	_, err = r.Next()
+	ExpectThat(t, err, ErrorIs(io.ErrUnexpectedEOF))
+	_, err = NewReader(bytes.NewReader([]byte{2, 0})).Next()
+	ExpectThat(t, err, ErrorIs(ErrMalformed))
+}
@@ -0,0 +1,178 @@
+package gamedefio
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Change is one field that differs between two configs.
+type Change struct {
+	// The field's path, as in FieldError, with list indexes and map keys
+	// in brackets: "blinds.blind_levels[1]".
+	Field string `json:"field"`
+
+	// The field's value in each config, formatted for reading. Empty where
+	// the field is unset.
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("+ %s: %s", c.Field, c.New)
+	case c.New == "":
+		return fmt.Sprintf("- %s: %s", c.Field, c.Old)
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", c.Field, c.Old, c.New)
+}
+
+// Diff returns the fields that differ between two configs of the same
+// type, such as two TableConfigs or two MatchRules, in field number order.
+// Messages set in both are compared field by field, and lists element by
+// element.
+func Diff(old, new proto.Message) []Change {
+	var d differ
+	d.message("", old.ProtoReflect(), new.ProtoReflect())
+	return d.changes
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(path, old, new string) {
+	if old != new {
+		d.changes = append(d.changes, Change{Field: path, Old: old, New: new})
+	}
+}
+
+func (d *differ) message(prefix string, old, new protoreflect.Message) {
+	fields := old.Descriptor().Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+		switch {
+		case fd.IsList():
+			d.list(path, fd, old.Get(fd).List(), new.Get(fd).List())
+		case fd.IsMap():
+			d.mapField(path, fd, old.Get(fd).Map(), new.Get(fd).Map())
+		case !old.Has(fd) || !new.Has(fd):
+			d.add(path, field(old, fd), field(new, fd))
+		case fd.Message() != nil && !isLeaf(fd.Message()):
+			d.message(path+".", old.Get(fd).Message(), new.Get(fd).Message())
+		default:
+			d.add(path, format(fd, old.Get(fd)), format(fd, new.Get(fd)))
+		}
+	}
+}
+
+func (d *differ) list(path string, fd protoreflect.FieldDescriptor, old, new protoreflect.List) {
+	for i := range max(old.Len(), new.Len()) {
+		elem := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= old.Len():
+			d.add(elem, "", format(fd, new.Get(i)))
+		case i >= new.Len():
+			d.add(elem, format(fd, old.Get(i)), "")
+		case fd.Message() != nil && !isLeaf(fd.Message()):
+			d.message(elem+".", old.Get(i).Message(), new.Get(i).Message())
+		default:
+			d.add(elem, format(fd, old.Get(i)), format(fd, new.Get(i)))
+		}
+	}
+}
+
+func (d *differ) mapField(path string, fd protoreflect.FieldDescriptor, old, new protoreflect.Map) {
+	var keys []protoreflect.MapKey
+	collect := func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		if !slices.ContainsFunc(keys, func(o protoreflect.MapKey) bool { return o.Interface() == k.Interface() }) {
+			keys = append(keys, k)
+		}
+		return true
+	}
+	old.Range(collect)
+	new.Range(collect)
+	slices.SortFunc(keys, func(a, b protoreflect.MapKey) int { return cmp.Compare(a.String(), b.String()) })
+
+	vd := fd.MapValue()
+	for _, k := range keys {
+		elem := fmt.Sprintf("%s[%s]", path, k.String())
+		switch {
+		case !old.Has(k):
+			d.add(elem, "", format(vd, new.Get(k)))
+		case !new.Has(k):
+			d.add(elem, format(vd, old.Get(k)), "")
+		case vd.Message() != nil && !isLeaf(vd.Message()):
+			d.message(elem+".", old.Get(k).Message(), new.Get(k).Message())
+		default:
+			d.add(elem, format(vd, old.Get(k)), format(vd, new.Get(k)))
+		}
+	}
+}
+
+// isLeaf reports whether messages of a type are shown as one value rather
+// than compared field by field.
+func isLeaf(md protoreflect.MessageDescriptor) bool {
+	switch md.FullName() {
+	case "google.protobuf.Duration", "google.type.Money":
+		return true
+	}
+	return false
+}
+
+// field formats a singular field of m, or returns "" if it is unset.
+func field(m protoreflect.Message, fd protoreflect.FieldDescriptor) string {
+	if !m.Has(fd) {
+		return ""
+	}
+	return format(fd, m.Get(fd))
+}
+
+// format formats one value of a field, or of a list or map's elements.
+func format(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.Itoa(int(v.Enum()))
+	case protoreflect.StringKind:
+		return strconv.Quote(v.String())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return formatMessage(v.Message())
+	}
+	return v.String()
+}
+
+func formatMessage(m protoreflect.Message) string {
+	fields := m.Descriptor().Fields()
+	switch m.Descriptor().FullName() {
+	case "google.protobuf.Duration":
+		secs := m.Get(fields.ByName("seconds")).Int()
+		nanos := m.Get(fields.ByName("nanos")).Int()
+		return (time.Duration(secs)*time.Second + time.Duration(nanos)).String()
+	case "google.type.Money":
+		units, nanos := m.Get(fields.ByName("units")).Int(), m.Get(fields.ByName("nanos")).Int()
+		s := strconv.FormatInt(units, 10)
+		if units == 0 && nanos < 0 {
+			s = "-0"
+		}
+		if nanos != 0 {
+			s += strings.TrimRight(fmt.Sprintf(".%09d", max(nanos, -nanos)), "0")
+		}
+		if code := m.Get(fields.ByName("currency_code")).String(); code != "" {
+			s += " " + code
+		}
+		return s
+	}
+	return "{" + prototext.MarshalOptions{}.Format(m.Interface()) + "}"
+}
@@ -0,0 +1,64 @@
+package gamedefio
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+func TestDiff(t *testing.T) {
+	parse := func(text string) *pb.TableConfig {
+		cfg, err := Parse([]byte(text), Text)
+		AssertThat(t, err, Nil())
+		return cfg
+	}
+	old := parse(`
+standard_game_id: "holdem"
+blinds { blind_levels { units: 1 } blind_levels { units: 2 } }
+bets: NO_LIMIT
+action_clock { action { seconds: 30 } }
+rake { basis_points: 500 cap { units: 3 } }
+`)
+	new := parse(`
+standard_game_id: "holdem"
+blinds { blind_levels { units: 1 } blind_levels { units: 2 nanos: 500000000 } blind_levels { units: 5 } }
+bets: POT_LIMIT
+action_clock { action { seconds: 20 } }
+bot_fill { after { seconds: 30 } min_humans: 2 }
+rake { basis_points: 500 }
+`)
+
+	changes := Diff(old, new)
+	AssertThat(t, changes, Len(6))
+	ExpectEq(t, changes[0], Change{Field: "blinds.blind_levels[1]", Old: "2", New: "2.5"})
+	ExpectEq(t, changes[1], Change{Field: "blinds.blind_levels[2]", New: "5"})
+	ExpectEq(t, changes[2], Change{Field: "bets", Old: "NO_LIMIT", New: "POT_LIMIT"})
+	// Messages set on one side only are shown whole.
+	ExpectEq(t, changes[3].Field, "bot_fill")
+	ExpectEq(t, changes[3].Old, "")
+	ExpectEq(t, strings.Contains(changes[3].New, "min_humans"), true)
+	ExpectEq(t, changes[4], Change{Field: "action_clock.action", Old: "30s", New: "20s"})
+	ExpectEq(t, changes[5], Change{Field: "rake.cap", Old: "3"})
+	ExpectThat(t, Diff(old, old), Empty())
+}
+
+func TestDiff_Oneof(t *testing.T) {
+	old := pb.TableConfig_builder{StandardGameId: proto.String("holdem")}.Build()
+	custom := &pb.TableConfig{}
+	AssertThat(t, prototext.Unmarshal([]byte(`custom {}`), custom), Nil())
+	ExpectThat(t, Diff(old, custom), ElementsAre(
+		Change{Field: "standard_game_id", Old: `"holdem"`},
+		Change{Field: "custom", New: "{}"},
+	))
+}
+
+func TestChangeString(t *testing.T) {
+	ExpectEq(t, Change{Field: "bets", Old: "NO_LIMIT", New: "POT_LIMIT"}.String(), "~ bets: NO_LIMIT -> POT_LIMIT")
+	ExpectEq(t, Change{Field: "rake.cap", New: "3"}.String(), "+ rake.cap: 3")
+	ExpectEq(t, Change{Field: "rake.cap", Old: "3"}.String(), "- rake.cap: 3")
+}
@@ -0,0 +1,40 @@
+package gamedefio
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+// Resolve returns a copy of cfg with its feature flags for the environment
+// applied to its settings, and the flags removed, so that resolving it
+// again changes nothing. env is the name the server was started with, or
+// empty for only the flags that apply everywhere. A nil cfg stays nil.
+func Resolve(cfg *pb.TableConfig, env string) *pb.TableConfig {
+	if !cfg.HasFeatures() {
+		return cfg
+	}
+	cfg = proto.CloneOf(cfg)
+	flags := cfg.GetFeatures()
+	cfg.ClearFeatures()
+	apply(cfg, flags)
+	if override, ok := flags.GetEnvironments()[env]; ok && env != "" {
+		apply(cfg, override)
+	}
+	return cfg
+}
+
+func apply(cfg *pb.TableConfig, f *pb.FeatureFlags) {
+	if f.HasRunItTwice() {
+		cfg.SetRunItTwice(f.GetRunItTwice())
+	}
+	if f.HasStraddles() && !f.GetStraddles() && cfg.HasBlinds() {
+		cfg.GetBlinds().ClearStraddle()
+	}
+	if f.HasChat() {
+		cfg.SetNoChat(!f.GetChat())
+	}
+	if f.HasSpectators() {
+		cfg.SetNoSpectators(!f.GetSpectators())
+	}
+}
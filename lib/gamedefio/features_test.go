@@ -0,0 +1,65 @@
+package gamedefio
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+func TestResolve(t *testing.T) {
+	cfg, err := Parse([]byte(`
+blinds:
+  blind_levels: [{units: "1"}, {units: "2"}]
+  utg_straddle: 1
+no_chat: true
+features:
+  run_it_twice: true
+  chat: true
+  environments:
+    staging: {straddles: false, spectators: false}
+    production: {run_it_twice: false}
+`), YAML)
+	AssertThat(t, err, Nil())
+
+	// Flags for everywhere apply in every environment.
+	dev := Resolve(cfg, "")
+	ExpectEq(t, dev.GetRunItTwice(), true)
+	ExpectEq(t, dev.GetNoChat(), false)
+	ExpectEq(t, dev.GetNoSpectators(), false)
+	ExpectEq(t, dev.GetBlinds().GetUtgStraddle(), int32(1))
+	ExpectEq(t, dev.HasFeatures(), false)
+
+	staging := Resolve(cfg, "staging")
+	ExpectEq(t, staging.GetRunItTwice(), true)
+	ExpectEq(t, staging.GetBlinds().WhichStraddle(), pb.Blinds_Straddle_not_set_case)
+	ExpectEq(t, staging.GetNoSpectators(), true)
+
+	ExpectEq(t, Resolve(cfg, "production").GetRunItTwice(), false)
+	ExpectEq(t, Resolve(cfg, "qa").GetRunItTwice(), true)
+
+	// The config itself is unchanged, and resolving is done once.
+	ExpectEq(t, cfg.GetNoChat(), true)
+	ExpectEq(t, cfg.HasFeatures(), true)
+	ExpectEq(t, Resolve(staging, "production").GetRunItTwice(), true)
+
+	ExpectThat(t, Resolve(nil, "staging"), Nil())
+}
+
+func TestValidate_Features(t *testing.T) {
+	cfg, err := Parse([]byte(`
+blinds: {blind_levels: [{units: "1"}, {units: "2"}]}
+features:
+  environments:
+    staging: {environments: {canary: {chat: false}}}
+`), YAML)
+	AssertThat(t, err, Nil())
+	SetDefaults(cfg)
+
+	var errs Errors
+	AssertEq(t, errors.As(Validate(cfg), &errs), true)
+	AssertThat(t, errs, Len(1))
+	ExpectEq(t, errs[0].Field, "features.environments[staging].environments")
+}
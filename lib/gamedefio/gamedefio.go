@@ -0,0 +1,127 @@
+// Package gamedefio reads TableConfigs from files, so that operators can
+// define game modes without rebuilding the servers. Configs may be written
+// in YAML or JSON, using the proto field names as in protojson, or as
+// textproto.
+//
+// Loaded configs have unset fields filled with their defaults, and are
+// checked against everything the table engine needs; every problem found is
+// reported at once, each against the field it is in.
+package gamedefio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"gopkg.in/yaml.v3"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+var ErrUnknownFormat = errors.New("unknown config format")
+
+// Format is how a config file is written.
+type Format int
+
+const (
+	YAML Format = iota + 1
+	JSON
+	Text
+)
+
+// FormatFor returns the format of the file at path, from its extension.
+func FormatFor(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return YAML, nil
+	case ".json":
+		return JSON, nil
+	case ".txtpb", ".textproto", ".pbtxt":
+		return Text, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownFormat, path)
+}
+
+// Load reads the TableConfig in the file at path, fills in its defaults,
+// and validates it.
+func Load(path string) (*pb.TableConfig, error) {
+	f, err := FormatFor(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Parse(b, f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	SetDefaults(cfg)
+	if err := Validate(cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse reads a TableConfig written in format f, and upgrades it to
+// CurrentVersion. Unknown fields are an error, so that typos aren't
+// silently ignored. It neither fills in defaults nor validates the config.
+func Parse(b []byte, f Format) (*pb.TableConfig, error) {
+	cfg := &pb.TableConfig{}
+	switch f {
+	case YAML:
+		var doc any
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			doc = map[string]any{}
+		}
+		// protojson does the real work, so that YAML and JSON configs
+		// spell fields, enums and durations the same way.
+		js, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("YAML config is not valid JSON: %w", err)
+		}
+		b = js
+		fallthrough
+	case JSON:
+		b, err := UpgradeJSON(b)
+		if err != nil {
+			return nil, err
+		}
+		if err := protojson.Unmarshal(b, cfg); err != nil {
+			return nil, err
+		}
+	case Text:
+		if err := prototext.Unmarshal(b, cfg); err != nil {
+			return nil, err
+		}
+		if err := Upgrade(cfg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnknownFormat, f)
+	}
+	return cfg, nil
+}
+
+// SetDefaults fills in the fields of cfg that are unset but have a
+// default: no limit Hold'em, and posting missed blinds right away.
+func SetDefaults(cfg *pb.TableConfig) {
+	if cfg.GetBets() == pb.TableConfig_LIMIT_UNKNOWN {
+		cfg.SetBets(pb.TableConfig_NO_LIMIT)
+	}
+	if cfg.GetVariant() == pb.TableConfig_GAME_UNKNOWN {
+		cfg.SetVariant(pb.TableConfig_HOLDEM)
+	}
+	if cfg.GetMissedBlinds() == pb.TableConfig_UNKNOWN {
+		cfg.SetMissedBlinds(pb.TableConfig_POST)
+	}
+}
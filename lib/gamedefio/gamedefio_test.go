@@ -0,0 +1,177 @@
+package gamedefio
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+func write(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	AssertThat(t, os.WriteFile(path, []byte(contents), 0o644), Nil())
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	for _, path := range []string{
+		write(t, "holdem.yaml", `
+standard_game_id: holdem
+blinds:
+  blind_levels:
+    - {units: "1"}
+    - {units: "2"}
+action_clock:
+  action: 30s
+rake:
+  basis_points: 500
+`),
+		write(t, "holdem.json", `{
+  "standardGameId": "holdem",
+  "blinds": {"blindLevels": [{"units": "1"}, {"units": "2"}]},
+  "actionClock": {"action": "30s"},
+  "rake": {"basisPoints": 500}
+}`),
+		write(t, "holdem.txtpb", `
+standard_game_id: "holdem"
+blinds { blind_levels { units: 1 } blind_levels { units: 2 } }
+action_clock { action { seconds: 30 } }
+rake { basis_points: 500 }
+`),
+	} {
+		cfg, err := Load(path)
+		AssertThat(t, err, Nil())
+		ExpectEq(t, cfg.GetStandardGameId(), "holdem")
+		ExpectThat(t, cfg.GetBlinds().GetBlindLevels(), Len(2))
+		ExpectEq(t, cfg.GetActionClock().GetAction().AsDuration(), 30*time.Second)
+		ExpectEq(t, cfg.GetRake().GetBasisPoints(), int32(500))
+
+		// Defaults are filled in.
+		ExpectEq(t, cfg.GetBets(), pb.TableConfig_NO_LIMIT)
+		ExpectEq(t, cfg.GetVariant(), pb.TableConfig_HOLDEM)
+		ExpectEq(t, cfg.GetMissedBlinds(), pb.TableConfig_POST)
+	}
+
+	_, err := Load(write(t, "holdem.ini", ""))
+	ExpectThat(t, err, ErrorIs(ErrUnknownFormat))
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	ExpectThat(t, err, ErrorIs(os.ErrNotExist))
+
+	// Typos are caught rather than ignored.
+	_, err = Load(write(t, "typo.yaml", "blind: {}\n"))
+	ExpectThat(t, err, Not(Nil()))
+	_, err = Load(write(t, "typo.json", `{"blind": {}}`))
+	ExpectThat(t, err, Not(Nil()))
+}
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse(nil, YAML)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, cfg.GetBlinds(), Nil())
+
+	cfg, err = Parse([]byte("variant: OMAHA\nbets: POT_LIMIT\n"), YAML)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, cfg.GetVariant(), pb.TableConfig_OMAHA)
+	ExpectEq(t, cfg.GetBets(), pb.TableConfig_POT_LIMIT)
+
+	_, err = Parse([]byte("variant: [\n"), YAML)
+	ExpectThat(t, err, Not(Nil()))
+	_, err = Parse(nil, Format(0))
+	ExpectThat(t, err, ErrorIs(ErrUnknownFormat))
+}
+
+func TestValidate(t *testing.T) {
+	cfg, err := Parse([]byte(`
+blinds:
+  blind_levels: [{units: "2"}, {units: "1"}]
+buyin:
+  min: {units: "200"}
+  max: {units: "100"}
+max_party_size: -1
+action_clock:
+  time_bank: 60s
+rake:
+  basis_points: 20000
+`), YAML)
+	AssertThat(t, err, Nil())
+	SetDefaults(cfg)
+
+	err = Validate(cfg)
+	ExpectThat(t, err, ErrorIs(table.ErrInvalidConfig))
+	var errs Errors
+	AssertEq(t, errors.As(err, &errs), true)
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	ExpectThat(t, fields, ElementsAre("blinds", "buyin", "max_party_size", "action_clock", "rake"))
+
+	var fe *FieldError
+	AssertEq(t, errors.As(err, &fe), true)
+	ExpectEq(t, fe.Field, "blinds")
+
+	// Past the defaults, blinds are the only thing a table can't do
+	// without.
+	cfg = &pb.TableConfig{}
+	SetDefaults(cfg)
+	ExpectThat(t, Validate(cfg), ErrorIs(table.ErrInvalidConfig))
+	cfg, err = Parse([]byte(`blinds: {blind_levels: [{units: "1"}, {units: "2"}]}`), YAML)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, Validate(cfg), Not(Nil()))
+	SetDefaults(cfg)
+	ExpectThat(t, Validate(cfg), Nil())
+}
+
+func TestValidate_CrossField(t *testing.T) {
+	cfg, err := Parse([]byte(`
+blinds:
+  blind_levels: [{units: "1"}, {units: "20"}]
+  ante: {units: "25"}
+buyin:
+  max: {units: "10"}
+rake:
+  cap: {units: "5"}
+`), YAML)
+	AssertThat(t, err, Nil())
+	SetDefaults(cfg)
+
+	var errs Errors
+	AssertEq(t, errors.As(Validate(cfg), &errs), true)
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	ExpectThat(t, fields, ElementsAre("blinds.blind_levels[1]", "blinds.ante", "buyin.max", "rake.cap"))
+}
+
+func TestValidateSeats(t *testing.T) {
+	cfg, err := Parse([]byte(`
+blinds:
+  blind_levels: [{units: "1"}, {units: "2"}]
+  utg_straddle: 2
+max_party_size: 4
+bot_fill: {min_humans: 3}
+`), YAML)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, ValidateSeats(cfg, 6), Nil())
+
+	var errs Errors
+	AssertEq(t, errors.As(ValidateSeats(cfg, 3), &errs), true)
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	ExpectThat(t, fields, ElementsAre("blinds.utg_straddle", "max_party_size"))
+
+	err = ValidateSeats(cfg, 11)
+	ExpectThat(t, err, ErrorIs(table.ErrInvalidConfig))
+	AssertEq(t, errors.As(err, &errs), true)
+	ExpectEq(t, errs[0].Field, "seats")
+}
@@ -0,0 +1,68 @@
+package gamedefio
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+var ErrNoPreset = errors.New("no such preset")
+
+//go:embed presets/*.yaml
+var presetFiles embed.FS
+
+// Preset is a TableConfig built into the servers, so that a fresh
+// deployment has game modes to offer before anyone writes configs.
+type Preset struct {
+	Name string `json:"name"`
+
+	// Seats at the tables the preset is meant for.
+	Seats int `json:"seats"`
+}
+
+// Presets are the built-in TableConfigs: no-limit hold'em at micro, low
+// and mid stakes, and at heads-up, six-max and full-ring tables.
+var Presets = []Preset{
+	{Name: "micro", Seats: 6},
+	{Name: "low", Seats: 6},
+	{Name: "mid", Seats: 6},
+	{Name: "heads-up", Seats: 2},
+	{Name: "6-max", Seats: 6},
+	{Name: "9-max", Seats: 9},
+}
+
+// PresetNamed returns the built-in preset with the name, or ErrNoPreset.
+func PresetNamed(name string) (Preset, error) {
+	for _, p := range Presets {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Preset{}, fmt.Errorf("%w: %q", ErrNoPreset, name)
+}
+
+// Config returns a copy of the preset's TableConfig, with its defaults
+// filled in.
+func (p Preset) Config() (*pb.TableConfig, error) {
+	b, err := presetFiles.ReadFile("presets/" + p.Name + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrNoPreset, p.Name)
+	}
+	cfg, err := Parse(b, YAML)
+	if err != nil {
+		return nil, fmt.Errorf("preset %s: %w", p.Name, err)
+	}
+	SetDefaults(cfg)
+	return cfg, nil
+}
+
+// LoadPreset returns the TableConfig of the built-in preset with the name.
+func LoadPreset(name string) (*pb.TableConfig, error) {
+	p, err := PresetNamed(name)
+	if err != nil {
+		return nil, err
+	}
+	return p.Config()
+}
@@ -0,0 +1,40 @@
+package gamedefio
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+func TestPresets(t *testing.T) {
+	files, err := presetFiles.ReadDir("presets")
+	AssertThat(t, err, Nil())
+	// Every file is a listed preset.
+	AssertThat(t, files, Len(len(Presets)))
+
+	for _, p := range Presets {
+		cfg, err := p.Config()
+		AssertThat(t, err, Nil())
+		ExpectThat(t, Validate(cfg), Nil())
+		ExpectThat(t, ValidateSeats(cfg, p.Seats), Nil())
+		ExpectEq(t, cfg.GetStandardGameId(), "holdem")
+		ExpectEq(t, cfg.GetBets(), pb.TableConfig_NO_LIMIT)
+	}
+}
+
+func TestLoadPreset(t *testing.T) {
+	cfg, err := LoadPreset("heads-up")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, cfg.GetBotFill().GetMinHumans(), int32(1))
+
+	// Each call returns a fresh copy.
+	cfg.SetNoChat(true)
+	again, err := LoadPreset("heads-up")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, again.GetNoChat(), false)
+
+	_, err = LoadPreset("high-roller")
+	ExpectThat(t, err, ErrorIs(ErrNoPreset))
+}
@@ -0,0 +1,178 @@
+package gamedefio
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+const (
+	// Seats at the smallest and largest tables.
+	MinSeats = 2
+	MaxSeats = 10
+
+	// Largest a blind may be next to the one before it, so that a typo
+	// like 1/20 for 1/2 doesn't make it to a table.
+	MaxBlindRatio = 3
+)
+
+// FieldError is a problem with one field of a config.
+type FieldError struct {
+	// The field's path from the TableConfig, using proto field names, such
+	// as "buyin.rebuys".
+	Field string
+
+	// What is wrong with it. Wraps table.ErrInvalidConfig.
+	Err error
+}
+
+func (e *FieldError) Error() string { return e.Field + ": " + e.Err.Error() }
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// Errors is every problem found in a config, in field order.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// err returns e as an error, or nil if it is empty.
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate checks that a table could be run with cfg. If it can't, it
+// returns Errors.
+func Validate(cfg *pb.TableConfig) error {
+	var errs Errors
+	check := func(field string, err error) {
+		if err != nil {
+			errs = append(errs, &FieldError{Field: field, Err: err})
+		}
+	}
+	invalid := func(field, format string, args ...any) {
+		check(field, fmt.Errorf("%w: "+format, append([]any{table.ErrInvalidConfig}, args...)...))
+	}
+
+	if cfg.WhichStructure() == pb.TableConfig_StandardGameId_case && cfg.GetStandardGameId() == "" {
+		invalid("standard_game_id", "must not be empty")
+	}
+	blinds, err := table.BlindRulesFor(cfg)
+	check("blinds", err)
+	for i := 1; i < len(blinds.Blinds); i++ {
+		if blinds.Blinds[i] > MaxBlindRatio*blinds.Blinds[i-1] {
+			invalid(fmt.Sprintf("blinds.blind_levels[%d]", i), "more than %d times the blind before it", MaxBlindRatio)
+		}
+	}
+	var bigBlind int64
+	if n := len(blinds.Blinds); n > 0 {
+		bigBlind = blinds.Blinds[n-1]
+	}
+	if bigBlind > 0 && blinds.Ante > bigBlind {
+		invalid("blinds.ante", "is over the big blind")
+	}
+	if table.Chips(cfg.GetBlinds().GetBombPot()) < 0 {
+		invalid("blinds.bomb_pot", "must not be negative")
+	}
+	switch cfg.GetBets() {
+	case pb.TableConfig_NO_LIMIT, pb.TableConfig_FIXED_LIMIT, pb.TableConfig_POT_LIMIT:
+	default:
+		invalid("bets", "unknown betting structure %d", cfg.GetBets())
+	}
+	buyin, err := table.BuyinRulesFor(cfg)
+	check("buyin", err)
+	if buyin.Max > 0 && buyin.Max < bigBlind {
+		invalid("buyin.max", "is under the big blind")
+	}
+	_, err = table.RebuyRulesFor(cfg)
+	check("buyin.rebuys", err)
+	if cfg.GetMaxPartySize() < 0 {
+		invalid("max_party_size", "must not be negative")
+	}
+	if fill := cfg.GetBotFill(); fill.GetAfter().AsDuration() < 0 || fill.GetMinHumans() < 0 {
+		invalid("bot_fill", "must not be negative")
+	}
+	p := cfg.GetDodgePenalty()
+	for _, d := range p.GetDelays() {
+		if d.AsDuration() < 0 {
+			invalid("dodge_penalty.delays", "must not be negative")
+			break
+		}
+	}
+	if p.GetDecay().AsDuration() < 0 || p.GetAbandonWithin().AsDuration() < 0 {
+		invalid("dodge_penalty", "must not be negative")
+	}
+	switch cfg.GetMissedBlinds() {
+	case pb.TableConfig_UNKNOWN, pb.TableConfig_POST, pb.TableConfig_WAIT:
+	default:
+		invalid("missed_blinds", "unknown value %d", cfg.GetMissedBlinds())
+	}
+	_, err = table.ClockRulesFor(cfg)
+	check("action_clock", err)
+	_, err = table.SitOutRulesFor(cfg)
+	check("sit_out", err)
+	_, err = table.VariantFor(cfg)
+	check("variant", err)
+	rake, err := table.RakeRulesFor(cfg)
+	check("rake", err)
+	if rake.BasisPoints == 0 && rake.Cap > 0 {
+		invalid("rake.cap", "is set without basis_points")
+	}
+	envs := cfg.GetFeatures().GetEnvironments()
+	for _, env := range slices.Sorted(maps.Keys(envs)) {
+		switch {
+		case env == "":
+			invalid("features.environments", "names must not be empty")
+		case len(envs[env].GetEnvironments()) > 0:
+			invalid(fmt.Sprintf("features.environments[%s].environments", env), "environments can't have environments")
+		}
+	}
+	return errs.err()
+}
+
+// ValidateSeats checks that a table with cfg can seat the given number of
+// players, on top of what Validate checks.
+func ValidateSeats(cfg *pb.TableConfig, seats int) error {
+	var errs Errors
+	invalid := func(field, format string, args ...any) {
+		err := fmt.Errorf("%w: "+format, append([]any{table.ErrInvalidConfig}, args...)...)
+		errs = append(errs, &FieldError{Field: field, Err: err})
+	}
+
+	if seats < MinSeats || seats > MaxSeats {
+		invalid("seats", "%d seats is not between %d and %d", seats, MinSeats, MaxSeats)
+	}
+	b := cfg.GetBlinds()
+	if n := len(b.GetBlindLevels()); n > seats {
+		invalid("blinds.blind_levels", "%d blinds at a %d-seat table", n, seats)
+	}
+	if n := len(b.GetBlindLevels()) + int(b.GetUtgStraddle()); b.GetUtgStraddle() > 0 && n > seats {
+		invalid("blinds.utg_straddle", "%d blinds and straddles at a %d-seat table", n, seats)
+	}
+	if n := int(cfg.GetMaxPartySize()); n > seats {
+		invalid("max_party_size", "parties of %d at a %d-seat table", n, seats)
+	}
+	if n := int(cfg.GetBotFill().GetMinHumans()); n > seats {
+		invalid("bot_fill.min_humans", "%d humans at a %d-seat table", n, seats)
+	}
+	return errs.err()
+}
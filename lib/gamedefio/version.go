@@ -0,0 +1,119 @@
+package gamedefio
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+// CurrentVersion is the TableConfig schema version this build reads and
+// writes. Bump it, and add a migration, whenever a change to TableConfig
+// would stop old configs from parsing or change what they mean.
+const CurrentVersion = 1
+
+var ErrNewerVersion = errors.New("config is from a newer schema version")
+
+// migrations[v] upgrades a config written against version v to version
+// v+1. It is given the config as decoded from JSON, where fields may be
+// spelled with either their proto or their JSON names, and edits it in
+// place.
+var migrations = [CurrentVersion]func(doc map[string]any) error{
+	// Version 0 configs were written before versions were recorded, and
+	// are otherwise the same as version 1.
+	func(map[string]any) error { return nil },
+}
+
+// UpgradeJSON upgrades a TableConfig written as JSON to CurrentVersion,
+// and returns it with its schema_version set.
+func UpgradeJSON(b []byte) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var doc map[string]any
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+	v, err := version(doc)
+	if err != nil {
+		return nil, err
+	}
+	if v > CurrentVersion {
+		return nil, fmt.Errorf("%w: %d is newer than %d", ErrNewerVersion, v, CurrentVersion)
+	}
+	for ; v < CurrentVersion; v++ {
+		if err := migrations[v](doc); err != nil {
+			return nil, fmt.Errorf("upgrading from version %d: %w", v, err)
+		}
+	}
+	delete(doc, "schema_version")
+	doc["schemaVersion"] = CurrentVersion
+	return json.Marshal(doc)
+}
+
+// version returns the schema version of a config decoded from JSON.
+func version(doc map[string]any) (int, error) {
+	raw, ok := doc["schemaVersion"]
+	if !ok {
+		raw, ok = doc["schema_version"]
+	}
+	if !ok || raw == nil {
+		return 0, nil
+	}
+	var s string
+	switch raw := raw.(type) {
+	case json.Number:
+		s = raw.String()
+	case string:
+		s = raw
+	default:
+		return 0, fmt.Errorf("schema_version: not a number: %v", raw)
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("schema_version: not a version: %q", s)
+	}
+	return v, nil
+}
+
+// Upgrade upgrades a config that was parsed without going through
+// UpgradeJSON, such as one read from textproto, to CurrentVersion.
+func Upgrade(cfg *pb.TableConfig) error {
+	if cfg.GetSchemaVersion() == CurrentVersion {
+		return nil
+	}
+	b, err := protojson.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if b, err = UpgradeJSON(b); err != nil {
+		return err
+	}
+	up := &pb.TableConfig{}
+	if err := protojson.Unmarshal(b, up); err != nil {
+		return err
+	}
+	proto.Reset(cfg)
+	proto.Merge(cfg, up)
+	return nil
+}
+
+// Stamped returns cfg with its schema_version set, ready to be stored. It
+// returns cfg itself if the version is already set, and a copy otherwise,
+// since configs are often shared.
+func Stamped(cfg *pb.TableConfig) *pb.TableConfig {
+	if cfg.HasSchemaVersion() {
+		return cfg
+	}
+	cfg = proto.CloneOf(cfg)
+	cfg.SetSchemaVersion(CurrentVersion)
+	return cfg
+}
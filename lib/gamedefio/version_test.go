@@ -0,0 +1,59 @@
+package gamedefio
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+func TestUpgradeJSON(t *testing.T) {
+	for _, in := range []string{
+		`{"noChat": true}`,
+		`{"no_chat": true, "schema_version": 0}`,
+		`{"noChat": true, "schemaVersion": "1"}`,
+	} {
+		b, err := UpgradeJSON([]byte(in))
+		AssertThat(t, err, Nil())
+		cfg := &pb.TableConfig{}
+		AssertThat(t, protojson.Unmarshal(b, cfg), Nil())
+		ExpectEq(t, cfg.GetNoChat(), true)
+		ExpectEq(t, cfg.GetSchemaVersion(), int32(CurrentVersion))
+	}
+
+	_, err := UpgradeJSON([]byte(`{"schemaVersion": 99}`))
+	ExpectThat(t, err, ErrorIs(ErrNewerVersion))
+	_, err = UpgradeJSON([]byte(`{"schemaVersion": "one"}`))
+	ExpectThat(t, err, Not(Nil()))
+	_, err = UpgradeJSON([]byte(`[]`))
+	ExpectThat(t, err, Not(Nil()))
+}
+
+func TestUpgrade(t *testing.T) {
+	cfg := pb.TableConfig_builder{NoChat: proto.Bool(true)}.Build()
+	AssertThat(t, Upgrade(cfg), Nil())
+	ExpectEq(t, cfg.GetNoChat(), true)
+	ExpectEq(t, cfg.GetSchemaVersion(), int32(CurrentVersion))
+
+	cfg.SetSchemaVersion(CurrentVersion + 1)
+	ExpectThat(t, Upgrade(cfg), ErrorIs(ErrNewerVersion))
+
+	_, err := Parse([]byte("schema_version: 99\n"), Text)
+	ExpectThat(t, err, ErrorIs(ErrNewerVersion))
+	_, err = Parse([]byte("schema_version: 99\n"), YAML)
+	ExpectThat(t, err, ErrorIs(ErrNewerVersion))
+	cfg, err = Parse([]byte("no_chat: true\n"), Text)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, cfg.GetSchemaVersion(), int32(CurrentVersion))
+}
+
+func TestStamped(t *testing.T) {
+	cfg := &pb.TableConfig{}
+	stamped := Stamped(cfg)
+	ExpectEq(t, stamped.GetSchemaVersion(), int32(CurrentVersion))
+	ExpectEq(t, cfg.HasSchemaVersion(), false)
+	ExpectEq(t, Stamped(stamped), stamped)
+}
@@ -0,0 +1,143 @@
+package handeval
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rank is a card's rank, from Two to Ace.
+type Rank uint8
+
+const (
+	Two Rank = iota + 2
+	Three
+	Four
+	Five
+	Six
+	Seven
+	Eight
+	Nine
+	Ten
+	Jack
+	Queen
+	King
+	Ace
+)
+
+const rankChars = "23456789TJQKA"
+
+func (r Rank) String() string {
+	if r < Two || r > Ace {
+		return "?"
+	}
+	return rankChars[r-Two : r-Two+1]
+}
+
+// ParseRank parses a rank such as "A", "T" or "10".
+func ParseRank(s string) (Rank, error) {
+	if s == "10" {
+		return Ten, nil
+	}
+	if len(s) == 1 {
+		if i := strings.IndexByte(rankChars, strings.ToUpper(s)[0]); i >= 0 {
+			return Two + Rank(i), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: rank %q", ErrInvalidCard, s)
+}
+
+// Suit is a card's suit. Suits are never ranked against each other.
+type Suit uint8
+
+const (
+	Spades Suit = iota
+	Hearts
+	Diamonds
+	Clubs
+)
+
+const suitChars = "shdc"
+
+func (s Suit) String() string {
+	if s > Clubs {
+		return "?"
+	}
+	return suitChars[s : s+1]
+}
+
+// Card is a playing card.
+type Card struct {
+	Rank Rank
+	Suit Suit
+}
+
+// String returns the card in the short form ParseCard accepts, such as
+// "As" or "Td".
+func (c Card) String() string {
+	return c.Rank.String() + c.Suit.String()
+}
+
+// ParseCard parses a card written as its rank followed by its suit, such as
+// "As", "Td" or "10♦". Suits may be given as s, h, d and c, in either case,
+// or as ♠, ♥, ♦ and ♣.
+func ParseCard(s string) (Card, error) {
+	cards, err := ParseCards(s)
+	if err != nil {
+		return Card{}, err
+	}
+	if len(cards) != 1 {
+		return Card{}, fmt.Errorf("%w: %q is not one card", ErrInvalidCard, s)
+	}
+	return cards[0], nil
+}
+
+// ParseCards parses a list of cards, such as "As Kd" or "AsKd". Cards may be
+// separated by spaces or commas, or not at all.
+func ParseCards(s string) ([]Card, error) {
+	var cards []Card
+	rest := strings.NewReplacer(" ", "", ",", "", "\t", "").Replace(s)
+	for rest != "" {
+		n := 1
+		if strings.HasPrefix(rest, "10") {
+			n = 2
+		}
+		rank, err := ParseRank(rest[:n])
+		if err != nil {
+			return nil, err
+		}
+		rest = rest[n:]
+		r, size := utf8.DecodeRuneInString(rest)
+		suit, ok := parseSuit(r)
+		if !ok {
+			return nil, fmt.Errorf("%w: missing or bad suit after %s in %q", ErrInvalidCard, rank, s)
+		}
+		rest = rest[size:]
+		cards = append(cards, Card{Rank: rank, Suit: suit})
+	}
+	return cards, nil
+}
+
+func parseSuit(r rune) (Suit, bool) {
+	switch r {
+	case 's', 'S', '♠':
+		return Spades, true
+	case 'h', 'H', '♥':
+		return Hearts, true
+	case 'd', 'D', '♦':
+		return Diamonds, true
+	case 'c', 'C', '♣':
+		return Clubs, true
+	}
+	return 0, false
+}
+
+// FormatCards returns cards in the form ParseCards accepts, separated by
+// spaces.
+func FormatCards(cards []Card) string {
+	s := make([]string, len(cards))
+	for i, c := range cards {
+		s[i] = c.String()
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,38 @@
+package handeval
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestParseCards(t *testing.T) {
+	for _, s := range []string{"As Td 2c", "AsTd2c", "as,10D, 2C", "A♠ 10♦ 2♣"} {
+		got, err := ParseCards(s)
+		AssertThat(t, err, Nil())
+		ExpectThat(t, got, ElementsAre(Card{Ace, Spades}, Card{Ten, Diamonds}, Card{Two, Clubs}))
+	}
+	got, err := ParseCards("")
+	ExpectThat(t, err, Nil())
+	ExpectThat(t, got, Empty())
+
+	for _, s := range []string{"A", "Ax", "1s", "As K", "Zs"} {
+		_, err := ParseCards(s)
+		ExpectThat(t, err, ErrorIs(ErrInvalidCard))
+	}
+}
+
+func TestParseCard(t *testing.T) {
+	c, err := ParseCard("Qh")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, c, Card{Queen, Hearts})
+	ExpectEq(t, c.String(), "Qh")
+
+	_, err = ParseCard("Qh Js")
+	ExpectThat(t, err, ErrorIs(ErrInvalidCard))
+}
+
+func TestFormatCards(t *testing.T) {
+	ExpectEq(t, FormatCards([]Card{{Ten, Spades}, {Two, Clubs}}), "Ts 2c")
+	ExpectEq(t, FormatCards(nil), "")
+}
@@ -0,0 +1,344 @@
+// Package handeval ranks poker hands of five to seven cards, under the
+// standard rules or any of a family of variants: short-deck orderings,
+// lowball with the ace high or low, and low hands that must qualify.
+package handeval
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrInvalidCard   = errors.New("invalid card")
+	ErrCardCount     = errors.New("hands must have five to seven cards")
+	ErrDuplicateCard = errors.New("duplicate card")
+	ErrInvalidRules  = errors.New("invalid hand rules")
+)
+
+// Hand is the best five-card hand that can be made from some cards.
+type Hand struct {
+	Category Category
+
+	// The five cards that make the hand, most significant first: grouped
+	// cards before kickers, and straights from their top card down.
+	Cards []Card
+
+	// Whether the hand meets the rules' qualifier. Hands that don't lose to
+	// every hand that does.
+	Qualified bool
+
+	// Larger is better. Only comparable between hands ranked by the same
+	// Evaluator.
+	value int32
+}
+
+// Compare returns a positive number if h beats o, a negative number if o
+// beats h, and zero if they tie.
+func (h Hand) Compare(o Hand) int {
+	if h.Qualified != o.Qualified {
+		if h.Qualified {
+			return 1
+		}
+		return -1
+	}
+	return cmp.Compare(h.value, o.value)
+}
+
+// String describes the hand, such as "flush: As Js 8s 6s 2s".
+func (h Hand) String() string {
+	return h.Category.String() + ": " + FormatCards(h.Cards)
+}
+
+// Winners returns the indexes of the best qualified hands: more than one if
+// they tie, and none if no hand qualifies.
+func Winners(hands []Hand) []int {
+	var best []int
+	for i, h := range hands {
+		if !h.Qualified {
+			continue
+		}
+		switch {
+		case len(best) == 0:
+			best = []int{i}
+		case h.Compare(hands[best[0]]) > 0:
+			best = []int{i}
+		case h.Compare(hands[best[0]]) == 0:
+			best = append(best, i)
+		}
+	}
+	return best
+}
+
+// straight is a run of five ranks that makes a straight.
+type straight struct {
+	mask uint16 // bit i is set for the rank at position i
+	high int8   // position of the top card
+}
+
+// Evaluator ranks hands under one set of Rules. It is safe for concurrent
+// use.
+type Evaluator struct {
+	rules Rules
+
+	// Ranks in the deck from lowest to highest, and the position of each
+	// rank in that order, or -1 if it is not in the deck.
+	order []Rank
+	pos   [Ace + 1]int8
+
+	// Strength of each category, higher being stronger, or -1 if the rules
+	// don't recognize it.
+	strength [StraightFlush + 1]int8
+
+	straights []straight
+
+	// Position of the qualifying rank, or -1 if every hand qualifies.
+	qualifier int8
+}
+
+// New returns an Evaluator for r.
+func New(r Rules) (*Evaluator, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	e := &Evaluator{rules: r, qualifier: -1}
+	minRank := cmp.Or(r.MinRank, Two)
+	aceLow := r.Lo && r.AceLow
+	if aceLow {
+		e.order = append(e.order, Ace)
+	}
+	for rank := minRank; rank <= King; rank++ {
+		e.order = append(e.order, rank)
+	}
+	if !aceLow {
+		e.order = append(e.order, Ace)
+	}
+	for i := range e.pos {
+		e.pos[i] = -1
+	}
+	for i, rank := range e.order {
+		e.pos[rank] = int8(i)
+	}
+	for i := range e.strength {
+		e.strength[i] = -1
+	}
+	for i, c := range r.Order {
+		e.strength[c] = int8(len(r.Order) - 1 - i)
+	}
+
+	// Straights start at every position with four ranks above it and, if
+	// the rules allow, wrap around past the top rank.
+	n := len(e.order)
+	starts := make([]int, 0, n)
+	for s := 0; s+4 < n; s++ {
+		starts = append(starts, s)
+	}
+	if r.WheelStraight && !aceLow {
+		starts = append(starts, n-1)
+	}
+	if r.Wraparound {
+		starts = append(starts, n-2, n-3)
+	}
+	for _, s := range starts {
+		var st straight
+		for k := range 5 {
+			st.mask |= 1 << ((s + k) % n)
+		}
+		st.high = int8((s + 4) % n)
+		e.straights = append(e.straights, st)
+	}
+
+	if r.Lo && r.Qualifier != 0 {
+		e.qualifier = e.pos[r.Qualifier]
+	}
+	return e, nil
+}
+
+// MustNew is like New, but panics if r is invalid. It is meant for rules
+// that are known to be valid, such as Standard.
+func MustNew(r Rules) *Evaluator {
+	e, err := New(r)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Rules returns the rules e ranks hands by.
+func (e *Evaluator) Rules() Rules {
+	return e.rules
+}
+
+// Evaluate returns the best five-card hand that can be made from five to
+// seven cards.
+func (e *Evaluator) Evaluate(cards []Card) (Hand, error) {
+	if len(cards) < 5 || len(cards) > 7 {
+		return Hand{}, fmt.Errorf("%w: got %d", ErrCardCount, len(cards))
+	}
+	var seen [Ace + 1][Clubs + 1]bool
+	for _, c := range cards {
+		if c.Rank > Ace || c.Suit > Clubs || e.pos[c.Rank] < 0 {
+			return Hand{}, fmt.Errorf("%w: %s is not in the deck", ErrInvalidCard, c)
+		}
+		if seen[c.Rank][c.Suit] {
+			return Hand{}, fmt.Errorf("%w: %s", ErrDuplicateCard, c)
+		}
+		seen[c.Rank][c.Suit] = true
+	}
+
+	var best, five [5]Card
+	bestValue, bestCat := int32(0), Category(0)
+	n := len(cards)
+	for a := 0; a < n; a++ {
+		for b := a + 1; b < n; b++ {
+			for c := b + 1; c < n; c++ {
+				for d := c + 1; d < n; d++ {
+					for f := d + 1; f < n; f++ {
+						five = [5]Card{cards[a], cards[b], cards[c], cards[d], cards[f]}
+						value, cat := e.eval5(&five)
+						if bestCat == 0 || value > bestValue {
+							best, bestValue, bestCat = five, value, cat
+						}
+					}
+				}
+			}
+		}
+	}
+	return Hand{
+		Category:  bestCat,
+		Cards:     e.arrange(best, bestCat),
+		Qualified: e.qualifies(best, bestCat),
+		value:     bestValue,
+	}, nil
+}
+
+// eval5 returns the value and category of exactly five cards.
+func (e *Evaluator) eval5(cards *[5]Card) (int32, Category) {
+	var counts [16]int8
+	var mask uint16
+	flush := true
+	for _, c := range cards {
+		p := e.pos[c.Rank]
+		counts[p]++
+		mask |= 1 << p
+		if c.Suit != cards[0].Suit {
+			flush = false
+		}
+	}
+
+	// Distinct positions, by how many cards share them and then from high
+	// to low, so that pairs outrank kickers.
+	var groups [5]int8
+	distinct, most := 0, int8(0)
+	for k := int8(4); k >= 1; k-- {
+		for p := int8(len(e.order) - 1); p >= 0; p-- {
+			if counts[p] == k {
+				groups[distinct] = p
+				distinct++
+				most = max(most, k)
+			}
+		}
+	}
+
+	var pattern Category
+	switch {
+	case distinct == 5:
+		pattern = HighCard
+	case distinct == 4:
+		pattern = Pair
+	case distinct == 3 && most == 3:
+		pattern = ThreeOfAKind
+	case distinct == 3:
+		pattern = TwoPair
+	case most == 4:
+		pattern = FourOfAKind
+	default:
+		pattern = FullHouse
+	}
+
+	cat := pattern
+	high := int8(-1)
+	if distinct == 5 {
+		for _, st := range e.straights {
+			if st.mask == mask {
+				high = st.high
+				break
+			}
+		}
+		// Straights and flushes the rules don't recognize are ranked as
+		// high cards.
+		consider := func(c Category, ok bool) {
+			if ok && e.strength[c] > e.strength[cat] {
+				cat = c
+			}
+		}
+		consider(Straight, high >= 0)
+		consider(Flush, flush)
+		consider(StraightFlush, high >= 0 && flush)
+	}
+
+	value := int32(e.strength[cat]) << 20
+	if cat == Straight || cat == StraightFlush {
+		value |= int32(high) << 16
+	} else {
+		for i := range distinct {
+			value |= int32(groups[i]) << (16 - 4*i)
+		}
+	}
+	if e.rules.Lo {
+		value = -value
+	}
+	return value, cat
+}
+
+// arrange orders a hand's cards most significant first.
+func (e *Evaluator) arrange(cards [5]Card, cat Category) []Card {
+	var counts [16]int
+	for _, c := range cards {
+		counts[e.pos[c.Rank]]++
+	}
+	n := len(e.order)
+	var bottom int
+	if cat == Straight || cat == StraightFlush {
+		// Position the bottom card of the straight at 0, so that wheels
+		// sort with the ace last.
+		var mask uint16
+		for _, c := range cards {
+			mask |= 1 << e.pos[c.Rank]
+		}
+		for _, st := range e.straights {
+			if st.mask == mask {
+				bottom = (int(st.high) - 4 + n) % n
+			}
+		}
+	}
+	out := cards[:]
+	slices.SortFunc(out, func(a, b Card) int {
+		pa, pb := int(e.pos[a.Rank]), int(e.pos[b.Rank])
+		if cat == Straight || cat == StraightFlush {
+			return cmp.Compare((pb-bottom+n)%n, (pa-bottom+n)%n)
+		}
+		return cmp.Or(
+			cmp.Compare(counts[pb], counts[pa]),
+			cmp.Compare(pb, pa),
+			cmp.Compare(a.Suit, b.Suit),
+		)
+	})
+	return out
+}
+
+func (e *Evaluator) qualifies(cards [5]Card, cat Category) bool {
+	if e.qualifier < 0 {
+		return true
+	}
+	if cat != HighCard {
+		return false
+	}
+	for _, c := range cards {
+		if e.pos[c.Rank] > e.qualifier {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,277 @@
+package handeval
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func cards(t *testing.T, s string) []Card {
+	t.Helper()
+	c, err := ParseCards(s)
+	AssertThat(t, err, Nil())
+	return c
+}
+
+func eval(t *testing.T, e *Evaluator, s string) Hand {
+	t.Helper()
+	h, err := e.Evaluate(cards(t, s))
+	AssertThat(t, err, Nil())
+	return h
+}
+
+// deck returns every card from minRank up.
+func deck(minRank Rank) []Card {
+	var d []Card
+	for r := minRank; r <= Ace; r++ {
+		for s := Spades; s <= Clubs; s++ {
+			d = append(d, Card{r, s})
+		}
+	}
+	return d
+}
+
+// everyHand evaluates every five-card hand that can be dealt from the deck,
+// returning how many fall into each category and how many distinct values
+// there are.
+func everyHand(t *testing.T, e *Evaluator) (map[Category]int, int) {
+	t.Helper()
+	d := deck(e.Rules().MinRank)
+	if e.Rules().MinRank == 0 {
+		d = deck(Two)
+	}
+	counts := map[Category]int{}
+	values := map[int32]bool{}
+	hand := make([]Card, 5)
+	n := len(d)
+	for a := 0; a < n; a++ {
+		for b := a + 1; b < n; b++ {
+			for c := b + 1; c < n; c++ {
+				for x := c + 1; x < n; x++ {
+					for y := x + 1; y < n; y++ {
+						hand[0], hand[1], hand[2], hand[3], hand[4] = d[a], d[b], d[c], d[x], d[y]
+						h, err := e.Evaluate(hand)
+						AssertThat(t, err, Nil())
+						counts[h.Category]++
+						values[h.value] = true
+					}
+				}
+			}
+		}
+	}
+	return counts, len(values)
+}
+
+func TestEvaluate_EveryStandardHand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("evaluates 2.6 million hands")
+	}
+	counts, distinct := everyHand(t, MustNew(Standard))
+	ExpectEq(t, counts, map[Category]int{
+		StraightFlush: 40,
+		FourOfAKind:   624,
+		FullHouse:     3744,
+		Flush:         5108,
+		Straight:      10200,
+		ThreeOfAKind:  54912,
+		TwoPair:       123552,
+		Pair:          1098240,
+		HighCard:      1302540,
+	})
+	ExpectEq(t, distinct, 7462)
+}
+
+func TestEvaluate_EveryShortDeckHand(t *testing.T) {
+	counts, distinct := everyHand(t, MustNew(ShortDeck))
+	ExpectEq(t, counts, map[Category]int{
+		StraightFlush: 24,
+		FourOfAKind:   288,
+		FullHouse:     1728,
+		Flush:         480,
+		Straight:      6120,
+		ThreeOfAKind:  16128,
+		TwoPair:       36288,
+		Pair:          193536,
+		HighCard:      122400,
+	})
+	// 6 straight flushes, 72 quads, 72 full houses, 120 flushes, 6
+	// straights, 252 trips, 252 two pairs, 504 pairs and 120 high cards.
+	ExpectEq(t, distinct, 1404)
+}
+
+func TestEvaluate_EveryAceToFiveHand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("evaluates 2.6 million hands")
+	}
+	counts, distinct := everyHand(t, MustNew(AceToFive))
+	ExpectEq(t, counts, map[Category]int{
+		FourOfAKind:  624,
+		FullHouse:    3744,
+		ThreeOfAKind: 54912,
+		TwoPair:      123552,
+		Pair:         1098240,
+		HighCard:     1317888,
+	})
+	ExpectEq(t, distinct, 6175)
+}
+
+func TestEvaluate(t *testing.T) {
+	for _, tc := range []struct {
+		rules Rules
+		cards string
+		want  Category
+		best  string
+	}{
+		{Standard, "As Ks Qs Js Ts", StraightFlush, "As Ks Qs Js Ts"},
+		{Standard, "2h 3h 4h 6h Ah Kh Qh", Flush, "Ah Kh Qh 6h 4h"},
+		{Standard, "5d 4c 3s 2h Ah Kh 9c", Straight, "5d 4c 3s 2h Ah"},
+		{Standard, "6d 5d 4c 3s 2h Ah Kh", Straight, "6d 5d 4c 3s 2h"},
+		{Standard, "Kc Kd Ks 7h 7c 7d 2s", FullHouse, "Ks Kd Kc 7h 7c"},
+		{Standard, "9s 9h 9d 9c Ah Kh 2c", FourOfAKind, "9s 9h 9d 9c Ah"},
+		{Standard, "Js Jh 4d 4c 2h 2c Ac", TwoPair, "Js Jh 4d 4c Ac"},
+		{Standard, "Qs Qh 8d 7c 5h 3c 2c", Pair, "Qs Qh 8d 7c 5h"},
+		{Standard, "Ks Jh 8d 7c 5h 3c 2d", HighCard, "Ks Jh 8d 7c 5h"},
+		{Standard, "Ts Th Td 8c 6h 3c", ThreeOfAKind, "Ts Th Td 8c 6h"},
+		{ShortDeck, "As 6h 7d 8c 9h Kd", Straight, "9h 8c 7d 6h As"},
+		{ShortDeck, "As Ah Ad 6c 7h 8s 9d", ThreeOfAKind, "As Ah Ad 9d 8s"},
+		{ShortDeckHighStraight, "As Ah Ad 6c 7h 8s 9d", Straight, "9d 8s 7h 6c As"},
+		{ShortDeck, "Kh Kd Ks 7h 7c 9h 6h Th", Flush, ""},
+		{AceToFive, "5h 4h 3h 2h Ah Kc Kd", HighCard, "5h 4h 3h 2h Ah"},
+		{AceToFive, "As Ah 2c 2d 3h 3c 4s", Pair, "As Ah 4s 3h 2c"},
+		{AceToSix, "5h 4c 3h 2h Ah 6d Kd", HighCard, "6d 4c 3h 2h Ah"},
+		{DeuceToSeven, "7h 5c 4h 3h 2d Ad Kd", HighCard, "7h 5c 4h 3h 2d"},
+		{DeuceToSeven, "Ah 5c 4h 3h 2d", HighCard, "Ah 5c 4h 3h 2d"},
+		{DeuceToSeven, "7h 6c 5h 4h 3d", Straight, "7h 6c 5h 4h 3d"},
+		{Rules{Order: StandardOrder, WheelStraight: true, Wraparound: true}, "Kh As 2c 3d 4s 9h", Straight, "4s 3d 2c As Kh"},
+	} {
+		if tc.best == "" {
+			// Eight cards is too many.
+			_, err := MustNew(tc.rules).Evaluate(cards(t, tc.cards))
+			ExpectThat(t, err, ErrorIs(ErrCardCount))
+			continue
+		}
+		h := eval(t, MustNew(tc.rules), tc.cards)
+		ExpectEq(t, h.Category, tc.want)
+		ExpectEq(t, FormatCards(h.Cards), tc.best)
+		ExpectEq(t, h.Qualified, true)
+	}
+}
+
+func TestEvaluate_Errors(t *testing.T) {
+	e := MustNew(Standard)
+	_, err := e.Evaluate(cards(t, "As Ks Qs Js"))
+	ExpectThat(t, err, ErrorIs(ErrCardCount))
+	_, err = e.Evaluate(cards(t, "As Ks Qs Js As"))
+	ExpectThat(t, err, ErrorIs(ErrDuplicateCard))
+	_, err = e.Evaluate([]Card{{Ace, Spades}, {King, Spades}, {Queen, Spades}, {Jack, Spades}, {1, Spades}})
+	ExpectThat(t, err, ErrorIs(ErrInvalidCard))
+	_, err = MustNew(ShortDeck).Evaluate(cards(t, "As Ks Qs Js 5s"))
+	ExpectThat(t, err, ErrorIs(ErrInvalidCard))
+}
+
+// ranking lists hands from best to worst. Hands in the same string tie.
+func expectRanking(t *testing.T, rules Rules, hands ...[]string) {
+	t.Helper()
+	e := MustNew(rules)
+	var prev *Hand
+	for _, group := range hands {
+		first := eval(t, e, group[0])
+		for _, s := range group[1:] {
+			h := eval(t, e, s)
+			ExpectEq(t, h.Compare(first), 0)
+		}
+		if prev != nil && prev.Compare(first) <= 0 {
+			t.Errorf("%s does not beat %s", prev, first)
+		}
+		prev = &first
+	}
+}
+
+func TestCompare_Standard(t *testing.T) {
+	expectRanking(t, Standard,
+		[]string{"As Ks Qs Js Ts"},
+		[]string{"Ks Qs Js Ts 9s"},
+		[]string{"5s 4s 3s 2s As"},
+		[]string{"Ac Ad Ah As 2c"},
+		[]string{"Ac Ad Ah Ks Kc"},
+		[]string{"Kc Kd Kh As Ac"},
+		[]string{"Ah Jh 9h 7h 5h", "As Js 9s 7s 5s"},
+		[]string{"Ac Kd Qh Js Tc"},
+		[]string{"6c 5d 4h 3s 2c"},
+		[]string{"5c 4d 3h 2s Ac"},
+		[]string{"2c 2d 2h As Kc"},
+		[]string{"Ac Ad Kh Ks 2c"},
+		[]string{"Ac Ad Qh Qs Kc"},
+		[]string{"Ac Ad Qh Qs Jc"},
+		[]string{"Ac Ad Kh Qs Jc"},
+		[]string{"2c 2d Ah Ks Qc"},
+		[]string{"Ac Kd Qh Js 9c", "As Kh Qd Jc 9s"},
+		[]string{"7c 5d 4h 3s 2c"},
+	)
+}
+
+func TestCompare_Lowball(t *testing.T) {
+	expectRanking(t, AceToFive,
+		[]string{"5c 4d 3h 2s Ac", "5h 4h 3h 2h Ah"},
+		[]string{"6c 4d 3h 2s Ac"},
+		[]string{"6c 5d 4h 3s 2c"},
+		[]string{"8c 7d 6h 5s 4c"},
+		[]string{"Kc Qd Jh Ts 9c"},
+		[]string{"Ac Ad 3h 4s 5c"},
+		[]string{"2c 2d 3h 4s 5c"},
+		[]string{"Ac Ad 2h 2s 5c"},
+		[]string{"Kc Kd Kh Ks Qc"},
+	)
+	expectRanking(t, AceToSix,
+		[]string{"6c 4d 3h 2s Ac"},
+		[]string{"6c 5d 4h 3s Ac"},
+		[]string{"7c 5d 4h 3s 2c"},
+		[]string{"Kc Qd Jh Ts 8c"},
+		[]string{"Ac Ad 3h 4s 5c"},
+		[]string{"5c 4d 3h 2s Ac"},
+		[]string{"7c 5c 4c 3c 2c"},
+	)
+	expectRanking(t, DeuceToSeven,
+		[]string{"7c 5d 4h 3s 2c"},
+		[]string{"7c 6d 4h 3s 2c"},
+		[]string{"8c 5d 4h 3s 2c"},
+		[]string{"Ac Kd Qh Js 9c"},
+		[]string{"2c 2d 3h 4s 5c"},
+		[]string{"6c 5d 4h 3s 2c"},
+		[]string{"7c 5c 4c 3c 2c"},
+	)
+}
+
+func TestQualifier(t *testing.T) {
+	rules := AceToFive
+	rules.Qualifier = Eight
+	e := MustNew(rules)
+
+	ExpectEq(t, eval(t, e, "8c 7d 6h 5s 4c").Qualified, true)
+	ExpectEq(t, eval(t, e, "8c 7d 6h 5s Ac").Qualified, true)
+	ExpectEq(t, eval(t, e, "9c 7d 6h 5s 4c").Qualified, false)
+	ExpectEq(t, eval(t, e, "8c 8d 6h 5s 4c").Qualified, false)
+
+	// With seven cards the best low is found among them.
+	h := eval(t, e, "Kc Kd 8h 7s 3c 2d Ah")
+	ExpectEq(t, h.Qualified, true)
+	ExpectEq(t, FormatCards(h.Cards), "8h 7s 3c 2d Ah")
+
+	// A qualified hand beats every hand that isn't, even a lower one.
+	ExpectEq(t, eval(t, e, "8c 7d 6h 5s 4c").Compare(eval(t, e, "Ac Ad 2h 3s 4c")) > 0, true)
+}
+
+func TestWinners(t *testing.T) {
+	rules := AceToFive
+	rules.Qualifier = Eight
+	e := MustNew(rules)
+	hands := []Hand{
+		eval(t, e, "Kc Qd Jh Ts 9c"),
+		eval(t, e, "7c 5d 4h 3s 2c"),
+		eval(t, e, "7d 5h 4s 3c 2d"),
+		eval(t, e, "8d 5h 4s 3c 2d"),
+	}
+	ExpectThat(t, Winners(hands), ElementsAre(1, 2))
+	ExpectThat(t, Winners(hands[:1]), Empty())
+	ExpectThat(t, Winners(nil), Empty())
+}
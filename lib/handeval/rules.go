@@ -0,0 +1,153 @@
+package handeval
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Category is the kind of five-card hand, such as a flush. Categories are
+// numbered in the standard order from weakest to strongest, matching
+// gamedef.HandType, but Rules may rank them differently.
+type Category int
+
+const (
+	HighCard Category = iota + 1
+	Pair
+	TwoPair
+	ThreeOfAKind
+	Straight
+	Flush
+	FullHouse
+	FourOfAKind
+	StraightFlush
+)
+
+var categoryNames = map[Category]string{
+	HighCard:      "high card",
+	Pair:          "pair",
+	TwoPair:       "two pair",
+	ThreeOfAKind:  "three of a kind",
+	Straight:      "straight",
+	Flush:         "flush",
+	FullHouse:     "full house",
+	FourOfAKind:   "four of a kind",
+	StraightFlush: "straight flush",
+}
+
+func (c Category) String() string {
+	if name, ok := categoryNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Category(%d)", int(c))
+}
+
+// Rules says how hands are ranked.
+type Rules struct {
+	// Categories from strongest to weakest. Straights and flushes may be
+	// left out, in which case they are ranked by their cards alone, as in
+	// ace-to-five lowball; every other category must be listed.
+	Order []Category
+
+	// Lowest rank in the deck. Zero means Two; short-deck games use Six.
+	MinRank Rank
+
+	// Whether the ace may play below MinRank in a straight, as in A-2-3-4-5.
+	// Ignored if AceLow is set, since the ace is then always low.
+	WheelStraight bool
+
+	// Whether straights may wrap around the ace, as in K-A-2-3-4 and
+	// Q-K-A-2-3. These rank below every other straight.
+	Wraparound bool
+
+	// If set, the lowest hand wins instead of the highest: lower categories
+	// beat higher ones, and within a category, lower cards beat higher ones.
+	Lo bool
+
+	// Whether the ace is the lowest rank rather than the highest. Only
+	// applies if Lo is set: this is the difference between ace-to-five and
+	// deuce-to-seven lowball.
+	AceLow bool
+
+	// If set, only high-card hands whose highest card is at most this rank
+	// qualify, such as Eight for "eight or better". Only applies if Lo is
+	// set. Zero means every hand qualifies.
+	Qualifier Rank
+}
+
+// StandardOrder is the usual ranking of categories, from strongest to
+// weakest.
+var StandardOrder = []Category{StraightFlush, FourOfAKind, FullHouse, Flush, Straight, ThreeOfAKind, TwoPair, Pair, HighCard}
+
+var (
+	// Standard ranks hands as in Hold'em and most other poker games.
+	Standard = Rules{Order: StandardOrder, WheelStraight: true}
+
+	// ShortDeck ranks hands for a 36-card deck: flushes beat full houses,
+	// three of a kind beats a straight, and A-6-7-8-9 is the lowest
+	// straight.
+	ShortDeck = Rules{
+		Order:         []Category{StraightFlush, FourOfAKind, Flush, FullHouse, ThreeOfAKind, Straight, TwoPair, Pair, HighCard},
+		MinRank:       Six,
+		WheelStraight: true,
+	}
+
+	// ShortDeckHighStraight is ShortDeck, but with straights beating three
+	// of a kind.
+	ShortDeckHighStraight = Rules{
+		Order:         []Category{StraightFlush, FourOfAKind, Flush, FullHouse, Straight, ThreeOfAKind, TwoPair, Pair, HighCard},
+		MinRank:       Six,
+		WheelStraight: true,
+	}
+
+	// AceToFive is lowball where the ace is low and straights and flushes
+	// don't count, so A-2-3-4-5 is the best hand. Used for razz and the low
+	// half of hi-lo games, usually with an Eight qualifier.
+	AceToFive = Rules{
+		Order:  []Category{FourOfAKind, FullHouse, ThreeOfAKind, TwoPair, Pair, HighCard},
+		Lo:     true,
+		AceLow: true,
+	}
+
+	// AceToSix is lowball where the ace is low but straights and flushes
+	// count against the hand, so 6-4-3-2-A is the best hand.
+	AceToSix = Rules{Order: StandardOrder, Lo: true, AceLow: true}
+
+	// DeuceToSeven is lowball where the ace is high and straights and
+	// flushes count against the hand, so 7-5-4-3-2 is the best hand. Also
+	// known as Kansas City lowball.
+	DeuceToSeven = Rules{Order: StandardOrder, Lo: true}
+)
+
+// Validate checks that r can be used to rank hands.
+func (r Rules) Validate() error {
+	for _, c := range r.Order {
+		if _, ok := categoryNames[c]; !ok {
+			return fmt.Errorf("%w: unknown category %d", ErrInvalidRules, c)
+		}
+		if n := count(r.Order, c); n > 1 {
+			return fmt.Errorf("%w: %s is listed %d times", ErrInvalidRules, c, n)
+		}
+	}
+	for _, c := range []Category{HighCard, Pair, TwoPair, ThreeOfAKind, FullHouse, FourOfAKind} {
+		if !slices.Contains(r.Order, c) {
+			return fmt.Errorf("%w: %s is missing from the order", ErrInvalidRules, c)
+		}
+	}
+	if r.MinRank != 0 && (r.MinRank < Two || r.MinRank > Ten) {
+		return fmt.Errorf("%w: lowest rank must be between 2 and 10", ErrInvalidRules)
+	}
+	if r.Qualifier != 0 && (r.Qualifier < Two || r.Qualifier > Ace) {
+		return fmt.Errorf("%w: qualifier must be a rank", ErrInvalidRules)
+	}
+	return nil
+}
+
+func count[T comparable](s []T, v T) int {
+	n := 0
+	for _, x := range s {
+		if x == v {
+			n++
+		}
+	}
+	return n
+}
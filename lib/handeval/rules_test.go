@@ -0,0 +1,31 @@
+package handeval
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestValidate(t *testing.T) {
+	for _, r := range []Rules{Standard, ShortDeck, ShortDeckHighStraight, AceToFive, AceToSix, DeuceToSeven} {
+		ExpectThat(t, r.Validate(), Nil())
+	}
+
+	for _, r := range []Rules{
+		{},
+		{Order: []Category{FourOfAKind, FullHouse, ThreeOfAKind, TwoPair, Pair}},
+		{Order: []Category{FourOfAKind, FullHouse, ThreeOfAKind, TwoPair, Pair, HighCard, Pair}},
+		{Order: []Category{FourOfAKind, FullHouse, ThreeOfAKind, TwoPair, Pair, HighCard, 42}},
+		{Order: StandardOrder, MinRank: Jack},
+		{Order: StandardOrder, Lo: true, Qualifier: 1},
+	} {
+		ExpectThat(t, r.Validate(), ErrorIs(ErrInvalidRules))
+		_, err := New(r)
+		ExpectThat(t, err, ErrorIs(ErrInvalidRules))
+	}
+}
+
+func TestCategoryString(t *testing.T) {
+	ExpectEq(t, FullHouse.String(), "full house")
+	ExpectEq(t, Category(0).String(), "Category(0)")
+}
@@ -0,0 +1,327 @@
+// Package handhistory writes the record of a hand in the text format
+// PokerStars uses for its hand histories, which tracking tools and hand
+// replayers read.
+package handhistory
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jfmatt/snapfold/lib/handeval"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+// Seat is a player dealt into a hand.
+type Seat struct {
+	Seat   int
+	Player string
+
+	// Chips at the start of the hand, before posting.
+	Stack int64
+
+	// Whether the table played the seat, rather than a person.
+	Bot bool
+}
+
+// Hand is everything that happened in one hand.
+type Hand struct {
+	// Tracking tools expect hands to be numbered.
+	Number uint64
+
+	Table    string
+	MaxSeats int
+
+	// The game and betting structure, such as "Hold'em No Limit".
+	Game string
+
+	// Blinds from smallest to largest.
+	Blinds []int64
+
+	// ISO 4217 code of the currency chips are hundredths of, such as "USD".
+	// Empty for play money.
+	Currency string
+
+	PlayedAt time.Time
+	Button   int
+	Seats    []Seat
+
+	// Each seat's hole cards, and those shown at showdown.
+	Hole  map[int][]handeval.Card
+	Shown map[int][]handeval.Card
+
+	// Everything that happened, as the table engine recorded it.
+	Events []table.Event
+
+	// A bet nobody called, returned to UncalledSeat. -1 if none.
+	UncalledSeat int
+	Uncalled     int64
+
+	Rake int64
+}
+
+var symbols = map[string]string{"USD": "$", "EUR": "€", "GBP": "£"}
+
+// Format returns the hand's history as viewer sees it: the only hole cards
+// shown are viewer's own and those shown down. viewer is a player's name,
+// or empty to see nobody's.
+func Format(h Hand, viewer string) string {
+	var b strings.Builder
+	Write(&b, h, viewer)
+	return b.String()
+}
+
+// Write writes the hand's history as viewer sees it, as Format does.
+func Write(w io.Writer, h Hand, viewer string) error {
+	f := &formatter{h: h, names: map[int]string{}, folded: map[int]string{}, won: map[int]int64{}}
+	for _, s := range h.Seats {
+		f.names[s.Seat] = s.Player
+	}
+	f.write(viewer)
+	_, err := io.WriteString(w, f.b.String())
+	return err
+}
+
+type formatter struct {
+	h     Hand
+	b     strings.Builder
+	names map[int]string
+
+	// The board before any runout, and each run's board.
+	board []handeval.Card
+	runs  map[int][]handeval.Card
+
+	// The street each seat folded on, what each won, and the role of each
+	// blind.
+	folded map[int]string
+	won    map[int]int64
+	roles  map[int][]string
+}
+
+func (f *formatter) line(format string, args ...any) {
+	fmt.Fprintf(&f.b, format, args...)
+	f.b.WriteByte('\n')
+}
+
+// money formats chips as an amount of the hand's currency, leaving off
+// the cents when there are none, as PokerStars does. Play money is shown in
+// chips.
+func (f *formatter) money(chips int64) string {
+	if f.h.Currency == "" {
+		return fmt.Sprint(chips)
+	}
+	sym, ok := symbols[f.h.Currency]
+	if !ok {
+		sym = f.h.Currency + " "
+	}
+	if chips%100 == 0 {
+		return fmt.Sprintf("%s%d", sym, chips/100)
+	}
+	return fmt.Sprintf("%s%d.%02d", sym, chips/100, chips%100)
+}
+
+func (f *formatter) write(viewer string) {
+	h := f.h
+	stakes := make([]string, len(h.Blinds))
+	for i, b := range h.Blinds {
+		stakes[i] = f.money(b)
+	}
+	currency := ""
+	if h.Currency != "" {
+		currency = " " + h.Currency
+	}
+	f.line("PokerStars Hand #%d: %s (%s%s) - %s", h.Number, h.Game, strings.Join(stakes, "/"), currency,
+		h.PlayedAt.UTC().Format("2006/01/02 15:04:05 UTC"))
+	f.line("Table '%s' %d-max Seat #%d is the button", h.Table, h.MaxSeats, h.Button)
+	for _, s := range h.Seats {
+		// Tracking tools skip what follows the chips, as they do for
+		// players sitting out.
+		bot := ""
+		if s.Bot {
+			bot = " is a bot"
+		}
+		f.line("Seat %d: %s (%s in chips)%s", s.Seat, s.Player, f.money(s.Stack), bot)
+	}
+
+	f.roles = map[int][]string{h.Button: {"button"}}
+	blinds, dealt, showdown := 0, false, false
+	bet := int64(0)
+	for _, e := range h.Events {
+		name := f.names[e.Seat]
+		allIn := ""
+		if e.AllIn {
+			allIn = " and is all-in"
+		}
+		if !dealt && !isPost(e.Type) && e.Type != table.EventButton {
+			f.line("*** HOLE CARDS ***")
+			for _, s := range h.Seats {
+				if s.Player == viewer && len(h.Hole[s.Seat]) > 0 {
+					f.line("Dealt to %s %s", s.Player, cards(h.Hole[s.Seat]))
+				}
+			}
+			dealt = true
+		}
+		switch e.Type {
+		case table.EventBlind:
+			role := "big blind"
+			if blinds == 0 && len(h.Blinds) > 1 && e.Amount <= h.Blinds[0] {
+				role = "small blind"
+			}
+			blinds++
+			f.roles[e.Seat] = append(f.roles[e.Seat], role)
+			bet = max(bet, e.Total)
+			f.line("%s: posts %s %s%s", name, role, f.money(e.Amount), allIn)
+		case table.EventStraddle:
+			bet = max(bet, e.Total)
+			f.line("%s: posts straddle %s%s", name, f.money(e.Amount), allIn)
+		case table.EventDeadBlind:
+			f.line("%s: posts small blind %s%s", name, f.money(e.Amount), allIn)
+		case table.EventAnte:
+			f.line("%s: posts the ante %s%s", name, f.money(e.Amount), allIn)
+		case table.EventTimeout:
+			f.line("%s has timed out", name)
+		case table.EventFold:
+			f.folded[e.Seat] = f.street()
+			f.line("%s: folds", name)
+		case table.EventCheck:
+			f.line("%s: checks", name)
+		case table.EventCall:
+			f.line("%s: calls %s%s", name, f.money(e.Amount), allIn)
+		case table.EventBet:
+			bet = e.Total
+			f.line("%s: bets %s%s", name, f.money(e.Amount), allIn)
+		case table.EventRaise:
+			f.line("%s: raises %s to %s%s", name, f.money(e.Total-bet), f.money(e.Total), allIn)
+			bet = e.Total
+		case table.EventRoundOver:
+			bet = 0
+		case table.EventBoard:
+			f.deal(e)
+		case table.EventWin:
+			if !showdown {
+				if h.UncalledSeat >= 0 && h.Uncalled > 0 {
+					f.line("Uncalled bet (%s) returned to %s", f.money(h.Uncalled), f.names[h.UncalledSeat])
+				}
+				if len(h.Shown) > 0 {
+					f.line("*** SHOW DOWN ***")
+					for _, s := range h.Seats {
+						if c := h.Shown[s.Seat]; len(c) > 0 {
+							f.line("%s: shows %s", s.Player, cards(c))
+						}
+					}
+				}
+				showdown = true
+			}
+			f.won[e.Seat] += e.Amount
+			f.line("%s collected %s from pot", name, f.money(e.Amount))
+		}
+	}
+
+	f.line("*** SUMMARY ***")
+	var total int64
+	for _, w := range f.won {
+		total += w
+	}
+	f.line("Total pot %s | Rake %s", f.money(total+h.Rake), f.money(h.Rake))
+	if len(f.runs) == 0 {
+		if len(f.board) > 0 {
+			f.line("Board %s", cards(f.board))
+		}
+	} else {
+		for _, run := range slices.Sorted(maps.Keys(f.runs)) {
+			f.line("%s Board %s", strings.ToUpper(ordinal(run)), cards(f.runs[run]))
+		}
+	}
+	for _, s := range h.Seats {
+		desc := fmt.Sprintf("Seat %d: %s", s.Seat, s.Player)
+		for _, role := range f.roles[s.Seat] {
+			desc += " (" + role + ")"
+		}
+		shown := h.Shown[s.Seat]
+		switch {
+		case f.folded[s.Seat] != "":
+			desc += " folded " + f.folded[s.Seat]
+		case len(shown) > 0 && f.won[s.Seat] > 0:
+			desc += fmt.Sprintf(" showed %s and won (%s)", cards(shown), f.money(f.won[s.Seat]))
+		case len(shown) > 0:
+			desc += fmt.Sprintf(" showed %s and lost", cards(shown))
+		case f.won[s.Seat] > 0:
+			desc += fmt.Sprintf(" collected (%s)", f.money(f.won[s.Seat]))
+		default:
+			desc += " mucked"
+		}
+		f.line("%s", desc)
+	}
+}
+
+func isPost(t table.EventType) bool {
+	switch t {
+	case table.EventBlind, table.EventStraddle, table.EventDeadBlind, table.EventAnte:
+		return true
+	}
+	return false
+}
+
+// street describes when a player folded, by the cards on the board.
+func (f *formatter) street() string {
+	switch len(f.board) {
+	case 0:
+		return "before Flop"
+	case 3:
+		return "on the Flop"
+	case 4:
+		return "on the Turn"
+	}
+	return "on the River"
+}
+
+// deal writes the streets of cards dealt to the board, splitting a runout
+// dealt all at once into its flop, turn and river.
+func (f *formatter) deal(e table.Event) {
+	board := &f.board
+	prefix := ""
+	if e.Run > 0 {
+		if f.runs == nil {
+			f.runs = map[int][]handeval.Card{}
+		}
+		if _, ok := f.runs[e.Run]; !ok {
+			f.runs[e.Run] = slices.Clone(f.board)
+		}
+		b := f.runs[e.Run]
+		board = &b
+		defer func() { f.runs[e.Run] = b }()
+		prefix = strings.ToUpper(ordinal(e.Run)) + " "
+	}
+	rest := e.Cards
+	for len(rest) > 0 {
+		n, street := 1, "RIVER"
+		switch len(*board) {
+		case 0:
+			n, street = min(3, len(rest)), "FLOP"
+		case 3:
+			street = "TURN"
+		}
+		before := slices.Clone(*board)
+		*board = append(*board, rest[:n]...)
+		rest = rest[n:]
+		if len(before) == 0 {
+			f.line("*** %s%s *** %s", prefix, street, cards(*board))
+		} else {
+			f.line("*** %s%s *** %s %s", prefix, street, cards(before), cards((*board)[len(before):]))
+		}
+	}
+}
+
+func cards(c []handeval.Card) string {
+	return "[" + handeval.FormatCards(c) + "]"
+}
+
+func ordinal(n int) string {
+	if names := []string{"", "first", "second", "third"}; n < len(names) {
+		return names[n]
+	}
+	return fmt.Sprintf("run %d", n)
+}
@@ -0,0 +1,194 @@
+package handhistory
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/handeval"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+func parse(t *testing.T, s string) []handeval.Card {
+	t.Helper()
+	c, err := handeval.ParseCards(s)
+	AssertThat(t, err, Nil())
+	return c
+}
+
+func hand(t *testing.T) Hand {
+	return Hand{
+		Number:   42,
+		Table:    "m1",
+		MaxSeats: 6,
+		Game:     "Hold'em No Limit",
+		Blinds:   []int64{50, 100},
+		Currency: "USD",
+		PlayedAt: time.Date(2026, 10, 15, 12, 30, 0, 0, time.UTC),
+		Button:   1,
+		Seats: []Seat{
+			{Seat: 1, Player: "alice", Stack: 10000},
+			{Seat: 2, Player: "bob", Stack: 10000},
+			{Seat: 3, Player: "carol", Stack: 5050},
+		},
+		Hole: map[int][]handeval.Card{
+			1: parse(t, "As Ad"),
+			2: parse(t, "7c 2d"),
+			3: parse(t, "Kh Kd"),
+		},
+		Shown: map[int][]handeval.Card{
+			1: parse(t, "As Ad"),
+			3: parse(t, "Kh Kd"),
+		},
+		Events: []table.Event{
+			{Type: table.EventButton, Seat: 1},
+			{Type: table.EventBlind, Seat: 2, Amount: 50, Total: 50},
+			{Type: table.EventBlind, Seat: 3, Amount: 100, Total: 100},
+			{Type: table.EventTurn, Seat: 1},
+			{Type: table.EventRaise, Seat: 1, Amount: 300, Total: 300},
+			{Type: table.EventTurn, Seat: 2},
+			{Type: table.EventFold, Seat: 2},
+			{Type: table.EventTurn, Seat: 3},
+			{Type: table.EventRaise, Seat: 3, Amount: 4950, Total: 5050, AllIn: true},
+			{Type: table.EventTurn, Seat: 1},
+			{Type: table.EventCall, Seat: 1, Amount: 4750, Total: 5050},
+			{Type: table.EventRoundOver, Seat: -1},
+			{Type: table.EventBoard, Seat: -1, Cards: parse(t, "2c 7h 9s Jd 3h")},
+			{Type: table.EventWin, Seat: 1, Amount: 10150},
+		},
+		UncalledSeat: -1,
+	}
+}
+
+func TestFormat(t *testing.T) {
+	want := strings.Join([]string{
+		"PokerStars Hand #42: Hold'em No Limit ($0.50/$1 USD) - 2026/10/15 12:30:00 UTC",
+		"Table 'm1' 6-max Seat #1 is the button",
+		"Seat 1: alice ($100 in chips)",
+		"Seat 2: bob ($100 in chips)",
+		"Seat 3: carol ($50.50 in chips)",
+		"bob: posts small blind $0.50",
+		"carol: posts big blind $1",
+		"*** HOLE CARDS ***",
+		"Dealt to bob [7c 2d]",
+		"alice: raises $2 to $3",
+		"bob: folds",
+		"carol: raises $47.50 to $50.50 and is all-in",
+		"alice: calls $47.50",
+		"*** FLOP *** [2c 7h 9s]",
+		"*** TURN *** [2c 7h 9s] [Jd]",
+		"*** RIVER *** [2c 7h 9s Jd] [3h]",
+		"*** SHOW DOWN ***",
+		"alice: shows [As Ad]",
+		"carol: shows [Kh Kd]",
+		"alice collected $101.50 from pot",
+		"*** SUMMARY ***",
+		"Total pot $101.50 | Rake $0",
+		"Board [2c 7h 9s Jd 3h]",
+		"Seat 1: alice (button) showed [As Ad] and won ($101.50)",
+		"Seat 2: bob (small blind) folded before Flop",
+		"Seat 3: carol (big blind) showed [Kh Kd] and lost",
+		"",
+	}, "\n")
+	ExpectEq(t, Format(hand(t), "bob"), want)
+}
+
+func TestFormat_NoViewer(t *testing.T) {
+	ExpectEq(t, strings.Contains(Format(hand(t), ""), "Dealt to"), false)
+}
+
+func TestFormat_Bot(t *testing.T) {
+	h := hand(t)
+	h.Seats[1].Bot = true
+	lines := strings.Split(Format(h, ""), "\n")
+	ExpectThat(t, lines, Contains("Seat 2: bob ($100 in chips) is a bot"))
+	ExpectThat(t, lines, Contains("Seat 1: alice ($100 in chips)"))
+}
+
+func TestFormat_PlayMoney(t *testing.T) {
+	h := hand(t)
+	h.Currency = ""
+	got := Format(h, "")
+	ExpectEq(t, strings.HasPrefix(got, "PokerStars Hand #42: Hold'em No Limit (50/100) - "), true)
+	ExpectEq(t, strings.Contains(got, "alice collected 10150 from pot"), true)
+}
+
+func TestFormat_Uncalled(t *testing.T) {
+	h := hand(t)
+	h.Shown = nil
+	h.Events = []table.Event{
+		{Type: table.EventButton, Seat: 1},
+		{Type: table.EventBlind, Seat: 2, Amount: 50, Total: 50},
+		{Type: table.EventBlind, Seat: 3, Amount: 100, Total: 100},
+		{Type: table.EventRaise, Seat: 1, Amount: 300, Total: 300},
+		{Type: table.EventFold, Seat: 2},
+		{Type: table.EventFold, Seat: 3},
+		{Type: table.EventRoundOver, Seat: -1},
+		{Type: table.EventWin, Seat: 1, Amount: 250},
+	}
+	h.UncalledSeat, h.Uncalled = 1, 200
+	got := Format(h, "alice")
+	ExpectThat(t, strings.Split(got, "\n"), Contains("Uncalled bet ($2) returned to alice"))
+	ExpectThat(t, strings.Split(got, "\n"), Contains("Seat 1: alice (button) collected ($2.50)"))
+	ExpectEq(t, strings.Contains(got, "SHOW DOWN"), false)
+}
+
+func TestFormat_RunItTwice(t *testing.T) {
+	h := hand(t)
+	h.Events = []table.Event{
+		{Type: table.EventButton, Seat: 1},
+		{Type: table.EventBlind, Seat: 2, Amount: 50, Total: 50},
+		{Type: table.EventBlind, Seat: 3, Amount: 100, Total: 100},
+		{Type: table.EventFold, Seat: 1},
+		{Type: table.EventRaise, Seat: 2, Amount: 9950, Total: 10000, AllIn: true},
+		{Type: table.EventCall, Seat: 3, Amount: 4950, Total: 5050, AllIn: true},
+		{Type: table.EventRoundOver, Seat: -1},
+		{Type: table.EventBoard, Seat: -1, Cards: parse(t, "2c 7h 9s")},
+		{Type: table.EventBoard, Seat: -1, Cards: parse(t, "Jd 3h"), Run: 1},
+		{Type: table.EventBoard, Seat: -1, Cards: parse(t, "7d 7s"), Run: 2},
+		{Type: table.EventWin, Seat: 3, Amount: 5050, Run: 1},
+		{Type: table.EventWin, Seat: 2, Amount: 5050, Run: 2},
+	}
+	h.Shown = map[int][]handeval.Card{2: parse(t, "7c 2d"), 3: parse(t, "Kh Kd")}
+	h.UncalledSeat, h.Uncalled = 2, 4950
+	lines := strings.Split(Format(h, ""), "\n")
+	for _, want := range []string{
+		"*** FLOP *** [2c 7h 9s]",
+		"*** FIRST TURN *** [2c 7h 9s] [Jd]",
+		"*** FIRST RIVER *** [2c 7h 9s Jd] [3h]",
+		"*** SECOND TURN *** [2c 7h 9s] [7d]",
+		"*** SECOND RIVER *** [2c 7h 9s 7d] [7s]",
+		"Uncalled bet ($49.50) returned to bob",
+		"FIRST Board [2c 7h 9s Jd 3h]",
+		"SECOND Board [2c 7h 9s 7d 7s]",
+		"Seat 1: alice (button) folded before Flop",
+		"Seat 2: bob (small blind) showed [7c 2d] and won ($50.50)",
+		"Seat 3: carol (big blind) showed [Kh Kd] and won ($50.50)",
+	} {
+		ExpectThat(t, lines, Contains(want))
+	}
+}
+
+func TestFormat_Straddle(t *testing.T) {
+	h := hand(t)
+	h.Shown = nil
+	h.Events = []table.Event{
+		{Type: table.EventButton, Seat: 1},
+		{Type: table.EventBlind, Seat: 2, Amount: 50, Total: 50},
+		{Type: table.EventBlind, Seat: 3, Amount: 100, Total: 100},
+		{Type: table.EventStraddle, Seat: 1, Amount: 200, Total: 200},
+		{Type: table.EventRaise, Seat: 2, Amount: 550, Total: 600},
+		{Type: table.EventFold, Seat: 3},
+		{Type: table.EventFold, Seat: 1},
+		{Type: table.EventRoundOver, Seat: -1},
+		{Type: table.EventWin, Seat: 2, Amount: 700},
+	}
+	h.UncalledSeat, h.Uncalled = 2, 400
+	lines := strings.Split(Format(h, ""), "\n")
+	ExpectThat(t, lines, Contains("alice: posts straddle $2"))
+	ExpectThat(t, lines, Contains("bob: raises $4 to $6"))
+	ExpectEq(t, slices.Index(lines, "alice: posts straddle $2") < slices.Index(lines, "*** HOLE CARDS ***"), true)
+}
@@ -0,0 +1,150 @@
+// Package health serves the liveness and readiness checks that
+// orchestrators probe a server with.
+//
+// GET /healthz answers 200 for as long as the server can serve at all, so
+// that a server is only restarted when it has stopped responding. GET
+// /readyz answers 200 only while every dependency the server was given a
+// check for is usable, and the server isn't shutting down, so that traffic
+// is only sent to servers that can handle it. Both list the state of each
+// check.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDraining is the readiness error of a server that is shutting down.
+var ErrDraining = errors.New("shutting down")
+
+// DefaultTimeout is how long a check may take before it is failed, unless
+// set by WithTimeout.
+const DefaultTimeout = 2 * time.Second
+
+// Check reports whether a dependency is usable, returning why not if it
+// isn't.
+type Check func(ctx context.Context) error
+
+// Checker holds a server's readiness checks. It is safe for concurrent use.
+type Checker struct {
+	timeout  time.Duration
+	draining atomic.Bool
+
+	mu     sync.Mutex
+	names  []string
+	checks map[string]Check
+}
+
+// Option configures a Checker.
+type Option func(*Checker)
+
+// WithTimeout sets how long each check may take.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Checker) { c.timeout = d }
+}
+
+// New returns a Checker with no checks, which is ready.
+func New(opts ...Option) *Checker {
+	c := &Checker{timeout: DefaultTimeout, checks: map[string]Check{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Add adds a readiness check. Adding a name twice is a mistake in the
+// program, so it panics.
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.checks[name]; ok {
+		panic(fmt.Sprintf("health: %s added twice", name))
+	}
+	c.names = append(c.names, name)
+	c.checks[name] = check
+}
+
+// Drain marks the server as shutting down, so that it is no longer ready
+// whatever its checks say.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name string
+
+	// Nil if the check passed.
+	Err error
+}
+
+// Ready runs every check at once, and returns their results in the order
+// they were added, and whether they all passed. A draining server is never
+// ready.
+func (c *Checker) Ready(ctx context.Context) ([]Result, bool) {
+	c.mu.Lock()
+	names := slices.Clone(c.names)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = Result{Name: names[i], Err: check(ctx)}
+		}()
+	}
+	wg.Wait()
+
+	ready := !c.draining.Load()
+	for _, r := range results {
+		ready = ready && r.Err == nil
+	}
+	return results, ready
+}
+
+// Register serves GET /healthz and GET /readyz on mux.
+func (c *Checker) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", c.serveLive)
+	mux.HandleFunc("GET /readyz", c.serveReady)
+}
+
+func (c *Checker) serveLive(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+func (c *Checker) serveReady(w http.ResponseWriter, r *http.Request) {
+	results, ready := c.Ready(r.Context())
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if c.draining.Load() {
+		fmt.Fprintf(w, "server: %v\n", ErrDraining)
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "%s: %v\n", r.Name, r.Err)
+		} else {
+			fmt.Fprintf(w, "%s: ok\n", r.Name)
+		}
+	}
+	if ready {
+		fmt.Fprintln(w, "ok")
+	}
+}
@@ -0,0 +1,68 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func get(t *testing.T, c *Checker, path string) (int, []string) {
+	t.Helper()
+	mux := http.NewServeMux()
+	c.Register(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	return rec.Code, strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+}
+
+func TestChecker(t *testing.T) {
+	c := New()
+	code, body := get(t, c, "/readyz")
+	ExpectEq(t, code, http.StatusOK)
+	ExpectThat(t, body, ElementsAre("ok"))
+
+	var dbErr error
+	c.Add("db", func(context.Context) error { return dbErr })
+	c.Add("redis", func(context.Context) error { return nil })
+	code, body = get(t, c, "/readyz")
+	ExpectEq(t, code, http.StatusOK)
+	ExpectThat(t, body, ElementsAre("db: ok", "redis: ok", "ok"))
+
+	dbErr = errors.New("connection refused")
+	code, body = get(t, c, "/readyz")
+	ExpectEq(t, code, http.StatusServiceUnavailable)
+	ExpectThat(t, body, ElementsAre("db: connection refused", "redis: ok"))
+
+	// The server stays live.
+	code, _ = get(t, c, "/healthz")
+	ExpectEq(t, code, http.StatusOK)
+}
+
+func TestChecker_Drain(t *testing.T) {
+	c := New()
+	c.Add("db", func(context.Context) error { return nil })
+	c.Drain()
+	code, body := get(t, c, "/readyz")
+	ExpectEq(t, code, http.StatusServiceUnavailable)
+	ExpectThat(t, body, ElementsAre("server: shutting down", "db: ok"))
+	code, _ = get(t, c, "/healthz")
+	ExpectEq(t, code, http.StatusOK)
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	c := New(WithTimeout(time.Millisecond))
+	c.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	results, ready := c.Ready(context.Background())
+	ExpectEq(t, ready, false)
+	AssertThat(t, results, Len(1))
+	ExpectThat(t, results[0].Err, ErrorIs(context.DeadlineExceeded))
+}
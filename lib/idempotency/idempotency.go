@@ -0,0 +1,353 @@
+// Package idempotency lets clients retry requests that change something,
+// such as queueing or buying chips, without the change happening twice
+// when an earlier attempt got through but its response was lost.
+//
+// A client names a change with an idempotency key, unique to it, and sends
+// the same key with every attempt at that change. The first request with a
+// key is handled; later ones get its response without being handled again.
+// Keys are remembered for a while, and are scoped, such as to the player
+// making the request, so that one player's keys never collide with
+// another's.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// Header is the HTTP header that carries a request's idempotency key.
+	Header = "Idempotency-Key"
+
+	// ReplayedHeader is set on responses that were replayed rather than
+	// handled.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	// MaxKeyLen is the longest key accepted, in bytes.
+	MaxKeyLen = 255
+
+	// DefaultTTL is how long keys are remembered by default: long enough
+	// for a client to give up retrying.
+	DefaultTTL = 24 * time.Hour
+
+	// Lease is how long the first request with a key has to be handled.
+	// A retry after that takes the key over, as the request was abandoned,
+	// such as by a server that crashed handling it.
+	Lease = time.Minute
+
+	// maxBody is the largest request body a Handler reads.
+	maxBody = 1 << 20
+)
+
+var (
+	// ErrInProgress is returned for a retry that arrives while the first
+	// request with its key is still being handled.
+	ErrInProgress = errors.New("a request with this idempotency key is in progress")
+
+	// ErrMismatch is returned for a request whose key was first used for a
+	// different request.
+	ErrMismatch = errors.New("idempotency key was used for a different request")
+
+	// ErrInvalidKey is returned for keys longer than MaxKeyLen.
+	ErrInvalidKey = errors.New("invalid idempotency key")
+)
+
+// Record is what is remembered of a request made with an idempotency key.
+type Record struct {
+	Scope string
+	Key   string
+
+	// A hash of the request, so that a key reused for a different one is
+	// caught.
+	Fingerprint string
+
+	// Set once the request has been handled.
+	Done     bool
+	Response []byte
+
+	CreatedAt time.Time
+
+	// Until when a request not yet done holds its key. Zero if it holds it
+	// until it is done.
+	LeaseUntil time.Time
+}
+
+// held reports whether a record keeps its key from a request made at now.
+func (r Record) held(now time.Time) bool {
+	return r.Done || r.LeaseUntil.IsZero() || r.LeaseUntil.After(now)
+}
+
+// Store persists records.
+type Store interface {
+	// Reserve saves r, not yet done, unless there is already a record of its
+	// scope and key created after notBefore, which it returns instead.
+	// Older records are forgotten, as are records not done whose lease ran
+	// out by r.CreatedAt.
+	Reserve(ctx context.Context, r Record, notBefore time.Time) (existing Record, reserved bool, err error)
+
+	// Complete saves the response to a reserved request.
+	Complete(ctx context.Context, scope, key string, response []byte) error
+
+	// Release forgets a reserved request, so that it can be retried.
+	Release(ctx context.Context, scope, key string) error
+
+	// DeleteBefore forgets records created before t.
+	DeleteBefore(ctx context.Context, t time.Time) error
+}
+
+// Keeper makes requests idempotent, remembering them in a Store.
+type Keeper struct {
+	store Store
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// New returns a Keeper that remembers keys in store for ttl.
+func New(store Store, ttl time.Duration) *Keeper {
+	return &Keeper{store: store, ttl: ttl, now: time.Now}
+}
+
+// Do calls fn for the first request with a key in a scope, and returns
+// what it returned. For later requests with the key, it returns the first
+// one's response, with replayed set, without calling fn. The response is
+// only remembered if fn returns keep; otherwise, or if fn fails or panics,
+// the next request with the key is handled as if it were the first, as it
+// is once the first has held the key for Lease without finishing. Requests
+// without a key are always handled.
+func (k *Keeper) Do(ctx context.Context, scope, key, fingerprint string, fn func() (response []byte, keep bool, err error)) (response []byte, replayed bool, err error) {
+	if key == "" {
+		response, _, err = fn()
+		return response, false, err
+	}
+	if len(key) > MaxKeyLen {
+		return nil, false, fmt.Errorf("%w: longer than %d bytes", ErrInvalidKey, MaxKeyLen)
+	}
+	now := k.now()
+	existing, reserved, err := k.store.Reserve(ctx, Record{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		LeaseUntil:  now.Add(Lease),
+	}, now.Add(-k.ttl))
+	if err != nil {
+		return nil, false, err
+	}
+	if !reserved {
+		switch {
+		case existing.Fingerprint != fingerprint:
+			return nil, false, ErrMismatch
+		case !existing.Done:
+			return nil, false, ErrInProgress
+		}
+		return existing.Response, true, nil
+	}
+
+	// The request has been handled, whether or not the client is still
+	// waiting for it.
+	ctx = context.WithoutCancel(ctx)
+	defer func() {
+		if v := recover(); v != nil {
+			k.store.Release(ctx, scope, key)
+			panic(v)
+		}
+	}()
+	response, keep, err := fn()
+	if err != nil || !keep {
+		if rerr := k.store.Release(ctx, scope, key); rerr != nil {
+			err = errors.Join(err, rerr)
+		}
+		return response, false, err
+	}
+	if err := k.store.Complete(ctx, scope, key, response); err != nil {
+		return nil, false, err
+	}
+	return response, false, nil
+}
+
+// Run forgets expired keys every interval until ctx is done, reporting
+// failures to onError.
+func (k *Keeper) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := k.store.DeleteBefore(ctx, k.now().Add(-k.ttl)); err != nil && onError != nil {
+				onError(fmt.Errorf("forgetting idempotency keys: %w", err))
+			}
+		}
+	}
+}
+
+// Fingerprint returns a hash of a request's parts.
+func Fingerprint(parts ...[]byte) string {
+	h := sha256.New()
+	for _, p := range parts {
+		// Length-prefixed, so that parts can't run into each other.
+		fmt.Fprintf(h, "%d:", len(p))
+		h.Write(p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Handler makes next idempotent for requests with an Idempotency-Key
+// header, within the scope that scope returns for them. Responses with
+// server error statuses aren't remembered, so that those requests can be
+// retried. Retries that arrive while the first request is still being
+// handled get 409 Conflict, keys reused for a different request 422
+// Unprocessable Entity, and keys that are too long 400 Bad Request, with the
+// reason as {"error": message}.
+func (k *Keeper) Handler(scope func(*http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+		if err != nil {
+			writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := Fingerprint([]byte(r.Method), []byte(r.URL.Path), body)
+
+		b, replayed, err := k.Do(r.Context(), scope(r), key, fingerprint, func() ([]byte, bool, error) {
+			rec := &recorder{header: http.Header{}, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			b, err := json.Marshal(response{Status: rec.status, Header: rec.header, Body: rec.body.Bytes()})
+			return b, rec.status < 500, err
+		})
+		switch {
+		case errors.Is(err, ErrInProgress):
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		case errors.Is(err, ErrMismatch):
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		case errors.Is(err, ErrInvalidKey):
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		var resp response
+		if err := json.Unmarshal(b, &resp); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		maps.Copy(w.Header(), resp.Header)
+		if replayed {
+			w.Header().Set(ReplayedHeader, "true")
+		}
+		w.WriteHeader(resp.Status)
+		w.Write(resp.Body)
+	})
+}
+
+// response is how Handler remembers a response.
+type response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// recorder is a ResponseWriter that keeps the response to be written
+// later.
+type recorder struct {
+	header http.Header
+	status int
+	wrote  bool
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header { return r.header }
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wrote {
+		r.status, r.wrote = status, true
+	}
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// MemStore is an in-memory Store, for a single server and for tests. It
+// forgets expired records as it grows, without needing Keeper.Run.
+type MemStore struct {
+	mu      sync.Mutex
+	records map[[2]string]Record
+
+	// How many records to hold before forgetting expired ones.
+	sweepAt int
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{records: map[[2]string]Record{}, sweepAt: minSweep}
+}
+
+// minSweep is the fewest records a MemStore holds before forgetting
+// expired ones.
+const minSweep = 1024
+
+func (s *MemStore) Reserve(ctx context.Context, r Record, notBefore time.Time) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := [2]string{r.Scope, r.Key}
+	if existing, ok := s.records[id]; ok && existing.CreatedAt.After(notBefore) && existing.held(r.CreatedAt) {
+		return existing, false, nil
+	}
+	if len(s.records) >= s.sweepAt {
+		maps.DeleteFunc(s.records, func(_ [2]string, r Record) bool { return !r.CreatedAt.After(notBefore) })
+		s.sweepAt = max(2*len(s.records), minSweep)
+	}
+	s.records[id] = r
+	return Record{}, true, nil
+}
+
+func (s *MemStore) Complete(ctx context.Context, scope, key string, response []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := [2]string{scope, key}
+	r := s.records[id]
+	r.Done, r.Response = true, response
+	s.records[id] = r
+	return nil
+}
+
+func (s *MemStore) Release(ctx context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, [2]string{scope, key})
+	return nil
+}
+
+func (s *MemStore) DeleteBefore(ctx context.Context, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	maps.DeleteFunc(s.records, func(_ [2]string, r Record) bool { return r.CreatedAt.Before(t) })
+	return nil
+}
@@ -0,0 +1,173 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+var ctx = context.Background()
+
+func TestDo(t *testing.T) {
+	k := New(NewMemStore(), time.Hour)
+	calls := 0
+	fn := func() ([]byte, bool, error) {
+		calls++
+		return []byte("ticket"), true, nil
+	}
+
+	// Requests without a key are always handled.
+	for range 2 {
+		_, replayed, err := k.Do(ctx, "alice", "", "f", fn)
+		AssertThat(t, err, Nil())
+		ExpectEq(t, replayed, false)
+	}
+	ExpectEq(t, calls, 2)
+
+	calls = 0
+	b, replayed, err := k.Do(ctx, "alice", "k1", "f", fn)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, string(b), "ticket")
+	ExpectEq(t, replayed, false)
+	b, replayed, err = k.Do(ctx, "alice", "k1", "f", fn)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, string(b), "ticket")
+	ExpectEq(t, replayed, true)
+	ExpectEq(t, calls, 1)
+
+	// Keys are scoped.
+	_, replayed, err = k.Do(ctx, "bob", "k1", "f", fn)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, replayed, false)
+	ExpectEq(t, calls, 2)
+
+	_, _, err = k.Do(ctx, "alice", "k1", "other", fn)
+	ExpectThat(t, err, ErrorIs(ErrMismatch))
+	_, _, err = k.Do(ctx, "alice", strings.Repeat("k", MaxKeyLen+1), "f", fn)
+	ExpectThat(t, err, ErrorIs(ErrInvalidKey))
+
+	// Keys are forgotten once they expire.
+	k.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, replayed, err = k.Do(ctx, "alice", "k1", "other", fn)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, replayed, false)
+}
+
+func TestDo_NotKept(t *testing.T) {
+	k := New(NewMemStore(), time.Hour)
+	boom := errors.New("boom")
+
+	_, _, err := k.Do(ctx, "alice", "k1", "f", func() ([]byte, bool, error) { return nil, false, boom })
+	ExpectThat(t, err, ErrorIs(boom))
+	_, _, err = k.Do(ctx, "alice", "k1", "f", func() ([]byte, bool, error) { return []byte("5xx"), false, nil })
+	ExpectThat(t, err, Nil())
+
+	// Neither failure was remembered.
+	b, replayed, err := k.Do(ctx, "alice", "k1", "f", func() ([]byte, bool, error) { return []byte("ok"), true, nil })
+	AssertThat(t, err, Nil())
+	ExpectEq(t, string(b), "ok")
+	ExpectEq(t, replayed, false)
+}
+
+func TestDo_InProgress(t *testing.T) {
+	k := New(NewMemStore(), time.Hour)
+	_, _, err := k.Do(ctx, "alice", "k1", "f", func() ([]byte, bool, error) {
+		_, _, err := k.Do(ctx, "alice", "k1", "f", nil)
+		ExpectThat(t, err, ErrorIs(ErrInProgress))
+		return nil, true, nil
+	})
+	ExpectThat(t, err, Nil())
+}
+
+func TestDo_Panic(t *testing.T) {
+	k := New(NewMemStore(), time.Hour)
+	func() {
+		defer func() { ExpectEq(t, recover(), any("boom")) }()
+		k.Do(ctx, "alice", "k1", "f", func() ([]byte, bool, error) { panic("boom") })
+	}()
+
+	// The key was released as the panic went by.
+	b, replayed, err := k.Do(ctx, "alice", "k1", "f", func() ([]byte, bool, error) { return []byte("ok"), true, nil })
+	AssertThat(t, err, Nil())
+	ExpectEq(t, string(b), "ok")
+	ExpectEq(t, replayed, false)
+}
+
+func TestDo_Abandoned(t *testing.T) {
+	s := NewMemStore()
+	k := New(s, time.Hour)
+	now := time.Now()
+	k.now = func() time.Time { return now }
+
+	// A request that never finishes, such as on a server that crashed,
+	// holds its key until its lease runs out.
+	_, reserved, err := s.Reserve(ctx, Record{Scope: "alice", Key: "k1", Fingerprint: "f", CreatedAt: now, LeaseUntil: now.Add(Lease)}, now.Add(-time.Hour))
+	AssertThat(t, err, Nil())
+	AssertEq(t, reserved, true)
+	now = now.Add(Lease - time.Second)
+	_, _, err = k.Do(ctx, "alice", "k1", "f", func() ([]byte, bool, error) { return []byte("ok"), true, nil })
+	ExpectThat(t, err, ErrorIs(ErrInProgress))
+
+	now = now.Add(time.Second)
+	b, replayed, err := k.Do(ctx, "alice", "k1", "f", func() ([]byte, bool, error) { return []byte("ok"), true, nil })
+	AssertThat(t, err, Nil())
+	ExpectEq(t, string(b), "ok")
+	ExpectEq(t, replayed, false)
+
+	// Finished requests keep theirs for good.
+	now = now.Add(2 * Lease)
+	b, replayed, err = k.Do(ctx, "alice", "k1", "f", nil)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, string(b), "ok")
+	ExpectEq(t, replayed, true)
+}
+
+func TestHandler(t *testing.T) {
+	k := New(NewMemStore(), time.Hour)
+	calls := 0
+	h := k.Handler(func(r *http.Request) string { return r.Header.Get("Player") }, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Location", "/v1/tickets/t1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ticket_id":"t1"}`))
+	}))
+	do := func(path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Player", "alice")
+		if key != "" {
+			req.Header.Set(Header, key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("/v1/tickets", "k1", `{}`)
+	ExpectEq(t, rec.Code, http.StatusCreated)
+	ExpectEq(t, rec.Header().Get(ReplayedHeader), "")
+	rec = do("/v1/tickets", "k1", `{}`)
+	ExpectEq(t, rec.Code, http.StatusCreated)
+	ExpectEq(t, rec.Header().Get("Location"), "/v1/tickets/t1")
+	ExpectEq(t, rec.Header().Get(ReplayedHeader), "true")
+	ExpectEq(t, rec.Body.String(), `{"ticket_id":"t1"}`)
+	ExpectEq(t, calls, 1)
+
+	ExpectEq(t, do("/v1/tickets", "k1", `{"game_mode":"omaha"}`).Code, http.StatusUnprocessableEntity)
+	ExpectEq(t, do("/v1/tickets", strings.Repeat("k", MaxKeyLen+1), `{}`).Code, http.StatusBadRequest)
+
+	// Server errors aren't remembered.
+	calls = 0
+	ExpectEq(t, do("/fail", "k2", ``).Code, http.StatusServiceUnavailable)
+	ExpectEq(t, do("/fail", "k2", ``).Code, http.StatusServiceUnavailable)
+	ExpectEq(t, calls, 2)
+}
@@ -0,0 +1,129 @@
+// Package jsonschema describes protobuf messages as JSON Schema, so that
+// configs written as JSON or YAML can be checked by tools that know nothing
+// of protobuf: editors, dashboards and CI.
+//
+// The schema accepts what protojson accepts: fields under either their
+// proto or JSON names, 64-bit integers as numbers or strings, and enums by
+// name or number.
+package jsonschema
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Draft is the JSON Schema version generated.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document.
+type Schema map[string]any
+
+// For returns the schema of a message. Each message type it uses is
+// defined once under $defs, by its full name.
+func For(md protoreflect.MessageDescriptor) Schema {
+	g := &generator{defs: map[string]any{}}
+	g.message(md)
+	return Schema{
+		"$schema": Draft,
+		"$id":     string(md.FullName()),
+		"$ref":    ref(md),
+		"$defs":   g.defs,
+	}
+}
+
+// MarshalIndent returns the schema as indented JSON.
+func (s Schema) MarshalIndent() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+type generator struct {
+	defs map[string]any
+}
+
+func ref(md protoreflect.MessageDescriptor) string {
+	return "#/$defs/" + string(md.FullName())
+}
+
+// message defines the schema of a message type under $defs, if it isn't
+// already.
+func (g *generator) message(md protoreflect.MessageDescriptor) {
+	name := string(md.FullName())
+	if _, ok := g.defs[name]; ok {
+		return
+	}
+	if s, ok := wellKnown[md.FullName()]; ok {
+		g.defs[name] = s
+		return
+	}
+	// Defined before its fields, so that recursive types terminate.
+	props := map[string]any{}
+	s := Schema{"type": "object", "properties": props, "additionalProperties": false}
+	g.defs[name] = s
+
+	fields := md.Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		fs := g.field(fd)
+		props[string(fd.Name())] = fs
+		if fd.JSONName() != string(fd.Name()) {
+			props[fd.JSONName()] = fs
+		}
+	}
+}
+
+// field returns the schema of a field's value.
+func (g *generator) field(fd protoreflect.FieldDescriptor) Schema {
+	switch {
+	case fd.IsMap():
+		return Schema{"type": "object", "additionalProperties": g.value(fd.MapValue())}
+	case fd.IsList():
+		return Schema{"type": "array", "items": g.value(fd)}
+	}
+	return g.value(fd)
+}
+
+// value returns the schema of one value of a field, or of its elements if
+// it is a list or map.
+func (g *generator) value(fd protoreflect.FieldDescriptor) Schema {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return Schema{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return Schema{"type": "integer", "minimum": -1 << 31, "maximum": 1<<31 - 1}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return Schema{"type": "integer", "minimum": 0, "maximum": 1<<32 - 1}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return Schema{"type": []string{"integer", "string"}, "pattern": `^-?[0-9]+$`}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return Schema{"type": []string{"integer", "string"}, "minimum": 0, "pattern": `^[0-9]+$`}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return Schema{"type": []string{"number", "string"}, "pattern": `^(NaN|-?Infinity)$`}
+	case protoreflect.StringKind:
+		return Schema{"type": "string"}
+	case protoreflect.BytesKind:
+		return Schema{"type": "string", "contentEncoding": "base64"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		names := make([]any, 0, values.Len())
+		for i := range values.Len() {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		return Schema{"anyOf": []Schema{{"enum": names}, {"type": "integer"}}}
+	}
+	g.message(fd.Message())
+	return Schema{"$ref": ref(fd.Message())}
+}
+
+// wellKnown are the schemas of messages protojson writes as something
+// other than an object.
+var wellKnown = map[protoreflect.FullName]Schema{
+	"google.protobuf.Duration": {
+		"type":    "string",
+		"pattern": `^-?[0-9]+(\.[0-9]{1,9})?s$`,
+	},
+	"google.protobuf.Timestamp": {
+		"type":   "string",
+		"format": "date-time",
+	},
+}
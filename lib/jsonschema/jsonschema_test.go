@@ -0,0 +1,50 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+func TestFor(t *testing.T) {
+	s := For((&pb.TableConfig{}).ProtoReflect().Descriptor())
+	ExpectEq(t, s["$ref"], "#/$defs/snapfold.gamedef.TableConfig")
+	defs := s["$defs"].(map[string]any)
+
+	cfg := defs["snapfold.gamedef.TableConfig"].(Schema)
+	ExpectEq(t, cfg["additionalProperties"], any(false))
+	props := cfg["properties"].(map[string]any)
+	// Fields go by either name.
+	ExpectEq(t, props["max_party_size"].(Schema)["type"], any("integer"))
+	ExpectThat(t, props["maxPartySize"], Not(Nil()))
+	ExpectEq(t, props["blinds"].(Schema)["$ref"], any("#/$defs/snapfold.gamedef.Blinds"))
+
+	bets := props["bets"].(Schema)["anyOf"].([]Schema)
+	ExpectThat(t, bets[0]["enum"], ElementsAre("LIMIT_UNKNOWN", "NO_LIMIT", "FIXED_LIMIT", "POT_LIMIT"))
+
+	// Messages used anywhere are defined, well-known types as protojson
+	// writes them.
+	blinds := defs["snapfold.gamedef.Blinds"].(Schema)["properties"].(map[string]any)
+	ExpectEq(t, blinds["blind_levels"].(Schema)["type"], any("array"))
+	ExpectEq(t, blinds["blind_levels"].(Schema)["items"].(Schema)["$ref"], any("#/$defs/google.type.Money"))
+	money := defs["google.type.Money"].(Schema)["properties"].(map[string]any)
+	ExpectThat(t, money["units"].(Schema)["type"], ElementsAre("integer", "string"))
+	ExpectEq(t, defs["google.protobuf.Duration"].(Schema)["type"], any("string"))
+
+	b, err := s.MarshalIndent()
+	AssertThat(t, err, Nil())
+	var doc map[string]any
+	AssertThat(t, json.Unmarshal(b, &doc), Nil())
+	ExpectEq(t, doc["$schema"], any(Draft))
+}
+
+func TestFor_Maps(t *testing.T) {
+	s := For((&pb.CreateTableRequest{}).ProtoReflect().Descriptor())
+	props := s["$defs"].(map[string]any)["snapfold.gamedef.CreateTableRequest"].(Schema)["properties"].(map[string]any)
+	stacks := props["stacks"].(Schema)
+	ExpectEq(t, stacks["type"], any("object"))
+	ExpectThat(t, stacks["additionalProperties"].(Schema)["type"], ElementsAre("integer", "string"))
+}
@@ -0,0 +1,149 @@
+// Package log sets up the services' structured logging, on top of log/slog.
+//
+// Records carry the fields in their context: those added with With, such as
+// the ID of the request, ticket or table being worked on, and the IDs of the
+// trace and span in it. Log with the *Context methods of slog.Logger to
+// include them.
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Keys of the fields the services log.
+const (
+	ComponentKey = "component"
+	RequestIDKey = "request_id"
+	TicketIDKey  = "ticket_id"
+	MatchIDKey   = "match_id"
+	TableIDKey   = "table_id"
+	PlayerIDKey  = "player_id"
+	ErrorKey     = "error"
+	TraceIDKey   = "trace_id"
+	SpanIDKey    = "span_id"
+)
+
+// Formats records are written in.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// New returns a logger that writes records at level or above to w, in
+// format: FormatText or FormatJSON.
+func New(w io.Writer, level slog.Leveler, format string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch format {
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("log: unknown format %q", format)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+// ParseLevel parses a level's name, such as debug, info, warn or error.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("log: unknown level %q", s)
+	}
+	return l, nil
+}
+
+// Component returns a logger for one part of a service, whose records name
+// it.
+func Component(l *slog.Logger, name string) *slog.Logger {
+	return l.With(ComponentKey, name)
+}
+
+// RequestID is the field of the ID of the request being served.
+func RequestID(id string) slog.Attr { return slog.String(RequestIDKey, id) }
+
+// TicketID is the field of a queue ticket's ID.
+func TicketID(id string) slog.Attr { return slog.String(TicketIDKey, id) }
+
+// MatchID is the field of a match's ID.
+func MatchID(id string) slog.Attr { return slog.String(MatchIDKey, id) }
+
+// TableID is the field of a table's ID.
+func TableID(id string) slog.Attr { return slog.String(TableIDKey, id) }
+
+// PlayerID is the field of a player's ID.
+func PlayerID(id string) slog.Attr { return slog.String(PlayerIDKey, id) }
+
+// Err is the field of an error.
+func Err(err error) slog.Attr { return slog.Any(ErrorKey, err) }
+
+type fieldsKey struct{}
+
+// With returns ctx carrying fields, which records logged with it include
+// alongside any fields ctx already carries.
+func With(ctx context.Context, fields ...slog.Attr) context.Context {
+	prev, _ := ctx.Value(fieldsKey{}).([]slog.Attr)
+	return context.WithValue(ctx, fieldsKey{}, append(slices.Clip(prev), fields...))
+}
+
+// contextHandler adds the fields in each record's context to it.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	addContext(ctx, &r)
+	return h.Handler.Handle(ctx, r)
+}
+
+// addContext adds the fields in ctx to r.
+func addContext(ctx context.Context, r *slog.Record) {
+	if fields, ok := ctx.Value(fieldsKey{}).([]slog.Attr); ok {
+		r.AddAttrs(fields...)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String(TraceIDKey, sc.TraceID().String()), slog.String(SpanIDKey, sc.SpanID().String()))
+	}
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// RequestIDHeader is the header a request's ID is read from, if the caller
+// set it, and returned in.
+const RequestIDHeader = "X-Request-ID"
+
+// Handler gives each request an ID, from its X-Request-ID header or else
+// random, which is returned in the response's header and carried in the
+// request's context for its records to include.
+func Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = newID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		h.ServeHTTP(w, r.WithContext(With(r.Context(), RequestID(id))))
+	})
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,95 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/tracing"
+)
+
+// records decodes each JSON record written to b.
+func records(t *testing.T, b *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	dec := json.NewDecoder(b)
+	for dec.More() {
+		var r map[string]any
+		AssertThat(t, dec.Decode(&r), Nil())
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestNew(t *testing.T) {
+	var b bytes.Buffer
+	l, err := New(&b, slog.LevelInfo, FormatJSON)
+	AssertThat(t, err, Nil())
+	l = Component(l, "matchmaking")
+
+	ctx := With(context.Background(), TicketID("t1"))
+	ctx = With(ctx, MatchID("m1"))
+	ctx = tracing.Continue(ctx, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	l.DebugContext(ctx, "hidden")
+	l.ErrorContext(ctx, "allocating a server", Err(errors.New("no capacity")))
+	l.Info("listening")
+
+	rs := records(t, &b)
+	AssertThat(t, rs, Len(2))
+	ExpectEq(t, rs[0]["msg"], "allocating a server")
+	ExpectEq(t, rs[0]["level"], "ERROR")
+	ExpectEq(t, rs[0][ComponentKey], "matchmaking")
+	ExpectEq(t, rs[0][TicketIDKey], "t1")
+	ExpectEq(t, rs[0][MatchIDKey], "m1")
+	ExpectEq(t, rs[0][ErrorKey], "no capacity")
+	ExpectEq(t, rs[0][TraceIDKey], "0af7651916cd43dd8448eb211c80319c")
+	ExpectEq(t, rs[0][SpanIDKey], "b7ad6b7169203331")
+	ExpectEq(t, rs[1]["msg"], "listening")
+	ExpectThat(t, rs[1][TicketIDKey], Nil())
+
+	_, err = New(&b, slog.LevelInfo, "xml")
+	ExpectThat(t, err, Not(Nil()))
+}
+
+func TestParseLevel(t *testing.T) {
+	l, err := ParseLevel("debug")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, l, slog.LevelDebug)
+	l, err = ParseLevel("WARN")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, l, slog.LevelWarn)
+	_, err = ParseLevel("loud")
+	ExpectThat(t, err, Not(Nil()))
+}
+
+func TestHandler(t *testing.T) {
+	var b bytes.Buffer
+	l, err := New(&b, slog.LevelInfo, FormatJSON)
+	AssertThat(t, err, Nil())
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.InfoContext(r.Context(), "served")
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	id := rec.Header().Get(RequestIDHeader)
+	ExpectThat(t, id, Not(Empty()))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "abc")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	ExpectEq(t, rec.Header().Get(RequestIDHeader), "abc")
+
+	rs := records(t, &b)
+	AssertThat(t, rs, Len(2))
+	ExpectEq(t, rs[0][RequestIDKey], any(id))
+	ExpectEq(t, rs[1][RequestIDKey], "abc")
+}
@@ -0,0 +1,124 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Entry is a record kept by Recent.
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"msg"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Recent keeps the last records logged at error level, such as for a
+// dashboard to show. It is safe for concurrent use.
+type Recent struct {
+	mu      sync.Mutex
+	entries []Entry // oldest first
+	size    int
+}
+
+// NewRecent returns a Recent that keeps the last size errors.
+func NewRecent(size int) *Recent {
+	return &Recent{size: size}
+}
+
+// Entries returns the errors kept, newest first.
+func (r *Recent) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := slices.Clone(r.entries)
+	slices.Reverse(entries)
+	return entries
+}
+
+func (r *Recent) add(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) == r.size {
+		r.entries = slices.Delete(r.entries, 0, 1)
+	}
+	r.entries = append(r.entries, e)
+}
+
+// WithRecent returns a logger that logs to l, and also keeps the records
+// at error level in r, with their fields and those of their context. Call
+// it before Component, so that components' errors are kept too.
+func WithRecent(l *slog.Logger, r *Recent) *slog.Logger {
+	return slog.New(recentHandler{next: l.Handler(), recent: r})
+}
+
+// recentHandler passes records on to next, keeping errors in recent.
+type recentHandler struct {
+	next   slog.Handler
+	recent *Recent
+	attrs  []slog.Attr
+	group  string
+}
+
+func (h recentHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelError || h.next.Enabled(ctx, level)
+}
+
+func (h recentHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError {
+		e := Entry{Time: r.Time, Level: r.Level.String(), Message: r.Message, Fields: map[string]string{}}
+		for _, a := range h.attrs {
+			addField(e.Fields, "", a)
+		}
+		withContext := r.Clone()
+		addContext(ctx, &withContext)
+		withContext.Attrs(func(a slog.Attr) bool {
+			addField(e.Fields, h.group, a)
+			return true
+		})
+		h.recent.add(e)
+	}
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h recentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	// Attributes are kept qualified by the group they were added in.
+	qualified := slices.Clone(h.attrs)
+	for _, a := range attrs {
+		if h.group != "" {
+			a.Key = h.group + a.Key
+		}
+		qualified = append(qualified, a)
+	}
+	return recentHandler{next: h.next.WithAttrs(attrs), recent: h.recent, attrs: qualified, group: h.group}
+}
+
+func (h recentHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return recentHandler{next: h.next.WithGroup(name), recent: h.recent, attrs: h.attrs, group: h.group + name + "."}
+}
+
+// addField formats a and adds it to fields, flattening groups into dotted
+// keys.
+func addField(fields map[string]string, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			addField(fields, prefix, ga)
+		}
+		return
+	}
+	if a.Key != "" {
+		fields[prefix+a.Key] = a.Value.String()
+	}
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestRecent(t *testing.T) {
+	var b bytes.Buffer
+	l, err := New(&b, slog.LevelWarn, FormatJSON)
+	AssertThat(t, err, Nil())
+	recent := NewRecent(2)
+	l = Component(WithRecent(l, recent), "matchmaking")
+
+	ctx := With(context.Background(), MatchID("m1"))
+	l.InfoContext(ctx, "formed match")
+	l.ErrorContext(ctx, "seating match", Err(errors.New("store down")))
+	l.WithGroup("db").Error("pinging", slog.Int("attempt", 2))
+
+	// Only errors are kept, but records still reach the logger by its
+	// level.
+	entries := recent.Entries()
+	AssertThat(t, entries, Len(2))
+	ExpectEq(t, entries[0].Message, "pinging")
+	ExpectEq(t, entries[0].Fields, map[string]string{ComponentKey: "matchmaking", "db.attempt": "2"})
+	ExpectEq(t, entries[1].Message, "seating match")
+	ExpectEq(t, entries[1].Level, "ERROR")
+	ExpectEq(t, entries[1].Fields, map[string]string{ComponentKey: "matchmaking", MatchIDKey: "m1", ErrorKey: "store down"})
+	ExpectThat(t, records(t, &b), Len(2))
+
+	// Only the last errors are kept.
+	l.Error("third")
+	entries = recent.Entries()
+	AssertThat(t, entries, Len(2))
+	ExpectEq(t, entries[0].Message, "third")
+	ExpectEq(t, entries[1].Message, "pinging")
+}
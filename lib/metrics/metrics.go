@@ -0,0 +1,351 @@
+// Package metrics registers the counters, gauges and histograms a server
+// keeps, and serves them in the Prometheus text format.
+//
+// Metrics may have labels, whose values are given, in the order the labels
+// were registered in, each time one is updated. The methods of a nil
+// *Counter, *Gauge or *Histogram do nothing, so that code can be
+// instrumented whether or not it was given a registry.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kinds of metric, as written in TYPE lines.
+const (
+	KindCounter   = "counter"
+	KindGauge     = "gauge"
+	KindHistogram = "histogram"
+)
+
+// DefaultBuckets are the upper bounds of histogram buckets suited to
+// latencies, in seconds.
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds a server's metrics. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	names    map[string]bool
+	families []func(io.Writer)
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+// add registers a family written by write. Registering a name twice is a
+// mistake in the program, so it panics.
+func (r *Registry) add(name string, write func(io.Writer)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic(fmt.Sprintf("metrics: %s registered twice", name))
+	}
+	r.names[name] = true
+	r.families = append(r.families, write)
+}
+
+// Counter registers a counter: a total that only goes up.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{newVec(name, labels, func() *value { return new(value) })}
+	r.add(name, func(w io.Writer) {
+		WriteHeader(w, name, KindCounter, help)
+		c.vec.each(func(labels string, v *value) { fmt.Fprintf(w, "%s%s %g\n", name, labels, v.load()) })
+	})
+	return c
+}
+
+// Gauge registers a gauge: a value that goes up and down.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{newVec(name, labels, func() *value { return new(value) })}
+	r.add(name, func(w io.Writer) {
+		WriteHeader(w, name, KindGauge, help)
+		g.vec.each(func(labels string, v *value) { fmt.Fprintf(w, "%s%s %g\n", name, labels, v.load()) })
+	})
+	return g
+}
+
+// Histogram registers a histogram, which counts observations into buckets
+// by the given upper bounds, in ascending order.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if !slices.IsSorted(buckets) {
+		panic(fmt.Sprintf("metrics: %s's buckets are not in order", name))
+	}
+	buckets = slices.Clone(buckets)
+	h := &Histogram{buckets: buckets, vec: newVec(name, labels, func() *histogram {
+		return &histogram{counts: make([]uint64, len(buckets))}
+	})}
+	r.add(name, func(w io.Writer) {
+		WriteHeader(w, name, KindHistogram, help)
+		h.vec.each(func(labels string, s *histogram) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			var n uint64
+			for i, b := range buckets {
+				n += s.counts[i]
+				fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", fmt.Sprintf("%g", b)), n)
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", "+Inf"), s.count)
+			fmt.Fprintf(w, "%s_sum%s %g\n", name, labels, s.sum)
+			fmt.Fprintf(w, "%s_count%s %d\n", name, labels, s.count)
+		})
+	})
+	return h
+}
+
+// GaugeFunc registers a gauge whose value is read from value when the
+// metrics are written, for values kept elsewhere.
+func (r *Registry) GaugeFunc(name, help string, value func() float64) {
+	r.add(name, func(w io.Writer) {
+		WriteHeader(w, name, KindGauge, help)
+		fmt.Fprintf(w, "%s %g\n", name, value())
+	})
+}
+
+// CounterFunc is GaugeFunc for a counter.
+func (r *Registry) CounterFunc(name, help string, value func() float64) {
+	r.add(name, func(w io.Writer) {
+		WriteHeader(w, name, KindCounter, help)
+		fmt.Fprintf(w, "%s %g\n", name, value())
+	})
+}
+
+// Collect registers metrics that write writes out itself, in the text
+// format, such as those with a series for each of a changing set of things.
+func (r *Registry) Collect(write func(w io.Writer)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, write)
+}
+
+// WriteTo writes every metric to w, in the order they were registered.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+	cw := &countingWriter{w: w}
+	for _, write := range families {
+		write(cw)
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP serves the metrics.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.WriteTo(w)
+}
+
+// WriteHeader writes the HELP and TYPE lines that start a metric, for
+// metrics written by a Collect function.
+func WriteHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// Counter is a total that only goes up.
+type Counter struct {
+	vec *vec[value]
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+// Add adds d, which may not be negative, to the counter.
+func (c *Counter) Add(d float64, labels ...string) {
+	if c == nil {
+		return
+	}
+	if d < 0 {
+		panic(fmt.Sprintf("metrics: %s decreased", c.vec.name))
+	}
+	c.vec.get(labels).add(d)
+}
+
+// Gauge is a value that goes up and down.
+type Gauge struct {
+	vec *vec[value]
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64, labels ...string) {
+	if g == nil {
+		return
+	}
+	g.vec.get(labels).store(v)
+}
+
+// Add adds d to the gauge, or takes it away if negative.
+func (g *Gauge) Add(d float64, labels ...string) {
+	if g == nil {
+		return
+	}
+	g.vec.get(labels).add(d)
+}
+
+// Histogram counts observations, such as latencies, into buckets.
+type Histogram struct {
+	buckets []float64
+	vec     *vec[histogram]
+}
+
+// Observe counts v.
+func (h *Histogram) Observe(v float64, labels ...string) {
+	if h == nil {
+		return
+	}
+	s := h.vec.get(labels)
+	i, _ := slices.BinarySearch(h.buckets, v)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+// Since observes the seconds since start.
+func (h *Histogram) Since(start time.Time, labels ...string) {
+	h.Observe(time.Since(start).Seconds(), labels...)
+}
+
+// histogram is one series of a Histogram. counts are per bucket, not
+// cumulative; observations above the last bucket are only in count.
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// value is a float64 that is updated atomically.
+type value struct {
+	bits atomic.Uint64
+}
+
+func (v *value) load() float64 {
+	return math.Float64frombits(v.bits.Load())
+}
+
+func (v *value) store(f float64) {
+	v.bits.Store(math.Float64bits(f))
+}
+
+func (v *value) add(d float64) {
+	for {
+		old := v.bits.Load()
+		if v.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+d)) {
+			return
+		}
+	}
+}
+
+// vec is the series of a metric, one for each combination of its labels'
+// values.
+type vec[T any] struct {
+	name      string
+	labels    []string
+	newSeries func() *T
+
+	mu     sync.Mutex
+	series map[string]*T
+}
+
+func newVec[T any](name string, labels []string, newSeries func() *T) *vec[T] {
+	v := &vec[T]{name: name, labels: slices.Clone(labels), newSeries: newSeries, series: map[string]*T{}}
+	if len(labels) == 0 {
+		// Metrics without labels are written from the start, as zero.
+		v.get(nil)
+	}
+	return v
+}
+
+// get returns the series with the given label values, creating it if
+// needed. Passing the wrong number of values is a mistake in the program,
+// so it panics.
+func (v *vec[T]) get(values []string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, not %d", v.name, len(v.labels), len(values)))
+	}
+	key := formatLabels(v.labels, values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = v.newSeries()
+		v.series[key] = s
+	}
+	return s
+}
+
+// each calls fn with each series and its labels, as written after the
+// metric's name, in order of their labels.
+func (v *vec[T]) each(fn func(labels string, s *T)) {
+	v.mu.Lock()
+	keys := slices.Sorted(maps.Keys(v.series))
+	series := make([]*T, len(keys))
+	for i, k := range keys {
+		series[i] = v.series[k]
+	}
+	v.mu.Unlock()
+	for i, k := range keys {
+		fn(k, series[i])
+	}
+}
+
+// formatLabels returns labels and their values as written after a metric's
+// name, or "" if there are none.
+func formatLabels(labels, values []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", l, values[i])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// withLabel adds a label to formatted labels.
+func withLabel(labels, name, value string) string {
+	l := fmt.Sprintf("%s=%q", name, value)
+	if labels == "" {
+		return "{" + l + "}"
+	}
+	return labels[:len(labels)-1] + "," + l + "}"
+}
+
+// countingWriter counts what is written to w, and remembers the first
+// error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func scrape(t *testing.T, r *Registry) []string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	ExpectThat(t, rec.Header().Get("Content-Type"), HasSubstr("text/plain"))
+	return strings.Split(rec.Body.String(), "\n")
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	hands := r.Counter("hands_total", "Hands played.", "game_mode")
+	hands.Inc("holdem")
+	hands.Add(2, "holdem")
+	hands.Inc("omaha")
+	depth := r.Gauge("queue_depth", "Tickets waiting.")
+	depth.Set(5)
+	depth.Add(-2)
+	r.GaugeFunc("capacity", "Most tables.", func() float64 { return 10 })
+	r.Collect(func(w io.Writer) {
+		WriteHeader(w, "tables", KindGauge, "Tables open.")
+		fmt.Fprintln(w, `tables{region="eu"} 1`)
+	})
+
+	lines := scrape(t, r)
+	ExpectThat(t, lines, Contains("# TYPE hands_total counter"))
+	ExpectThat(t, lines, Contains(`hands_total{game_mode="holdem"} 3`))
+	ExpectThat(t, lines, Contains(`hands_total{game_mode="omaha"} 1`))
+	ExpectThat(t, lines, Contains("# HELP queue_depth Tickets waiting."))
+	ExpectThat(t, lines, Contains("queue_depth 3"))
+	ExpectThat(t, lines, Contains("capacity 10"))
+	ExpectThat(t, lines, Contains(`tables{region="eu"} 1`))
+
+	defer func() { ExpectEq(t, recover() != nil, true) }()
+	r.Gauge("capacity", "Again.")
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.Histogram("latency_seconds", "Latency.", []float64{.1, 1}, "op")
+	for _, v := range []float64{.05, .1, .5, 3} {
+		h.Observe(v, "read")
+	}
+	lines := scrape(t, r)
+	ExpectThat(t, lines, Contains("# TYPE latency_seconds histogram"))
+	ExpectThat(t, lines, Contains(`latency_seconds_bucket{op="read",le="0.1"} 2`))
+	ExpectThat(t, lines, Contains(`latency_seconds_bucket{op="read",le="1"} 3`))
+	ExpectThat(t, lines, Contains(`latency_seconds_bucket{op="read",le="+Inf"} 4`))
+	ExpectThat(t, lines, Contains(`latency_seconds_sum{op="read"} 3.65`))
+	ExpectThat(t, lines, Contains(`latency_seconds_count{op="read"} 4`))
+
+	defer func() { ExpectEq(t, recover() != nil, true) }()
+	h.Observe(1)
+}
+
+func TestNil(t *testing.T) {
+	var c *Counter
+	var g *Gauge
+	var h *Histogram
+	c.Inc("x")
+	g.Set(1)
+	h.Observe(1)
+}
@@ -0,0 +1,173 @@
+// Package protocol versions the protocol between snapfold's clients and
+// servers, so that a server can turn away clients too old to understand it
+// with a clear error, and leave out of its replies the features a client
+// says it can't handle.
+//
+// A client names the version it speaks and its optional capabilities in the
+// Snapfold-Protocol header of each HTTP request, WebSocket handshake and gRPC
+// call, as "2 ready-checks": a version, then capabilities separated by
+// spaces. Browsers can't set headers on WebSocket handshakes, so those may
+// carry it in the protocol query parameter instead. Servers reply with the
+// version they speak in the same header.
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Version is the protocol version this build speaks. It goes up whenever
+	// a message or endpoint changes in a way that older peers would misread.
+	Version = 3
+
+	// MinVersion is the oldest client version that servers of this build
+	// accept.
+	MinVersion = 1
+
+	// Header is the HTTP header, and the gRPC metadata key, lower-cased,
+	// that carries a peer's version.
+	Header = "Snapfold-Protocol"
+
+	// queryParam carries a client's version on WebSocket handshakes from
+	// browsers.
+	queryParam = "protocol"
+)
+
+// Capability is an optional feature of the protocol that a client handles.
+// Servers leave a feature out of their replies to clients that don't name
+// it, doing what they would have done on the client's behalf where they can.
+type Capability string
+
+const (
+	// ReadyChecks clients answer a match's ready check. For other clients,
+	// the matchmaker accepts ready checks itself as it would send them.
+	ReadyChecks Capability = "ready-checks"
+)
+
+// ErrUnsupported is returned for clients too old for the server.
+var ErrUnsupported = errors.New("unsupported protocol version")
+
+// Hello is what a client says of itself: the version it speaks and its
+// capabilities.
+type Hello struct {
+	Version      int
+	Capabilities []Capability
+}
+
+// Current is what clients of this build say.
+var Current = Hello{Version: Version, Capabilities: []Capability{ReadyChecks}}
+
+// framedSince is the first version whose WebSocket messages are framed.
+const framedSince = 3
+
+// Legacy is what is assumed of clients that don't say: the clients from
+// before versions were negotiated, which handle every feature they had.
+var Legacy = Hello{Version: 1, Capabilities: []Capability{ReadyChecks}}
+
+// Has reports whether the client handles a capability.
+func (h Hello) Has(c Capability) bool {
+	return slices.Contains(h.Capabilities, c)
+}
+
+// Framed reports whether WebSocket messages to and from the client are
+// frames, as package frame encodes them, rather than bare protobuf messages.
+func (h Hello) Framed() bool {
+	return h.Version >= framedSince
+}
+
+func (h Hello) String() string {
+	s := strconv.Itoa(h.Version)
+	for _, c := range h.Capabilities {
+		s += " " + string(c)
+	}
+	return s
+}
+
+// Parse parses a Hello from its header value. An empty value is Legacy.
+func Parse(s string) (Hello, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return Legacy, nil
+	}
+	v, err := strconv.Atoi(fields[0])
+	if err != nil || v < 0 {
+		return Hello{}, fmt.Errorf("malformed %s header %q", Header, s)
+	}
+	h := Hello{Version: v}
+	for _, c := range fields[1:] {
+		h.Capabilities = append(h.Capabilities, Capability(c))
+	}
+	return h, nil
+}
+
+// Negotiate checks the Hello in a client's header value, returning
+// ErrUnsupported if the client is too old. A client newer than the server
+// is accepted at the server's version, and must speak that.
+func Negotiate(s string) (Hello, error) {
+	h, err := Parse(s)
+	if err != nil {
+		return Hello{}, err
+	}
+	if h.Version < MinVersion {
+		return Hello{}, fmt.Errorf("%w: this client speaks version %d, but the server needs %d or later; please update", ErrUnsupported, h.Version, MinVersion)
+	}
+	h.Version = min(h.Version, Version)
+	return h, nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying a client's Hello.
+func NewContext(ctx context.Context, h Hello) context.Context {
+	return context.WithValue(ctx, contextKey{}, h)
+}
+
+// FromContext returns the client's Hello, or Legacy if the context carries
+// none.
+func FromContext(ctx context.Context) Hello {
+	if h, ok := ctx.Value(contextKey{}).(Hello); ok {
+		return h
+	}
+	return Legacy
+}
+
+// UpgradeHeader returns the header to reply to a WebSocket handshake with,
+// naming the server's version as Handler does for other replies. WebSocket
+// upgraders write their reply themselves, without the ResponseWriter's
+// header.
+func UpgradeHeader() http.Header {
+	return http.Header{Header: {strconv.Itoa(Version)}}
+}
+
+// Handler negotiates the protocol with each request's client before handing
+// the request to next, with the client's Hello in its context. Clients too
+// old for the server get 426 Upgrade Required, and malformed headers 400,
+// with the reason as {"error": message}.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(Header, strconv.Itoa(Version))
+		hello := r.Header.Get(Header)
+		if hello == "" {
+			hello = r.URL.Query().Get(queryParam)
+		}
+		h, err := Negotiate(hello)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrUnsupported) {
+				status = http.StatusUpgradeRequired
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), h)))
+	})
+}
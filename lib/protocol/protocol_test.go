@@ -0,0 +1,74 @@
+package protocol
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestNegotiate(t *testing.T) {
+	for _, tc := range []struct {
+		hello string
+		want  Hello
+	}{
+		{"", Legacy},
+		{"1", Hello{Version: 1}},
+		{"2 ready-checks", Hello{Version: 2, Capabilities: []Capability{ReadyChecks}}},
+		// Newer clients are spoken to at the server's version.
+		{"7  ready-checks  holograms", Hello{Version: Version, Capabilities: []Capability{ReadyChecks, "holograms"}}},
+	} {
+		t.Run(tc.hello, func(t *testing.T) {
+			h, err := Negotiate(tc.hello)
+			AssertThat(t, err, Nil())
+			ExpectEq(t, h, tc.want)
+		})
+	}
+	_, err := Negotiate("0")
+	ExpectThat(t, err, ErrorIs(ErrUnsupported))
+	for _, bad := range []string{"v2", "-1", "two ready-checks"} {
+		_, err := Negotiate(bad)
+		ExpectThat(t, err, Not(Nil()))
+	}
+}
+
+func TestHello(t *testing.T) {
+	h, err := Parse(Current.String())
+	AssertThat(t, err, Nil())
+	ExpectEq(t, h, Current)
+	ExpectEq(t, h.Has(ReadyChecks), true)
+	ExpectEq(t, Hello{Version: 2}.Has(ReadyChecks), false)
+	ExpectEq(t, FromContext(context.Background()), Legacy)
+	ExpectEq(t, Current.Framed(), true)
+	ExpectEq(t, Legacy.Framed(), false)
+}
+
+func TestHandler(t *testing.T) {
+	var got Hello
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+	serve := func(target, hello string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		if hello != "" {
+			req.Header.Set(Header, hello)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		ExpectEq(t, rec.Header().Get(Header), "3")
+		return rec
+	}
+
+	ExpectEq(t, serve("/", "2").Code, http.StatusOK)
+	ExpectEq(t, got, Hello{Version: 2})
+	// WebSocket handshakes from browsers carry it in the URL.
+	ExpectEq(t, serve("/?protocol=3+ready-checks", "").Code, http.StatusOK)
+	ExpectEq(t, got, Current)
+
+	rec := serve("/", "0")
+	ExpectEq(t, rec.Code, http.StatusUpgradeRequired)
+	ExpectThat(t, rec.Body.String(), HasSubstr("please update"))
+	ExpectEq(t, serve("/", "two").Code, http.StatusBadRequest)
+}
@@ -0,0 +1,186 @@
+// Package shuffle shuffles decks so that every hand can be audited. Each
+// shuffle is driven by a 32-byte seed, and produces a commitment: a hash of
+// the seed and the shuffled deck that can be published before the hand is
+// dealt. Revealing the seed afterward lets anyone check that the deck was
+// not changed once the hand began, and reproduce the shuffle exactly.
+//
+// In production, seeds come from crypto/rand. Tests and replays may inject
+// a seed so that shuffles are reproducible.
+package shuffle
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	mrand "math/rand/v2"
+	"slices"
+	"sync"
+
+	"github.com/jfmatt/snapfold/lib/handeval"
+)
+
+var (
+	ErrInvalidSeed = errors.New("invalid seed")
+	ErrMismatch    = errors.New("shuffle does not match its commitment")
+)
+
+// Seed drives one shuffle.
+type Seed [32]byte
+
+// NewSeed returns a random seed from crypto/rand.
+func NewSeed() (Seed, error) {
+	var s Seed
+	if _, err := rand.Read(s[:]); err != nil {
+		return Seed{}, fmt.Errorf("reading random seed: %w", err)
+	}
+	return s, nil
+}
+
+// ParseSeed parses a seed in the hex form String returns.
+func ParseSeed(s string) (Seed, error) {
+	var seed Seed
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(seed) {
+		return Seed{}, fmt.Errorf("%w: want %d hex-encoded bytes", ErrInvalidSeed, len(seed))
+	}
+	copy(seed[:], b)
+	return seed, nil
+}
+
+func (s Seed) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// Commitment is a hash of a shuffle's seed and the deck it produced.
+type Commitment [sha256.Size]byte
+
+func (c Commitment) String() string {
+	return hex.EncodeToString(c[:])
+}
+
+// commitPrefix versions commitments, so that the format can change without
+// old ones verifying against the new one.
+const commitPrefix = "snapfold shuffle v1\n"
+
+// Commit returns the commitment to a seed and the deck it shuffled into.
+func Commit(seed Seed, deck []handeval.Card) Commitment {
+	h := sha256.New()
+	h.Write([]byte(commitPrefix))
+	h.Write(seed[:])
+	for _, c := range deck {
+		h.Write([]byte(c.String()))
+	}
+	var c Commitment
+	h.Sum(c[:0])
+	return c
+}
+
+// Deck returns a fresh deck, in order: every rank from low to ace in each
+// suit. A standard deck is Deck(handeval.Two), and a short deck
+// Deck(handeval.Six).
+func Deck(low handeval.Rank) []handeval.Card {
+	var deck []handeval.Card
+	for _, s := range []handeval.Suit{handeval.Spades, handeval.Hearts, handeval.Diamonds, handeval.Clubs} {
+		for r := low; r <= handeval.Ace; r++ {
+			deck = append(deck, handeval.Card{Rank: r, Suit: s})
+		}
+	}
+	return deck
+}
+
+// Apply shuffles a copy of deck with seed. The same seed and deck always
+// give the same order.
+func Apply(seed Seed, deck []handeval.Card) []handeval.Card {
+	// The shuffle is spelled out rather than left to math/rand, whose
+	// methods may change how they use the source, so that shuffles can be
+	// replayed across Go versions. ChaCha8's output is stable.
+	src := mrand.NewChaCha8(seed)
+	out := slices.Clone(deck)
+	for i := len(out) - 1; i > 0; i-- {
+		j := uniform(src, uint64(i+1))
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// uniform returns a number in [0, n) without modulo bias.
+func uniform(src *mrand.ChaCha8, n uint64) uint64 {
+	limit := math.MaxUint64 - math.MaxUint64%n
+	for {
+		if v := src.Uint64(); v < limit {
+			return v % n
+		}
+	}
+}
+
+// Shuffle is one shuffled deck and what is needed to audit it.
+type Shuffle struct {
+	Seed Seed
+
+	// The shuffled deck, in the order it is dealt.
+	Deck []handeval.Card
+
+	// The commitment to Seed and Deck, to publish before the hand.
+	Commitment Commitment
+}
+
+// Verify checks that s is what shuffling deck with s.Seed gives, and that
+// its commitment matches.
+func Verify(s Shuffle, deck []handeval.Card) error {
+	if !slices.Equal(Apply(s.Seed, deck), s.Deck) {
+		return fmt.Errorf("%w: deck is not what the seed shuffles to", ErrMismatch)
+	}
+	if want := Commit(s.Seed, s.Deck); !bytes.Equal(want[:], s.Commitment[:]) {
+		return fmt.Errorf("%w: commitment is %s; want %s", ErrMismatch, s.Commitment, want)
+	}
+	return nil
+}
+
+// Shuffler shuffles decks, one per hand. It is safe for concurrent use.
+type Shuffler struct {
+	mu   sync.Mutex
+	base *Seed // nil for seeds from crypto/rand
+	hand uint64
+}
+
+// New returns a Shuffler that seeds every shuffle from crypto/rand.
+func New() *Shuffler {
+	return &Shuffler{}
+}
+
+// NewSeeded returns a Shuffler whose shuffles are all derived from seed,
+// for tests and replays. Two Shufflers with the same seed shuffle the same
+// decks the same way, in the same order.
+func NewSeeded(seed Seed) *Shuffler {
+	return &Shuffler{base: &seed}
+}
+
+// Shuffle shuffles a copy of deck with a new seed.
+func (s *Shuffler) Shuffle(deck []handeval.Card) (Shuffle, error) {
+	seed, err := s.next()
+	if err != nil {
+		return Shuffle{}, err
+	}
+	shuffled := Apply(seed, deck)
+	return Shuffle{Seed: seed, Deck: shuffled, Commitment: Commit(seed, shuffled)}, nil
+}
+
+func (s *Shuffler) next() (Seed, error) {
+	if s.base == nil {
+		return NewSeed()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := sha256.New()
+	h.Write(s.base[:])
+	h.Write(binary.BigEndian.AppendUint64(nil, s.hand))
+	s.hand++
+	var seed Seed
+	h.Sum(seed[:0])
+	return seed, nil
+}
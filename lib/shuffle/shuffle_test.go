@@ -0,0 +1,104 @@
+package shuffle
+
+import (
+	"slices"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/handeval"
+)
+
+func seed(b byte) Seed {
+	var s Seed
+	s[0] = b
+	return s
+}
+
+func TestDeck(t *testing.T) {
+	deck := Deck(handeval.Two)
+	AssertThat(t, deck, Len(52))
+	ExpectEq(t, deck[0].String(), "2s")
+	ExpectEq(t, deck[51].String(), "Ac")
+	ExpectThat(t, Deck(handeval.Six), Len(36))
+}
+
+func TestApply(t *testing.T) {
+	deck := Deck(handeval.Two)
+	a := Apply(seed(1), deck)
+	ExpectThat(t, a, Len(52))
+	ExpectEq(t, slices.Equal(a, Apply(seed(1), deck)), true)
+	ExpectEq(t, slices.Equal(a, Apply(seed(2), deck)), false)
+	ExpectEq(t, slices.Equal(a, deck), false)
+	// The deck passed in is left alone.
+	ExpectEq(t, deck[0].String(), "2s")
+
+	sorted := slices.Clone(a)
+	slices.SortFunc(sorted, func(x, y handeval.Card) int {
+		return slices.Index(deck, x) - slices.Index(deck, y)
+	})
+	ExpectEq(t, slices.Equal(sorted, deck), true)
+}
+
+func TestApply_Stable(t *testing.T) {
+	// Shuffles must replay the same way forever, so that old hands can be
+	// audited.
+	deck := Apply(seed(0), Deck(handeval.Two))
+	ExpectEq(t, handeval.FormatCards(deck[:8]), "2s 2d 5s 7h 4d 6c 7s 6d")
+	ExpectEq(t, Commit(seed(0), deck).String(), "8886bc17d08a6ebfab606d7946237f05cb961d0fc4d418d10490affdb82bd2fa")
+}
+
+func TestShuffler_Seeded(t *testing.T) {
+	deck := Deck(handeval.Two)
+	a, b := NewSeeded(seed(7)), NewSeeded(seed(7))
+	a1, err := a.Shuffle(deck)
+	AssertThat(t, err, Nil())
+	a2, err := a.Shuffle(deck)
+	AssertThat(t, err, Nil())
+	b1, err := b.Shuffle(deck)
+	AssertThat(t, err, Nil())
+
+	ExpectEq(t, a1.Seed, b1.Seed)
+	ExpectEq(t, a1.Commitment, b1.Commitment)
+	ExpectEq(t, slices.Equal(a1.Deck, b1.Deck), true)
+	ExpectThat(t, a1.Seed, Not(Eq(a2.Seed)))
+}
+
+func TestShuffler_Random(t *testing.T) {
+	deck := Deck(handeval.Two)
+	s := New()
+	a, err := s.Shuffle(deck)
+	AssertThat(t, err, Nil())
+	b, err := s.Shuffle(deck)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, a.Seed, Not(Eq(b.Seed)))
+	ExpectThat(t, Verify(a, deck), Nil())
+}
+
+func TestVerify(t *testing.T) {
+	deck := Deck(handeval.Two)
+	s, err := NewSeeded(seed(3)).Shuffle(deck)
+	AssertThat(t, err, Nil())
+	AssertThat(t, Verify(s, deck), Nil())
+
+	swapped := s
+	swapped.Deck = slices.Clone(s.Deck)
+	swapped.Deck[0], swapped.Deck[1] = swapped.Deck[1], swapped.Deck[0]
+	ExpectThat(t, Verify(swapped, deck), ErrorIs(ErrMismatch))
+
+	wrong := s
+	wrong.Commitment = Commit(seed(4), s.Deck)
+	ExpectThat(t, Verify(wrong, deck), ErrorIs(ErrMismatch))
+}
+
+func TestParseSeed(t *testing.T) {
+	s := seed(9)
+	got, err := ParseSeed(s.String())
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got, s)
+
+	_, err = ParseSeed("abcd")
+	ExpectThat(t, err, ErrorIs(ErrInvalidSeed))
+	_, err = ParseSeed("zz")
+	ExpectThat(t, err, ErrorIs(ErrInvalidSeed))
+}
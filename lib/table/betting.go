@@ -0,0 +1,271 @@
+package table
+
+import "fmt"
+
+// Player is a player taking part in a betting round.
+type Player struct {
+	Seat int
+
+	// Chips behind, not counting Bet.
+	Stack int64
+
+	// Chips already bet this round, such as a posted blind.
+	Bet int64
+
+	Folded bool
+}
+
+// AllIn reports whether the player has no chips left to bet.
+func (p Player) AllIn() bool {
+	return p.Stack == 0 && !p.Folded
+}
+
+// Options are what the player whose turn it is may do. They may always
+// fold.
+type Options struct {
+	Seat int
+
+	// Chips needed to call. Zero if the player may check instead.
+	Call int64
+
+	// Smallest and largest total the player may bet or raise to this round.
+	// Both are zero if they may not bet or raise. A player may always go
+	// all in for less than MinTo.
+	MinTo, MaxTo int64
+}
+
+// Betting runs one betting round. Players act in turn until everyone still
+// in the hand has matched the largest bet, or all but one have folded.
+type Betting struct {
+	rules   BettingRules
+	pot     int64
+	players []Player
+
+	// Whether each player has acted since the last full raise, and so may
+	// only call or fold if the bet goes up by less than a full raise.
+	acted []bool
+
+	turn   int // index into players, or -1 once the round is over
+	bet    int64
+	raise  int64 // size of the last full raise
+	raises int
+	events []Event
+}
+
+// NewBetting starts a betting round. Players are given in the order they
+// act, starting from first, and pot is what was bet in earlier rounds.
+// Players who posted blinds still get to act even if nobody raises.
+func NewBetting(rules BettingRules, players []Player, first int, pot int64) (*Betting, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	if first < 0 || first >= len(players) {
+		return nil, fmt.Errorf("%w: first player %d of %d", ErrInvalidAction, first, len(players))
+	}
+	b := &Betting{
+		rules:   rules,
+		pot:     pot,
+		players: append([]Player(nil), players...),
+		acted:   make([]bool, len(players)),
+		raise:   rules.MinBet,
+	}
+	for _, p := range players {
+		b.bet = max(b.bet, p.Bet)
+	}
+	if b.bet > 0 {
+		// A blind opens the betting.
+		b.raises = 1
+	}
+	b.turn = first - 1
+	b.advance()
+	return b, nil
+}
+
+// Over reports whether the round has ended.
+func (b *Betting) Over() bool {
+	return b.turn < 0
+}
+
+// Players returns the players as they stand now, in the order they were
+// given.
+func (b *Betting) Players() []Player {
+	return append([]Player(nil), b.players...)
+}
+
+// Pot returns every chip bet so far, in this round and earlier ones.
+func (b *Betting) Pot() int64 {
+	total := b.pot
+	for _, p := range b.players {
+		total += p.Bet
+	}
+	return total
+}
+
+// Events returns everything that has happened in the round, in order.
+func (b *Betting) Events() []Event {
+	return append([]Event(nil), b.events...)
+}
+
+// Options returns what the player whose turn it is may do, or false if the
+// round is over.
+func (b *Betting) Options() (Options, bool) {
+	if b.Over() {
+		return Options{}, false
+	}
+	p := b.players[b.turn]
+	opts := Options{Seat: p.Seat, Call: min(b.bet-p.Bet, p.Stack)}
+	allIn := p.Bet + p.Stack
+	if b.acted[b.turn] || allIn <= b.bet || !b.othersCanAct(b.turn) {
+		return opts, true
+	}
+	if b.rules.Limit == FixedLimit && b.rules.MaxRaises > 0 && b.raises >= b.rules.MaxRaises {
+		return opts, true
+	}
+	opts.MinTo = b.bet + b.raise
+	switch b.rules.Limit {
+	case NoLimit:
+		opts.MaxTo = allIn
+	case PotLimit:
+		// The most a player can raise is the size of the pot after they
+		// call.
+		opts.MaxTo = b.bet + b.Pot() + (b.bet - p.Bet)
+	case FixedLimit:
+		opts.MaxTo = opts.MinTo
+	}
+	opts.MinTo = min(opts.MinTo, allIn)
+	opts.MaxTo = min(opts.MaxTo, allIn)
+	return opts, true
+}
+
+// Act takes an action for the player in seat. For Bet and Raise, to is the
+// player's total bet for the round afterward; it is ignored otherwise.
+func (b *Betting) Act(seat int, a Action, to int64) error {
+	opts, ok := b.Options()
+	if !ok {
+		return ErrRoundOver
+	}
+	if seat != opts.Seat {
+		return fmt.Errorf("%w: waiting for seat %d", ErrNotYourTurn, opts.Seat)
+	}
+	p := &b.players[b.turn]
+	ev := Event{Seat: seat}
+	switch a {
+	case Fold:
+		p.Folded = true
+		ev.Type = EventFold
+	case Check:
+		if opts.Call > 0 {
+			return fmt.Errorf("%w: cannot check facing a bet of %d", ErrInvalidAction, b.bet)
+		}
+		ev.Type = EventCheck
+	case Call:
+		if opts.Call == 0 {
+			return fmt.Errorf("%w: nothing to call", ErrInvalidAction)
+		}
+		ev.Type = EventCall
+		ev.Amount = opts.Call
+	case Bet, Raise:
+		if a == Bet && b.bet > 0 {
+			return fmt.Errorf("%w: cannot bet facing a bet of %d; raise instead", ErrInvalidAction, b.bet)
+		}
+		if a == Raise && b.bet == 0 {
+			return fmt.Errorf("%w: nothing to raise; bet instead", ErrInvalidAction)
+		}
+		if opts.MaxTo == 0 {
+			return fmt.Errorf("%w: cannot %s", ErrInvalidAction, a)
+		}
+		allIn := p.Bet + p.Stack
+		if (to < opts.MinTo || to > opts.MaxTo) && to != allIn {
+			return fmt.Errorf("%w: %s to %d; must be %d to %d", ErrInvalidAction, a, to, opts.MinTo, opts.MaxTo)
+		}
+		if to > opts.MaxTo {
+			return fmt.Errorf("%w: %s to %d; most is %d", ErrInvalidAction, a, to, opts.MaxTo)
+		}
+		if increase := to - b.bet; increase >= b.raise {
+			// A full raise reopens the betting to everyone.
+			b.raise = max(b.raise, increase)
+			if b.rules.Limit == FixedLimit {
+				b.raise = b.rules.MinBet
+			}
+			b.raises++
+			clear(b.acted)
+		}
+		b.bet = to
+		ev.Type = EventRaise
+		if a == Bet {
+			ev.Type = EventBet
+		}
+		ev.Amount = to - p.Bet
+	default:
+		return fmt.Errorf("%w: unknown action %d", ErrInvalidAction, a)
+	}
+	p.Stack -= ev.Amount
+	p.Bet += ev.Amount
+	ev.Total = p.Bet
+	ev.AllIn = p.AllIn()
+	b.acted[b.turn] = true
+	b.events = append(b.events, ev)
+	b.advance()
+	return nil
+}
+
+// TimeOut acts for the player in seat, who ran out of time: it checks if
+// they can, and folds otherwise.
+func (b *Betting) TimeOut(seat int) error {
+	opts, ok := b.Options()
+	if !ok {
+		return ErrRoundOver
+	}
+	if seat != opts.Seat {
+		return fmt.Errorf("%w: waiting for seat %d", ErrNotYourTurn, opts.Seat)
+	}
+	b.events = append(b.events, Event{Type: EventTimeout, Seat: seat})
+	return b.Act(seat, TimeoutAction(opts), 0)
+}
+
+// advance passes the turn to the next player who needs to act, or ends
+// the round if nobody does.
+func (b *Betting) advance() {
+	in := 0
+	for _, p := range b.players {
+		if !p.Folded {
+			in++
+		}
+	}
+	if in > 1 {
+		for i := 1; i <= len(b.players); i++ {
+			next := (b.turn + i) % len(b.players)
+			if b.needsAction(next) {
+				b.turn = next
+				b.events = append(b.events, Event{Type: EventTurn, Seat: b.players[next].Seat})
+				return
+			}
+		}
+	}
+	b.turn = -1
+	b.events = append(b.events, Event{Type: EventRoundOver, Seat: -1})
+}
+
+func (b *Betting) needsAction(i int) bool {
+	p := b.players[i]
+	if p.Folded || p.AllIn() {
+		return false
+	}
+	if p.Bet < b.bet {
+		return true
+	}
+	// A player who has matched the bet still gets to act if they haven't
+	// yet, unless nobody could answer a raise.
+	return !b.acted[i] && b.othersCanAct(i)
+}
+
+// othersCanAct reports whether anyone besides players[i] could still put
+// chips in.
+func (b *Betting) othersCanAct(i int) bool {
+	for j, p := range b.players {
+		if j != i && !p.Folded && !p.AllIn() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package table
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+var noLimit = BettingRules{Limit: NoLimit, MinBet: 100}
+
+func mustBetting(t *testing.T, rules BettingRules, players []Player, first int, pot int64) *Betting {
+	t.Helper()
+	b, err := NewBetting(rules, players, first, pot)
+	AssertThat(t, err, Nil())
+	return b
+}
+
+func act(t *testing.T, b *Betting, seat int, a Action, to int64) {
+	t.Helper()
+	AssertThat(t, b.Act(seat, a, to), Nil())
+}
+
+func options(t *testing.T, b *Betting) Options {
+	t.Helper()
+	opts, ok := b.Options()
+	AssertEq(t, ok, true)
+	return opts
+}
+
+func eventStrings(b *Betting) []string {
+	var s []string
+	for _, ev := range b.Events() {
+		s = append(s, ev.String())
+	}
+	return s
+}
+
+func TestBetting_Preflop(t *testing.T) {
+	b := mustBetting(t, noLimit, []Player{
+		{Seat: 1, Stack: 950, Bet: 50},
+		{Seat: 2, Stack: 900, Bet: 100},
+		{Seat: 3, Stack: 1000},
+	}, 2, 0)
+
+	ExpectEq(t, options(t, b), Options{Seat: 3, Call: 100, MinTo: 200, MaxTo: 1000})
+	act(t, b, 3, Raise, 300)
+	ExpectEq(t, options(t, b), Options{Seat: 1, Call: 250, MinTo: 500, MaxTo: 1000})
+	act(t, b, 1, Call, 0)
+	act(t, b, 2, Fold, 0)
+
+	ExpectEq(t, b.Over(), true)
+	ExpectEq(t, b.Pot(), int64(700))
+	ExpectThat(t, b.Players(), ElementsAre(
+		Player{Seat: 1, Stack: 700, Bet: 300},
+		Player{Seat: 2, Stack: 900, Bet: 100, Folded: true},
+		Player{Seat: 3, Stack: 700, Bet: 300},
+	))
+	ExpectThat(t, eventStrings(b), ElementsAre(
+		"seat 3: turn",
+		"seat 3: raise to 300",
+		"seat 1: turn",
+		"seat 1: call 250",
+		"seat 2: turn",
+		"seat 2: fold",
+		"round over",
+	))
+	ExpectThat(t, b.Act(3, Check, 0), ErrorIs(ErrRoundOver))
+	_, ok := b.Options()
+	ExpectEq(t, ok, false)
+}
+
+func TestBetting_BigBlindOption(t *testing.T) {
+	b := mustBetting(t, noLimit, []Player{
+		{Seat: 1, Stack: 950, Bet: 50},
+		{Seat: 2, Stack: 900, Bet: 100},
+	}, 0, 0)
+	act(t, b, 1, Call, 0)
+
+	// The big blind may check or raise even though their bet is matched.
+	ExpectEq(t, options(t, b), Options{Seat: 2, MinTo: 200, MaxTo: 1000})
+	act(t, b, 2, Check, 0)
+	ExpectEq(t, b.Over(), true)
+}
+
+func TestBetting_CheckAround(t *testing.T) {
+	b := mustBetting(t, noLimit, []Player{{Seat: 4, Stack: 500}, {Seat: 6, Stack: 500}}, 1, 200)
+	ExpectEq(t, options(t, b), Options{Seat: 6, MinTo: 100, MaxTo: 500})
+	act(t, b, 6, Check, 0)
+	act(t, b, 4, Check, 0)
+	ExpectEq(t, b.Over(), true)
+	ExpectEq(t, b.Pot(), int64(200))
+}
+
+func TestBetting_InvalidActions(t *testing.T) {
+	b := mustBetting(t, noLimit, []Player{{Seat: 1, Stack: 1000}, {Seat: 2, Stack: 1000}}, 0, 0)
+	ExpectThat(t, b.Act(2, Check, 0), ErrorIs(ErrNotYourTurn))
+	ExpectThat(t, b.Act(1, Call, 0), ErrorIs(ErrInvalidAction))
+	ExpectThat(t, b.Act(1, Raise, 200), ErrorIs(ErrInvalidAction))
+	ExpectThat(t, b.Act(1, Bet, 50), ErrorIs(ErrInvalidAction))
+	ExpectThat(t, b.Act(1, Bet, 1001), ErrorIs(ErrInvalidAction))
+	ExpectThat(t, b.Act(1, Action(42), 0), ErrorIs(ErrInvalidAction))
+	act(t, b, 1, Bet, 100)
+
+	ExpectThat(t, b.Act(2, Check, 0), ErrorIs(ErrInvalidAction))
+	ExpectThat(t, b.Act(2, Bet, 300), ErrorIs(ErrInvalidAction))
+	ExpectThat(t, b.Act(2, Raise, 150), ErrorIs(ErrInvalidAction))
+	act(t, b, 2, Raise, 200)
+	ExpectEq(t, b.Over(), false)
+
+	_, err := NewBetting(noLimit, []Player{{Seat: 1}}, 1, 0)
+	ExpectThat(t, err, ErrorIs(ErrInvalidAction))
+	_, err = NewBetting(BettingRules{}, []Player{{Seat: 1}}, 0, 0)
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+}
+
+func TestBetting_MinRaise(t *testing.T) {
+	b := mustBetting(t, noLimit, []Player{{Seat: 1, Stack: 5000}, {Seat: 2, Stack: 5000}, {Seat: 3, Stack: 5000}}, 0, 0)
+	act(t, b, 1, Bet, 300)
+	// A raise must be at least as large as the last one.
+	ExpectEq(t, options(t, b).MinTo, int64(600))
+	act(t, b, 2, Raise, 1000)
+	ExpectEq(t, options(t, b).MinTo, int64(1700))
+	act(t, b, 3, Raise, 1700)
+	ExpectEq(t, options(t, b), Options{Seat: 1, Call: 1400, MinTo: 2400, MaxTo: 5000})
+}
+
+func TestBetting_IncompleteRaise(t *testing.T) {
+	b := mustBetting(t, noLimit, []Player{
+		{Seat: 1, Stack: 1000},
+		{Seat: 2, Stack: 150},
+		{Seat: 3, Stack: 1000},
+	}, 0, 0)
+	act(t, b, 1, Bet, 100)
+
+	// Going all in for less than a full raise is allowed...
+	ExpectEq(t, options(t, b), Options{Seat: 2, Call: 100, MinTo: 150, MaxTo: 150})
+	act(t, b, 2, Raise, 150)
+	ExpectEq(t, b.Events()[len(b.Events())-2].AllIn, true)
+
+	// ...and players who haven't acted yet may still raise...
+	ExpectEq(t, options(t, b), Options{Seat: 3, Call: 150, MinTo: 250, MaxTo: 1000})
+	act(t, b, 3, Call, 0)
+
+	// ...but it doesn't reopen the betting to those who have.
+	ExpectEq(t, options(t, b), Options{Seat: 1, Call: 50})
+	ExpectThat(t, b.Act(1, Raise, 400), ErrorIs(ErrInvalidAction))
+	act(t, b, 1, Call, 0)
+	ExpectEq(t, b.Over(), true)
+	ExpectEq(t, b.Pot(), int64(450))
+}
+
+func TestBetting_FullAllInRaiseReopens(t *testing.T) {
+	b := mustBetting(t, noLimit, []Player{
+		{Seat: 1, Stack: 1000},
+		{Seat: 2, Stack: 200},
+		{Seat: 3, Stack: 1000},
+	}, 0, 0)
+	act(t, b, 1, Bet, 100)
+	act(t, b, 2, Raise, 200)
+	act(t, b, 3, Call, 0)
+	ExpectEq(t, options(t, b), Options{Seat: 1, Call: 100, MinTo: 300, MaxTo: 1000})
+}
+
+func TestBetting_AllIn(t *testing.T) {
+	b := mustBetting(t, noLimit, []Player{{Seat: 1, Stack: 500}, {Seat: 2, Stack: 800}}, 0, 0)
+	act(t, b, 1, Bet, 500)
+
+	// Nobody is left to answer a raise.
+	ExpectEq(t, options(t, b), Options{Seat: 2, Call: 500})
+	act(t, b, 2, Call, 0)
+	ExpectEq(t, b.Over(), true)
+
+	// A later round with everyone all in needs no action.
+	b = mustBetting(t, noLimit, []Player{{Seat: 1}, {Seat: 2, Stack: 300}}, 0, 1000)
+	ExpectEq(t, b.Over(), true)
+
+	// Nor does calling an all-in blind for less.
+	b = mustBetting(t, noLimit, []Player{{Seat: 1, Bet: 50}, {Seat: 2, Stack: 900, Bet: 100}}, 1, 0)
+	ExpectEq(t, b.Over(), true)
+}
+
+func TestBetting_PotLimit(t *testing.T) {
+	rules := BettingRules{Limit: PotLimit, MinBet: 100}
+	b := mustBetting(t, rules, []Player{
+		{Seat: 1, Stack: 950, Bet: 50},
+		{Seat: 2, Stack: 900, Bet: 100},
+		{Seat: 3, Stack: 5000},
+	}, 2, 0)
+	// Calling 100 makes the pot 250, so the most is a raise of 250 more.
+	ExpectEq(t, options(t, b), Options{Seat: 3, Call: 100, MinTo: 200, MaxTo: 350})
+	ExpectThat(t, b.Act(3, Raise, 400), ErrorIs(ErrInvalidAction))
+	act(t, b, 3, Raise, 350)
+	ExpectEq(t, options(t, b), Options{Seat: 1, Call: 300, MinTo: 600, MaxTo: 1000})
+
+	// With chips from earlier rounds.
+	b = mustBetting(t, rules, []Player{{Seat: 1, Stack: 5000}, {Seat: 2, Stack: 5000}}, 0, 600)
+	ExpectEq(t, options(t, b), Options{Seat: 1, MinTo: 100, MaxTo: 600})
+}
+
+func TestBetting_FixedLimit(t *testing.T) {
+	rules := BettingRules{Limit: FixedLimit, MinBet: 100, MaxRaises: 4}
+	b := mustBetting(t, rules, []Player{
+		{Seat: 1, Stack: 950, Bet: 50},
+		{Seat: 2, Stack: 900, Bet: 100},
+		{Seat: 3, Stack: 5000},
+	}, 2, 0)
+	ExpectEq(t, options(t, b), Options{Seat: 3, Call: 100, MinTo: 200, MaxTo: 200})
+	ExpectThat(t, b.Act(3, Raise, 300), ErrorIs(ErrInvalidAction))
+	act(t, b, 3, Raise, 200)
+	act(t, b, 1, Raise, 300)
+	act(t, b, 2, Raise, 400)
+
+	// The blind and three raises make the cap.
+	ExpectEq(t, options(t, b), Options{Seat: 3, Call: 200})
+	act(t, b, 3, Call, 0)
+	act(t, b, 1, Call, 0)
+	ExpectEq(t, b.Over(), true)
+	ExpectEq(t, b.Pot(), int64(1200))
+}
+
+func TestBetting_EveryoneFolds(t *testing.T) {
+	b := mustBetting(t, noLimit, []Player{{Seat: 1, Stack: 1000}, {Seat: 2, Stack: 1000}, {Seat: 3, Stack: 1000}}, 0, 0)
+	act(t, b, 1, Bet, 100)
+	act(t, b, 2, Fold, 0)
+	act(t, b, 3, Fold, 0)
+	ExpectEq(t, b.Over(), true)
+	ExpectEq(t, b.Events()[len(b.Events())-1].Type, EventRoundOver)
+}
@@ -0,0 +1,394 @@
+package table
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+var ErrNotEnoughPlayers = errors.New("not enough players to deal a hand")
+
+// BlindRules say who posts what at the start of each hand.
+type BlindRules struct {
+	// Blinds from smallest to largest, posted by the players clockwise from
+	// the button. Usually a small and a big blind.
+	Blinds []int64
+
+	// Posted dead by every player dealt in.
+	Ante int64
+
+	// See TableConfig.dead_button.
+	DeadButton bool
+
+	// Whether players who owe blinds wait for the big blind rather than
+	// posting what they owe.
+	WaitForBigBlind bool
+
+	// How many players in a row left of the big blind may straddle, each
+	// twice the last blind or straddle. See Blinds.utg_straddle.
+	Straddles int
+
+	// Whether the button may straddle. See Blinds.button_straddle.
+	ButtonStraddle bool
+}
+
+// BlindRulesFor returns the blind rules from a table's config.
+func BlindRulesFor(cfg *pb.TableConfig) (BlindRules, error) {
+	var blinds []int64
+	for _, b := range cfg.GetBlinds().GetBlindLevels() {
+		blinds = append(blinds, Chips(b))
+	}
+	return LevelBlindRules(cfg, blinds, Chips(cfg.GetBlinds().GetAnte()))
+}
+
+// LevelBlindRules returns the blind rules for a table whose blinds and ante
+// are set by something other than its config, like the level a
+// tournament is at. The rest of the rules come from the config.
+func LevelBlindRules(cfg *pb.TableConfig, blinds []int64, ante int64) (BlindRules, error) {
+	r := BlindRules{
+		Blinds:          slices.Clone(blinds),
+		Ante:            ante,
+		DeadButton:      cfg.GetDeadButton(),
+		WaitForBigBlind: cfg.GetMissedBlinds() == pb.TableConfig_WAIT,
+	}
+	if len(r.Blinds) == 0 {
+		return BlindRules{}, fmt.Errorf("%w: blinds are unset", ErrInvalidConfig)
+	}
+	if !slices.IsSorted(r.Blinds) || r.Blinds[0] <= 0 {
+		return BlindRules{}, fmt.Errorf("%w: blinds must be positive and given from smallest to largest", ErrInvalidConfig)
+	}
+	if r.Ante < 0 {
+		return BlindRules{}, fmt.Errorf("%w: ante must not be negative", ErrInvalidConfig)
+	}
+	switch b := cfg.GetBlinds(); b.WhichStraddle() {
+	case pb.Blinds_UtgStraddle_case:
+		r.Straddles = int(b.GetUtgStraddle())
+		if r.Straddles < 0 {
+			return BlindRules{}, fmt.Errorf("%w: straddles must not be negative", ErrInvalidConfig)
+		}
+	case pb.Blinds_ButtonStraddle_case:
+		r.ButtonStraddle = b.GetButtonStraddle()
+	case pb.Blinds_MississippiStraddle_case:
+		if b.GetMississippiStraddle() {
+			return BlindRules{}, fmt.Errorf("%w: Mississippi straddles are not supported", ErrInvalidConfig)
+		}
+	}
+	if (r.Straddles > 0 || r.ButtonStraddle) && cfg.GetBets() == pb.TableConfig_FIXED_LIMIT {
+		return BlindRules{}, fmt.Errorf("%w: straddles need a no limit or pot limit game", ErrInvalidConfig)
+	}
+	return r, nil
+}
+
+// Seat is a player sitting at the table between hands.
+type Seat struct {
+	Seat  int
+	Stack int64
+
+	SittingOut bool
+
+	// Blinds the player owes for missing them while sitting out. Players
+	// who just sat down owe a big blind.
+	OwesSmall, OwesBig bool
+
+	// Orbits the player has missed: times the big blind has passed them
+	// while they sat out. Zero once they are dealt in again.
+	OrbitsOut int
+
+	// Whether the player will straddle when the rules let them.
+	Straddle bool
+}
+
+func (s Seat) owes() bool {
+	return s.OwesSmall || s.OwesBig
+}
+
+// Rotation is where the button and blinds were in a hand. Under the dead
+// button rule these are positions, which need not have a player.
+type Rotation struct {
+	// -1 before the first hand.
+	Button int
+
+	// Seat of each blind, from smallest to largest.
+	Blinds []int
+}
+
+// NoRotation is the rotation before the first hand at a table.
+var NoRotation = Rotation{Button: -1}
+
+// Post is chips put in before the cards are dealt.
+type Post struct {
+	Seat   int
+	Amount int64
+
+	// Dead chips go to the pot rather than counting toward the player's
+	// bet.
+	Dead bool
+}
+
+// Deal is how a hand starts: where the button is, who is dealt in, and what
+// they post.
+type Deal struct {
+	Rotation Rotation
+
+	// Players dealt in, in seat order, after posting. Live posts are their
+	// bets for the first round.
+	Players []Player
+
+	// Dead chips posted.
+	Pot int64
+
+	// Seat of the last live blind or straddle left of the big blind, for
+	// FirstToAct, or -1 if none was posted.
+	LastBlind int
+
+	// The largest straddle posted, or zero if nobody straddled. It stands in
+	// for the big blind in the first betting round, so that raises must be
+	// at least this much.
+	Straddle int64
+
+	// Whether the button straddled, and so acts last in the first betting
+	// round. See ButtonStraddleOrder.
+	ButtonStraddled bool
+
+	Posts  []Post
+	Events []Event
+}
+
+// NextHand moves the button and blinds on from the last hand and works
+// out who is dealt into the next one. It returns the seats with what each
+// player owes updated: players sitting out who the blinds pass owe them,
+// and have missed another orbit if it was the big blind, and players who
+// post owe nothing more.
+//
+// With two players, the button posts the small blind and the other player
+// the big blind. A player who was the big blind when play goes heads-up
+// gets the button, so that nobody is the big blind twice in a row.
+//
+// Players who owe blinds and land on a blind post it and owe nothing more.
+//
+// Players who asked to straddle do so after the blinds are posted, if the
+// rules let them and they have the chips to cover it. Nobody straddles
+// heads-up.
+func NextHand(r BlindRules, last Rotation, seats []Seat) (Deal, []Seat, error) {
+	seats = slices.Clone(seats)
+	slices.SortFunc(seats, func(a, b Seat) int { return a.Seat - b.Seat })
+	bySeat := map[int]*Seat{}
+	for i := range seats {
+		bySeat[seats[i].Seat] = &seats[i]
+	}
+	// Players who may take the big blind, and players who may be dealt in
+	// anywhere else.
+	inPlay := func(s Seat) bool { return !s.SittingOut && s.Stack > 0 }
+	dealable := func(s Seat) bool { return inPlay(s) && !(r.WaitForBigBlind && s.owes()) }
+	next := func(from int, ok func(Seat) bool) int {
+		for i := range seats {
+			s := seats[(nextIndex(seats, from)+i)%len(seats)]
+			if ok(s) {
+				return s.Seat
+			}
+		}
+		return -1
+	}
+	playing := 0
+	for _, s := range seats {
+		if inPlay(s) {
+			playing++
+		}
+	}
+	if playing < 2 {
+		return Deal{}, nil, fmt.Errorf("%w: %d", ErrNotEnoughPlayers, playing)
+	}
+
+	n := len(r.Blinds)
+	rot := Rotation{Blinds: make([]int, n)}
+	lastBig := -1
+	if len(last.Blinds) == n {
+		lastBig = last.Blinds[n-1]
+	}
+	switch {
+	case playing == 2:
+		if s, ok := bySeat[lastBig]; ok && inPlay(*s) {
+			rot.Button = lastBig
+		} else {
+			rot.Button = next(last.Button, inPlay)
+		}
+		for i := range rot.Blinds {
+			rot.Blinds[i] = -1
+		}
+		rot.Blinds[n-1] = next(rot.Button, inPlay)
+		if n > 1 {
+			rot.Blinds[n-2] = rot.Button
+		}
+	case r.DeadButton && last.Button >= 0 && lastBig >= 0:
+		// Each position moves up one, and the big blind moves to the next
+		// player.
+		rot.Button = last.Blinds[0]
+		copy(rot.Blinds, last.Blinds[1:])
+		rot.Blinds[n-1] = next(lastBig, inPlay)
+	default:
+		// Nobody posts two blinds. If too few players are dealt in to post
+		// them all, the smaller blinds go unposted.
+		used := map[int]bool{}
+		unused := func(ok func(Seat) bool) func(Seat) bool {
+			return func(s Seat) bool { return ok(s) && !used[s.Seat] }
+		}
+		rot.Button = next(last.Button, dealable)
+		prev := rot.Button
+		for i := range n - 1 {
+			rot.Blinds[i] = next(prev, unused(dealable))
+			used[rot.Blinds[i]] = true
+			if rot.Blinds[i] >= 0 {
+				prev = rot.Blinds[i]
+			}
+		}
+		rot.Blinds[n-1] = next(prev, unused(inPlay))
+		if rot.Blinds[n-1] < 0 {
+			return Deal{}, nil, fmt.Errorf("%w: nobody can post the big blind", ErrNotEnoughPlayers)
+		}
+	}
+
+	// Players sitting out whom a blind passed, or who were skipped at its
+	// position, missed it.
+	if len(last.Blinds) == n {
+		for i, pos := range rot.Blinds {
+			from := last.Blinds[i]
+			if from < 0 || pos < 0 || from == pos {
+				continue
+			}
+			for _, s := range seats {
+				if inPlay(s) || !between(s.Seat, from, pos) {
+					continue
+				}
+				if i == n-1 {
+					bySeat[s.Seat].OwesBig = true
+					bySeat[s.Seat].OrbitsOut++
+				} else {
+					bySeat[s.Seat].OwesSmall = true
+				}
+			}
+		}
+	}
+
+	d := Deal{Rotation: rot, LastBlind: -1}
+	d.Events = append(d.Events, Event{Type: EventButton, Seat: rot.Button})
+	blindAt := map[int]int64{}
+	for i, seat := range rot.Blinds {
+		if s, ok := bySeat[seat]; ok && (inPlay(*s) && (playing == 2 || i == n-1 || dealable(*s))) {
+			blindAt[seat] = r.Blinds[i]
+		}
+	}
+	var dealt []*Seat
+	for i := range seats {
+		s := &seats[i]
+		if _, ok := blindAt[s.Seat]; ok || (inPlay(*s) && (playing == 2 || dealable(*s))) {
+			dealt = append(dealt, s)
+		}
+	}
+
+	stacks := map[int]int64{}
+	bets := map[int]int64{}
+	for _, s := range dealt {
+		stacks[s.Seat] = s.Stack
+	}
+	post := func(seat int, amount int64, dead bool, ev EventType) {
+		amount = min(amount, stacks[seat])
+		if amount <= 0 {
+			return
+		}
+		stacks[seat] -= amount
+		if dead {
+			d.Pot += amount
+		} else {
+			bets[seat] += amount
+		}
+		d.Posts = append(d.Posts, Post{Seat: seat, Amount: amount, Dead: dead})
+		d.Events = append(d.Events, Event{Type: ev, Seat: seat, Amount: amount, Total: bets[seat], AllIn: stacks[seat] == 0})
+	}
+	if r.Ante > 0 {
+		for _, s := range dealt {
+			post(s.Seat, r.Ante, true, EventAnte)
+		}
+	}
+	for _, seat := range rot.Blinds {
+		if amount, ok := blindAt[seat]; ok {
+			post(seat, amount, false, EventBlind)
+			d.LastBlind = seat
+			bySeat[seat].OwesSmall, bySeat[seat].OwesBig = false, false
+		}
+	}
+	if playing > 2 {
+		d.straddle(r, rot, dealt, blindAt, stacks, post)
+	}
+	for _, s := range dealt {
+		if _, ok := blindAt[s.Seat]; ok || !s.owes() {
+			continue
+		}
+		if s.OwesBig {
+			post(s.Seat, r.Blinds[n-1], false, EventBlind)
+		}
+		if s.OwesSmall && n > 1 {
+			post(s.Seat, r.Blinds[0], true, EventDeadBlind)
+		}
+		s.OwesSmall, s.OwesBig = false, false
+	}
+
+	for _, s := range dealt {
+		s.OrbitsOut = 0
+		d.Players = append(d.Players, Player{Seat: s.Seat, Stack: stacks[s.Seat], Bet: bets[s.Seat]})
+	}
+	return d, seats, nil
+}
+
+// straddle posts the straddles of the players dealt in who asked to:
+// consecutive players left of the big blind, or the button.
+func (d *Deal) straddle(r BlindRules, rot Rotation, dealt []*Seat, blindAt map[int]int64, stacks map[int]int64, post func(int, int64, bool, EventType)) {
+	amount := 2 * r.Blinds[len(r.Blinds)-1]
+	can := func(s *Seat) bool {
+		_, blind := blindAt[s.Seat]
+		return s.Straddle && !blind && !s.owes() && stacks[s.Seat] >= amount
+	}
+	if r.ButtonStraddle {
+		for _, s := range dealt {
+			if s.Seat == rot.Button && can(s) {
+				post(s.Seat, amount, false, EventStraddle)
+				d.Straddle, d.ButtonStraddled = amount, true
+			}
+		}
+		return
+	}
+	start := slices.IndexFunc(dealt, func(s *Seat) bool { return s.Seat > rot.Blinds[len(rot.Blinds)-1] })
+	if start < 0 {
+		start = 0
+	}
+	for i := range min(r.Straddles, len(dealt)) {
+		s := dealt[(start+i)%len(dealt)]
+		if !can(s) {
+			return
+		}
+		post(s.Seat, amount, false, EventStraddle)
+		d.Straddle, d.LastBlind = amount, s.Seat
+		amount *= 2
+	}
+}
+
+// nextIndex returns the index of the first seat after seat, wrapping
+// around.
+func nextIndex(seats []Seat, seat int) int {
+	for i, s := range seats {
+		if s.Seat > seat {
+			return i
+		}
+	}
+	return 0
+}
+
+// between reports whether seat is after from, up to and including to,
+// going clockwise.
+func between(seat, from, to int) bool {
+	if from < to {
+		return seat > from && seat <= to
+	}
+	return seat > from || seat <= to
+}
@@ -0,0 +1,281 @@
+package table
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+var blinds = BlindRules{Blinds: []int64{50, 100}}
+
+func nextHand(t *testing.T, r BlindRules, last Rotation, seats []Seat) (Deal, []Seat) {
+	t.Helper()
+	d, seats, err := NextHand(r, last, seats)
+	AssertThat(t, err, Nil())
+	return d, seats
+}
+
+func stacked(seats ...int) []Seat {
+	var s []Seat
+	for _, seat := range seats {
+		s = append(s, Seat{Seat: seat, Stack: 1000})
+	}
+	return s
+}
+
+func TestBlindRulesFor(t *testing.T) {
+	r, err := BlindRulesFor(tableConfig(t, `
+		blinds {
+			blind_levels { units: 1 }
+			blind_levels { units: 2 }
+			ante { nanos: 250000000 }
+		}
+		dead_button: true
+		missed_blinds: MISSED_BLINDS_WAIT
+	`))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, BlindRules{Blinds: []int64{100, 200}, Ante: 25, DeadButton: true, WaitForBigBlind: true})
+
+	r, err = BlindRulesFor(tableConfig(t, `blinds { blind_levels { units: 1 } utg_straddle: 2 }`))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.Straddles, 2)
+	r, err = BlindRulesFor(tableConfig(t, `blinds { blind_levels { units: 1 } button_straddle: true }`))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.ButtonStraddle, true)
+
+	for _, text := range []string{
+		``,
+		`blinds { blind_levels { units: 1 } mississippi_straddle: true }`,
+		`blinds { blind_levels { units: 1 } utg_straddle: 1 } bets: FIXED_LIMIT`,
+	} {
+		_, err = BlindRulesFor(tableConfig(t, text))
+		ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+	}
+	_, err = BlindRulesFor(tableConfig(t, `blinds { blind_levels { units: 2 } blind_levels { units: 1 } }`))
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+}
+
+func TestLevelBlindRules(t *testing.T) {
+	cfg := tableConfig(t, `blinds { blind_levels { units: 1 } utg_straddle: 1 } dead_button: true`)
+	r, err := LevelBlindRules(cfg, []int64{400, 800}, 100)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, BlindRules{Blinds: []int64{400, 800}, Ante: 100, DeadButton: true, Straddles: 1})
+
+	_, err = LevelBlindRules(cfg, nil, 0)
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+	_, err = LevelBlindRules(cfg, []int64{400, 800}, -1)
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+}
+
+func TestNextHand(t *testing.T) {
+	d, _ := nextHand(t, blinds, NoRotation, stacked(1, 3, 5, 8))
+	ExpectEq(t, d.Rotation, Rotation{Button: 1, Blinds: []int{3, 5}})
+	ExpectEq(t, d.LastBlind, 5)
+	ExpectThat(t, d.Players, ElementsAre(
+		Player{Seat: 1, Stack: 1000},
+		Player{Seat: 3, Stack: 950, Bet: 50},
+		Player{Seat: 5, Stack: 900, Bet: 100},
+		Player{Seat: 8, Stack: 1000},
+	))
+	var events []string
+	for _, ev := range d.Events {
+		events = append(events, ev.String())
+	}
+	ExpectThat(t, events, ElementsAre("seat 1: button", "seat 3: blind 50", "seat 5: blind 100"))
+
+	d, _ = nextHand(t, blinds, d.Rotation, stacked(1, 3, 5, 8))
+	ExpectEq(t, d.Rotation, Rotation{Button: 3, Blinds: []int{5, 8}})
+	d, _ = nextHand(t, blinds, d.Rotation, stacked(1, 3, 5, 8))
+	ExpectEq(t, d.Rotation, Rotation{Button: 5, Blinds: []int{8, 1}})
+
+	// The button skips seats that empty.
+	d, _ = nextHand(t, blinds, d.Rotation, stacked(1, 3, 5))
+	ExpectEq(t, d.Rotation, Rotation{Button: 1, Blinds: []int{3, 5}})
+}
+
+func TestNextHand_Ante(t *testing.T) {
+	r := BlindRules{Blinds: []int64{50, 100}, Ante: 10}
+	seats := stacked(1, 2, 3)
+	seats[1].Stack = 30
+	d, _ := nextHand(t, r, NoRotation, seats)
+	ExpectEq(t, d.Pot, int64(30))
+	ExpectThat(t, d.Players, ElementsAre(
+		Player{Seat: 1, Stack: 990},
+		// Blinds are capped by what the player has left.
+		Player{Seat: 2, Stack: 0, Bet: 20},
+		Player{Seat: 3, Stack: 890, Bet: 100},
+	))
+	ExpectThat(t, d.Posts, ElementsAre(
+		Post{Seat: 1, Amount: 10, Dead: true},
+		Post{Seat: 2, Amount: 10, Dead: true},
+		Post{Seat: 3, Amount: 10, Dead: true},
+		Post{Seat: 2, Amount: 20},
+		Post{Seat: 3, Amount: 100},
+	))
+	ExpectEq(t, d.Events[4].AllIn, true)
+}
+
+func TestNextHand_Straddle(t *testing.T) {
+	r := BlindRules{Blinds: []int64{50, 100}, Straddles: 2}
+	seats := stacked(1, 2, 3, 4, 5)
+	for i := range seats {
+		seats[i].Straddle = true
+	}
+	d, _ := nextHand(t, r, NoRotation, seats)
+	ExpectEq(t, d.Rotation, Rotation{Button: 1, Blinds: []int{2, 3}})
+	ExpectThat(t, d.Posts, ElementsAre(
+		Post{Seat: 2, Amount: 50},
+		Post{Seat: 3, Amount: 100},
+		Post{Seat: 4, Amount: 200},
+		Post{Seat: 5, Amount: 400},
+	))
+	ExpectEq(t, d.Events[4].String(), "seat 5: straddle 400")
+	ExpectEq(t, d.Straddle, int64(400))
+	ExpectEq(t, d.LastBlind, 5)
+
+	// Straddles stop at the first player who won't, or can't cover one.
+	seats[4].Stack = 300
+	d, _ = nextHand(t, r, NoRotation, seats)
+	ExpectThat(t, d.Posts, ElementsAre(
+		Post{Seat: 2, Amount: 50},
+		Post{Seat: 3, Amount: 100},
+		Post{Seat: 4, Amount: 200},
+	))
+	seats[3].Straddle = false
+	d, _ = nextHand(t, r, NoRotation, seats)
+	ExpectThat(t, d.Posts, Len(2))
+	ExpectEq(t, d.Straddle, int64(0))
+	ExpectEq(t, d.LastBlind, 3)
+
+	// Nobody straddles heads-up.
+	d, _ = nextHand(t, r, NoRotation, seats[:2])
+	ExpectThat(t, d.Posts, Len(2))
+}
+
+func TestNextHand_ButtonStraddle(t *testing.T) {
+	r := BlindRules{Blinds: []int64{50, 100}, ButtonStraddle: true}
+	seats := stacked(1, 2, 3, 4)
+	seats[0].Straddle = true
+	d, _ := nextHand(t, r, NoRotation, seats)
+	ExpectThat(t, d.Posts, ElementsAre(
+		Post{Seat: 2, Amount: 50},
+		Post{Seat: 3, Amount: 100},
+		Post{Seat: 1, Amount: 200},
+	))
+	ExpectEq(t, d.Straddle, int64(200))
+	ExpectEq(t, d.ButtonStraddled, true)
+
+	// Action still starts left of the big blind.
+	ExpectEq(t, d.LastBlind, 3)
+
+	// Only the button may straddle.
+	d, _ = nextHand(t, r, d.Rotation, seats)
+	ExpectThat(t, d.Posts, Len(2))
+	ExpectEq(t, d.ButtonStraddled, false)
+}
+
+func TestNextHand_HeadsUp(t *testing.T) {
+	// The button posts the small blind.
+	d, _ := nextHand(t, blinds, NoRotation, stacked(2, 6))
+	ExpectEq(t, d.Rotation, Rotation{Button: 2, Blinds: []int{2, 6}})
+	d, _ = nextHand(t, blinds, d.Rotation, stacked(2, 6))
+	ExpectEq(t, d.Rotation, Rotation{Button: 6, Blinds: []int{6, 2}})
+
+	// Going heads-up, the last big blind takes the button rather than
+	// paying the big blind again.
+	d, _ = nextHand(t, blinds, Rotation{Button: 1, Blinds: []int{2, 3}}, stacked(2, 3))
+	ExpectEq(t, d.Rotation, Rotation{Button: 3, Blinds: []int{3, 2}})
+
+	// And back again.
+	d, _ = nextHand(t, blinds, d.Rotation, stacked(1, 2, 3))
+	ExpectEq(t, d.Rotation, Rotation{Button: 1, Blinds: []int{2, 3}})
+}
+
+func TestNextHand_NotEnoughPlayers(t *testing.T) {
+	seats := stacked(1, 2, 3)
+	seats[0].SittingOut = true
+	seats[1].Stack = 0
+	_, _, err := NextHand(blinds, NoRotation, seats)
+	ExpectThat(t, err, ErrorIs(ErrNotEnoughPlayers))
+}
+
+func TestNextHand_MissedBlinds(t *testing.T) {
+	seats := stacked(1, 2, 3, 4)
+	seats[3].SittingOut = true
+	d, seats := nextHand(t, blinds, Rotation{Button: 1, Blinds: []int{2, 3}}, seats)
+	ExpectEq(t, d.Rotation, Rotation{Button: 2, Blinds: []int{3, 1}})
+	ExpectEq(t, seats[3], Seat{Seat: 4, Stack: 1000, SittingOut: true, OwesBig: true, OrbitsOut: 1})
+	ExpectThat(t, d.Players, Len(3))
+
+	// Sitting out longer, they miss the small blind too.
+	d, seats = nextHand(t, blinds, d.Rotation, seats)
+	ExpectEq(t, d.Rotation, Rotation{Button: 3, Blinds: []int{1, 2}})
+	ExpectEq(t, seats[3], Seat{Seat: 4, Stack: 1000, SittingOut: true, OwesSmall: true, OwesBig: true, OrbitsOut: 1})
+
+	// Coming back, they post the big blind live and the small blind dead.
+	seats[3].SittingOut = false
+	d, seats = nextHand(t, blinds, Rotation{Button: 2, Blinds: []int{3, 1}}, seats)
+	ExpectEq(t, d.Rotation, Rotation{Button: 3, Blinds: []int{4, 1}})
+	ExpectEq(t, seats[3], Seat{Seat: 4, Stack: 1000})
+}
+
+func TestNextHand_PostMissedBlinds(t *testing.T) {
+	seats := stacked(1, 2, 3, 4, 5)
+	seats[1].OwesSmall, seats[1].OwesBig = true, true
+	d, seats := nextHand(t, blinds, Rotation{Button: 1, Blinds: []int{2, 3}}, seats)
+	ExpectEq(t, d.Rotation, Rotation{Button: 2, Blinds: []int{3, 4}})
+	ExpectEq(t, d.Pot, int64(50))
+	ExpectThat(t, d.Players, ElementsAre(
+		Player{Seat: 1, Stack: 1000},
+		Player{Seat: 2, Stack: 850, Bet: 100},
+		Player{Seat: 3, Stack: 950, Bet: 50},
+		Player{Seat: 4, Stack: 900, Bet: 100},
+		Player{Seat: 5, Stack: 1000},
+	))
+	ExpectEq(t, d.LastBlind, 4)
+	ExpectEq(t, seats[1], Seat{Seat: 2, Stack: 850 + 150})
+}
+
+func TestNextHand_WaitForBigBlind(t *testing.T) {
+	r := BlindRules{Blinds: []int64{50, 100}, WaitForBigBlind: true}
+	seats := stacked(1, 2, 3, 4, 5)
+	seats[1].OwesBig = true
+
+	// They sit out until the big blind reaches them...
+	d, seats := nextHand(t, r, Rotation{Button: 1, Blinds: []int{2, 3}}, seats)
+	ExpectEq(t, d.Rotation, Rotation{Button: 3, Blinds: []int{4, 5}})
+	ExpectThat(t, d.Players, Len(4))
+	d, seats = nextHand(t, r, d.Rotation, seats)
+	ExpectEq(t, d.Rotation, Rotation{Button: 4, Blinds: []int{5, 1}})
+	ExpectThat(t, d.Players, Len(4))
+
+	// ...then post it as usual.
+	d, seats = nextHand(t, r, d.Rotation, seats)
+	ExpectEq(t, d.Rotation, Rotation{Button: 5, Blinds: []int{1, 2}})
+	ExpectThat(t, d.Players, Len(5))
+	ExpectEq(t, seats[1].OwesBig, false)
+}
+
+func TestNextHand_DeadButton(t *testing.T) {
+	r := BlindRules{Blinds: []int64{50, 100}, DeadButton: true}
+
+	// The small blind left, so the button is dead in their seat.
+	d, seats := nextHand(t, r, Rotation{Button: 1, Blinds: []int{2, 3}}, stacked(1, 3, 4, 5))
+	ExpectEq(t, d.Rotation, Rotation{Button: 2, Blinds: []int{3, 4}})
+	ExpectEq(t, d.Events[0].String(), "seat 2: button")
+	d, _ = nextHand(t, r, d.Rotation, seats)
+	ExpectEq(t, d.Rotation, Rotation{Button: 3, Blinds: []int{4, 5}})
+
+	// The big blind left, so nobody posts the small blind.
+	d, _ = nextHand(t, r, Rotation{Button: 1, Blinds: []int{2, 3}}, stacked(1, 2, 4, 5))
+	ExpectEq(t, d.Rotation, Rotation{Button: 2, Blinds: []int{3, 4}})
+	ExpectThat(t, d.Posts, ElementsAre(Post{Seat: 4, Amount: 100}))
+
+	// A player sitting out in the small blind misses it.
+	seats = stacked(1, 2, 3, 4, 5)
+	seats[2].SittingOut = true
+	d, seats = nextHand(t, r, Rotation{Button: 1, Blinds: []int{2, 3}}, seats)
+	ExpectEq(t, d.Rotation, Rotation{Button: 2, Blinds: []int{3, 4}})
+	ExpectThat(t, d.Posts, ElementsAre(Post{Seat: 4, Amount: 100}))
+	ExpectEq(t, seats[2].OwesSmall, true)
+}
@@ -0,0 +1,143 @@
+package table
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+var ErrBuyinNotAllowed = errors.New("buying chips not allowed")
+
+// BuyinRules limit the chips players bring to a cash table.
+type BuyinRules struct {
+	// Fewest chips a player may sit down with, and most they may have after
+	// buying more. Zero means no limit.
+	Min, Max int64
+
+	// Whether players may buy up to the largest stack at the table, even
+	// past Max.
+	TableMax bool
+}
+
+// BuyinRulesFor returns the buy-in rules from a table's config.
+func BuyinRulesFor(cfg *pb.TableConfig) (BuyinRules, error) {
+	b := cfg.GetBuyin()
+	r := BuyinRules{
+		Min:      Chips(b.GetMin()),
+		Max:      Chips(b.GetMax()),
+		TableMax: b.GetTableMax(),
+	}
+	if r.Min < 0 || r.Max < 0 {
+		return BuyinRules{}, fmt.Errorf("%w: buy-in limits must not be negative", ErrInvalidConfig)
+	}
+	if r.Max > 0 && r.Min > r.Max {
+		return BuyinRules{}, fmt.Errorf("%w: minimum buy-in is over the maximum", ErrInvalidConfig)
+	}
+	return r, nil
+}
+
+// TopUp checks that a player with stack chips may buy chips more, where the
+// largest stack at the table is largest. They must end up with at least Min
+// and at most Max, or the largest stack under TableMax.
+func (r BuyinRules) TopUp(stack, largest, chips int64) error {
+	if chips <= 0 {
+		return fmt.Errorf("%w: %d chips", ErrBuyinNotAllowed, chips)
+	}
+	after := stack + chips
+	if after < r.Min {
+		return fmt.Errorf("%w: %d chips is under the %d minimum", ErrBuyinNotAllowed, after, r.Min)
+	}
+	if most := r.most(largest); most > 0 && after > most {
+		return fmt.Errorf("%w: %d chips is over the %d maximum", ErrBuyinNotAllowed, after, most)
+	}
+	return nil
+}
+
+func (r BuyinRules) most(largest int64) int64 {
+	if r.TableMax && r.Max > 0 {
+		return max(r.Max, largest)
+	}
+	return r.Max
+}
+
+// RebuyRules say when tournament players may buy more chips.
+type RebuyRules struct {
+	// How long after the start players may rebuy. Zero means the table is
+	// not a tournament, and has no rebuys.
+	Period time.Duration
+
+	// Most rebuys per player. Zero means no limit.
+	MaxRebuys int
+
+	// What a rebuy costs, in money chips, and the tournament chips it adds.
+	Cost, Chips int64
+
+	// What the add-on costs and adds. Zero chips means there is no add-on.
+	AddOnCost, AddOnChips int64
+
+	// How long after rebuys end the add-on may be taken.
+	AddOnWindow time.Duration
+}
+
+// RebuyRulesFor returns the rebuy rules from a table's config. They are
+// zero if the table is not a tournament.
+func RebuyRulesFor(cfg *pb.TableConfig) (RebuyRules, error) {
+	if !cfg.GetBuyin().HasRebuys() {
+		return RebuyRules{}, nil
+	}
+	rb := cfg.GetBuyin().GetRebuys()
+	r := RebuyRules{
+		Period:      rb.GetPeriod().AsDuration(),
+		MaxRebuys:   int(rb.GetMaxRebuys()),
+		Cost:        Chips(rb.GetCost()),
+		Chips:       rb.GetChips(),
+		AddOnCost:   Chips(rb.GetAddOnCost()),
+		AddOnChips:  rb.GetAddOnChips(),
+		AddOnWindow: rb.GetAddOnWindow().AsDuration(),
+	}
+	switch {
+	case r.Period <= 0:
+		return RebuyRules{}, fmt.Errorf("%w: rebuys have no period", ErrInvalidConfig)
+	case r.Chips <= 0:
+		return RebuyRules{}, fmt.Errorf("%w: rebuys must add chips", ErrInvalidConfig)
+	case r.MaxRebuys < 0 || r.Cost < 0 || r.AddOnCost < 0 || r.AddOnChips < 0 || r.AddOnWindow < 0:
+		return RebuyRules{}, fmt.Errorf("%w: rebuy limits must not be negative", ErrInvalidConfig)
+	case r.AddOnChips > 0 && r.AddOnWindow == 0:
+		return RebuyRules{}, fmt.Errorf("%w: add-on has no window", ErrInvalidConfig)
+	}
+	return r, nil
+}
+
+// Rebuy checks that a player who has rebought rebuys times and has stack
+// chips may rebuy, elapsed after the tournament started.
+func (r RebuyRules) Rebuy(elapsed time.Duration, rebuys int, stack int64) error {
+	switch {
+	case r.Period == 0:
+		return fmt.Errorf("%w: no rebuys", ErrBuyinNotAllowed)
+	case elapsed >= r.Period:
+		return fmt.Errorf("%w: the rebuy period is over", ErrBuyinNotAllowed)
+	case r.MaxRebuys > 0 && rebuys >= r.MaxRebuys:
+		return fmt.Errorf("%w: already rebought %d times", ErrBuyinNotAllowed, rebuys)
+	case stack > r.Chips:
+		return fmt.Errorf("%w: %d chips is over the %d rebuy", ErrBuyinNotAllowed, stack, r.Chips)
+	}
+	return nil
+}
+
+// AddOn checks that a player may take the add-on elapsed after the
+// tournament started, given whether they already have.
+func (r RebuyRules) AddOn(elapsed time.Duration, taken bool) error {
+	switch {
+	case r.AddOnChips == 0:
+		return fmt.Errorf("%w: no add-on", ErrBuyinNotAllowed)
+	case taken:
+		return fmt.Errorf("%w: add-on already taken", ErrBuyinNotAllowed)
+	case elapsed < r.Period:
+		return fmt.Errorf("%w: rebuys have not ended", ErrBuyinNotAllowed)
+	case elapsed >= r.Period+r.AddOnWindow:
+		return fmt.Errorf("%w: the add-on window is over", ErrBuyinNotAllowed)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package table
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestBuyinRulesFor(t *testing.T) {
+	r, err := BuyinRulesFor(tableConfig(t, `buyin {
+		min { units: 40 }
+		max { units: 200 }
+		table_max: true
+	}`))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, BuyinRules{Min: 4000, Max: 20000, TableMax: true})
+
+	_, err = BuyinRulesFor(tableConfig(t, `buyin { min { units: 200 } max { units: 40 } }`))
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+}
+
+func TestTopUp(t *testing.T) {
+	r := BuyinRules{Min: 4000, Max: 20000}
+	for _, tc := range []struct {
+		name                  string
+		rules                 BuyinRules
+		stack, largest, chips int64
+		ok                    bool
+	}{
+		{"up to the max", r, 5000, 30000, 15000, true},
+		{"busted, at least the min", r, 0, 30000, 4000, true},
+		{"under the min", r, 1000, 30000, 2000, false},
+		{"over the max", r, 5000, 30000, 15001, false},
+		{"up to the table max", BuyinRules{Max: 20000, TableMax: true}, 5000, 30000, 25000, true},
+		{"over the table max", BuyinRules{Max: 20000, TableMax: true}, 5000, 30000, 25001, false},
+		{"no limit", BuyinRules{}, 5000, 0, 1_000_000, true},
+		{"nothing", r, 5000, 30000, 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rules.TopUp(tc.stack, tc.largest, tc.chips)
+			if tc.ok {
+				ExpectThat(t, err, Nil())
+			} else {
+				ExpectThat(t, err, ErrorIs(ErrBuyinNotAllowed))
+			}
+		})
+	}
+}
+
+func TestRebuyRulesFor(t *testing.T) {
+	r, err := RebuyRulesFor(tableConfig(t, ``))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, RebuyRules{})
+
+	r, err = RebuyRulesFor(tableConfig(t, `buyin { rebuys {
+		period { seconds: 3600 }
+		max_rebuys: 3
+		cost { units: 10 }
+		chips: 1500
+		add_on_cost { units: 10 }
+		add_on_chips: 3000
+		add_on_window { seconds: 600 }
+	} }`))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, RebuyRules{
+		Period:      time.Hour,
+		MaxRebuys:   3,
+		Cost:        1000,
+		Chips:       1500,
+		AddOnCost:   1000,
+		AddOnChips:  3000,
+		AddOnWindow: 10 * time.Minute,
+	})
+
+	for _, text := range []string{
+		`buyin { rebuys { chips: 1500 } }`,
+		`buyin { rebuys { period { seconds: 3600 } } }`,
+		`buyin { rebuys { period { seconds: 3600 } chips: 1500 add_on_chips: 3000 } }`,
+	} {
+		_, err := RebuyRulesFor(tableConfig(t, text))
+		ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+	}
+}
+
+func TestRebuy(t *testing.T) {
+	r := RebuyRules{Period: time.Hour, MaxRebuys: 2, Chips: 1500}
+	ExpectThat(t, r.Rebuy(time.Minute, 0, 0), Nil())
+	ExpectThat(t, r.Rebuy(time.Minute, 1, 1500), Nil())
+	ExpectThat(t, r.Rebuy(time.Minute, 1, 1501), ErrorIs(ErrBuyinNotAllowed))
+	ExpectThat(t, r.Rebuy(time.Minute, 2, 0), ErrorIs(ErrBuyinNotAllowed))
+	ExpectThat(t, r.Rebuy(time.Hour, 0, 0), ErrorIs(ErrBuyinNotAllowed))
+	ExpectThat(t, RebuyRules{}.Rebuy(0, 0, 0), ErrorIs(ErrBuyinNotAllowed))
+}
+
+func TestAddOn(t *testing.T) {
+	r := RebuyRules{Period: time.Hour, Chips: 1500, AddOnChips: 3000, AddOnWindow: 10 * time.Minute}
+	ExpectThat(t, r.AddOn(time.Hour, false), Nil())
+	ExpectThat(t, r.AddOn(time.Hour+5*time.Minute, false), Nil())
+	ExpectThat(t, r.AddOn(time.Hour, true), ErrorIs(ErrBuyinNotAllowed))
+	ExpectThat(t, r.AddOn(time.Minute, false), ErrorIs(ErrBuyinNotAllowed))
+	ExpectThat(t, r.AddOn(time.Hour+10*time.Minute, false), ErrorIs(ErrBuyinNotAllowed))
+	ExpectThat(t, RebuyRules{Period: time.Hour, Chips: 1500}.AddOn(time.Hour, false), ErrorIs(ErrBuyinNotAllowed))
+}
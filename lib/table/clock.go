@@ -0,0 +1,130 @@
+package table
+
+import (
+	"fmt"
+	"time"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+// ClockRules limit how long players may take to act.
+type ClockRules struct {
+	// Time to act on each turn. Zero means turns have no time limit.
+	Action time.Duration
+
+	// Extra time each player may draw on once their action time runs out.
+	// It is spent across the whole table, and does not refill.
+	TimeBank time.Duration
+}
+
+// Validate checks that r can be used to time turns.
+func (r ClockRules) Validate() error {
+	if r.Action < 0 || r.TimeBank < 0 {
+		return fmt.Errorf("%w: action time and time bank must not be negative", ErrInvalidConfig)
+	}
+	if r.Action == 0 && r.TimeBank > 0 {
+		return fmt.Errorf("%w: time bank without an action time", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// ClockRulesFor returns the clock rules for a table. They are zero, so
+// that turns have no time limit, if the table has no action clock.
+func ClockRulesFor(cfg *pb.TableConfig) (ClockRules, error) {
+	if !cfg.HasActionClock() {
+		return ClockRules{}, nil
+	}
+	c := cfg.GetActionClock()
+	r := ClockRules{
+		Action:   c.GetAction().AsDuration(),
+		TimeBank: c.GetTimeBank().AsDuration(),
+	}
+	if r.Action <= 0 {
+		return ClockRules{}, fmt.Errorf("%w: action clock has no action time", ErrInvalidConfig)
+	}
+	return r, r.Validate()
+}
+
+// Turn is the clock on one player's turn.
+type Turn struct {
+	Seat    int
+	Started time.Time
+
+	// When the player's action time runs out and they start drawing on
+	// their time bank. Zero if turns have no time limit.
+	BankStarts time.Time
+
+	// When the player times out. Zero if turns have no time limit.
+	Deadline time.Time
+
+	// What was left in the player's time bank when the turn started.
+	TimeBank time.Duration
+}
+
+// Clock times players' turns and keeps their time banks. Times are passed
+// in rather than read, so that a Clock can be replayed.
+type Clock struct {
+	rules ClockRules
+	spent map[int]time.Duration // seat -> time bank used
+}
+
+// NewClock returns a Clock where every player starts with a full time
+// bank.
+func NewClock(r ClockRules) (*Clock, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return &Clock{rules: r, spent: map[int]time.Duration{}}, nil
+}
+
+// Rules returns the rules c was created with.
+func (c *Clock) Rules() ClockRules {
+	return c.rules
+}
+
+// TimeBank returns what is left in a seat's time bank.
+func (c *Clock) TimeBank(seat int) time.Duration {
+	return c.rules.TimeBank - c.spent[seat]
+}
+
+// Start starts the clock on a seat's turn at now.
+func (c *Clock) Start(seat int, now time.Time) Turn {
+	t := Turn{Seat: seat, Started: now, TimeBank: c.TimeBank(seat)}
+	if c.rules.Action > 0 {
+		t.BankStarts = now.Add(c.rules.Action)
+		t.Deadline = t.BankStarts.Add(t.TimeBank)
+	}
+	return t
+}
+
+// Stop ends a turn at now, charging whatever the player took past their
+// action time to their time bank. It reports whether they ran out of time.
+func (c *Clock) Stop(t Turn, now time.Time) (timedOut bool) {
+	if t.BankStarts.IsZero() {
+		return false
+	}
+	used := min(max(now.Sub(t.BankStarts), 0), t.TimeBank)
+	c.spent[t.Seat] += used
+	return !now.Before(t.Deadline)
+}
+
+// Spend takes d from a seat's time bank, as replaying a turn that used it
+// would.
+func (c *Clock) Spend(seat int, d time.Duration) {
+	c.spent[seat] = min(c.spent[seat]+d, c.rules.TimeBank)
+}
+
+// Refill gives a seat a full time bank again, such as when a new player
+// takes it.
+func (c *Clock) Refill(seat int) {
+	delete(c.spent, seat)
+}
+
+// TimeoutAction is what the table does for a player who runs out of time:
+// check if they can, and fold otherwise.
+func TimeoutAction(o Options) Action {
+	if o.Call == 0 {
+		return Check
+	}
+	return Fold
+}
@@ -0,0 +1,101 @@
+package table
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+var start = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestClockRulesFor(t *testing.T) {
+	r, err := ClockRulesFor(tableConfig(t, `action_clock { action { seconds: 20 } time_bank { seconds: 60 } }`))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, ClockRules{Action: 20 * time.Second, TimeBank: time.Minute})
+
+	r, err = ClockRulesFor(tableConfig(t, ``))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, ClockRules{})
+
+	_, err = ClockRulesFor(tableConfig(t, `action_clock { time_bank { seconds: 60 } }`))
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+	_, err = ClockRulesFor(tableConfig(t, `action_clock { action { seconds: 20 } time_bank { seconds: -1 } }`))
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+}
+
+func TestClock(t *testing.T) {
+	c, err := NewClock(ClockRules{Action: 20 * time.Second, TimeBank: time.Minute})
+	AssertThat(t, err, Nil())
+
+	turn := c.Start(3, start)
+	ExpectEq(t, turn, Turn{
+		Seat:       3,
+		Started:    start,
+		BankStarts: start.Add(20 * time.Second),
+		Deadline:   start.Add(80 * time.Second),
+		TimeBank:   time.Minute,
+	})
+	// Acting within the action time leaves the bank alone.
+	ExpectEq(t, c.Stop(turn, start.Add(15*time.Second)), false)
+	ExpectEq(t, c.TimeBank(3), time.Minute)
+
+	turn = c.Start(3, start)
+	ExpectEq(t, c.Stop(turn, start.Add(45*time.Second)), false)
+	ExpectEq(t, c.TimeBank(3), 35*time.Second)
+	ExpectEq(t, c.TimeBank(4), time.Minute)
+
+	turn = c.Start(3, start)
+	ExpectEq(t, turn.Deadline, start.Add(55*time.Second))
+	ExpectEq(t, c.Stop(turn, start.Add(time.Minute)), true)
+	ExpectEq(t, c.TimeBank(3), time.Duration(0))
+
+	// With an empty bank, only the action time is left.
+	turn = c.Start(3, start)
+	ExpectEq(t, turn.Deadline, turn.BankStarts)
+
+	c.Refill(3)
+	ExpectEq(t, c.TimeBank(3), time.Minute)
+
+	c.Spend(3, 25*time.Second)
+	ExpectEq(t, c.TimeBank(3), 35*time.Second)
+	c.Spend(3, time.Hour)
+	ExpectEq(t, c.TimeBank(3), time.Duration(0))
+}
+
+func TestClock_NoLimit(t *testing.T) {
+	c, err := NewClock(ClockRules{})
+	AssertThat(t, err, Nil())
+	turn := c.Start(1, start)
+	ExpectEq(t, turn, Turn{Seat: 1, Started: start})
+	ExpectEq(t, c.Stop(turn, start.Add(time.Hour)), false)
+
+	_, err = NewClock(ClockRules{TimeBank: time.Minute})
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+}
+
+func TestBetting_TimeOut(t *testing.T) {
+	b := mustBetting(t, noLimit, []Player{
+		{Seat: 1, Stack: 950, Bet: 50},
+		{Seat: 2, Stack: 900, Bet: 100},
+		{Seat: 3, Stack: 1000},
+	}, 2, 0)
+
+	ExpectThat(t, b.TimeOut(1), ErrorIs(ErrNotYourTurn))
+	AssertThat(t, b.TimeOut(3), Nil())
+	act(t, b, 1, Call, 0)
+	AssertThat(t, b.TimeOut(2), Nil())
+
+	ExpectThat(t, eventStrings(b), ElementsAre(
+		"seat 3: turn",
+		"seat 3: timed out",
+		"seat 3: fold",
+		"seat 1: turn",
+		"seat 1: call 50",
+		"seat 2: turn",
+		"seat 2: timed out",
+		"seat 2: check",
+		"round over",
+	))
+	ExpectThat(t, b.TimeOut(2), ErrorIs(ErrRoundOver))
+}
@@ -0,0 +1,71 @@
+package table
+
+import (
+	"fmt"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+// DefaultMaxRaises caps the bets and raises in a fixed-limit round: a bet
+// and three raises.
+const DefaultMaxRaises = 4
+
+// BettingRules are the limits on one betting round.
+type BettingRules struct {
+	Limit Limit
+
+	// Smallest opening bet and raise. Under fixed limit, every bet and
+	// raise is exactly this much.
+	MinBet int64
+
+	// Most bets and raises in a fixed-limit round, counting a posted blind
+	// as the opening bet. Zero means no cap.
+	MaxRaises int
+}
+
+// Validate checks that r can be used to run a round.
+func (r BettingRules) Validate() error {
+	switch r.Limit {
+	case NoLimit, PotLimit, FixedLimit:
+	default:
+		return fmt.Errorf("%w: unknown betting structure %d", ErrInvalidConfig, r.Limit)
+	}
+	if r.MinBet <= 0 {
+		return fmt.Errorf("%w: minimum bet must be positive", ErrInvalidConfig)
+	}
+	if r.MaxRaises < 0 {
+		return fmt.Errorf("%w: raise cap must not be negative", ErrInvalidConfig)
+	}
+	return nil
+}
+
+var limits = map[pb.TableConfig_BettingStructure]Limit{
+	pb.TableConfig_NO_LIMIT:    NoLimit,
+	pb.TableConfig_POT_LIMIT:   PotLimit,
+	pb.TableConfig_FIXED_LIMIT: FixedLimit,
+}
+
+// RulesFor returns the rules for one of a table's betting rounds. The
+// minimum bet is the round's min_bet, or 1 if unset, times the largest
+// blind.
+func RulesFor(cfg *pb.TableConfig, round *pb.Phase_BettingRound) (BettingRules, error) {
+	limit, ok := limits[cfg.GetBets()]
+	if !ok {
+		return BettingRules{}, fmt.Errorf("%w: betting structure is unset", ErrInvalidConfig)
+	}
+	var bigBlind int64
+	for _, b := range cfg.GetBlinds().GetBlindLevels() {
+		bigBlind = max(bigBlind, Chips(b))
+	}
+	if bigBlind <= 0 {
+		return BettingRules{}, fmt.Errorf("%w: blinds are unset", ErrInvalidConfig)
+	}
+	r := BettingRules{
+		Limit:  limit,
+		MinBet: int64(max(round.GetMinBet(), 1)) * bigBlind,
+	}
+	if limit == FixedLimit {
+		r.MaxRaises = DefaultMaxRaises
+	}
+	return r, nil
+}
@@ -0,0 +1,54 @@
+package table
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/encoding/prototext"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+func tableConfig(t *testing.T, text string) *pb.TableConfig {
+	t.Helper()
+	cfg := &pb.TableConfig{}
+	AssertThat(t, prototext.Unmarshal([]byte(text), cfg), Nil())
+	return cfg
+}
+
+func TestChips(t *testing.T) {
+	cfg := tableConfig(t, `blinds { ante { units: 2 nanos: 500000000 } }`)
+	ExpectEq(t, Chips(cfg.GetBlinds().GetAnte()), int64(250))
+	ExpectEq(t, Chips(cfg.GetBlinds().GetBombPot()), int64(0))
+}
+
+func TestRulesFor(t *testing.T) {
+	cfg := tableConfig(t, `
+		bets: NO_LIMIT
+		blinds {
+			blind_levels { units: 1 }
+			blind_levels { units: 2 }
+		}
+	`)
+	r, err := RulesFor(cfg, &pb.Phase_BettingRound{})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, BettingRules{Limit: NoLimit, MinBet: 200})
+
+	cfg.SetBets(pb.TableConfig_FIXED_LIMIT)
+	round := tableConfig(t, `custom { phases { betting_round { min_bet: 2 } } }`).GetCustom().GetPhases()[0].GetBettingRound()
+	r, err = RulesFor(cfg, round)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, BettingRules{Limit: FixedLimit, MinBet: 400, MaxRaises: DefaultMaxRaises})
+
+	_, err = RulesFor(tableConfig(t, `blinds { blind_levels { units: 2 } }`), round)
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+	_, err = RulesFor(tableConfig(t, `bets: POT_LIMIT`), round)
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+}
+
+func TestBettingRulesValidate(t *testing.T) {
+	ExpectThat(t, BettingRules{Limit: PotLimit, MinBet: 1}.Validate(), Nil())
+	ExpectThat(t, BettingRules{MinBet: 1}.Validate(), ErrorIs(ErrInvalidConfig))
+	ExpectThat(t, BettingRules{Limit: NoLimit}.Validate(), ErrorIs(ErrInvalidConfig))
+	ExpectThat(t, BettingRules{Limit: FixedLimit, MinBet: 1, MaxRaises: -1}.Validate(), ErrorIs(ErrInvalidConfig))
+}
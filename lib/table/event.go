@@ -0,0 +1,117 @@
+package table
+
+import (
+	"fmt"
+
+	"github.com/jfmatt/snapfold/lib/handeval"
+)
+
+// EventType identifies what happened in an Event.
+type EventType int
+
+const (
+	// The button is at Seat for this hand. The seat may be empty under
+	// the dead button rule.
+	EventButton EventType = iota + 1
+
+	// Seat posted chips before the deal: a blind or a straddle, which
+	// count toward their bet, or a dead blind or an ante, which don't.
+	EventBlind
+	EventStraddle
+	EventDeadBlind
+	EventAnte
+
+	// It is Seat's turn to act.
+	EventTurn
+
+	// Seat ran out of time, and the table acted for them. The action
+	// follows.
+	EventTimeout
+
+	// Seat acted. Amount is what they put in, and Total their bet for the
+	// round after it.
+	EventFold
+	EventCheck
+	EventCall
+	EventBet
+	EventRaise
+
+	// The betting round ended.
+	EventRoundOver
+
+	// Cards were dealt to the board. When the board is run more than once,
+	// Run says which run they are for.
+	EventBoard
+
+	// Seat won Amount from a pot, on Run if the board was run more than
+	// once.
+	EventWin
+)
+
+var eventNames = map[EventType]string{
+	EventButton:    "button",
+	EventBlind:     "blind",
+	EventStraddle:  "straddle",
+	EventDeadBlind: "dead blind",
+	EventAnte:      "ante",
+	EventTurn:      "turn",
+	EventTimeout:   "timed out",
+	EventFold:      "fold",
+	EventCheck:     "check",
+	EventCall:      "call",
+	EventBet:       "bet",
+	EventRaise:     "raise",
+	EventRoundOver: "round over",
+	EventBoard:     "board",
+	EventWin:       "wins",
+}
+
+func (t EventType) String() string {
+	if name, ok := eventNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event is one step of a hand.
+type Event struct {
+	Type EventType
+
+	// The seat the event is about, or -1 if it isn't about one.
+	Seat int
+
+	Amount int64
+	Total  int64
+
+	// Whether the action left the player with no chips behind.
+	AllIn bool
+
+	// Cards dealt, for EventBoard.
+	Cards []handeval.Card
+
+	// Which run of the board the event is for, from 1, when the board is
+	// run more than once. Zero otherwise.
+	Run int
+}
+
+func (e Event) String() string {
+	s := e.Type.String()
+	if e.Seat >= 0 {
+		s = fmt.Sprintf("seat %d: %s", e.Seat, s)
+	}
+	switch e.Type {
+	case EventCall, EventBlind, EventStraddle, EventDeadBlind, EventAnte, EventWin:
+		s += fmt.Sprintf(" %d", e.Amount)
+	case EventBoard:
+		s += " " + handeval.FormatCards(e.Cards)
+	case EventBet, EventRaise:
+		s += fmt.Sprintf(" to %d", e.Total)
+	}
+	if e.AllIn {
+		s += " (all in)"
+	}
+	if e.Run > 0 {
+		s += fmt.Sprintf(" (run %d)", e.Run)
+	}
+	return s
+}
@@ -0,0 +1,127 @@
+package table
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/handeval"
+)
+
+// Position is where players sit relative to the button, for deciding who
+// acts first.
+type Position struct {
+	// Seats of the players in the round, clockwise.
+	Seats []int
+
+	Button int
+
+	// Seat that posted the last blind or straddle, or -1 if none was
+	// posted.
+	LastBlind int
+
+	// Each seat's face-up cards.
+	Showing map[int][]handeval.Card
+}
+
+// FirstToAct returns the index into p.Seats of the player who acts first in
+// a betting round.
+func FirstToAct(order pb.Phase_BettingRound_BettingOrder, p Position) (int, error) {
+	if len(p.Seats) == 0 {
+		return 0, fmt.Errorf("%w: no players", ErrInvalidAction)
+	}
+	switch order {
+	case pb.Phase_BettingRound_FOLLOW_BLINDS:
+		if p.LastBlind >= 0 {
+			return leftOf(p.Seats, p.LastBlind), nil
+		}
+		// With no blinds, as in a bomb pot, the deal starts left of the
+		// button.
+		return leftOf(p.Seats, p.Button), nil
+	case pb.Phase_BettingRound_LEFT_OF_DEALER:
+		return leftOf(p.Seats, p.Button), nil
+	case pb.Phase_BettingRound_BEST_FACEUP:
+		best := -1
+		start := leftOf(p.Seats, p.Button)
+		for k := range p.Seats {
+			i := (start + k) % len(p.Seats)
+			if best < 0 || compareShowing(p.Showing[p.Seats[i]], p.Showing[p.Seats[best]]) > 0 {
+				best = i
+			}
+		}
+		if len(p.Showing[p.Seats[best]]) == 0 {
+			return 0, fmt.Errorf("%w: no face-up cards to order by", ErrInvalidConfig)
+		}
+		return best, nil
+	}
+	return 0, fmt.Errorf("%w: unknown betting order %s", ErrInvalidConfig, order)
+}
+
+// ButtonStraddleOrder returns the players of the first betting round in the
+// order they act when the button straddled: clockwise from first, as
+// FirstToAct gives, but with the button after the blinds, last. Pass the
+// result to NewBetting with the first player at index 0.
+func ButtonStraddleOrder(players []Player, first, button int) []Player {
+	order := make([]Player, 0, len(players))
+	var last []Player
+	for i := range players {
+		p := players[(first+i)%len(players)]
+		if p.Seat == button {
+			last = append(last, p)
+			continue
+		}
+		order = append(order, p)
+	}
+	return append(order, last...)
+}
+
+// leftOf returns the index of the first seat clockwise after seat, which
+// need not be among seats.
+func leftOf(seats []int, seat int) int {
+	for i, s := range seats {
+		if s > seat {
+			return i
+		}
+	}
+	return 0
+}
+
+// compareShowing compares partial hands of face-up cards, as in stud, where
+// only pairs and their kin count: straights and flushes don't.
+func compareShowing(a, b []handeval.Card) int {
+	ga, gb := groups(a), groups(b)
+	for i := range min(len(ga), len(gb)) {
+		if c := cmp.Compare(ga[i].n, gb[i].n); c != 0 {
+			return c
+		}
+	}
+	for i := range min(len(ga), len(gb)) {
+		if c := cmp.Compare(ga[i].rank, gb[i].rank); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(ga), len(gb))
+}
+
+type group struct {
+	rank handeval.Rank
+	n    int
+}
+
+// groups returns the ranks among cards, most common first, then highest.
+func groups(cards []handeval.Card) []group {
+	var gs []group
+	for _, c := range cards {
+		i := slices.IndexFunc(gs, func(g group) bool { return g.rank == c.Rank })
+		if i < 0 {
+			gs = append(gs, group{rank: c.Rank})
+			i = len(gs) - 1
+		}
+		gs[i].n++
+	}
+	slices.SortFunc(gs, func(a, b group) int {
+		return cmp.Or(cmp.Compare(b.n, a.n), cmp.Compare(b.rank, a.rank))
+	})
+	return gs
+}
@@ -0,0 +1,93 @@
+package table
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/handeval"
+)
+
+func showing(t *testing.T, s string) []handeval.Card {
+	t.Helper()
+	c, err := handeval.ParseCards(s)
+	AssertThat(t, err, Nil())
+	return c
+}
+
+func TestFirstToAct(t *testing.T) {
+	p := Position{Seats: []int{1, 3, 4, 7}, Button: 3, LastBlind: 7}
+
+	got, err := FirstToAct(pb.Phase_BettingRound_FOLLOW_BLINDS, p)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got, 0)
+	got, err = FirstToAct(pb.Phase_BettingRound_LEFT_OF_DEALER, p)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got, 2)
+
+	// The button need not be in the hand.
+	p.Button = 5
+	got, err = FirstToAct(pb.Phase_BettingRound_LEFT_OF_DEALER, p)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got, 3)
+
+	p.LastBlind = -1
+	got, err = FirstToAct(pb.Phase_BettingRound_FOLLOW_BLINDS, p)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got, 3)
+
+	_, err = FirstToAct(pb.Phase_BettingRound_UNKNOWN, p)
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+	_, err = FirstToAct(pb.Phase_BettingRound_LEFT_OF_DEALER, Position{})
+	ExpectThat(t, err, ErrorIs(ErrInvalidAction))
+}
+
+func TestFirstToAct_BestFaceUp(t *testing.T) {
+	p := Position{Seats: []int{1, 3, 4, 7}, Button: 3, LastBlind: -1}
+	_, err := FirstToAct(pb.Phase_BettingRound_BEST_FACEUP, p)
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+
+	for _, tc := range []struct {
+		showing map[int]string
+		want    int
+	}{
+		{map[int]string{1: "Ah", 3: "Kd", 4: "2c", 7: "Qs"}, 0},
+		{map[int]string{1: "Ah Kh", 3: "2d 2c", 4: "Qc Js", 7: "Ts 9s"}, 1},
+		{map[int]string{1: "Ah Ad Kc", 3: "2d 2c 2h", 4: "Qc Js 7h", 7: "Ts 9s 8s"}, 1},
+		{map[int]string{1: "Ah Ad Kc Ks", 3: "2d 2c 2h 4s", 4: "Qc Js 7h 5d", 7: "Ts 9s 8s 7s"}, 1},
+		{map[int]string{1: "Ah Ad Kc Ks", 3: "Ac As Qc Qs", 4: "Qc Js 7h 5d", 7: "Ts 9s 8s 7s"}, 0},
+		// Ties go to the first player left of the button.
+		{map[int]string{1: "Ah Kd", 3: "Ac Ks", 4: "Ad Kc", 7: "Ts 9s"}, 2},
+	} {
+		p.Showing = map[int][]handeval.Card{}
+		for seat, s := range tc.showing {
+			p.Showing[seat] = showing(t, s)
+		}
+		got, err := FirstToAct(pb.Phase_BettingRound_BEST_FACEUP, p)
+		AssertThat(t, err, Nil())
+		ExpectEq(t, got, tc.want)
+	}
+}
+
+func TestButtonStraddleOrder(t *testing.T) {
+	players := []Player{
+		{Seat: 1, Stack: 800, Bet: 200},
+		{Seat: 2, Stack: 950, Bet: 50},
+		{Seat: 3, Stack: 900, Bet: 100},
+		{Seat: 4, Stack: 1000},
+		{Seat: 5, Stack: 1000},
+	}
+	first, err := FirstToAct(pb.Phase_BettingRound_FOLLOW_BLINDS, Position{Seats: []int{1, 2, 3, 4, 5}, Button: 1, LastBlind: 3})
+	AssertThat(t, err, Nil())
+	var seats []int
+	for _, p := range ButtonStraddleOrder(players, first, 1) {
+		seats = append(seats, p.Seat)
+	}
+	ExpectThat(t, seats, ElementsAre(4, 5, 2, 3, 1))
+
+	// The straddler gets the last word, and raises must be at least the
+	// straddle.
+	b := mustBetting(t, BettingRules{Limit: NoLimit, MinBet: 200}, ButtonStraddleOrder(players, first, 1), 0, 0)
+	ExpectEq(t, options(t, b), Options{Seat: 4, Call: 200, MinTo: 400, MaxTo: 1000})
+}
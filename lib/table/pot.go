@@ -0,0 +1,127 @@
+package table
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Contribution is what one player put in over a hand.
+type Contribution struct {
+	Seat   int
+	Amount int64
+	Folded bool
+}
+
+// Pot is the main pot or a side pot.
+type Pot struct {
+	Amount int64
+
+	// Seats that may win the pot, in increasing order.
+	Eligible []int
+}
+
+// Pots splits what the players in a hand put in into a main pot and side
+// pots, smallest stake first. A player is eligible for each pot up to the
+// amount they put in, unless they folded; folded players' chips still count
+// toward the pots they reached.
+//
+// Chips that nobody else matched are not in any pot. They are returned as
+// uncalled, which is zero if every chip was called. At least one player
+// must not have folded.
+func Pots(contribs []Contribution) (pots []Pot, uncalledSeat int, uncalled int64) {
+	byAmount := slices.Clone(contribs)
+	slices.SortStableFunc(byAmount, func(a, b Contribution) int {
+		return cmp.Compare(b.Amount, a.Amount)
+	})
+	uncalledSeat = -1
+	if len(byAmount) > 0 {
+		top := byAmount[0]
+		var next int64
+		if len(byAmount) > 1 {
+			next = byAmount[1].Amount
+		}
+		if top.Amount > next {
+			uncalledSeat, uncalled = top.Seat, top.Amount-next
+		}
+	}
+	amounts := map[int]int64{}
+	for _, c := range contribs {
+		amounts[c.Seat] = c.Amount
+		if c.Seat == uncalledSeat {
+			amounts[c.Seat] -= uncalled
+		}
+	}
+
+	// Each pot is capped at the stake of a player still in the hand.
+	var levels []int64
+	for _, c := range contribs {
+		if !c.Folded && amounts[c.Seat] > 0 {
+			levels = append(levels, amounts[c.Seat])
+		}
+	}
+	slices.Sort(levels)
+	levels = slices.Compact(levels)
+
+	var prev int64
+	for i, level := range levels {
+		var pot Pot
+		for _, c := range contribs {
+			have := amounts[c.Seat]
+			if i == len(levels)-1 {
+				// Folded players' chips above every remaining stake go
+				// in the last pot.
+				pot.Amount += max(have-prev, 0)
+			} else {
+				pot.Amount += max(min(have, level)-prev, 0)
+			}
+			if !c.Folded && have >= level {
+				pot.Eligible = append(pot.Eligible, c.Seat)
+			}
+		}
+		slices.Sort(pot.Eligible)
+		pots = append(pots, pot)
+		prev = level
+	}
+	return pots, uncalledSeat, uncalled
+}
+
+// Shares divides amount into n shares as evenly as it can, giving the
+// chips left over one each to the first shares. It is used to split a pot
+// between scorings, as in hi-lo, where the high hand gets the odd chip.
+func Shares(amount int64, n int) []int64 {
+	shares := make([]int64, n)
+	if n == 0 {
+		return shares
+	}
+	each, odd := amount/int64(n), amount%int64(n)
+	for i := range shares {
+		shares[i] = each
+		if int64(i) < odd {
+			shares[i]++
+		}
+	}
+	return shares
+}
+
+// Split divides amount among the seats that tied for it. Chips that don't
+// divide evenly go one each to the winners closest clockwise from the
+// button.
+func Split(amount int64, winners []int, button int) map[int]int64 {
+	order := slices.Clone(winners)
+	slices.SortFunc(order, func(a, b int) int {
+		// Seats after the button come first, then those at or before it.
+		return cmp.Or(cmp.Compare(wraps(a, button), wraps(b, button)), cmp.Compare(a, b))
+	})
+	won := map[int]int64{}
+	for i, share := range Shares(amount, len(order)) {
+		won[order[i]] += share
+	}
+	return won
+}
+
+func wraps(seat, button int) int {
+	if seat <= button {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,144 @@
+package table
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestPots(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		contribs     []Contribution
+		want         []Pot
+		uncalledSeat int
+		uncalled     int64
+	}{
+		{
+			name:         "heads up, short stack all in",
+			contribs:     []Contribution{{Seat: 1, Amount: 1000}, {Seat: 2, Amount: 300}},
+			want:         []Pot{{600, []int{1, 2}}},
+			uncalledSeat: 1, uncalled: 700,
+		},
+		{
+			name:         "everyone folds to a bet",
+			contribs:     []Contribution{{Seat: 1, Amount: 300}, {Seat: 2, Amount: 100, Folded: true}, {Seat: 3, Amount: 100, Folded: true}},
+			want:         []Pot{{300, []int{1}}},
+			uncalledSeat: 1, uncalled: 200,
+		},
+		{
+			name: "three all in, two covering",
+			contribs: []Contribution{
+				{Seat: 1, Amount: 100},
+				{Seat: 2, Amount: 300},
+				{Seat: 3, Amount: 500},
+				{Seat: 4, Amount: 500},
+			},
+			want: []Pot{
+				{400, []int{1, 2, 3, 4}},
+				{600, []int{2, 3, 4}},
+				{400, []int{3, 4}},
+			},
+			uncalledSeat: -1,
+		},
+		{
+			name: "four all in for different amounts",
+			contribs: []Contribution{
+				{Seat: 6, Amount: 50},
+				{Seat: 2, Amount: 800},
+				{Seat: 9, Amount: 250},
+				{Seat: 4, Amount: 600},
+			},
+			want: []Pot{
+				{200, []int{2, 4, 6, 9}},
+				{600, []int{2, 4, 9}},
+				{700, []int{2, 4}},
+			},
+			uncalledSeat: 2, uncalled: 200,
+		},
+		{
+			name: "equal all-ins share a pot",
+			contribs: []Contribution{
+				{Seat: 1, Amount: 200},
+				{Seat: 2, Amount: 200},
+				{Seat: 3, Amount: 500},
+				{Seat: 4, Amount: 500},
+			},
+			want: []Pot{
+				{800, []int{1, 2, 3, 4}},
+				{600, []int{3, 4}},
+			},
+			uncalledSeat: -1,
+		},
+		{
+			name: "folded player's chips count toward the pots they reached",
+			contribs: []Contribution{
+				{Seat: 1, Amount: 100},
+				{Seat: 2, Amount: 300},
+				{Seat: 3, Amount: 500},
+				{Seat: 4, Amount: 200, Folded: true},
+			},
+			want: []Pot{
+				{400, []int{1, 2, 3}},
+				{500, []int{2, 3}},
+			},
+			uncalledSeat: 3, uncalled: 200,
+		},
+		{
+			name: "folded chips above every remaining stake",
+			contribs: []Contribution{
+				{Seat: 1, Amount: 400, Folded: true},
+				{Seat: 2, Amount: 400, Folded: true},
+				{Seat: 3, Amount: 100},
+				{Seat: 4, Amount: 300},
+			},
+			want: []Pot{
+				{400, []int{3, 4}},
+				{800, []int{4}},
+			},
+			uncalledSeat: -1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pots, seat, uncalled := Pots(tc.contribs)
+			ExpectEq(t, pots, tc.want)
+			ExpectEq(t, seat, tc.uncalledSeat)
+			ExpectEq(t, uncalled, tc.uncalled)
+
+			// Every chip is accounted for.
+			var in, out int64
+			for _, c := range tc.contribs {
+				in += c.Amount
+			}
+			for _, p := range pots {
+				out += p.Amount
+			}
+			ExpectEq(t, out+uncalled, in)
+		})
+	}
+}
+
+func TestShares(t *testing.T) {
+	ExpectThat(t, Shares(7, 2), ElementsAre(int64(4), int64(3)))
+	ExpectThat(t, Shares(10, 3), ElementsAre(int64(4), int64(3), int64(3)))
+	ExpectThat(t, Shares(2, 3), ElementsAre(int64(1), int64(1), int64(0)))
+	ExpectThat(t, Shares(5, 0), Empty())
+}
+
+func TestSplit(t *testing.T) {
+	for _, tc := range []struct {
+		amount  int64
+		winners []int
+		button  int
+		want    map[int]int64
+	}{
+		{100, []int{4}, 0, map[int]int64{4: 100}},
+		{101, []int{2, 5}, 3, map[int]int64{5: 51, 2: 50}},
+		{101, []int{2, 5}, 1, map[int]int64{2: 51, 5: 50}},
+		{101, []int{2, 5}, 5, map[int]int64{2: 51, 5: 50}},
+		{100, []int{1, 2, 3}, 0, map[int]int64{1: 34, 2: 33, 3: 33}},
+		{101, []int{8, 1, 3}, 2, map[int]int64{3: 34, 8: 34, 1: 33}},
+	} {
+		ExpectEq(t, Split(tc.amount, tc.winners, tc.button), tc.want)
+	}
+}
@@ -0,0 +1,59 @@
+package table
+
+import (
+	"fmt"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+// RakeRules say how much of each hand the house takes.
+type RakeRules struct {
+	// Share of each pot taken, in hundredths of a percent.
+	BasisPoints int64
+
+	// Most taken from one hand. Zero means no cap.
+	Cap int64
+
+	// Whether hands that end before the flop are not raked.
+	NoFlopNoDrop bool
+}
+
+// RakeRulesFor returns the rake rules from a table's config. Tables
+// without a rake take none.
+func RakeRulesFor(cfg *pb.TableConfig) (RakeRules, error) {
+	rake := cfg.GetRake()
+	r := RakeRules{
+		BasisPoints:  int64(rake.GetBasisPoints()),
+		Cap:          Chips(rake.GetCap()),
+		NoFlopNoDrop: rake.GetNoFlopNoDrop(),
+	}
+	if r.BasisPoints < 0 || r.BasisPoints > 10000 {
+		return RakeRules{}, fmt.Errorf("%w: rake must be between 0 and 10000 basis points", ErrInvalidConfig)
+	}
+	if r.Cap < 0 {
+		return RakeRules{}, fmt.Errorf("%w: rake cap must not be negative", ErrInvalidConfig)
+	}
+	return r, nil
+}
+
+// Take takes the rake from a hand's pots before they are awarded, the main
+// pot first, and returns what is left of them and how much was taken.
+// flopped is whether the hand reached the flop. Uncalled chips, which are
+// in no pot, are never raked.
+func (r RakeRules) Take(pots []Pot, flopped bool) ([]Pot, int64) {
+	left := make([]Pot, len(pots))
+	copy(left, pots)
+	if r.BasisPoints == 0 || (r.NoFlopNoDrop && !flopped) {
+		return left, 0
+	}
+	var taken int64
+	for i := range left {
+		rake := left[i].Amount * r.BasisPoints / 10000
+		if r.Cap > 0 {
+			rake = min(rake, r.Cap-taken)
+		}
+		left[i].Amount -= rake
+		taken += rake
+	}
+	return left, taken
+}
@@ -0,0 +1,60 @@
+package table
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestRakeRulesFor(t *testing.T) {
+	r, err := RakeRulesFor(tableConfig(t, ``))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, RakeRules{})
+
+	r, err = RakeRulesFor(tableConfig(t, `rake {
+		basis_points: 500
+		cap { units: 3 }
+		no_flop_no_drop: true
+	}`))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, RakeRules{BasisPoints: 500, Cap: 300, NoFlopNoDrop: true})
+
+	for _, text := range []string{
+		`rake { basis_points: -1 }`,
+		`rake { basis_points: 10001 }`,
+		`rake { basis_points: 500 cap { units: -3 } }`,
+	} {
+		_, err := RakeRulesFor(tableConfig(t, text))
+		ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+	}
+}
+
+func TestTake(t *testing.T) {
+	pots := []Pot{{Amount: 3000, Eligible: []int{1, 2, 3}}, {Amount: 1010, Eligible: []int{2, 3}}}
+	for _, tc := range []struct {
+		name    string
+		rules   RakeRules
+		flopped bool
+		want    []int64
+		taken   int64
+	}{
+		{"no rake", RakeRules{}, true, []int64{3000, 1010}, 0},
+		{"five percent", RakeRules{BasisPoints: 500}, true, []int64{2850, 960}, 200},
+		{"capped", RakeRules{BasisPoints: 500, Cap: 170}, true, []int64{2850, 990}, 170},
+		{"cap met by the main pot", RakeRules{BasisPoints: 500, Cap: 100}, true, []int64{2900, 1010}, 100},
+		{"no flop, no drop", RakeRules{BasisPoints: 500, NoFlopNoDrop: true}, false, []int64{3000, 1010}, 0},
+		{"no flop, drop", RakeRules{BasisPoints: 500}, false, []int64{2850, 960}, 200},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			left, taken := tc.rules.Take(pots, tc.flopped)
+			var amounts []int64
+			for _, p := range left {
+				amounts = append(amounts, p.Amount)
+			}
+			ExpectEq(t, amounts, tc.want)
+			ExpectEq(t, taken, tc.taken)
+		})
+	}
+	// The pots given are left alone.
+	ExpectEq(t, pots[0].Amount, int64(3000))
+}
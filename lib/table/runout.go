@@ -0,0 +1,152 @@
+package table
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/handeval"
+)
+
+var (
+	ErrNotEnoughCards  = errors.New("not enough cards left in the deck")
+	ErrInvalidShowdown = errors.New("invalid showdown")
+)
+
+// RunoutRules say how the rest of the board is dealt once nobody can bet.
+type RunoutRules struct {
+	// Whether the board may be run twice. See TableConfig.run_it_twice.
+	RunItTwice bool
+}
+
+// RunoutRulesFor returns the runout rules from a table's config.
+func RunoutRulesFor(cfg *pb.TableConfig) RunoutRules {
+	return RunoutRules{RunItTwice: cfg.GetRunItTwice()}
+}
+
+// Runs returns how many times to deal the rest of the board, of which left
+// cards are still to come, once the betting is over. It is twice if the
+// rules allow it, the players still in the hand are all in but at most one,
+// and every one of them agreed to in agreed, which is keyed by seat.
+// Otherwise it is once.
+func (r RunoutRules) Runs(players []Player, agreed map[int]bool, left int) int {
+	if !r.RunItTwice || left <= 0 {
+		return 1
+	}
+	in, behind := 0, 0
+	for _, p := range players {
+		if p.Folded {
+			continue
+		}
+		if !agreed[p.Seat] {
+			return 1
+		}
+		in++
+		if !p.AllIn() {
+			behind++
+		}
+	}
+	if in < 2 || behind > 1 {
+		return 1
+	}
+	return 2
+}
+
+// Runout is the rest of the board, dealt one or more times.
+type Runout struct {
+	// Each run's whole board, including the cards dealt before the runout.
+	Boards [][]handeval.Card
+
+	// An EventBoard for the cards dealt on each run.
+	Events []Event
+}
+
+// DealRunout deals the board up to size cards once for each of runs runs,
+// each run in full before the next, from the top of deck. It returns the
+// cards left in the deck.
+func DealRunout(deck, board []handeval.Card, size, runs int) (Runout, []handeval.Card, error) {
+	if runs < 1 {
+		return Runout{}, nil, fmt.Errorf("%w: %d runs", ErrInvalidShowdown, runs)
+	}
+	left := max(size-len(board), 0)
+	if len(deck) < left*runs {
+		return Runout{}, nil, fmt.Errorf("%w: %d cards for %d runs of %d", ErrNotEnoughCards, len(deck), runs, left)
+	}
+	var ro Runout
+	for i := range runs {
+		dealt := slices.Clone(deck[:left])
+		deck = deck[left:]
+		ro.Boards = append(ro.Boards, slices.Concat(board, dealt))
+		ev := Event{Type: EventBoard, Seat: -1, Cards: dealt}
+		if runs > 1 {
+			ev.Run = i + 1
+		}
+		if left > 0 {
+			ro.Events = append(ro.Events, ev)
+		}
+	}
+	return ro, deck, nil
+}
+
+// AwardRuns divides each pot evenly between the runs of the board, the odd
+// chip going to the earlier runs, and each run's share between the seats
+// that tied for it on that run, as Split does. winners[run][pot] are the
+// seats that won pots[pot] on that run; each must be eligible for it.
+//
+// It returns an EventWin for what each seat won on each run, in run and
+// then seat order. With one run, the events have no Run.
+func AwardRuns(pots []Pot, winners [][][]int, button int) ([]Event, error) {
+	if len(winners) == 0 {
+		return nil, fmt.Errorf("%w: no runs", ErrInvalidShowdown)
+	}
+	won := make([]map[int]int64, len(winners))
+	for run, potWinners := range winners {
+		if len(potWinners) != len(pots) {
+			return nil, fmt.Errorf("%w: run %d has winners for %d of %d pots", ErrInvalidShowdown, run+1, len(potWinners), len(pots))
+		}
+		won[run] = map[int]int64{}
+	}
+	for i, pot := range pots {
+		shares := Shares(pot.Amount, len(winners))
+		for run, potWinners := range winners {
+			seats := potWinners[i]
+			if len(seats) == 0 {
+				return nil, fmt.Errorf("%w: nobody won pot %d on run %d", ErrInvalidShowdown, i+1, run+1)
+			}
+			for _, seat := range seats {
+				if !slices.Contains(pot.Eligible, seat) {
+					return nil, fmt.Errorf("%w: seat %d is not eligible for pot %d", ErrInvalidShowdown, seat, i+1)
+				}
+			}
+			for seat, amount := range Split(shares[run], seats, button) {
+				won[run][seat] += amount
+			}
+		}
+	}
+	var events []Event
+	for run, bySeat := range won {
+		for _, seat := range slices.Sorted(maps.Keys(bySeat)) {
+			ev := Event{Type: EventWin, Seat: seat, Amount: bySeat[seat]}
+			if len(winners) > 1 {
+				ev.Run = run + 1
+			}
+			if ev.Amount > 0 {
+				events = append(events, ev)
+			}
+		}
+	}
+	return events, nil
+}
+
+// Winnings totals what each seat won in a hand's events.
+func Winnings(events []Event) map[int]int64 {
+	won := map[int]int64{}
+	for _, e := range events {
+		if e.Type == EventWin {
+			won[e.Seat] += e.Amount
+		}
+	}
+	return won
+}
@@ -0,0 +1,127 @@
+package table
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/handeval"
+)
+
+func cards(t *testing.T, s string) []handeval.Card {
+	t.Helper()
+	c, err := handeval.ParseCards(s)
+	AssertThat(t, err, Nil())
+	return c
+}
+
+func TestRunoutRulesFor(t *testing.T) {
+	ExpectEq(t, RunoutRulesFor(tableConfig(t, ``)), RunoutRules{})
+	ExpectEq(t, RunoutRulesFor(tableConfig(t, `run_it_twice: true`)), RunoutRules{RunItTwice: true})
+}
+
+func TestRuns(t *testing.T) {
+	allIn := func(seat int) Player { return Player{Seat: seat} }
+	behind := func(seat int) Player { return Player{Seat: seat, Stack: 500} }
+	folded := func(seat int) Player { return Player{Seat: seat, Stack: 500, Folded: true} }
+	everyone := map[int]bool{1: true, 2: true, 3: true}
+	twice := RunoutRules{RunItTwice: true}
+
+	for _, tc := range []struct {
+		name    string
+		rules   RunoutRules
+		players []Player
+		agreed  map[int]bool
+		left    int
+		want    int
+	}{
+		{"all in, all agreed", twice, []Player{allIn(1), allIn(2)}, everyone, 2, 2},
+		{"one covers the rest", twice, []Player{allIn(1), behind(2), allIn(3)}, everyone, 5, 2},
+		{"folded players don't count", twice, []Player{allIn(1), allIn(2), folded(3)}, map[int]bool{1: true, 2: true}, 2, 2},
+		{"not allowed", RunoutRules{}, []Player{allIn(1), allIn(2)}, everyone, 2, 1},
+		{"one declined", twice, []Player{allIn(1), allIn(2)}, map[int]bool{1: true}, 2, 1},
+		{"two with chips behind", twice, []Player{allIn(1), behind(2), behind(3)}, everyone, 2, 1},
+		{"everyone else folded", twice, []Player{allIn(1), folded(2)}, everyone, 2, 1},
+		{"board complete", twice, []Player{allIn(1), allIn(2)}, everyone, 0, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ExpectEq(t, tc.rules.Runs(tc.players, tc.agreed, tc.left), tc.want)
+		})
+	}
+}
+
+func TestDealRunout(t *testing.T) {
+	deck := cards(t, "2c 3c 4c 5c 6c 7c")
+	board := cards(t, "Ah Kh Qh")
+
+	ro, rest, err := DealRunout(deck, board, 5, 2)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, ro.Boards, [][]handeval.Card{
+		cards(t, "Ah Kh Qh 2c 3c"),
+		cards(t, "Ah Kh Qh 4c 5c"),
+	})
+	ExpectEq(t, ro.Events, []Event{
+		{Type: EventBoard, Seat: -1, Cards: cards(t, "2c 3c"), Run: 1},
+		{Type: EventBoard, Seat: -1, Cards: cards(t, "4c 5c"), Run: 2},
+	})
+	ExpectEq(t, rest, cards(t, "6c 7c"))
+	ExpectEq(t, ro.Events[1].String(), "board 4c 5c (run 2)")
+}
+
+func TestDealRunout_Once(t *testing.T) {
+	ro, rest, err := DealRunout(cards(t, "2c 3c"), cards(t, "Ah Kh Qh Jh"), 5, 1)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, ro.Boards, [][]handeval.Card{cards(t, "Ah Kh Qh Jh 2c")})
+	ExpectEq(t, ro.Events, []Event{{Type: EventBoard, Seat: -1, Cards: cards(t, "2c")}})
+	ExpectEq(t, rest, cards(t, "3c"))
+}
+
+func TestDealRunout_NotEnoughCards(t *testing.T) {
+	_, _, err := DealRunout(cards(t, "2c 3c 4c"), cards(t, "Ah Kh Qh"), 5, 2)
+	ExpectThat(t, err, ErrorIs(ErrNotEnoughCards))
+}
+
+func TestAwardRuns(t *testing.T) {
+	pots := []Pot{
+		{301, []int{1, 2, 3}},
+		{400, []int{2, 3}},
+	}
+	events, err := AwardRuns(pots, [][][]int{
+		{{1}, {3}},
+		{{2, 3}, {2}},
+	}, 3)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, events, []Event{
+		// The first run gets the odd chip of the main pot.
+		{Type: EventWin, Seat: 1, Amount: 151, Run: 1},
+		{Type: EventWin, Seat: 3, Amount: 200, Run: 1},
+		{Type: EventWin, Seat: 2, Amount: 75 + 200, Run: 2},
+		{Type: EventWin, Seat: 3, Amount: 75, Run: 2},
+	})
+	ExpectEq(t, Winnings(events), map[int]int64{1: 151, 2: 275, 3: 275})
+	ExpectEq(t, events[0].String(), "seat 1: wins 151 (run 1)")
+}
+
+func TestAwardRuns_Once(t *testing.T) {
+	events, err := AwardRuns([]Pot{{600, []int{1, 2}}}, [][][]int{{{2}}}, 1)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, events, []Event{{Type: EventWin, Seat: 2, Amount: 600}})
+}
+
+func TestAwardRuns_Invalid(t *testing.T) {
+	pots := []Pot{{600, []int{1, 2}}}
+	for _, tc := range []struct {
+		name    string
+		winners [][][]int
+	}{
+		{"no runs", nil},
+		{"missing pot", [][][]int{{{1}}, {}}},
+		{"no winner", [][][]int{{{}}}},
+		{"not eligible", [][][]int{{{3}}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := AwardRuns(pots, tc.winners, 1)
+			ExpectThat(t, err, ErrorIs(ErrInvalidShowdown))
+		})
+	}
+}
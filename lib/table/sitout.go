@@ -0,0 +1,64 @@
+package table
+
+import (
+	"fmt"
+	"slices"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+// SitOutRules limit how long players may sit out.
+type SitOutRules struct {
+	// Players who miss this many orbits are removed from the table. Zero
+	// means no limit.
+	MaxOrbits int
+}
+
+// SitOutRulesFor returns the sit-out rules for a table.
+func SitOutRulesFor(cfg *pb.TableConfig) (SitOutRules, error) {
+	r := SitOutRules{MaxOrbits: int(cfg.GetSitOut().GetMaxOrbits())}
+	if r.MaxOrbits < 0 {
+		return SitOutRules{}, fmt.Errorf("%w: sit-out orbit limit must not be negative", ErrInvalidConfig)
+	}
+	return r, nil
+}
+
+// Expired returns the seats of players who have sat out too long and should
+// be removed from the table, in seat order.
+func (r SitOutRules) Expired(seats []Seat) []int {
+	if r.MaxOrbits == 0 {
+		return nil
+	}
+	var expired []int
+	for _, s := range seats {
+		if s.SittingOut && s.OrbitsOut >= r.MaxOrbits {
+			expired = append(expired, s.Seat)
+		}
+	}
+	slices.Sort(expired)
+	return expired
+}
+
+// SitOut marks the player in seat as sitting out from the next hand on.
+// They keep their seat and chips, but are not dealt in and miss the blinds
+// that pass them.
+func SitOut(seats []Seat, seat int) ([]Seat, error) {
+	return setSittingOut(seats, seat, true)
+}
+
+// Return marks the player in seat as back in play. They are dealt in from
+// the next hand on, once they have posted or, under the wait-for-big-blind
+// policy, waited for any blinds they missed.
+func Return(seats []Seat, seat int) ([]Seat, error) {
+	return setSittingOut(seats, seat, false)
+}
+
+func setSittingOut(seats []Seat, seat int, out bool) ([]Seat, error) {
+	i := slices.IndexFunc(seats, func(s Seat) bool { return s.Seat == seat })
+	if i < 0 {
+		return nil, fmt.Errorf("%w: nobody in seat %d", ErrInvalidAction, seat)
+	}
+	seats = slices.Clone(seats)
+	seats[i].SittingOut = out
+	return seats, nil
+}
@@ -0,0 +1,47 @@
+package table
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestSitOutRulesFor(t *testing.T) {
+	r, err := SitOutRulesFor(tableConfig(t, `sit_out { max_orbits: 3 }`))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, SitOutRules{MaxOrbits: 3})
+	r, err = SitOutRulesFor(tableConfig(t, ``))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, SitOutRules{})
+	_, err = SitOutRulesFor(tableConfig(t, `sit_out { max_orbits: -1 }`))
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+}
+
+func TestSitOutAndReturn(t *testing.T) {
+	r := SitOutRules{MaxOrbits: 2}
+	seats, err := SitOut(stacked(1, 2, 3, 4), 4)
+	AssertThat(t, err, Nil())
+	_, err = SitOut(seats, 5)
+	ExpectThat(t, err, ErrorIs(ErrInvalidAction))
+
+	// Each time the big blind passes, they miss an orbit.
+	d, seats := nextHand(t, blinds, Rotation{Button: 1, Blinds: []int{2, 3}}, seats)
+	ExpectThat(t, d.Players, Len(3))
+	ExpectEq(t, seats[3].OrbitsOut, 1)
+	ExpectThat(t, r.Expired(seats), Empty())
+	for range 3 {
+		d, seats = nextHand(t, blinds, d.Rotation, seats)
+	}
+	ExpectEq(t, seats[3].OrbitsOut, 2)
+	ExpectThat(t, r.Expired(seats), ElementsAre(4))
+	ExpectThat(t, SitOutRules{}.Expired(seats), Empty())
+
+	// Coming back, they post what they owe and the count starts over.
+	seats, err = Return(seats, 4)
+	AssertThat(t, err, Nil())
+	d, seats = nextHand(t, blinds, d.Rotation, seats)
+	ExpectThat(t, d.Players, Len(4))
+	ExpectEq(t, seats[3].OrbitsOut, 0)
+	ExpectEq(t, seats[3].OwesBig, false)
+	ExpectThat(t, r.Expired(seats), Empty())
+}
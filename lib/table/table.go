@@ -0,0 +1,80 @@
+// Package table is the engine that runs poker hands at a table: whose turn
+// it is, what they may do, and what happens when they do it. Every change
+// is recorded as an Event, so that a hand can be streamed to players,
+// written to its history, or replayed.
+//
+// Amounts are in chips, the smallest unit a table's stakes are given in:
+// hundredths of the currency unit.
+package table
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidConfig = errors.New("invalid table config")
+	ErrInvalidAction = errors.New("invalid action")
+	ErrNotYourTurn   = errors.New("not this seat's turn to act")
+	ErrRoundOver     = errors.New("betting round is over")
+)
+
+// Limit is a betting structure.
+type Limit int
+
+const (
+	NoLimit Limit = iota + 1
+	PotLimit
+	FixedLimit
+)
+
+func (l Limit) String() string {
+	switch l {
+	case NoLimit:
+		return "no limit"
+	case PotLimit:
+		return "pot limit"
+	case FixedLimit:
+		return "fixed limit"
+	}
+	return fmt.Sprintf("Limit(%d)", int(l))
+}
+
+// Action is something a player may do on their turn.
+type Action int
+
+const (
+	Fold Action = iota + 1
+	Check
+	Call
+	Bet
+	Raise
+)
+
+func (a Action) String() string {
+	switch a {
+	case Fold:
+		return "fold"
+	case Check:
+		return "check"
+	case Call:
+		return "call"
+	case Bet:
+		return "bet"
+	case Raise:
+		return "raise"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// amount is a google.type.Money.
+type amount interface {
+	GetUnits() int64
+	GetNanos() int32
+}
+
+// Chips converts an amount of money to chips. Fractions of a chip are
+// dropped.
+func Chips(m amount) int64 {
+	return m.GetUnits()*100 + int64(m.GetNanos())/10_000_000
+}
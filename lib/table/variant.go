@@ -0,0 +1,191 @@
+package table
+
+import (
+	"fmt"
+	"slices"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/handeval"
+)
+
+// Variant is a game the engine can deal: how many cards each player gets,
+// and how their hands are judged at showdown. Betting, pots and the board
+// work the same in every variant.
+type Variant interface {
+	// Name is the game as hand histories write it, such as "Hold'em".
+	Name() string
+
+	// HoleCards is how many cards each player is dealt face down.
+	HoleCards() int
+
+	// Best returns the best hand a player can make from their hole cards
+	// and a full board.
+	Best(hole, board []handeval.Card) (handeval.Hand, error)
+}
+
+// BoardSize is how many cards the board has once every street is dealt:
+// the flop, turn and river.
+const BoardSize = 5
+
+var standard = handeval.MustNew(handeval.Standard)
+
+// Holdem is Texas Hold'em: two hole cards, and the best five of those and
+// the board.
+var Holdem Variant = holdem{}
+
+type holdem struct{}
+
+func (holdem) Name() string   { return "Hold'em" }
+func (holdem) HoleCards() int { return 2 }
+
+func (holdem) Best(hole, board []handeval.Card) (handeval.Hand, error) {
+	if err := checkCards(2, hole, board); err != nil {
+		return handeval.Hand{}, err
+	}
+	return standard.Evaluate(slices.Concat(hole, board))
+}
+
+// Omaha is four hole cards, of which exactly two play with exactly three
+// from the board.
+var Omaha Variant = omaha{}
+
+type omaha struct{}
+
+func (omaha) Name() string   { return "Omaha" }
+func (omaha) HoleCards() int { return 4 }
+
+func (omaha) Best(hole, board []handeval.Card) (handeval.Hand, error) {
+	if err := checkCards(4, hole, board); err != nil {
+		return handeval.Hand{}, err
+	}
+	return bestOf(hole, 2, 2, board)
+}
+
+// Snapfold is the house game: three hole cards, of which at most two play
+// with the board. Unlike Hold'em, a player can't make a flush or straight
+// with three of their own cards; unlike Omaha, they may play the board.
+var Snapfold Variant = snapfold{}
+
+type snapfold struct{}
+
+func (snapfold) Name() string   { return "Snapfold" }
+func (snapfold) HoleCards() int { return 3 }
+
+func (snapfold) Best(hole, board []handeval.Card) (handeval.Hand, error) {
+	if err := checkCards(3, hole, board); err != nil {
+		return handeval.Hand{}, err
+	}
+	return bestOf(hole, 0, 2, board)
+}
+
+var variants = map[pb.TableConfig_Variant]Variant{
+	pb.TableConfig_GAME_UNKNOWN: Holdem,
+	pb.TableConfig_HOLDEM:       Holdem,
+	pb.TableConfig_OMAHA:        Omaha,
+	pb.TableConfig_SNAPFOLD:     Snapfold,
+}
+
+// VariantFor returns the variant a table deals. Hold'em is the default.
+func VariantFor(cfg *pb.TableConfig) (Variant, error) {
+	v, ok := variants[cfg.GetVariant()]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown variant %d", ErrInvalidConfig, cfg.GetVariant())
+	}
+	return v, nil
+}
+
+// DealHoleCards deals each seat its variant's hole cards from the top of
+// deck, a card at a time around the table starting with the first seat. It
+// returns the cards left in the deck.
+func DealHoleCards(v Variant, deck []handeval.Card, seats []int) (map[int][]handeval.Card, []handeval.Card, error) {
+	n := v.HoleCards()
+	if len(deck) < n*len(seats) {
+		return nil, nil, fmt.Errorf("%w: %d cards for %d seats of %d", ErrNotEnoughCards, len(deck), len(seats), n)
+	}
+	hole := make(map[int][]handeval.Card, len(seats))
+	for range n {
+		for _, seat := range seats {
+			hole[seat] = append(hole[seat], deck[0])
+			deck = deck[1:]
+		}
+	}
+	return hole, deck, nil
+}
+
+// Showdown returns the seats that win each pot on a board, as the variant
+// judges them: the eligible seats with the best hands, more than one if
+// they tie. hole holds the cards of every seat still in the hand. The
+// result is one run's winners for AwardRuns.
+func Showdown(v Variant, pots []Pot, hole map[int][]handeval.Card, board []handeval.Card) ([][]int, error) {
+	hands := map[int]handeval.Hand{}
+	for seat, cards := range hole {
+		h, err := v.Best(cards, board)
+		if err != nil {
+			return nil, fmt.Errorf("seat %d: %w", seat, err)
+		}
+		hands[seat] = h
+	}
+	winners := make([][]int, len(pots))
+	for i, pot := range pots {
+		var seats []int
+		var contenders []handeval.Hand
+		for _, seat := range pot.Eligible {
+			if h, ok := hands[seat]; ok {
+				seats = append(seats, seat)
+				contenders = append(contenders, h)
+			}
+		}
+		for _, w := range handeval.Winners(contenders) {
+			winners[i] = append(winners[i], seats[w])
+		}
+		if len(winners[i]) == 0 {
+			return nil, fmt.Errorf("%w: nobody showed down for pot %d", ErrInvalidShowdown, i+1)
+		}
+	}
+	return winners, nil
+}
+
+func checkCards(holeCards int, hole, board []handeval.Card) error {
+	if len(hole) != holeCards {
+		return fmt.Errorf("%w: %d hole cards, want %d", ErrInvalidShowdown, len(hole), holeCards)
+	}
+	if len(board) != BoardSize {
+		return fmt.Errorf("%w: %d board cards, want %d", ErrInvalidShowdown, len(board), BoardSize)
+	}
+	return nil
+}
+
+// bestOf returns the best five-card hand using between min and max of the
+// hole cards, and the rest from the board.
+func bestOf(hole []handeval.Card, minHole, maxHole int, board []handeval.Card) (handeval.Hand, error) {
+	var best handeval.Hand
+	found := false
+	for k := minHole; k <= maxHole; k++ {
+		for _, h := range combinations(hole, k) {
+			for _, b := range combinations(board, 5-k) {
+				hand, err := standard.Evaluate(slices.Concat(h, b))
+				if err != nil {
+					return handeval.Hand{}, err
+				}
+				if !found || hand.Compare(best) > 0 {
+					best, found = hand, true
+				}
+			}
+		}
+	}
+	return best, nil
+}
+
+// combinations returns every way to choose k of cards, in order.
+func combinations(cards []handeval.Card, k int) [][]handeval.Card {
+	if k == 0 {
+		return [][]handeval.Card{nil}
+	}
+	var out [][]handeval.Card
+	for i := 0; i+k <= len(cards); i++ {
+		for _, rest := range combinations(cards[i+1:], k-1) {
+			out = append(out, append([]handeval.Card{cards[i]}, rest...))
+		}
+	}
+	return out
+}
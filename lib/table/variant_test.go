@@ -0,0 +1,109 @@
+package table
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/handeval"
+)
+
+func TestVariantFor(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want Variant
+	}{
+		{``, Holdem},
+		{`variant: HOLDEM`, Holdem},
+		{`variant: OMAHA`, Omaha},
+		{`variant: SNAPFOLD`, Snapfold},
+	} {
+		v, err := VariantFor(tableConfig(t, tc.text))
+		AssertThat(t, err, Nil())
+		ExpectEq(t, v, tc.want)
+	}
+
+	cfg := pb.TableConfig_builder{Variant: pb.TableConfig_Variant(99).Enum()}.Build()
+	_, err := VariantFor(cfg)
+	ExpectThat(t, err, ErrorIs(ErrInvalidConfig))
+}
+
+func TestDealHoleCards(t *testing.T) {
+	deck := cards(t, "As Ks Qs Js Ts 9s 8s 7s 6s 5s")
+	hole, rest, err := DealHoleCards(Omaha, deck, []int{3, 1})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, hole[3], cards(t, "As Qs Ts 8s"))
+	ExpectEq(t, hole[1], cards(t, "Ks Js 9s 7s"))
+	ExpectEq(t, rest, cards(t, "6s 5s"))
+
+	_, _, err = DealHoleCards(Omaha, deck, []int{1, 2, 3})
+	ExpectThat(t, err, ErrorIs(ErrNotEnoughCards))
+}
+
+func TestBest(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		variant     Variant
+		hole, board string
+		want        handeval.Category
+	}{
+		// A lone heart makes a flush in Hold'em, but Omaha needs two.
+		{"holdem one heart", Holdem, "3h 3s", "Ah Kh Qh 7h 2d", handeval.Flush},
+		{"omaha one heart", Omaha, "3h 3s 4s 5s", "Ah Kh Qh 7h 2d", handeval.Pair},
+		{"omaha two hearts", Omaha, "Jh Th 4s 5s", "Ah Kh Qh 7h 2d", handeval.StraightFlush},
+
+		// Omaha can't play the board; the house game can.
+		{"holdem board", Holdem, "2c 3c", "As Ks Qs Js Ts", handeval.StraightFlush},
+		{"omaha board", Omaha, "2c 3c 4d 5d", "As Ks Qs Js Ts", handeval.HighCard},
+		{"snapfold board", Snapfold, "2c 3c 4d", "As Ks Qs Js Ts", handeval.StraightFlush},
+
+		// Nor can the house game play all three hole cards.
+		{"holdem three", Holdem, "Qh Jh", "Ah Kh Th 2d 3s", handeval.StraightFlush},
+		{"snapfold three", Snapfold, "Qh Jh Th", "Ah Kh 7c 2d 3s", handeval.HighCard},
+		{"snapfold two", Snapfold, "Qh Jh 4s", "Ah Kh Th 2d 3s", handeval.StraightFlush},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := tc.variant.Best(cards(t, tc.hole), cards(t, tc.board))
+			AssertThat(t, err, Nil())
+			ExpectEq(t, h.Category, tc.want)
+		})
+	}
+
+	_, err := Omaha.Best(cards(t, "Jh Th"), cards(t, "Ah Kh Qh 7h 2d"))
+	ExpectThat(t, err, ErrorIs(ErrInvalidShowdown))
+	_, err = Holdem.Best(cards(t, "Jh Th"), cards(t, "Ah Kh Qh"))
+	ExpectThat(t, err, ErrorIs(ErrInvalidShowdown))
+}
+
+func TestShowdown(t *testing.T) {
+	pots := []Pot{{Amount: 300, Eligible: []int{1, 2, 3}}, {Amount: 200, Eligible: []int{2, 3}}}
+	board := cards(t, "Ah Kh Qh 2c 2d")
+	hole := map[int][]handeval.Card{
+		1: cards(t, "Jh Th 4s 5s"),
+		2: cards(t, "Ac As 3c 3d"),
+		3: cards(t, "Ad Ks 3h 3s"),
+	}
+	winners, err := Showdown(Omaha, pots, hole, board)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, winners, [][]int{{1}, {2}})
+
+	// A folded seat has no cards, so can't win a pot it was eligible for.
+	delete(hole, 2)
+	delete(hole, 3)
+	_, err = Showdown(Omaha, pots, hole, board)
+	ExpectThat(t, err, ErrorIs(ErrInvalidShowdown))
+
+	_, err = Showdown(Holdem, pots, hole, board)
+	ExpectThat(t, err, ErrorIs(ErrInvalidShowdown))
+}
+
+func TestShowdown_Tie(t *testing.T) {
+	pots := []Pot{{Amount: 300, Eligible: []int{1, 2}}}
+	winners, err := Showdown(Holdem, pots, map[int][]handeval.Card{
+		1: cards(t, "3c 4d"),
+		2: cards(t, "3d 4c"),
+	}, cards(t, "Ah Kh Qh Jc Tc"))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, winners, [][]int{{1, 2}})
+}
@@ -0,0 +1,139 @@
+package table
+
+import (
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/handeval"
+)
+
+// The messages players send and are sent over a table's connection are the
+// same whatever carries them. These convert between them and the engine's
+// types. Seats are named by their players' IDs, given as a map from seat to
+// player.
+
+var actionKinds = map[pb.TableAction_Kind]Action{
+	pb.TableAction_FOLD:  Fold,
+	pb.TableAction_CHECK: Check,
+	pb.TableAction_CALL:  Call,
+	pb.TableAction_BET:   Bet,
+	pb.TableAction_RAISE: Raise,
+}
+
+// ActionFor returns the action a player sent, and for bets and raises their
+// total bet for the round afterward.
+func ActionFor(a *pb.TableAction) (Action, int64, error) {
+	action, ok := actionKinds[a.GetKind()]
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: unknown action %v", ErrInvalidAction, a.GetKind())
+	}
+	if a.HasTo() != (action == Bet || action == Raise) {
+		return 0, 0, fmt.Errorf("%w: only bets and raises have an amount", ErrInvalidAction)
+	}
+	return action, a.GetTo(), nil
+}
+
+var actedKinds = map[EventType]pb.PlayerActed_Kind{
+	EventFold:      pb.PlayerActed_FOLD,
+	EventCheck:     pb.PlayerActed_CHECK,
+	EventCall:      pb.PlayerActed_CALL,
+	EventBet:       pb.PlayerActed_BET,
+	EventRaise:     pb.PlayerActed_RAISE,
+	EventBlind:     pb.PlayerActed_BLIND,
+	EventStraddle:  pb.PlayerActed_STRADDLE,
+	EventDeadBlind: pb.PlayerActed_DEAD_BLIND,
+	EventAnte:      pb.PlayerActed_ANTE,
+}
+
+// EventMessage returns the message players are sent for an event of a
+// hand. It returns false for events that have messages of their own, such
+// as turns and timeouts, or none.
+func EventMessage(e Event, players map[int]string) (*pb.TableEvent, bool) {
+	if kind, ok := actedKinds[e.Type]; ok {
+		return pb.TableEvent_builder{
+			PlayerActed: pb.PlayerActed_builder{
+				PlayerId: proto.String(players[e.Seat]),
+				Kind:     kind.Enum(),
+				Amount:   proto.Int64(e.Amount),
+				Total:    proto.Int64(e.Total),
+				AllIn:    proto.Bool(e.AllIn),
+			}.Build(),
+		}.Build(), true
+	}
+	switch e.Type {
+	case EventButton:
+		return pb.TableEvent_builder{
+			HandDealt: pb.HandDealt_builder{ButtonPlayerId: proto.String(players[e.Seat])}.Build(),
+		}.Build(), true
+	case EventBoard:
+		return pb.TableEvent_builder{
+			BoardDealt: pb.BoardDealt_builder{Cards: cardStrings(e.Cards), Run: proto.Int32(int32(e.Run))}.Build(),
+		}.Build(), true
+	case EventWin:
+		return pb.TableEvent_builder{
+			PotAwarded: pb.PotAwarded_builder{
+				PlayerId: proto.String(players[e.Seat]),
+				Amount:   proto.Int64(e.Amount),
+				Run:      proto.Int32(int32(e.Run)),
+			}.Build(),
+		}.Build(), true
+	}
+	return nil, false
+}
+
+// OptionsMessage returns the message telling the player whose turn it is
+// what they may do.
+func OptionsMessage(o Options, players map[int]string) *pb.TableEvent {
+	return pb.TableEvent_builder{
+		TurnOptions: pb.TurnOptions_builder{
+			PlayerId: proto.String(players[o.Seat]),
+			Call:     proto.Int64(o.Call),
+			MinTo:    proto.Int64(o.MinTo),
+			MaxTo:    proto.Int64(o.MaxTo),
+		}.Build(),
+	}.Build()
+}
+
+// PotsMessage returns the message showing the pots as they stand.
+func PotsMessage(pots []Pot, players map[int]string) *pb.TableEvent {
+	msgs := make([]*pb.Pot, len(pots))
+	for i, p := range pots {
+		ids := make([]string, len(p.Eligible))
+		for j, seat := range p.Eligible {
+			ids[j] = players[seat]
+		}
+		msgs[i] = pb.Pot_builder{Amount: proto.Int64(p.Amount), PlayerIds: ids}.Build()
+	}
+	return pb.TableEvent_builder{PotUpdate: pb.PotUpdate_builder{Pots: msgs}.Build()}.Build()
+}
+
+// ShowdownMessage returns the message showing the cards shown down, by
+// seat, in seat order. made holds the best hand each seat makes, if the
+// board was run once.
+func ShowdownMessage(shown map[int][]handeval.Card, made map[int]handeval.Hand, players map[int]string) *pb.TableEvent {
+	seats := make([]int, 0, len(shown))
+	for seat := range shown {
+		seats = append(seats, seat)
+	}
+	slices.Sort(seats)
+	hands := make([]*pb.Showdown_Hand, len(seats))
+	for i, seat := range seats {
+		h := pb.Showdown_Hand_builder{PlayerId: proto.String(players[seat]), Cards: cardStrings(shown[seat])}
+		if m, ok := made[seat]; ok {
+			h.Description = proto.String(m.String())
+		}
+		hands[i] = h.Build()
+	}
+	return pb.TableEvent_builder{Showdown: pb.Showdown_builder{Hands: hands}.Build()}.Build()
+}
+
+func cardStrings(cards []handeval.Card) []string {
+	strs := make([]string, len(cards))
+	for i, c := range cards {
+		strs[i] = c.String()
+	}
+	return strs
+}
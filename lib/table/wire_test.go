@@ -0,0 +1,100 @@
+package table
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/handeval"
+)
+
+func TestActionFor(t *testing.T) {
+	action := func(kind pb.TableAction_Kind, to *int64) *pb.TableAction {
+		return pb.TableAction_builder{Kind: kind.Enum(), To: to}.Build()
+	}
+
+	a, to, err := ActionFor(action(pb.TableAction_RAISE, proto.Int64(300)))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a, Raise)
+	ExpectEq(t, to, int64(300))
+	a, _, err = ActionFor(action(pb.TableAction_CHECK, nil))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a, Check)
+
+	_, _, err = ActionFor(action(pb.TableAction_UNKNOWN, nil))
+	ExpectThat(t, err, ErrorIs(ErrInvalidAction))
+	_, _, err = ActionFor(action(pb.TableAction_BET, nil))
+	ExpectThat(t, err, ErrorIs(ErrInvalidAction))
+	_, _, err = ActionFor(action(pb.TableAction_CALL, proto.Int64(100)))
+	ExpectThat(t, err, ErrorIs(ErrInvalidAction))
+}
+
+func TestEventMessage(t *testing.T) {
+	players := map[int]string{1: "alice", 2: "bob"}
+
+	ev, ok := EventMessage(Event{Type: EventButton, Seat: 3}, players)
+	AssertEq(t, ok, true)
+	ExpectEq(t, ev.GetHandDealt().GetButtonPlayerId(), "")
+
+	ev, ok = EventMessage(Event{Type: EventRaise, Seat: 2, Amount: 250, Total: 300, AllIn: true}, players)
+	AssertEq(t, ok, true)
+	acted := ev.GetPlayerActed()
+	ExpectEq(t, acted.GetPlayerId(), "bob")
+	ExpectEq(t, acted.GetKind(), pb.PlayerActed_RAISE)
+	ExpectEq(t, acted.GetAmount(), int64(250))
+	ExpectEq(t, acted.GetTotal(), int64(300))
+	ExpectEq(t, acted.GetAllIn(), true)
+
+	ev, ok = EventMessage(Event{Type: EventAnte, Seat: 1, Amount: 5}, players)
+	AssertEq(t, ok, true)
+	ExpectEq(t, ev.GetPlayerActed().GetKind(), pb.PlayerActed_ANTE)
+
+	ev, ok = EventMessage(Event{Type: EventBoard, Seat: -1, Cards: cards(t, "Ah Kd 2c"), Run: 2}, players)
+	AssertEq(t, ok, true)
+	ExpectThat(t, ev.GetBoardDealt().GetCards(), ElementsAre("Ah", "Kd", "2c"))
+	ExpectEq(t, ev.GetBoardDealt().GetRun(), int32(2))
+
+	ev, ok = EventMessage(Event{Type: EventWin, Seat: 1, Amount: 600}, players)
+	AssertEq(t, ok, true)
+	ExpectEq(t, ev.GetPotAwarded().GetPlayerId(), "alice")
+	ExpectEq(t, ev.GetPotAwarded().GetAmount(), int64(600))
+
+	for _, typ := range []EventType{EventTurn, EventTimeout, EventRoundOver} {
+		_, ok := EventMessage(Event{Type: typ, Seat: 1}, players)
+		ExpectEq(t, ok, false)
+	}
+}
+
+func TestOptionsMessage(t *testing.T) {
+	opts := OptionsMessage(Options{Seat: 2, Call: 100, MinTo: 200, MaxTo: 1000}, map[int]string{2: "bob"}).GetTurnOptions()
+	ExpectEq(t, opts.GetPlayerId(), "bob")
+	ExpectEq(t, opts.GetCall(), int64(100))
+	ExpectEq(t, opts.GetMinTo(), int64(200))
+	ExpectEq(t, opts.GetMaxTo(), int64(1000))
+}
+
+func TestPotsMessage(t *testing.T) {
+	players := map[int]string{1: "alice", 2: "bob", 3: "carol"}
+	pots := PotsMessage([]Pot{{Amount: 300, Eligible: []int{1, 2, 3}}, {Amount: 400, Eligible: []int{2, 3}}}, players).GetPotUpdate().GetPots()
+	AssertThat(t, pots, Len(2))
+	ExpectEq(t, pots[0].GetAmount(), int64(300))
+	ExpectThat(t, pots[0].GetPlayerIds(), ElementsAre("alice", "bob", "carol"))
+	ExpectThat(t, pots[1].GetPlayerIds(), ElementsAre("bob", "carol"))
+}
+
+func TestShowdownMessage(t *testing.T) {
+	players := map[int]string{1: "alice", 2: "bob"}
+	made, err := handeval.MustNew(handeval.Standard).Evaluate(cards(t, "As Ad Ks Kd 2c"))
+	AssertThat(t, err, Nil())
+	shown := map[int][]handeval.Card{2: cards(t, "7c 2d"), 1: cards(t, "As Ad")}
+
+	hands := ShowdownMessage(shown, map[int]handeval.Hand{1: made}, players).GetShowdown().GetHands()
+	AssertThat(t, hands, Len(2))
+	ExpectEq(t, hands[0].GetPlayerId(), "alice")
+	ExpectThat(t, hands[0].GetCards(), ElementsAre("As", "Ad"))
+	ExpectEq(t, hands[0].GetDescription(), made.String())
+	ExpectEq(t, hands[1].GetPlayerId(), "bob")
+	ExpectEq(t, hands[1].HasDescription(), false)
+}
@@ -0,0 +1,225 @@
+// Package tournament applies the rules of a tournament that span its
+// tables: the blind schedule, late registration, and payouts. Each table is
+// run by package table, with the blinds of the level the tournament is at.
+//
+// Amounts are in tournament chips, except for the prize pool, which is in
+// the chips the buy-ins were paid in.
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+var ErrInvalidStructure = errors.New("invalid tournament structure")
+
+// DefaultRatio is how many times the next place's payout each place gets
+// when a structure doesn't say.
+const DefaultRatio = 1.5
+
+// Level is one level of the blind schedule.
+type Level struct {
+	// Blinds from smallest to largest, and the ante.
+	Blinds []int64
+	Ante   int64
+
+	// How long the level lasts. Zero for the last level, which lasts until
+	// the tournament ends.
+	Duration time.Duration
+}
+
+// Structure is a tournament's structure, checked and with defaults filled
+// in.
+type Structure struct {
+	StartingStack int64
+	Levels        []Level
+
+	// Whether players may register after the start, and if so for how
+	// long after it, and until which level starts, from 0. A zero period
+	// or a level of -1 means no limit of that kind.
+	LateRegistration bool
+	LatePeriod       time.Duration
+	LateLevel        int
+
+	Payouts Payouts
+
+	// The game every table plays.
+	table *pb.TableConfig
+}
+
+// StructureFor returns the structure from its config.
+func StructureFor(cfg *pb.TournamentStructure) (Structure, error) {
+	s := Structure{
+		StartingStack: cfg.GetStartingStack(),
+		LateLevel:     -1,
+		table:         cfg.GetTable(),
+	}
+	if s.StartingStack <= 0 {
+		return Structure{}, fmt.Errorf("%w: starting stack must be positive", ErrInvalidStructure)
+	}
+
+	levels := cfg.GetLevels()
+	if len(levels) == 0 {
+		return Structure{}, fmt.Errorf("%w: no levels", ErrInvalidStructure)
+	}
+	for i, l := range levels {
+		level := Level{Blinds: l.GetBlinds(), Ante: l.GetAnte()}
+		if i < len(levels)-1 {
+			level.Duration = cfg.GetLevelDuration().AsDuration()
+			if l.HasDuration() {
+				level.Duration = l.GetDuration().AsDuration()
+			}
+			if level.Duration <= 0 {
+				return Structure{}, fmt.Errorf("%w: level %d has no duration", ErrInvalidStructure, i+1)
+			}
+		}
+		// The table checks each level's blinds along with the rest of its
+		// blind rules.
+		if _, err := table.LevelBlindRules(s.table, level.Blinds, level.Ante); err != nil {
+			return Structure{}, fmt.Errorf("%w: level %d: %w", ErrInvalidStructure, i+1, err)
+		}
+		s.Levels = append(s.Levels, level)
+	}
+
+	if cfg.HasLateRegistration() {
+		late := cfg.GetLateRegistration()
+		s.LateRegistration = true
+		s.LatePeriod = late.GetPeriod().AsDuration()
+		if n := late.GetUntilLevel(); n > 0 {
+			s.LateLevel = int(n) - 1
+		}
+		if s.LatePeriod < 0 || late.GetUntilLevel() < 0 {
+			return Structure{}, fmt.Errorf("%w: late registration limits must not be negative", ErrInvalidStructure)
+		}
+		if s.LatePeriod == 0 && s.LateLevel < 0 {
+			return Structure{}, fmt.Errorf("%w: late registration has no limit", ErrInvalidStructure)
+		}
+	}
+
+	p, err := PayoutsFor(cfg.GetPayouts())
+	if err != nil {
+		return Structure{}, err
+	}
+	s.Payouts = p
+	return s, nil
+}
+
+// LevelAt returns the index of the level the tournament is at elapsed
+// after it started, and how long is left in it. Time left is zero in the
+// last level.
+func (s Structure) LevelAt(elapsed time.Duration) (int, time.Duration) {
+	for i, l := range s.Levels[:len(s.Levels)-1] {
+		if elapsed < l.Duration {
+			return i, l.Duration - max(elapsed, 0)
+		}
+		elapsed -= l.Duration
+	}
+	return len(s.Levels) - 1, 0
+}
+
+// BlindRules returns the blind rules for tables at the given level.
+func (s Structure) BlindRules(level int) (table.BlindRules, error) {
+	l := s.Levels[level]
+	return table.LevelBlindRules(s.table, l.Blinds, l.Ante)
+}
+
+// Registering reports whether players may still register elapsed after
+// the tournament started.
+func (s Structure) Registering(elapsed time.Duration) bool {
+	if elapsed < 0 {
+		return true
+	}
+	if !s.LateRegistration {
+		return false
+	}
+	if s.LatePeriod > 0 && elapsed >= s.LatePeriod {
+		return false
+	}
+	if level, _ := s.LevelAt(elapsed); s.LateLevel >= 0 && level >= s.LateLevel {
+		return false
+	}
+	return true
+}
+
+// Payouts say how the prize pool is split.
+type Payouts struct {
+	// Shares of the pool by place, first place first, in basis points.
+	Shares []int64
+
+	// Without shares, the share of entrants paid in basis points, and the
+	// ratio between each place's payout and the next.
+	PaidBasisPoints int64
+	Ratio           float64
+}
+
+// PayoutsFor returns the payouts from their config.
+func PayoutsFor(cfg *pb.TournamentStructure_Payouts) (Payouts, error) {
+	p := Payouts{
+		PaidBasisPoints: int64(cfg.GetPaidBasisPoints()),
+		Ratio:           cfg.GetRatio(),
+	}
+	for _, share := range cfg.GetShares() {
+		p.Shares = append(p.Shares, int64(share))
+	}
+	if len(p.Shares) > 0 {
+		var total int64
+		for i, share := range p.Shares {
+			if share <= 0 || (i > 0 && share > p.Shares[i-1]) {
+				return Payouts{}, fmt.Errorf("%w: payout shares must be positive and given from first place down", ErrInvalidStructure)
+			}
+			total += share
+		}
+		if total != 10000 {
+			return Payouts{}, fmt.Errorf("%w: payout shares add up to %d basis points, not 10000", ErrInvalidStructure, total)
+		}
+		return p, nil
+	}
+	if p.PaidBasisPoints <= 0 || p.PaidBasisPoints > 10000 {
+		return Payouts{}, fmt.Errorf("%w: paid share must be between 1 and 10000 basis points", ErrInvalidStructure)
+	}
+	if p.Ratio == 0 {
+		p.Ratio = DefaultRatio
+	}
+	if p.Ratio < 1 || math.IsInf(p.Ratio, 0) || math.IsNaN(p.Ratio) {
+		return Payouts{}, fmt.Errorf("%w: payout ratio must be at least 1", ErrInvalidStructure)
+	}
+	return p, nil
+}
+
+// Pay splits a prize pool between the places paid when entrants players
+// entered, and returns each place's prize, first place first. Fractions of
+// a chip go to first place.
+func (p Payouts) Pay(pool int64, entrants int) []int64 {
+	if entrants <= 0 || pool <= 0 {
+		return nil
+	}
+	var weights []float64
+	if len(p.Shares) > 0 {
+		for _, share := range p.Shares[:min(len(p.Shares), entrants)] {
+			weights = append(weights, float64(share))
+		}
+	} else {
+		paid := max(int(int64(entrants)*p.PaidBasisPoints/10000), 1)
+		for i := range paid {
+			weights = append(weights, math.Pow(p.Ratio, float64(paid-1-i)))
+		}
+	}
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+
+	prizes := make([]int64, len(weights))
+	left := pool
+	for i, w := range weights {
+		prizes[i] = int64(float64(pool) * w / total)
+		left -= prizes[i]
+	}
+	prizes[0] += left
+	return prizes
+}
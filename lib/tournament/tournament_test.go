@@ -0,0 +1,120 @@
+package tournament
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/encoding/prototext"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/table"
+)
+
+func structure(t *testing.T, text string) *pb.TournamentStructure {
+	t.Helper()
+	cfg := &pb.TournamentStructure{}
+	AssertThat(t, prototext.Unmarshal([]byte(text), cfg), Nil())
+	return cfg
+}
+
+const turbo = `
+	table { blinds { utg_straddle: 1 } }
+	starting_stack: 5000
+	level_duration { seconds: 300 }
+	levels { blinds: [25, 50] }
+	levels { blinds: [50, 100] ante: 10 duration { seconds: 600 } }
+	levels { blinds: [100, 200] ante: 25 }
+	late_registration { period { seconds: 1200 } until_level: 3 }
+	payouts { paid_basis_points: 2000 ratio: 2 }
+`
+
+func TestStructureFor(t *testing.T) {
+	s, err := StructureFor(structure(t, turbo))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.StartingStack, int64(5000))
+	ExpectThat(t, s.Levels, ElementsAre(
+		Level{Blinds: []int64{25, 50}, Duration: 5 * time.Minute},
+		Level{Blinds: []int64{50, 100}, Ante: 10, Duration: 10 * time.Minute},
+		Level{Blinds: []int64{100, 200}, Ante: 25},
+	))
+	ExpectEq(t, s.LatePeriod, 20*time.Minute)
+	ExpectEq(t, s.LateLevel, 2)
+
+	for _, text := range []string{
+		`levels { blinds: [1, 2] }`,
+		`starting_stack: 100`,
+		`starting_stack: 100 levels { blinds: [1, 2] } levels { blinds: [2, 4] } payouts { shares: 10000 }`,
+		`starting_stack: 100 levels { blinds: [2, 1] } payouts { shares: 10000 }`,
+		`starting_stack: 100 levels { blinds: [1, 2] ante: -1 } payouts { shares: 10000 }`,
+		`starting_stack: 100 levels { blinds: [1, 2] } late_registration {} payouts { shares: 10000 }`,
+		`starting_stack: 100 levels { blinds: [1, 2] }`,
+		`starting_stack: 100 levels { blinds: [1, 2] } payouts { shares: [5000, 4000] }`,
+		`starting_stack: 100 levels { blinds: [1, 2] } payouts { paid_basis_points: 1000 ratio: 0.5 }`,
+		`table { bets: FIXED_LIMIT blinds { utg_straddle: 1 } } starting_stack: 100 levels { blinds: [1, 2] } payouts { shares: 10000 }`,
+	} {
+		_, err := StructureFor(structure(t, text))
+		ExpectThat(t, err, ErrorIs(ErrInvalidStructure))
+	}
+}
+
+func TestLevelAt(t *testing.T) {
+	s, err := StructureFor(structure(t, turbo))
+	AssertThat(t, err, Nil())
+	for _, tc := range []struct {
+		elapsed time.Duration
+		level   int
+		left    time.Duration
+	}{
+		{0, 0, 5 * time.Minute},
+		{4 * time.Minute, 0, time.Minute},
+		{5 * time.Minute, 1, 10 * time.Minute},
+		{14 * time.Minute, 1, time.Minute},
+		{15 * time.Minute, 2, 0},
+		{10 * time.Hour, 2, 0},
+	} {
+		level, left := s.LevelAt(tc.elapsed)
+		ExpectEq(t, level, tc.level)
+		ExpectEq(t, left, tc.left)
+	}
+
+	r, err := s.BlindRules(1)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, table.BlindRules{Blinds: []int64{50, 100}, Ante: 10, Straddles: 1})
+}
+
+func TestRegistering(t *testing.T) {
+	s, err := StructureFor(structure(t, turbo))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.Registering(-time.Minute), true)
+	ExpectEq(t, s.Registering(14*time.Minute), true)
+	// Level 3 starts before the period ends.
+	ExpectEq(t, s.Registering(15*time.Minute), false)
+
+	s.LateLevel = -1
+	ExpectEq(t, s.Registering(19*time.Minute), true)
+	ExpectEq(t, s.Registering(20*time.Minute), false)
+
+	s.LateRegistration = false
+	ExpectEq(t, s.Registering(0), false)
+}
+
+func TestPay(t *testing.T) {
+	p, err := PayoutsFor(structure(t, turbo).GetPayouts())
+	AssertThat(t, err, Nil())
+	// 20% of 10 entrants is 2 places, the first getting twice the second.
+	ExpectThat(t, p.Pay(1000, 10), ElementsAre(int64(667), int64(333)))
+	// At least one place is paid.
+	ExpectThat(t, p.Pay(1000, 3), ElementsAre(int64(1000)))
+	ExpectThat(t, p.Pay(1000, 0), Empty())
+
+	p, err = PayoutsFor(structure(t, `payouts { shares: [5000, 3000, 2000] }`).GetPayouts())
+	AssertThat(t, err, Nil())
+	ExpectThat(t, p.Pay(1001, 9), ElementsAre(int64(501), int64(300), int64(200)))
+	// With only two entrants, their shares take the whole pool.
+	ExpectThat(t, p.Pay(800, 2), ElementsAre(int64(500), int64(300)))
+
+	p, err = PayoutsFor(structure(t, `payouts { paid_basis_points: 10000 }`).GetPayouts())
+	AssertThat(t, err, Nil())
+	ExpectEq(t, p.Ratio, DefaultRatio)
+}
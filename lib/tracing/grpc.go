@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// ServerHandler returns a gRPC stats handler, for grpc.StatsHandler, that
+// serves each call in a server span, continuing the caller's trace from its
+// metadata.
+func ServerHandler() stats.Handler {
+	return &statsHandler{kind: trace.SpanKindServer}
+}
+
+// ClientHandler returns a gRPC stats handler, for grpc.WithStatsHandler,
+// that makes each call in a client span and passes that span's trace
+// context on in the call's metadata.
+func ClientHandler() stats.Handler {
+	return &statsHandler{kind: trace.SpanKindClient}
+}
+
+type statsHandler struct {
+	kind trace.SpanKind
+}
+
+// metadataCarrier adapts gRPC metadata to a propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+var _ propagation.TextMapCarrier = metadataCarrier{}
+
+func (h *statsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	attrs := []attribute.KeyValue{semconv.RPCSystemGRPC}
+	if service, method, ok := strings.Cut(strings.TrimPrefix(info.FullMethodName, "/"), "/"); ok {
+		attrs = append(attrs, semconv.RPCService(service), semconv.RPCMethod(method))
+	}
+	if h.kind == trace.SpanKindServer {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = propagator.Extract(ctx, metadataCarrier(md))
+	}
+	ctx, _ = otel.Tracer(instrumentation).Start(ctx, strings.TrimPrefix(info.FullMethodName, "/"),
+		trace.WithSpanKind(h.kind), trace.WithAttributes(attrs...))
+	if h.kind == trace.SpanKindClient {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		md = md.Copy()
+		propagator.Inject(ctx, metadataCarrier(md))
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+	return ctx
+}
+
+func (h *statsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	end, ok := s.(*stats.End)
+	if !ok {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	st, _ := status.FromError(end.Error)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(st.Code())))
+	if end.Error != nil {
+		span.SetStatus(codes.Error, st.Message())
+	}
+	span.End()
+}
+
+func (h *statsHandler) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *statsHandler) HandleConn(ctx context.Context, s stats.ConnStats) {}
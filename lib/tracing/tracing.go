@@ -0,0 +1,120 @@
+// Package tracing exports the services' OpenTelemetry spans over OTLP, and
+// carries trace context across the HTTP and gRPC calls between them.
+//
+// Trace context travels in W3C traceparent headers. Where work outlives the
+// request that started it, such as a queued ticket or a match handed to a
+// game server, the traceparent is kept alongside it as a string, so that
+// later spans can continue or link to the trace.
+//
+// Spans are started from the global tracer provider, which does nothing
+// until Setup installs one, so code can be traced whether or not spans are
+// exported.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/jfmatt/snapfold/lib/tracing"
+
+// propagator reads and writes the trace context of calls between services.
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Config says where to export spans.
+type Config struct {
+	// Name the service's spans are reported under, such as matchmaker.
+	Service string
+
+	// host:port of an OTLP/gRPC collector. If empty, spans are not
+	// exported, though trace context is still passed on.
+	Endpoint string
+
+	// Whether to connect to the collector without TLS.
+	Insecure bool
+
+	// Fraction of traces started by this service to sample, from 0 to 1.
+	// Traces continued from a caller are sampled if the caller's are.
+	SampleRatio float64
+}
+
+// Setup installs a global tracer provider that exports spans as cfg says.
+// The returned function flushes any spans not yet exported and stops
+// exporting; it should be called before the service exits.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagator)
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("tracing: sample ratio %g is not between 0 and 1", cfg.SampleRatio)
+	}
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(cfg.Service)),
+		resource.WithHost(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Traceparent returns the W3C traceparent of the span in ctx, or "" if
+// there is none.
+func Traceparent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// Continue returns ctx with the span described by traceparent as the
+// parent of spans started from it. If traceparent is empty or malformed,
+// ctx is returned as is.
+func Continue(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{"traceparent": traceparent})
+}
+
+// Link returns a link to the span described by traceparent, and whether
+// there is one.
+func Link(traceparent string) (trace.Link, bool) {
+	sc := trace.SpanContextFromContext(Continue(context.Background(), traceparent))
+	return trace.Link{SpanContext: sc}, sc.IsValid()
+}
+
+// End ends span, first marking it failed if err is not nil. Cancellations
+// are not counted as failures.
+func End(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, context.Canceled) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
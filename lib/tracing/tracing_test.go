@@ -0,0 +1,131 @@
+package tracing
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// record installs a tracer provider that keeps every span, for the rest of
+// the test.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+// spanNamed returns the ended span with the name, failing the test if
+// there is none.
+func spanNamed(t *testing.T, rec *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, s := range rec.Ended() {
+		if s.Name() == name {
+			return s
+		}
+	}
+	t.Fatalf("no span named %q", name)
+	return nil
+}
+
+func TestTraceparent(t *testing.T) {
+	record(t)
+	ExpectEq(t, Traceparent(context.Background()), "")
+	_, ok := Link("")
+	ExpectEq(t, ok, false)
+	_, ok = Link("bogus")
+	ExpectEq(t, ok, false)
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "root")
+	defer span.End()
+	tp := Traceparent(ctx)
+	ExpectThat(t, tp, HasSubstr(span.SpanContext().TraceID().String()))
+
+	got := trace.SpanContextFromContext(Continue(context.Background(), tp))
+	ExpectEq(t, got.TraceID(), span.SpanContext().TraceID())
+	ExpectEq(t, got.SpanID(), span.SpanContext().SpanID())
+	ExpectEq(t, got.IsRemote(), true)
+	link, ok := Link(tp)
+	ExpectEq(t, ok, true)
+	ExpectEq(t, link.SpanContext.SpanID(), span.SpanContext().SpanID())
+}
+
+func TestHTTP(t *testing.T) {
+	rec := record(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tables/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	srv := httptest.NewServer(Handler(mux))
+	defer srv.Close()
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root")
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/tables/t1", nil)
+	AssertThat(t, err, Nil())
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	AssertThat(t, err, Nil())
+	resp.Body.Close()
+	root.End()
+
+	client := spanNamed(t, rec, "GET")
+	ExpectEq(t, client.Parent().SpanID(), root.SpanContext().SpanID())
+	ExpectEq(t, client.SpanKind(), trace.SpanKindClient)
+	server := spanNamed(t, rec, "GET /tables/{id}")
+	ExpectEq(t, server.SpanContext().TraceID(), root.SpanContext().TraceID())
+	ExpectEq(t, server.Parent().SpanID(), client.SpanContext().SpanID())
+	ExpectEq(t, server.SpanKind(), trace.SpanKindServer)
+	ExpectThat(t, server.Attributes(), Contains(semconv.HTTPResponseStatusCode(http.StatusTeapot)))
+}
+
+func TestGRPC(t *testing.T) {
+	rec := record(t)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	AssertThat(t, err, Nil())
+	srv := grpc.NewServer(grpc.StatsHandler(ServerHandler()))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(ClientHandler()))
+	AssertThat(t, err, Nil())
+	defer conn.Close()
+
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root")
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	AssertThat(t, err, Nil())
+	root.End()
+	srv.GracefulStop()
+
+	var client, server sdktrace.ReadOnlySpan
+	for _, s := range rec.Ended() {
+		if s.Name() != "grpc.health.v1.Health/Check" {
+			continue
+		}
+		switch s.SpanKind() {
+		case trace.SpanKindClient:
+			client = s
+		case trace.SpanKindServer:
+			server = s
+		}
+	}
+	AssertThat(t, client, Not(Nil()))
+	AssertThat(t, server, Not(Nil()))
+	ExpectEq(t, client.Parent().SpanID(), root.SpanContext().SpanID())
+	ExpectEq(t, server.Parent().SpanID(), client.SpanContext().SpanID())
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os/user"
+
+	"github.com/jfmatt/flagr"
+	"github.com/spf13/cobra"
+
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+	"github.com/jfmatt/snapfold/matchmaker/store"
+)
+
+func AccountCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "account",
+		Short: "Manage player accounts",
+	}
+
+	setRoleCmd := &cobra.Command{
+		Use:   "set-role",
+		Short: "Change an account's role, e.g. to make the first admin",
+	}
+	setRoleCmd.RunE = flagr.Run(setRoleCmd, SetRole)
+	c.AddCommand(setRoleCmd)
+
+	return c
+}
+
+type SetRoleArgs struct {
+	Dsn      string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	Username string `flag:"username,required,help=Account to change"`
+	Role     string `flag:"role,required,help=New role: player, moderator or admin"`
+}
+
+// consoleActor is who the audit log shows as taking actions from the
+// command line, which has no account signed in: the operating system user.
+func consoleActor() string {
+	if u, err := user.Current(); err == nil {
+		return "console:" + u.Username
+	}
+	return "console"
+}
+
+func SetRole(flags *SetRoleArgs, cmd *cobra.Command, args []string) error {
+	role, err := account.ParseRole(flags.Role)
+	if err != nil {
+		return err
+	}
+	db, err := store.Open(cmd.Context(), flags.Dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m := account.NewManager(store.NewAccounts(db))
+	a, err := m.Get(cmd.Context(), flags.Username)
+	if err != nil {
+		return err
+	}
+	if err := audit.New(store.NewAuditLog(db)).Record(cmd.Context(), consoleActor(), audit.RoleSet, a.Username, a.Role, role); err != nil {
+		return fmt.Errorf("recording the change in the audit log: %w", err)
+	}
+	if err := m.SetRole(cmd.Context(), a.Username, role); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s is now %s\n", flags.Username, role)
+	return nil
+}
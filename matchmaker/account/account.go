@@ -0,0 +1,418 @@
+// Package account manages player accounts: registering a username and
+// password, and checking them at login.
+package account
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jfmatt/snapfold/matchmaker/identity"
+)
+
+var (
+	ErrNotFound           = errors.New("account not found")
+	ErrUsernameTaken      = errors.New("username is taken")
+	ErrInvalidUsername    = errors.New("invalid username")
+	ErrInvalidPassword    = errors.New("invalid password")
+	ErrInvalidCredentials = errors.New("incorrect username or password")
+	ErrUnknownProvider    = errors.New("unknown identity provider")
+	ErrIdentityLinked     = errors.New("identity is linked to another account")
+	ErrProviderLinked     = errors.New("account already has an identity from this provider")
+	ErrLastCredential     = errors.New("cannot remove the account's only way to log in")
+	ErrNotGuest           = errors.New("account is not a guest")
+	ErrInvalidEmail       = errors.New("invalid email address")
+)
+
+const (
+	MinUsernameLen = 3
+	MaxUsernameLen = 32
+
+	MinPasswordLen = 8
+	// Passwords are capped so that hashing one stays cheap.
+	MaxPasswordLen = 256
+)
+
+// Account is a registered player. Usernames double as player IDs.
+type Account struct {
+	Username string
+
+	// Email is where account mail, such as verification links, is sent.
+	// Empty for guests and accounts created by signing in with an identity
+	// provider.
+	Email         string
+	EmailVerified bool
+
+	// The password, hashed with Hash. Empty for accounts created by signing
+	// in with an identity provider, which cannot log in with a password.
+	PasswordHash string
+
+	// Guests are created without a username or password and are bound to
+	// the device that created them, which logs in with a device token in
+	// place of a password. Upgrading a guest keeps its username, and so its
+	// ratings and match history.
+	Guest bool
+
+	Role Role
+
+	// Set if the account has been banned, even if the ban has since ended.
+	Ban *Ban
+
+	CreatedAt time.Time
+}
+
+// Link ties an identity provider's user to an account.
+type Link struct {
+	Provider string
+	Subject  string
+	Username string
+	LinkedAt time.Time
+}
+
+// Manager registers accounts and checks logins. It is safe for concurrent
+// use.
+type Manager struct {
+	store     Store
+	params    Params
+	providers map[string]identity.Provider
+	verifier  *mailer
+	resetter  *mailer
+	now       func() time.Time
+
+	// Hash of a random password, checked against when a username is not
+	// found so that logins take as long whether or not it exists.
+	dummy string
+}
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithParams sets the cost of hashing new passwords. Passwords already
+// hashed keep the parameters they were hashed with.
+func WithParams(p Params) Option {
+	return func(m *Manager) { m.params = p }
+}
+
+// WithProviders lets players sign in with the identity providers.
+func WithProviders(providers ...identity.Provider) Option {
+	return func(m *Manager) {
+		for _, p := range providers {
+			m.providers[p.Name()] = p
+		}
+	}
+}
+
+// NewManager returns a Manager that keeps accounts in store.
+func NewManager(store Store, opts ...Option) *Manager {
+	m := &Manager{store: store, params: DefaultParams, providers: map[string]identity.Provider{}, now: time.Now}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.dummy = Hash(rand.Text(), m.params)
+	return m
+}
+
+// Register creates an account. It fails with ErrUsernameTaken if the
+// username is in use. If email verification is enabled, a verification link
+// is sent to email, and the account cannot log in until it is followed.
+func (m *Manager) Register(ctx context.Context, username, email, password string) (Account, error) {
+	if err := ValidateUsername(username); err != nil {
+		return Account{}, err
+	}
+	if err := ValidateEmail(email); err != nil {
+		return Account{}, err
+	}
+	if err := ValidatePassword(password); err != nil {
+		return Account{}, err
+	}
+	a := Account{
+		Username:     username,
+		Email:        email,
+		PasswordHash: Hash(password, m.params),
+		Role:         RolePlayer,
+		CreatedAt:    m.now(),
+	}
+	if err := m.store.CreateAccount(ctx, a); err != nil {
+		return Account{}, err
+	}
+	if err := m.sendVerification(ctx, a); err != nil {
+		return Account{}, err
+	}
+	return a, nil
+}
+
+// CreateGuest creates a guest account and returns it along with the device
+// token that logs in to it, which the device should keep.
+func (m *Manager) CreateGuest(ctx context.Context) (Account, string, error) {
+	token := rand.Text()
+	for {
+		a := Account{
+			Username:     "guest-" + rand.Text()[:10],
+			PasswordHash: Hash(token, m.params),
+			Guest:        true,
+			Role:         RolePlayer,
+			CreatedAt:    m.now(),
+		}
+		err := m.store.CreateAccount(ctx, a)
+		if err == nil {
+			return a, token, nil
+		}
+		if !errors.Is(err, ErrUsernameTaken) {
+			return Account{}, "", err
+		}
+	}
+}
+
+// Upgrade turns a guest account into a full one that logs in with a
+// password, and verifies email as Register does. The device token stops
+// working. It fails with ErrNotGuest for accounts that are not guests.
+func (m *Manager) Upgrade(ctx context.Context, username, email, password string) (Account, error) {
+	if err := ValidateEmail(email); err != nil {
+		return Account{}, err
+	}
+	if err := ValidatePassword(password); err != nil {
+		return Account{}, err
+	}
+	a, err := m.store.GetAccount(ctx, username)
+	if err != nil {
+		return Account{}, err
+	}
+	if !a.Guest {
+		return Account{}, ErrNotGuest
+	}
+	a.Guest = false
+	a.Email = email
+	a.PasswordHash = Hash(password, m.params)
+	if err := m.store.UpdateAccount(ctx, a); err != nil {
+		return Account{}, err
+	}
+	if err := m.sendVerification(ctx, a); err != nil {
+		return Account{}, err
+	}
+	return a, nil
+}
+
+// Login returns the account if the password is correct, and
+// ErrInvalidCredentials if the username or password is wrong. If email
+// verification is enabled, it fails with ErrEmailUnverified until the
+// account's email has been verified. Banned accounts fail with ErrBanned.
+func (m *Manager) Login(ctx context.Context, username, password string) (Account, error) {
+	a, err := m.checkPassword(ctx, username, password)
+	if err != nil {
+		return Account{}, err
+	}
+	if m.verifier != nil && a.Email != "" && !a.EmailVerified {
+		return Account{}, ErrEmailUnverified
+	}
+	if err := m.checkBan(a); err != nil {
+		return Account{}, err
+	}
+	return a, nil
+}
+
+// checkPassword returns the account if the password is correct.
+func (m *Manager) checkPassword(ctx context.Context, username, password string) (Account, error) {
+	if len(password) > MaxPasswordLen {
+		return Account{}, ErrInvalidCredentials
+	}
+	a, err := m.store.GetAccount(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		Verify(password, m.dummy)
+		return Account{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return Account{}, err
+	}
+	if !Verify(password, a.PasswordHash) {
+		return Account{}, ErrInvalidCredentials
+	}
+	return a, nil
+}
+
+// SignIn verifies a token from an identity provider and returns the account
+// linked to the identity it names, creating one the first time. Banned
+// accounts fail with ErrBanned.
+func (m *Manager) SignIn(ctx context.Context, provider, token string) (Account, error) {
+	a, err := m.signIn(ctx, provider, token)
+	if err != nil {
+		return Account{}, err
+	}
+	if err := m.checkBan(a); err != nil {
+		return Account{}, err
+	}
+	return a, nil
+}
+
+func (m *Manager) signIn(ctx context.Context, provider, token string) (Account, error) {
+	id, err := m.verify(ctx, provider, token)
+	if err != nil {
+		return Account{}, err
+	}
+	username, err := m.store.GetIdentity(ctx, id.Provider, id.Subject)
+	if err == nil {
+		return m.store.GetAccount(ctx, username)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return Account{}, err
+	}
+
+	a, err := m.createFor(ctx, id)
+	if err != nil {
+		return Account{}, err
+	}
+	link := Link{Provider: id.Provider, Subject: id.Subject, Username: a.Username, LinkedAt: m.now()}
+	if err := m.store.LinkIdentity(ctx, link); errors.Is(err, ErrIdentityLinked) {
+		// Another sign-in with the same identity won the race.
+		username, err := m.store.GetIdentity(ctx, id.Provider, id.Subject)
+		if err != nil {
+			return Account{}, err
+		}
+		return m.store.GetAccount(ctx, username)
+	} else if err != nil {
+		return Account{}, err
+	}
+	return a, nil
+}
+
+// Link verifies a token from an identity provider and links the identity it
+// names to an existing account, so that the player can sign in with it.
+// Linking an identity to a guest upgrades it, and its device token stops
+// working. It fails with ErrIdentityLinked if the identity belongs to
+// another account, and ErrProviderLinked if the account is already linked to
+// another identity from the same provider.
+func (m *Manager) Link(ctx context.Context, username, provider, token string) error {
+	id, err := m.verify(ctx, provider, token)
+	if err != nil {
+		return err
+	}
+	a, err := m.store.GetAccount(ctx, username)
+	if err != nil {
+		return err
+	}
+	links, err := m.store.ListIdentities(ctx, username)
+	if err != nil {
+		return err
+	}
+	for _, l := range links {
+		if l.Provider == id.Provider && l.Subject == id.Subject {
+			return nil
+		}
+		if l.Provider == id.Provider {
+			return ErrProviderLinked
+		}
+	}
+	err = m.store.LinkIdentity(ctx, Link{Provider: id.Provider, Subject: id.Subject, Username: username, LinkedAt: m.now()})
+	if err != nil || !a.Guest {
+		return err
+	}
+	a.Guest = false
+	a.PasswordHash = ""
+	return m.store.UpdateAccount(ctx, a)
+}
+
+// Identities returns the identities linked to an account, oldest first.
+func (m *Manager) Identities(ctx context.Context, username string) ([]Link, error) {
+	return m.store.ListIdentities(ctx, username)
+}
+
+// Unlink removes the account's identity from a provider, so that it can no
+// longer sign in with it. It fails with ErrLastCredential if the account has
+// no password or other identity to log in with instead.
+func (m *Manager) Unlink(ctx context.Context, username, provider string) error {
+	a, err := m.store.GetAccount(ctx, username)
+	if err != nil {
+		return err
+	}
+	links, err := m.store.ListIdentities(ctx, username)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(links, func(l Link) bool { return l.Provider == provider }) {
+		return fmt.Errorf("%w: no %s identity is linked", ErrNotFound, provider)
+	}
+	if a.PasswordHash == "" && len(links) == 1 {
+		return ErrLastCredential
+	}
+	return m.store.UnlinkIdentity(ctx, username, provider)
+}
+
+func (m *Manager) verify(ctx context.Context, provider, token string) (identity.Identity, error) {
+	p, ok := m.providers[provider]
+	if !ok {
+		return identity.Identity{}, fmt.Errorf("%w: %q", ErrUnknownProvider, provider)
+	}
+	return p.Verify(ctx, token)
+}
+
+// createFor creates a password-less account for a new identity, with a
+// username based on its display name.
+func (m *Manager) createFor(ctx context.Context, id identity.Identity) (Account, error) {
+	base := usernameBase(id.Name)
+	for {
+		a := Account{Username: base + "-" + rand.Text()[:6], Role: RolePlayer, CreatedAt: m.now()}
+		err := m.store.CreateAccount(ctx, a)
+		if !errors.Is(err, ErrUsernameTaken) {
+			return a, err
+		}
+	}
+}
+
+// usernameBase returns the longest prefix of name's permitted characters
+// that leaves room for a suffix, or "player" if there are none.
+func usernameBase(name string) string {
+	var b strings.Builder
+	for _, c := range name {
+		if b.Len() == MaxUsernameLen-7 {
+			break
+		}
+		if validUsernameRune(c) {
+			b.WriteRune(c)
+		}
+	}
+	if b.Len() < MinUsernameLen {
+		return "player"
+	}
+	return b.String()
+}
+
+func validUsernameRune(c rune) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_' || c == '-' || c == '.'
+}
+
+// ValidateUsername checks that a username is of a permitted length and made
+// up of letters, digits, '_', '-' and '.'.
+func ValidateUsername(username string) error {
+	if n := len(username); n < MinUsernameLen || n > MaxUsernameLen {
+		return fmt.Errorf("%w: must be %d to %d characters", ErrInvalidUsername, MinUsernameLen, MaxUsernameLen)
+	}
+	for _, c := range username {
+		if !validUsernameRune(c) {
+			return fmt.Errorf("%w: may only contain letters, digits, '_', '-' and '.'", ErrInvalidUsername)
+		}
+	}
+	return nil
+}
+
+// ValidateEmail checks that email is a bare address, such as
+// "alice@example.com".
+func ValidateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Errorf("%w: %q", ErrInvalidEmail, email)
+	}
+	return nil
+}
+
+// ValidatePassword checks that a password is of a permitted length.
+func ValidatePassword(password string) error {
+	if n := utf8.RuneCountInString(password); n < MinPasswordLen || len(password) > MaxPasswordLen {
+		return fmt.Errorf("%w: must be %d to %d characters", ErrInvalidPassword, MinPasswordLen, MaxPasswordLen)
+	}
+	return nil
+}
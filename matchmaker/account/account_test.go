@@ -0,0 +1,188 @@
+package account
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/identity"
+)
+
+var ctx = context.Background()
+
+// Cheap enough to keep tests fast.
+var testParams = Params{Time: 1, Memory: 64, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+func TestRegisterAndLogin(t *testing.T) {
+	m := NewManager(NewMemStore(), WithParams(testParams))
+
+	a, err := m.Register(ctx, "alice", "alice@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.Username, "alice")
+	ExpectThat(t, a.PasswordHash, Not(Eq("correct horse")))
+
+	_, err = m.Register(ctx, "alice", "alice@example.com", "battery staple")
+	ExpectThat(t, err, ErrorIs(ErrUsernameTaken))
+
+	a, err = m.Login(ctx, "alice", "correct horse")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.Username, "alice")
+
+	_, err = m.Login(ctx, "alice", "battery staple")
+	ExpectThat(t, err, ErrorIs(ErrInvalidCredentials))
+	_, err = m.Login(ctx, "bob", "correct horse")
+	ExpectThat(t, err, ErrorIs(ErrInvalidCredentials))
+}
+
+func TestRegister_Validates(t *testing.T) {
+	m := NewManager(NewMemStore(), WithParams(testParams))
+
+	for _, name := range []string{"", "al", "alice smith", "alice!", strings.Repeat("a", MaxUsernameLen+1)} {
+		_, err := m.Register(ctx, name, "alice@example.com", "correct horse")
+		ExpectThat(t, err, ErrorIs(ErrInvalidUsername))
+	}
+	for _, pw := range []string{"", "short", strings.Repeat("a", MaxPasswordLen+1)} {
+		_, err := m.Register(ctx, "alice", "alice@example.com", pw)
+		ExpectThat(t, err, ErrorIs(ErrInvalidPassword))
+	}
+	for _, email := range []string{"", "alice", "Alice <alice@example.com>"} {
+		_, err := m.Register(ctx, "alice", email, "correct horse")
+		ExpectThat(t, err, ErrorIs(ErrInvalidEmail))
+	}
+	_, err := m.Register(ctx, "alice.b-c_1", "alice@example.com", "correct horse")
+	ExpectThat(t, err, Nil())
+}
+
+// fakeProvider accepts tokens of the form "valid:<subject>".
+type fakeProvider string
+
+func (p fakeProvider) Name() string { return string(p) }
+
+func (p fakeProvider) Verify(ctx context.Context, token string) (identity.Identity, error) {
+	sub, ok := strings.CutPrefix(token, "valid:")
+	if !ok {
+		return identity.Identity{}, identity.ErrInvalidToken
+	}
+	return identity.Identity{Provider: string(p), Subject: sub, Name: "Alice Smith"}, nil
+}
+
+func TestSignIn(t *testing.T) {
+	m := NewManager(NewMemStore(), WithParams(testParams), WithProviders(fakeProvider("fake")))
+
+	a, err := m.SignIn(ctx, "fake", "valid:1")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, strings.HasPrefix(a.Username, "AliceSmith-"), true)
+	ExpectThat(t, ValidateUsername(a.Username), Nil())
+
+	// Signing in again finds the same account.
+	again, err := m.SignIn(ctx, "fake", "valid:1")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, again.Username, a.Username)
+
+	// Accounts created this way have no password.
+	_, err = m.Login(ctx, a.Username, "")
+	ExpectThat(t, err, ErrorIs(ErrInvalidCredentials))
+
+	_, err = m.SignIn(ctx, "fake", "bogus")
+	ExpectThat(t, err, ErrorIs(identity.ErrInvalidToken))
+	_, err = m.SignIn(ctx, "google", "valid:1")
+	ExpectThat(t, err, ErrorIs(ErrUnknownProvider))
+}
+
+func TestLink(t *testing.T) {
+	m := NewManager(NewMemStore(), WithParams(testParams), WithProviders(fakeProvider("fake")))
+	_, err := m.Register(ctx, "alice", "alice@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+
+	AssertThat(t, m.Link(ctx, "alice", "fake", "valid:1"), Nil())
+	AssertThat(t, m.Link(ctx, "alice", "fake", "valid:1"), Nil())
+	a, err := m.SignIn(ctx, "fake", "valid:1")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.Username, "alice")
+
+	_, err = m.Register(ctx, "bob", "bob@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, m.Link(ctx, "bob", "fake", "valid:1"), ErrorIs(ErrIdentityLinked))
+	ExpectThat(t, m.Link(ctx, "alice", "fake", "valid:2"), ErrorIs(ErrProviderLinked))
+	ExpectThat(t, m.Link(ctx, "carol", "fake", "valid:2"), ErrorIs(ErrNotFound))
+}
+
+func TestUnlink(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := NewManager(NewMemStore(), WithParams(testParams), WithProviders(fakeProvider("steam"), fakeProvider("google")))
+	m.now = func() time.Time { return now }
+
+	a, err := m.SignIn(ctx, "steam", "valid:1")
+	AssertThat(t, err, Nil())
+	now = now.Add(time.Second)
+	AssertThat(t, m.Link(ctx, a.Username, "google", "valid:2"), Nil())
+	links, err := m.Identities(ctx, a.Username)
+	AssertThat(t, err, Nil())
+	AssertThat(t, links, Len(2))
+	ExpectEq(t, links[0].Provider, "steam")
+	ExpectEq(t, links[1].Provider, "google")
+
+	AssertThat(t, m.Unlink(ctx, a.Username, "steam"), Nil())
+	ExpectThat(t, m.Unlink(ctx, a.Username, "steam"), ErrorIs(ErrNotFound))
+
+	// The last way to log in stays.
+	ExpectThat(t, m.Unlink(ctx, a.Username, "google"), ErrorIs(ErrLastCredential))
+
+	// Accounts with a password can drop every identity.
+	_, err = m.Register(ctx, "alice", "alice@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+	AssertThat(t, m.Link(ctx, "alice", "steam", "valid:3"), Nil())
+	ExpectThat(t, m.Unlink(ctx, "alice", "steam"), Nil())
+}
+
+func TestUsernameBase(t *testing.T) {
+	ExpectEq(t, usernameBase("Alice Smith"), "AliceSmith")
+	ExpectEq(t, usernameBase("李"), "player")
+	ExpectEq(t, usernameBase(""), "player")
+	ExpectEq(t, len(usernameBase(strings.Repeat("a", 40))), MaxUsernameLen-7)
+}
+
+func TestGuest(t *testing.T) {
+	m := NewManager(NewMemStore(), WithParams(testParams))
+	g, token, err := m.CreateGuest(ctx)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, g.Guest, true)
+	ExpectEq(t, strings.HasPrefix(g.Username, "guest-"), true)
+
+	// The device logs in with its token.
+	a, err := m.Login(ctx, g.Username, token)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.Username, g.Username)
+
+	_, err = m.Upgrade(ctx, g.Username, "guest@example.com", "short")
+	ExpectThat(t, err, ErrorIs(ErrInvalidPassword))
+	a, err = m.Upgrade(ctx, g.Username, "guest@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.Username, g.Username)
+	ExpectEq(t, a.Guest, false)
+
+	_, err = m.Login(ctx, g.Username, token)
+	ExpectThat(t, err, ErrorIs(ErrInvalidCredentials))
+	_, err = m.Login(ctx, g.Username, "correct horse")
+	ExpectThat(t, err, Nil())
+
+	_, err = m.Upgrade(ctx, g.Username, "guest@example.com", "battery staple")
+	ExpectThat(t, err, ErrorIs(ErrNotGuest))
+}
+
+func TestGuest_Link(t *testing.T) {
+	m := NewManager(NewMemStore(), WithParams(testParams), WithProviders(fakeProvider("fake")))
+	g, token, err := m.CreateGuest(ctx)
+	AssertThat(t, err, Nil())
+
+	AssertThat(t, m.Link(ctx, g.Username, "fake", "valid:1"), Nil())
+	a, err := m.SignIn(ctx, "fake", "valid:1")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.Username, g.Username)
+	ExpectEq(t, a.Guest, false)
+	_, err = m.Login(ctx, g.Username, token)
+	ExpectThat(t, err, ErrorIs(ErrInvalidCredentials))
+}
@@ -0,0 +1,93 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrBanned = errors.New("account is banned")
+
+// Ban keeps an account from logging in or signing in.
+type Ban struct {
+	Reason string
+
+	// Username of the moderator who banned the account.
+	By string
+
+	At time.Time
+
+	// When the ban ends. Zero if it lasts until the account is unbanned.
+	Until time.Time
+}
+
+// Active reports whether the ban is in force at now. A nil ban never is.
+func (b *Ban) Active(now time.Time) bool {
+	return b != nil && (b.Until.IsZero() || now.Before(b.Until))
+}
+
+// Get returns an account.
+func (m *Manager) Get(ctx context.Context, username string) (Account, error) {
+	return m.store.GetAccount(ctx, username)
+}
+
+// Ban bans an account, until the time given or, if it is zero, until it is
+// unbanned, replacing any ban it has. by is the moderator banning it, who
+// must outrank the account's role so that moderators can't ban each other
+// or admins. Callers should end the account's sessions.
+func (m *Manager) Ban(ctx context.Context, username, by, reason string, until time.Time) (Account, error) {
+	a, err := m.CheckBan(ctx, username, by)
+	if err != nil {
+		return Account{}, err
+	}
+	a.Ban = &Ban{Reason: reason, By: by, At: m.now(), Until: until}
+	if err := m.store.UpdateAccount(ctx, a); err != nil {
+		return Account{}, err
+	}
+	return a, nil
+}
+
+// CheckBan returns the account as it is before being banned, failing with
+// ErrForbidden unless by may ban it.
+func (m *Manager) CheckBan(ctx context.Context, username, by string) (Account, error) {
+	mod, err := m.store.GetAccount(ctx, by)
+	if errors.Is(err, ErrNotFound) {
+		return Account{}, ErrForbidden
+	}
+	if err != nil {
+		return Account{}, err
+	}
+	a, err := m.store.GetAccount(ctx, username)
+	if err != nil {
+		return Account{}, err
+	}
+	if rank(mod.Role) <= rank(a.Role) {
+		return Account{}, fmt.Errorf("%w: %s can't ban %s, who is a %s", ErrForbidden, by, username, a.Role)
+	}
+	return a, nil
+}
+
+// Unban lifts an account's ban, if it has one.
+func (m *Manager) Unban(ctx context.Context, username string) error {
+	a, err := m.store.GetAccount(ctx, username)
+	if err != nil {
+		return err
+	}
+	if a.Ban == nil {
+		return nil
+	}
+	a.Ban = nil
+	return m.store.UpdateAccount(ctx, a)
+}
+
+// checkBan returns ErrBanned if the account's ban is in force.
+func (m *Manager) checkBan(a Account) error {
+	if !a.Ban.Active(m.now()) {
+		return nil
+	}
+	if a.Ban.Until.IsZero() {
+		return fmt.Errorf("%w: %s", ErrBanned, a.Ban.Reason)
+	}
+	return fmt.Errorf("%w until %s: %s", ErrBanned, a.Ban.Until.UTC().Format(time.RFC3339), a.Ban.Reason)
+}
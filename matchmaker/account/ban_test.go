@@ -0,0 +1,71 @@
+package account
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestBan(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := NewManager(NewMemStore(), WithParams(testParams))
+	m.now = func() time.Time { return now }
+	for _, name := range []string{"alice", "bob"} {
+		_, err := m.Register(ctx, name, name+"@example.com", "correct horse")
+		AssertThat(t, err, Nil())
+	}
+	AssertThat(t, m.SetRole(ctx, "alice", RoleModerator), Nil())
+
+	_, err := m.Ban(ctx, "bob", "alice", "collusion", now.Add(time.Hour))
+	AssertThat(t, err, Nil())
+	_, err = m.Login(ctx, "bob", "correct horse")
+	ExpectThat(t, err, ErrorIs(ErrBanned))
+	a, err := m.Get(ctx, "bob")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.Ban.By, "alice")
+	ExpectEq(t, a.Ban.Reason, "collusion")
+
+	// The ban lapses on its own.
+	now = now.Add(time.Hour)
+	_, err = m.Login(ctx, "bob", "correct horse")
+	ExpectThat(t, err, Nil())
+
+	_, err = m.Ban(ctx, "bob", "alice", "collusion, again", time.Time{})
+	AssertThat(t, err, Nil())
+	now = now.Add(365 * 24 * time.Hour)
+	_, err = m.Login(ctx, "bob", "correct horse")
+	ExpectThat(t, err, ErrorIs(ErrBanned))
+
+	AssertThat(t, m.Unban(ctx, "bob"), Nil())
+	_, err = m.Login(ctx, "bob", "correct horse")
+	ExpectThat(t, err, Nil())
+}
+
+func TestBan_Outranked(t *testing.T) {
+	m := NewManager(NewMemStore(), WithParams(testParams))
+	for _, name := range []string{"alice", "bob", "carol"} {
+		_, err := m.Register(ctx, name, name+"@example.com", "correct horse")
+		AssertThat(t, err, Nil())
+	}
+	AssertThat(t, m.SetRole(ctx, "alice", RoleAdmin), Nil())
+	AssertThat(t, m.SetRole(ctx, "bob", RoleModerator), Nil())
+	AssertThat(t, m.SetRole(ctx, "carol", RoleModerator), Nil())
+
+	_, err := m.Ban(ctx, "carol", "bob", "spite", time.Time{})
+	ExpectThat(t, err, ErrorIs(ErrForbidden))
+	_, err = m.Ban(ctx, "alice", "bob", "spite", time.Time{})
+	ExpectThat(t, err, ErrorIs(ErrForbidden))
+	_, err = m.Ban(ctx, "bob", "alice", "abusing the ban hammer", time.Time{})
+	ExpectThat(t, err, Nil())
+
+	_, err = m.Ban(ctx, "dave", "alice", "spam", time.Time{})
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+
+	// CheckBan checks the same without banning.
+	_, err = m.CheckBan(ctx, "carol", "bob")
+	ExpectThat(t, err, ErrorIs(ErrForbidden))
+	a, err := m.CheckBan(ctx, "carol", "alice")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, a.Ban, Nil())
+}
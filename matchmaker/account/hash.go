@@ -0,0 +1,79 @@
+package account
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Params sets the cost of hashing a password with argon2id.
+type Params struct {
+	// Passes over memory.
+	Time uint32
+	// Memory used, in KiB.
+	Memory  uint32
+	Threads uint8
+
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultParams follows the second recommendation of RFC 9106, for
+// environments where memory is constrained.
+var DefaultParams = Params{Time: 3, Memory: 64 * 1024, Threads: 4, SaltLen: 16, KeyLen: 32}
+
+var b64 = base64.RawStdEncoding
+
+// Hash hashes a password with argon2id and a random salt, returning it in
+// the PHC string format, which records the parameters used alongside the
+// salt and key:
+//
+//	$argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+func Hash(password string, p Params) string {
+	salt := make([]byte, p.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads, b64.EncodeToString(salt), b64.EncodeToString(key))
+}
+
+// Verify reports whether password matches a hash returned by Hash. It takes
+// as long to reject a wrong password as it does to accept the right one.
+func Verify(password, hash string) bool {
+	p, salt, key, ok := parseHash(hash)
+	if !ok {
+		return false
+	}
+	got := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	return subtle.ConstantTimeCompare(got, key) == 1
+}
+
+func parseHash(hash string) (p Params, salt, key []byte, ok bool) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return Params{}, nil, nil, false
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Params{}, nil, nil, false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return Params{}, nil, nil, false
+	}
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return Params{}, nil, nil, false
+	}
+	key, err = b64.DecodeString(parts[5])
+	if err != nil || len(key) == 0 || p.Time == 0 || p.Threads == 0 {
+		return Params{}, nil, nil, false
+	}
+	p.SaltLen, p.KeyLen = uint32(len(salt)), uint32(len(key))
+	return p, salt, key, true
+}
@@ -0,0 +1,38 @@
+package account
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestHash(t *testing.T) {
+	h := Hash("hunter22", testParams)
+	ExpectEq(t, strings.HasPrefix(h, "$argon2id$v=19$m=64,t=1,p=1$"), true)
+	ExpectEq(t, Verify("hunter22", h), true)
+	ExpectEq(t, Verify("hunter23", h), false)
+
+	// Salts are random, so the same password hashes differently each time.
+	ExpectThat(t, Hash("hunter22", testParams), Not(Eq(h)))
+
+	// Hashes keep working after the parameters for new ones change.
+	p := testParams
+	p.Time = 2
+	ExpectEq(t, Verify("hunter22", h), true)
+	ExpectEq(t, strings.Contains(Hash("hunter22", p), ",t=2,"), true)
+}
+
+func TestVerify_Malformed(t *testing.T) {
+	for _, h := range []string{
+		"",
+		"hunter22",
+		"$argon2i$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$!!$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$",
+	} {
+		ExpectEq(t, Verify("hunter22", h), false)
+	}
+}
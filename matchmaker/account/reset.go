@@ -0,0 +1,133 @@
+package account
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/mail"
+)
+
+var (
+	ErrResetDisabled = errors.New("password reset is disabled")
+	// ErrInvalidReset is returned for password reset tokens that are
+	// unknown, used or expired.
+	ErrInvalidReset = errors.New("invalid or expired password reset token")
+	ErrNoEmail      = errors.New("account has no email address")
+)
+
+// How long a password reset link works.
+const ResetTTL = time.Hour
+
+// Reset is an outstanding password reset. An account has at most one;
+// requesting another replaces it.
+type Reset struct {
+	Username string
+
+	// SHA-256 of the token, hex encoded.
+	TokenHash string
+
+	SentAt    time.Time
+	ExpiresAt time.Time
+}
+
+// WithPasswordReset lets players who forget their password reset it by
+// following a link sent by sender to their email address. The link is link
+// with the token added as the "token" query parameter.
+func WithPasswordReset(sender mail.Sender, link string) Option {
+	return func(m *Manager) { m.resetter = &mailer{sender: sender, link: link} }
+}
+
+// RequestReset emails a password reset link to each account with the email
+// address. So as not to reveal which addresses have accounts, it succeeds
+// whether or not there are any, and skips accounts sent a link within the
+// last ResendInterval.
+func (m *Manager) RequestReset(ctx context.Context, email string) error {
+	if m.resetter == nil {
+		return ErrResetDisabled
+	}
+	accounts, err := m.store.GetAccountsByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, a := range accounts {
+		errs = append(errs, m.sendReset(ctx, a))
+	}
+	return errors.Join(errs...)
+}
+
+// SendReset emails a password reset link to an account, as RequestReset
+// does, for support staff helping a player who can't ask for one. It fails
+// with ErrNoEmail if the account has no email address.
+func (m *Manager) SendReset(ctx context.Context, username string) error {
+	if m.resetter == nil {
+		return ErrResetDisabled
+	}
+	a, err := m.store.GetAccount(ctx, username)
+	if err != nil {
+		return err
+	}
+	if a.Email == "" {
+		return fmt.Errorf("%w: %s", ErrNoEmail, username)
+	}
+	return m.sendReset(ctx, a)
+}
+
+func (m *Manager) sendReset(ctx context.Context, a Account) error {
+	now := m.now()
+	prev, err := m.store.GetReset(ctx, a.Username)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if err == nil && now.Sub(prev.SentAt) < ResendInterval {
+		return nil
+	}
+
+	token := rand.Text()
+	err = m.store.SaveReset(ctx, Reset{
+		Username:  a.Username,
+		TokenHash: hashToken(token),
+		SentAt:    now,
+		ExpiresAt: now.Add(ResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+	return m.resetter.send(ctx, a.Email, "Reset your snapfold password", token, func(link string) string {
+		return fmt.Sprintf("Hi %s,\n\nFollow this link to choose a new password:\n\n%s\n\nIt expires in %s. If you did not ask to reset your password, you can ignore this email.\n",
+			a.Username, link, ResetTTL)
+	})
+}
+
+// ResetPassword sets a new password for the account a reset token was sent
+// to, and returns it. Since following the link proves the player reads the
+// account's email, it also verifies the email. Callers should end the
+// account's existing sessions.
+func (m *Manager) ResetPassword(ctx context.Context, token, password string) (Account, error) {
+	if err := ValidatePassword(password); err != nil {
+		return Account{}, err
+	}
+	r, err := m.store.TakeReset(ctx, hashToken(token))
+	if errors.Is(err, ErrNotFound) {
+		return Account{}, ErrInvalidReset
+	}
+	if err != nil {
+		return Account{}, err
+	}
+	if !m.now().Before(r.ExpiresAt) {
+		return Account{}, ErrInvalidReset
+	}
+	a, err := m.store.GetAccount(ctx, r.Username)
+	if err != nil {
+		return Account{}, err
+	}
+	a.PasswordHash = Hash(password, m.params)
+	a.EmailVerified = true
+	if err := m.store.UpdateAccount(ctx, a); err != nil {
+		return Account{}, err
+	}
+	return a, nil
+}
@@ -0,0 +1,81 @@
+package account
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestResetPassword(t *testing.T) {
+	var sent outbox
+	now := time.Unix(1000, 0)
+	m := NewManager(NewMemStore(), WithParams(testParams), WithPasswordReset(&sent, "https://snapfold.example/reset"))
+	m.now = func() time.Time { return now }
+	_, err := m.Register(ctx, "alice", "alice@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+
+	// Unknown addresses are not revealed.
+	AssertThat(t, m.RequestReset(ctx, "bob@example.com"), Nil())
+	ExpectThat(t, sent, Empty())
+
+	AssertThat(t, m.RequestReset(ctx, "alice@example.com"), Nil())
+	AssertThat(t, sent, Len(1))
+	ExpectEq(t, sent[0].To, "alice@example.com")
+
+	// Requests are throttled.
+	AssertThat(t, m.RequestReset(ctx, "alice@example.com"), Nil())
+	ExpectThat(t, sent, Len(1))
+
+	_, err = m.ResetPassword(ctx, token(t, sent[0]), "short")
+	ExpectThat(t, err, ErrorIs(ErrInvalidPassword))
+	_, err = m.ResetPassword(ctx, "bogus", "battery staple")
+	ExpectThat(t, err, ErrorIs(ErrInvalidReset))
+
+	a, err := m.ResetPassword(ctx, token(t, sent[0]), "battery staple")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.Username, "alice")
+	_, err = m.Login(ctx, "alice", "correct horse")
+	ExpectThat(t, err, ErrorIs(ErrInvalidCredentials))
+	_, err = m.Login(ctx, "alice", "battery staple")
+	ExpectThat(t, err, Nil())
+
+	// Tokens work once.
+	_, err = m.ResetPassword(ctx, token(t, sent[0]), "tr0ub4dor&3")
+	ExpectThat(t, err, ErrorIs(ErrInvalidReset))
+}
+
+func TestResetPassword_Expires(t *testing.T) {
+	var sent outbox
+	now := time.Unix(1000, 0)
+	m := NewManager(NewMemStore(), WithParams(testParams), WithPasswordReset(&sent, "https://snapfold.example/reset"))
+	m.now = func() time.Time { return now }
+	_, err := m.Register(ctx, "alice", "alice@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+	AssertThat(t, m.RequestReset(ctx, "alice@example.com"), Nil())
+
+	now = now.Add(ResetTTL)
+	_, err = m.ResetPassword(ctx, token(t, sent[0]), "battery staple")
+	ExpectThat(t, err, ErrorIs(ErrInvalidReset))
+}
+
+func TestResetPassword_Disabled(t *testing.T) {
+	m := NewManager(NewMemStore(), WithParams(testParams))
+	ExpectThat(t, m.RequestReset(ctx, "alice@example.com"), ErrorIs(ErrResetDisabled))
+}
+
+func TestSendReset(t *testing.T) {
+	var sent outbox
+	m := NewManager(NewMemStore(), WithParams(testParams), WithPasswordReset(&sent, "https://snapfold.example/reset"))
+	_, err := m.Register(ctx, "alice", "alice@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+	guest, _, err := m.CreateGuest(ctx)
+	AssertThat(t, err, Nil())
+
+	AssertThat(t, m.SendReset(ctx, "alice"), Nil())
+	AssertThat(t, sent, Len(1))
+	ExpectEq(t, sent[0].To, "alice@example.com")
+
+	ExpectThat(t, m.SendReset(ctx, guest.Username), ErrorIs(ErrNoEmail))
+	ExpectThat(t, m.SendReset(ctx, "bob"), ErrorIs(ErrNotFound))
+}
@@ -0,0 +1,73 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrInvalidRole = errors.New("invalid role")
+	ErrForbidden   = errors.New("account lacks the role required")
+)
+
+// Role sets what an account may do. Each role may do everything the roles
+// before it may.
+type Role string
+
+const (
+	RolePlayer    Role = "player"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// roles lists the roles from least to most privileged.
+var roles = []Role{RolePlayer, RoleModerator, RoleAdmin}
+
+// ParseRole returns the role with the name, or ErrInvalidRole.
+func ParseRole(name string) (Role, error) {
+	r := Role(name)
+	if !slices.Contains(roles, r) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidRole, name)
+	}
+	return r, nil
+}
+
+// Includes reports whether r may do everything other may. Unknown roles,
+// including the empty one, are treated as RolePlayer.
+func (r Role) Includes(other Role) bool {
+	return rank(r) >= rank(other)
+}
+
+func rank(r Role) int {
+	return max(slices.Index(roles, r), 0)
+}
+
+// SetRole changes an account's role.
+func (m *Manager) SetRole(ctx context.Context, username string, role Role) error {
+	if _, err := ParseRole(string(role)); err != nil {
+		return err
+	}
+	a, err := m.store.GetAccount(ctx, username)
+	if err != nil {
+		return err
+	}
+	a.Role = role
+	return m.store.UpdateAccount(ctx, a)
+}
+
+// Authorize returns ErrForbidden unless the account's role includes role.
+func (m *Manager) Authorize(ctx context.Context, username string, role Role) error {
+	a, err := m.store.GetAccount(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		return ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if !a.Role.Includes(role) {
+		return fmt.Errorf("%w: %s needs %s", ErrForbidden, username, role)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package account
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestRole(t *testing.T) {
+	ExpectEq(t, RoleAdmin.Includes(RoleModerator), true)
+	ExpectEq(t, RoleModerator.Includes(RoleModerator), true)
+	ExpectEq(t, RoleModerator.Includes(RoleAdmin), false)
+	ExpectEq(t, Role("").Includes(RolePlayer), true)
+	ExpectEq(t, Role("").Includes(RoleModerator), false)
+
+	r, err := ParseRole("moderator")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r, RoleModerator)
+	_, err = ParseRole("root")
+	ExpectThat(t, err, ErrorIs(ErrInvalidRole))
+}
+
+func TestAuthorize(t *testing.T) {
+	m := NewManager(NewMemStore(), WithParams(testParams))
+	a, err := m.Register(ctx, "alice", "alice@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.Role, RolePlayer)
+
+	ExpectThat(t, m.Authorize(ctx, "alice", RolePlayer), Nil())
+	ExpectThat(t, m.Authorize(ctx, "alice", RoleModerator), ErrorIs(ErrForbidden))
+	ExpectThat(t, m.Authorize(ctx, "bob", RolePlayer), ErrorIs(ErrForbidden))
+
+	AssertThat(t, m.SetRole(ctx, "alice", RoleAdmin), Nil())
+	ExpectThat(t, m.Authorize(ctx, "alice", RoleModerator), Nil())
+	ExpectThat(t, m.SetRole(ctx, "alice", "root"), ErrorIs(ErrInvalidRole))
+	ExpectThat(t, m.SetRole(ctx, "bob", RoleAdmin), ErrorIs(ErrNotFound))
+}
@@ -0,0 +1,224 @@
+package account
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+)
+
+// Store persists accounts.
+type Store interface {
+	// CreateAccount adds an account, failing with ErrUsernameTaken if one
+	// with the same username exists.
+	CreateAccount(ctx context.Context, a Account) error
+
+	// GetAccount returns the account with the username, or ErrNotFound.
+	GetAccount(ctx context.Context, username string) (Account, error)
+
+	// UpdateAccount replaces an existing account, or returns ErrNotFound.
+	UpdateAccount(ctx context.Context, a Account) error
+
+	// GetAccountsByEmail returns the accounts with the email address.
+	GetAccountsByEmail(ctx context.Context, email string) ([]Account, error)
+
+	// LinkIdentity links a provider's user to an account, failing with
+	// ErrIdentityLinked if they are already linked to one.
+	LinkIdentity(ctx context.Context, l Link) error
+
+	// GetIdentity returns the username of the account a provider's user is
+	// linked to, or ErrNotFound.
+	GetIdentity(ctx context.Context, provider, subject string) (string, error)
+
+	// ListIdentities returns the links to an account, oldest first.
+	ListIdentities(ctx context.Context, username string) ([]Link, error)
+
+	// UnlinkIdentity removes an account's link to a provider, if it has one.
+	UnlinkIdentity(ctx context.Context, username, provider string) error
+
+	// SaveVerification stores a verification, replacing any other for the
+	// same account.
+	SaveVerification(ctx context.Context, v Verification) error
+
+	// GetVerification returns an account's verification, or ErrNotFound.
+	GetVerification(ctx context.Context, username string) (Verification, error)
+
+	// TakeVerification removes and returns the verification with the token
+	// hash, or returns ErrNotFound.
+	TakeVerification(ctx context.Context, tokenHash string) (Verification, error)
+
+	// SaveReset stores a password reset, replacing any other for the same
+	// account.
+	SaveReset(ctx context.Context, r Reset) error
+
+	// GetReset returns an account's password reset, or ErrNotFound.
+	GetReset(ctx context.Context, username string) (Reset, error)
+
+	// TakeReset removes and returns the password reset with the token hash,
+	// or returns ErrNotFound.
+	TakeReset(ctx context.Context, tokenHash string) (Reset, error)
+}
+
+// MemStore is an in-memory Store, for development and tests.
+type MemStore struct {
+	mu            sync.Mutex
+	accounts      map[string]Account      // username -> account
+	identities    map[[2]string]Link      // provider and subject -> link
+	verifications map[string]Verification // username -> verification
+	resets        map[string]Reset        // username -> reset
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{accounts: map[string]Account{}, identities: map[[2]string]Link{}, verifications: map[string]Verification{}, resets: map[string]Reset{}}
+}
+
+func (s *MemStore) CreateAccount(ctx context.Context, a Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.accounts[a.Username]; ok {
+		return ErrUsernameTaken
+	}
+	s.accounts[a.Username] = a
+	return nil
+}
+
+func (s *MemStore) GetAccount(ctx context.Context, username string) (Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.accounts[username]
+	if !ok {
+		return Account{}, ErrNotFound
+	}
+	return a, nil
+}
+
+func (s *MemStore) UpdateAccount(ctx context.Context, a Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.accounts[a.Username]; !ok {
+		return ErrNotFound
+	}
+	s.accounts[a.Username] = a
+	return nil
+}
+
+func (s *MemStore) GetAccountsByEmail(ctx context.Context, email string) ([]Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var accounts []Account
+	for _, a := range s.accounts {
+		if a.Email == email {
+			accounts = append(accounts, a)
+		}
+	}
+	return accounts, nil
+}
+
+func (s *MemStore) LinkIdentity(ctx context.Context, l Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := [2]string{l.Provider, l.Subject}
+	if _, ok := s.identities[key]; ok {
+		return ErrIdentityLinked
+	}
+	s.identities[key] = l
+	return nil
+}
+
+func (s *MemStore) GetIdentity(ctx context.Context, provider, subject string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.identities[[2]string{provider, subject}]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return l.Username, nil
+}
+
+func (s *MemStore) ListIdentities(ctx context.Context, username string) ([]Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var links []Link
+	for _, l := range s.identities {
+		if l.Username == username {
+			links = append(links, l)
+		}
+	}
+	slices.SortFunc(links, func(a, b Link) int {
+		if c := a.LinkedAt.Compare(b.LinkedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Provider, b.Provider)
+	})
+	return links, nil
+}
+
+func (s *MemStore) UnlinkIdentity(ctx context.Context, username, provider string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, l := range s.identities {
+		if l.Username == username && l.Provider == provider {
+			delete(s.identities, key)
+		}
+	}
+	return nil
+}
+
+func (s *MemStore) SaveVerification(ctx context.Context, v Verification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.verifications[v.Username] = v
+	return nil
+}
+
+func (s *MemStore) GetVerification(ctx context.Context, username string) (Verification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.verifications[username]
+	if !ok {
+		return Verification{}, ErrNotFound
+	}
+	return v, nil
+}
+
+func (s *MemStore) TakeVerification(ctx context.Context, tokenHash string) (Verification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for username, v := range s.verifications {
+		if v.TokenHash == tokenHash {
+			delete(s.verifications, username)
+			return v, nil
+		}
+	}
+	return Verification{}, ErrNotFound
+}
+
+func (s *MemStore) SaveReset(ctx context.Context, r Reset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resets[r.Username] = r
+	return nil
+}
+
+func (s *MemStore) GetReset(ctx context.Context, username string) (Reset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.resets[username]
+	if !ok {
+		return Reset{}, ErrNotFound
+	}
+	return r, nil
+}
+
+func (s *MemStore) TakeReset(ctx context.Context, tokenHash string) (Reset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for username, r := range s.resets {
+		if r.TokenHash == tokenHash {
+			delete(s.resets, username)
+			return r, nil
+		}
+	}
+	return Reset{}, ErrNotFound
+}
@@ -0,0 +1,144 @@
+package account
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/mail"
+)
+
+var (
+	ErrEmailUnverified     = errors.New("email address has not been verified")
+	ErrEmailVerified       = errors.New("email address is already verified")
+	ErrInvalidVerification = errors.New("invalid or expired verification token")
+	ErrResendTooSoon       = errors.New("verification email was sent too recently")
+)
+
+const (
+	// How long a verification link works.
+	VerificationTTL = 24 * time.Hour
+	// How long a player must wait before another verification email is
+	// sent.
+	ResendInterval = time.Minute
+)
+
+// Verification is an outstanding email verification. An account has at most
+// one; sending another replaces it.
+type Verification struct {
+	Username string
+	Email    string
+
+	// SHA-256 of the token, hex encoded, so that the store never holds a
+	// usable one.
+	TokenHash string
+
+	SentAt    time.Time
+	ExpiresAt time.Time
+}
+
+// mailer sends links to a page that passes a token on to the API.
+type mailer struct {
+	sender mail.Sender
+	link   string
+}
+
+// send emails a player a link carrying token, in a message whose body is
+// written around the link by body.
+func (ml *mailer) send(ctx context.Context, to, subject, token string, body func(link string) string) error {
+	link, err := url.Parse(ml.link)
+	if err != nil {
+		return err
+	}
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+	return ml.sender.Send(ctx, mail.Message{To: to, Subject: subject, Body: body(link.String())})
+}
+
+// WithVerification requires players who register with an email address to
+// verify it before they can log in, by following a link sent by sender. The
+// link is link with the token added as the "token" query parameter.
+func WithVerification(sender mail.Sender, link string) Option {
+	return func(m *Manager) { m.verifier = &mailer{sender: sender, link: link} }
+}
+
+// VerifyEmail marks the email of the account a verification token was sent
+// for as verified.
+func (m *Manager) VerifyEmail(ctx context.Context, token string) error {
+	v, err := m.store.TakeVerification(ctx, hashToken(token))
+	if errors.Is(err, ErrNotFound) {
+		return ErrInvalidVerification
+	}
+	if err != nil {
+		return err
+	}
+	if !m.now().Before(v.ExpiresAt) {
+		return ErrInvalidVerification
+	}
+	a, err := m.store.GetAccount(ctx, v.Username)
+	if err != nil {
+		return err
+	}
+	if a.Email != v.Email {
+		// The address changed since the link was sent.
+		return ErrInvalidVerification
+	}
+	a.EmailVerified = true
+	return m.store.UpdateAccount(ctx, a)
+}
+
+// ResendVerification sends a new verification link for an account whose
+// email is unverified, replacing the last one. Only one is sent per
+// ResendInterval.
+func (m *Manager) ResendVerification(ctx context.Context, username, password string) error {
+	a, err := m.checkPassword(ctx, username, password)
+	if err != nil {
+		return err
+	}
+	if m.verifier == nil || a.Email == "" || a.EmailVerified {
+		return ErrEmailVerified
+	}
+	v, err := m.store.GetVerification(ctx, username)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if err == nil && m.now().Sub(v.SentAt) < ResendInterval {
+		return fmt.Errorf("%w: try again in %s", ErrResendTooSoon, (ResendInterval - m.now().Sub(v.SentAt)).Round(time.Second))
+	}
+	return m.sendVerification(ctx, a)
+}
+
+// sendVerification emails a verification link to the account, if
+// verification is enabled.
+func (m *Manager) sendVerification(ctx context.Context, a Account) error {
+	if m.verifier == nil || a.Email == "" || a.EmailVerified {
+		return nil
+	}
+	token := rand.Text()
+	now := m.now()
+	err := m.store.SaveVerification(ctx, Verification{
+		Username:  a.Username,
+		Email:     a.Email,
+		TokenHash: hashToken(token),
+		SentAt:    now,
+		ExpiresAt: now.Add(VerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+	return m.verifier.send(ctx, a.Email, "Verify your snapfold account", token, func(link string) string {
+		return fmt.Sprintf("Hi %s,\n\nFollow this link to verify your email address:\n\n%s\n\nIt expires in %s. If you did not create a snapfold account, you can ignore this email.\n",
+			a.Username, link, VerificationTTL)
+	})
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,75 @@
+package account
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/mail"
+)
+
+// outbox records the messages sent through it.
+type outbox []mail.Message
+
+func (o *outbox) Send(ctx context.Context, msg mail.Message) error {
+	*o = append(*o, msg)
+	return nil
+}
+
+var linkRE = regexp.MustCompile(`https://\S+`)
+
+// token returns the verification token from the link in msg.
+func token(t *testing.T, msg mail.Message) string {
+	t.Helper()
+	u, err := url.Parse(linkRE.FindString(msg.Body))
+	AssertThat(t, err, Nil())
+	return u.Query().Get("token")
+}
+
+func TestVerifyEmail(t *testing.T) {
+	var sent outbox
+	now := time.Unix(1000, 0)
+	m := NewManager(NewMemStore(), WithParams(testParams), WithVerification(&sent, "https://snapfold.example/verify?from=email"))
+	m.now = func() time.Time { return now }
+
+	_, err := m.Register(ctx, "alice", "alice@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+	AssertThat(t, sent, Len(1))
+	ExpectEq(t, sent[0].To, "alice@example.com")
+
+	_, err = m.Login(ctx, "alice", "correct horse")
+	ExpectThat(t, err, ErrorIs(ErrEmailUnverified))
+
+	// Resending is throttled, and replaces the earlier link.
+	ExpectThat(t, m.ResendVerification(ctx, "alice", "battery staple"), ErrorIs(ErrInvalidCredentials))
+	ExpectThat(t, m.ResendVerification(ctx, "alice", "correct horse"), ErrorIs(ErrResendTooSoon))
+	now = now.Add(ResendInterval)
+	AssertThat(t, m.ResendVerification(ctx, "alice", "correct horse"), Nil())
+	AssertThat(t, sent, Len(2))
+	ExpectThat(t, m.VerifyEmail(ctx, token(t, sent[0])), ErrorIs(ErrInvalidVerification))
+
+	AssertThat(t, m.VerifyEmail(ctx, token(t, sent[1])), Nil())
+	a, err := m.Login(ctx, "alice", "correct horse")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.EmailVerified, true)
+
+	// Links work once.
+	ExpectThat(t, m.VerifyEmail(ctx, token(t, sent[1])), ErrorIs(ErrInvalidVerification))
+	ExpectThat(t, m.ResendVerification(ctx, "alice", "correct horse"), ErrorIs(ErrEmailVerified))
+}
+
+func TestVerifyEmail_Expires(t *testing.T) {
+	var sent outbox
+	now := time.Unix(1000, 0)
+	m := NewManager(NewMemStore(), WithParams(testParams), WithVerification(&sent, "https://snapfold.example/verify"))
+	m.now = func() time.Time { return now }
+
+	_, err := m.Register(ctx, "alice", "alice@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+	now = now.Add(VerificationTTL)
+	ExpectThat(t, m.VerifyEmail(ctx, token(t, sent[0])), ErrorIs(ErrInvalidVerification))
+}
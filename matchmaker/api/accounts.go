@@ -0,0 +1,452 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+type registerRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type registerResponse struct {
+	PlayerID string `json:"player_id"`
+}
+
+// handleRegister creates an account. The player logs in separately, after
+// verifying their email if verification is enabled.
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		writeError(w, http.StatusNotFound, "accounts are disabled")
+		return
+	}
+	var req registerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	a, err := s.accounts.Register(r.Context(), req.Username, req.Email, req.Password)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, registerResponse{PlayerID: a.Username})
+}
+
+type verifyRequest struct {
+	Token string `json:"token"`
+}
+
+// handleVerifyEmail verifies an account's email with the token from the
+// link sent to it.
+func (s *Server) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		writeError(w, http.StatusNotFound, "accounts are disabled")
+		return
+	}
+	var req verifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if err := s.accounts.VerifyEmail(r.Context(), req.Token); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleResendVerification sends a new verification link. Players cannot
+// log in until they verify, so it takes their username and password.
+func (s *Server) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		writeError(w, http.StatusNotFound, "accounts are disabled")
+		return
+	}
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if err := s.accounts.ResendVerification(r.Context(), req.Username, req.Password); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// handleForgotPassword emails a password reset link to the accounts with an
+// email address. It replies the same whether or not there are any.
+func (s *Server) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		writeError(w, http.StatusNotFound, "accounts are disabled")
+		return
+	}
+	var req forgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if err := s.accounts.RequestReset(r.Context(), req.Email); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// handleResetPassword sets a new password with the token from a reset link,
+// and logs the account out everywhere.
+func (s *Server) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		writeError(w, http.StatusNotFound, "accounts are disabled")
+		return
+	}
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	a, err := s.accounts.ResetPassword(r.Context(), req.Token, req.Password)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.sessions.LogoutAll(r.Context(), a.Username); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type guestResponse struct {
+	loginResponse
+
+	// Logs in to the guest account in place of a password. The device
+	// should keep it, along with player_id, until the guest is upgraded.
+	DeviceToken string `json:"device_token"`
+}
+
+// handleCreateGuest creates a guest account bound to the calling device and
+// logs it in, so that players can queue without registering.
+func (s *Server) handleCreateGuest(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		writeError(w, http.StatusNotFound, "accounts are disabled")
+		return
+	}
+	a, deviceToken, err := s.accounts.CreateGuest(r.Context())
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	tokens, err := s.sessions.Login(r.Context(), a.Username)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, guestResponse{loginResponse: newLoginResponse(tokens), DeviceToken: deviceToken})
+}
+
+type upgradeRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// handleUpgrade gives the caller's guest account a password, making it a
+// full account. Its player ID, and so its ratings and match history, stay
+// the same.
+func (s *Server) handleUpgrade(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		writeError(w, http.StatusNotFound, "accounts are disabled")
+		return
+	}
+	playerID, _ := session.PlayerFrom(r.Context())
+	var req upgradeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if _, err := s.accounts.Upgrade(r.Context(), playerID, req.Email, req.Password); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	// Access token, presented as a bearer token on other requests.
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Exchanged at /v1/sessions/refresh for new tokens before Token
+	// expires. Each refresh token may be used once.
+	RefreshToken string `json:"refresh_token"`
+
+	PlayerID string `json:"player_id"`
+}
+
+func newLoginResponse(tokens session.Tokens) loginResponse {
+	return loginResponse{
+		Token:        tokens.Access,
+		ExpiresAt:    tokens.ExpiresAt,
+		RefreshToken: tokens.Refresh,
+		PlayerID:     tokens.PlayerID,
+	}
+}
+
+// handleLogin issues session tokens in exchange for a username and
+// password, or a guest's player ID and device token. If accounts are
+// disabled, any non-empty username is accepted and no password is needed.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if req.Username == "" {
+		writeError(w, http.StatusBadRequest, "username is required")
+		return
+	}
+
+	playerID := req.Username
+	if s.accounts != nil {
+		a, err := s.accounts.Login(r.Context(), req.Username, req.Password)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		playerID = a.Username
+	}
+	tokens, err := s.sessions.Login(r.Context(), playerID)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newLoginResponse(tokens))
+}
+
+type signInRequest struct {
+	// Token obtained from the provider: an ID token from Google or Apple,
+	// or a hex encoded session ticket from Steam.
+	Token string `json:"token"`
+}
+
+// handleSignIn issues session tokens in exchange for a token from an
+// identity provider, creating an account the first time a player signs in.
+func (s *Server) handleSignIn(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		writeError(w, http.StatusNotFound, "accounts are disabled")
+		return
+	}
+	var req signInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	a, err := s.accounts.SignIn(r.Context(), r.PathValue("provider"), req.Token)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	tokens, err := s.sessions.Login(r.Context(), a.Username)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newLoginResponse(tokens))
+}
+
+// handleLinkIdentity links an identity provider's user to the caller's
+// account, so that they can sign in with it.
+func (s *Server) handleLinkIdentity(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		writeError(w, http.StatusNotFound, "accounts are disabled")
+		return
+	}
+	playerID, _ := session.PlayerFrom(r.Context())
+	var req signInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if err := s.accounts.Link(r.Context(), playerID, r.PathValue("provider"), req.Token); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type identityResponse struct {
+	Provider string    `json:"provider"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+type listIdentitiesResponse struct {
+	Identities []identityResponse `json:"identities"`
+}
+
+// handleListIdentities lists the identity providers linked to the caller's
+// account.
+func (s *Server) handleListIdentities(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		writeError(w, http.StatusNotFound, "accounts are disabled")
+		return
+	}
+	playerID, _ := session.PlayerFrom(r.Context())
+	links, err := s.accounts.Identities(r.Context(), playerID)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	resp := listIdentitiesResponse{Identities: []identityResponse{}}
+	for _, l := range links {
+		resp.Identities = append(resp.Identities, identityResponse{Provider: l.Provider, LinkedAt: l.LinkedAt})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleUnlinkIdentity unlinks an identity provider from the caller's
+// account, unless it is their only way to log in.
+func (s *Server) handleUnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	if s.accounts == nil {
+		writeError(w, http.StatusNotFound, "accounts are disabled")
+		return
+	}
+	playerID, _ := session.PlayerFrom(r.Context())
+	if err := s.accounts.Unlink(r.Context(), playerID, r.PathValue("provider")); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// handleRefresh exchanges a refresh token for new tokens. The old refresh
+// token stops working.
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	tokens, err := s.sessions.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newLoginResponse(tokens))
+}
+
+// handleLogout ends the session a refresh token belongs to.
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if err := s.sessions.Logout(r.Context(), req.RefreshToken); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type sessionResponse struct {
+	ID          string    `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	RefreshedAt time.Time `json:"refreshed_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+
+	// Whether this is the session the request was made with.
+	Current bool `json:"current"`
+}
+
+type listSessionsResponse struct {
+	Sessions []sessionResponse `json:"sessions"`
+}
+
+// handleListSessions lists the caller's active sessions.
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	current, _ := session.SessionFrom(r.Context())
+	sessions, err := s.sessions.Sessions(r.Context(), playerID)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	resp := listSessionsResponse{Sessions: []sessionResponse{}}
+	for _, sess := range sessions {
+		resp.Sessions = append(resp.Sessions, sessionResponse{
+			ID:          sess.ID,
+			CreatedAt:   sess.CreatedAt,
+			RefreshedAt: sess.RefreshedAt,
+			ExpiresAt:   sess.ExpiresAt,
+			Current:     sess.ID == current,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleRevokeSession ends one of the caller's sessions, closing any streams
+// it holds.
+func (s *Server) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	if err := s.sessions.Revoke(r.Context(), playerID, r.PathValue("id")); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type revokedSessionsResponse struct {
+	SessionIDs []string `json:"session_ids"`
+}
+
+// handleRevokedSessions lists the sessions revoked at or after the since
+// query parameter, an RFC 3339 time, so that game servers can stop
+// accepting their access tokens and end their streams.
+func (s *Server) handleRevokedSessions(w http.ResponseWriter, r *http.Request) {
+	since, err := time.Parse(time.RFC3339Nano, r.URL.Query().Get("since"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "since must be an RFC 3339 time")
+		return
+	}
+	ids, err := s.sessions.RevokedSessions(r.Context(), since)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, revokedSessionsResponse{SessionIDs: append([]string{}, ids...)})
+}
+
+// handleRevokeAllSessions logs the caller out everywhere, including the
+// session the request was made with.
+func (s *Server) handleRevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	if err := s.sessions.LogoutAll(r.Context(), playerID); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/identity"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/mail"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+// Cheap enough to keep tests fast.
+var testParams = account.Params{Time: 1, Memory: 64, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+func TestRegisterAndLogin(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore(), Accounts: accounts})
+
+	rec := do(t, s, "POST", "/v1/accounts", "", `{"username": "alice", "email": "alice@example.com", "password": "correct horse"}`)
+	AssertEq(t, rec.Code, http.StatusCreated)
+	var reg registerResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&reg), Nil())
+	ExpectEq(t, reg.PlayerID, "alice")
+
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts", "", `{"username": "alice", "email": "alice@example.com", "password": "battery staple"}`).Code, http.StatusConflict)
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts", "", `{"username": "bob", "email": "bob@example.com", "password": "short"}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts", "", `{"username": "b", "email": "b@example.com", "password": "correct horse"}`).Code, http.StatusBadRequest)
+
+	ExpectEq(t, do(t, s, "POST", "/v1/login", "", `{"username": "alice", "password": "battery staple"}`).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", "/v1/login", "", `{"username": "bob", "password": "correct horse"}`).Code, http.StatusUnauthorized)
+
+	rec = do(t, s, "POST", "/v1/login", "", `{"username": "alice", "password": "correct horse"}`)
+	AssertEq(t, rec.Code, http.StatusOK)
+	var resp loginResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectEq(t, resp.PlayerID, "alice")
+	ExpectEq(t, do(t, s, "POST", "/v1/tickets", resp.Token, `{"game_mode": "holdem"}`).Code, http.StatusCreated)
+}
+
+func TestRegister_Disabled(t *testing.T) {
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore()})
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts", "", `{"username": "alice", "email": "alice@example.com", "password": "correct horse"}`).Code, http.StatusNotFound)
+}
+
+func TestRefreshAndLogout(t *testing.T) {
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore()})
+	rec := do(t, s, "POST", "/v1/login", "", `{"username": "alice"}`)
+	AssertEq(t, rec.Code, http.StatusOK)
+	var first loginResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&first), Nil())
+	ExpectThat(t, first.RefreshToken, Not(Eq("")))
+
+	rec = do(t, s, "POST", "/v1/sessions/refresh", "", `{"refresh_token": "`+first.RefreshToken+`"}`)
+	AssertEq(t, rec.Code, http.StatusOK)
+	var second loginResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&second), Nil())
+	ExpectEq(t, second.PlayerID, "alice")
+	ExpectEq(t, do(t, s, "POST", "/v1/tickets", second.Token, `{"game_mode": "holdem"}`).Code, http.StatusCreated)
+
+	// Refresh tokens are single use.
+	ExpectEq(t, do(t, s, "POST", "/v1/sessions/refresh", "", `{"refresh_token": "`+first.RefreshToken+`"}`).Code, http.StatusUnauthorized)
+
+	rec = do(t, s, "POST", "/v1/login", "", `{"username": "alice"}`)
+	var third loginResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&third), Nil())
+	ExpectEq(t, do(t, s, "POST", "/v1/logout", "", `{"refresh_token": "`+third.RefreshToken+`"}`).Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "POST", "/v1/sessions/refresh", "", `{"refresh_token": "`+third.RefreshToken+`"}`).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", "/v1/logout", "", `{"refresh_token": "bogus"}`).Code, http.StatusUnauthorized)
+}
+
+func TestSessions(t *testing.T) {
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore()})
+	var phone, laptop loginResponse
+	for _, resp := range []*loginResponse{&phone, &laptop} {
+		rec := do(t, s, "POST", "/v1/login", "", `{"username": "alice"}`)
+		AssertEq(t, rec.Code, http.StatusOK)
+		AssertThat(t, json.NewDecoder(rec.Body).Decode(resp), Nil())
+	}
+	bob := login(t, s, "bob")
+
+	rec := do(t, s, "GET", "/v1/sessions", laptop.Token, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var list listSessionsResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&list), Nil())
+	AssertThat(t, list.Sessions, Len(2))
+	ExpectEq(t, list.Sessions[0].Current, false)
+	ExpectEq(t, list.Sessions[1].Current, true)
+	phoneID := list.Sessions[0].ID
+
+	ExpectEq(t, do(t, s, "DELETE", "/v1/sessions/"+phoneID, bob, "").Code, http.StatusNotFound)
+	ExpectEq(t, do(t, s, "DELETE", "/v1/sessions/"+phoneID, laptop.Token, "").Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "GET", "/v1/sessions", phone.Token, "").Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", "/v1/sessions/refresh", "", `{"refresh_token": "`+phone.RefreshToken+`"}`).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "GET", "/v1/sessions", laptop.Token, "").Code, http.StatusOK)
+
+	ExpectEq(t, do(t, s, "DELETE", "/v1/sessions", laptop.Token, "").Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "GET", "/v1/sessions", laptop.Token, "").Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "GET", "/v1/sessions", bob, "").Code, http.StatusOK)
+}
+
+func TestRevokedSessions(t *testing.T) {
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore(), InternalToken: "secret"})
+	start := time.Now().Add(-time.Second)
+	alice := login(t, s, "alice")
+	rec := do(t, s, "GET", "/v1/sessions", alice, "")
+	var list listSessionsResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&list), Nil())
+	AssertThat(t, list.Sessions, Len(1))
+	AssertEq(t, do(t, s, "DELETE", "/v1/sessions", alice, "").Code, http.StatusNoContent)
+
+	since := url.QueryEscape(start.Format(time.RFC3339Nano))
+	ExpectEq(t, do(t, s, "GET", "/v1/sessions/revoked?since="+since, "", "").Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "GET", "/v1/sessions/revoked?since=yesterday", "secret", "").Code, http.StatusBadRequest)
+	rec = do(t, s, "GET", "/v1/sessions/revoked?since="+since, "secret", "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var resp revokedSessionsResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectThat(t, resp.SessionIDs, ElementsAre(list.Sessions[0].ID))
+
+	later := url.QueryEscape(time.Now().Add(time.Minute).Format(time.RFC3339Nano))
+	rec = do(t, s, "GET", "/v1/sessions/revoked?since="+later, "secret", "")
+	resp = revokedSessionsResponse{}
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectThat(t, resp.SessionIDs, Empty())
+}
+
+// fakeProvider accepts tokens of the form "valid:<subject>".
+type fakeProvider string
+
+func (p fakeProvider) Name() string { return string(p) }
+
+func (p fakeProvider) Verify(ctx context.Context, token string) (identity.Identity, error) {
+	sub, ok := strings.CutPrefix(token, "valid:")
+	if !ok {
+		return identity.Identity{}, identity.ErrInvalidToken
+	}
+	return identity.Identity{Provider: string(p), Subject: sub}, nil
+}
+
+func TestSignIn(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams), account.WithProviders(fakeProvider("fake")))
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore(), Accounts: accounts})
+
+	rec := do(t, s, "POST", "/v1/login/fake", "", `{"token": "valid:1"}`)
+	AssertEq(t, rec.Code, http.StatusOK)
+	var first loginResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&first), Nil())
+	ExpectEq(t, strings.HasPrefix(first.PlayerID, "player-"), true)
+
+	rec = do(t, s, "POST", "/v1/login/fake", "", `{"token": "valid:1"}`)
+	AssertEq(t, rec.Code, http.StatusOK)
+	var second loginResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&second), Nil())
+	ExpectEq(t, second.PlayerID, first.PlayerID)
+
+	ExpectEq(t, do(t, s, "POST", "/v1/login/fake", "", `{"token": "bogus"}`).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", "/v1/login/google", "", `{"token": "valid:1"}`).Code, http.StatusNotFound)
+
+	// A player with a password can link an identity and then sign in with it.
+	AssertEq(t, do(t, s, "POST", "/v1/accounts", "", `{"username": "alice", "email": "alice@example.com", "password": "correct horse"}`).Code, http.StatusCreated)
+	rec = do(t, s, "POST", "/v1/login", "", `{"username": "alice", "password": "correct horse"}`)
+	AssertEq(t, rec.Code, http.StatusOK)
+	var alice loginResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&alice), Nil())
+	ExpectEq(t, do(t, s, "PUT", "/v1/identities/fake", alice.Token, `{"token": "valid:2"}`).Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "PUT", "/v1/identities/fake", alice.Token, `{"token": "valid:1"}`).Code, http.StatusConflict)
+	ExpectEq(t, do(t, s, "PUT", "/v1/identities/fake", alice.Token, `{"token": "valid:3"}`).Code, http.StatusConflict)
+	rec = do(t, s, "POST", "/v1/login/fake", "", `{"token": "valid:2"}`)
+	AssertEq(t, rec.Code, http.StatusOK)
+	var third loginResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&third), Nil())
+	ExpectEq(t, third.PlayerID, "alice")
+}
+
+func TestGuest(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore(), Accounts: accounts})
+
+	rec := do(t, s, "POST", "/v1/guests", "", "")
+	AssertEq(t, rec.Code, http.StatusCreated)
+	var guest guestResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&guest), Nil())
+	ExpectThat(t, guest.DeviceToken, Not(Eq("")))
+	ExpectEq(t, do(t, s, "POST", "/v1/tickets", guest.Token, `{"game_mode": "holdem"}`).Code, http.StatusCreated)
+
+	// The device can log back in.
+	ExpectEq(t, do(t, s, "POST", "/v1/login", "", `{"username": "`+guest.PlayerID+`", "password": "`+guest.DeviceToken+`"}`).Code, http.StatusOK)
+
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts/upgrade", guest.Token, `{"email": "guest@example.com", "password": "short"}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts/upgrade", guest.Token, `{"email": "guest@example.com", "password": "correct horse"}`).Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts/upgrade", guest.Token, `{"email": "guest@example.com", "password": "correct horse"}`).Code, http.StatusConflict)
+
+	rec = do(t, s, "POST", "/v1/login", "", `{"username": "`+guest.PlayerID+`", "password": "correct horse"}`)
+	AssertEq(t, rec.Code, http.StatusOK)
+	var resp loginResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectEq(t, resp.PlayerID, guest.PlayerID)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	var sent strings.Builder
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams), account.WithVerification(mail.NewLog(&sent), "https://snapfold.example/verify"))
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore(), Accounts: accounts})
+
+	AssertEq(t, do(t, s, "POST", "/v1/accounts", "", `{"username": "alice", "email": "alice@example.com", "password": "correct horse"}`).Code, http.StatusCreated)
+	ExpectEq(t, do(t, s, "POST", "/v1/login", "", `{"username": "alice", "password": "correct horse"}`).Code, http.StatusForbidden)
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts/verify/resend", "", `{"username": "alice", "password": "correct horse"}`).Code, http.StatusTooManyRequests)
+
+	_, token, ok := strings.Cut(sent.String(), "?token=")
+	AssertEq(t, ok, true)
+	token, _, _ = strings.Cut(token, "\n")
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts/verify", "", `{"token": "bogus"}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts/verify", "", `{"token": "`+token+`"}`).Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "POST", "/v1/login", "", `{"username": "alice", "password": "correct horse"}`).Code, http.StatusOK)
+}
+
+func TestResetPassword(t *testing.T) {
+	var sent strings.Builder
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams), account.WithPasswordReset(mail.NewLog(&sent), "https://snapfold.example/reset"))
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore(), Accounts: accounts})
+	AssertEq(t, do(t, s, "POST", "/v1/accounts", "", `{"username": "alice", "email": "alice@example.com", "password": "correct horse"}`).Code, http.StatusCreated)
+	rec := do(t, s, "POST", "/v1/login", "", `{"username": "alice", "password": "correct horse"}`)
+	AssertEq(t, rec.Code, http.StatusOK)
+	var before loginResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&before), Nil())
+
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts/forgot-password", "", `{"email": "bob@example.com"}`).Code, http.StatusNoContent)
+	ExpectEq(t, sent.Len(), 0)
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts/forgot-password", "", `{"email": "alice@example.com"}`).Code, http.StatusNoContent)
+	_, token, ok := strings.Cut(sent.String(), "?token=")
+	AssertEq(t, ok, true)
+	token, _, _ = strings.Cut(token, "\n")
+
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts/reset-password", "", `{"token": "bogus", "password": "battery staple"}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "POST", "/v1/accounts/reset-password", "", `{"token": "`+token+`", "password": "battery staple"}`).Code, http.StatusNoContent)
+
+	// Existing sessions end.
+	ExpectEq(t, do(t, s, "POST", "/v1/sessions/refresh", "", `{"refresh_token": "`+before.RefreshToken+`"}`).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", "/v1/login", "", `{"username": "alice", "password": "correct horse"}`).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", "/v1/login", "", `{"username": "alice", "password": "battery staple"}`).Code, http.StatusOK)
+}
+
+func TestUnlinkIdentity(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams), account.WithProviders(fakeProvider("steam"), fakeProvider("google")))
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore(), Accounts: accounts})
+	rec := do(t, s, "POST", "/v1/login/steam", "", `{"token": "valid:1"}`)
+	AssertEq(t, rec.Code, http.StatusOK)
+	var login loginResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&login), Nil())
+
+	ExpectEq(t, do(t, s, "PUT", "/v1/identities/google", login.Token, `{"token": "valid:2"}`).Code, http.StatusNoContent)
+	rec = do(t, s, "GET", "/v1/identities", login.Token, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var resp listIdentitiesResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectThat(t, resp.Identities, Len(2))
+
+	ExpectEq(t, do(t, s, "DELETE", "/v1/identities/steam", login.Token, "").Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "DELETE", "/v1/identities/steam", login.Token, "").Code, http.StatusNotFound)
+	ExpectEq(t, do(t, s, "DELETE", "/v1/identities/google", login.Token, "").Code, http.StatusConflict)
+}
@@ -0,0 +1,330 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/apikey"
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+type setRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// handleSetRole changes another account's role.
+func (s *Server) handleSetRole(w http.ResponseWriter, r *http.Request) {
+	var req setRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	role, err := account.ParseRole(req.Role)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	a, err := s.accounts.Get(r.Context(), r.PathValue("username"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.record(r, audit.RoleSet, a.Username, a.Role, role); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.accounts.SetRole(r.Context(), a.Username, role); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type banResponse struct {
+	Reason string     `json:"reason"`
+	By     string     `json:"by"`
+	At     time.Time  `json:"at"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+// newBanResponse describes a ban, or returns nil if it isn't in force.
+func newBanResponse(b *account.Ban) *banResponse {
+	if !b.Active(time.Now()) {
+		return nil
+	}
+	resp := &banResponse{Reason: b.Reason, By: b.By, At: b.At}
+	if !b.Until.IsZero() {
+		resp.Until = &b.Until
+	}
+	return resp
+}
+
+type accountResponse struct {
+	Username      string    `json:"username"`
+	Email         string    `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	Guest         bool      `json:"guest"`
+	Role          string    `json:"role"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	// The account's ban, if one is in force.
+	Ban *banResponse `json:"ban,omitempty"`
+
+	// Identity providers the account can sign in with.
+	Identities []string `json:"identities"`
+
+	// Number of active sessions.
+	Sessions int `json:"sessions"`
+}
+
+// handleGetAccount describes an account for support staff.
+func (s *Server) handleGetAccount(w http.ResponseWriter, r *http.Request) {
+	a, err := s.accounts.Get(r.Context(), r.PathValue("username"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	links, err := s.accounts.Identities(r.Context(), a.Username)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	sessions, err := s.sessions.Sessions(r.Context(), a.Username)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	resp := accountResponse{
+		Username:      a.Username,
+		Email:         a.Email,
+		EmailVerified: a.EmailVerified,
+		Guest:         a.Guest,
+		Role:          string(a.Role),
+		CreatedAt:     a.CreatedAt,
+		Identities:    []string{},
+		Sessions:      len(sessions),
+		Ban:           newBanResponse(a.Ban),
+	}
+	for _, l := range links {
+		resp.Identities = append(resp.Identities, l.Provider)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type banRequest struct {
+	Reason string `json:"reason"`
+
+	// When the ban ends. Unset bans last until the account is unbanned.
+	Until *time.Time `json:"until"`
+}
+
+// handleBan bans an account, ends its sessions and tells webhooks.
+func (s *Server) handleBan(w http.ResponseWriter, r *http.Request) {
+	var req banRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if req.Reason == "" {
+		writeError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+	var until time.Time
+	if req.Until != nil {
+		if !req.Until.After(time.Now()) {
+			writeError(w, http.StatusBadRequest, "until must be in the future")
+			return
+		}
+		until = *req.Until
+	}
+	moderator, _ := session.PlayerFrom(r.Context())
+	before, err := s.accounts.CheckBan(r.Context(), r.PathValue("username"), moderator)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	ban := &account.Ban{Reason: req.Reason, By: moderator, At: time.Now(), Until: until}
+	if err := s.record(r, audit.AccountBanned, before.Username, newBanResponse(before.Ban), newBanResponse(ban)); err != nil {
+		writeErr(w, err)
+		return
+	}
+	a, err := s.accounts.Ban(r.Context(), before.Username, moderator, req.Reason, until)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.sessions.LogoutAll(r.Context(), a.Username); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if s.webhooks != nil {
+		if err := s.webhooks.PublishPlayerBanned(r.Context(), a); err != nil {
+			writeErr(w, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleUnban lifts an account's ban.
+func (s *Server) handleUnban(w http.ResponseWriter, r *http.Request) {
+	a, err := s.accounts.Get(r.Context(), r.PathValue("username"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.record(r, audit.AccountUnbanned, a.Username, newBanResponse(a.Ban), nil); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.accounts.Unban(r.Context(), a.Username); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSendReset emails an account a password reset link.
+func (s *Server) handleSendReset(w http.ResponseWriter, r *http.Request) {
+	a, err := s.accounts.Get(r.Context(), r.PathValue("username"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.record(r, audit.PasswordResetSent, a.Username, nil, nil); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.accounts.SendReset(r.Context(), a.Username); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type issueAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type apiKeyResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func newAPIKeyResponse(k apikey.Key) apiKeyResponse {
+	resp := apiKeyResponse{ID: k.ID, Name: k.Name, Scopes: []string{}, CreatedAt: k.CreatedAt}
+	for _, s := range k.Scopes {
+		resp.Scopes = append(resp.Scopes, string(s))
+	}
+	if k.Revoked() {
+		resp.RevokedAt = &k.RevokedAt
+	}
+	return resp
+}
+
+type issueAPIKeyResponse struct {
+	apiKeyResponse
+
+	// Presented as a bearer token by the key's holder. It is only ever
+	// shown here.
+	Token string `json:"token"`
+}
+
+// handleIssueAPIKey issues an API key for another service.
+func (s *Server) handleIssueAPIKey(w http.ResponseWriter, r *http.Request) {
+	if s.apiKeys == nil {
+		writeError(w, http.StatusNotFound, "api keys are disabled")
+		return
+	}
+	var req issueAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	var scopes []apikey.Scope
+	for _, name := range req.Scopes {
+		scope, err := apikey.ParseScope(name)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		scopes = append(scopes, scope)
+	}
+	k, token, err := s.apiKeys.NewKey(req.Name, scopes...)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.record(r, audit.APIKeyIssued, k.ID, nil, newAPIKeyResponse(k)); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.apiKeys.Save(r.Context(), k); err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, issueAPIKeyResponse{apiKeyResponse: newAPIKeyResponse(k), Token: token})
+}
+
+type listAPIKeysResponse struct {
+	Keys          []apiKeyResponse `json:"keys"`
+	NextPageToken string           `json:"next_page_token,omitempty"`
+}
+
+// handleListAPIKeys lists every API key, including revoked ones, oldest
+// first unless sorted by -created_at. The page_size, page_token and sort
+// query parameters page through them.
+func (s *Server) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if s.apiKeys == nil {
+		writeError(w, http.StatusNotFound, "api keys are disabled")
+		return
+	}
+	req, err := apikey.Listing.FromQuery(r.URL.Query())
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	p, err := s.apiKeys.ListPage(r.Context(), req)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	resp := listAPIKeysResponse{Keys: []apiKeyResponse{}, NextPageToken: p.NextToken}
+	for _, k := range p.Items {
+		resp.Keys = append(resp.Keys, newAPIKeyResponse(k))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleRevokeAPIKey stops an API key from working.
+func (s *Server) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if s.apiKeys == nil {
+		writeError(w, http.StatusNotFound, "api keys are disabled")
+		return
+	}
+	k, err := s.apiKeys.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.record(r, audit.APIKeyRevoked, k.ID, newAPIKeyResponse(k), nil); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.apiKeys.Revoke(r.Context(), k.ID); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/apikey"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func TestSetRole(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	sessions := session.NewStore()
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: sessions, Accounts: accounts})
+	for _, name := range []string{"alice", "bob"} {
+		_, err := accounts.Register(ctx, name, name+"@example.com", "correct horse")
+		AssertThat(t, err, Nil())
+	}
+	AssertThat(t, accounts.SetRole(ctx, "alice", account.RoleAdmin), Nil())
+	alice, bob := sessions.Create("alice"), sessions.Create("bob")
+
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/alice/role", "", `{"role": "player"}`).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/bob/role", bob, `{"role": "admin"}`).Code, http.StatusForbidden)
+
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/bob/role", alice, `{"role": "moderator"}`).Code, http.StatusNoContent)
+	ExpectThat(t, accounts.Authorize(ctx, "bob", account.RoleModerator), Nil())
+	// Moderators are still not admins.
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/bob/role", bob, `{"role": "admin"}`).Code, http.StatusForbidden)
+
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/bob/role", alice, `{"role": "root"}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/carol/role", alice, `{"role": "admin"}`).Code, http.StatusNotFound)
+}
+
+func TestSetRole_AccountsDisabled(t *testing.T) {
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore()})
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/alice/role", login(t, s, "alice"), `{"role": "admin"}`).Code, http.StatusForbidden)
+}
+
+func TestModeration(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	sessions := session.NewStore()
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: sessions, Accounts: accounts})
+	for _, name := range []string{"alice", "bob", "carol"} {
+		_, err := accounts.Register(ctx, name, name+"@example.com", "correct horse")
+		AssertThat(t, err, Nil())
+	}
+	AssertThat(t, accounts.SetRole(ctx, "alice", account.RoleModerator), Nil())
+	alice := sessions.Create("alice")
+	tokens, err := sessions.Login(ctx, "bob")
+	AssertThat(t, err, Nil())
+	bob := tokens.Access
+
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/accounts/carol", bob, "").Code, http.StatusForbidden)
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/carol/ban", bob, `{"reason": "spite"}`).Code, http.StatusForbidden)
+
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/bob/ban", alice, `{}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/bob/ban", alice, `{"reason": "collusion", "until": "2001-01-01T00:00:00Z"}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/bob/ban", alice, `{"reason": "collusion"}`).Code, http.StatusNoContent)
+	// Banning ends the account's sessions and stops it logging in again.
+	ExpectEq(t, do(t, s, "GET", "/v1/sessions", bob, "").Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", "/v1/login", "", `{"username": "bob", "password": "correct horse"}`).Code, http.StatusForbidden)
+	// Moderators can't ban each other.
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/alice/ban", alice, `{"reason": "oops"}`).Code, http.StatusForbidden)
+
+	rec := do(t, s, "GET", "/v1/admin/accounts/bob", alice, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var info accountResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&info), Nil())
+	ExpectEq(t, info.Email, "bob@example.com")
+	ExpectEq(t, info.Sessions, 0)
+	AssertThat(t, info.Ban, Not(Nil()))
+	ExpectEq(t, info.Ban.By, "alice")
+	ExpectEq(t, info.Ban.Reason, "collusion")
+
+	ExpectEq(t, do(t, s, "DELETE", "/v1/admin/accounts/bob/ban", alice, "").Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "POST", "/v1/login", "", `{"username": "bob", "password": "correct horse"}`).Code, http.StatusOK)
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/accounts/dave", alice, "").Code, http.StatusNotFound)
+
+	// Accounts has no mail sender, so resets can't be sent.
+	ExpectEq(t, do(t, s, "POST", "/v1/admin/accounts/bob/password-reset", alice, "").Code, http.StatusNotFound)
+}
+
+func TestAPIKeys(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	sessions := session.NewStore()
+	s := NewServer(Config{
+		Lobby:    lobby.New(queue.New(), party.NewManager(), nil),
+		Sessions: sessions,
+		Accounts: accounts,
+		APIKeys:  apikey.NewManager(apikey.NewMemStore()),
+	})
+	_, err := accounts.Register(ctx, "alice", "alice@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+	AssertThat(t, accounts.SetRole(ctx, "alice", account.RoleAdmin), Nil())
+	alice := sessions.Create("alice")
+
+	ExpectEq(t, do(t, s, "POST", "/v1/admin/api-keys", alice, `{"name": "game servers", "scopes": ["root"]}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "POST", "/v1/admin/api-keys", alice, `{"name": "game servers", "scopes": []}`).Code, http.StatusBadRequest)
+	rec := do(t, s, "POST", "/v1/admin/api-keys", alice, `{"name": "game servers", "scopes": ["matches"]}`)
+	AssertEq(t, rec.Code, http.StatusCreated)
+	var key issueAPIKeyResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&key), Nil())
+	ExpectThat(t, key.Scopes, ElementsAre("matches"))
+
+	// The key may report results, so the unknown match is all that stops it,
+	// but it may not close tables.
+	ExpectEq(t, do(t, s, "POST", "/v1/matches/x/results", key.Token, `{"places": {"alice": 1, "bob": 2}}`).Code, http.StatusNotFound)
+	ExpectEq(t, do(t, s, "DELETE", "/v1/tables/t1", key.Token, "").Code, http.StatusForbidden)
+	// Nor is it a player session.
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/api-keys", key.Token, "").Code, http.StatusUnauthorized)
+
+	rec = do(t, s, "GET", "/v1/admin/api-keys", alice, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var list listAPIKeysResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&list), Nil())
+	AssertThat(t, list.Keys, Len(1))
+	ExpectEq(t, list.Keys[0].ID, key.ID)
+	ExpectThat(t, list.Keys[0].RevokedAt, Nil())
+
+	rec = do(t, s, "POST", "/v1/admin/api-keys", alice, `{"name": "bots", "scopes": ["backfills"]}`)
+	AssertEq(t, rec.Code, http.StatusCreated)
+	rec = do(t, s, "GET", "/v1/admin/api-keys?page_size=1&sort=-created_at", alice, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	list = listAPIKeysResponse{}
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&list), Nil())
+	AssertThat(t, list.Keys, Len(1))
+	ExpectEq(t, list.Keys[0].Name, "bots")
+	rec = do(t, s, "GET", "/v1/admin/api-keys?page_size=1&sort=-created_at&page_token="+list.NextPageToken, alice, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	list = listAPIKeysResponse{}
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&list), Nil())
+	AssertThat(t, list.Keys, Len(1))
+	ExpectEq(t, list.Keys[0].ID, key.ID)
+	ExpectEq(t, list.NextPageToken, "")
+
+	ExpectEq(t, do(t, s, "DELETE", "/v1/admin/api-keys/"+key.ID, alice, "").Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "POST", "/v1/matches/x/results", key.Token, `{"places": {"alice": 1, "bob": 2}}`).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "DELETE", "/v1/admin/api-keys/nobody", alice, "").Code, http.StatusNotFound)
+}
+
+func TestDebug(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	sessions := session.NewStore()
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: sessions, Accounts: accounts})
+	for _, name := range []string{"alice", "bob"} {
+		_, err := accounts.Register(ctx, name, name+"@example.com", "correct horse")
+		AssertThat(t, err, Nil())
+	}
+	AssertThat(t, accounts.SetRole(ctx, "alice", account.RoleAdmin), Nil())
+	AssertThat(t, accounts.SetRole(ctx, "bob", account.RoleModerator), Nil())
+	alice, bob := sessions.Create("alice"), sessions.Create("bob")
+
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/debug/vars", "", "").Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/debug/vars", bob, "").Code, http.StatusForbidden)
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/debug/pprof/heap", bob, "").Code, http.StatusForbidden)
+
+	rec := do(t, s, "GET", "/v1/admin/debug/vars", alice, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var vars map[string]json.RawMessage
+	AssertThat(t, json.Unmarshal(rec.Body.Bytes(), &vars), Nil())
+	ExpectThat(t, vars["runtime"], Not(Empty()))
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/debug/pprof/", alice, "").Code, http.StatusOK)
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/debug/pprof/goroutine", alice, "").Code, http.StatusOK)
+}
+
+func TestDashboard(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	sessions := session.NewStore()
+	dashboard := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(r.URL.Path)) })
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: sessions, Accounts: accounts, Dashboard: dashboard})
+	for _, name := range []string{"alice", "bob"} {
+		_, err := accounts.Register(ctx, name, name+"@example.com", "correct horse")
+		AssertThat(t, err, Nil())
+	}
+	AssertThat(t, accounts.SetRole(ctx, "alice", account.RoleAdmin), Nil())
+	AssertThat(t, accounts.SetRole(ctx, "bob", account.RoleModerator), Nil())
+	alice, bob := sessions.Create("alice"), sessions.Create("bob")
+
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/dashboard/", "", "").Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/dashboard/stats", bob, "").Code, http.StatusForbidden)
+
+	rec := do(t, s, "GET", "/v1/admin/dashboard/stats", alice, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	ExpectEq(t, rec.Body.String(), "/stats")
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/dashboard/", alice, "").Body.String(), "/")
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+)
+
+type auditEntryResponse struct {
+	ID     string          `json:"id"`
+	Time   time.Time       `json:"time"`
+	Actor  string          `json:"actor"`
+	Action string          `json:"action"`
+	Target string          `json:"target"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+type listAuditResponse struct {
+	Entries       []auditEntryResponse `json:"entries"`
+	NextPageToken string               `json:"next_page_token,omitempty"`
+}
+
+// handleListAudit lists the admin actions in the audit log, newest first
+// unless sorted by time. The actor, action and target query parameters
+// filter them, and page_size, page_token and sort page through them.
+func (s *Server) handleListAudit(w http.ResponseWriter, r *http.Request) {
+	if s.audit == nil {
+		writeError(w, http.StatusNotFound, "the audit log is disabled")
+		return
+	}
+	req, err := audit.Listing.FromQuery(r.URL.Query())
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	p, err := s.audit.List(r.Context(), req)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	resp := listAuditResponse{Entries: []auditEntryResponse{}, NextPageToken: p.NextToken}
+	for _, e := range p.Items {
+		resp.Entries = append(resp.Entries, auditEntryResponse{
+			ID:     e.ID,
+			Time:   e.Time,
+			Actor:  e.Actor,
+			Action: string(e.Action),
+			Target: e.Target,
+			Before: e.Before,
+			After:  e.After,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type recordTableActionRequest struct {
+	Actor  string          `json:"actor"`
+	Action string          `json:"action"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// handleRecordTableAction lets a game server add an action taken on one of
+// its tables with its admin token to the audit log.
+func (s *Server) handleRecordTableAction(w http.ResponseWriter, r *http.Request) {
+	if s.audit == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	var req recordTableActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	action, err := audit.ParseAction(req.Action)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if !action.Table() {
+		writeError(w, http.StatusBadRequest, "game servers may only record table actions")
+		return
+	}
+	if req.Actor == "" {
+		writeError(w, http.StatusBadRequest, "actor is required")
+		return
+	}
+	if err := s.audit.Record(r.Context(), req.Actor, action, r.PathValue("id"), req.Before, req.After); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
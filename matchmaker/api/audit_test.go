@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/apikey"
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/registry"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func listAudit(t *testing.T, s *Server, token, query string) []auditEntryResponse {
+	t.Helper()
+	rec := do(t, s, "GET", "/v1/admin/audit"+query, token, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var resp listAuditResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	return resp.Entries
+}
+
+func TestAudit(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	sessions := session.NewStore()
+	s := NewServer(Config{
+		Lobby:         lobby.New(queue.New(), party.NewManager(), nil),
+		Sessions:      sessions,
+		Accounts:      accounts,
+		Registry:      registry.New(registry.NewMemStore()),
+		Audit:         audit.New(audit.NewMemStore()),
+		InternalToken: "secret",
+	})
+	for _, name := range []string{"alice", "bob", "carol"} {
+		_, err := accounts.Register(ctx, name, name+"@example.com", "correct horse")
+		AssertThat(t, err, Nil())
+	}
+	AssertThat(t, accounts.SetRole(ctx, "alice", account.RoleAdmin), Nil())
+	alice := sessions.Create("alice")
+
+	AssertEq(t, do(t, s, "PUT", "/v1/admin/accounts/bob/role", alice, `{"role": "moderator"}`).Code, http.StatusNoContent)
+	bob := sessions.Create("bob")
+	AssertEq(t, do(t, s, "PUT", "/v1/admin/accounts/carol/ban", bob, `{"reason": "spam"}`).Code, http.StatusNoContent)
+	AssertEq(t, do(t, s, "DELETE", "/v1/admin/accounts/carol/ban", bob, "").Code, http.StatusNoContent)
+	const cfg = `{"blinds": {"blindLevels": [{"units": "1"}, {"units": "2"}]}}`
+	AssertEq(t, do(t, s, "PUT", "/v1/configs/table-configs/holdem", alice, cfg).Code, http.StatusOK)
+	AssertEq(t, do(t, s, "DELETE", "/v1/configs/table-configs/holdem", alice, "").Code, http.StatusNoContent)
+	// Failed actions aren't recorded.
+	AssertEq(t, do(t, s, "PUT", "/v1/admin/accounts/dave/ban", bob, `{"reason": "spam"}`).Code, http.StatusNotFound)
+
+	// Only admins may review the log.
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/audit", bob, "").Code, http.StatusForbidden)
+
+	entries := listAudit(t, s, alice, "?sort=time")
+	AssertThat(t, entries, Len(5))
+	role, ban, unban, put, del := entries[0], entries[1], entries[2], entries[3], entries[4]
+	ExpectEq(t, role.Actor, "alice")
+	ExpectEq(t, role.Action, "account.role")
+	ExpectEq(t, role.Target, "bob")
+	ExpectEq(t, string(role.Before), `"player"`)
+	ExpectEq(t, string(role.After), `"moderator"`)
+
+	ExpectEq(t, ban.Actor, "bob")
+	ExpectEq(t, ban.Action, "account.ban")
+	ExpectEq(t, ban.Target, "carol")
+	ExpectThat(t, ban.Before, Nil())
+	var after banResponse
+	AssertThat(t, json.Unmarshal(ban.After, &after), Nil())
+	ExpectEq(t, after.Reason, "spam")
+	ExpectEq(t, after.By, "bob")
+	ExpectEq(t, unban.Action, "account.unban")
+	var lifted banResponse
+	AssertThat(t, json.Unmarshal(unban.Before, &lifted), Nil())
+	ExpectEq(t, lifted.Reason, "spam")
+	ExpectEq(t, lifted.By, "bob")
+	ExpectThat(t, unban.After, Nil())
+
+	ExpectEq(t, put.Action, "config.put")
+	ExpectEq(t, put.Target, "table-configs/holdem")
+	ExpectThat(t, put.Before, Nil())
+	var rev configRevision
+	AssertThat(t, json.Unmarshal(put.After, &rev), Nil())
+	ExpectEq(t, rev.Revision, int64(1))
+	ExpectThat(t, string(rev.Config), HasSubstr("blindLevels"))
+	ExpectEq(t, del.Action, "config.delete")
+	ExpectEq(t, string(del.Before), string(put.After))
+
+	entries = listAudit(t, s, alice, "?actor=bob")
+	AssertThat(t, entries, Len(2))
+	ExpectEq(t, entries[0].Action, "account.unban")
+	entries = listAudit(t, s, alice, "?target=carol&action=account.ban")
+	AssertThat(t, entries, Len(1))
+	ExpectEq(t, entries[0].ID, ban.ID)
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/audit?action=account.delete", alice, "").Code, http.StatusBadRequest)
+}
+
+// failingAuditStore can't record anything.
+type failingAuditStore struct {
+	audit.MemStore
+}
+
+func (s *failingAuditStore) Append(ctx context.Context, e audit.Entry) error {
+	return errors.New("database unavailable")
+}
+
+func TestAudit_RecordFails(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	sessions := session.NewStore()
+	keys := apikey.NewManager(apikey.NewMemStore())
+	s := NewServer(Config{
+		Lobby:    lobby.New(queue.New(), party.NewManager(), nil),
+		Sessions: sessions,
+		Accounts: accounts,
+		APIKeys:  keys,
+		Audit:    audit.New(&failingAuditStore{}),
+	})
+	for _, name := range []string{"alice", "bob"} {
+		_, err := accounts.Register(ctx, name, name+"@example.com", "correct horse")
+		AssertThat(t, err, Nil())
+	}
+	AssertThat(t, accounts.SetRole(ctx, "alice", account.RoleAdmin), Nil())
+	alice := sessions.Create("alice")
+
+	// Changes that can't be recorded aren't made.
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/bob/role", alice, `{"role": "moderator"}`).Code, http.StatusInternalServerError)
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/bob/ban", alice, `{"reason": "spam"}`).Code, http.StatusInternalServerError)
+	ExpectEq(t, do(t, s, "POST", "/v1/admin/api-keys", alice, `{"name": "gs", "scopes": ["tables"]}`).Code, http.StatusInternalServerError)
+	bob, err := accounts.Get(ctx, "bob")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, bob.Role, account.RolePlayer)
+	ExpectThat(t, bob.Ban, Nil())
+	list, err := keys.List(ctx)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, list, Empty())
+}
+
+func TestAudit_TableActions(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	sessions := session.NewStore()
+	s := NewServer(Config{
+		Lobby:         lobby.New(queue.New(), party.NewManager(), nil),
+		Sessions:      sessions,
+		Accounts:      accounts,
+		Audit:         audit.New(audit.NewMemStore()),
+		InternalToken: "secret",
+	})
+	_, err := accounts.Register(ctx, "alice", "alice@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+	AssertThat(t, accounts.SetRole(ctx, "alice", account.RoleAdmin), Nil())
+	alice := sessions.Create("alice")
+
+	const body = `{"actor": "admin token on gs-1", "action": "table.close", "after": {"reason": "maintenance"}}`
+	ExpectEq(t, do(t, s, "POST", "/v1/tables/t1/audit", alice, body).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", "/v1/tables/t1/audit", "secret", `{"actor": "x", "action": "account.ban"}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "POST", "/v1/tables/t1/audit", "secret", `{"action": "table.close"}`).Code, http.StatusBadRequest)
+	AssertEq(t, do(t, s, "POST", "/v1/tables/t1/audit", "secret", body).Code, http.StatusNoContent)
+
+	entries := listAudit(t, s, alice, "")
+	AssertThat(t, entries, Len(1))
+	ExpectEq(t, entries[0].Actor, "admin token on gs-1")
+	ExpectEq(t, entries[0].Action, "table.close")
+	ExpectEq(t, entries[0].Target, "t1")
+	ExpectEq(t, string(entries[0].After), `{"reason":"maintenance"}`)
+}
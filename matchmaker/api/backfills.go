@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+type backfillRequest struct {
+	TableID  string `json:"table_id"`
+	GameMode string `json:"game_mode"`
+	Region   string `json:"region"`
+	Seats    int    `json:"seats"`
+}
+
+type backfillResponse struct {
+	BackfillID string `json:"backfill_id"`
+	TableID    string `json:"table_id"`
+	GameMode   string `json:"game_mode"`
+	Region     string `json:"region,omitempty"`
+	Seats      int    `json:"seats"`
+}
+
+// handleRequestBackfill lets a game server ask for players to fill empty
+// seats at a running table. Players sent to the table arrive as matches
+// carrying its table ID.
+func (s *Server) handleRequestBackfill(w http.ResponseWriter, r *http.Request) {
+	var req backfillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if req.TableID == "" || req.GameMode == "" {
+		writeError(w, http.StatusBadRequest, "table_id and game_mode are required")
+		return
+	}
+	if req.Seats < 1 {
+		writeError(w, http.StatusBadRequest, "seats must be positive")
+		return
+	}
+
+	b, err := s.lobby.RequestBackfill(req.TableID, req.GameMode, req.Region, req.Seats)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, backfillResponse{
+		BackfillID: b.ID,
+		TableID:    b.TableID,
+		GameMode:   b.GameMode,
+		Region:     b.Region,
+		Seats:      b.Seats,
+	})
+}
+
+func (s *Server) handleCancelBackfill(w http.ResponseWriter, r *http.Request) {
+	if err := s.queue.CancelBackfill(r.PathValue("id")); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type priorityTicketsRequest struct {
+	PlayerIDs []string `json:"player_ids"`
+	GameMode  string   `json:"game_mode"`
+	Priority  string   `json:"priority"`
+}
+
+type priorityTicketsResponse struct {
+	Tickets []ticketResponse `json:"tickets"`
+}
+
+// handlePriorityTickets queues players ahead of normal traffic on behalf of
+// another service, such as after a match is abandoned before it starts or
+// when a tournament overflows.
+func (s *Server) handlePriorityTickets(w http.ResponseWriter, r *http.Request) {
+	var req priorityTicketsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if len(req.PlayerIDs) == 0 || req.GameMode == "" {
+		writeError(w, http.StatusBadRequest, "player_ids and game_mode are required")
+		return
+	}
+	p, err := queue.ParsePriority(req.Priority)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tickets, err := s.lobby.EnqueuePriority(r.Context(), req.PlayerIDs, req.GameMode, p)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	resp := priorityTicketsResponse{Tickets: []ticketResponse{}}
+	for _, t := range tickets {
+		rec, pos, _ := s.queue.Status(r.Context(), t.ID)
+		resp.Tickets = append(resp.Tickets, newTicketResponse(rec, pos))
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func TestBackfills(t *testing.T) {
+	q := queue.New()
+	s := NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: session.NewStore(), InternalToken: "secret"})
+
+	body := `{"table_id": "t1", "game_mode": "holdem", "seats": 2}`
+	ExpectEq(t, do(t, s, "POST", "/v1/backfills", "", body).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", "/v1/backfills", "secret", `{"table_id": "t1", "game_mode": "holdem"}`).Code, http.StatusBadRequest)
+
+	rec := do(t, s, "POST", "/v1/backfills", "secret", body)
+	AssertEq(t, rec.Code, http.StatusCreated)
+	var resp backfillResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectEq(t, resp.Seats, 2)
+	AssertThat(t, q.Backfills(), Len(1))
+
+	ExpectEq(t, do(t, s, "DELETE", "/v1/backfills/"+resp.BackfillID, "secret", "").Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "DELETE", "/v1/backfills/"+resp.BackfillID, "secret", "").Code, http.StatusNotFound)
+}
+
+func TestPriorityTickets(t *testing.T) {
+	q := queue.New()
+	s := NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: session.NewStore(), InternalToken: "secret"})
+	alice := login(t, s, "alice")
+	AssertEq(t, do(t, s, "POST", "/v1/tickets", alice, `{"game_mode": "holdem"}`).Code, http.StatusCreated)
+
+	body := `{"player_ids": ["bob"], "game_mode": "holdem", "priority": "requeue"}`
+	ExpectEq(t, do(t, s, "POST", "/v1/priority-tickets", "", body).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", "/v1/priority-tickets", "secret", `{"player_ids": ["bob"], "game_mode": "holdem", "priority": "vip"}`).Code, http.StatusBadRequest)
+
+	rec := do(t, s, "POST", "/v1/priority-tickets", "secret", body)
+	AssertEq(t, rec.Code, http.StatusCreated)
+	var resp priorityTicketsResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	AssertThat(t, resp.Tickets, Len(1))
+	ExpectEq(t, resp.Tickets[0].Priority, "requeue")
+
+	// Bob jumps ahead of alice.
+	ExpectEq(t, resp.Tickets[0].Position, 0)
+	ExpectEq(t, q.Len(), 2)
+}
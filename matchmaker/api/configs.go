@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+	"github.com/jfmatt/snapfold/matchmaker/registry"
+)
+
+// maxConfigSize is the largest config body accepted.
+const maxConfigSize = 1 << 20
+
+type configResponse struct {
+	Name      string    `json:"name"`
+	Revision  int64     `json:"revision"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newConfigResponse(e registry.Entry) configResponse {
+	return configResponse{Name: e.Name, Revision: e.Revision, UpdatedAt: e.UpdatedAt}
+}
+
+// configRevision is how the audit log shows a revision of a config.
+type configRevision struct {
+	Revision int64           `json:"revision"`
+	Config   json.RawMessage `json:"config"`
+}
+
+// currentRevision returns the stored revision of a config, or nil if there
+// is none.
+func (s *Server) currentRevision(r *http.Request, kind registry.Kind, name string) (*configRevision, error) {
+	e, err := s.registry.Get(r.Context(), kind, name)
+	if errors.Is(err, registry.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &configRevision{Revision: e.Revision, Config: e.Config}, nil
+}
+
+// configKind returns the kind of config a request is for, or writes an
+// error and returns false.
+func (s *Server) configKind(w http.ResponseWriter, r *http.Request) (registry.Kind, bool) {
+	if s.registry == nil {
+		writeError(w, http.StatusNotFound, "the config registry is disabled")
+		return "", false
+	}
+	kind, err := registry.ParseKind(r.PathValue("kind"))
+	if err != nil {
+		writeErr(w, err)
+		return "", false
+	}
+	return kind, true
+}
+
+// handleListConfigs lists the stored configs of a kind.
+func (s *Server) handleListConfigs(w http.ResponseWriter, r *http.Request) {
+	kind, ok := s.configKind(w, r)
+	if !ok {
+		return
+	}
+	entries, err := s.registry.List(r.Context(), kind)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	resp := []configResponse{}
+	for _, e := range entries {
+		resp = append(resp, newConfigResponse(e))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleGetConfig replies with a stored config as protojson, tagged with
+// its revision so that callers can ask for it only if it has changed.
+func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	kind, ok := s.configKind(w, r)
+	if !ok {
+		return
+	}
+	e, err := s.registry.Get(r.Context(), kind, r.PathValue("name"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.Header().Set("ETag", e.ETag())
+	if r.Header.Get("If-None-Match") == e.ETag() {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(e.Config)
+}
+
+// handlePutConfig stores a config given as protojson. If the request has
+// an If-Match header, the config is only stored if the one it replaces is
+// at that revision.
+func (s *Server) handlePutConfig(w http.ResponseWriter, r *http.Request) {
+	kind, ok := s.configKind(w, r)
+	if !ok {
+		return
+	}
+	var revision int64
+	if tag := r.Header.Get("If-Match"); tag != "" {
+		var err error
+		revision, err = strconv.ParseInt(strings.Trim(tag, `"`), 10, 64)
+		if err != nil || revision <= 0 {
+			writeError(w, http.StatusBadRequest, "If-Match must be a config's ETag")
+			return
+		}
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	before, err := s.currentRevision(r, kind, r.PathValue("name"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	e, err := s.registry.Prepare(r.Context(), kind, r.PathValue("name"), body, revision)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	after := &configRevision{Revision: e.Revision, Config: e.Config}
+	if err := s.record(r, audit.ConfigPut, string(kind)+"/"+e.Name, before, after); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.registry.Save(r.Context(), e); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.Header().Set("ETag", e.ETag())
+	writeJSON(w, http.StatusOK, newConfigResponse(e))
+}
+
+// handleDeleteConfig removes a stored config.
+func (s *Server) handleDeleteConfig(w http.ResponseWriter, r *http.Request) {
+	kind, ok := s.configKind(w, r)
+	if !ok {
+		return
+	}
+	before, err := s.currentRevision(r, kind, r.PathValue("name"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if before == nil {
+		writeErr(w, registry.ErrNotFound)
+		return
+	}
+	if err := s.record(r, audit.ConfigDeleted, string(kind)+"/"+r.PathValue("name"), before, nil); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.registry.Delete(r.Context(), kind, r.PathValue("name")); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
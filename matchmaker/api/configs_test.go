@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/registry"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+// doWith is do with an extra request header.
+func doWith(t *testing.T, s *Server, method, path, token, body, header, value string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(header, value)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestConfigs(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	sessions := session.NewStore()
+	s := NewServer(Config{
+		Lobby:         lobby.New(queue.New(), party.NewManager(), nil),
+		Sessions:      sessions,
+		Accounts:      accounts,
+		Registry:      registry.New(registry.NewMemStore()),
+		InternalToken: "secret",
+	})
+	for _, name := range []string{"alice", "bob"} {
+		_, err := accounts.Register(ctx, name, name+"@example.com", "correct horse")
+		AssertThat(t, err, Nil())
+	}
+	AssertThat(t, accounts.SetRole(ctx, "alice", account.RoleAdmin), Nil())
+	alice, bob := sessions.Create("alice"), sessions.Create("bob")
+	const path = "/v1/configs/table-configs/holdem"
+	const cfg = `{"blinds": {"blindLevels": [{"units": "1"}, {"units": "2"}]}}`
+
+	ExpectEq(t, do(t, s, "PUT", path, bob, cfg).Code, http.StatusForbidden)
+	ExpectEq(t, do(t, s, "PUT", "/v1/configs/widgets/holdem", alice, cfg).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "PUT", "/v1/configs/table-configs/Hold'em", alice, cfg).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "PUT", path, alice, `{"blinds": {"blindLevels": [{"units": "2"}, {"units": "1"}]}}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "GET", path, "secret", "").Code, http.StatusNotFound)
+
+	rec := do(t, s, "PUT", path, alice, cfg)
+	AssertEq(t, rec.Code, http.StatusOK)
+	ExpectEq(t, rec.Header().Get("ETag"), `"1"`)
+
+	// Only game servers and tools read configs.
+	ExpectEq(t, do(t, s, "GET", path, bob, "").Code, http.StatusUnauthorized)
+	rec = do(t, s, "GET", path, "secret", "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	ExpectEq(t, rec.Header().Get("ETag"), `"1"`)
+	var got map[string]any
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&got), Nil())
+	ExpectEq(t, got["variant"], "HOLDEM")
+	ExpectEq(t, doWith(t, s, "GET", path, "secret", "", "If-None-Match", `"1"`).Code, http.StatusNotModified)
+
+	ExpectEq(t, doWith(t, s, "PUT", path, alice, cfg, "If-Match", `"1"`).Code, http.StatusOK)
+	// Someone else's change came first.
+	ExpectEq(t, doWith(t, s, "PUT", path, alice, cfg, "If-Match", `"1"`).Code, http.StatusPreconditionFailed)
+	ExpectEq(t, doWith(t, s, "PUT", path, alice, cfg, "If-Match", `*`).Code, http.StatusBadRequest)
+	ExpectEq(t, doWith(t, s, "GET", path, "secret", "", "If-None-Match", `"1"`).Code, http.StatusOK)
+
+	rec = do(t, s, "GET", "/v1/configs/table-configs", "secret", "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var list []configResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&list), Nil())
+	AssertThat(t, list, Len(1))
+	ExpectEq(t, list[0].Name, "holdem")
+	ExpectEq(t, list[0].Revision, int64(2))
+
+	ExpectEq(t, do(t, s, "DELETE", path, alice, "").Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "DELETE", path, alice, "").Code, http.StatusNotFound)
+}
+
+func TestConfigs_Disabled(t *testing.T) {
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore(), InternalToken: "secret"})
+	ExpectEq(t, do(t, s, "GET", "/v1/configs/table-configs/holdem", "secret", "").Code, http.StatusNotFound)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+type handRequest struct {
+	Number   uint64    `json:"number"`
+	PlayedAt time.Time `json:"played_at"`
+
+	// Each player's history of the hand, by player ID.
+	Histories map[string]string `json:"histories"`
+
+	// Chips the house took from the hand.
+	Rake int64 `json:"rake"`
+}
+
+// handleRecordHand saves the histories of a hand a game server played at
+// one of its tables.
+func (s *Server) handleRecordHand(w http.ResponseWriter, r *http.Request) {
+	var req handRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if req.Rake != 0 {
+		err := s.lobby.History().RecordRake(r.Context(), r.PathValue("id"), req.Number, req.PlayedAt, req.Rake)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+	}
+	err := s.lobby.History().RecordHand(r.Context(), r.PathValue("id"), req.Number, req.PlayedAt, req.Histories)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDownloadHands sends the caller's hand histories as a text file,
+// oldest first, in the format PokerStars uses, which tracking tools import.
+// The since query parameter, an RFC 3339 time, skips older hands, and limit
+// caps how many are sent.
+func (s *Server) handleDownloadHands(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "since must be an RFC 3339 time")
+			return
+		}
+		since = t
+	}
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a non-negative integer")
+			return
+		}
+		limit = n
+	}
+	hands, err := s.lobby.History().Hands(r.Context(), playerID, since, limit)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="hands.txt"`)
+	for _, h := range hands {
+		// Hands in a history file are separated by blank lines.
+		fmt.Fprintf(w, "%s\n\n", h.Text)
+	}
+}
+
+type rakeResponse struct {
+	Total int64 `json:"total"`
+
+	// Rake by table ID.
+	Tables map[string]int64 `json:"tables"`
+}
+
+// handleRakeReport totals the rake taken from hands played between the
+// since and until query parameters, RFC 3339 times. since defaults to the
+// beginning of time, and until to now.
+func (s *Server) handleRakeReport(w http.ResponseWriter, r *http.Request) {
+	since, until := time.Time{}, time.Now()
+	for name, dst := range map[string]*time.Time{"since": &since, "until": &until} {
+		if v := r.URL.Query().Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, name+" must be an RFC 3339 time")
+				return
+			}
+			*dst = t
+		}
+	}
+	tables, err := s.lobby.History().RakeByTable(r.Context(), since, until)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	resp := rakeResponse{Tables: tables}
+	for _, amount := range tables {
+		resp.Total += amount
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
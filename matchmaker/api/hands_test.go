@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func TestHands(t *testing.T) {
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore(), InternalToken: "secret"})
+	alice := login(t, s, "alice")
+
+	ExpectEq(t, do(t, s, "POST", "/v1/tables/m1/hands", "", `{}`).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", "/v1/tables/m1/hands", "secret", `{"number": 1}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "POST", "/v1/tables/m1/hands", "secret", `{
+		"number": 2,
+		"played_at": "2026-10-15T12:01:00Z",
+		"histories": {"alice": "PokerStars Hand #2: alice's view", "bob": "PokerStars Hand #2: bob's view"}
+	}`).Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "POST", "/v1/tables/m1/hands", "secret", `{
+		"number": 1,
+		"played_at": "2026-10-15T12:00:00Z",
+		"histories": {"alice": "PokerStars Hand #1: alice's view"}
+	}`).Code, http.StatusNoContent)
+
+	ExpectEq(t, do(t, s, "GET", "/v1/hands", "", "").Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "GET", "/v1/hands?since=yesterday", alice, "").Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "GET", "/v1/hands?limit=-1", alice, "").Code, http.StatusBadRequest)
+
+	w := do(t, s, "GET", "/v1/hands", alice, "")
+	AssertEq(t, w.Code, http.StatusOK)
+	ExpectEq(t, w.Header().Get("Content-Disposition"), `attachment; filename="hands.txt"`)
+	ExpectEq(t, w.Body.String(), "PokerStars Hand #1: alice's view\n\nPokerStars Hand #2: alice's view\n\n")
+
+	w = do(t, s, "GET", "/v1/hands?since=2026-10-15T12:00:30Z&limit=5", alice, "")
+	AssertEq(t, w.Code, http.StatusOK)
+	ExpectEq(t, w.Body.String(), "PokerStars Hand #2: alice's view\n\n")
+}
+
+func TestRakeReport(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	sessions := session.NewStore()
+	s := NewServer(Config{
+		Lobby:         lobby.New(queue.New(), party.NewManager(), nil),
+		Sessions:      sessions,
+		Accounts:      accounts,
+		InternalToken: "secret",
+	})
+	for _, name := range []string{"alice", "bob"} {
+		_, err := accounts.Register(ctx, name, name+"@example.com", "correct horse")
+		AssertThat(t, err, Nil())
+	}
+	AssertThat(t, accounts.SetRole(ctx, "alice", account.RoleAdmin), Nil())
+	alice, bob := sessions.Create("alice"), sessions.Create("bob")
+
+	for _, body := range []string{
+		`{"number": 1, "played_at": "2026-10-01T12:00:00Z", "histories": {"alice": "hand 1"}, "rake": 150}`,
+		`{"number": 2, "played_at": "2026-10-01T13:00:00Z", "histories": {"alice": "hand 2"}, "rake": 300}`,
+		`{"number": 3, "played_at": "2026-10-01T13:00:00Z", "histories": {"alice": "hand 3"}}`,
+	} {
+		ExpectEq(t, do(t, s, "POST", "/v1/tables/m1/hands", "secret", body).Code, http.StatusNoContent)
+	}
+	ExpectEq(t, do(t, s, "POST", "/v1/tables/m1/hands", "secret", `{"number": 4, "histories": {"alice": "hand 4"}, "rake": -1}`).Code, http.StatusBadRequest)
+
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/rake", bob, "").Code, http.StatusForbidden)
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/rake?since=today", alice, "").Code, http.StatusBadRequest)
+
+	w := do(t, s, "GET", "/v1/admin/rake", alice, "")
+	AssertEq(t, w.Code, http.StatusOK)
+	ExpectEq(t, strings.TrimSpace(w.Body.String()), `{"total":450,"tables":{"m1":450}}`)
+
+	w = do(t, s, "GET", "/v1/admin/rake?since=2026-10-01T12:30:00Z&until=2026-10-01T14:00:00Z", alice, "")
+	AssertEq(t, w.Code, http.StatusOK)
+	ExpectEq(t, strings.TrimSpace(w.Body.String()), `{"total":300,"tables":{"m1":300}}`)
+}
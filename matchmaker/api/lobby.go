@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/frame"
+	"github.com/jfmatt/snapfold/lib/protocol"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+const (
+	// How long to wait for a write to a lobby socket before giving up.
+	lobbyWriteTimeout = 10 * time.Second
+
+	// Interval between keepalive pings on lobby sockets.
+	lobbyPingInterval = 30 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// handleLobby upgrades the connection to a WebSocket and streams LobbyEvent
+// messages for one of the caller's tickets until it leaves the queue or the
+// caller's session is revoked.
+func (s *Server) handleLobby(w http.ResponseWriter, r *http.Request) {
+	ctx, updates, stop, ok := s.watchLobby(w, r)
+	if !ok {
+		return
+	}
+	defer stop()
+	hello := protocol.FromContext(ctx)
+
+	conn, err := upgrader.Upgrade(w, r, protocol.UpgradeHeader())
+	if err != nil {
+		// The upgrader has already replied to the client.
+		return
+	}
+	defer conn.Close()
+
+	// Clients don't send anything, but reading is required to process
+	// control frames and to notice when the client goes away.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(lobbyPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ctx.Done():
+			msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, session.ErrRevoked.Error())
+			conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(lobbyWriteTimeout))
+			return
+		case <-ping.C:
+			deadline := time.Now().Add(lobbyWriteTimeout)
+			if err := conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				return
+			}
+		case u, ok := <-updates:
+			if !ok {
+				msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+				conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(lobbyWriteTimeout))
+				return
+			}
+			ev := s.lobbyEventFor(ctx, hello, u)
+			if ev == nil {
+				continue
+			}
+			var b []byte
+			if hello.Framed() {
+				b, err = frame.Marshal(ev)
+			} else {
+				b, err = proto.Marshal(ev)
+			}
+			if err != nil {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(lobbyWriteTimeout))
+			if err := conn.WriteMessage(websocket.BinaryMessage, b); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleLobbyEvents streams the same events as handleLobby as server-sent
+// events, for web clients on networks that block WebSockets. Each event's
+// data is a LobbyEvent in the protobuf JSON mapping. The stream ends with an
+// end event once the ticket leaves the queue, or with an error event if the
+// caller's session is revoked; clients shouldn't reconnect after either.
+func (s *Server) handleLobbyEvents(w http.ResponseWriter, r *http.Request) {
+	ctx, updates, stop, ok := s.watchLobby(w, r)
+	if !ok {
+		return
+	}
+	defer stop()
+	hello := protocol.FromContext(ctx)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stop proxies that buffer responses, such as nginx, from holding
+	// events back.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if rc.Flush() != nil {
+		return
+	}
+	send := func(event string, data []byte) bool {
+		rc.SetWriteDeadline(time.Now().Add(lobbyWriteTimeout))
+		if event != "" {
+			fmt.Fprintf(w, "event: %s\n", event)
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		return rc.Flush() == nil
+	}
+
+	ping := time.NewTicker(lobbyPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ctx.Done():
+			b, _ := json.Marshal(map[string]string{"error": session.ErrRevoked.Error()})
+			send("error", b)
+			return
+		case <-ping.C:
+			// A comment, which clients ignore, keeps idle connections
+			// from being dropped.
+			rc.SetWriteDeadline(time.Now().Add(lobbyWriteTimeout))
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+		case u, ok := <-updates:
+			if !ok {
+				send("end", []byte("{}"))
+				return
+			}
+			ev := s.lobbyEventFor(ctx, hello, u)
+			if ev == nil {
+				continue
+			}
+			b, err := protojson.Marshal(ev)
+			if err != nil || !send("", b) {
+				return
+			}
+		}
+	}
+}
+
+// watchLobby starts watching one of the caller's queued tickets for the
+// lobby endpoints, or replies with why it can't. The returned context is
+// done once the caller's session is revoked.
+func (s *Server) watchLobby(w http.ResponseWriter, r *http.Request) (context.Context, <-chan queue.Update, func(), bool) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	rec, _, err := s.lobby.Ticket(r.Context(), playerID, r.PathValue("id"))
+	if err == nil && rec.State != queue.StateQueued {
+		err = fmt.Errorf("%w: ticket was %s", queue.ErrClosed, rec.State)
+	}
+	if err != nil {
+		writeErr(w, err)
+		return nil, nil, nil, false
+	}
+
+	updates, stop, err := s.queue.Watch(rec.Ticket.ID)
+	if err != nil {
+		writeErr(w, err)
+		return nil, nil, nil, false
+	}
+	ctx, unwatch := s.sessions.Watch(r.Context())
+	return ctx, updates, func() { unwatch(); stop() }, true
+}
+
+// lobbyEventFor returns the event to send the caller for an update, or nil
+// if there's nothing to send. Ready checks are accepted on behalf of clients
+// that don't handle them.
+func (s *Server) lobbyEventFor(ctx context.Context, hello protocol.Hello, u queue.Update) *pb.LobbyEvent {
+	if u.ReadyCheck != nil && !hello.Has(protocol.ReadyChecks) {
+		playerID, _ := session.PlayerFrom(ctx)
+		acceptFor(ctx, s.queue, u.ReadyCheck, playerID)
+		return nil
+	}
+	return lobbyEvent(u)
+}
+
+// acceptFor accepts a ready check for a player whose client can't. If that
+// fails, the ready check runs out as if they had ignored it.
+func acceptFor(ctx context.Context, q *queue.Queue, rc *queue.ReadyCheck, playerID string) {
+	if !slices.Contains(rc.Accepted, playerID) {
+		q.Accept(ctx, rc.Match.ID, playerID)
+	}
+}
+
+func lobbyEvent(u queue.Update) *pb.LobbyEvent {
+	id := proto.String(u.Ticket.ID)
+	switch {
+	case u.Canceled:
+		return pb.LobbyEvent_builder{
+			TicketCanceled: pb.TicketCanceled_builder{TicketId: id}.Build(),
+		}.Build()
+	case u.Expired:
+		return pb.LobbyEvent_builder{
+			TicketExpired: pb.TicketExpired_builder{TicketId: id}.Build(),
+		}.Build()
+	case u.Match != nil:
+		found := pb.MatchFound_builder{
+			TicketId:  id,
+			MatchId:   proto.String(u.Match.ID),
+			GameMode:  proto.String(u.Match.GameMode),
+			PlayerIds: u.Match.Players(),
+		}
+		if u.Match.Region != "" {
+			found.Region = proto.String(u.Match.Region)
+		}
+		if u.Match.TableID != "" {
+			found.TableId = proto.String(u.Match.TableID)
+		}
+		if u.Match.Bots > 0 {
+			found.Bots = proto.Int32(int32(u.Match.Bots))
+		}
+		return pb.LobbyEvent_builder{MatchFound: found.Build()}.Build()
+	case u.ReadyCheck != nil:
+		return pb.LobbyEvent_builder{
+			ReadyCheck: pb.ReadyCheck_builder{
+				TicketId:          id,
+				MatchId:           proto.String(u.ReadyCheck.Match.ID),
+				GameMode:          proto.String(u.ReadyCheck.Match.GameMode),
+				PlayerIds:         u.ReadyCheck.Match.Players(),
+				AcceptedPlayerIds: u.ReadyCheck.Accepted,
+				Deadline:          timestamppb.New(u.ReadyCheck.Deadline),
+			}.Build(),
+		}.Build()
+	default:
+		status := pb.QueueStatus_builder{
+			TicketId: id,
+			Position: proto.Int32(int32(u.Position)),
+		}
+		if u.EstimatedWait > 0 {
+			status.EstimatedWait = durationpb.New(u.EstimatedWait)
+		}
+		return pb.LobbyEvent_builder{QueueStatus: status.Build()}.Build()
+	}
+}
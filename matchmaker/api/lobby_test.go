@@ -0,0 +1,218 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/protocol"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func readEvent(t *testing.T, conn *websocket.Conn) *pb.LobbyEvent {
+	t.Helper()
+	kind, b, err := conn.ReadMessage()
+	AssertThat(t, err, Nil())
+	AssertEq(t, kind, websocket.BinaryMessage)
+	ev := &pb.LobbyEvent{}
+	AssertThat(t, proto.Unmarshal(b, ev), Nil())
+	return ev
+}
+
+func TestLobby(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: sessions}))
+	defer srv.Close()
+
+	token := sessions.Create("alice")
+	tk, err := q.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/tickets/" + tk.ID + "/lobby?access_token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	AssertThat(t, err, Nil())
+	defer conn.Close()
+
+	ev := readEvent(t, conn)
+	AssertEq(t, ev.HasQueueStatus(), true)
+	ExpectEq(t, ev.GetQueueStatus().GetTicketId(), tk.ID)
+	ExpectEq(t, ev.GetQueueStatus().GetPosition(), int32(0))
+
+	_, err = q.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+	readEvent(t, conn) // Unchanged position, re-sent on queue change.
+
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+
+	ev = readEvent(t, conn)
+	AssertEq(t, ev.HasMatchFound(), true)
+	ExpectEq(t, ev.GetMatchFound().GetMatchId(), matches[0].ID)
+	ExpectThat(t, ev.GetMatchFound().GetPlayerIds(), ElementsAre("alice", "bob"))
+
+	_, _, err = conn.ReadMessage()
+	ExpectEq(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), true)
+}
+
+func TestLobby_SessionRevoked(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: sessions}))
+	defer srv.Close()
+
+	tokens, err := sessions.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	tk, err := q.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/tickets/" + tk.ID + "/lobby?access_token=" + tokens.Access
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	AssertThat(t, err, Nil())
+	defer conn.Close()
+	readEvent(t, conn)
+
+	AssertThat(t, sessions.Logout(ctx, tokens.Refresh), Nil())
+	_, _, err = conn.ReadMessage()
+	ExpectEq(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), true)
+}
+
+func TestLobby_OtherPlayersTicket(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: sessions}))
+	defer srv.Close()
+
+	tk, err := q.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/tickets/" + tk.ID + "/lobby?access_token=" + sessions.Create("bob")
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	ExpectThat(t, err, Not(Nil()))
+	ExpectEq(t, resp.StatusCode, 404)
+}
+
+func TestLobby_AcceptsForClientsWithoutReadyChecks(t *testing.T) {
+	q := queue.New(queue.WithReadyCheck(time.Minute))
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: sessions}))
+	defer srv.Close()
+
+	alice, err := q.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	bob, err := q.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/tickets/" + alice.ID + "/lobby?access_token=" + sessions.Create("alice")
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{protocol.Header: {"2"}})
+	AssertThat(t, err, Nil())
+	defer conn.Close()
+	readEvent(t, conn)
+
+	updates, stop, err := q.Watch(bob.ID)
+	AssertThat(t, err, Nil())
+	defer stop()
+	<-updates
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Empty())
+	for u := range updates {
+		if u.ReadyCheck != nil && len(u.ReadyCheck.Accepted) > 0 {
+			ExpectThat(t, u.ReadyCheck.Accepted, ElementsAre("alice"))
+			AssertThat(t, q.Accept(ctx, u.ReadyCheck.Match.ID, "bob"), Nil())
+			break
+		}
+	}
+	matches, err = q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+
+	// The client never sees the ready check.
+	ev := readEvent(t, conn)
+	for ev.HasQueueStatus() {
+		ev = readEvent(t, conn)
+	}
+	ExpectEq(t, ev.HasMatchFound(), true)
+}
+
+func TestLobbyEvent_Bots(t *testing.T) {
+	tk := &queue.Ticket{ID: "t1", Members: []string{"alice"}}
+	ev := lobbyEvent(queue.Update{Ticket: tk, Match: &queue.Match{ID: "m1", Tickets: []*queue.Ticket{tk}, Bots: 5}})
+	AssertEq(t, ev.HasMatchFound(), true)
+	ExpectEq(t, ev.GetMatchFound().GetBots(), int32(5))
+	ExpectThat(t, ev.GetMatchFound().GetPlayerIds(), ElementsAre("alice"))
+}
+
+func TestLobbyEvents(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: sessions}))
+	defer srv.Close()
+
+	tk, err := q.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	url := srv.URL + "/v1/tickets/" + tk.ID + "/events?access_token=" + sessions.Create("alice")
+
+	// Only event streams may carry the token in the URL.
+	resp, err := http.Get(url)
+	AssertThat(t, err, Nil())
+	resp.Body.Close()
+	ExpectEq(t, resp.StatusCode, http.StatusUnauthorized)
+
+	req, err := http.NewRequest("GET", url, nil)
+	AssertThat(t, err, Nil())
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err = http.DefaultClient.Do(req)
+	AssertThat(t, err, Nil())
+	defer resp.Body.Close()
+	AssertEq(t, resp.StatusCode, http.StatusOK)
+	ExpectEq(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	events := bufio.NewScanner(resp.Body)
+	next := func() (string, string) {
+		t.Helper()
+		var event, data string
+		for events.Scan() && events.Text() != "" {
+			field, value, _ := strings.Cut(events.Text(), ": ")
+			switch field {
+			case "event":
+				event = value
+			case "data":
+				data = value
+			}
+		}
+		return event, data
+	}
+	readEvent := func() *pb.LobbyEvent {
+		t.Helper()
+		event, data := next()
+		AssertEq(t, event, "")
+		ev := &pb.LobbyEvent{}
+		AssertThat(t, protojson.Unmarshal([]byte(data), ev), Nil())
+		return ev
+	}
+
+	ExpectEq(t, readEvent().GetQueueStatus().GetTicketId(), tk.ID)
+	_, err = q.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+	readEvent()
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, readEvent().GetMatchFound().GetMatchId(), matches[0].ID)
+
+	event, _ := next()
+	ExpectEq(t, event, "end")
+}
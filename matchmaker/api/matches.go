@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/jfmatt/snapfold/matchmaker/history"
+	"github.com/jfmatt/snapfold/matchmaker/rating"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+type matchResponse struct {
+	ID       string   `json:"id"`
+	GameMode string   `json:"game_mode"`
+	Region   string   `json:"region,omitempty"`
+	TableID  string   `json:"table_id,omitempty"`
+	Players  []string `json:"players"`
+	Bots     int      `json:"bots,omitempty"`
+
+	// The game mode's TableConfig in protobuf JSON form, if it has one.
+	TableConfig json.RawMessage `json:"table_config,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Set once the game server reports the match's results.
+	Places  map[string]int `json:"places,omitempty"`
+	EndedAt *time.Time     `json:"ended_at,omitempty"`
+}
+
+func newMatchResponse(m history.Match) matchResponse {
+	resp := matchResponse{
+		ID:        m.ID,
+		GameMode:  m.GameMode,
+		Region:    m.Region,
+		TableID:   m.TableID,
+		Players:   m.Players,
+		Bots:      m.Bots,
+		CreatedAt: m.CreatedAt,
+		Places:    m.Places,
+	}
+	if m.Config != nil {
+		resp.TableConfig, _ = protojson.Marshal(m.Config)
+	}
+	if m.Ended() {
+		resp.EndedAt = &m.EndedAt
+	}
+	return resp
+}
+
+type listMatchesResponse struct {
+	Matches       []matchResponse `json:"matches"`
+	NextPageToken string          `json:"next_page_token,omitempty"`
+}
+
+// handleListMatches lists the matches the caller has played, newest first
+// unless sorted by created_at. The page_size, page_token and sort query
+// parameters page through them, and game_mode filters them.
+func (s *Server) handleListMatches(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	req, err := history.Matches.FromQuery(r.URL.Query())
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	p, err := s.lobby.History().List(r.Context(), playerID, req)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	resp := listMatchesResponse{Matches: []matchResponse{}, NextPageToken: p.NextToken}
+	for _, m := range p.Items {
+		resp.Matches = append(resp.Matches, newMatchResponse(m))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleGetMatch returns a match from the history.
+func (s *Server) handleGetMatch(w http.ResponseWriter, r *http.Request) {
+	m, err := s.lobby.History().Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newMatchResponse(m))
+}
+
+// handleAcceptMatch confirms that the caller is ready to play a match
+// awaiting its ready check.
+func (s *Server) handleAcceptMatch(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	if err := s.queue.Accept(r.Context(), r.PathValue("id"), playerID); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeclineMatch backs the caller out of a match awaiting its ready
+// check, canceling their ticket.
+func (s *Server) handleDeclineMatch(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	if err := s.queue.Decline(r.Context(), r.PathValue("id"), playerID); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type matchResultsRequest struct {
+	// Finishing position of every player in the match, where 1 is the
+	// winner. Tied players share a position.
+	Places map[string]int `json:"places"`
+}
+
+// handleMatchResults records the outcome of a match in its history,
+// updates player ratings from it and tells webhooks. Results are accepted
+// once per match.
+func (s *Server) handleMatchResults(w http.ResponseWriter, r *http.Request) {
+	var req matchResultsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if err := s.lobby.History().Finish(r.Context(), r.PathValue("id"), req.Places); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := rating.RecordMatch(r.Context(), s.ratings, req.Places); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if s.webhooks != nil {
+		m, err := s.lobby.History().Get(r.Context(), r.PathValue("id"))
+		if err == nil {
+			err = s.webhooks.PublishMatchCompleted(r.Context(), m)
+		}
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/rating"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func TestMatchResults(t *testing.T) {
+	q := queue.New()
+	l := lobby.New(q, party.NewManager(), nil)
+	ratings := rating.NewMemStore()
+	s := NewServer(Config{Lobby: l, Sessions: session.NewStore(), Ratings: ratings, InternalToken: "secret"})
+
+	_, err := q.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+	AssertThat(t, l.RecordMatch(ctx, matches[0]), Nil())
+	path := "/v1/matches/" + matches[0].ID + "/results"
+
+	ExpectEq(t, do(t, s, "POST", path, "", `{"places": {"alice": 1, "bob": 2}}`).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", path, "wrong", `{"places": {"alice": 1, "bob": 2}}`).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", path, "secret", `{"places": {"alice": 1}}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "POST", path, "secret", `{"places": {"alice": 1, "carol": 2}}`).Code, http.StatusBadRequest)
+
+	ExpectEq(t, do(t, s, "POST", path, "secret", `{"places": {"alice": 1, "bob": 2}}`).Code, http.StatusNoContent)
+	got, err := ratings.Get(ctx, "alice", "bob")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, got["alice"].Rating, Gt(got["bob"].Rating))
+
+	// Results can only be reported once.
+	ExpectEq(t, do(t, s, "POST", path, "secret", `{"places": {"alice": 1, "bob": 2}}`).Code, http.StatusConflict)
+	ExpectEq(t, do(t, s, "POST", "/v1/matches/x/results", "secret", `{"places": {"alice": 1, "bob": 2}}`).Code, http.StatusNotFound)
+}
+
+func TestListMatches(t *testing.T) {
+	q := queue.New()
+	l := lobby.New(q, party.NewManager(), nil)
+	s := NewServer(Config{Lobby: l, Sessions: session.NewStore(), Ratings: rating.NewMemStore(), InternalToken: "secret"})
+	alice, carol := login(t, s, "alice"), login(t, s, "carol")
+
+	var ids []string
+	for range 3 {
+		_, err := q.Enqueue(ctx, "alice", "holdem")
+		AssertThat(t, err, Nil())
+		_, err = q.Enqueue(ctx, "bob", "holdem")
+		AssertThat(t, err, Nil())
+		matches, err := q.FormMatches(ctx, 2)
+		AssertThat(t, err, Nil())
+		AssertThat(t, matches, Len(1))
+		AssertThat(t, l.RecordMatch(ctx, matches[0]), Nil())
+		ids = append(ids, matches[0].ID)
+	}
+	ExpectEq(t, do(t, s, "POST", "/v1/matches/"+ids[0]+"/results", "secret", `{"places": {"alice": 1, "bob": 2}}`).Code, http.StatusNoContent)
+
+	ExpectEq(t, do(t, s, "GET", "/v1/matches", "", "").Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "GET", "/v1/matches?page_size=x", alice, "").Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "GET", "/v1/matches?page_token=x", alice, "").Code, http.StatusBadRequest)
+
+	var got []string
+	token := ""
+	for {
+		w := do(t, s, "GET", "/v1/matches?page_size=2&page_token="+token, alice, "")
+		AssertEq(t, w.Code, http.StatusOK)
+		var resp listMatchesResponse
+		AssertThat(t, json.Unmarshal(w.Body.Bytes(), &resp), Nil())
+		for _, m := range resp.Matches {
+			got = append(got, m.ID)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		token = resp.NextPageToken
+	}
+	ExpectThat(t, got, ElementsAre(ids[2], ids[1], ids[0]))
+
+	w := do(t, s, "GET", "/v1/matches?sort=created_at&game_mode=holdem", alice, "")
+	AssertEq(t, w.Code, http.StatusOK)
+	var oldest listMatchesResponse
+	AssertThat(t, json.Unmarshal(w.Body.Bytes(), &oldest), Nil())
+	AssertThat(t, oldest.Matches, Len(3))
+	ExpectEq(t, oldest.Matches[0].ID, ids[0])
+	ExpectEq(t, do(t, s, "GET", "/v1/matches?sort=region", alice, "").Code, http.StatusBadRequest)
+	// A page token only continues the list it came from.
+	w = do(t, s, "GET", "/v1/matches?page_size=1", alice, "")
+	AssertEq(t, w.Code, http.StatusOK)
+	var first listMatchesResponse
+	AssertThat(t, json.Unmarshal(w.Body.Bytes(), &first), Nil())
+	ExpectEq(t, do(t, s, "GET", "/v1/matches?sort=created_at&page_token="+first.NextPageToken, alice, "").Code, http.StatusBadRequest)
+
+	w = do(t, s, "GET", "/v1/matches", carol, "")
+	AssertEq(t, w.Code, http.StatusOK)
+	ExpectEq(t, strings.TrimSpace(w.Body.String()), `{"matches":[]}`)
+
+	w = do(t, s, "GET", "/v1/matches/"+ids[0], carol, "")
+	AssertEq(t, w.Code, http.StatusOK)
+	var m matchResponse
+	AssertThat(t, json.Unmarshal(w.Body.Bytes(), &m), Nil())
+	ExpectThat(t, m.Players, ElementsAre("alice", "bob"))
+	ExpectEq(t, m.Places["alice"], 1)
+	ExpectThat(t, m.EndedAt, Not(Nil()))
+	ExpectEq(t, do(t, s, "GET", "/v1/matches/nope", carol, "").Code, http.StatusNotFound)
+}
+
+func TestMatchResults_DisabledWithoutToken(t *testing.T) {
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore(), Ratings: rating.NewMemStore()})
+	ExpectEq(t, do(t, s, "POST", "/v1/matches/x/results", "", `{}`).Code, http.StatusUnauthorized)
+}
+
+func TestAcceptMatch(t *testing.T) {
+	q := queue.New(queue.WithReadyCheck(time.Minute))
+	s := NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: session.NewStore()})
+	alice, bob, carol := login(t, s, "alice"), login(t, s, "bob"), login(t, s, "carol")
+
+	tk, err := q.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+	updates, stop, err := q.Watch(tk.ID)
+	AssertThat(t, err, Nil())
+	defer stop()
+	<-updates
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, matches, Empty())
+	u := <-updates
+	AssertThat(t, u.ReadyCheck, Not(Nil()))
+	path := "/v1/matches/" + u.ReadyCheck.Match.ID
+
+	ExpectEq(t, do(t, s, "POST", path+"/accept", carol, "").Code, http.StatusNotFound)
+	ExpectEq(t, do(t, s, "POST", path+"/accept", alice, "").Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "POST", path+"/accept", bob, "").Code, http.StatusNoContent)
+	matches, err = q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+
+	// The check is over once the match is seated.
+	ExpectEq(t, do(t, s, "POST", path+"/decline", bob, "").Code, http.StatusNotFound)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+type partyResponse struct {
+	PartyID string   `json:"party_id"`
+	Leader  string   `json:"leader"`
+	Members []string `json:"members"`
+	Invited []string `json:"invited"`
+}
+
+func newPartyResponse(p party.Party) partyResponse {
+	return partyResponse{PartyID: p.ID, Leader: p.Leader, Members: p.Members, Invited: p.Invited}
+}
+
+func (s *Server) handleCreateParty(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	p, err := s.lobby.Parties().Create(playerID)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newPartyResponse(p))
+}
+
+// handleGetParty shows a party to its members and to players with a pending
+// invite.
+func (s *Server) handleGetParty(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	p, err := s.lobby.Parties().Get(r.PathValue("id"))
+	if err == nil && !slices.Contains(p.Members, playerID) && !slices.Contains(p.Invited, playerID) {
+		err = party.ErrNotFound
+	}
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newPartyResponse(p))
+}
+
+type inviteRequest struct {
+	PlayerID string `json:"player_id"`
+}
+
+func (s *Server) handleInvite(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	var req inviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if req.PlayerID == "" {
+		writeError(w, http.StatusBadRequest, "player_id is required")
+		return
+	}
+	p, err := s.lobby.Parties().Invite(r.PathValue("id"), playerID, req.PlayerID)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newPartyResponse(p))
+}
+
+func (s *Server) handleJoinParty(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	p, err := s.lobby.JoinParty(r.Context(), r.PathValue("id"), playerID)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newPartyResponse(p))
+}
+
+func (s *Server) handleLeaveParty(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	if _, err := s.lobby.LeaveParty(r.Context(), playerID); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/idempotency"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func decodeParty(t *testing.T, rec *httptest.ResponseRecorder) partyResponse {
+	t.Helper()
+	var resp partyResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	return resp
+}
+
+func TestParties(t *testing.T) {
+	q := queue.New()
+	s := NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: session.NewStore()})
+	alice, bob, carol := login(t, s, "alice"), login(t, s, "bob"), login(t, s, "carol")
+
+	rec := do(t, s, "POST", "/v1/parties", alice, "")
+	AssertEq(t, rec.Code, http.StatusCreated)
+	p := decodeParty(t, rec)
+	ExpectEq(t, p.Leader, "alice")
+
+	// Only invited players can see or join the party.
+	ExpectEq(t, do(t, s, "GET", "/v1/parties/"+p.PartyID, bob, "").Code, http.StatusNotFound)
+	ExpectEq(t, do(t, s, "POST", "/v1/parties/"+p.PartyID+"/join", bob, "").Code, http.StatusForbidden)
+	ExpectEq(t, do(t, s, "POST", "/v1/parties/"+p.PartyID+"/invites", bob, `{"player_id": "carol"}`).Code, http.StatusForbidden)
+
+	rec = do(t, s, "POST", "/v1/parties/"+p.PartyID+"/invites", alice, `{"player_id": "bob"}`)
+	AssertEq(t, rec.Code, http.StatusOK)
+	ExpectThat(t, decodeParty(t, rec).Invited, ElementsAre("bob"))
+	ExpectEq(t, do(t, s, "GET", "/v1/parties/"+p.PartyID, bob, "").Code, http.StatusOK)
+
+	rec = do(t, s, "POST", "/v1/parties/"+p.PartyID+"/join", bob, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	ExpectThat(t, decodeParty(t, rec).Members, ElementsAre("alice", "bob"))
+
+	// The leader queues the whole party.
+	ExpectEq(t, do(t, s, "POST", "/v1/tickets", bob, `{"game_mode": "holdem"}`).Code, http.StatusForbidden)
+	rec = do(t, s, "POST", "/v1/tickets", alice, `{"game_mode": "holdem"}`)
+	AssertEq(t, rec.Code, http.StatusCreated)
+	var tk ticketResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&tk), Nil())
+	ExpectThat(t, tk.Players, ElementsAre("alice", "bob"))
+	ExpectEq(t, do(t, s, "GET", "/v1/tickets/"+tk.TicketID, bob, "").Code, http.StatusOK)
+	ExpectEq(t, do(t, s, "GET", "/v1/tickets/"+tk.TicketID, carol, "").Code, http.StatusNotFound)
+
+	// Leaving cancels the party's ticket.
+	ExpectEq(t, do(t, s, "POST", "/v1/parties/leave", bob, "").Code, http.StatusNoContent)
+	ExpectEq(t, q.Len(), 0)
+	ExpectEq(t, do(t, s, "POST", "/v1/parties/leave", bob, "").Code, http.StatusNotFound)
+}
+
+func TestCancelTicket(t *testing.T) {
+	q := queue.New()
+	s := NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: session.NewStore()})
+	alice, bob := login(t, s, "alice"), login(t, s, "bob")
+
+	rec := do(t, s, "POST", "/v1/tickets", alice, `{"game_mode": "holdem"}`)
+	AssertEq(t, rec.Code, http.StatusCreated)
+	var tk ticketResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&tk), Nil())
+
+	ExpectEq(t, do(t, s, "DELETE", "/v1/tickets/"+tk.TicketID, bob, "").Code, http.StatusNotFound)
+	ExpectEq(t, do(t, s, "DELETE", "/v1/tickets/"+tk.TicketID, alice, "").Code, http.StatusNoContent)
+	ExpectEq(t, q.Len(), 0)
+}
+
+func TestEnqueue_IdempotencyKey(t *testing.T) {
+	q := queue.New()
+	s := NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: session.NewStore()})
+	alice := login(t, s, "alice")
+
+	rec := doWith(t, s, "POST", "/v1/tickets", alice, `{"game_mode": "holdem"}`, idempotency.Header, "k1")
+	AssertEq(t, rec.Code, http.StatusCreated)
+	var first ticketResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&first), Nil())
+
+	// A retry gets the same ticket, rather than being told alice is already
+	// queued.
+	rec = doWith(t, s, "POST", "/v1/tickets", alice, `{"game_mode": "holdem"}`, idempotency.Header, "k1")
+	AssertEq(t, rec.Code, http.StatusCreated)
+	ExpectEq(t, rec.Header().Get(idempotency.ReplayedHeader), "true")
+	var retried ticketResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&retried), Nil())
+	ExpectEq(t, retried.TicketID, first.TicketID)
+	ExpectEq(t, q.Len(), 1)
+
+	rec = doWith(t, s, "POST", "/v1/tickets", alice, `{"game_mode": "omaha"}`, idempotency.Header, "k1")
+	ExpectEq(t, rec.Code, http.StatusUnprocessableEntity)
+}
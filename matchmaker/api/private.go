@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/matchmaker/private"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+type createPrivateTableRequest struct {
+	GameMode string `json:"game_mode"`
+	Seats    int    `json:"seats"`
+
+	// A TableConfig in protobuf JSON form. If omitted, the game mode's
+	// configuration is used.
+	TableConfig json.RawMessage `json:"table_config"`
+}
+
+type joinPrivateTableRequest struct {
+	Code string `json:"code"`
+}
+
+type privateTableResponse struct {
+	TableID     string          `json:"table_id"`
+	Code        string          `json:"code"`
+	Host        string          `json:"host"`
+	GameMode    string          `json:"game_mode"`
+	Seats       int             `json:"seats"`
+	Players     []string        `json:"players"`
+	TableConfig json.RawMessage `json:"table_config"`
+}
+
+func newPrivateTableResponse(t private.Table) privateTableResponse {
+	cfg, _ := protojson.Marshal(t.Config)
+	return privateTableResponse{
+		TableID:     t.ID,
+		Code:        t.Code,
+		Host:        t.Host,
+		GameMode:    t.GameMode,
+		Seats:       t.Seats,
+		Players:     t.Players,
+		TableConfig: cfg,
+	}
+}
+
+// handleCreatePrivateTable opens a table that other players join with the
+// returned code rather than through matchmaking.
+func (s *Server) handleCreatePrivateTable(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	var req createPrivateTableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if req.GameMode == "" && req.TableConfig == nil {
+		writeError(w, http.StatusBadRequest, "game_mode or table_config is required")
+		return
+	}
+	var cfg *pb.TableConfig
+	if req.TableConfig != nil {
+		cfg = &pb.TableConfig{}
+		if err := protojson.Unmarshal(req.TableConfig, cfg); err != nil {
+			writeError(w, http.StatusBadRequest, "malformed table_config: "+err.Error())
+			return
+		}
+	}
+
+	t, err := s.lobby.CreatePrivateTable(playerID, req.GameMode, cfg, req.Seats)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newPrivateTableResponse(t))
+}
+
+func (s *Server) handleJoinPrivateTable(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	var req joinPrivateTableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if req.Code == "" {
+		writeError(w, http.StatusBadRequest, "code is required")
+		return
+	}
+
+	t, err := s.lobby.JoinPrivateTable(playerID, req.Code)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newPrivateTableResponse(t))
+}
+
+// handleGetPrivateTable shows a private table to the players seated at it.
+func (s *Server) handleGetPrivateTable(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	t, err := s.lobby.PrivateTables().Get(r.PathValue("code"))
+	if err == nil && !slices.Contains(t.Players, playerID) {
+		err = private.ErrNotFound
+	}
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newPrivateTableResponse(t))
+}
+
+func (s *Server) handleLeavePrivateTable(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	if _, err := s.lobby.PrivateTables().Leave(playerID); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func decodePrivateTable(t *testing.T, rec *httptest.ResponseRecorder) privateTableResponse {
+	t.Helper()
+	var resp privateTableResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	return resp
+}
+
+func TestPrivateTables(t *testing.T) {
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore()})
+	alice, bob, carol := login(t, s, "alice"), login(t, s, "bob"), login(t, s, "carol")
+
+	ExpectEq(t, do(t, s, "POST", "/v1/private-tables", alice, `{"game_mode": "holdem", "seats": 1}`).Code, http.StatusBadRequest)
+	rec := do(t, s, "POST", "/v1/private-tables", alice, `{"game_mode": "holdem", "seats": 2, "table_config": {"standardGameId": "nlhe", "maxPartySize": 2}}`)
+	AssertEq(t, rec.Code, http.StatusCreated)
+	tb := decodePrivateTable(t, rec)
+	ExpectEq(t, tb.Host, "alice")
+	ExpectThat(t, tb.Players, ElementsAre("alice"))
+
+	// Only seated players can see the table.
+	ExpectEq(t, do(t, s, "GET", "/v1/private-tables/"+tb.Code, bob, "").Code, http.StatusNotFound)
+	rec = do(t, s, "POST", "/v1/private-tables/join", bob, `{"code": "`+tb.Code+`"}`)
+	AssertEq(t, rec.Code, http.StatusOK)
+	ExpectThat(t, decodePrivateTable(t, rec).Players, ElementsAre("alice", "bob"))
+	ExpectEq(t, do(t, s, "GET", "/v1/private-tables/"+tb.Code, bob, "").Code, http.StatusOK)
+	ExpectEq(t, do(t, s, "POST", "/v1/private-tables/join", carol, `{"code": "`+tb.Code+`"}`).Code, http.StatusBadRequest)
+
+	// Seated players can't queue until they leave.
+	ExpectEq(t, do(t, s, "POST", "/v1/tickets", bob, `{"game_mode": "holdem"}`).Code, http.StatusConflict)
+	ExpectEq(t, do(t, s, "POST", "/v1/private-tables/leave", bob, "").Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "POST", "/v1/tickets", bob, `{"game_mode": "holdem"}`).Code, http.StatusCreated)
+	ExpectEq(t, do(t, s, "POST", "/v1/private-tables/leave", bob, "").Code, http.StatusNotFound)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+type region struct {
+	Name  string `json:"name"`
+	Probe string `json:"probe"`
+}
+
+type regionsResponse struct {
+	Regions []region `json:"regions"`
+}
+
+// handleRegions lists the regions that host games, so that clients know
+// which probe addresses to measure their latency to.
+func (s *Server) handleRegions(w http.ResponseWriter, r *http.Request) {
+	regions := s.lobby.Regions()
+	resp := regionsResponse{Regions: []region{}}
+	for _, name := range slices.Sorted(maps.Keys(regions)) {
+		resp.Regions = append(resp.Regions, region{Name: name, Probe: regions[name]})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type latencyRequest struct {
+	// Round-trip time to each region, in milliseconds.
+	RTTMillis map[string]float64 `json:"rtt_ms"`
+}
+
+func (s *Server) handleReportLatency(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	var req latencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if len(req.RTTMillis) == 0 {
+		writeError(w, http.StatusBadRequest, "rtt_ms is required")
+		return
+	}
+
+	rtts := queue.Latencies{}
+	for name, ms := range req.RTTMillis {
+		rtts[name] = time.Duration(ms * float64(time.Millisecond))
+	}
+	if err := s.lobby.ReportLatency(r.Context(), playerID, r.PathValue("id"), rtts); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func TestRegions(t *testing.T) {
+	l := lobby.New(queue.New(), party.NewManager(), nil, lobby.WithRegions(map[string]string{
+		"us-east": "use.example.com:7000",
+		"eu-west": "euw.example.com:7000",
+	}))
+	s := NewServer(Config{Lobby: l, Sessions: session.NewStore()})
+
+	rec := do(t, s, "GET", "/v1/regions", "", "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var resp regionsResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectThat(t, resp.Regions, ElementsAre(
+		region{Name: "eu-west", Probe: "euw.example.com:7000"},
+		region{Name: "us-east", Probe: "use.example.com:7000"},
+	))
+}
+
+func TestReportLatency(t *testing.T) {
+	q := queue.New()
+	l := lobby.New(q, party.NewManager(), nil, lobby.WithRegions(map[string]string{"us-east": "use.example.com:7000"}))
+	s := NewServer(Config{Lobby: l, Sessions: session.NewStore()})
+	alice, bob := login(t, s, "alice"), login(t, s, "bob")
+
+	rec := do(t, s, "POST", "/v1/tickets", alice, `{"game_mode": "holdem"}`)
+	AssertEq(t, rec.Code, http.StatusCreated)
+	var tk ticketResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&tk), Nil())
+	path := "/v1/tickets/" + tk.TicketID + "/latency"
+
+	ExpectEq(t, do(t, s, "PUT", path, alice, `{}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "PUT", path, alice, `{"rtt_ms": {"mars": 20}}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "PUT", path, bob, `{"rtt_ms": {"us-east": 20}}`).Code, http.StatusNotFound)
+
+	ExpectEq(t, do(t, s, "PUT", path, alice, `{"rtt_ms": {"us-east": 20.5}}`).Code, http.StatusNoContent)
+	ExpectEq(t, q.Latency(tk.TicketID), queue.Latencies{"us-east": 20500 * time.Microsecond})
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/season"
+)
+
+type seasonResponse struct {
+	Number    int        `json:"number"`
+	Name      string     `json:"name"`
+	StartedAt time.Time  `json:"started_at"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+}
+
+// handleCurrentSeason describes the latest ranked season, so that clients
+// can show its name and how long is left. Once a season has been closed, it
+// is still returned until the next one opens.
+func (s *Server) handleCurrentSeason(w http.ResponseWriter, r *http.Request) {
+	if s.seasons == nil {
+		writeErr(w, season.ErrNoSeason)
+		return
+	}
+	cur, err := s.seasons.Current(r.Context())
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	resp := seasonResponse{Number: cur.Number, Name: cur.Name, StartedAt: cur.StartedAt}
+	if !cur.EndsAt.IsZero() {
+		resp.EndsAt = &cur.EndsAt
+	}
+	if !cur.Open() {
+		resp.ClosedAt = &cur.ClosedAt
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type standingResponse struct {
+	Rank       int     `json:"rank"`
+	PlayerID   string  `json:"player_id"`
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+}
+
+type standingsResponse struct {
+	Standings     []standingResponse `json:"standings"`
+	NextPageToken string             `json:"next_page_token,omitempty"`
+}
+
+// handleStandings lists the final standings of a closed season, as a
+// leaderboard. The page_size, page_token and sort query parameters page
+// through them.
+func (s *Server) handleStandings(w http.ResponseWriter, r *http.Request) {
+	if s.seasons == nil {
+		writeErr(w, season.ErrNoSeason)
+		return
+	}
+	number, err := strconv.Atoi(r.PathValue("number"))
+	if err != nil || number < 1 {
+		writeError(w, http.StatusBadRequest, "season number must be a positive integer")
+		return
+	}
+	req, err := season.Leaderboard.FromQuery(r.URL.Query())
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	p, err := s.seasons.LeaderboardPage(r.Context(), number, req)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	resp := standingsResponse{Standings: []standingResponse{}, NextPageToken: p.NextToken}
+	for _, st := range p.Items {
+		resp.Standings = append(resp.Standings, standingResponse{
+			Rank:       st.Rank,
+			PlayerID:   st.PlayerID,
+			Rating:     st.Rating.Rating,
+			Deviation:  st.Rating.Deviation,
+			Volatility: st.Rating.Volatility,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/rating"
+	"github.com/jfmatt/snapfold/matchmaker/season"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func TestCurrentSeason(t *testing.T) {
+	ctx := context.Background()
+	seasons := season.NewManager(season.NewMemStore(), rating.NewMemStore())
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore(), Seasons: seasons})
+
+	ExpectEq(t, do(t, s, "GET", "/v1/seasons/current", "", "").Code, http.StatusNotFound)
+
+	endsAt := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	_, err := seasons.Open(ctx, "Spring", endsAt, season.Reset{Keep: 0.5})
+	AssertThat(t, err, Nil())
+	rec := do(t, s, "GET", "/v1/seasons/current", "", "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var resp seasonResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectEq(t, resp.Number, 1)
+	ExpectEq(t, resp.Name, "Spring")
+	AssertThat(t, resp.EndsAt, Not(Nil()))
+	ExpectEq(t, resp.EndsAt.Equal(endsAt), true)
+	ExpectThat(t, resp.ClosedAt, Nil())
+
+	_, err = seasons.Close(ctx)
+	AssertThat(t, err, Nil())
+	rec = do(t, s, "GET", "/v1/seasons/current", "", "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	resp = seasonResponse{}
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectThat(t, resp.ClosedAt, Not(Nil()))
+}
+
+func TestStandings(t *testing.T) {
+	ctx := context.Background()
+	ratings := rating.NewMemStore()
+	seasons := season.NewManager(season.NewMemStore(), ratings)
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore(), Seasons: seasons})
+
+	AssertThat(t, ratings.Put(ctx, map[string]rating.Rating{
+		"alice": {Rating: 1700},
+		"bob":   {Rating: 1600},
+		"carol": {Rating: 1500},
+	}), Nil())
+	_, err := seasons.Open(ctx, "Spring", time.Time{}, season.Reset{Keep: 1})
+	AssertThat(t, err, Nil())
+	_, err = seasons.Close(ctx)
+	AssertThat(t, err, Nil())
+
+	ExpectEq(t, do(t, s, "GET", "/v1/seasons/zero/standings", "", "").Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "GET", "/v1/seasons/1/standings?sort=rating", "", "").Code, http.StatusBadRequest)
+
+	var ids []string
+	token := ""
+	for {
+		rec := do(t, s, "GET", "/v1/seasons/1/standings?page_size=2&page_token="+token, "", "")
+		AssertEq(t, rec.Code, http.StatusOK)
+		var resp standingsResponse
+		AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+		for _, st := range resp.Standings {
+			ids = append(ids, st.PlayerID)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		token = resp.NextPageToken
+	}
+	ExpectThat(t, ids, ElementsAre("alice", "bob", "carol"))
+
+	rec := do(t, s, "GET", "/v1/seasons/1/standings?sort=-rank&page_size=1", "", "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var resp standingsResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	AssertThat(t, resp.Standings, Len(1))
+	ExpectEq(t, resp.Standings[0].PlayerID, "carol")
+	ExpectEq(t, resp.Standings[0].Rank, 3)
+}
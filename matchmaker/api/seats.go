@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/seat"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+type seatResponse struct {
+	TableID  string `json:"table_id"`
+	MatchID  string `json:"match_id"`
+	GameMode string `json:"game_mode"`
+	Region   string `json:"region,omitempty"`
+
+	// host:port of the game server hosting the table, if known.
+	ServerAddress string `json:"server_address,omitempty"`
+
+	// Set while the player is disconnected: when they lose their seat unless
+	// they rejoin.
+	RejoinDeadline *time.Time `json:"rejoin_deadline,omitempty"`
+}
+
+func newSeatResponse(s seat.Seat, grace time.Duration) seatResponse {
+	resp := seatResponse{
+		TableID:  s.TableID,
+		MatchID:  s.MatchID,
+		GameMode: s.GameMode,
+		Region:   s.Region,
+
+		ServerAddress: s.Address,
+	}
+	if s.Disconnected() {
+		deadline := s.DisconnectedAt.Add(grace)
+		resp.RejoinDeadline = &deadline
+	}
+	return resp
+}
+
+type disconnectRequest struct {
+	PlayerID string `json:"player_id"`
+}
+
+// handleRejoin tells a player who lost their connection which table to
+// reconnect to. The game server sends the full table state once they do.
+func (s *Server) handleRejoin(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	seats := s.lobby.Seats()
+	st, err := seats.Rejoin(r.Context(), playerID)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newSeatResponse(st, seats.Grace()))
+}
+
+// handleDisconnect lets a game server report that a player dropped from a
+// table. Their seat is held for the rejoin grace window; the game server is
+// expected to release it if they do not return in time.
+func (s *Server) handleDisconnect(w http.ResponseWriter, r *http.Request) {
+	var req disconnectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if req.PlayerID == "" {
+		writeError(w, http.StatusBadRequest, "player_id is required")
+		return
+	}
+
+	seats := s.lobby.Seats()
+	st, err := seats.Disconnect(r.Context(), r.PathValue("id"), req.PlayerID)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newSeatResponse(st, seats.Grace()))
+}
+
+// handleReleaseSeat lets a game server report that a player has left a
+// table for good. Players who leave soon after the table starts are
+// penalized as dodgers if the game mode says so.
+func (s *Server) handleReleaseSeat(w http.ResponseWriter, r *http.Request) {
+	if err := s.lobby.ReleaseSeat(r.Context(), r.PathValue("id"), r.PathValue("player")); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCloseTable lets a game server report that a table has finished,
+// freeing all of its seats.
+func (s *Server) handleCloseTable(w http.ResponseWriter, r *http.Request) {
+	if err := s.lobby.Seats().CloseTable(r.Context(), r.PathValue("id")); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/penalty"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func TestRejoin(t *testing.T) {
+	q := queue.New()
+	l := lobby.New(q, party.NewManager(), nil)
+	s := NewServer(Config{Lobby: l, Sessions: session.NewStore(), InternalToken: "secret"})
+	alice, carol := login(t, s, "alice"), login(t, s, "carol")
+	for _, p := range []string{"alice", "bob"} {
+		_, err := q.Enqueue(ctx, p, "holdem")
+		AssertThat(t, err, Nil())
+	}
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+	m := matches[0]
+	AssertThat(t, l.Seats().Assign(ctx, m, "10.0.0.1:7000"), Nil())
+
+	ExpectEq(t, do(t, s, "POST", "/v1/rejoin", carol, "").Code, http.StatusNotFound)
+
+	body := `{"player_id": "alice"}`
+	ExpectEq(t, do(t, s, "POST", "/v1/tables/"+m.ID+"/disconnects", "", body).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", "/v1/tables/other/disconnects", "secret", body).Code, http.StatusNotFound)
+	AssertEq(t, do(t, s, "POST", "/v1/tables/"+m.ID+"/disconnects", "secret", body).Code, http.StatusOK)
+
+	rec := do(t, s, "POST", "/v1/rejoin", alice, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var resp seatResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectEq(t, resp.TableID, m.ID)
+	ExpectEq(t, resp.GameMode, "holdem")
+	ExpectEq(t, resp.ServerAddress, "10.0.0.1:7000")
+
+	ExpectEq(t, do(t, s, "DELETE", "/v1/tables/"+m.ID+"/seats/alice", "secret", "").Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "POST", "/v1/rejoin", alice, "").Code, http.StatusNotFound)
+	ExpectEq(t, do(t, s, "DELETE", "/v1/tables/"+m.ID, "secret", "").Code, http.StatusNoContent)
+	_, err = l.Seats().Rejoin(ctx, "bob")
+	ExpectThat(t, err, Not(Nil()))
+}
+
+func TestReleaseSeat_Abandon(t *testing.T) {
+	q := queue.New()
+	penalties := penalty.NewManager(penalty.NewMemStore(), map[string]penalty.Policy{
+		"holdem": {Delays: []time.Duration{time.Minute}, AbandonWithin: time.Minute},
+	})
+	l := lobby.New(q, party.NewManager(), nil, lobby.WithPenalties(penalties))
+	s := NewServer(Config{Lobby: l, Sessions: session.NewStore(), InternalToken: "secret"})
+	alice := login(t, s, "alice")
+	for _, p := range []string{"alice", "bob"} {
+		_, err := q.Enqueue(ctx, p, "holdem")
+		AssertThat(t, err, Nil())
+	}
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+	AssertThat(t, l.Seats().Assign(ctx, matches[0], ""), Nil())
+
+	// Leaving straight away keeps the player out of the queue for a while.
+	ExpectEq(t, do(t, s, "DELETE", "/v1/tables/"+matches[0].ID+"/seats/alice", "secret", "").Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "POST", "/v1/tickets", alice, `{"game_mode": "holdem"}`).Code, http.StatusTooManyRequests)
+}
@@ -0,0 +1,481 @@
+// Package api implements the matchmaker's HTTP interface.
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/apikey"
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+	"github.com/jfmatt/snapfold/matchmaker/history"
+	"github.com/jfmatt/snapfold/matchmaker/identity"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/paging"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/penalty"
+	"github.com/jfmatt/snapfold/matchmaker/private"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/rating"
+	"github.com/jfmatt/snapfold/matchmaker/registry"
+	"github.com/jfmatt/snapfold/matchmaker/season"
+	"github.com/jfmatt/snapfold/matchmaker/seat"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+	"github.com/jfmatt/snapfold/matchmaker/wallet"
+	"github.com/jfmatt/snapfold/matchmaker/webhook"
+
+	"github.com/jfmatt/snapfold/lib/debug"
+	"github.com/jfmatt/snapfold/lib/idempotency"
+	"github.com/jfmatt/snapfold/lib/protocol"
+	"github.com/jfmatt/snapfold/lib/tracing"
+)
+
+// Config holds the dependencies and settings of a Server.
+type Config struct {
+	Lobby    *lobby.Lobby
+	Sessions *session.Store
+	Ratings  rating.Store
+
+	// Player accounts. If nil, logging in needs no password and any username
+	// is accepted, which is only suitable for development.
+	Accounts *account.Manager
+
+	// Ranked seasons. If nil, there is never a current season.
+	Seasons *season.Manager
+
+	// Keys that other services present when calling internal endpoints,
+	// each limited to its scopes. If nil, only InternalToken is accepted.
+	APIKeys *apikey.Manager
+
+	// Named game configs that game servers and tools fetch. If nil, the
+	// config endpoints reply 404.
+	Registry *registry.Registry
+
+	// Remembers the idempotency keys of requests to enqueue, so that they
+	// can be retried safely. If nil, keys are remembered in memory, which
+	// only suits a single matchmaker.
+	Idempotency *idempotency.Keeper
+
+	// Sends operators' webhooks events about matches and bans. If nil, the
+	// webhook endpoints reply 404 and no events are sent.
+	Webhooks *webhook.Manager
+
+	// Records what admins and moderators do, and what game servers report
+	// was done to their tables with their admin tokens. If nil, nothing is
+	// recorded and the audit endpoints reply 404.
+	Audit *audit.Log
+
+	// Players' money, which game servers charge for the chips players buy
+	// at real-money tables. If nil, the wallet endpoints reply 404.
+	Wallets *wallet.Wallets
+
+	// The operators' dashboard, served to admins under
+	// /v1/admin/dashboard/ with that prefix stripped. If nil, it isn't
+	// served.
+	Dashboard http.Handler
+
+	// Bearer token that grants other services every scope. If empty, only
+	// API keys are accepted.
+	InternalToken string
+}
+
+// Server serves the login and matchmaking endpoints.
+type Server struct {
+	lobby         *lobby.Lobby
+	queue         *queue.Queue
+	sessions      *session.Store
+	accounts      *account.Manager
+	ratings       rating.Store
+	seasons       *season.Manager
+	apiKeys       *apikey.Manager
+	registry      *registry.Registry
+	idempotency   *idempotency.Keeper
+	webhooks      *webhook.Manager
+	audit         *audit.Log
+	wallets       *wallet.Wallets
+	internalToken string
+	mux           *http.ServeMux
+}
+
+// NewServer returns a Server built from cfg.
+func NewServer(cfg Config) *Server {
+	s := &Server{
+		lobby:         cfg.Lobby,
+		queue:         cfg.Lobby.Queue(),
+		sessions:      cfg.Sessions,
+		accounts:      cfg.Accounts,
+		ratings:       cfg.Ratings,
+		seasons:       cfg.Seasons,
+		apiKeys:       cfg.APIKeys,
+		registry:      cfg.Registry,
+		idempotency:   cfg.Idempotency,
+		webhooks:      cfg.Webhooks,
+		audit:         cfg.Audit,
+		wallets:       cfg.Wallets,
+		internalToken: cfg.InternalToken,
+		mux:           http.NewServeMux(),
+	}
+	if s.idempotency == nil {
+		s.idempotency = idempotency.New(idempotency.NewMemStore(), idempotency.DefaultTTL)
+	}
+	s.mux.HandleFunc("POST /v1/accounts", s.handleRegister)
+	s.mux.HandleFunc("POST /v1/accounts/upgrade", s.authenticated(s.handleUpgrade))
+	s.mux.HandleFunc("POST /v1/accounts/verify", s.handleVerifyEmail)
+	s.mux.HandleFunc("POST /v1/accounts/verify/resend", s.handleResendVerification)
+	s.mux.HandleFunc("POST /v1/accounts/forgot-password", s.handleForgotPassword)
+	s.mux.HandleFunc("POST /v1/accounts/reset-password", s.handleResetPassword)
+	s.mux.HandleFunc("POST /v1/guests", s.handleCreateGuest)
+	s.mux.HandleFunc("POST /v1/login", s.handleLogin)
+	s.mux.HandleFunc("POST /v1/login/{provider}", s.handleSignIn)
+	s.mux.HandleFunc("GET /v1/identities", s.authenticated(s.handleListIdentities))
+	s.mux.HandleFunc("PUT /v1/identities/{provider}", s.authenticated(s.handleLinkIdentity))
+	s.mux.HandleFunc("DELETE /v1/identities/{provider}", s.authenticated(s.handleUnlinkIdentity))
+	s.mux.HandleFunc("GET /v1/admin/accounts/{username}", s.requireRole(account.RoleModerator, s.handleGetAccount))
+	s.mux.HandleFunc("PUT /v1/admin/accounts/{username}/role", s.requireRole(account.RoleAdmin, s.handleSetRole))
+	s.mux.HandleFunc("PUT /v1/admin/accounts/{username}/ban", s.requireRole(account.RoleModerator, s.handleBan))
+	s.mux.HandleFunc("DELETE /v1/admin/accounts/{username}/ban", s.requireRole(account.RoleModerator, s.handleUnban))
+	s.mux.HandleFunc("POST /v1/admin/accounts/{username}/password-reset", s.requireRole(account.RoleModerator, s.handleSendReset))
+	s.mux.HandleFunc("POST /v1/admin/webhooks", s.requireRole(account.RoleAdmin, s.handleRegisterWebhook))
+	s.mux.HandleFunc("GET /v1/admin/webhooks", s.requireRole(account.RoleAdmin, s.handleListWebhooks))
+	s.mux.HandleFunc("DELETE /v1/admin/webhooks/{id}", s.requireRole(account.RoleAdmin, s.handleDeleteWebhook))
+	s.mux.HandleFunc("GET /v1/admin/webhooks/{id}/deliveries", s.requireRole(account.RoleAdmin, s.handleListDeliveries))
+	s.mux.HandleFunc("POST /v1/admin/api-keys", s.requireRole(account.RoleAdmin, s.handleIssueAPIKey))
+	s.mux.HandleFunc("GET /v1/admin/api-keys", s.requireRole(account.RoleAdmin, s.handleListAPIKeys))
+	s.mux.HandleFunc("DELETE /v1/admin/api-keys/{id}", s.requireRole(account.RoleAdmin, s.handleRevokeAPIKey))
+	s.mux.HandleFunc("GET /v1/admin/rake", s.requireRole(account.RoleAdmin, s.handleRakeReport))
+	s.mux.HandleFunc("GET /v1/admin/audit", s.requireRole(account.RoleAdmin, s.handleListAudit))
+	s.mux.HandleFunc("POST /v1/admin/wallets/{username}/deposits", s.requireRole(account.RoleAdmin, s.handleDeposit))
+	s.mux.HandleFunc("/v1/admin/debug/", s.requireRole(account.RoleAdmin, debug.Handler("/v1/admin/debug/").ServeHTTP))
+	if cfg.Dashboard != nil {
+		s.mux.HandleFunc("GET /v1/admin/dashboard/", s.requireRole(account.RoleAdmin, http.StripPrefix("/v1/admin/dashboard", cfg.Dashboard).ServeHTTP))
+	}
+	s.mux.HandleFunc("GET /v1/configs/{kind}", s.internal(apikey.ScopeConfigs, s.handleListConfigs))
+	s.mux.HandleFunc("GET /v1/configs/{kind}/{name}", s.internal(apikey.ScopeConfigs, s.handleGetConfig))
+	s.mux.HandleFunc("PUT /v1/configs/{kind}/{name}", s.requireRole(account.RoleAdmin, s.handlePutConfig))
+	s.mux.HandleFunc("DELETE /v1/configs/{kind}/{name}", s.requireRole(account.RoleAdmin, s.handleDeleteConfig))
+	s.mux.HandleFunc("GET /v1/sessions", s.authenticated(s.handleListSessions))
+	s.mux.HandleFunc("DELETE /v1/sessions", s.authenticated(s.handleRevokeAllSessions))
+	s.mux.HandleFunc("DELETE /v1/sessions/{id}", s.authenticated(s.handleRevokeSession))
+	s.mux.HandleFunc("POST /v1/sessions/refresh", s.handleRefresh)
+	s.mux.HandleFunc("POST /v1/logout", s.handleLogout)
+	s.mux.HandleFunc("POST /v1/tickets", s.authenticated(s.idempotent(s.handleEnqueue)))
+	s.mux.HandleFunc("GET /v1/tickets/{id}", s.authenticated(s.handleGetTicket))
+	s.mux.HandleFunc("DELETE /v1/tickets/{id}", s.authenticated(s.handleCancelTicket))
+	s.mux.HandleFunc("GET /v1/tickets/{id}/lobby", s.authenticated(s.handleLobby))
+	s.mux.HandleFunc("GET /v1/tickets/{id}/events", s.authenticated(s.handleLobbyEvents))
+	s.mux.HandleFunc("PUT /v1/tickets/{id}/latency", s.authenticated(s.handleReportLatency))
+	s.mux.HandleFunc("GET /v1/regions", s.handleRegions)
+	s.mux.HandleFunc("GET /v1/wait-estimate", s.handleWaitEstimate)
+	s.mux.HandleFunc("GET /v1/seasons/current", s.handleCurrentSeason)
+	s.mux.HandleFunc("GET /v1/seasons/{number}/standings", s.handleStandings)
+	s.mux.HandleFunc("POST /v1/parties", s.authenticated(s.handleCreateParty))
+	s.mux.HandleFunc("GET /v1/parties/{id}", s.authenticated(s.handleGetParty))
+	s.mux.HandleFunc("POST /v1/parties/{id}/invites", s.authenticated(s.handleInvite))
+	s.mux.HandleFunc("POST /v1/parties/{id}/join", s.authenticated(s.handleJoinParty))
+	s.mux.HandleFunc("POST /v1/parties/leave", s.authenticated(s.handleLeaveParty))
+	s.mux.HandleFunc("POST /v1/private-tables", s.authenticated(s.handleCreatePrivateTable))
+	s.mux.HandleFunc("GET /v1/private-tables/{code}", s.authenticated(s.handleGetPrivateTable))
+	s.mux.HandleFunc("POST /v1/private-tables/join", s.authenticated(s.handleJoinPrivateTable))
+	s.mux.HandleFunc("POST /v1/private-tables/leave", s.authenticated(s.handleLeavePrivateTable))
+	s.mux.HandleFunc("GET /v1/matches", s.authenticated(s.handleListMatches))
+	s.mux.HandleFunc("GET /v1/matches/{id}", s.authenticated(s.handleGetMatch))
+	s.mux.HandleFunc("POST /v1/matches/{id}/accept", s.authenticated(s.handleAcceptMatch))
+	s.mux.HandleFunc("POST /v1/matches/{id}/decline", s.authenticated(s.handleDeclineMatch))
+	s.mux.HandleFunc("POST /v1/matches/{id}/results", s.internal(apikey.ScopeMatches, s.handleMatchResults))
+	s.mux.HandleFunc("GET /v1/hands", s.authenticated(s.handleDownloadHands))
+	s.mux.HandleFunc("GET /v1/wallet", s.authenticated(s.handleGetWallet))
+	s.mux.HandleFunc("POST /v1/rejoin", s.authenticated(s.handleRejoin))
+	s.mux.HandleFunc("POST /v1/tables/{id}/disconnects", s.internal(apikey.ScopeTables, s.handleDisconnect))
+	s.mux.HandleFunc("DELETE /v1/tables/{id}/seats/{player}", s.internal(apikey.ScopeTables, s.handleReleaseSeat))
+	s.mux.HandleFunc("POST /v1/tables/{id}/hands", s.internal(apikey.ScopeTables, s.handleRecordHand))
+	s.mux.HandleFunc("DELETE /v1/tables/{id}", s.internal(apikey.ScopeTables, s.handleCloseTable))
+	s.mux.HandleFunc("POST /v1/tables/{id}/audit", s.internal(apikey.ScopeTables, s.handleRecordTableAction))
+	s.mux.HandleFunc("GET /v1/sessions/revoked", s.internal(apikey.ScopeTables, s.handleRevokedSessions))
+	s.mux.HandleFunc("POST /v1/wallets/{player}/debits", s.internal(apikey.ScopeTables, s.handleDebit))
+	s.mux.HandleFunc("POST /v1/wallets/{player}/credits", s.internal(apikey.ScopeTables, s.handleCredit))
+	s.mux.HandleFunc("POST /v1/backfills", s.internal(apikey.ScopeBackfills, s.handleRequestBackfill))
+	s.mux.HandleFunc("DELETE /v1/backfills/{id}", s.internal(apikey.ScopeBackfills, s.handleCancelBackfill))
+	s.mux.HandleFunc("POST /v1/priority-tickets", s.internal(apikey.ScopeBackfills, s.handlePriorityTickets))
+	return s
+}
+
+// ServeHTTP serves a request, once its client's protocol version is known
+// to be supported.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	protocol.Handler(tracing.Handler(s.mux)).ServeHTTP(w, r)
+}
+
+type enqueueRequest struct {
+	GameMode string `json:"game_mode"`
+}
+
+type ticketResponse struct {
+	TicketID string   `json:"ticket_id"`
+	GameMode string   `json:"game_mode"`
+	Players  []string `json:"players"`
+	Priority string   `json:"priority"`
+	State    string   `json:"state"`
+
+	// Only meaningful while the ticket is queued.
+	Position int `json:"position"`
+
+	// Set once the ticket is matched.
+	MatchID string `json:"match_id,omitempty"`
+}
+
+func newTicketResponse(r queue.Record, pos int) ticketResponse {
+	return ticketResponse{
+		TicketID: r.Ticket.ID,
+		GameMode: r.Ticket.GameMode,
+		Players:  r.Ticket.Members,
+		Priority: r.Ticket.Priority.String(),
+		State:    r.State.String(),
+		Position: pos,
+		MatchID:  r.MatchID,
+	}
+}
+
+func (s *Server) handleEnqueue(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	var req enqueueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if req.GameMode == "" {
+		writeError(w, http.StatusBadRequest, "game_mode is required")
+		return
+	}
+
+	t, err := s.lobby.Enqueue(r.Context(), playerID, req.GameMode)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	rec, pos, _ := s.queue.Status(r.Context(), t.ID)
+	writeJSON(w, http.StatusCreated, newTicketResponse(rec, pos))
+}
+
+// handleGetTicket reports a ticket's state. Tickets remain visible for some
+// time after they leave the queue, so clients can learn how their search
+// ended.
+func (s *Server) handleGetTicket(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	rec, pos, err := s.lobby.Ticket(r.Context(), playerID, r.PathValue("id"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTicketResponse(rec, pos))
+}
+
+// handleCancelTicket takes a ticket out of the queue. It is idempotent:
+// canceling a canceled ticket succeeds again, while canceling one that was
+// matched or has expired is a conflict.
+func (s *Server) handleCancelTicket(w http.ResponseWriter, r *http.Request) {
+	playerID, _ := session.PlayerFrom(r.Context())
+	if _, err := s.lobby.Cancel(r.Context(), playerID, r.PathValue("id")); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authenticated wraps a handler so that it only runs for requests carrying a
+// valid bearer token. The session's player ID is available to the handler via
+// session.PlayerFrom, and its ID via session.SessionFrom.
+//
+// Browsers cannot set headers on WebSocket handshakes or event streams, so
+// those may carry the token in an access_token query parameter instead.
+func (s *Server) authenticated(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := session.BearerToken(r.Header.Get("Authorization"))
+		if !ok && (websocket.IsWebSocketUpgrade(r) || r.Header.Get("Accept") == "text/event-stream") {
+			token = r.URL.Query().Get("access_token")
+			ok = token != ""
+		}
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		ctx, ok := s.sessions.Authenticate(r.Context(), token)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "invalid session")
+			return
+		}
+		h(w, r.WithContext(ctx))
+	}
+}
+
+// idempotent wraps an authenticated handler so that a request carrying an
+// Idempotency-Key header is only handled once per key and player; retries
+// get the first response.
+func (s *Server) idempotent(h http.HandlerFunc) http.HandlerFunc {
+	return s.idempotency.Handler(func(r *http.Request) string {
+		playerID, _ := session.PlayerFrom(r.Context())
+		return playerID
+	}, h).ServeHTTP
+}
+
+// requireRole wraps a handler so that it only runs for authenticated
+// requests from accounts whose role includes role. The role is looked up on
+// every request, so changes take effect without the player logging in again.
+func (s *Server) requireRole(role account.Role, h http.HandlerFunc) http.HandlerFunc {
+	return s.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		if s.accounts == nil {
+			writeError(w, http.StatusForbidden, "accounts are disabled")
+			return
+		}
+		playerID, _ := session.PlayerFrom(r.Context())
+		if err := s.accounts.Authorize(r.Context(), playerID, role); err != nil {
+			writeErr(w, err)
+			return
+		}
+		h(w, r)
+	})
+}
+
+// record adds an admin action taken by the request's account to the audit
+// log, if there is one. Handlers record an action before making its change,
+// and make it only if recording succeeds, so that no change is missing from
+// the log. They check first what they can; a change that fails after being
+// recorded is left in the log as an attempt.
+func (s *Server) record(r *http.Request, action audit.Action, target string, before, after any) error {
+	if s.audit == nil {
+		return nil
+	}
+	actor, _ := session.PlayerFrom(r.Context())
+	return s.audit.Record(r.Context(), actor, action, target, before, after)
+}
+
+// internal wraps a handler so that it only runs for requests from other
+// snapfold services, which carry either the configured internal token or an
+// API key with the scope.
+func (s *Server) internal(scope apikey.Scope, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := session.BearerToken(r.Header.Get("Authorization"))
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		if s.internalToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.internalToken)) == 1 {
+			h(w, r)
+			return
+		}
+		if s.apiKeys == nil {
+			writeError(w, http.StatusUnauthorized, "invalid internal token")
+			return
+		}
+		if _, err := s.apiKeys.Verify(r.Context(), token, scope); err != nil {
+			writeErr(w, err)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// writeErr replies with an error from the matchmaking packages, choosing a
+// status code based on its cause.
+func writeErr(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, queue.ErrNotFound),
+		errors.Is(err, queue.ErrNoBackfill),
+		errors.Is(err, queue.ErrNoReadyCheck),
+		errors.Is(err, party.ErrNotFound),
+		errors.Is(err, party.ErrNotInParty),
+		errors.Is(err, private.ErrNotFound),
+		errors.Is(err, private.ErrNotSeated),
+		errors.Is(err, seat.ErrNotSeated),
+		errors.Is(err, account.ErrNotFound),
+		errors.Is(err, session.ErrNotFound),
+		errors.Is(err, account.ErrUnknownProvider),
+		errors.Is(err, account.ErrResetDisabled),
+		errors.Is(err, apikey.ErrNotFound),
+		errors.Is(err, history.ErrNotFound),
+		errors.Is(err, registry.ErrNotFound),
+		errors.Is(err, webhook.ErrNotFound),
+		errors.Is(err, season.ErrNoSeason):
+		status = http.StatusNotFound
+	case errors.Is(err, queue.ErrAlreadyQueued),
+		errors.Is(err, queue.ErrClosed),
+		errors.Is(err, party.ErrInParty),
+		errors.Is(err, private.ErrSeated),
+		errors.Is(err, seat.ErrGraceExpired),
+		errors.Is(err, history.ErrReported),
+		errors.Is(err, season.ErrSeasonOpen),
+		errors.Is(err, account.ErrUsernameTaken),
+		errors.Is(err, account.ErrIdentityLinked),
+		errors.Is(err, account.ErrNotGuest),
+		errors.Is(err, account.ErrEmailVerified),
+		errors.Is(err, account.ErrProviderLinked),
+		errors.Is(err, account.ErrLastCredential),
+		errors.Is(err, account.ErrNoEmail):
+		status = http.StatusConflict
+	case errors.Is(err, account.ErrInvalidCredentials),
+		errors.Is(err, session.ErrInvalidToken),
+		errors.Is(err, identity.ErrInvalidToken),
+		errors.Is(err, apikey.ErrInvalidKey):
+		status = http.StatusUnauthorized
+	case errors.Is(err, account.ErrEmailUnverified),
+		errors.Is(err, account.ErrBanned),
+		errors.Is(err, account.ErrForbidden),
+		errors.Is(err, apikey.ErrScope),
+		errors.Is(err, party.ErrNotLeader),
+		errors.Is(err, party.ErrNotInvited):
+		status = http.StatusForbidden
+	case errors.Is(err, lobby.ErrUnknownGameMode),
+		errors.Is(err, account.ErrInvalidUsername),
+		errors.Is(err, account.ErrInvalidPassword),
+		errors.Is(err, account.ErrInvalidEmail),
+		errors.Is(err, account.ErrInvalidVerification),
+		errors.Is(err, account.ErrInvalidReset),
+		errors.Is(err, account.ErrInvalidRole),
+		errors.Is(err, apikey.ErrInvalidScope),
+		errors.Is(err, audit.ErrInvalidAction),
+		errors.Is(err, webhook.ErrInvalidURL),
+		errors.Is(err, webhook.ErrInvalidEvent),
+		errors.Is(err, lobby.ErrPartyTooLarge),
+		errors.Is(err, lobby.ErrUnknownRegion),
+		errors.Is(err, lobby.ErrInvalidLatency),
+		errors.Is(err, party.ErrPartyFull),
+		errors.Is(err, party.ErrInviteSelf),
+		errors.Is(err, private.ErrTableFull),
+		errors.Is(err, private.ErrInvalidSeats),
+		errors.Is(err, history.ErrInvalidPlaces),
+		errors.Is(err, history.ErrInvalidHand),
+		errors.Is(err, paging.ErrInvalidToken),
+		errors.Is(err, paging.ErrInvalidSize),
+		errors.Is(err, paging.ErrInvalidSort),
+		errors.Is(err, registry.ErrInvalidKind),
+		errors.Is(err, registry.ErrInvalidName),
+		errors.Is(err, registry.ErrInvalidConfig),
+		errors.Is(err, wallet.ErrInvalidAmount),
+		errors.Is(err, wallet.ErrInvalidRef):
+		status = http.StatusBadRequest
+	case errors.Is(err, wallet.ErrInsufficientFunds):
+		status = http.StatusPaymentRequired
+	case errors.Is(err, wallet.ErrNoDebit):
+		status = http.StatusConflict
+	case errors.Is(err, registry.ErrConflict):
+		status = http.StatusPreconditionFailed
+	case errors.Is(err, penalty.ErrPenalized),
+		errors.Is(err, account.ErrResendTooSoon):
+		status = http.StatusTooManyRequests
+	}
+	writeError(w, status, err.Error())
+}
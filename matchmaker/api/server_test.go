@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/protocol"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+var ctx = context.Background()
+
+func do(t *testing.T, s *Server, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func login(t *testing.T, s *Server, username string) string {
+	t.Helper()
+	rec := do(t, s, "POST", "/v1/login", "", `{"username": "`+username+`"}`)
+	AssertEq(t, rec.Code, http.StatusOK)
+	var resp loginResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectEq(t, resp.PlayerID, username)
+	return resp.Token
+}
+
+func TestLogin_RequiresUsername(t *testing.T) {
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore()})
+	ExpectEq(t, do(t, s, "POST", "/v1/login", "", `{}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "POST", "/v1/login", "", `not json`).Code, http.StatusBadRequest)
+}
+
+func TestProtocol(t *testing.T) {
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore()})
+	for _, tc := range []struct {
+		hello  string
+		status int
+	}{
+		{"", http.StatusOK},
+		{"1", http.StatusOK},
+		{"2 ready-checks", http.StatusOK},
+		{"99 teleportation", http.StatusOK},
+		{"0", http.StatusUpgradeRequired},
+		{"latest", http.StatusBadRequest},
+	} {
+		t.Run(tc.hello, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/regions", nil)
+			req.Header.Set(protocol.Header, tc.hello)
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			ExpectEq(t, rec.Code, tc.status)
+			ExpectEq(t, rec.Header().Get(protocol.Header), "3")
+		})
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	q := queue.New()
+	s := NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: session.NewStore()})
+	token := login(t, s, "alice")
+
+	rec := do(t, s, "POST", "/v1/tickets", token, `{"game_mode": "holdem"}`)
+	AssertEq(t, rec.Code, http.StatusCreated)
+	var resp ticketResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectEq(t, resp.GameMode, "holdem")
+	ExpectEq(t, resp.Position, 0)
+	ExpectEq(t, q.Len(), 1)
+
+	rec = do(t, s, "GET", "/v1/tickets/"+resp.TicketID, token, "")
+	ExpectEq(t, rec.Code, http.StatusOK)
+
+	// Queueing twice is a conflict.
+	rec = do(t, s, "POST", "/v1/tickets", token, `{"game_mode": "holdem"}`)
+	ExpectEq(t, rec.Code, http.StatusConflict)
+
+	// Other players can't see the ticket.
+	other := login(t, s, "bob")
+	ExpectEq(t, do(t, s, "GET", "/v1/tickets/"+resp.TicketID, other, "").Code, http.StatusNotFound)
+}
+
+func TestCancelTicket_Idempotent(t *testing.T) {
+	q := queue.New()
+	s := NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: session.NewStore()})
+	alice := login(t, s, "alice")
+	bob := login(t, s, "bob")
+
+	var a, b ticketResponse
+	rec := do(t, s, "POST", "/v1/tickets", alice, `{"game_mode": "holdem"}`)
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&a), Nil())
+	rec = do(t, s, "POST", "/v1/tickets", bob, `{"game_mode": "holdem"}`)
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&b), Nil())
+
+	// Canceling is idempotent, and the ticket stays visible afterwards.
+	ExpectEq(t, do(t, s, "DELETE", "/v1/tickets/"+a.TicketID, alice, "").Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "DELETE", "/v1/tickets/"+a.TicketID, alice, "").Code, http.StatusNoContent)
+	rec = do(t, s, "GET", "/v1/tickets/"+a.TicketID, alice, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var resp ticketResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectEq(t, resp.State, "canceled")
+
+	// A matched ticket can't be canceled.
+	_, err := q.Enqueue(ctx, "carol", "holdem")
+	AssertThat(t, err, Nil())
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, do(t, s, "DELETE", "/v1/tickets/"+b.TicketID, bob, "").Code, http.StatusConflict)
+	rec = do(t, s, "GET", "/v1/tickets/"+b.TicketID, bob, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectEq(t, resp.State, "matched")
+	ExpectEq(t, resp.MatchID, matches[0].ID)
+
+	// Nor can it be watched.
+	ExpectEq(t, do(t, s, "GET", "/v1/tickets/"+b.TicketID+"/lobby", bob, "").Code, http.StatusConflict)
+}
+
+func TestEnqueue_RequiresSession(t *testing.T) {
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore()})
+	ExpectEq(t, do(t, s, "POST", "/v1/tickets", "", `{"game_mode": "holdem"}`).Code, http.StatusUnauthorized)
+	ExpectEq(t, do(t, s, "POST", "/v1/tickets", "bogus", `{"game_mode": "holdem"}`).Code, http.StatusUnauthorized)
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+)
+
+type waitEstimateResponse struct {
+	GameMode string `json:"game_mode"`
+	Region   string `json:"region,omitempty"`
+	MedianMS int64  `json:"median_ms"`
+	P90MS    int64  `json:"p90_ms"`
+	Samples  int    `json:"samples"`
+}
+
+// handleWaitEstimate reports how long recent tickets for a game mode waited,
+// so that clients can show an expected wait before and during queueing. The
+// estimate covers a single region if the region parameter is given.
+func (s *Server) handleWaitEstimate(w http.ResponseWriter, r *http.Request) {
+	gameMode, region := r.URL.Query().Get("game_mode"), r.URL.Query().Get("region")
+	if gameMode == "" {
+		writeError(w, http.StatusBadRequest, "game_mode is required")
+		return
+	}
+	e, err := s.lobby.EstimateWait(gameMode, region)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, waitEstimateResponse{
+		GameMode: e.GameMode,
+		Region:   e.Region,
+		MedianMS: e.Median.Milliseconds(),
+		P90MS:    e.P90.Milliseconds(),
+		Samples:  e.Samples,
+	})
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func TestWaitEstimate(t *testing.T) {
+	q := queue.New()
+	s := NewServer(Config{Lobby: lobby.New(q, party.NewManager(), nil), Sessions: session.NewStore()})
+	ExpectEq(t, do(t, s, "GET", "/v1/wait-estimate", "", "").Code, http.StatusBadRequest)
+
+	_, err := q.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+
+	rec := do(t, s, "GET", "/v1/wait-estimate?game_mode=holdem", "", "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var resp waitEstimateResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&resp), Nil())
+	ExpectEq(t, resp.GameMode, "holdem")
+	ExpectEq(t, resp.Samples, 2)
+	ExpectThat(t, resp.MedianMS, Le(int64(1000)))
+}
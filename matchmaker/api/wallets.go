@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+	"github.com/jfmatt/snapfold/matchmaker/wallet"
+)
+
+type transactionRequest struct {
+	Amount int64 `json:"amount"`
+
+	// Identifies the transaction, so that a retried one is applied once.
+	Ref string `json:"ref"`
+}
+
+type walletResponse struct {
+	Balance int64 `json:"balance"`
+}
+
+// handleGetWallet replies with the money in the caller's wallet.
+func (s *Server) handleGetWallet(w http.ResponseWriter, r *http.Request) {
+	if s.wallets == nil {
+		writeError(w, http.StatusNotFound, "wallets are disabled")
+		return
+	}
+	playerID, _ := session.PlayerFrom(r.Context())
+	balance, err := s.wallets.Balance(r.Context(), playerID)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, walletResponse{Balance: balance})
+}
+
+// handleDebit charges a player for chips a game server is adding at one of
+// its tables.
+func (s *Server) handleDebit(w http.ResponseWriter, r *http.Request) {
+	s.transact(w, r, s.wallets.Debit)
+}
+
+// handleCredit gives back a debit whose chips a game server could not add.
+func (s *Server) handleCredit(w http.ResponseWriter, r *http.Request) {
+	s.transact(w, r, s.wallets.Credit)
+}
+
+// transact applies the transaction in the request body to the wallet of
+// the player in the path, and replies with their balance.
+func (s *Server) transact(w http.ResponseWriter, r *http.Request, apply func(ctx context.Context, playerID string, amount int64, ref string) (int64, error)) {
+	if s.wallets == nil {
+		writeError(w, http.StatusNotFound, "wallets are disabled")
+		return
+	}
+	var req transactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	balance, err := apply(r.Context(), r.PathValue("player"), req.Amount, req.Ref)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, walletResponse{Balance: balance})
+}
+
+// handleDeposit adds money to an account's wallet.
+func (s *Server) handleDeposit(w http.ResponseWriter, r *http.Request) {
+	if s.wallets == nil {
+		writeError(w, http.StatusNotFound, "wallets are disabled")
+		return
+	}
+	var req transactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	if err := wallet.Check(req.Amount, req.Ref); err != nil {
+		writeErr(w, err)
+		return
+	}
+	a, err := s.accounts.Get(r.Context(), r.PathValue("username"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	before, err := s.wallets.Balance(r.Context(), a.Username)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	after := walletResponse{Balance: before + req.Amount}
+	if err := s.record(r, audit.WalletDeposit, a.Username, walletResponse{Balance: before}, after); err != nil {
+		writeErr(w, err)
+		return
+	}
+	balance, err := s.wallets.Deposit(r.Context(), a.Username, req.Amount, req.Ref)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, walletResponse{Balance: balance})
+}
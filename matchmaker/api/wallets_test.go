@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+	"github.com/jfmatt/snapfold/matchmaker/wallet"
+)
+
+func TestWallets(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	sessions := session.NewStore()
+	s := NewServer(Config{
+		Lobby:         lobby.New(queue.New(), party.NewManager(), nil),
+		Sessions:      sessions,
+		Accounts:      accounts,
+		Audit:         audit.New(audit.NewMemStore()),
+		Wallets:       wallet.New(wallet.NewMemStore()),
+		InternalToken: "secret",
+	})
+	for _, name := range []string{"alice", "bob"} {
+		_, err := accounts.Register(ctx, name, name+"@example.com", "correct horse")
+		AssertThat(t, err, Nil())
+	}
+	AssertThat(t, accounts.SetRole(ctx, "alice", account.RoleAdmin), Nil())
+	alice, bob := sessions.Create("alice"), sessions.Create("bob")
+	balance := func(rec *httptest.ResponseRecorder) int64 {
+		t.Helper()
+		AssertEq(t, rec.Code, http.StatusOK)
+		var w walletResponse
+		AssertThat(t, json.NewDecoder(rec.Body).Decode(&w), Nil())
+		return w.Balance
+	}
+
+	ExpectEq(t, do(t, s, "POST", "/v1/admin/wallets/bob/deposits", bob, `{"amount": 5000, "ref": "d1"}`).Code, http.StatusForbidden)
+	ExpectEq(t, do(t, s, "POST", "/v1/admin/wallets/bob/deposits", alice, `{"amount": 0, "ref": "d1"}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "POST", "/v1/admin/wallets/carol/deposits", alice, `{"amount": 5000, "ref": "d1"}`).Code, http.StatusNotFound)
+	ExpectEq(t, balance(do(t, s, "POST", "/v1/admin/wallets/bob/deposits", alice, `{"amount": 5000, "ref": "d1"}`)), int64(5000))
+	ExpectEq(t, balance(do(t, s, "GET", "/v1/wallet", bob, "")), int64(5000))
+	entries := listAudit(t, s, alice, "?action=wallet.deposit")
+	AssertThat(t, entries, Len(1))
+	ExpectEq(t, entries[0].Target, "bob")
+	ExpectEq(t, string(entries[0].After), `{"balance":5000}`)
+
+	// Game servers debit players for chips, and credit them with debits
+	// whose chips they couldn't add.
+	ExpectEq(t, do(t, s, "POST", "/v1/wallets/bob/debits", bob, `{"amount": 2000, "ref": "m1/1"}`).Code, http.StatusUnauthorized)
+	for range 2 {
+		ExpectEq(t, balance(do(t, s, "POST", "/v1/wallets/bob/debits", "secret", `{"amount": 2000, "ref": "m1/1"}`)), int64(3000))
+	}
+	ExpectEq(t, do(t, s, "POST", "/v1/wallets/bob/debits", "secret", `{"amount": 4000, "ref": "m1/2"}`).Code, http.StatusPaymentRequired)
+	ExpectEq(t, do(t, s, "POST", "/v1/wallets/bob/debits", "secret", `{"amount": 1000}`).Code, http.StatusBadRequest)
+	ExpectEq(t, balance(do(t, s, "POST", "/v1/wallets/bob/credits", "secret", `{"amount": 2000, "ref": "m1/1"}`)), int64(5000))
+}
+
+func TestWallets_Disabled(t *testing.T) {
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: session.NewStore(), InternalToken: "secret"})
+	ExpectEq(t, do(t, s, "GET", "/v1/wallet", login(t, s, "alice"), "").Code, http.StatusNotFound)
+	ExpectEq(t, do(t, s, "POST", "/v1/wallets/alice/debits", "secret", `{"amount": 1, "ref": "m1/1"}`).Code, http.StatusNotFound)
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+	"github.com/jfmatt/snapfold/matchmaker/webhook"
+)
+
+type registerWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type webhookResponse struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newWebhookResponse(h webhook.Hook) webhookResponse {
+	resp := webhookResponse{ID: h.ID, URL: h.URL, Events: []string{}, CreatedAt: h.CreatedAt}
+	for _, e := range h.Events {
+		resp.Events = append(resp.Events, string(e))
+	}
+	return resp
+}
+
+type registerWebhookResponse struct {
+	webhookResponse
+
+	// Signs the webhook's deliveries. It is only ever shown here.
+	Secret string `json:"secret"`
+}
+
+// handleRegisterWebhook registers a URL to be sent events.
+func (s *Server) handleRegisterWebhook(w http.ResponseWriter, r *http.Request) {
+	if s.webhooks == nil {
+		writeError(w, http.StatusNotFound, "webhooks are disabled")
+		return
+	}
+	var req registerWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+	var events []webhook.Event
+	for _, name := range req.Events {
+		e, err := webhook.ParseEvent(name)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		events = append(events, e)
+	}
+	h, err := s.webhooks.NewHook(req.URL, events...)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.record(r, audit.WebhookRegistered, h.ID, nil, newWebhookResponse(h)); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.webhooks.Save(r.Context(), h); err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, registerWebhookResponse{webhookResponse: newWebhookResponse(h), Secret: h.Secret})
+}
+
+type listWebhooksResponse struct {
+	Webhooks []webhookResponse `json:"webhooks"`
+}
+
+// handleListWebhooks lists every registered webhook.
+func (s *Server) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	if s.webhooks == nil {
+		writeError(w, http.StatusNotFound, "webhooks are disabled")
+		return
+	}
+	hooks, err := s.webhooks.List(r.Context())
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	resp := listWebhooksResponse{Webhooks: []webhookResponse{}}
+	for _, h := range hooks {
+		resp.Webhooks = append(resp.Webhooks, newWebhookResponse(h))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleDeleteWebhook stops sending events to a webhook.
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if s.webhooks == nil {
+		writeError(w, http.StatusNotFound, "webhooks are disabled")
+		return
+	}
+	h, err := s.webhooks.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.record(r, audit.WebhookDeleted, h.ID, newWebhookResponse(h), nil); err != nil {
+		writeErr(w, err)
+		return
+	}
+	if err := s.webhooks.Delete(r.Context(), h.ID); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type deliveryResponse struct {
+	ID            string          `json:"id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+	State         string          `json:"state"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatus    int             `json:"last_status,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+type listDeliveriesResponse struct {
+	Deliveries    []deliveryResponse `json:"deliveries"`
+	NextPageToken string             `json:"next_page_token,omitempty"`
+}
+
+// handleListDeliveries lists a webhook's deliveries and the outcome of
+// their last attempts, newest first unless sorted by created_at. The
+// page_size, page_token and sort query parameters page through them.
+func (s *Server) handleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	if s.webhooks == nil {
+		writeError(w, http.StatusNotFound, "webhooks are disabled")
+		return
+	}
+	req, err := webhook.DeliveryLog.FromQuery(r.URL.Query())
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	p, err := s.webhooks.Deliveries(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	resp := listDeliveriesResponse{Deliveries: []deliveryResponse{}, NextPageToken: p.NextToken}
+	for _, d := range p.Items {
+		dr := deliveryResponse{
+			ID:         d.ID,
+			Event:      string(d.Event),
+			Payload:    d.Payload,
+			CreatedAt:  d.CreatedAt,
+			State:      string(d.State),
+			Attempts:   d.Attempts,
+			LastStatus: d.LastStatus,
+			LastError:  d.LastError,
+		}
+		if d.State == webhook.Pending {
+			dr.NextAttemptAt = &d.NextAttemptAt
+		}
+		if d.State == webhook.Delivered {
+			dr.DeliveredAt = &d.DeliveredAt
+		}
+		resp.Deliveries = append(resp.Deliveries, dr)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+	"github.com/jfmatt/snapfold/matchmaker/webhook"
+)
+
+func TestWebhooks(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	sessions := session.NewStore()
+	s := NewServer(Config{
+		Lobby:    lobby.New(queue.New(), party.NewManager(), nil),
+		Sessions: sessions,
+		Accounts: accounts,
+		Webhooks: webhook.NewManager(webhook.NewMemStore()),
+	})
+	for _, name := range []string{"alice", "bob"} {
+		_, err := accounts.Register(ctx, name, name+"@example.com", "correct horse")
+		AssertThat(t, err, Nil())
+	}
+	AssertThat(t, accounts.SetRole(ctx, "alice", account.RoleAdmin), Nil())
+	alice, bob := sessions.Create("alice"), sessions.Create("bob")
+
+	ExpectEq(t, do(t, s, "POST", "/v1/admin/webhooks", bob, `{"url": "https://example.com/hook"}`).Code, http.StatusForbidden)
+	ExpectEq(t, do(t, s, "POST", "/v1/admin/webhooks", alice, `{"url": "ftp://example.com/hook"}`).Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "POST", "/v1/admin/webhooks", alice, `{"url": "https://example.com/hook", "events": ["match.lost"]}`).Code, http.StatusBadRequest)
+	rec := do(t, s, "POST", "/v1/admin/webhooks", alice, `{"url": "https://example.com/hook", "events": ["player.banned"]}`)
+	AssertEq(t, rec.Code, http.StatusCreated)
+	var hook registerWebhookResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&hook), Nil())
+	ExpectThat(t, hook.Events, ElementsAre("player.banned"))
+	ExpectThat(t, hook.Secret, Not(Empty()))
+
+	rec = do(t, s, "GET", "/v1/admin/webhooks", alice, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var list listWebhooksResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&list), Nil())
+	AssertThat(t, list.Webhooks, Len(1))
+	ExpectEq(t, list.Webhooks[0].ID, hook.ID)
+
+	// Banning bob queues a delivery.
+	ExpectEq(t, do(t, s, "PUT", "/v1/admin/accounts/bob/ban", alice, `{"reason": "collusion"}`).Code, http.StatusNoContent)
+	rec = do(t, s, "GET", "/v1/admin/webhooks/"+hook.ID+"/deliveries", alice, "")
+	AssertEq(t, rec.Code, http.StatusOK)
+	var deliveries listDeliveriesResponse
+	AssertThat(t, json.NewDecoder(rec.Body).Decode(&deliveries), Nil())
+	AssertThat(t, deliveries.Deliveries, Len(1))
+	ExpectEq(t, deliveries.Deliveries[0].Event, "player.banned")
+	ExpectEq(t, deliveries.Deliveries[0].State, "pending")
+	ExpectThat(t, string(deliveries.Deliveries[0].Payload), HasSubstr(`"bob"`))
+
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/webhooks/"+hook.ID+"/deliveries?sort=attempts", alice, "").Code, http.StatusBadRequest)
+	ExpectEq(t, do(t, s, "DELETE", "/v1/admin/webhooks/"+hook.ID, alice, "").Code, http.StatusNoContent)
+	ExpectEq(t, do(t, s, "DELETE", "/v1/admin/webhooks/"+hook.ID, alice, "").Code, http.StatusNotFound)
+}
+
+func TestWebhooks_Disabled(t *testing.T) {
+	accounts := account.NewManager(account.NewMemStore(), account.WithParams(testParams))
+	sessions := session.NewStore()
+	s := NewServer(Config{Lobby: lobby.New(queue.New(), party.NewManager(), nil), Sessions: sessions, Accounts: accounts})
+	_, err := accounts.Register(ctx, "alice", "alice@example.com", "correct horse")
+	AssertThat(t, err, Nil())
+	AssertThat(t, accounts.SetRole(ctx, "alice", account.RoleAdmin), Nil())
+	ExpectEq(t, do(t, s, "GET", "/v1/admin/webhooks", sessions.Create("alice"), "").Code, http.StatusNotFound)
+}
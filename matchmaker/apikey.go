@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jfmatt/flagr"
+	"github.com/spf13/cobra"
+
+	"github.com/jfmatt/snapfold/matchmaker/apikey"
+	"github.com/jfmatt/snapfold/matchmaker/store"
+)
+
+func APIKeyCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "apikey",
+		Short: "Issue, list and revoke the API keys other services call internal endpoints with",
+	}
+
+	issueCmd := &cobra.Command{
+		Use:   "issue",
+		Short: "Issue a key and print its token, which cannot be shown again",
+	}
+	issueCmd.RunE = flagr.Run(issueCmd, IssueAPIKey)
+	c.AddCommand(issueCmd)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List every key",
+	}
+	listCmd.RunE = flagr.Run(listCmd, ListAPIKeys)
+	c.AddCommand(listCmd)
+
+	revokeCmd := &cobra.Command{
+		Use:   "revoke",
+		Short: "Stop a key from working",
+	}
+	revokeCmd.RunE = flagr.Run(revokeCmd, RevokeAPIKey)
+	c.AddCommand(revokeCmd)
+
+	return c
+}
+
+type IssueAPIKeyArgs struct {
+	Dsn    string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	Name   string `flag:"name,required,help=Who or what the key is for"`
+	Scopes string `flag:"scopes,required,help=Comma-separated endpoints the key may call: matches, tables, backfills, fleet or configs"`
+}
+
+func IssueAPIKey(flags *IssueAPIKeyArgs, cmd *cobra.Command, args []string) error {
+	var scopes []apikey.Scope
+	for _, name := range strings.Split(flags.Scopes, ",") {
+		scope, err := apikey.ParseScope(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+		scopes = append(scopes, scope)
+	}
+	db, err := store.Open(cmd.Context(), flags.Dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	k, token, err := apikey.NewManager(store.NewAPIKeys(db)).Issue(cmd.Context(), flags.Name, scopes...)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "issued key %s; its token follows and cannot be shown again\n", k.ID)
+	fmt.Fprintln(cmd.OutOrStdout(), token)
+	return nil
+}
+
+type ListAPIKeysArgs struct {
+	Dsn string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+}
+
+func ListAPIKeys(flags *ListAPIKeysArgs, cmd *cobra.Command, args []string) error {
+	db, err := store.Open(cmd.Context(), flags.Dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	keys, err := apikey.NewManager(store.NewAPIKeys(db)).List(cmd.Context())
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	for _, k := range keys {
+		scopes := make([]string, len(k.Scopes))
+		for i, s := range k.Scopes {
+			scopes[i] = string(s)
+		}
+		state := "active"
+		if k.Revoked() {
+			state = "revoked " + k.RevokedAt.Format(time.DateOnly)
+		}
+		fmt.Fprintf(out, "%s  %-24s %-32s %s\n", k.ID, k.Name, strings.Join(scopes, ","), state)
+	}
+	return nil
+}
+
+type RevokeAPIKeyArgs struct {
+	Dsn string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	ID  string `flag:"id,required,help=Key to revoke"`
+}
+
+func RevokeAPIKey(flags *RevokeAPIKeyArgs, cmd *cobra.Command, args []string) error {
+	db, err := store.Open(cmd.Context(), flags.Dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := apikey.NewManager(store.NewAPIKeys(db)).Revoke(cmd.Context(), flags.ID); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "revoked key %s\n", flags.ID)
+	return nil
+}
@@ -0,0 +1,222 @@
+// Package apikey issues and checks the keys that other services, such as
+// game servers, present when calling the matchmaker's internal endpoints.
+// Each key is limited to the scopes it was issued with.
+package apikey
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/paging"
+)
+
+var (
+	ErrNotFound     = errors.New("api key not found")
+	ErrInvalidKey   = errors.New("invalid api key")
+	ErrInvalidScope = errors.New("invalid scope")
+	ErrScope        = errors.New("api key lacks the scope required")
+)
+
+// Scope is a group of internal endpoints a key may call.
+type Scope string
+
+const (
+	// Reporting match results.
+	ScopeMatches Scope = "matches"
+
+	// Reporting disconnects, releasing seats and closing tables.
+	ScopeTables Scope = "tables"
+
+	// Requesting backfills and queueing players with priority.
+	ScopeBackfills Scope = "backfills"
+
+	// Registering game servers with the fleet.
+	ScopeFleet Scope = "fleet"
+
+	// Fetching game configs from the registry.
+	ScopeConfigs Scope = "configs"
+)
+
+// Scopes lists every scope.
+var Scopes = []Scope{ScopeMatches, ScopeTables, ScopeBackfills, ScopeFleet, ScopeConfigs}
+
+// ParseScope returns the scope with the name, or ErrInvalidScope.
+func ParseScope(name string) (Scope, error) {
+	s := Scope(name)
+	if !slices.Contains(Scopes, s) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidScope, name)
+	}
+	return s, nil
+}
+
+// prefix starts every key, so that leaked keys are easy to search for.
+const prefix = "sfk_"
+
+// Key is an issued API key. The secret half of the key is only known to
+// whoever it was issued to; only its hash is kept.
+type Key struct {
+	// Identifies the key. It is the public half of the key, so it may be
+	// logged.
+	ID string
+
+	// Who or what the key was issued to, for people reading a list of keys.
+	Name   string
+	Scopes []Scope
+
+	// Hex SHA-256 of the secret half of the key.
+	SecretHash string
+
+	CreatedAt time.Time
+
+	// When the key was revoked. Zero while it is usable.
+	RevokedAt time.Time
+}
+
+// Revoked reports whether the key has been revoked.
+func (k Key) Revoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// Allows reports whether the key may call endpoints in the scope.
+func (k Key) Allows(s Scope) bool {
+	return slices.Contains(k.Scopes, s)
+}
+
+// Manager issues, checks and revokes keys. It is safe for concurrent use.
+type Manager struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewManager returns a Manager that keeps keys in store.
+func NewManager(store Store) *Manager {
+	return &Manager{store: store, now: time.Now}
+}
+
+// Issue creates a key with the scopes and returns it, along with the token
+// its holder presents. The token cannot be recovered later.
+func (m *Manager) Issue(ctx context.Context, name string, scopes ...Scope) (Key, string, error) {
+	k, token, err := m.NewKey(name, scopes...)
+	if err != nil {
+		return Key{}, "", err
+	}
+	if err := m.Save(ctx, k); err != nil {
+		return Key{}, "", err
+	}
+	return k, token, nil
+}
+
+// NewKey returns a key with the scopes, and the token its holder presents,
+// without saving it. It doesn't work until it is saved with Save.
+func (m *Manager) NewKey(name string, scopes ...Scope) (Key, string, error) {
+	if len(scopes) == 0 {
+		return Key{}, "", fmt.Errorf("%w: a key needs at least one scope", ErrInvalidScope)
+	}
+	for _, s := range scopes {
+		if _, err := ParseScope(string(s)); err != nil {
+			return Key{}, "", err
+		}
+	}
+	secret := strings.ToLower(rand.Text())
+	k := Key{
+		ID:         strings.ToLower(rand.Text()[:12]),
+		Name:       name,
+		Scopes:     slices.Compact(slices.Sorted(slices.Values(scopes))),
+		SecretHash: hashSecret(secret),
+		CreatedAt:  m.now(),
+	}
+	return k, prefix + k.ID + "_" + secret, nil
+}
+
+// Save records a key made by NewKey, after which its token works.
+func (m *Manager) Save(ctx context.Context, k Key) error {
+	return m.store.SaveKey(ctx, k)
+}
+
+// Get returns the key with the ID, or ErrNotFound.
+func (m *Manager) Get(ctx context.Context, id string) (Key, error) {
+	return m.store.GetKey(ctx, id)
+}
+
+// Verify returns the key a token belongs to if it may call endpoints in the
+// scope. It fails with ErrInvalidKey if the token is not a usable key, and
+// ErrScope if the key lacks the scope.
+func (m *Manager) Verify(ctx context.Context, token string, scope Scope) (Key, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(token, prefix), "_")
+	if !ok || !strings.HasPrefix(token, prefix) {
+		return Key{}, ErrInvalidKey
+	}
+	k, err := m.store.GetKey(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return Key{}, ErrInvalidKey
+	}
+	if err != nil {
+		return Key{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(k.SecretHash)) != 1 || k.Revoked() {
+		return Key{}, ErrInvalidKey
+	}
+	if !k.Allows(scope) {
+		return Key{}, fmt.Errorf("%w: %s needs %s", ErrScope, k.ID, scope)
+	}
+	return k, nil
+}
+
+// List returns every key, including revoked ones, oldest first.
+func (m *Manager) List(ctx context.Context) ([]Key, error) {
+	return m.store.ListKeys(ctx)
+}
+
+// Listing is how keys may be listed: by when they were issued, oldest first
+// unless sorted by -created_at.
+var Listing = paging.Spec{
+	Default: paging.Sort{Field: "created_at"},
+	Fields:  []string{"created_at"},
+}
+
+// ListPage returns a page of every key, including revoked ones, for a
+// request made with the Listing spec.
+func (m *Manager) ListPage(ctx context.Context, r paging.Request) (paging.Page[Key], error) {
+	keys, err := m.store.ListKeys(ctx)
+	if err != nil {
+		return paging.Page[Key]{}, err
+	}
+	if r.After != nil && len(r.After) != 2 {
+		return paging.Page[Key]{}, paging.ErrInvalidToken
+	}
+	return paging.Slice(r, keys, func(k Key) []string {
+		return []string{strconv.FormatInt(k.CreatedAt.UnixNano(), 10), k.ID}
+	}, func(k Key, after []string) int {
+		return cmp.Or(cmp.Compare(k.CreatedAt.UnixNano(), paging.Int(after[0])), strings.Compare(k.ID, after[1]))
+	}), nil
+}
+
+// Revoke stops a key from working. Revoking a revoked key does nothing.
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	k, err := m.store.GetKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	if k.Revoked() {
+		return nil
+	}
+	k.RevokedAt = m.now()
+	return m.store.UpdateKey(ctx, k)
+}
+
+// hashSecret hashes the secret half of a key. Secrets are random, so a fast
+// hash is enough.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,85 @@
+package apikey
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+var ctx = context.Background()
+
+func TestIssueAndVerify(t *testing.T) {
+	m := NewManager(NewMemStore())
+	k, token, err := m.Issue(ctx, "game servers", ScopeMatches, ScopeTables, ScopeMatches)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, k.Scopes, ElementsAre(ScopeMatches, ScopeTables))
+	ExpectEq(t, strings.HasPrefix(token, "sfk_"+k.ID+"_"), true)
+
+	got, err := m.Verify(ctx, token, ScopeTables)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got.Name, "game servers")
+
+	_, err = m.Verify(ctx, token, ScopeFleet)
+	ExpectThat(t, err, ErrorIs(ErrScope))
+	_, err = m.Verify(ctx, token+"x", ScopeMatches)
+	ExpectThat(t, err, ErrorIs(ErrInvalidKey))
+	_, err = m.Verify(ctx, strings.TrimPrefix(token, "sfk_"), ScopeMatches)
+	ExpectThat(t, err, ErrorIs(ErrInvalidKey))
+	_, err = m.Verify(ctx, "sfk_nobody_secret", ScopeMatches)
+	ExpectThat(t, err, ErrorIs(ErrInvalidKey))
+}
+
+func TestIssue_InvalidScopes(t *testing.T) {
+	m := NewManager(NewMemStore())
+	_, _, err := m.Issue(ctx, "nothing")
+	ExpectThat(t, err, ErrorIs(ErrInvalidScope))
+	_, _, err = m.Issue(ctx, "everything", "admin")
+	ExpectThat(t, err, ErrorIs(ErrInvalidScope))
+}
+
+func TestNewKey(t *testing.T) {
+	m := NewManager(NewMemStore())
+	_, _, err := m.NewKey("nothing")
+	ExpectThat(t, err, ErrorIs(ErrInvalidScope))
+	k, token, err := m.NewKey("game servers", ScopeTables)
+	AssertThat(t, err, Nil())
+
+	// Keys don't work until they are saved.
+	_, err = m.Verify(ctx, token, ScopeTables)
+	ExpectThat(t, err, ErrorIs(ErrInvalidKey))
+	_, err = m.Get(ctx, k.ID)
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+
+	AssertThat(t, m.Save(ctx, k), Nil())
+	_, err = m.Verify(ctx, token, ScopeTables)
+	ExpectThat(t, err, Nil())
+	got, err := m.Get(ctx, k.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got.Name, "game servers")
+}
+
+func TestRevoke(t *testing.T) {
+	m := NewManager(NewMemStore())
+	now := time.Unix(1000, 0)
+	m.now = func() time.Time { return now }
+	k, token, err := m.Issue(ctx, "game servers", ScopeMatches)
+	AssertThat(t, err, Nil())
+	now = now.Add(time.Second)
+	_, _, err = m.Issue(ctx, "backfiller", ScopeBackfills)
+	AssertThat(t, err, Nil())
+
+	AssertThat(t, m.Revoke(ctx, k.ID), Nil())
+	_, err = m.Verify(ctx, token, ScopeMatches)
+	ExpectThat(t, err, ErrorIs(ErrInvalidKey))
+	ExpectThat(t, m.Revoke(ctx, k.ID), Nil())
+	ExpectThat(t, m.Revoke(ctx, "nobody"), ErrorIs(ErrNotFound))
+
+	keys, err := m.List(ctx)
+	AssertThat(t, err, Nil())
+	AssertThat(t, keys, Len(2))
+	ExpectEq(t, keys[0].Revoked(), true)
+	ExpectEq(t, keys[1].Name, "backfiller")
+}
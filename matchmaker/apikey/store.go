@@ -0,0 +1,70 @@
+package apikey
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Store persists keys.
+type Store interface {
+	// SaveKey records a new key.
+	SaveKey(ctx context.Context, k Key) error
+
+	// GetKey returns the key with the ID, or ErrNotFound.
+	GetKey(ctx context.Context, id string) (Key, error)
+
+	// UpdateKey replaces the record of a key, or returns ErrNotFound.
+	UpdateKey(ctx context.Context, k Key) error
+
+	// ListKeys returns every key, oldest first.
+	ListKeys(ctx context.Context) ([]Key, error)
+}
+
+// MemStore is an in-memory Store, for development and tests.
+type MemStore struct {
+	mu   sync.Mutex
+	keys map[string]Key
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{keys: map[string]Key{}}
+}
+
+func (s *MemStore) SaveKey(ctx context.Context, k Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[k.ID] = k
+	return nil
+}
+
+func (s *MemStore) GetKey(ctx context.Context, id string) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrNotFound
+	}
+	return k, nil
+}
+
+func (s *MemStore) UpdateKey(ctx context.Context, k Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[k.ID]; !ok {
+		return ErrNotFound
+	}
+	s.keys[k.ID] = k
+	return nil
+}
+
+func (s *MemStore) ListKeys(ctx context.Context) ([]Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.SortedFunc(maps.Values(s.keys), func(a, b Key) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	}), nil
+}
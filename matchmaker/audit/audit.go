@@ -0,0 +1,188 @@
+// Package audit keeps the log of what admins and moderators have done, such
+// as banning players, granting roles, changing configs and closing tables,
+// so that operators can review who changed what, and when.
+//
+// The log is append-only: entries are never changed or removed.
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/paging"
+)
+
+var ErrInvalidAction = errors.New("invalid audit action")
+
+// Action is a kind of admin action.
+type Action string
+
+const (
+	// An account's role was changed.
+	RoleSet Action = "account.role"
+
+	// An account was banned, or its ban replaced.
+	AccountBanned Action = "account.ban"
+
+	// An account's ban was lifted.
+	AccountUnbanned Action = "account.unban"
+
+	// An account was emailed a password reset link.
+	PasswordResetSent Action = "account.password_reset"
+
+	// A webhook was registered or deleted.
+	WebhookRegistered Action = "webhook.register"
+	WebhookDeleted    Action = "webhook.delete"
+
+	// An API key was issued or revoked.
+	APIKeyIssued  Action = "api_key.issue"
+	APIKeyRevoked Action = "api_key.revoke"
+
+	// A config in the registry was stored or deleted.
+	ConfigPut     Action = "config.put"
+	ConfigDeleted Action = "config.delete"
+
+	// A table was created, paused, resumed or closed through a game
+	// server's admin API.
+	TableCreated Action = "table.create"
+	TablePaused  Action = "table.pause"
+	TableResumed Action = "table.resume"
+	TableClosed  Action = "table.close"
+
+	// Money was deposited into a player's wallet.
+	WalletDeposit Action = "wallet.deposit"
+)
+
+// Actions lists every action.
+var Actions = []Action{
+	RoleSet, AccountBanned, AccountUnbanned, PasswordResetSent,
+	WebhookRegistered, WebhookDeleted, APIKeyIssued, APIKeyRevoked,
+	ConfigPut, ConfigDeleted,
+	TableCreated, TablePaused, TableResumed, TableClosed,
+	WalletDeposit,
+}
+
+// ParseAction returns the action with the name, or ErrInvalidAction.
+func ParseAction(name string) (Action, error) {
+	a := Action(name)
+	if !slices.Contains(Actions, a) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAction, name)
+	}
+	return a, nil
+}
+
+// Table reports whether the action is taken on a game server's table.
+func (a Action) Table() bool {
+	return strings.HasPrefix(string(a), "table.")
+}
+
+// Entry records one admin action.
+type Entry struct {
+	ID   string
+	Time time.Time
+
+	// Who took the action: an account's username, or for actions reported
+	// by a game server, whoever it says held its admin token.
+	Actor string
+
+	Action Action
+
+	// What the action was taken on, such as an account's username, a
+	// config's kind and name, or a table's ID.
+	Target string
+
+	// What the target was like before and after the action, as JSON. Nil
+	// where there is nothing to show, such as before something was created.
+	Before json.RawMessage
+	After  json.RawMessage
+}
+
+// Log records admin actions. It is safe for concurrent use.
+type Log struct {
+	store Store
+	now   func() time.Time
+}
+
+// New returns a Log that keeps its entries in store.
+func New(store Store) *Log {
+	return &Log{store: store, now: time.Now}
+}
+
+// Record appends an entry for an action to the log. before and after are
+// written as JSON, and left out if nil.
+func (l *Log) Record(ctx context.Context, actor string, action Action, target string, before, after any) error {
+	if _, err := ParseAction(string(action)); err != nil {
+		return err
+	}
+	e := Entry{
+		ID:     "au_" + strings.ToLower(rand.Text()[:16]),
+		Time:   l.now(),
+		Actor:  actor,
+		Action: action,
+		Target: target,
+	}
+	var err error
+	if e.Before, err = marshal(before); err != nil {
+		return err
+	}
+	if e.After, err = marshal(after); err != nil {
+		return err
+	}
+	return l.store.Append(ctx, e)
+}
+
+func marshal(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "null" {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Listing is how the log may be listed: by when actions were taken, newest
+// first unless sorted by time, and only those of an actor, action or target
+// if asked.
+var Listing = paging.Spec{
+	Default: paging.Sort{Field: "time", Desc: true},
+	Fields:  []string{"time"},
+	Filters: []string{"actor", "action", "target"},
+}
+
+// List returns a page of the log, for a request made with the Listing spec.
+func (l *Log) List(ctx context.Context, r paging.Request) (paging.Page[Entry], error) {
+	q := Query{
+		Actor:       r.Filter["actor"],
+		Action:      Action(r.Filter["action"]),
+		Target:      r.Filter["target"],
+		OldestFirst: !r.Sort.Desc,
+		Limit:       r.Limit(),
+	}
+	if q.Action != "" {
+		if _, err := ParseAction(string(q.Action)); err != nil {
+			return paging.Page[Entry]{}, err
+		}
+	}
+	if r.After != nil {
+		if len(r.After) != 2 {
+			return paging.Page[Entry]{}, paging.ErrInvalidToken
+		}
+		q.AfterTime, q.AfterID = time.Unix(0, paging.Int(r.After[0])), r.After[1]
+	}
+	entries, err := l.store.List(ctx, q)
+	if err != nil {
+		return paging.Page[Entry]{}, err
+	}
+	return paging.Finish(r, entries, func(e Entry) []string {
+		return []string{strconv.FormatInt(e.Time.UnixNano(), 10), e.ID}
+	}), nil
+}
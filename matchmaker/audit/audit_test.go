@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/paging"
+)
+
+var ctx = context.Background()
+
+func TestRecord(t *testing.T) {
+	l := New(NewMemStore())
+	now := time.Unix(1_800_000_000, 0)
+	l.now = func() time.Time { return now }
+
+	AssertThat(t, l.Record(ctx, "alice", RoleSet, "bob", "player", "moderator"), Nil())
+	now = now.Add(time.Second)
+	AssertThat(t, l.Record(ctx, "alice", PasswordResetSent, "carol", nil, nil), Nil())
+	ExpectThat(t, l.Record(ctx, "alice", "account.delete", "bob", nil, nil), ErrorIs(ErrInvalidAction))
+
+	p, err := l.List(ctx, paging.Request{Size: 10, Sort: Listing.Default})
+	AssertThat(t, err, Nil())
+	AssertThat(t, p.Items, Len(2))
+	e := p.Items[1]
+	ExpectThat(t, e.ID, HasSubstr("au_"))
+	ExpectEq(t, e.Time, now.Add(-time.Second))
+	ExpectEq(t, e.Actor, "alice")
+	ExpectEq(t, e.Action, RoleSet)
+	ExpectEq(t, e.Target, "bob")
+	ExpectEq(t, string(e.Before), `"player"`)
+	ExpectEq(t, string(e.After), `"moderator"`)
+	ExpectThat(t, p.Items[0].Before, Nil())
+	ExpectThat(t, p.Items[0].After, Nil())
+}
+
+func TestList(t *testing.T) {
+	l := New(NewMemStore())
+	now := time.Unix(1_800_000_000, 0)
+	l.now = func() time.Time { return now }
+	for _, target := range []string{"bob", "carol", "bob", "dave"} {
+		now = now.Add(time.Minute)
+		AssertThat(t, l.Record(ctx, "alice", AccountBanned, target, nil, map[string]string{"reason": "spam"}), Nil())
+	}
+	AssertThat(t, l.Record(ctx, "erin", AccountUnbanned, "bob", nil, nil), Nil())
+
+	targets := func(es []Entry) []string {
+		var out []string
+		for _, e := range es {
+			out = append(out, string(e.Action)+" "+e.Target)
+		}
+		return out
+	}
+	r, err := Listing.Parse(2, "", "", map[string]string{"actor": "alice"})
+	AssertThat(t, err, Nil())
+	p, err := l.List(ctx, r)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, targets(p.Items), ElementsAre("account.ban dave", "account.ban bob"))
+	AssertThat(t, p.NextToken, Not(Empty()))
+
+	r, err = Listing.Parse(2, p.NextToken, "", map[string]string{"actor": "alice"})
+	AssertThat(t, err, Nil())
+	p, err = l.List(ctx, r)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, targets(p.Items), ElementsAre("account.ban carol", "account.ban bob"))
+	ExpectEq(t, p.NextToken, "")
+
+	r, err = Listing.Parse(10, "", "time", map[string]string{"target": "bob"})
+	AssertThat(t, err, Nil())
+	p, err = l.List(ctx, r)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, targets(p.Items), ElementsAre("account.ban bob", "account.ban bob", "account.unban bob"))
+
+	r, err = Listing.Parse(10, "", "", map[string]string{"action": "account.unban"})
+	AssertThat(t, err, Nil())
+	p, err = l.List(ctx, r)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, targets(p.Items), ElementsAre("account.unban bob"))
+
+	r, err = Listing.Parse(10, "", "", map[string]string{"action": "account.delete"})
+	AssertThat(t, err, Nil())
+	_, err = l.List(ctx, r)
+	ExpectThat(t, err, ErrorIs(ErrInvalidAction))
+}
+
+func TestAction_Table(t *testing.T) {
+	ExpectEq(t, TableClosed.Table(), true)
+	ExpectEq(t, AccountBanned.Table(), false)
+}
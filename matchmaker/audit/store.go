@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Query picks the entries List returns.
+type Query struct {
+	// Only entries with these, if set.
+	Actor  string
+	Action Action
+	Target string
+
+	// Whether the log runs from oldest to newest, rather than newest to
+	// oldest.
+	OldestFirst bool
+
+	// Only entries after this one in the log, if set. Entries are ordered
+	// by time and then by ID.
+	AfterTime time.Time
+	AfterID   string
+
+	Limit int
+}
+
+// includes reports whether q picks e.
+func (q Query) includes(e Entry) bool {
+	if (q.Actor != "" && e.Actor != q.Actor) || (q.Action != "" && e.Action != q.Action) ||
+		(q.Target != "" && e.Target != q.Target) {
+		return false
+	}
+	if q.AfterID == "" {
+		return true
+	}
+	c := cmp.Or(e.Time.Compare(q.AfterTime), strings.Compare(e.ID, q.AfterID))
+	if q.OldestFirst {
+		return c > 0
+	}
+	return c < 0
+}
+
+// Store persists the log. It has no way to change or remove entries.
+type Store interface {
+	// Append adds an entry to the log.
+	Append(ctx context.Context, e Entry) error
+
+	// List returns up to q.Limit of the entries q picks, in q's order.
+	List(ctx context.Context, q Query) ([]Entry, error)
+}
+
+// MemStore is an in-memory Store, for development and tests.
+type MemStore struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{}
+}
+
+func (s *MemStore) Append(ctx context.Context, e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.Before, e.After = slices.Clone(e.Before), slices.Clone(e.After)
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *MemStore) List(ctx context.Context, q Query) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entries []Entry
+	for _, e := range s.entries {
+		if q.includes(e) {
+			entries = append(entries, e)
+		}
+	}
+	slices.SortFunc(entries, func(a, b Entry) int {
+		c := cmp.Or(a.Time.Compare(b.Time), cmp.Compare(a.ID, b.ID))
+		if q.OldestFirst {
+			return c
+		}
+		return -c
+	})
+	return entries[:min(len(entries), q.Limit)], nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jfmatt/snapfold/matchmaker/store"
+)
+
+// BackupArgs configure the backup taken before destructive migrations. With
+// neither a command nor a webhook, none is taken.
+type BackupArgs struct {
+	Command string        `flag:"command,help=Shell command to run before destructive migrations, such as pg_dump -Fc -f backup.dump $SNAPFOLD_DSN; it gets the DSN as $SNAPFOLD_DSN and the migrations as $SNAPFOLD_MIGRATIONS, and migrating stops if it fails"`
+	Webhook string        `flag:"webhook,help=URL to POST the direction and migrations to as JSON before destructive migrations, such as one that snapshots the database; migrating stops unless it responds with a 2xx status"`
+	Timeout time.Duration `flag:"timeout,default=30m,help=How long the backup may take; 0 for no limit"`
+}
+
+// backupRequest is what the webhook is sent.
+type backupRequest struct {
+	Direction  string   `json:"direction"`
+	Migrations []string `json:"migrations"`
+}
+
+// run takes the backup if any of the migrations in plan is destructive,
+// judged by the script that would be run for it. The plan is made before
+// the migration lock is taken, so it may include migrations another
+// replica applies first; it can't miss any.
+func (b BackupArgs) run(cmd *cobra.Command, dsn, direction string, plan []store.Migration, script func(store.Migration) string) error {
+	if b.Command == "" && b.Webhook == "" {
+		return nil
+	}
+	var destructive []string
+	for _, m := range plan {
+		if store.Destructive(script(m)) {
+			destructive = append(destructive, m.String())
+		}
+	}
+	if len(destructive) == 0 {
+		return nil
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "backing up before destructive migrations %s\n", strings.Join(destructive, ", "))
+
+	ctx := cmd.Context()
+	if b.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.Timeout)
+		defer cancel()
+	}
+	names := make([]string, len(plan))
+	for i, m := range plan {
+		names[i] = m.String()
+	}
+	if b.Command != "" {
+		c := exec.CommandContext(ctx, "sh", "-c", b.Command)
+		c.Env = append(os.Environ(), "SNAPFOLD_DSN="+dsn, "SNAPFOLD_MIGRATIONS="+strings.Join(names, " "))
+		c.Stdout, c.Stderr = cmd.ErrOrStderr(), cmd.ErrOrStderr()
+		if err := c.Run(); err != nil {
+			return fmt.Errorf("backup command failed, so nothing was migrated: %w", err)
+		}
+	}
+	if b.Webhook != "" {
+		body, err := json.Marshal(backupRequest{Direction: direction, Migrations: names})
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.Webhook, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("backup webhook failed, so nothing was migrated: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("backup webhook failed, so nothing was migrated: %s", resp.Status)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jfmatt/flagr"
+	"github.com/spf13/cobra"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/matchmaker/registry"
+	"github.com/jfmatt/snapfold/matchmaker/rules"
+	"github.com/jfmatt/snapfold/matchmaker/store"
+)
+
+func ConfigCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the named TableConfigs and MatchRules in the config registry",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the configs of a kind",
+	}
+	listCmd.RunE = flagr.Run(listCmd, ListConfigs)
+	c.AddCommand(listCmd)
+
+	getCmd := &cobra.Command{
+		Use:   "get",
+		Short: "Print a config as JSON",
+	}
+	getCmd.RunE = flagr.Run(getCmd, GetConfig)
+	c.AddCommand(getCmd)
+
+	putCmd := &cobra.Command{
+		Use:   "put",
+		Short: "Check a config file and store it under a name",
+	}
+	putCmd.RunE = flagr.Run(putCmd, PutConfig)
+	c.AddCommand(putCmd)
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Remove a config",
+	}
+	deleteCmd.RunE = flagr.Run(deleteCmd, DeleteConfig)
+	c.AddCommand(deleteCmd)
+
+	return c
+}
+
+// openRegistry parses kind and opens the registry in the database.
+func openRegistry(cmd *cobra.Command, dsn, kind string) (*registry.Registry, registry.Kind, *store.DB, error) {
+	k, err := registry.ParseKind(kind)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	db, err := store.Open(cmd.Context(), dsn)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return registry.New(store.NewConfigs(db)), k, db, nil
+}
+
+type ListConfigsArgs struct {
+	Dsn  string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	Kind string `flag:"kind,default=table-configs,help=Kind of config: table-configs or match-rules"`
+}
+
+func ListConfigs(flags *ListConfigsArgs, cmd *cobra.Command, args []string) error {
+	r, kind, db, err := openRegistry(cmd, flags.Dsn, flags.Kind)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	entries, err := r.List(cmd.Context(), kind)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		fmt.Fprintf(cmd.OutOrStdout(), "%-32s revision %-4d updated %s\n", e.Name, e.Revision, e.UpdatedAt.Format(time.DateTime))
+	}
+	return nil
+}
+
+type GetConfigArgs struct {
+	Dsn  string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	Kind string `flag:"kind,default=table-configs,help=Kind of config: table-configs or match-rules"`
+	Name string `flag:"name,required,help=Name of the config"`
+}
+
+func GetConfig(flags *GetConfigArgs, cmd *cobra.Command, args []string) error {
+	r, kind, db, err := openRegistry(cmd, flags.Dsn, flags.Kind)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	e, err := r.Get(cmd.Context(), kind, flags.Name)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, e.Config, "", "  "); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), out.String())
+	fmt.Fprintf(cmd.ErrOrStderr(), "revision %d\n", e.Revision)
+	return nil
+}
+
+type PutConfigArgs struct {
+	Dsn      string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	Kind     string `flag:"kind,default=table-configs,help=Kind of config: table-configs or match-rules"`
+	Name     string `flag:"name,required,help=Name to store the config under"`
+	File     string `flag:"file,help=Config to store: a TableConfig in YAML, JSON or textproto, or a MatchRules textproto"`
+	Preset   string `flag:"preset,help=Built-in TableConfig preset to store instead of a file, such as low or heads-up"`
+	Revision int64  `flag:"revision,help=Only replace the config if it is at this revision, so that others' changes aren't lost; 0 to replace it regardless"`
+}
+
+func PutConfig(flags *PutConfigArgs, cmd *cobra.Command, args []string) error {
+	if (flags.File == "") == (flags.Preset == "") {
+		return errors.New("give exactly one of --file and --preset")
+	}
+	r, kind, db, err := openRegistry(cmd, flags.Dsn, flags.Kind)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var e registry.Entry
+	switch kind {
+	case registry.KindTableConfig:
+		var cfg *pb.TableConfig
+		if flags.Preset != "" {
+			cfg, err = gamedefio.LoadPreset(flags.Preset)
+		} else {
+			cfg, err = gamedefio.Load(flags.File)
+		}
+		if err != nil {
+			return err
+		}
+		e, err = r.PutTableConfig(cmd.Context(), flags.Name, cfg, flags.Revision)
+		if err != nil {
+			return err
+		}
+	case registry.KindMatchRules:
+		if flags.Preset != "" {
+			return errors.New("presets are TableConfigs")
+		}
+		mr, err := rules.Load(flags.File)
+		if err != nil {
+			return err
+		}
+		e, err = r.PutMatchRules(cmd.Context(), flags.Name, mr, flags.Revision)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "stored %s %s at revision %d\n", kind, e.Name, e.Revision)
+	return nil
+}
+
+type DeleteConfigArgs struct {
+	Dsn  string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	Kind string `flag:"kind,default=table-configs,help=Kind of config: table-configs or match-rules"`
+	Name string `flag:"name,required,help=Name of the config"`
+}
+
+func DeleteConfig(flags *DeleteConfigArgs, cmd *cobra.Command, args []string) error {
+	r, kind, db, err := openRegistry(cmd, flags.Dsn, flags.Kind)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := r.Delete(cmd.Context(), kind, flags.Name); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "deleted %s %s\n", kind, flags.Name)
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/jfmatt/snapfold/lib/log"
+	"github.com/jfmatt/snapfold/lib/metrics"
+	"github.com/jfmatt/snapfold/matchmaker/fleet"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+//go:embed dashboard.html
+var dashboardPage []byte
+
+// How many recent errors the dashboard shows.
+const dashboardErrors = 50
+
+// dashboard serves a page for operators showing the queue, the fleet's
+// tables and players, and recent errors. It reads them from the same
+// places as the metrics it registers.
+type dashboard struct {
+	queue  *queue.Queue
+	fleet  *fleet.Fleet // nil unless game servers register by heartbeat
+	recent *log.Recent
+}
+
+type dashboardStats struct {
+	Time time.Time `json:"time"`
+
+	// Tickets waiting, by game mode.
+	Queue map[string]int `json:"queue"`
+
+	// Omitted unless game servers register by heartbeat.
+	Fleet *fleetStats `json:"fleet,omitempty"`
+
+	Errors []log.Entry `json:"errors"`
+}
+
+type fleetStats struct {
+	Servers []serverStats `json:"servers"`
+	Tables  int           `json:"tables"`
+	Players int           `json:"players"`
+}
+
+type serverStats struct {
+	ID       string    `json:"id"`
+	Address  string    `json:"address"`
+	Region   string    `json:"region,omitempty"`
+	Capacity int       `json:"capacity"`
+	Tables   int       `json:"tables"`
+	Players  int       `json:"players"`
+	Draining bool      `json:"draining,omitempty"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+func (d *dashboard) stats() dashboardStats {
+	s := dashboardStats{Time: time.Now(), Queue: d.queue.Depths(), Fleet: d.fleetStats(), Errors: d.recent.Entries()}
+	if s.Errors == nil {
+		s.Errors = []log.Entry{}
+	}
+	return s
+}
+
+// fleetStats totals the live servers' tables and players, or returns nil
+// if there is no fleet.
+func (d *dashboard) fleetStats() *fleetStats {
+	if d.fleet == nil {
+		return nil
+	}
+	fs := &fleetStats{Servers: []serverStats{}}
+	for _, s := range d.fleet.Servers() {
+		fs.Servers = append(fs.Servers, serverStats{
+			ID:       s.ID,
+			Address:  s.Address,
+			Region:   s.Region,
+			Capacity: s.Capacity,
+			Tables:   s.Tables,
+			Players:  s.Players,
+			Draining: s.Draining,
+			LastSeen: s.LastSeen,
+		})
+		fs.Tables += s.Tables
+		fs.Players += s.Players
+	}
+	slices.SortFunc(fs.Servers, func(a, b serverStats) int { return cmp.Compare(a.ID, b.ID) })
+	return fs
+}
+
+// registerMetrics registers the dashboard's figures in reg.
+func (d *dashboard) registerMetrics(reg *metrics.Registry) {
+	reg.Collect(func(w io.Writer) {
+		depths := d.queue.Depths()
+		metrics.WriteHeader(w, "matchmaker_queue_depth", metrics.KindGauge, "Tickets waiting in the queue, by game mode.")
+		for _, mode := range slices.Sorted(maps.Keys(depths)) {
+			fmt.Fprintf(w, "matchmaker_queue_depth{game_mode=%q} %d\n", mode, depths[mode])
+		}
+	})
+	if d.fleet == nil {
+		return
+	}
+	reg.GaugeFunc("matchmaker_fleet_servers", "Game servers that have heartbeated recently.",
+		func() float64 { return float64(len(d.fleetStats().Servers)) })
+	reg.GaugeFunc("matchmaker_fleet_tables", "Tables open on the fleet's game servers.",
+		func() float64 { return float64(d.fleetStats().Tables) })
+	reg.GaugeFunc("matchmaker_fleet_players", "Player connections open to the fleet's tables.",
+		func() float64 { return float64(d.fleetStats().Players) })
+}
+
+// Handler serves the page at GET /, and the figures it shows as JSON at
+// GET /stats. It is meant to be mounted under a prefix, which the page
+// fetches its figures relative to.
+func (d *dashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(dashboardPage)
+	})
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(d.stats())
+	})
+	return mux
+}
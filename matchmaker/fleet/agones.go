@@ -0,0 +1,135 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jfmatt/snapfold/lib/tracing"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+// Labels and annotations the Agones allocator reads and writes on
+// GameServers.
+const (
+	// Selects GameServers in the match's region.
+	agonesRegionLabel = "snapfold.dev/region"
+
+	agonesMatchAnnotation    = "snapfold.dev/match-id"
+	agonesGameModeAnnotation = "snapfold.dev/game-mode"
+	agonesPlayersAnnotation  = "snapfold.dev/players"
+	agonesBotsAnnotation     = "snapfold.dev/bots"
+
+	// The W3C traceparent of the allocation, if it was traced.
+	agonesTraceAnnotation = "snapfold.dev/traceparent"
+)
+
+// Agones is an Allocator for game servers run as an Agones fleet on
+// Kubernetes. It asks the Agones allocator service for a Ready GameServer
+// and hands the match off in the allocated GameServer's annotations, which
+// the game server reads through the Agones SDK.
+type Agones struct {
+	endpoint  string
+	namespace string
+	client    *http.Client
+}
+
+// NewAgones returns an Allocator using the Agones allocator service at
+// endpoint, for example "https://agones-allocator:443", to allocate
+// GameServers in namespace. The allocator service requires mutual TLS, which
+// client must be configured for.
+func NewAgones(endpoint, namespace string, client *http.Client) *Agones {
+	return &Agones{endpoint: strings.TrimSuffix(endpoint, "/"), namespace: namespace, client: client}
+}
+
+// The JSON forms of the allocator service's AllocationRequest and
+// AllocationResponse, trimmed to the fields the matchmaker uses.
+type agonesRequest struct {
+	Namespace           string           `json:"namespace"`
+	GameServerSelectors []agonesSelector `json:"gameServerSelectors"`
+	Metadata            agonesMetadata   `json:"metadata"`
+}
+
+type agonesSelector struct {
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+}
+
+type agonesMetadata struct {
+	Annotations map[string]string `json:"annotations"`
+}
+
+type agonesResponse struct {
+	GameServerName string       `json:"gameServerName"`
+	Address        string       `json:"address"`
+	Ports          []agonesPort `json:"ports"`
+}
+
+type agonesPort struct {
+	Name string `json:"name"`
+	Port int    `json:"port"`
+}
+
+func (a *Agones) Allocate(ctx context.Context, m *queue.Match) (_ Allocation, err error) {
+	ctx, span := startAllocation(ctx, "agones.Allocate", m)
+	defer func() { tracing.End(span, err) }()
+
+	annotations := map[string]string{
+		agonesMatchAnnotation:    m.ID,
+		agonesGameModeAnnotation: m.GameMode,
+		agonesPlayersAnnotation:  strings.Join(m.Players(), ","),
+		agonesBotsAnnotation:     strconv.Itoa(m.Bots),
+	}
+	if tp := tracing.Traceparent(ctx); tp != "" {
+		annotations[agonesTraceAnnotation] = tp
+	}
+	selector := agonesSelector{}
+	if m.Region != "" {
+		selector.MatchLabels = map[string]string{agonesRegionLabel: m.Region}
+	}
+	body, err := json.Marshal(agonesRequest{
+		Namespace:           a.namespace,
+		GameServerSelectors: []agonesSelector{selector},
+		Metadata:            agonesMetadata{Annotations: annotations},
+	})
+	if err != nil {
+		return Allocation{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", a.endpoint+"/gameserverallocation", bytes.NewReader(body))
+	if err != nil {
+		return Allocation{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return Allocation{}, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		// The allocator reports that no GameServer is Ready as
+		// RESOURCE_EXHAUSTED.
+		return Allocation{}, ErrNoCapacity
+	case resp.StatusCode != http.StatusOK:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Allocation{}, fmt.Errorf("agones allocation: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var out agonesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Allocation{}, fmt.Errorf("agones allocation: %w", err)
+	}
+	if len(out.Ports) == 0 {
+		return Allocation{}, fmt.Errorf("agones allocation: GameServer %s has no ports", out.GameServerName)
+	}
+	return Allocation{
+		ServerID: out.GameServerName,
+		Address:  net.JoinHostPort(out.Address, strconv.Itoa(out.Ports[0].Port)),
+	}, nil
+}
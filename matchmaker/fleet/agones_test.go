@@ -0,0 +1,59 @@
+package fleet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+func TestAgones(t *testing.T) {
+	var got agonesRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/gameserverallocation" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(agonesResponse{
+			GameServerName: "gs-1",
+			Address:        "10.0.0.1",
+			Ports:          []agonesPort{{Name: "default", Port: 7654}},
+		})
+	}))
+	defer srv.Close()
+
+	a := NewAgones(srv.URL, "games", srv.Client())
+	m := &queue.Match{
+		ID:       "m1",
+		GameMode: "holdem",
+		Region:   "us-east",
+		Tickets:  []*queue.Ticket{{Members: []string{"alice", "bob"}}},
+		Bots:     1,
+	}
+	alloc, err := a.Allocate(ctx, m)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, alloc.ServerID, "gs-1")
+	ExpectEq(t, alloc.Address, "10.0.0.1:7654")
+
+	ExpectEq(t, got.Namespace, "games")
+	AssertThat(t, got.GameServerSelectors, Len(1))
+	ExpectEq(t, got.GameServerSelectors[0].MatchLabels[agonesRegionLabel], "us-east")
+	ExpectEq(t, got.Metadata.Annotations[agonesMatchAnnotation], "m1")
+	ExpectEq(t, got.Metadata.Annotations[agonesPlayersAnnotation], "alice,bob")
+	ExpectEq(t, got.Metadata.Annotations[agonesBotsAnnotation], "1")
+}
+
+func TestAgones_NoCapacity(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code": 8, "message": "no available GameServer to allocate"}`, http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := NewAgones(srv.URL, "games", srv.Client()).Allocate(ctx, &queue.Match{ID: "m1"})
+	ExpectThat(t, err, ErrorIs(ErrNoCapacity))
+}
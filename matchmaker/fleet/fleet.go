@@ -0,0 +1,168 @@
+// Package fleet places newly formed matches on game servers.
+package fleet
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jfmatt/snapfold/lib/tracing"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+// ErrNoCapacity is returned when no game server can host a match.
+var ErrNoCapacity = errors.New("no game server has capacity")
+
+// Allocation is the game server chosen to host a match.
+type Allocation struct {
+	ServerID string
+
+	// host:port that players connect to.
+	Address string
+}
+
+// Allocator picks a game server for each new match and hands the match off
+// to it.
+type Allocator interface {
+	Allocate(ctx context.Context, m *queue.Match) (Allocation, error)
+}
+
+// Server is a game server instance as reported in its heartbeats.
+type Server struct {
+	ID      string
+	Address string
+	Region  string
+
+	// Number of tables the server can run at once, and the number it is
+	// running now.
+	Capacity int
+	Tables   int
+
+	// Player connections open to the server's tables.
+	Players int
+
+	// Whether the server is shutting down, so that it is given no new
+	// matches.
+	Draining bool
+
+	// When the server's last heartbeat arrived.
+	LastSeen time.Time
+}
+
+// Assignment is a match handed off to a server in a heartbeat response.
+type Assignment struct {
+	Match *queue.Match
+
+	// The W3C traceparent of the match's allocation, if it was traced,
+	// which the server continues when it opens the match's table.
+	Traceparent string
+}
+
+// Fleet is an Allocator for game servers that register themselves with
+// periodic heartbeats. Matches are handed off in the response to the chosen
+// server's next heartbeat. It is safe for concurrent use.
+type Fleet struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	timeout time.Duration
+	servers map[string]*member
+}
+
+type member struct {
+	Server
+
+	// Matches assigned to the server that it has not yet collected.
+	pending []Assignment
+
+	// Matches handed off in the last heartbeat response, which the server's
+	// reported table count does not include until its next heartbeat.
+	inFlight int
+}
+
+// free returns the number of tables the server can still accept.
+func (m *member) free() int {
+	return m.Capacity - m.Tables - len(m.pending) - m.inFlight
+}
+
+// NewFleet returns a Fleet with no servers. Servers that go longer than
+// timeout without a heartbeat are not given new matches.
+func NewFleet(timeout time.Duration) *Fleet {
+	return &Fleet{now: time.Now, timeout: timeout, servers: map[string]*member{}}
+}
+
+// Heartbeat registers a server or renews its registration, and returns the
+// matches assigned to it since its last heartbeat.
+func (f *Fleet) Heartbeat(s Server) []Assignment {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	m := f.servers[s.ID]
+	if m == nil {
+		m = &member{}
+		f.servers[s.ID] = m
+	}
+	s.LastSeen = f.now()
+	m.Server = s
+	assigned := m.pending
+	m.pending = nil
+	m.inFlight = len(assigned)
+	return assigned
+}
+
+// Servers returns every server that has heartbeated within the timeout.
+func (f *Fleet) Servers() []Server {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var live []Server
+	now := f.now()
+	for _, m := range f.servers {
+		if now.Sub(m.LastSeen) <= f.timeout {
+			live = append(live, m.Server)
+		}
+	}
+	return live
+}
+
+// Allocate assigns a match to the live server in its region with the most
+// free capacity, leaving out those draining. Matches with no region may go to a server in any region.
+func (f *Fleet) Allocate(ctx context.Context, match *queue.Match) (_ Allocation, err error) {
+	ctx, span := startAllocation(ctx, "fleet.Allocate", match)
+	defer func() { tracing.End(span, err) }()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var best *member
+	now := f.now()
+	for _, m := range f.servers {
+		if now.Sub(m.LastSeen) > f.timeout || m.Draining || m.free() <= 0 {
+			continue
+		}
+		if match.Region != "" && m.Region != match.Region {
+			continue
+		}
+		if best == nil || cmp.Or(cmp.Compare(best.free(), m.free()), cmp.Compare(m.ID, best.ID)) < 0 {
+			best = m
+		}
+	}
+	if best == nil {
+		return Allocation{}, ErrNoCapacity
+	}
+	span.SetAttributes(attribute.String("snapfold.server_id", best.ID))
+	best.pending = append(best.pending, Assignment{Match: match, Traceparent: tracing.Traceparent(ctx)})
+	return Allocation{ServerID: best.ID, Address: best.Address}, nil
+}
+
+// startAllocation starts the span of a match's allocation.
+func startAllocation(ctx context.Context, name string, m *queue.Match) (context.Context, trace.Span) {
+	return otel.Tracer("github.com/jfmatt/snapfold/matchmaker/fleet").Start(ctx, name, trace.WithAttributes(
+		attribute.String("snapfold.match_id", m.ID),
+		attribute.String("snapfold.region", m.Region),
+	))
+}
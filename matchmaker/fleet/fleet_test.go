@@ -0,0 +1,110 @@
+package fleet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/tracing"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+var ctx = context.Background()
+
+func TestAllocate(t *testing.T) {
+	f := NewFleet(time.Minute)
+	f.Heartbeat(Server{ID: "s1", Address: "s1:7000", Region: "us-east", Capacity: 2})
+	f.Heartbeat(Server{ID: "s2", Address: "s2:7000", Region: "us-east", Capacity: 3, Tables: 2})
+	f.Heartbeat(Server{ID: "s3", Address: "s3:7000", Region: "eu-west", Capacity: 10})
+
+	// The server with the most free tables wins.
+	a, err := f.Allocate(ctx, &queue.Match{ID: "m1", Region: "us-east"})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.ServerID, "s1")
+	ExpectEq(t, a.Address, "s1:7000")
+
+	// Tables not yet collected count against capacity.
+	a, err = f.Allocate(ctx, &queue.Match{ID: "m2", Region: "us-east"})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.ServerID, "s1")
+	a, err = f.Allocate(ctx, &queue.Match{ID: "m3", Region: "us-east"})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.ServerID, "s2")
+	_, err = f.Allocate(ctx, &queue.Match{ID: "m4", Region: "us-east"})
+	ExpectThat(t, err, ErrorIs(ErrNoCapacity))
+
+	a, err = f.Allocate(ctx, &queue.Match{ID: "m5"})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.ServerID, "s3")
+}
+
+func TestHeartbeat_HandsOffMatches(t *testing.T) {
+	f := NewFleet(time.Minute)
+	f.Heartbeat(Server{ID: "s1", Capacity: 1})
+	_, err := f.Allocate(ctx, &queue.Match{ID: "m1"})
+	AssertThat(t, err, Nil())
+
+	assigned := f.Heartbeat(Server{ID: "s1", Capacity: 1})
+	AssertThat(t, assigned, Len(1))
+	ExpectEq(t, assigned[0].Match.ID, "m1")
+	ExpectThat(t, f.Heartbeat(Server{ID: "s1", Capacity: 1, Tables: 1}), Empty())
+
+	// The match still holds its table until the server reports it.
+	_, err = f.Allocate(ctx, &queue.Match{ID: "m2"})
+	ExpectThat(t, err, ErrorIs(ErrNoCapacity))
+}
+
+func TestHeartbeat_Traceparent(t *testing.T) {
+	f := NewFleet(time.Minute)
+	f.Heartbeat(Server{ID: "s1", Capacity: 2})
+	const tp = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	_, err := f.Allocate(tracing.Continue(ctx, tp), &queue.Match{ID: "m1"})
+	AssertThat(t, err, Nil())
+	_, err = f.Allocate(ctx, &queue.Match{ID: "m2"})
+	AssertThat(t, err, Nil())
+
+	// Without an exporter, the allocation's span carries on its parent's
+	// context.
+	assigned := f.Heartbeat(Server{ID: "s1", Capacity: 2})
+	AssertThat(t, assigned, Len(2))
+	ExpectEq(t, assigned[0].Traceparent, tp)
+	ExpectEq(t, assigned[1].Traceparent, "")
+}
+
+func TestHeartbeat_InFlight(t *testing.T) {
+	f := NewFleet(time.Minute)
+	f.Heartbeat(Server{ID: "s1", Capacity: 1})
+	_, err := f.Allocate(ctx, &queue.Match{ID: "m1"})
+	AssertThat(t, err, Nil())
+
+	// The server reports its table count from before it collected m1.
+	AssertThat(t, f.Heartbeat(Server{ID: "s1", Capacity: 1}), Len(1))
+	_, err = f.Allocate(ctx, &queue.Match{ID: "m2"})
+	ExpectThat(t, err, ErrorIs(ErrNoCapacity))
+}
+
+func TestAllocate_SkipsDrainingServers(t *testing.T) {
+	f := NewFleet(time.Minute)
+	f.Heartbeat(Server{ID: "s1", Capacity: 4})
+	_, err := f.Allocate(ctx, &queue.Match{ID: "m1"})
+	AssertThat(t, err, Nil())
+
+	// Matches already assigned are still handed off.
+	ExpectThat(t, f.Heartbeat(Server{ID: "s1", Capacity: 4, Draining: true}), Len(1))
+	_, err = f.Allocate(ctx, &queue.Match{ID: "m2"})
+	ExpectThat(t, err, ErrorIs(ErrNoCapacity))
+}
+
+func TestAllocate_SkipsSilentServers(t *testing.T) {
+	now := time.Unix(1000, 0)
+	f := NewFleet(time.Minute)
+	f.now = func() time.Time { return now }
+	f.Heartbeat(Server{ID: "s1", Capacity: 4})
+
+	now = now.Add(2 * time.Minute)
+	ExpectThat(t, f.Servers(), Empty())
+	_, err := f.Allocate(ctx, &queue.Match{ID: "m1"})
+	ExpectThat(t, err, ErrorIs(ErrNoCapacity))
+}
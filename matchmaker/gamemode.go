@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jfmatt/flagr"
+	"github.com/spf13/cobra"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+)
+
+func GameModeCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "game-mode",
+		Short: "Check game mode configs",
+	}
+
+	lintCmd := &cobra.Command{
+		Use:   "lint FILE...",
+		Short: "Report every problem in TableConfig files, as the server would find them at startup",
+		Args:  cobra.MinimumNArgs(1),
+	}
+	lintCmd.RunE = flagr.Run(lintCmd, LintGameModes)
+	c.AddCommand(lintCmd)
+
+	return c
+}
+
+type LintGameModesArgs struct {
+	TableSize int `flag:"table-size,default=6,help=Number of players seated per match; 0 to skip checks that depend on it"`
+}
+
+var errLint = errors.New("game mode configs have problems")
+
+func LintGameModes(flags *LintGameModesArgs, cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	bad := 0
+	for _, path := range args {
+		errs, err := lintGameMode(path, flags.TableSize)
+		switch {
+		case err != nil:
+			fmt.Fprintf(out, "%s: %v\n", path, err)
+		case len(errs) > 0:
+			for _, e := range errs {
+				fmt.Fprintf(out, "%s: %s\n", path, e)
+			}
+		default:
+			fmt.Fprintf(out, "%s: ok\n", path)
+			continue
+		}
+		bad++
+	}
+	if bad > 0 {
+		return fmt.Errorf("%w: %d of %d", errLint, bad, len(args))
+	}
+	return nil
+}
+
+// lintGameMode returns every problem in the TableConfig at path, or an
+// error if it can't be read at all.
+func lintGameMode(path string, tableSize int) (gamedefio.Errors, error) {
+	f, err := gamedefio.FormatFor(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := gamedefio.Parse(b, f)
+	if err != nil {
+		return nil, err
+	}
+	gamedefio.SetDefaults(cfg)
+
+	var all gamedefio.Errors
+	for _, err := range []error{gamedefio.Validate(cfg), seatErrors(cfg, tableSize)} {
+		var errs gamedefio.Errors
+		if errors.As(err, &errs) {
+			all = append(all, errs...)
+		}
+	}
+	return all, nil
+}
+
+func seatErrors(cfg *pb.TableConfig, tableSize int) error {
+	if tableSize == 0 {
+		return nil
+	}
+	return gamedefio.ValidateSeats(cfg, tableSize)
+}
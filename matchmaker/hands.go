@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jfmatt/flagr"
+	"github.com/spf13/cobra"
+
+	"github.com/jfmatt/snapfold/matchmaker/history"
+	"github.com/jfmatt/snapfold/matchmaker/store"
+)
+
+func HandsCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "hands",
+		Short: "Export players' hand histories",
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write a player's hand histories, oldest first, in the format tracking tools import",
+	}
+	exportCmd.RunE = flagr.Run(exportCmd, ExportHands)
+	c.AddCommand(exportCmd)
+
+	return c
+}
+
+type ExportHandsArgs struct {
+	Dsn    string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	Player string `flag:"player,required,help=ID of the player whose hands to export"`
+	Since  string `flag:"since,help=Date to export hands from, as YYYY-MM-DD; every hand if unset"`
+	Limit  int    `flag:"limit,default=10000,help=Most hands to export"`
+	Out    string `flag:"out,help=File to write the hands to; standard output if unset"`
+}
+
+func ExportHands(flags *ExportHandsArgs, cmd *cobra.Command, args []string) error {
+	var since time.Time
+	if flags.Since != "" {
+		var err error
+		if since, err = time.Parse(time.DateOnly, flags.Since); err != nil {
+			return fmt.Errorf("since: %w", err)
+		}
+	}
+	db, err := store.Open(cmd.Context(), flags.Dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	hands, err := history.New(store.NewMatches(db)).Hands(cmd.Context(), flags.Player, since, flags.Limit)
+	if err != nil {
+		return err
+	}
+	var out io.Writer = cmd.OutOrStdout()
+	if flags.Out != "" {
+		f, err := os.Create(flags.Out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	for _, h := range hands {
+		if _, err := fmt.Fprintf(out, "%s\n\n", h.Text); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "exported %d hands\n", len(hands))
+	return nil
+}
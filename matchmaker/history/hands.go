@@ -0,0 +1,115 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"time"
+)
+
+var ErrInvalidHand = errors.New("invalid hand history")
+
+// Limits on how many hands Hands returns.
+const (
+	DefaultHandLimit = 1000
+	MaxHandLimit     = 10000
+)
+
+// Hand is one player's history of a hand, in the text format PokerStars
+// uses, as written by the handhistory package. Each player's history shows
+// only their own hole cards and those shown down.
+type Hand struct {
+	// Hand numbers are unique across tables.
+	Number   uint64
+	TableID  string
+	PlayerID string
+	PlayedAt time.Time
+	Text     string
+}
+
+// RecordHand saves the histories of a hand played at a table, keyed by
+// player ID. Recording a hand again replaces its histories.
+func (h *History) RecordHand(ctx context.Context, tableID string, number uint64, playedAt time.Time, histories map[string]string) error {
+	if number == 0 || len(histories) == 0 {
+		return ErrInvalidHand
+	}
+	hands := make([]Hand, 0, len(histories))
+	for playerID, text := range histories {
+		if playerID == "" || text == "" {
+			return ErrInvalidHand
+		}
+		hands = append(hands, Hand{Number: number, TableID: tableID, PlayerID: playerID, PlayedAt: playedAt, Text: text})
+	}
+	return h.store.SaveHands(ctx, hands)
+}
+
+// Hands returns up to limit of the player's hand histories played at or
+// after since, oldest first. limit is clamped to MaxHandLimit, and
+// DefaultHandLimit is used if it is not positive.
+func (h *History) Hands(ctx context.Context, playerID string, since time.Time, limit int) ([]Hand, error) {
+	if limit <= 0 {
+		limit = DefaultHandLimit
+	}
+	return h.store.ListHands(ctx, playerID, since, min(limit, MaxHandLimit))
+}
+
+// An ArchiveFunc saves hand histories before they are pruned, and fails
+// to have them kept. It is given a batch of hands played at or after from,
+// or any time if it is zero, and before to, which hands yields oldest first.
+// hands stops at the first error reading them, and yields it.
+type ArchiveFunc func(ctx context.Context, from, to time.Time, hands iter.Seq2[Hand, error]) error
+
+// PruneHands deletes the hand histories played more than keep ago,
+// archiving them first with archive unless it is nil. If keep is 0, no hands
+// are deleted, but the store still prepares for hands to come.
+func (h *History) PruneHands(ctx context.Context, keep time.Duration, archive ArchiveFunc) error {
+	var before time.Time
+	if keep > 0 {
+		before = h.now().Add(-keep)
+	}
+	return h.store.PruneHands(ctx, before, archive)
+}
+
+// RunRetention calls PruneHands every interval until ctx is done. Errors are
+// passed to onError, and do not stop the loop.
+func (h *History) RunRetention(ctx context.Context, interval, keep time.Duration, archive ArchiveFunc, onError func(error)) {
+	if err := h.PruneHands(ctx, keep, archive); err != nil {
+		onError(err)
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			if err := h.PruneHands(ctx, keep, archive); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Rake is what the house took from one hand.
+type Rake struct {
+	Number   uint64
+	TableID  string
+	PlayedAt time.Time
+	Amount   int64
+}
+
+// RecordRake saves what the house took from a hand played at a table.
+// Recording a hand's rake again replaces it.
+func (h *History) RecordRake(ctx context.Context, tableID string, number uint64, playedAt time.Time, amount int64) error {
+	if number == 0 || amount < 0 {
+		return ErrInvalidHand
+	}
+	return h.store.SaveRake(ctx, Rake{Number: number, TableID: tableID, PlayedAt: playedAt, Amount: amount})
+}
+
+// RakeByTable returns the rake taken at each table from hands played at or
+// after since and before until, by table ID. Tables that took none are left
+// out.
+func (h *History) RakeByTable(ctx context.Context, since, until time.Time) (map[string]int64, error) {
+	return h.store.SumRake(ctx, since, until)
+}
@@ -0,0 +1,112 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestHands(t *testing.T) {
+	h := New(NewMemStore())
+	t0 := time.Unix(1000, 0)
+	AssertThat(t, h.RecordHand(ctx, "m1", 2, t0.Add(time.Minute), map[string]string{"alice": "hand 2 for alice", "bob": "hand 2 for bob"}), Nil())
+	AssertThat(t, h.RecordHand(ctx, "m1", 1, t0, map[string]string{"alice": "hand 1 for alice"}), Nil())
+	AssertThat(t, h.RecordHand(ctx, "m1", 3, t0.Add(2*time.Minute), map[string]string{"alice": "hand 3 for alice"}), Nil())
+
+	texts := func(hands []Hand) []string {
+		var s []string
+		for _, h := range hands {
+			s = append(s, h.Text)
+		}
+		return s
+	}
+	hands, err := h.Hands(ctx, "alice", time.Time{}, 0)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, texts(hands), ElementsAre("hand 1 for alice", "hand 2 for alice", "hand 3 for alice"))
+	ExpectEq(t, hands[0], Hand{Number: 1, TableID: "m1", PlayerID: "alice", PlayedAt: t0, Text: "hand 1 for alice"})
+
+	hands, err = h.Hands(ctx, "alice", t0.Add(time.Minute), 1)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, texts(hands), ElementsAre("hand 2 for alice"))
+
+	// Recording a hand again replaces it.
+	AssertThat(t, h.RecordHand(ctx, "m1", 2, t0.Add(time.Minute), map[string]string{"bob": "hand 2 again"}), Nil())
+	hands, err = h.Hands(ctx, "bob", time.Time{}, 0)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, texts(hands), ElementsAre("hand 2 again"))
+}
+
+func TestRecordHand_Invalid(t *testing.T) {
+	h := New(NewMemStore())
+	ExpectThat(t, h.RecordHand(ctx, "m1", 0, time.Now(), map[string]string{"alice": "text"}), ErrorIs(ErrInvalidHand))
+	ExpectThat(t, h.RecordHand(ctx, "m1", 1, time.Now(), nil), ErrorIs(ErrInvalidHand))
+	ExpectThat(t, h.RecordHand(ctx, "m1", 1, time.Now(), map[string]string{"alice": ""}), ErrorIs(ErrInvalidHand))
+}
+
+func TestRake(t *testing.T) {
+	h := New(NewMemStore())
+	t0 := time.Unix(1000, 0)
+	AssertThat(t, h.RecordRake(ctx, "m1", 1, t0, 150), Nil())
+	AssertThat(t, h.RecordRake(ctx, "m1", 2, t0.Add(time.Minute), 300), Nil())
+	AssertThat(t, h.RecordRake(ctx, "m2", 3, t0.Add(time.Minute), 50), Nil())
+	AssertThat(t, h.RecordRake(ctx, "m2", 4, t0.Add(time.Hour), 75), Nil())
+
+	got, err := h.RakeByTable(ctx, t0, t0.Add(time.Hour))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got, map[string]int64{"m1": 450, "m2": 50})
+
+	// Recording a hand's rake again replaces it.
+	AssertThat(t, h.RecordRake(ctx, "m1", 2, t0.Add(time.Minute), 0), Nil())
+	got, err = h.RakeByTable(ctx, t0.Add(time.Second), t0.Add(2*time.Hour))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got, map[string]int64{"m2": 125})
+
+	ExpectThat(t, h.RecordRake(ctx, "m1", 0, t0, 10), ErrorIs(ErrInvalidHand))
+	ExpectThat(t, h.RecordRake(ctx, "m1", 5, t0, -10), ErrorIs(ErrInvalidHand))
+}
+
+func TestPruneHands(t *testing.T) {
+	h := New(NewMemStore())
+	t0 := time.Unix(1000, 0)
+	h.now = func() time.Time { return t0.Add(time.Hour) }
+	AssertThat(t, h.RecordHand(ctx, "m1", 1, t0, map[string]string{"alice": "hand 1 for alice", "bob": "hand 1 for bob"}), Nil())
+	AssertThat(t, h.RecordHand(ctx, "m1", 2, t0.Add(50*time.Minute), map[string]string{"alice": "hand 2 for alice"}), Nil())
+
+	// Nothing is pruned without a keep window.
+	AssertThat(t, h.PruneHands(ctx, 0, nil), Nil())
+	hands, err := h.Hands(ctx, "alice", time.Time{}, 0)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, hands, Len(2))
+
+	// Hands that fail to archive are kept.
+	failed := errors.New("archive unavailable")
+	err = h.PruneHands(ctx, 30*time.Minute, func(ctx context.Context, from, to time.Time, hands iter.Seq2[Hand, error]) error {
+		return failed
+	})
+	ExpectThat(t, err, ErrorIs(failed))
+	hands, err = h.Hands(ctx, "bob", time.Time{}, 0)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, hands, Len(1))
+
+	var archived []string
+	err = h.PruneHands(ctx, 30*time.Minute, func(ctx context.Context, from, to time.Time, hands iter.Seq2[Hand, error]) error {
+		ExpectEq(t, to, t0.Add(30*time.Minute))
+		for h, err := range hands {
+			AssertThat(t, err, Nil())
+			archived = append(archived, h.Text)
+		}
+		return nil
+	})
+	AssertThat(t, err, Nil())
+	ExpectThat(t, archived, ElementsAre("hand 1 for alice", "hand 1 for bob"))
+	hands, err = h.Hands(ctx, "alice", time.Time{}, 0)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, hands, Len(1))
+	hands, err = h.Hands(ctx, "bob", time.Time{}, 0)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, hands, Empty())
+}
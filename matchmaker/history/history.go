@@ -0,0 +1,170 @@
+// Package history records the matches the matchmaker forms and how they
+// ended, so that players can look back over their recent games.
+package history
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/matchmaker/paging"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+var (
+	ErrNotFound      = errors.New("match not found")
+	ErrReported      = errors.New("results already reported")
+	ErrInvalidPlaces = errors.New("places must cover every player in the match")
+)
+
+// Match is the record of a formed match.
+type Match struct {
+	ID       string
+	GameMode string
+	Region   string
+
+	// Set when the match filled seats at a running table rather than
+	// starting a new one.
+	TableID string
+
+	Players []string
+	Bots    int
+
+	// The table configuration of the match's game mode when it formed.
+	Config *pb.TableConfig
+
+	CreatedAt time.Time
+
+	// Finishing position of every player, where 1 is the winner, and when
+	// the game server reported it. Nil and zero until then.
+	Places  map[string]int
+	EndedAt time.Time
+}
+
+// Ended reports whether the match's results have been reported.
+func (m Match) Ended() bool {
+	return !m.EndedAt.IsZero()
+}
+
+// Cursor is a position in a list of matches, which are ordered by when
+// they formed and then by ID. The zero Cursor is the start of the list.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// Query picks the matches ListMatches returns.
+type Query struct {
+	// Only matches of this game mode, if set.
+	GameMode string
+
+	// Whether the list runs from oldest to newest, rather than newest to
+	// oldest.
+	OldestFirst bool
+
+	// Only matches after this position in the list.
+	After Cursor
+
+	Limit int
+}
+
+// Matches reports whether m is in the list that q picks from, after q's
+// cursor.
+func (q Query) Matches(m Match) bool {
+	if q.GameMode != "" && m.GameMode != q.GameMode {
+		return false
+	}
+	if q.After.ID == "" {
+		return true
+	}
+	c := cmp.Or(m.CreatedAt.Compare(q.After.CreatedAt), strings.Compare(m.ID, q.After.ID))
+	if q.OldestFirst {
+		return c > 0
+	}
+	return c < 0
+}
+
+// History records matches and looks them up. It is safe for concurrent use.
+type History struct {
+	store Store
+	now   func() time.Time
+}
+
+// New returns a History that keeps matches in store.
+func New(store Store) *History {
+	return &History{store: store, now: time.Now}
+}
+
+// Record saves a newly formed match, which was played with cfg.
+func (h *History) Record(ctx context.Context, m *queue.Match, cfg *pb.TableConfig) error {
+	return h.store.SaveMatch(ctx, Match{
+		ID:        m.ID,
+		GameMode:  m.GameMode,
+		Region:    m.Region,
+		TableID:   m.TableID,
+		Players:   m.Players(),
+		Bots:      m.Bots,
+		Config:    cfg,
+		CreatedAt: m.CreatedAt,
+	})
+}
+
+// Finish records the results of a match. places must give a position of at
+// least 1 to every player in the match, and nobody else. Results may only be
+// reported once.
+func (h *History) Finish(ctx context.Context, matchID string, places map[string]int) error {
+	m, err := h.store.GetMatch(ctx, matchID)
+	if err != nil {
+		return err
+	}
+	if m.Ended() {
+		return ErrReported
+	}
+	if len(places) != len(m.Players) {
+		return ErrInvalidPlaces
+	}
+	for _, id := range m.Players {
+		if place, ok := places[id]; !ok || place < 1 {
+			return ErrInvalidPlaces
+		}
+	}
+	return h.store.SaveResult(ctx, matchID, maps.Clone(places), h.now())
+}
+
+// Get returns the match with the given ID.
+func (h *History) Get(ctx context.Context, matchID string) (Match, error) {
+	return h.store.GetMatch(ctx, matchID)
+}
+
+// Matches is how a player's matches may be listed: by when they formed,
+// newest first unless asked otherwise, and only those of a game mode if
+// asked.
+var Matches = paging.Spec{
+	Default: paging.Sort{Field: "created_at", Desc: true},
+	Fields:  []string{"created_at"},
+	Filters: []string{"game_mode"},
+}
+
+// List returns a page of the matches the player played in, for a request
+// made with the Matches spec.
+func (h *History) List(ctx context.Context, playerID string, r paging.Request) (paging.Page[Match], error) {
+	q := Query{GameMode: r.Filter["game_mode"], OldestFirst: !r.Sort.Desc, Limit: r.Limit()}
+	if r.After != nil {
+		if len(r.After) != 2 {
+			return paging.Page[Match]{}, paging.ErrInvalidToken
+		}
+		q.After = Cursor{CreatedAt: time.Unix(0, paging.Int(r.After[0])), ID: r.After[1]}
+	}
+	matches, err := h.store.ListMatches(ctx, playerID, q)
+	if err != nil {
+		return paging.Page[Match]{}, err
+	}
+	return paging.Finish(r, matches, func(m Match) []string {
+		return []string{strconv.FormatInt(m.CreatedAt.UnixNano(), 10), m.ID}
+	}), nil
+}
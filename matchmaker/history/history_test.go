@@ -0,0 +1,99 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+var ctx = context.Background()
+
+func newMatch(id string, createdAt time.Time, players ...string) *queue.Match {
+	m := &queue.Match{ID: id, GameMode: "holdem", CreatedAt: createdAt}
+	for _, p := range players {
+		m.Tickets = append(m.Tickets, &queue.Ticket{PlayerID: p, Members: []string{p}})
+	}
+	return m
+}
+
+func TestFinish(t *testing.T) {
+	h := New(NewMemStore())
+	cfg := pb.TableConfig_builder{StandardGameId: proto.String("holdem-nl")}.Build()
+	AssertThat(t, h.Record(ctx, newMatch("m1", time.Unix(1000, 0), "alice", "bob"), cfg), Nil())
+
+	m, err := h.Get(ctx, "m1")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, m.Players, ElementsAre("alice", "bob"))
+	ExpectEq(t, m.Config.GetStandardGameId(), "holdem-nl")
+	ExpectEq(t, m.Ended(), false)
+
+	ExpectThat(t, h.Finish(ctx, "m2", map[string]int{"alice": 1}), ErrorIs(ErrNotFound))
+	ExpectThat(t, h.Finish(ctx, "m1", map[string]int{"alice": 1}), ErrorIs(ErrInvalidPlaces))
+	ExpectThat(t, h.Finish(ctx, "m1", map[string]int{"alice": 1, "carol": 2}), ErrorIs(ErrInvalidPlaces))
+	ExpectThat(t, h.Finish(ctx, "m1", map[string]int{"alice": 1, "bob": 0}), ErrorIs(ErrInvalidPlaces))
+
+	AssertThat(t, h.Finish(ctx, "m1", map[string]int{"alice": 2, "bob": 1}), Nil())
+	m, err = h.Get(ctx, "m1")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, m.Ended(), true)
+	ExpectEq(t, m.Places["bob"], 1)
+
+	// Results are recorded once.
+	ExpectThat(t, h.Finish(ctx, "m1", map[string]int{"alice": 1, "bob": 2}), ErrorIs(ErrReported))
+}
+
+func TestList(t *testing.T) {
+	h := New(NewMemStore())
+	start := time.Unix(1000, 0)
+	for i := range 5 {
+		AssertThat(t, h.Record(ctx, newMatch(fmt.Sprintf("m%d", i), start.Add(time.Duration(i)*time.Second), "alice", "bob"), nil), Nil())
+	}
+	// Matches formed together are still listed in a fixed order.
+	AssertThat(t, h.Record(ctx, newMatch("m5", start, "alice", "carol"), nil), Nil())
+
+	list := func(playerID string, size int, sort string) []string {
+		t.Helper()
+		var ids []string
+		token := ""
+		for {
+			r, err := Matches.Parse(size, token, sort, nil)
+			AssertThat(t, err, Nil())
+			p, err := h.List(ctx, playerID, r)
+			AssertThat(t, err, Nil())
+			for _, m := range p.Items {
+				ids = append(ids, m.ID)
+			}
+			if p.NextToken == "" {
+				return ids
+			}
+			token = p.NextToken
+		}
+	}
+	ExpectThat(t, list("alice", 4, ""), ElementsAre("m4", "m3", "m2", "m1", "m5", "m0"))
+	ExpectThat(t, list("alice", 4, "created_at"), ElementsAre("m0", "m5", "m1", "m2", "m3", "m4"))
+	ExpectThat(t, list("carol", 0, ""), ElementsAre("m5"))
+}
+
+func TestList_GameMode(t *testing.T) {
+	h := New(NewMemStore())
+	holdem := newMatch("m1", time.Unix(1000, 0), "alice", "bob")
+	omaha := newMatch("m2", time.Unix(1001, 0), "alice", "bob")
+	omaha.GameMode = "omaha"
+	AssertThat(t, h.Record(ctx, holdem, nil), Nil())
+	AssertThat(t, h.Record(ctx, omaha, nil), Nil())
+
+	r, err := Matches.Parse(1, "", "", map[string]string{"game_mode": "omaha"})
+	AssertThat(t, err, Nil())
+	p, err := h.List(ctx, "alice", r)
+	AssertThat(t, err, Nil())
+	AssertThat(t, p.Items, Len(1))
+	ExpectEq(t, p.Items[0].ID, "m2")
+	ExpectEq(t, p.NextToken, "")
+}
@@ -0,0 +1,210 @@
+package history
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store persists match history.
+type Store interface {
+	// SaveMatch records a newly formed match.
+	SaveMatch(ctx context.Context, m Match) error
+
+	// SaveResult records the finishing places of a match's players. It
+	// returns ErrNotFound if the match was never saved, and ErrReported if
+	// its results already were.
+	SaveResult(ctx context.Context, matchID string, places map[string]int, endedAt time.Time) error
+
+	// GetMatch returns the match with the given ID, or ErrNotFound.
+	GetMatch(ctx context.Context, matchID string) (Match, error)
+
+	// ListMatches returns up to q.Limit of the matches that the player
+	// played in that q picks, in q's order.
+	ListMatches(ctx context.Context, playerID string, q Query) ([]Match, error)
+
+	// SaveHands records players' histories of a hand, replacing any saved
+	// for the same hand and player.
+	SaveHands(ctx context.Context, hands []Hand) error
+
+	// ListHands returns up to limit of the player's hands played at or after
+	// since, oldest first.
+	ListHands(ctx context.Context, playerID string, since time.Time, limit int) ([]Hand, error)
+
+	// SaveRake records the rake taken from a hand, replacing any saved for
+	// the same hand.
+	SaveRake(ctx context.Context, r Rake) error
+
+	// SumRake totals the rake of hands played at or after since and before
+	// until, by table ID.
+	SumRake(ctx context.Context, since, until time.Time) (map[string]int64, error)
+
+	// PruneHands deletes the hands played before before, passing them to
+	// archive first, in batches, unless it is nil; a batch that fails to
+	// archive is kept. Stores may keep some of those hands until a later
+	// prune, to delete whole batches at once, and may use it to prepare for
+	// hands to come.
+	PruneHands(ctx context.Context, before time.Time, archive ArchiveFunc) error
+}
+
+// MemStore is an in-memory Store, for development and tests.
+type MemStore struct {
+	mu      sync.Mutex
+	matches map[string]Match // match ID -> match
+	hands   map[handKey]Hand
+	rake    map[uint64]Rake // hand number -> rake
+}
+
+type handKey struct {
+	number   uint64
+	playerID string
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{matches: map[string]Match{}, hands: map[handKey]Hand{}, rake: map[uint64]Rake{}}
+}
+
+func (s *MemStore) SaveMatch(ctx context.Context, m Match) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m.Players = slices.Clone(m.Players)
+	s.matches[m.ID] = m
+	return nil
+}
+
+func (s *MemStore) SaveResult(ctx context.Context, matchID string, places map[string]int, endedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.matches[matchID]
+	if !ok {
+		return ErrNotFound
+	}
+	if m.Ended() {
+		return ErrReported
+	}
+	m.Places, m.EndedAt = maps.Clone(places), endedAt
+	s.matches[matchID] = m
+	return nil
+}
+
+func (s *MemStore) GetMatch(ctx context.Context, matchID string) (Match, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.matches[matchID]
+	if !ok {
+		return Match{}, ErrNotFound
+	}
+	return clone(m), nil
+}
+
+func (s *MemStore) ListMatches(ctx context.Context, playerID string, q Query) ([]Match, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matches []Match
+	for _, m := range s.matches {
+		if slices.Contains(m.Players, playerID) && q.Matches(m) {
+			matches = append(matches, clone(m))
+		}
+	}
+	slices.SortFunc(matches, func(a, b Match) int {
+		c := cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID, b.ID))
+		if q.OldestFirst {
+			return c
+		}
+		return -c
+	})
+	return matches[:min(len(matches), q.Limit)], nil
+}
+
+func clone(m Match) Match {
+	m.Players = slices.Clone(m.Players)
+	m.Places = maps.Clone(m.Places)
+	return m
+}
+
+func (s *MemStore) SaveHands(ctx context.Context, hands []Hand) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, h := range hands {
+		s.hands[handKey{h.Number, h.PlayerID}] = h
+	}
+	return nil
+}
+
+func (s *MemStore) ListHands(ctx context.Context, playerID string, since time.Time, limit int) ([]Hand, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var hands []Hand
+	for _, h := range s.hands {
+		if h.PlayerID == playerID && !h.PlayedAt.Before(since) {
+			hands = append(hands, h)
+		}
+	}
+	slices.SortFunc(hands, func(a, b Hand) int {
+		if c := a.PlayedAt.Compare(b.PlayedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Number, b.Number)
+	})
+	return hands[:min(len(hands), limit)], nil
+}
+
+func (s *MemStore) PruneHands(ctx context.Context, before time.Time, archive ArchiveFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var hands []Hand
+	for _, h := range s.hands {
+		if h.PlayedAt.Before(before) {
+			hands = append(hands, h)
+		}
+	}
+	if len(hands) == 0 {
+		return nil
+	}
+	slices.SortFunc(hands, func(a, b Hand) int {
+		if c := a.PlayedAt.Compare(b.PlayedAt); c != 0 {
+			return c
+		}
+		return cmp.Or(cmp.Compare(a.Number, b.Number), cmp.Compare(a.PlayerID, b.PlayerID))
+	})
+	if archive != nil {
+		seq := func(yield func(Hand, error) bool) {
+			for _, h := range hands {
+				if !yield(h, nil) {
+					return
+				}
+			}
+		}
+		if err := archive(ctx, time.Time{}, before, seq); err != nil {
+			return err
+		}
+	}
+	for _, h := range hands {
+		delete(s.hands, handKey{h.Number, h.PlayerID})
+	}
+	return nil
+}
+
+func (s *MemStore) SaveRake(ctx context.Context, r Rake) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rake[r.Number] = r
+	return nil
+}
+
+func (s *MemStore) SumRake(ctx context.Context, since, until time.Time) (map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tables := map[string]int64{}
+	for _, r := range s.rake {
+		if !r.PlayedAt.Before(since) && r.PlayedAt.Before(until) && r.Amount > 0 {
+			tables[r.TableID] += r.Amount
+		}
+	}
+	return tables, nil
+}
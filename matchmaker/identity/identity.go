@@ -0,0 +1,35 @@
+// Package identity verifies tokens issued by external identity providers,
+// such as Google, Apple and Steam, so that players can sign in with an
+// account they already have.
+package identity
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidToken is returned for provider tokens that are malformed,
+// expired, or not meant for snapfold.
+var ErrInvalidToken = errors.New("invalid identity token")
+
+// Identity is a user as known to an identity provider.
+type Identity struct {
+	// Name of the provider, such as "google".
+	Provider string
+
+	// The provider's stable ID for the user.
+	Subject string
+
+	// Display name, if the provider shares one.
+	Name string
+}
+
+// Provider verifies tokens from one identity provider.
+type Provider interface {
+	// Name identifies the provider, such as "google".
+	Name() string
+
+	// Verify checks a token that a client obtained from the provider and
+	// returns who it identifies, or ErrInvalidToken.
+	Verify(ctx context.Context, token string) (Identity, error)
+}
@@ -0,0 +1,166 @@
+package identity
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// How long fetched signing keys are trusted, and how often an unknown key
+// ID may trigger a refetch.
+const (
+	keysTTL        = time.Hour
+	keysMinRefresh = time.Minute
+)
+
+// OIDC verifies OpenID Connect ID tokens signed with RSA keys published at
+// a JWKS URL.
+type OIDC struct {
+	name      string
+	issuers   []string
+	audiences []string
+	keysURL   string
+	client    *http.Client
+	now       func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey // key ID -> key
+	fetchedAt time.Time
+}
+
+// NewOIDC returns a provider that accepts ID tokens from one of issuers,
+// issued to one of the client IDs in audiences and signed with a key
+// published at keysURL.
+func NewOIDC(name string, issuers, audiences []string, keysURL string, client *http.Client) *OIDC {
+	return &OIDC{
+		name:      name,
+		issuers:   issuers,
+		audiences: audiences,
+		keysURL:   keysURL,
+		client:    client,
+		now:       time.Now,
+	}
+}
+
+// Google returns a provider for Google Sign-In ID tokens issued to any of
+// the OAuth client IDs.
+func Google(clientIDs []string, client *http.Client) *OIDC {
+	return NewOIDC("google", []string{"accounts.google.com", "https://accounts.google.com"}, clientIDs,
+		"https://www.googleapis.com/oauth2/v3/certs", client)
+}
+
+// Apple returns a provider for Sign in with Apple ID tokens issued to any of
+// the client IDs, which are app bundle IDs or services IDs.
+func Apple(clientIDs []string, client *http.Client) *OIDC {
+	return NewOIDC("apple", []string{"https://appleid.apple.com"}, clientIDs,
+		"https://appleid.apple.com/auth/keys", client)
+}
+
+func (p *OIDC) Name() string {
+	return p.name
+}
+
+type idClaims struct {
+	jwt.RegisteredClaims
+	Name string `json:"name"`
+}
+
+func (p *OIDC) Verify(ctx context.Context, token string) (Identity, error) {
+	var claims idClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(p.now),
+	)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if !slices.Contains(p.issuers, claims.Issuer) {
+		return Identity{}, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if !slices.ContainsFunc(claims.Audience, func(aud string) bool { return slices.Contains(p.audiences, aud) }) {
+		return Identity{}, fmt.Errorf("%w: not issued to this client", ErrInvalidToken)
+	}
+	if claims.Subject == "" {
+		return Identity{}, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+	return Identity{Provider: p.name, Subject: claims.Subject, Name: claims.Name}, nil
+}
+
+// key returns the signing key with the ID, fetching the provider's keys if
+// they are stale or do not include it, since providers rotate their keys.
+func (p *OIDC) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	age := p.now().Sub(p.fetchedAt)
+	if k, ok := p.keys[kid]; ok && age < keysTTL {
+		return k, nil
+	}
+	if p.keys == nil || age >= keysMinRefresh {
+		keys, err := fetchKeys(ctx, p.client, p.keysURL)
+		if err != nil {
+			return nil, err
+		}
+		p.keys, p.fetchedAt = keys, p.now()
+	}
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+func fetchKeys(ctx context.Context, client *http.Client, url string) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching signing keys: %s", resp.Status)
+	}
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("fetching signing keys: %w", err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys, nil
+}
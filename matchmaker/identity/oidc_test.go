@@ -0,0 +1,111 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/jfmatt/gotest"
+)
+
+var ctx = context.Background()
+
+// keyServer publishes the public halves of its keys as a JWKS.
+type keyServer struct {
+	*httptest.Server
+	keys    map[string]*rsa.PrivateKey
+	fetches int
+}
+
+func newKeyServer(t *testing.T, kids ...string) *keyServer {
+	ks := &keyServer{keys: map[string]*rsa.PrivateKey{}}
+	for _, kid := range kids {
+		ks.addKey(t, kid)
+	}
+	ks.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ks.fetches++
+		var set jwks
+		for kid, k := range ks.keys {
+			set.Keys = append(set.Keys, struct {
+				Kty string `json:"kty"`
+				Kid string `json:"kid"`
+				N   string `json:"n"`
+				E   string `json:"e"`
+			}{"RSA", kid, base64.RawURLEncoding.EncodeToString(k.N.Bytes()), base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())})
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(ks.Close)
+	return ks
+}
+
+func (ks *keyServer) addKey(t *testing.T, kid string) {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	AssertThat(t, err, Nil())
+	ks.keys[kid] = k
+}
+
+func (ks *keyServer) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = kid
+	s, err := tok.SignedString(ks.keys[kid])
+	AssertThat(t, err, Nil())
+	return s
+}
+
+func TestOIDC(t *testing.T) {
+	ks := newKeyServer(t, "k1")
+	now := time.Unix(1_700_000_000, 0)
+	p := NewOIDC("google", []string{"accounts.google.com"}, []string{"web", "ios"}, ks.URL, ks.Client())
+	p.now = func() time.Time { return now }
+
+	claims := func(iss, aud string, exp time.Time) jwt.MapClaims {
+		return jwt.MapClaims{"iss": iss, "aud": aud, "sub": "123", "name": "Alice", "exp": exp.Unix()}
+	}
+
+	id, err := p.Verify(ctx, ks.sign(t, "k1", claims("accounts.google.com", "ios", now.Add(time.Hour))))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, id, Identity{Provider: "google", Subject: "123", Name: "Alice"})
+
+	for _, tok := range []string{
+		"bogus",
+		ks.sign(t, "k1", claims("evil.example.com", "ios", now.Add(time.Hour))),
+		ks.sign(t, "k1", claims("accounts.google.com", "android", now.Add(time.Hour))),
+		ks.sign(t, "k1", claims("accounts.google.com", "ios", now.Add(-time.Hour))),
+	} {
+		_, err := p.Verify(ctx, tok)
+		ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+	}
+	ExpectEq(t, ks.fetches, 1)
+}
+
+func TestOIDC_KeyRotation(t *testing.T) {
+	ks := newKeyServer(t, "k1")
+	now := time.Unix(1_700_000_000, 0)
+	p := NewOIDC("apple", []string{"https://appleid.apple.com"}, []string{"app"}, ks.URL, ks.Client())
+	p.now = func() time.Time { return now }
+	claims := jwt.MapClaims{"iss": "https://appleid.apple.com", "aud": "app", "sub": "abc", "exp": now.Add(time.Hour).Unix()}
+
+	_, err := p.Verify(ctx, ks.sign(t, "k1", claims))
+	AssertThat(t, err, Nil())
+
+	// A new key is picked up, but not refetched for every unknown key ID.
+	ks.addKey(t, "k2")
+	_, err = p.Verify(ctx, ks.sign(t, "k2", claims))
+	ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+	ExpectEq(t, ks.fetches, 1)
+
+	now = now.Add(keysMinRefresh)
+	claims["exp"] = now.Add(time.Hour).Unix()
+	_, err = p.Verify(ctx, ks.sign(t, "k2", claims))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, ks.fetches, 2)
+}
@@ -0,0 +1,68 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Steam verifies session tickets from the Steamworks GetAuthSessionTicket
+// API, hex encoded, using the Steam Web API.
+type Steam struct {
+	appID   string
+	key     string
+	client  *http.Client
+	baseURL string
+}
+
+// NewSteam returns a provider for tickets issued to the app, checked with a
+// Steam Web API publisher key.
+func NewSteam(appID, key string, client *http.Client) *Steam {
+	return &Steam{appID: appID, key: key, client: client, baseURL: "https://partner.steam-api.com"}
+}
+
+func (p *Steam) Name() string {
+	return "steam"
+}
+
+type steamResponse struct {
+	Response struct {
+		Params *struct {
+			Result  string `json:"result"`
+			SteamID string `json:"steamid"`
+		} `json:"params"`
+		Error *struct {
+			Code int    `json:"errorcode"`
+			Desc string `json:"errordesc"`
+		} `json:"error"`
+	} `json:"response"`
+}
+
+func (p *Steam) Verify(ctx context.Context, ticket string) (Identity, error) {
+	q := url.Values{"key": {p.key}, "appid": {p.appID}, "ticket": {ticket}}
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/ISteamUserAuth/AuthenticateUserTicket/v1/?"+q.Encode(), nil)
+	if err != nil {
+		return Identity{}, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Identity{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Identity{}, fmt.Errorf("steam: %s", resp.Status)
+	}
+	var body steamResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Identity{}, fmt.Errorf("steam: %w", err)
+	}
+	if e := body.Response.Error; e != nil {
+		return Identity{}, fmt.Errorf("%w: steam error %d: %s", ErrInvalidToken, e.Code, e.Desc)
+	}
+	if r := body.Response.Params; r == nil || r.Result != "OK" || r.SteamID == "" {
+		return Identity{}, fmt.Errorf("%w: steam did not accept the ticket", ErrInvalidToken)
+	}
+	return Identity{Provider: "steam", Subject: body.Response.Params.SteamID}, nil
+}
@@ -0,0 +1,39 @@
+package identity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestSteam(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/ISteamUserAuth/AuthenticateUserTicket/v1/" || q.Get("key") != "secret" || q.Get("appid") != "480" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if q.Get("ticket") != "abcd" {
+			w.Write([]byte(`{"response": {"error": {"errorcode": 101, "errordesc": "Invalid ticket"}}}`))
+			return
+		}
+		w.Write([]byte(`{"response": {"params": {"result": "OK", "steamid": "76561197960287930"}}}`))
+	}))
+	defer srv.Close()
+
+	p := NewSteam("480", "secret", srv.Client())
+	p.baseURL = srv.URL
+	id, err := p.Verify(ctx, "abcd")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, id, Identity{Provider: "steam", Subject: "76561197960287930"})
+
+	_, err = p.Verify(ctx, "ffff")
+	ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+
+	p = NewSteam("480", "wrong", srv.Client())
+	p.baseURL = srv.URL
+	_, err = p.Verify(ctx, "abcd")
+	ExpectThat(t, err, Not(Nil()))
+}
@@ -0,0 +1,408 @@
+// Package lobby applies the matchmaking rules shared by the matchmaker's
+// HTTP and gRPC interfaces: which game modes exist, how parties queue, and
+// who may sit at private tables.
+package lobby
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/matchmaker/history"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/penalty"
+	"github.com/jfmatt/snapfold/matchmaker/private"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/seat"
+)
+
+var (
+	ErrUnknownGameMode = errors.New("unknown game mode")
+	ErrPartyTooLarge   = errors.New("party is too large for the game mode")
+	ErrUnknownRegion   = errors.New("unknown region")
+	ErrInvalidLatency  = errors.New("latency must be positive")
+)
+
+// Lobby is where players gather into parties and join the queue.
+type Lobby struct {
+	queue     *queue.Queue
+	parties   *party.Manager
+	regions   map[string]string
+	tables    *private.Manager
+	seats     *seat.Manager
+	history   *history.History
+	penalties *penalty.Manager
+
+	mu        sync.RWMutex
+	gameModes map[string]*pb.TableConfig
+}
+
+// How long disconnected players may rejoin their table for, unless
+// WithSeats says otherwise.
+const defaultRejoinGrace = 2 * time.Minute
+
+// Option configures a Lobby.
+type Option func(*Lobby)
+
+// WithRegions sets the regions that host games, mapping each region's name
+// to the address clients should probe to measure their latency to it.
+// Without it, latency reports may name any region.
+func WithRegions(regions map[string]string) Option {
+	return func(l *Lobby) { l.regions = regions }
+}
+
+// WithSeats sets the manager that records where matched players are seated.
+// Without it, seats are kept in memory and players have two minutes to
+// rejoin after disconnecting.
+func WithSeats(m *seat.Manager) Option {
+	return func(l *Lobby) { l.seats = m }
+}
+
+// WithHistory sets where formed matches and their results are recorded.
+// Without it, match history is kept in memory.
+func WithHistory(h *history.History) Option {
+	return func(l *Lobby) { l.history = h }
+}
+
+// WithPenalties sets the manager that bars players who dodge matches from
+// queueing. Without it, nobody is penalized.
+func WithPenalties(m *penalty.Manager) Option {
+	return func(l *Lobby) { l.penalties = m }
+}
+
+// New returns a Lobby placing players into q. gameModes holds the table
+// configuration for each game mode that may be queued for; if it is empty,
+// any game mode is accepted with default settings.
+func New(q *queue.Queue, parties *party.Manager, gameModes map[string]*pb.TableConfig, opts ...Option) *Lobby {
+	l := &Lobby{
+		queue:     q,
+		parties:   parties,
+		gameModes: gameModes,
+		tables:    private.NewManager(),
+		seats:     seat.NewManager(seat.NewMemStore(), defaultRejoinGrace),
+		history:   history.New(history.NewMemStore()),
+		penalties: penalty.NewManager(penalty.NewMemStore(), nil),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Regions returns the configured regions and their probe addresses.
+func (l *Lobby) Regions() map[string]string {
+	return l.regions
+}
+
+// checkRegion returns an error unless region is empty or configured. Any
+// region is accepted if none are configured.
+func (l *Lobby) checkRegion(region string) error {
+	if _, ok := l.regions[region]; region != "" && len(l.regions) > 0 && !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownRegion, region)
+	}
+	return nil
+}
+
+// Queue returns the queue that players are placed into.
+func (l *Lobby) Queue() *queue.Queue {
+	return l.queue
+}
+
+// Parties returns the manager tracking the lobby's parties.
+func (l *Lobby) Parties() *party.Manager {
+	return l.parties
+}
+
+// PrivateTables returns the manager tracking the lobby's private tables.
+func (l *Lobby) PrivateTables() *private.Manager {
+	return l.tables
+}
+
+// Seats returns the manager tracking where matched players are seated.
+func (l *Lobby) Seats() *seat.Manager {
+	return l.seats
+}
+
+// Penalties returns the manager tracking players who dodge matches.
+func (l *Lobby) Penalties() *penalty.Manager {
+	return l.penalties
+}
+
+// History returns the record of formed matches.
+func (l *Lobby) History() *history.History {
+	return l.history
+}
+
+// RecordMatch adds a newly formed match to the history, along with its game
+// mode's configuration.
+func (l *Lobby) RecordMatch(ctx context.Context, m *queue.Match) error {
+	cfg, err := l.TableConfig(m.GameMode)
+	if err != nil {
+		return err
+	}
+	return l.history.Record(ctx, m, cfg)
+}
+
+// TableConfig returns the configuration for a game mode.
+func (l *Lobby) TableConfig(gameMode string) (*pb.TableConfig, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if len(l.gameModes) == 0 {
+		return &pb.TableConfig{}, nil
+	}
+	cfg, ok := l.gameModes[gameMode]
+	if !ok {
+		return nil, ErrUnknownGameMode
+	}
+	return cfg, nil
+}
+
+// SetTableConfig replaces the configuration for a game mode, for the
+// matches formed from then on. The game mode must already exist.
+func (l *Lobby) SetTableConfig(gameMode string, cfg *pb.TableConfig) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.gameModes[gameMode]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownGameMode, gameMode)
+	}
+	l.gameModes[gameMode] = cfg
+	return nil
+}
+
+// MaxPartySize returns the largest party that may queue for a game mode.
+func MaxPartySize(cfg *pb.TableConfig) int {
+	if n := int(cfg.GetMaxPartySize()); n > 0 && n < party.MaxSize {
+		return n
+	}
+	return party.MaxSize
+}
+
+// BotPolicies returns the bot fill settings of the game modes whose
+// TableConfig allows bots, for use with queue.WithBots.
+func BotPolicies(gameModes map[string]*pb.TableConfig) map[string]queue.BotPolicy {
+	policies := map[string]queue.BotPolicy{}
+	for name, cfg := range gameModes {
+		if !cfg.HasBotFill() {
+			continue
+		}
+		fill := cfg.GetBotFill()
+		policies[name] = queue.BotPolicy{
+			After:     fill.GetAfter().AsDuration(),
+			MinHumans: int(fill.GetMinHumans()),
+		}
+	}
+	return policies
+}
+
+// PenaltyPolicies returns the dodge penalty settings of the game modes whose
+// TableConfig sets them, for use with penalty.NewManager.
+func PenaltyPolicies(gameModes map[string]*pb.TableConfig) map[string]penalty.Policy {
+	policies := map[string]penalty.Policy{}
+	for name, cfg := range gameModes {
+		if !cfg.HasDodgePenalty() {
+			continue
+		}
+		p := cfg.GetDodgePenalty()
+		policy := penalty.Policy{
+			Decay:         p.GetDecay().AsDuration(),
+			AbandonWithin: p.GetAbandonWithin().AsDuration(),
+		}
+		for _, d := range p.GetDelays() {
+			policy.Delays = append(policy.Delays, d.AsDuration())
+		}
+		policies[name] = policy
+	}
+	return policies
+}
+
+// Enqueue places the player in the queue. A player in a party queues the
+// whole party, which only the leader may do. Players serving a dodge penalty
+// may not queue, and neither may parties that include one.
+func (l *Lobby) Enqueue(ctx context.Context, playerID, gameMode string) (*queue.Ticket, error) {
+	cfg, err := l.TableConfig(gameMode)
+	if err != nil {
+		return nil, err
+	}
+
+	p, ok := l.parties.ForPlayer(playerID)
+	if !ok {
+		if _, seated := l.tables.ForPlayer(playerID); seated {
+			return nil, private.ErrSeated
+		}
+		if err := l.penalties.Check(ctx, gameMode, playerID); err != nil {
+			return nil, err
+		}
+		return l.queue.Enqueue(ctx, playerID, gameMode)
+	}
+	if p.Leader != playerID {
+		return nil, party.ErrNotLeader
+	}
+	if len(p.Members) > MaxPartySize(cfg) {
+		return nil, ErrPartyTooLarge
+	}
+	for _, id := range p.Members {
+		if _, seated := l.tables.ForPlayer(id); seated {
+			return nil, private.ErrSeated
+		}
+	}
+	if err := l.penalties.Check(ctx, gameMode, p.Members...); err != nil {
+		return nil, err
+	}
+	return l.queue.EnqueueParty(ctx, p.ID, p.Members, gameMode)
+}
+
+// CreatePrivateTable opens a private table hosted by the player. The table
+// uses cfg if it is set, and otherwise the game mode's configuration. Players
+// must leave the queue before sitting at a private table.
+func (l *Lobby) CreatePrivateTable(playerID, gameMode string, cfg *pb.TableConfig, seats int) (private.Table, error) {
+	if cfg == nil {
+		var err error
+		if cfg, err = l.TableConfig(gameMode); err != nil {
+			return private.Table{}, err
+		}
+	}
+	if _, ok := l.queue.TicketFor(playerID); ok {
+		return private.Table{}, queue.ErrAlreadyQueued
+	}
+	return l.tables.Create(playerID, gameMode, cfg, seats)
+}
+
+// JoinPrivateTable seats the player at the private table with the given join
+// code.
+func (l *Lobby) JoinPrivateTable(playerID, code string) (private.Table, error) {
+	if _, ok := l.queue.TicketFor(playerID); ok {
+		return private.Table{}, queue.ErrAlreadyQueued
+	}
+	return l.tables.Join(code, playerID)
+}
+
+// ReleaseSeat frees a player's seat once they have left its table for good.
+// Leaving soon after being seated counts against the player as a dodge.
+func (l *Lobby) ReleaseSeat(ctx context.Context, tableID, playerID string) error {
+	s, err := l.seats.Release(ctx, tableID, playerID)
+	if err != nil {
+		return err
+	}
+	_, err = l.penalties.Left(ctx, s.GameMode, playerID, s.SeatedAt)
+	return err
+}
+
+// EnqueuePriority places each player in the queue individually at priority
+// p, for players returned to matchmaking by another service. Players who are
+// already queued keep their existing ticket.
+func (l *Lobby) EnqueuePriority(ctx context.Context, playerIDs []string, gameMode string, p queue.Priority) ([]*queue.Ticket, error) {
+	if _, err := l.TableConfig(gameMode); err != nil {
+		return nil, err
+	}
+	var tickets []*queue.Ticket
+	for _, id := range playerIDs {
+		t, err := l.queue.Enqueue(ctx, id, gameMode, queue.WithPriority(p))
+		if errors.Is(err, queue.ErrAlreadyQueued) {
+			continue
+		} else if err != nil {
+			return tickets, err
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, nil
+}
+
+// EstimateWait returns recent wait times for a game mode, in a region or, if
+// region is empty, across all regions.
+func (l *Lobby) EstimateWait(gameMode, region string) (queue.WaitEstimate, error) {
+	if _, err := l.TableConfig(gameMode); err != nil {
+		return queue.WaitEstimate{}, err
+	}
+	if err := l.checkRegion(region); err != nil {
+		return queue.WaitEstimate{}, err
+	}
+	return l.queue.EstimateWait(gameMode, region), nil
+}
+
+// RequestBackfill asks for players to fill open seats at a running table.
+func (l *Lobby) RequestBackfill(tableID, gameMode, region string, seats int) (queue.Backfill, error) {
+	if _, err := l.TableConfig(gameMode); err != nil {
+		return queue.Backfill{}, err
+	}
+	if err := l.checkRegion(region); err != nil {
+		return queue.Backfill{}, err
+	}
+	return l.queue.RequestBackfill(tableID, gameMode, region, seats)
+}
+
+// Ticket returns the record of a ticket, and its position if it is still
+// queued, provided that the player is one of its members. Tickets belonging
+// to others are reported as not found.
+func (l *Lobby) Ticket(ctx context.Context, playerID, ticketID string) (queue.Record, int, error) {
+	r, pos, err := l.queue.Status(ctx, ticketID)
+	if err != nil {
+		return queue.Record{}, 0, err
+	}
+	if !r.Ticket.Has(playerID) {
+		return queue.Record{}, 0, queue.ErrNotFound
+	}
+	return r, pos, nil
+}
+
+// Cancel removes a ticket from the queue. Any member of the ticket may cancel
+// it, and canceling a ticket again has no effect.
+func (l *Lobby) Cancel(ctx context.Context, playerID, ticketID string) (*queue.Ticket, error) {
+	if _, _, err := l.Ticket(ctx, playerID, ticketID); err != nil {
+		return nil, err
+	}
+	return l.queue.Cancel(ctx, ticketID)
+}
+
+// ReportLatency records the player's round-trip times to each region for a
+// queued ticket they are a member of.
+func (l *Lobby) ReportLatency(ctx context.Context, playerID, ticketID string, rtts queue.Latencies) error {
+	if _, _, err := l.Ticket(ctx, playerID, ticketID); err != nil {
+		return err
+	}
+	for region, rtt := range rtts {
+		if err := l.checkRegion(region); err != nil || region == "" {
+			return fmt.Errorf("%w: %q", ErrUnknownRegion, region)
+		}
+		if rtt <= 0 {
+			return fmt.Errorf("%w: %s", ErrInvalidLatency, region)
+		}
+	}
+	return l.queue.ReportLatency(ticketID, playerID, rtts)
+}
+
+// JoinParty adds the player to a party they were invited to. A player with a
+// ticket of their own must cancel it first. If the party is queued, its
+// ticket is canceled so that the leader can requeue with the new member.
+func (l *Lobby) JoinParty(ctx context.Context, partyID, playerID string) (party.Party, error) {
+	if _, ok := l.queue.TicketFor(playerID); ok {
+		return party.Party{}, queue.ErrAlreadyQueued
+	}
+	p, err := l.parties.Join(partyID, playerID)
+	if err != nil {
+		return party.Party{}, err
+	}
+	if t, ok := l.queue.TicketFor(p.Leader); ok && t.PartyID == p.ID {
+		if _, err := l.queue.Cancel(ctx, t.ID); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// LeaveParty removes the player from their party. If the party is queued, its
+// ticket is canceled, since it no longer reflects the party's members.
+func (l *Lobby) LeaveParty(ctx context.Context, playerID string) (party.Party, error) {
+	p, err := l.parties.Leave(playerID)
+	if err != nil {
+		return party.Party{}, err
+	}
+	if t, ok := l.queue.TicketFor(playerID); ok && t.PartyID == p.ID {
+		if _, err := l.queue.Cancel(ctx, t.ID); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
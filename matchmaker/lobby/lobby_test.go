@@ -0,0 +1,292 @@
+package lobby
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/penalty"
+	"github.com/jfmatt/snapfold/matchmaker/private"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+var ctx = context.Background()
+
+func newParty(t *testing.T, m *party.Manager, members ...string) party.Party {
+	t.Helper()
+	p, err := m.Create(members[0])
+	AssertThat(t, err, Nil())
+	for _, id := range members[1:] {
+		_, err := m.Invite(p.ID, members[0], id)
+		AssertThat(t, err, Nil())
+		p, err = m.Join(p.ID, id)
+		AssertThat(t, err, Nil())
+	}
+	return p
+}
+
+func TestEnqueue_UnknownGameMode(t *testing.T) {
+	l := New(queue.New(), party.NewManager(), map[string]*pb.TableConfig{"holdem": {}})
+	_, err := l.Enqueue(ctx, "alice", "omaha")
+	ExpectThat(t, err, ErrorIs(ErrUnknownGameMode))
+
+	_, err = l.Enqueue(ctx, "alice", "holdem")
+	ExpectThat(t, err, Nil())
+}
+
+func TestEnqueue_Party(t *testing.T) {
+	parties := party.NewManager()
+	l := New(queue.New(), parties, nil)
+	p := newParty(t, parties, "alice", "bob")
+
+	_, err := l.Enqueue(ctx, "bob", "holdem")
+	ExpectThat(t, err, ErrorIs(party.ErrNotLeader))
+
+	tk, err := l.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, tk.PartyID, p.ID)
+	ExpectThat(t, tk.Members, ElementsAre("alice", "bob"))
+
+	// Any member can see and cancel the ticket.
+	_, _, err = l.Ticket(ctx, "bob", tk.ID)
+	ExpectThat(t, err, Nil())
+	_, _, err = l.Ticket(ctx, "carol", tk.ID)
+	ExpectThat(t, err, ErrorIs(queue.ErrNotFound))
+	_, err = l.Cancel(ctx, "bob", tk.ID)
+	ExpectThat(t, err, Nil())
+
+	// The ticket's final state remains visible to its members.
+	r, _, err := l.Ticket(ctx, "alice", tk.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.State, queue.StateCanceled)
+}
+
+func TestEnqueue_PartySizeLimit(t *testing.T) {
+	parties := party.NewManager()
+	modes := map[string]*pb.TableConfig{
+		"headsup": pb.TableConfig_builder{MaxPartySize: proto.Int32(2)}.Build(),
+	}
+	l := New(queue.New(), parties, modes)
+	newParty(t, parties, "a", "b", "c")
+
+	_, err := l.Enqueue(ctx, "a", "headsup")
+	ExpectThat(t, err, ErrorIs(ErrPartyTooLarge))
+}
+
+func TestLeaveParty_CancelsTicket(t *testing.T) {
+	q := queue.New()
+	parties := party.NewManager()
+	l := New(q, parties, nil)
+	newParty(t, parties, "alice", "bob")
+
+	_, err := l.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+
+	_, err = l.LeaveParty(ctx, "bob")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, q.Len(), 0)
+}
+
+func TestJoinParty(t *testing.T) {
+	q := queue.New()
+	parties := party.NewManager()
+	l := New(q, parties, nil)
+	p := newParty(t, parties, "alice")
+	for _, id := range []string{"bob", "carol"} {
+		_, err := parties.Invite(p.ID, "alice", id)
+		AssertThat(t, err, Nil())
+	}
+
+	// A queued player must cancel before joining.
+	_, err := l.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = l.JoinParty(ctx, p.ID, "bob")
+	ExpectThat(t, err, ErrorIs(queue.ErrAlreadyQueued))
+
+	// Joining a queued party cancels its ticket.
+	_, err = l.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = l.JoinParty(ctx, p.ID, "carol")
+	AssertThat(t, err, Nil())
+	_, ok := q.TicketFor("alice")
+	ExpectEq(t, ok, false)
+}
+
+func TestReportLatency(t *testing.T) {
+	q := queue.New()
+	l := New(q, party.NewManager(), nil, WithRegions(map[string]string{"us-east": "probe.use:7000"}))
+	tk, err := l.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+
+	err = l.ReportLatency(ctx, "alice", tk.ID, queue.Latencies{"mars": time.Millisecond})
+	ExpectThat(t, err, ErrorIs(ErrUnknownRegion))
+	err = l.ReportLatency(ctx, "alice", tk.ID, queue.Latencies{"us-east": 0})
+	ExpectThat(t, err, ErrorIs(ErrInvalidLatency))
+	err = l.ReportLatency(ctx, "bob", tk.ID, queue.Latencies{"us-east": time.Millisecond})
+	ExpectThat(t, err, ErrorIs(queue.ErrNotFound))
+
+	err = l.ReportLatency(ctx, "alice", tk.ID, queue.Latencies{"us-east": time.Millisecond})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, q.Latency(tk.ID), queue.Latencies{"us-east": time.Millisecond})
+}
+
+func TestEnqueuePriority(t *testing.T) {
+	q := queue.New()
+	l := New(q, party.NewManager(), map[string]*pb.TableConfig{"holdem": {}})
+	_, err := l.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+
+	_, err = l.EnqueuePriority(ctx, []string{"bob"}, "omaha", queue.PriorityRequeue)
+	ExpectThat(t, err, ErrorIs(ErrUnknownGameMode))
+
+	tickets, err := l.EnqueuePriority(ctx, []string{"alice", "bob"}, "holdem", queue.PriorityRequeue)
+	AssertThat(t, err, Nil())
+	AssertThat(t, tickets, Len(1))
+	ExpectEq(t, tickets[0].PlayerID, "bob")
+	ExpectEq(t, tickets[0].Priority, queue.PriorityRequeue)
+}
+
+func TestRequestBackfill(t *testing.T) {
+	l := New(queue.New(), party.NewManager(), nil, WithRegions(map[string]string{"us-east": "probe.use:7000"}))
+	_, err := l.RequestBackfill("table1", "holdem", "mars", 1)
+	ExpectThat(t, err, ErrorIs(ErrUnknownRegion))
+
+	b, err := l.RequestBackfill("table1", "holdem", "us-east", 1)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, b.TableID, "table1")
+}
+
+func TestRecordMatch(t *testing.T) {
+	cfg := pb.TableConfig_builder{MaxPartySize: proto.Int32(2)}.Build()
+	q := queue.New()
+	l := New(q, party.NewManager(), map[string]*pb.TableConfig{"holdem": cfg})
+	_, err := l.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = l.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+
+	AssertThat(t, l.RecordMatch(ctx, matches[0]), Nil())
+	m, err := l.History().Get(ctx, matches[0].ID)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, m.Players, ElementsAre("alice", "bob"))
+	ExpectEq(t, m.Config.GetMaxPartySize(), int32(2))
+}
+
+func TestSetTableConfig(t *testing.T) {
+	l := New(queue.New(), party.NewManager(), map[string]*pb.TableConfig{"holdem": {}})
+	cfg := pb.TableConfig_builder{MaxPartySize: proto.Int32(2)}.Build()
+	AssertThat(t, l.SetTableConfig("holdem", cfg), Nil())
+	got, err := l.TableConfig("holdem")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got.GetMaxPartySize(), int32(2))
+
+	ExpectThat(t, l.SetTableConfig("omaha", cfg), ErrorIs(ErrUnknownGameMode))
+}
+
+func TestEstimateWait(t *testing.T) {
+	l := New(queue.New(), party.NewManager(), map[string]*pb.TableConfig{"holdem": {}},
+		WithRegions(map[string]string{"us-east": "probe.use:7000"}))
+	_, err := l.EstimateWait("omaha", "")
+	ExpectThat(t, err, ErrorIs(ErrUnknownGameMode))
+	_, err = l.EstimateWait("holdem", "mars")
+	ExpectThat(t, err, ErrorIs(ErrUnknownRegion))
+
+	e, err := l.EstimateWait("holdem", "us-east")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, e.Samples, 0)
+}
+
+func TestMaxPartySize(t *testing.T) {
+	ExpectEq(t, MaxPartySize(&pb.TableConfig{}), party.MaxSize)
+	ExpectEq(t, MaxPartySize(pb.TableConfig_builder{MaxPartySize: proto.Int32(3)}.Build()), 3)
+	ExpectEq(t, MaxPartySize(pb.TableConfig_builder{MaxPartySize: proto.Int32(10)}.Build()), party.MaxSize)
+}
+
+func TestBotPolicies(t *testing.T) {
+	policies := BotPolicies(map[string]*pb.TableConfig{
+		"holdem": pb.TableConfig_builder{
+			BotFill: pb.TableConfig_BotFill_builder{
+				After:     durationpb.New(90 * time.Second),
+				MinHumans: proto.Int32(2),
+			}.Build(),
+		}.Build(),
+		"omaha": {},
+	})
+	ExpectEq(t, policies, map[string]queue.BotPolicy{"holdem": {After: 90 * time.Second, MinHumans: 2}})
+}
+
+func TestCreatePrivateTable(t *testing.T) {
+	parties := party.NewManager()
+	l := New(queue.New(), parties, map[string]*pb.TableConfig{"holdem": {}})
+	_, err := l.CreatePrivateTable("alice", "omaha", nil, 4)
+	ExpectThat(t, err, ErrorIs(ErrUnknownGameMode))
+
+	// Queued players can't sit at a private table.
+	_, err = l.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = l.CreatePrivateTable("bob", "holdem", nil, 4)
+	ExpectThat(t, err, ErrorIs(queue.ErrAlreadyQueued))
+
+	tb, err := l.CreatePrivateTable("alice", "holdem", nil, 4)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, tb.Config, Not(Nil()))
+	_, err = l.JoinPrivateTable("bob", tb.Code)
+	ExpectThat(t, err, ErrorIs(queue.ErrAlreadyQueued))
+	_, err = l.JoinPrivateTable("carol", tb.Code)
+	AssertThat(t, err, Nil())
+
+	// Nor can seated players queue, alone or in a party.
+	_, err = l.Enqueue(ctx, "alice", "holdem")
+	ExpectThat(t, err, ErrorIs(private.ErrSeated))
+	newParty(t, parties, "dave", "carol")
+	_, err = l.Enqueue(ctx, "dave", "holdem")
+	ExpectThat(t, err, ErrorIs(private.ErrSeated))
+}
+
+func TestDodgePenalty(t *testing.T) {
+	penalties := penalty.NewManager(penalty.NewMemStore(), map[string]penalty.Policy{
+		"holdem": {Delays: []time.Duration{time.Minute}, AbandonWithin: time.Minute},
+	})
+	parties := party.NewManager()
+	l := New(queue.New(), parties, nil, WithPenalties(penalties))
+	AssertThat(t, penalties.Dodged(ctx, "holdem", "bob"), Nil())
+
+	_, err := l.Enqueue(ctx, "bob", "holdem")
+	ExpectThat(t, err, ErrorIs(penalty.ErrPenalized))
+	_, err = l.Enqueue(ctx, "bob", "omaha")
+	ExpectThat(t, err, Nil())
+
+	// A party can't queue while any member is penalized.
+	newParty(t, parties, "alice", "bob")
+	_, err = l.Enqueue(ctx, "alice", "holdem")
+	ExpectThat(t, err, ErrorIs(penalty.ErrPenalized))
+
+	// Leaving a table right after it starts is a dodge.
+	m := &queue.Match{ID: "m1", GameMode: "holdem", Tickets: []*queue.Ticket{{PlayerID: "carol", Members: []string{"carol"}}}}
+	AssertThat(t, l.Seats().Assign(ctx, m, ""), Nil())
+	AssertThat(t, l.ReleaseSeat(ctx, "m1", "carol"), Nil())
+	_, err = l.Enqueue(ctx, "carol", "holdem")
+	ExpectThat(t, err, ErrorIs(penalty.ErrPenalized))
+}
+
+func TestPenaltyPolicies(t *testing.T) {
+	policies := PenaltyPolicies(map[string]*pb.TableConfig{
+		"holdem": pb.TableConfig_builder{DodgePenalty: pb.TableConfig_DodgePenalty_builder{
+			Delays: []*durationpb.Duration{durationpb.New(time.Minute), durationpb.New(time.Hour)},
+			Decay:  durationpb.New(24 * time.Hour),
+		}.Build()}.Build(),
+		"omaha": {},
+	})
+	ExpectEq(t, len(policies), 1)
+	ExpectThat(t, policies["holdem"].Delays, ElementsAre(time.Minute, time.Hour))
+	ExpectEq(t, policies["holdem"].Decay, 24*time.Hour)
+}
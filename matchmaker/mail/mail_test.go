@@ -0,0 +1,22 @@
+package mail
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestLog(t *testing.T) {
+	var b strings.Builder
+	l := NewLog(&b)
+	AssertThat(t, l.Send(context.Background(), Message{To: "alice@example.com", Subject: "Hi", Body: "Hello"}), Nil())
+	ExpectEq(t, b.String(), "To: alice@example.com\nSubject: Hi\n\nHello\n\n")
+}
+
+func TestFormat(t *testing.T) {
+	got := string(format("snapfold@example.com", Message{To: "alice@example.com", Subject: "Hi", Body: "one\ntwo"}))
+	ExpectEq(t, strings.HasPrefix(got, "From: snapfold@example.com\r\nTo: alice@example.com\r\nSubject: Hi\r\n"), true)
+	ExpectEq(t, strings.HasSuffix(got, "\r\n\r\none\r\ntwo"), true)
+}
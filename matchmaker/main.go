@@ -1,11 +1,60 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/jfmatt/flagr"
+	"github.com/redis/go-redis/v9"
 	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/config"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/lib/health"
+	"github.com/jfmatt/snapfold/lib/idempotency"
+	"github.com/jfmatt/snapfold/lib/log"
+	"github.com/jfmatt/snapfold/lib/metrics"
+	"github.com/jfmatt/snapfold/lib/tracing"
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/api"
+	"github.com/jfmatt/snapfold/matchmaker/apikey"
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+	"github.com/jfmatt/snapfold/matchmaker/fleet"
+	"github.com/jfmatt/snapfold/matchmaker/history"
+	"github.com/jfmatt/snapfold/matchmaker/identity"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/mail"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/penalty"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/rating"
+	"github.com/jfmatt/snapfold/matchmaker/redispool"
+	"github.com/jfmatt/snapfold/matchmaker/registry"
+	"github.com/jfmatt/snapfold/matchmaker/rpc"
+	"github.com/jfmatt/snapfold/matchmaker/rules"
+	"github.com/jfmatt/snapfold/matchmaker/season"
+	"github.com/jfmatt/snapfold/matchmaker/seat"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+	"github.com/jfmatt/snapfold/matchmaker/store"
+	"github.com/jfmatt/snapfold/matchmaker/wallet"
+	"github.com/jfmatt/snapfold/matchmaker/webhook"
 )
 
 func main() {
@@ -15,7 +64,15 @@ func main() {
 	}
 
 	c.AddCommand(MigrationCommand())
+	c.AddCommand(SeedCommand())
 	c.AddCommand(ServerCommand())
+	c.AddCommand(SeasonCommand())
+	c.AddCommand(AccountCommand())
+	c.AddCommand(APIKeyCommand())
+	c.AddCommand(HandsCommand())
+	c.AddCommand(GameModeCommand())
+	c.AddCommand(ConfigCommand())
+	config.Register(c, "MATCHMAKER")
 
 	if err := c.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -23,30 +80,710 @@ func main() {
 	}
 }
 
-type MigrateArgs struct {
-	Dsn string
+type ServeArgs struct {
+	Host string `flag:"host,default=0.0.0.0,help=Address to bind the HTTP server to"`
+	Port int    `flag:"port,default=8080,help=Port for the HTTP server, which also serves the gRPC services as JSON under /rpc/"`
+
+	GrpcPort int `flag:"grpc-port,default=9090,help=Port for the gRPC server"`
+
+	MetricsPort int `flag:"metrics-port,default=9091,help=Port to serve Prometheus metrics and the /healthz and /readyz checks on, as well as the main port; 0 to not serve them"`
+
+	OTLP OTLPArgs `flag:"otlp"`
+
+	LogLevel  string `flag:"log-level,default=info,help=Least severe records to log: debug, info, warn or error"`
+	LogFormat string `flag:"log-format,default=text,help=Format to log records in: text or json"`
+
+	Dsn           string      `flag:"dsn,help=Postgres connection string, or sqlite:PATH for a SQLite database; state is kept in memory if unset"`
+	ReplicaDsn    string      `flag:"replica-dsn,help=Postgres connection string of a read replica to send reads that may be a little stale to, such as match history and season standings; requires dsn, and db.health-interval to measure its lag"`
+	RedisURL      string      `flag:"redis-url,help=URL of a Redis server holding the queue so that several replicas can share it; requires dsn; the queue is kept in memory if unset"`
+	InternalToken string      `flag:"internal-token,help=Token that lets other services call every internal endpoint; prefer API keys, which are limited to scopes and can be revoked"`
+	DB            DBArgs      `flag:"db"`
+	Session       SessionArgs `flag:"session"`
+
+	HandRetention HandRetentionArgs `flag:"hand-retention"`
+
+	Google GoogleArgs `flag:"google"`
+	Apple  AppleArgs  `flag:"apple"`
+	Steam  SteamArgs  `flag:"steam"`
+	Mail   MailArgs   `flag:"mail"`
+
+	GameModes     map[string]string `flag:"game-mode,help=Game mode name and path to its TableConfig in YAML, JSON or textproto, as name=path, or name=preset:NAME for a built-in preset; any mode is accepted if unset"`
+	MatchRules    string            `flag:"match-rules,help=Path to a MatchRules textproto; if set, it replaces the table-size, rating-window, ticket-ttl, max-rtt and region-fallback flags"`
+	TableSize     int               `flag:"table-size,default=6,help=Number of players seated per match"`
+	MatchInterval time.Duration     `flag:"match-interval,default=1s,help=How often to form matches from the queue"`
+	RatingWindow  RatingWindowArgs  `flag:"rating-window"`
+	WaitWindow    time.Duration     `flag:"wait-window,default=15m,help=How far back to look at matched tickets when estimating wait times"`
+	PriorityAging time.Duration     `flag:"priority-aging,default=30s,help=Wait after which a ticket moves up one priority tier; 0 to disable"`
+	TicketTTL     time.Duration     `flag:"ticket-ttl,default=10m,help=How long a ticket may wait before it is dropped from the queue; 0 to wait forever"`
+	ReadyCheck    time.Duration     `flag:"ready-check,default=20s,help=How long players have to accept a new match; 0 to seat matches without asking"`
+	RejoinGrace   time.Duration     `flag:"rejoin-grace,default=2m,help=How long a player who disconnects mid-match may rejoin their seat"`
+
+	RegistryGameModes bool `flag:"registry-game-modes,help=Also offer every TableConfig in the config registry as a game mode, as stored at startup apart from later changes to rake and feature flags; game-mode files take precedence"`
+	PresetGameModes   bool `flag:"preset-game-modes,help=Also offer each built-in TableConfig preset as a game mode of its name; game-mode files and the registry take precedence"`
+
+	Environment string `flag:"environment,help=Environment the matchmaker runs in, such as staging, which chooses the feature flags that apply to game modes"`
+
+	Regions        map[string]string  `flag:"region,help=Region name and address of its latency probe, as name=host:port"`
+	MaxRTT         time.Duration      `flag:"max-rtt,help=Largest round-trip time a player may have to their match's region; 0 for no limit"`
+	RegionFallback RegionFallbackArgs `flag:"region-fallback"`
+
+	Allocator        string        `flag:"allocator,default=none,help=How to place new matches on game servers: none, fleet for servers that register by heartbeat, or agones"`
+	HeartbeatTimeout time.Duration `flag:"heartbeat-timeout,default=15s,help=How long a game server may go without a heartbeat before it stops receiving matches"`
+	Agones           AgonesArgs    `flag:"agones"`
+
+	ReloadInterval time.Duration `flag:"reload-interval,default=30s,help=How often to check the config file, match rules and game modes for changes that apply without a restart: the log level, queue thresholds, and game modes' rake and feature flags; 0 to not check"`
+
+	ShutdownTimeout time.Duration `flag:"shutdown-timeout,default=10s,help=How long to wait for in-flight requests on shutdown"`
+}
+
+type OTLPArgs struct {
+	Endpoint    string  `flag:"endpoint,help=host:port of an OTLP/gRPC collector to export traces to; traces are not exported if unset, though their context is still passed on"`
+	Insecure    bool    `flag:"insecure,help=Connect to the collector without TLS"`
+	SampleRatio float64 `flag:"sample-ratio,default=1,help=Fraction of traces started here to export, from 0 to 1; traces continued from a caller are exported if the caller's are"`
+}
+
+type DBArgs struct {
+	MaxOpenConns    int           `flag:"max-open-conns,default=20,help=Most connections to the database open at once; 0 for no limit"`
+	MaxIdleConns    int           `flag:"max-idle-conns,default=10,help=Most idle connections kept open for reuse"`
+	ConnMaxLifetime time.Duration `flag:"conn-max-lifetime,default=30m,help=Age at which connections are closed and replaced, so that they are spread over database failovers; 0 to keep them"`
+	ConnMaxIdleTime time.Duration `flag:"conn-max-idle-time,default=5m,help=How long a connection may sit idle before it is closed; 0 to keep it"`
+	HealthInterval  time.Duration `flag:"health-interval,default=10s,help=How often to ping the database to check it can be reached; 0 to not check"`
+	HealthTimeout   time.Duration `flag:"health-timeout,default=2s,help=How long a health check ping may take before the database is considered unreachable"`
+}
+
+type SessionArgs struct {
+	Key          string        `flag:"key,help=Secret that access tokens are signed with, shared by every replica; if unset a random one is used, so sessions end on restart"`
+	AccessTTL    time.Duration `flag:"access-ttl,default=15m,help=How long an access token lasts before it must be refreshed"`
+	RefreshTTL   time.Duration `flag:"refresh-ttl,default=720h,help=How long a refresh token lasts, and so how long a player stays logged in without using it"`
+	SyncInterval time.Duration `flag:"sync-interval,default=5s,help=How often to check for sessions revoked by other replicas, which keep working here until then"`
+}
+
+type GoogleArgs struct {
+	ClientIDs string `flag:"client-ids,help=Comma-separated OAuth client IDs whose Google ID tokens players may sign in with; Google sign-in is disabled if unset"`
+}
+
+type AppleArgs struct {
+	ClientIDs string `flag:"client-ids,help=Comma-separated bundle or services IDs whose Apple ID tokens players may sign in with; Apple sign-in is disabled if unset"`
+}
+
+type SteamArgs struct {
+	AppID     string `flag:"app-id,help=Steam app ID that players' session tickets are issued for; Steam sign-in is disabled if unset"`
+	WebAPIKey string `flag:"web-api-key,help=Steam Web API publisher key used to check session tickets"`
+}
+
+type MailArgs struct {
+	VerifyURL string `flag:"verify-url,help=Page linked from verification emails, which passes the token in its query on to /v1/accounts/verify; email is not verified if unset"`
+	ResetURL  string `flag:"reset-url,help=Page linked from password reset emails, which passes the token in its query on to /v1/accounts/reset-password; passwords cannot be reset if unset"`
+	Sender    string `flag:"sender,default=log,help=How to send email: log to write it to stdout, or smtp"`
+	SMTPAddr  string `flag:"smtp-addr,help=SMTP server to relay through, as host:port"`
+	SMTPUser  string `flag:"smtp-user,help=Username for the SMTP server; no authentication if unset"`
+	SMTPPass  string `flag:"smtp-password,help=Password for the SMTP server"`
+	From      string `flag:"from,default=noreply@snapfold.invalid,help=Address email is sent from"`
 }
 
-func MigrationCommand() *cobra.Command {
+type RatingWindowArgs struct {
+	Initial float64 `flag:"initial,default=100,help=Largest rating gap allowed between newly queued players"`
+	Growth  float64 `flag:"growth,default=5,help=Rating gap added per second of waiting"`
+	Max     float64 `flag:"max,help=Upper bound on the rating gap; 0 for no bound"`
+}
+
+type RegionFallbackArgs struct {
+	After     time.Duration     `flag:"after,default=1m,help=Wait after which a ticket may be matched in regions neighboring its own"`
+	Neighbors map[string]string `flag:"neighbor,help=Neighboring regions and the latency penalty for matching across them, as region/region=duration"`
+}
+
+type AgonesArgs struct {
+	Endpoint  string `flag:"endpoint,help=URL of the Agones allocator service"`
+	Namespace string `flag:"namespace,default=default,help=Namespace of the game server fleet"`
+	Cert      string `flag:"cert,help=Path to the client certificate presented to the allocator service"`
+	Key       string `flag:"key,help=Path to the client certificate's private key"`
+	CA        string `flag:"ca,help=Path to the CA certificate that signed the allocator service's certificate"`
+}
+
+func ServerCommand() *cobra.Command {
+	return serverCommand(ServeHttp)
+}
+
+// serverCommand returns the serve command, which runs serve with its flags.
+func serverCommand(serve func(*ServeArgs, *cobra.Command, []string) error) *cobra.Command {
 	c := &cobra.Command{
-		Use:   "migrate",
-		Short: "Update database schemas",
+		Use:   "serve",
+		Short: "Serve login and matchmaking server",
 	}
-	c.RunE = flagr.Run(c, Migrate)
+	c.RunE = flagr.Run(c, serve)
 	return c
 }
 
-func Migrate(flags *MigrateArgs, cmd *cobra.Command, args []string) error {
+func ServeHttp(flags *ServeArgs, cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	level, err := log.ParseLevel(flags.LogLevel)
+	if err != nil {
+		return err
+	}
+	var logLevel slog.LevelVar
+	logLevel.Set(level)
+	logger, err := log.New(cmd.ErrOrStderr(), &logLevel, flags.LogFormat)
+	if err != nil {
+		return err
+	}
+	recentErrors := log.NewRecent(dashboardErrors)
+	logger = log.WithRecent(logger, recentErrors)
+	// Changes to settings are audited whatever the log level.
+	auditLog, err := log.New(cmd.ErrOrStderr(), slog.LevelInfo, flags.LogFormat)
+	if err != nil {
+		return err
+	}
+	auditLog = log.Component(auditLog, "audit")
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Service:     "matchmaker",
+		Endpoint:    flags.OTLP.Endpoint,
+		Insecure:    flags.OTLP.Insecure,
+		SampleRatio: flags.OTLP.SampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("exporting traces", log.Err(err))
+		}
+	}()
+
+	var ratings rating.Store = rating.NewMemStore()
+	var tickets queue.TicketStore = queue.NewMemTicketStore()
+	var seats seat.Store = seat.NewMemStore()
+	var matches history.Store = history.NewMemStore()
+	var offenses penalty.Store = penalty.NewMemStore()
+	var seasons season.Store = season.NewMemStore()
+	var accounts account.Store = account.NewMemStore()
+	var refreshTokens session.RefreshStore = session.NewMemRefreshStore()
+	var apiKeys apikey.Store = apikey.NewMemStore()
+	var configStore registry.Store = registry.NewMemStore()
+	var idempotencyKeys idempotency.Store = idempotency.NewMemStore()
+	var webhookStore webhook.Store = webhook.NewMemStore()
+	var auditStore audit.Store = audit.NewMemStore()
+	var walletStore wallet.Store = wallet.NewMemStore()
+	var db *store.DB
+	var dbHealth *store.Health
+	if flags.ReplicaDsn != "" && (flags.Dsn == "" || flags.DB.HealthInterval <= 0) {
+		return errors.New("replica-dsn requires dsn, and db.health-interval to measure the replica's lag")
+	}
+	if flags.Dsn != "" {
+		var err error
+		db, err = store.Open(ctx, flags.Dsn)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if flags.ReplicaDsn != "" {
+			if err := db.OpenReplica(ctx, flags.ReplicaDsn); err != nil {
+				return fmt.Errorf("replica: %w", err)
+			}
+		}
+		db.Configure(store.Pool{
+			MaxOpen:     flags.DB.MaxOpenConns,
+			MaxIdle:     flags.DB.MaxIdleConns,
+			MaxLifetime: flags.DB.ConnMaxLifetime,
+			MaxIdleTime: flags.DB.ConnMaxIdleTime,
+		})
+		dbHealth = store.NewHealth(db)
+		ratings = store.NewRatings(db)
+		tickets = store.NewTickets(db)
+		seats = store.NewSeats(db)
+		matches = store.NewMatches(db)
+		offenses = store.NewPenalties(db)
+		seasons = store.NewSeasons(db)
+		accounts = store.NewAccounts(db)
+		refreshTokens = store.NewRefreshTokens(db)
+		apiKeys = store.NewAPIKeys(db)
+		configStore = store.NewConfigs(db)
+		idempotencyKeys = store.NewIdempotencyKeys(db)
+		webhookStore = store.NewWebhooks(db)
+		auditStore = store.NewAuditLog(db)
+		walletStore = store.NewWallets(db)
+	}
+	configs := registry.New(configStore)
+
+	loadedModes, err := gatherGameModes(ctx, flags, configs)
+	if err != nil {
+		return err
+	}
+	matchRules, err := loadMatchRules(flags)
+	if err != nil {
+		return err
+	}
+	gameModes := maps.Clone(loadedModes)
+	for name, cfg := range gameModes {
+		cfg = gamedefio.Resolve(cfg, flags.Environment)
+		gameModes[name] = cfg
+		if err := gamedefio.ValidateSeats(cfg, rules.TableSize(matchRules)); err != nil {
+			return fmt.Errorf("game mode %s: %w", name, err)
+		}
+	}
+
+	penalties := penalty.NewManager(offenses, lobby.PenaltyPolicies(gameModes))
+
+	// Readiness covers what serving requests needs: the database and Redis.
+	checker := health.New()
+	if db != nil {
+		checker.Add("db", func(ctx context.Context) error {
+			if flags.DB.HealthInterval > 0 {
+				return dbHealth.Err()
+			}
+			return db.PingContext(ctx)
+		})
+	}
+
+	queueOpts := rules.QueueOptions(matchRules)
+	if flags.RedisURL != "" {
+		if flags.Dsn == "" {
+			return errors.New("redis-url requires dsn, so that every replica can read ticket history")
+		}
+		opts, err := redis.ParseURL(flags.RedisURL)
+		if err != nil {
+			return err
+		}
+		client := redis.NewClient(opts)
+		defer client.Close()
+		checker.Add("redis", func(ctx context.Context) error { return client.Ping(ctx).Err() })
+		queueOpts = append(queueOpts, queue.WithPool(redispool.New(client, "snapfold:queue")))
+	}
+
+	reg := metrics.NewRegistry()
+	if db != nil {
+		registerDBMetrics(reg, db, dbHealth)
+	}
+	q := queue.New(append(queueOpts,
+		queue.WithRatings(func(ctx context.Context, playerID string) (float64, error) {
+			r, err := ratings.Get(ctx, playerID)
+			return r[playerID].Rating, err
+		}),
+		queue.WithPriorityAging(flags.PriorityAging),
+		queue.WithWaitWindow(flags.WaitWindow),
+		queue.WithTicketStore(tickets),
+		queue.WithReadyCheck(flags.ReadyCheck),
+		queue.WithBots(lobby.BotPolicies(gameModes)),
+		queue.WithDodgeFunc(func(ctx context.Context, m *queue.Match, playerIDs []string) error {
+			return penalties.Dodged(ctx, m.GameMode, playerIDs...)
+		}),
+	)...)
+	reg.GaugeFunc("matchmaker_queue_tickets", "Tickets waiting in the queue.", func() float64 { return float64(q.Len()) })
+	matchWait := reg.Histogram("matchmaker_match_wait_seconds", "Time tickets waited in the queue before being matched.",
+		[]float64{1, 5, 10, 30, 60, 120, 300, 600, 1800}, "game_mode")
+	hist := history.New(matches)
+	l := lobby.New(q, party.NewManager(), gameModes,
+		lobby.WithRegions(flags.Regions),
+		lobby.WithSeats(seat.NewManager(seats, flags.RejoinGrace)),
+		lobby.WithHistory(hist),
+		lobby.WithPenalties(penalties),
+	)
+	providers, err := identityProviders(flags)
+	if err != nil {
+		return err
+	}
+	accountOpts := []account.Option{account.WithProviders(providers...)}
+	if flags.Mail.VerifyURL != "" || flags.Mail.ResetURL != "" {
+		sender, err := mailSender(flags.Mail)
+		if err != nil {
+			return err
+		}
+		if flags.Mail.VerifyURL != "" {
+			accountOpts = append(accountOpts, account.WithVerification(sender, flags.Mail.VerifyURL))
+		}
+		if flags.Mail.ResetURL != "" {
+			accountOpts = append(accountOpts, account.WithPasswordReset(sender, flags.Mail.ResetURL))
+		}
+	}
+	sessionOpts := []session.Option{
+		session.WithRefreshStore(refreshTokens),
+		session.WithTTLs(flags.Session.AccessTTL, flags.Session.RefreshTTL),
+	}
+	if flags.Session.Key != "" {
+		sessionOpts = append(sessionOpts, session.WithKey([]byte(flags.Session.Key)))
+	} else if flags.RedisURL != "" {
+		return errors.New("session.key is required with redis-url, so that every replica accepts the same tokens")
+	}
+	sessions := session.NewStore(sessionOpts...)
+	keys := apikey.NewManager(apiKeys)
+	idempotent := idempotency.New(idempotencyKeys, idempotency.DefaultTTL)
+	webhooks := webhook.NewManager(webhookStore)
+
+	allocator, registry, err := newAllocator(flags)
+	if err != nil {
+		return err
+	}
+	dash := &dashboard{queue: q, fleet: registry, recent: recentErrors}
+	dash.registerMetrics(reg)
+
+	handler := api.NewServer(api.Config{
+		Lobby:         l,
+		Sessions:      sessions,
+		Accounts:      account.NewManager(accounts, accountOpts...),
+		Ratings:       ratings,
+		Seasons:       season.NewManager(seasons, ratings),
+		APIKeys:       keys,
+		Registry:      configs,
+		Idempotency:   idempotent,
+		Webhooks:      webhooks,
+		Audit:         audit.New(auditStore),
+		Wallets:       wallet.New(walletStore),
+		Dashboard:     dash.Handler(),
+		InternalToken: flags.InternalToken,
+	})
+	grpcAddr := net.JoinHostPort(flags.Host, strconv.Itoa(flags.GrpcPort))
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		return err
+	}
+	grpcOpts := []rpc.ServerOption{rpc.WithAPIKeys(keys), rpc.WithIdempotency(idempotent)}
+	if registry != nil {
+		grpcOpts = append(grpcOpts, rpc.WithFleet(registry, flags.InternalToken))
+	}
+	grpcSrv := rpc.NewServer(l, sessions, grpcOpts...)
+	// Browsers reach the gRPC services through the JSON gateway, alongside
+	// the REST endpoints.
+	mux := http.NewServeMux()
+	mux.Handle("/", handler)
+	mux.Handle("/rpc/", rpc.NewGateway(l, sessions, grpcOpts...))
+	checker.Register(mux)
+	srv := &http.Server{
+		Addr:    net.JoinHostPort(flags.Host, strconv.Itoa(flags.Port)),
+		Handler: log.Handler(mux),
+	}
+
+	matchLog := log.Component(logger, "matchmaking")
+	go q.Run(ctx, flags.MatchInterval, rules.TableSize(matchRules), func(ctx context.Context, m *queue.Match) {
+		ctx = log.With(ctx, log.MatchID(m.ID))
+		for _, t := range m.Tickets {
+			matchWait.Observe(m.CreatedAt.Sub(t.CreatedAt).Seconds(), m.GameMode)
+		}
+		if err := l.RecordMatch(ctx, m); err != nil {
+			matchLog.ErrorContext(ctx, "recording match", log.Err(err))
+		}
+		if err := webhooks.PublishMatchCreated(ctx, m); err != nil {
+			matchLog.ErrorContext(ctx, "publishing match to webhooks", log.Err(err))
+		}
+		var address string
+		if allocator != nil && m.TableID == "" {
+			alloc, err := allocator.Allocate(ctx, m)
+			if err != nil {
+				matchLog.WarnContext(ctx, "allocating a server; returning the match's players to the queue", log.Err(err))
+				if _, err := l.EnqueuePriority(ctx, m.Players(), m.GameMode, queue.PriorityRequeue); err != nil {
+					matchLog.ErrorContext(ctx, "requeueing match", log.Err(err))
+				}
+				return
+			}
+			address = alloc.Address
+		}
+		if err := l.Seats().Assign(ctx, m, address); err != nil {
+			matchLog.ErrorContext(ctx, "seating match", log.Err(err))
+		}
+		if m.TableID != "" {
+			matchLog.InfoContext(ctx, "backfilled table", log.TableID(m.TableID), slog.Int("players", len(m.Players())))
+			return
+		}
+		matchLog.InfoContext(ctx, "formed match", slog.String("game_mode", m.GameMode), slog.Int("players", len(m.Players())),
+			slog.Int("bots", m.Bots), slog.String("region", m.Region), slog.String("address", address))
+	}, func(err error) {
+		matchLog.Error("matchmaking", log.Err(err))
+	})
+
+	if flags.ReloadInterval > 0 {
+		watcher, err := config.NewWatcher(cmd.Flags(), reloadFlags(flags)...)
+		if err != nil {
+			return err
+		}
+		r := &reloader{
+			args:         *flags,
+			fs:           cmd.Flags(),
+			watcher:      watcher,
+			level:        &logLevel,
+			queue:        q,
+			lobby:        l,
+			configs:      configs,
+			rules:        matchRules,
+			modes:        loadedModes,
+			pendingModes: map[string]*pb.TableConfig{},
+			log:          log.Component(logger, "reload"),
+			audit:        auditLog,
+		}
+		go r.Run(ctx, flags.ReloadInterval)
+	}
+	go sessions.Run(ctx, flags.Session.SyncInterval, func(err error) {
+		log.Component(logger, "sessions").Error("syncing revoked sessions", log.Err(err))
+	})
+	go idempotent.Run(ctx, time.Hour, func(err error) {
+		log.Component(logger, "idempotency").Error("pruning idempotency keys", log.Err(err))
+	})
+	go webhooks.Run(ctx, 5*time.Second, func(err error) {
+		log.Component(logger, "webhooks").Error("delivering webhooks", log.Err(err))
+	})
+	go hist.RunRetention(ctx, cmp.Or(flags.HandRetention.Interval, time.Hour), flags.HandRetention.Keep,
+		archiveCommand(flags.HandRetention.ArchiveCommand), func(err error) {
+			log.Component(logger, "retention").Error("pruning hand histories", log.Err(err))
+		})
+	if dbHealth != nil && flags.DB.HealthInterval > 0 {
+		dbLog := log.Component(logger, "db")
+		go dbHealth.Run(ctx, flags.DB.HealthInterval, cmp.Or(flags.DB.HealthTimeout, flags.DB.HealthInterval), func(err error) {
+			if err != nil {
+				dbLog.Error("database unreachable", log.Err(err))
+				return
+			}
+			dbLog.Info("database reachable again")
+		})
+	}
+	if flags.MetricsPort != 0 {
+		metricsSrv := &http.Server{
+			Addr:    net.JoinHostPort(flags.Host, strconv.Itoa(flags.MetricsPort)),
+			Handler: metricsHandler(reg, checker),
+		}
+		defer metricsSrv.Close()
+		go func() {
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("serving metrics", log.Err(err))
+			}
+		}()
+	}
+
+	errc := make(chan error, 2)
+	go func() {
+		logger.Info("listening", slog.String("addr", srv.Addr))
+		errc <- srv.ListenAndServe()
+	}()
+	go func() {
+		logger.Info("gRPC listening", slog.String("addr", grpcAddr))
+		errc <- grpcSrv.Serve(lis)
+	}()
+
+	select {
+	case err := <-errc:
+		srv.Close()
+		grpcSrv.Stop()
+		return err
+	case <-ctx.Done():
+	}
+
+	checker.Drain()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cmp.Or(flags.ShutdownTimeout, 10*time.Second))
+	defer cancel()
+	go func() {
+		<-shutdownCtx.Done()
+		grpcSrv.Stop()
+	}()
+	grpcSrv.GracefulStop()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	for range 2 {
+		if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	}
 	return nil
 }
 
-func ServerCommand() *cobra.Command {
-	return &cobra.Command{
-		Use:   "serve",
-		Short: "Serve login and matchmaking server",
+// newAllocator returns the allocator chosen by the flags, or nil if matches
+// are not placed on servers. If game servers register by heartbeat, it also
+// returns the fleet they join.
+func newAllocator(flags *ServeArgs) (fleet.Allocator, *fleet.Fleet, error) {
+	switch flags.Allocator {
+	case "none":
+		return nil, nil, nil
+	case "fleet":
+		f := fleet.NewFleet(flags.HeartbeatTimeout)
+		return f, f, nil
+	case "agones":
+		if flags.Agones.Endpoint == "" {
+			return nil, nil, errors.New("agones.endpoint is required with the agones allocator")
+		}
+		client, err := agonesClient(flags.Agones)
+		if err != nil {
+			return nil, nil, err
+		}
+		return fleet.NewAgones(flags.Agones.Endpoint, flags.Agones.Namespace, client), nil, nil
+	}
+	return nil, nil, fmt.Errorf("unknown allocator %q", flags.Allocator)
+}
+
+// agonesClient returns an HTTP client that authenticates to the Agones
+// allocator service with mutual TLS.
+func agonesClient(args AgonesArgs) (*http.Client, error) {
+	cfg := &tls.Config{}
+	if args.Cert != "" || args.Key != "" {
+		cert, err := tls.LoadX509KeyPair(args.Cert, args.Key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
 	}
+	if args.CA != "" {
+		pem, err := os.ReadFile(args.CA)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found", args.CA)
+		}
+	}
+	return &http.Client{
+		Transport: tracing.Transport(&http.Transport{TLSClientConfig: cfg}),
+		Timeout:   10 * time.Second,
+	}, nil
+}
+
+// gatherGameModes reads the TableConfig for each game mode offered, from
+// the flags' files and presets and, if the flags say so, the registry and
+// the built-in presets. Their feature flags are not yet resolved.
+func gatherGameModes(ctx context.Context, flags *ServeArgs, configs *registry.Registry) (map[string]*pb.TableConfig, error) {
+	modes, err := loadGameModes(flags.GameModes)
+	if err != nil {
+		return nil, err
+	}
+	if flags.RegistryGameModes {
+		if err := loadRegistryGameModes(ctx, configs, modes); err != nil {
+			return nil, err
+		}
+	}
+	if flags.PresetGameModes {
+		if err := loadPresetGameModes(modes); err != nil {
+			return nil, err
+		}
+	}
+	return modes, nil
 }
 
-func ServeHttp(cmd *cobra.Command, args []string) error {
+// loadGameModes reads the TableConfig for each configured game mode, from
+// its file or built-in preset.
+func loadGameModes(paths map[string]string) (map[string]*pb.TableConfig, error) {
+	modes := map[string]*pb.TableConfig{}
+	for name, path := range paths {
+		var cfg *pb.TableConfig
+		var err error
+		if preset, ok := strings.CutPrefix(path, "preset:"); ok {
+			cfg, err = gamedefio.LoadPreset(preset)
+		} else {
+			cfg, err = gamedefio.Load(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("game mode %s: %w", name, err)
+		}
+		modes[name] = cfg
+	}
+	return modes, nil
+}
+
+// loadRegistryGameModes adds each TableConfig in the registry to modes,
+// unless a mode of its name is already there.
+func loadRegistryGameModes(ctx context.Context, configs *registry.Registry, modes map[string]*pb.TableConfig) error {
+	entries, err := configs.List(ctx, registry.KindTableConfig)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, ok := modes[e.Name]; ok {
+			continue
+		}
+		cfg, _, err := configs.TableConfig(ctx, e.Name)
+		if err != nil {
+			return fmt.Errorf("game mode %s: %w", e.Name, err)
+		}
+		modes[e.Name] = cfg
+	}
+	return nil
+}
+
+// loadPresetGameModes adds each built-in preset to modes, unless a mode of
+// its name is already there.
+func loadPresetGameModes(modes map[string]*pb.TableConfig) error {
+	for _, p := range gamedefio.Presets {
+		if _, ok := modes[p.Name]; ok {
+			continue
+		}
+		cfg, err := p.Config()
+		if err != nil {
+			return err
+		}
+		modes[p.Name] = cfg
+	}
 	return nil
 }
+
+// loadMatchRules reads the MatchRules file named by the flags, or builds
+// rules from the individual flags if there is none.
+func loadMatchRules(flags *ServeArgs) (*pb.MatchRules, error) {
+	if flags.MatchRules != "" {
+		return rules.Load(flags.MatchRules)
+	}
+
+	policy := pb.RegionPolicy_builder{
+		MaxRtt:        durationpb.New(flags.MaxRTT),
+		FallbackAfter: durationpb.New(flags.RegionFallback.After),
+	}
+	for pair, value := range flags.RegionFallback.Neighbors {
+		a, b, ok := strings.Cut(pair, "/")
+		if !ok {
+			return nil, fmt.Errorf("region neighbor %q: want region/region", pair)
+		}
+		penalty, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("region neighbor %s: %w", pair, err)
+		}
+		policy.Neighbors = append(policy.Neighbors, pb.RegionPolicy_Neighbors_builder{
+			A:       proto.String(a),
+			B:       proto.String(b),
+			Penalty: durationpb.New(penalty),
+		}.Build())
+	}
+	r := pb.MatchRules_builder{
+		TeamSize: proto.Int32(1),
+		Teams:    proto.Int32(int32(flags.TableSize)),
+		RatingWindow: pb.RatingWindow_builder{
+			Initial: proto.Float64(flags.RatingWindow.Initial),
+			Rate:    proto.Float64(flags.RatingWindow.Growth),
+			Max:     proto.Float64(flags.RatingWindow.Max),
+		}.Build(),
+		MaxWait:      durationpb.New(flags.TicketTTL),
+		RegionPolicy: policy.Build(),
+	}.Build()
+	return r, rules.Validate(r)
+}
+
+func mailSender(flags MailArgs) (mail.Sender, error) {
+	switch flags.Sender {
+	case "log":
+		return mail.NewLog(os.Stdout), nil
+	case "smtp":
+		if flags.SMTPAddr == "" {
+			return nil, errors.New("mail.smtp-addr is required with the smtp sender")
+		}
+		return mail.NewSMTP(flags.SMTPAddr, flags.SMTPUser, flags.SMTPPass, flags.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail sender %q", flags.Sender)
+	}
+}
+
+// identityProviders returns the identity providers that players may sign in
+// with, as configured by flags.
+func identityProviders(flags *ServeArgs) ([]identity.Provider, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	var providers []identity.Provider
+	if flags.Google.ClientIDs != "" {
+		providers = append(providers, identity.Google(strings.Split(flags.Google.ClientIDs, ","), client))
+	}
+	if flags.Apple.ClientIDs != "" {
+		providers = append(providers, identity.Apple(strings.Split(flags.Apple.ClientIDs, ","), client))
+	}
+	if flags.Steam.AppID != "" {
+		if flags.Steam.WebAPIKey == "" {
+			return nil, errors.New("steam.web-api-key is required with steam.app-id")
+		}
+		providers = append(providers, identity.NewSteam(flags.Steam.AppID, flags.Steam.WebAPIKey, client))
+	}
+	return providers, nil
+}
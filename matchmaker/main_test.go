@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"github.com/spf13/cobra"
+
+	"github.com/jfmatt/snapfold/lib/config"
+)
+
+// flagr leaves time.Duration flags that aren't given at 0, which once
+// panicked the matching loop and expired tokens as they were issued.
+func TestServerCommand_DefaultDurations(t *testing.T) {
+	var got *ServeArgs
+	root := &cobra.Command{Use: "matchmaker"}
+	root.AddCommand(serverCommand(func(flags *ServeArgs, _ *cobra.Command, _ []string) error {
+		got = flags
+		return nil
+	}))
+	config.Register(root, "MATCHMAKER_TEST")
+	root.SetArgs([]string{"serve", "--ticket-ttl=1m"})
+	AssertThat(t, root.Execute(), Nil())
+
+	ExpectEq(t, got.MatchInterval, time.Second)
+	ExpectEq(t, got.TicketTTL, time.Minute)
+	ExpectEq(t, got.HeartbeatTimeout, 15*time.Second)
+	ExpectEq(t, got.ShutdownTimeout, 10*time.Second)
+	ExpectEq(t, got.DB.HealthInterval, 10*time.Second)
+	ExpectEq(t, got.Session.AccessTTL, 15*time.Minute)
+	ExpectEq(t, got.Session.RefreshTTL, 720*time.Hour)
+	ExpectEq(t, got.Session.SyncInterval, 5*time.Second)
+	ExpectEq(t, got.MaxRTT, time.Duration(0))
+}
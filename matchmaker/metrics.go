@@ -0,0 +1,76 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/jfmatt/snapfold/lib/health"
+	"github.com/jfmatt/snapfold/lib/metrics"
+	"github.com/jfmatt/snapfold/matchmaker/store"
+)
+
+// metricsHandler serves reg's metrics in the Prometheus text format at
+// /metrics, and checker's /healthz and /readyz.
+func metricsHandler(reg *metrics.Registry, checker *health.Checker) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", reg)
+	checker.Register(mux)
+	return mux
+}
+
+// dbMetric is one metric of the connection pool.
+type dbMetric struct {
+	name, kind, help string
+	value            func(sql.DBStats) float64
+}
+
+var dbMetrics = []dbMetric{
+	{"matchmaker_db_open_connections", metrics.KindGauge, "Connections to the database, in use or idle.",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+	{"matchmaker_db_in_use_connections", metrics.KindGauge, "Connections running a query or transaction.",
+		func(s sql.DBStats) float64 { return float64(s.InUse) }},
+	{"matchmaker_db_idle_connections", metrics.KindGauge, "Connections kept open for reuse.",
+		func(s sql.DBStats) float64 { return float64(s.Idle) }},
+	{"matchmaker_db_max_open_connections", metrics.KindGauge, "Most connections the pool opens at once; 0 for no limit.",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+	{"matchmaker_db_waits_total", metrics.KindCounter, "Times a query waited for a connection because the pool was full.",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+	{"matchmaker_db_wait_seconds_total", metrics.KindCounter, "Time queries have spent waiting for a connection.",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+	{"matchmaker_db_max_idle_closed_total", metrics.KindCounter, "Connections closed because too many were idle.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+	{"matchmaker_db_max_idle_time_closed_total", metrics.KindCounter, "Connections closed for being idle too long.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+	{"matchmaker_db_max_lifetime_closed_total", metrics.KindCounter, "Connections closed for reaching their maximum age.",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+}
+
+// registerDBMetrics registers the database's pool and health metrics in
+// reg, and times its statements.
+func registerDBMetrics(reg *metrics.Registry, db *store.DB, health *store.Health) {
+	db.Instrument(reg.Histogram("matchmaker_db_statement_seconds", "Time statements took to run, outside transactions.", metrics.DefaultBuckets, "op"))
+	reg.Collect(func(w io.Writer) {
+		writeDBMetrics(w, db.Stats(), health.Stats())
+		if lag, ok := db.ReplicaLag(); ok {
+			metrics.WriteHeader(w, "matchmaker_db_replica_lag_seconds", metrics.KindGauge, "How far the read replica was behind the primary when last measured.")
+			fmt.Fprintf(w, "matchmaker_db_replica_lag_seconds %g\n", lag.Seconds())
+		}
+	})
+}
+
+func writeDBMetrics(w io.Writer, pool sql.DBStats, health store.HealthStats) {
+	up := 1
+	if health.Err != nil {
+		up = 0
+	}
+	metrics.WriteHeader(w, "matchmaker_db_up", metrics.KindGauge, "Whether the last health check reached the database.")
+	fmt.Fprintf(w, "matchmaker_db_up %d\n", up)
+	metrics.WriteHeader(w, "matchmaker_db_health_check_failures_total", metrics.KindCounter, "Health checks that failed to reach the database.")
+	fmt.Fprintf(w, "matchmaker_db_health_check_failures_total %d\n", health.Failures)
+	for _, m := range dbMetrics {
+		metrics.WriteHeader(w, m.name, m.kind, m.help)
+		fmt.Fprintf(w, "%s %g\n", m.name, m.value(pool))
+	}
+}
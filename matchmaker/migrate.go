@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jfmatt/flagr"
+	"github.com/spf13/cobra"
+
+	"github.com/jfmatt/snapfold/matchmaker/store"
+)
+
+func MigrationCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "migrate",
+		Short: "Update database schemas",
+		Long: `Updates database schemas. Postgres databases are locked while up or down
+migrates them, so replicas deployed together may each run migrate up: the
+first applies the pending migrations while the rest wait, then find none left.`,
+	}
+
+	upCmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply pending migrations",
+	}
+	upCmd.RunE = flagr.Run(upCmd, MigrateUp)
+	c.AddCommand(upCmd)
+
+	downCmd := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the most recently applied migrations",
+	}
+	downCmd.RunE = flagr.Run(downCmd, MigrateDown)
+	c.AddCommand(downCmd)
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "List migrations and when each was applied",
+	}
+	statusCmd.RunE = flagr.Run(statusCmd, MigrateStatus)
+	c.AddCommand(statusCmd)
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check the schema is the one its applied migrations make",
+		Long: `Compares the database's tables, columns, constraints and indexes with the
+ones its applied migrations make, replaying them in a scratch schema that is
+rolled back, and lists the differences, to catch databases changed by hand.
+It fails if there are any. On Postgres, the user must be able to create
+schemas.`,
+	}
+	verifyCmd.RunE = flagr.Run(verifyCmd, MigrateVerify)
+	c.AddCommand(verifyCmd)
+
+	return c
+}
+
+type MigrateArgs struct {
+	Dsn string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+}
+
+type MigrateUpArgs struct {
+	Dsn       string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	ToVersion int    `flag:"to-version,help=Apply pending migrations up to and including this version rather than all of them"`
+	DryRun    bool   `flag:"dry-run,help=Print the migrations that would be applied and their SQL without applying them"`
+
+	Backup BackupArgs `flag:"backup"`
+}
+
+func MigrateUp(flags *MigrateUpArgs, cmd *cobra.Command, args []string) error {
+	db, err := store.Open(cmd.Context(), flags.Dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	plan, err := store.PlanUp(cmd.Context(), db, flags.ToVersion)
+	if err != nil {
+		return err
+	}
+	up := func(m store.Migration) string { return m.Up }
+	if flags.DryRun {
+		printPlan(cmd.OutOrStdout(), plan, "up", up)
+		return nil
+	}
+	if err := flags.Backup.run(cmd, flags.Dsn, "up", plan, up); err != nil {
+		return err
+	}
+	applied, err := store.MigrateUp(cmd.Context(), db, flags.ToVersion)
+	for _, m := range applied {
+		fmt.Fprintf(cmd.OutOrStdout(), "applied %s\n", m)
+	}
+	if err == nil && len(applied) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "already up to date")
+	}
+	return err
+}
+
+type MigrateDownArgs struct {
+	Dsn       string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	Steps     int    `flag:"steps,default=1,help=Number of migrations to revert"`
+	ToVersion int    `flag:"to-version,help=Revert every migration after this version instead of a number of steps; 0 reverts them all"`
+	DryRun    bool   `flag:"dry-run,help=Print the migrations that would be reverted and their SQL without reverting them"`
+
+	Backup BackupArgs `flag:"backup"`
+}
+
+func MigrateDown(flags *MigrateDownArgs, cmd *cobra.Command, args []string) error {
+	to := -1
+	if cmd.Flags().Changed("to-version") {
+		if cmd.Flags().Changed("steps") {
+			return fmt.Errorf("steps and to-version can't both be set")
+		}
+		to = flags.ToVersion
+	} else if flags.Steps < 1 {
+		return fmt.Errorf("steps must be at least 1")
+	}
+	db, err := store.Open(cmd.Context(), flags.Dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	plan, err := store.PlanDown(cmd.Context(), db, flags.Steps, to)
+	if err != nil {
+		return err
+	}
+	down := func(m store.Migration) string { return m.Down }
+	if flags.DryRun {
+		printPlan(cmd.OutOrStdout(), plan, "down", down)
+		return nil
+	}
+	if err := flags.Backup.run(cmd, flags.Dsn, "down", plan, down); err != nil {
+		return err
+	}
+	reverted, err := store.MigrateDown(cmd.Context(), db, flags.Steps, to)
+	for _, m := range reverted {
+		fmt.Fprintf(cmd.OutOrStdout(), "reverted %s\n", m)
+	}
+	if err == nil && to < 0 && len(reverted) < flags.Steps {
+		fmt.Fprintf(cmd.OutOrStdout(), "reverted %d migrations; none are left\n", len(reverted))
+	}
+	return err
+}
+
+// printPlan writes the migrations a dry run would run, each headed by a
+// comment so that the whole can be read as a SQL script.
+func printPlan(out io.Writer, plan []store.Migration, direction string, script func(store.Migration) string) {
+	if len(plan) == 0 {
+		fmt.Fprintln(out, "-- nothing to do")
+		return
+	}
+	for i, m := range plan {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		sql := script(m)
+		note := direction
+		if store.Destructive(sql) {
+			note += ", destructive"
+		}
+		fmt.Fprintf(out, "-- %s (%s)\n%s", m, note, sql)
+		if !strings.HasSuffix(sql, "\n") {
+			fmt.Fprintln(out)
+		}
+	}
+}
+
+func MigrateStatus(flags *MigrateArgs, cmd *cobra.Command, args []string) error {
+	db, err := store.Open(cmd.Context(), flags.Dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	states, err := store.MigrationStatus(cmd.Context(), db)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	for _, s := range states {
+		state := "pending"
+		switch {
+		case s.Unknown:
+			state = "applied " + s.AppliedAt.Format(time.DateTime) + " by a newer build"
+		case !s.AppliedAt.IsZero():
+			state = "applied " + s.AppliedAt.Format(time.DateTime)
+		}
+		fmt.Fprintf(out, "%-40s %s\n", s.Migration, state)
+	}
+	return nil
+}
+
+func MigrateVerify(flags *MigrateArgs, cmd *cobra.Command, args []string) error {
+	db, err := store.Open(cmd.Context(), flags.Dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	drift, err := store.VerifySchema(cmd.Context(), db)
+	if err != nil {
+		return err
+	}
+	for _, d := range drift {
+		fmt.Fprintln(cmd.OutOrStdout(), d)
+	}
+	if len(drift) > 0 {
+		return fmt.Errorf("found %d differences from the schema its migrations make", len(drift))
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "the schema matches its migrations")
+	return nil
+}
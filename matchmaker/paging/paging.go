@@ -0,0 +1,204 @@
+// Package paging pages through the matchmaker's lists, so that every list
+// endpoint takes the same page_size, page_token and sort parameters and
+// behaves the same way.
+//
+// Page tokens are opaque to clients. Each holds the sort key of the last
+// item on the page before, so pages stay consistent as items are added, and
+// the sort and filters it was issued for, so that it can't be used to page
+// through a different list.
+package paging
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Page sizes.
+const (
+	DefaultSize = 20
+	MaxSize     = 100
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid page token")
+	ErrInvalidSize  = errors.New("page_size must be a non-negative integer")
+	ErrInvalidSort  = errors.New("invalid sort")
+)
+
+// Sort orders a list by one of its fields. Items that tie are ordered by
+// ID, in the same direction.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// String returns the sort as clients write it: the field's name, prefixed
+// with - if descending.
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// Spec describes how a list may be paged.
+type Spec struct {
+	// The order of the list when a request doesn't ask for one.
+	Default Sort
+
+	// The fields the list may be sorted by, which include Default's.
+	Fields []string
+
+	// The query parameters that filter the list.
+	Filters []string
+}
+
+// Request is a request for a page of a list.
+type Request struct {
+	// The number of items wanted, between 1 and MaxSize.
+	Size int
+	Sort Sort
+
+	// The values of the spec's filters that were given.
+	Filter map[string]string
+
+	// The sort key of the last item on the page before. Nil for the first
+	// page.
+	After []string
+}
+
+// token is what a page token encodes.
+type token struct {
+	Sort   string            `json:"s"`
+	Filter map[string]string `json:"f,omitempty"`
+	After  []string          `json:"a"`
+}
+
+// Parse checks a request for a page of a list. A size of 0 means
+// DefaultSize, and larger sizes than MaxSize are treated as MaxSize. An
+// empty sort means the spec's default, and an empty pageToken the first
+// page. Filters that are empty are left out.
+func (sp Spec) Parse(size int, pageToken, sort string, filter map[string]string) (Request, error) {
+	switch {
+	case size < 0:
+		return Request{}, ErrInvalidSize
+	case size == 0:
+		size = DefaultSize
+	}
+	r := Request{Size: min(size, MaxSize), Sort: sp.Default}
+	if sort != "" {
+		field, desc := strings.CutPrefix(sort, "-")
+		if !slices.Contains(sp.Fields, field) {
+			return Request{}, fmt.Errorf("%w %q: must be one of %s", ErrInvalidSort, sort, strings.Join(sp.Fields, ", "))
+		}
+		r.Sort = Sort{Field: field, Desc: desc}
+	}
+	for _, f := range sp.Filters {
+		if v := filter[f]; v != "" {
+			if r.Filter == nil {
+				r.Filter = map[string]string{}
+			}
+			r.Filter[f] = v
+		}
+	}
+	if pageToken == "" {
+		return r, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(pageToken)
+	if err != nil {
+		return Request{}, ErrInvalidToken
+	}
+	var t token
+	if err := json.Unmarshal(b, &t); err != nil || len(t.After) == 0 {
+		return Request{}, ErrInvalidToken
+	}
+	if t.Sort != r.Sort.String() || !maps.Equal(t.Filter, r.Filter) {
+		return Request{}, fmt.Errorf("%w: it continues a list with a different sort or filters", ErrInvalidToken)
+	}
+	r.After = t.After
+	return r, nil
+}
+
+// FromQuery parses a request for a page of a list from the page_size,
+// page_token and sort query parameters, and the spec's filters.
+func (sp Spec) FromQuery(q url.Values) (Request, error) {
+	var size int
+	if v := q.Get("page_size"); v != "" {
+		var err error
+		if size, err = strconv.Atoi(v); err != nil {
+			return Request{}, ErrInvalidSize
+		}
+	}
+	filter := map[string]string{}
+	for _, f := range sp.Filters {
+		filter[f] = q.Get(f)
+	}
+	return sp.Parse(size, q.Get("page_token"), q.Get("sort"), filter)
+}
+
+// Limit is how many items to fetch for the request: one more than the page
+// holds, to learn whether there is a next page.
+func (r Request) Limit() int {
+	return r.Size + 1
+}
+
+// Page is one page of a list.
+type Page[T any] struct {
+	Items []T
+
+	// Token for the next page. Empty on the last page.
+	NextToken string
+}
+
+// Finish returns the page of up to r.Limit() items fetched for r. key
+// returns an item's sort key, which is what fetching the next page starts
+// after.
+func Finish[T any](r Request, items []T, key func(T) []string) Page[T] {
+	if len(items) <= r.Size {
+		return Page[T]{Items: items}
+	}
+	items = items[:r.Size]
+	b, _ := json.Marshal(token{Sort: r.Sort.String(), Filter: r.Filter, After: key(items[r.Size-1])})
+	return Page[T]{Items: items, NextToken: base64.RawURLEncoding.EncodeToString(b)}
+}
+
+// Slice returns the page r asks for of a list that is held in memory.
+// items must be sorted in ascending order of r.Sort.Field. compare orders
+// an item against a sort key in the same way.
+func Slice[T any](r Request, items []T, key func(T) []string, compare func(T, []string) int) Page[T] {
+	if r.Sort.Desc {
+		items = slices.Clone(items)
+		slices.Reverse(items)
+	}
+	start := 0
+	if r.After != nil {
+		start = len(items)
+		for i, it := range items {
+			c := compare(it, r.After)
+			if r.Sort.Desc {
+				c = -c
+			}
+			if c > 0 {
+				start = i
+				break
+			}
+		}
+	}
+	return Finish(r, items[start:min(len(items), start+r.Limit())], key)
+}
+
+// Int parses a number from a sort key, as an item's compare function
+// needs to. Keys that don't hold one, which page tokens can't produce,
+// sort first.
+func Int(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
@@ -0,0 +1,79 @@
+package paging
+
+import (
+	"cmp"
+	"net/url"
+	"strconv"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+var spec = Spec{
+	Default: Sort{Field: "rank"},
+	Fields:  []string{"rank"},
+	Filters: []string{"region"},
+}
+
+func TestParse(t *testing.T) {
+	r, err := spec.Parse(0, "", "", nil)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.Size, DefaultSize)
+	ExpectEq(t, r.Sort, Sort{Field: "rank"})
+	ExpectThat(t, r.After, Nil())
+
+	r, err = spec.Parse(1000, "", "-rank", map[string]string{"region": "eu", "other": "x"})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.Size, MaxSize)
+	ExpectEq(t, r.Sort, Sort{Field: "rank", Desc: true})
+	ExpectEq(t, len(r.Filter), 1)
+	ExpectEq(t, r.Filter["region"], "eu")
+
+	_, err = spec.Parse(-1, "", "", nil)
+	ExpectThat(t, err, ErrorIs(ErrInvalidSize))
+	_, err = spec.Parse(0, "", "rating", nil)
+	ExpectThat(t, err, ErrorIs(ErrInvalidSort))
+	_, err = spec.Parse(0, "bogus", "", nil)
+	ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+
+	_, err = spec.FromQuery(url.Values{"page_size": {"ten"}})
+	ExpectThat(t, err, ErrorIs(ErrInvalidSize))
+	r, err = spec.FromQuery(url.Values{"page_size": {"10"}, "sort": {"-rank"}, "region": {"us"}})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.Size, 10)
+	ExpectEq(t, r.Sort.Desc, true)
+	ExpectEq(t, r.Filter["region"], "us")
+}
+
+func TestSlice(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	key := func(n int) []string { return []string{strconv.Itoa(n)} }
+	compare := func(n int, after []string) int { return cmp.Compare(int64(n), Int(after[0])) }
+	list := func(sort string, filter map[string]string) []int {
+		t.Helper()
+		var got []int
+		token := ""
+		for {
+			r, err := spec.Parse(2, token, sort, filter)
+			AssertThat(t, err, Nil())
+			p := Slice(r, items, key, compare)
+			got = append(got, p.Items...)
+			if p.NextToken == "" {
+				return got
+			}
+			token = p.NextToken
+		}
+	}
+	ExpectThat(t, list("", nil), ElementsAre(1, 2, 3, 4, 5))
+	ExpectThat(t, list("-rank", nil), ElementsAre(5, 4, 3, 2, 1))
+	ExpectThat(t, list("", map[string]string{"region": "eu"}), ElementsAre(1, 2, 3, 4, 5))
+
+	// Tokens only continue the list they came from.
+	r, err := spec.Parse(2, "", "", nil)
+	AssertThat(t, err, Nil())
+	next := Slice(r, items, key, compare).NextToken
+	_, err = spec.Parse(2, next, "-rank", nil)
+	ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+	_, err = spec.Parse(2, next, "", map[string]string{"region": "eu"})
+	ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+}
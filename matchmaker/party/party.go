@@ -0,0 +1,173 @@
+// Package party manages groups of players who queue together.
+package party
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+// MaxSize is the largest party allowed in any game mode. Individual game
+// modes may set a lower limit.
+const MaxSize = 4
+
+var (
+	ErrNotFound   = errors.New("party not found")
+	ErrInParty    = errors.New("player is already in a party")
+	ErrNotInParty = errors.New("player is not in the party")
+	ErrNotLeader  = errors.New("only the party leader may do that")
+	ErrNotInvited = errors.New("player has not been invited to the party")
+	ErrPartyFull  = errors.New("party is full")
+	ErrInviteSelf = errors.New("players cannot invite themselves")
+)
+
+// Party is a snapshot of a group of players.
+type Party struct {
+	ID     string
+	Leader string
+
+	// Current members, in the order they joined. The leader is always
+	// included.
+	Members []string
+
+	// Players who have been invited but have not yet joined.
+	Invited []string
+}
+
+// Manager tracks all parties. It is safe for concurrent use.
+type Manager struct {
+	mu       sync.Mutex
+	parties  map[string]*Party
+	byPlayer map[string]string // player ID -> party ID
+}
+
+// NewManager returns a Manager with no parties.
+func NewManager() *Manager {
+	return &Manager{
+		parties:  map[string]*Party{},
+		byPlayer: map[string]string{},
+	}
+}
+
+// Create starts a new party led by the player.
+func (m *Manager) Create(leader string) (Party, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.byPlayer[leader]; ok {
+		return Party{}, ErrInParty
+	}
+	p := &Party{
+		ID:      queue.NewID(),
+		Leader:  leader,
+		Members: []string{leader},
+	}
+	m.parties[p.ID] = p
+	m.byPlayer[leader] = p.ID
+	return clone(p), nil
+}
+
+// Get returns the party with the given ID.
+func (m *Manager) Get(id string) (Party, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.parties[id]
+	if !ok {
+		return Party{}, ErrNotFound
+	}
+	return clone(p), nil
+}
+
+// ForPlayer returns the party the player belongs to, if any.
+func (m *Manager) ForPlayer(playerID string) (Party, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, ok := m.byPlayer[playerID]
+	if !ok {
+		return Party{}, false
+	}
+	return clone(m.parties[id]), true
+}
+
+// Invite allows invitee to join the party. Only the leader may invite.
+func (m *Manager) Invite(id, leader, invitee string) (Party, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.parties[id]
+	if !ok {
+		return Party{}, ErrNotFound
+	}
+	if p.Leader != leader {
+		return Party{}, ErrNotLeader
+	}
+	if invitee == leader {
+		return Party{}, ErrInviteSelf
+	}
+	if len(p.Members)+len(p.Invited) >= MaxSize {
+		return Party{}, ErrPartyFull
+	}
+	if !slices.Contains(p.Invited, invitee) && !slices.Contains(p.Members, invitee) {
+		p.Invited = append(p.Invited, invitee)
+	}
+	return clone(p), nil
+}
+
+// Join adds an invited player to the party.
+func (m *Manager) Join(id, playerID string) (Party, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.parties[id]
+	if !ok {
+		return Party{}, ErrNotFound
+	}
+	if _, ok := m.byPlayer[playerID]; ok {
+		return Party{}, ErrInParty
+	}
+	idx := slices.Index(p.Invited, playerID)
+	if idx < 0 {
+		return Party{}, ErrNotInvited
+	}
+	p.Invited = slices.Delete(p.Invited, idx, idx+1)
+	p.Members = append(p.Members, playerID)
+	m.byPlayer[playerID] = p.ID
+	return clone(p), nil
+}
+
+// Leave removes the player from their party. If the leader leaves, the
+// longest-standing remaining member takes over, and a party with no members
+// left is disbanded. It returns the party as it was before the player left.
+func (m *Manager) Leave(playerID string) (Party, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, ok := m.byPlayer[playerID]
+	if !ok {
+		return Party{}, ErrNotInParty
+	}
+	p := m.parties[id]
+	before := clone(p)
+
+	delete(m.byPlayer, playerID)
+	p.Members = slices.DeleteFunc(p.Members, func(s string) bool { return s == playerID })
+	if len(p.Members) == 0 {
+		delete(m.parties, id)
+		return before, nil
+	}
+	if p.Leader == playerID {
+		p.Leader = p.Members[0]
+	}
+	return before, nil
+}
+
+func clone(p *Party) Party {
+	return Party{
+		ID:      p.ID,
+		Leader:  p.Leader,
+		Members: slices.Clone(p.Members),
+		Invited: slices.Clone(p.Invited),
+	}
+}
@@ -0,0 +1,83 @@
+package party
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestInviteAndJoin(t *testing.T) {
+	m := NewManager()
+	p, err := m.Create("alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, p.Leader, "alice")
+
+	_, err = m.Join(p.ID, "bob")
+	ExpectThat(t, err, ErrorIs(ErrNotInvited))
+
+	_, err = m.Invite(p.ID, "bob", "carol")
+	ExpectThat(t, err, ErrorIs(ErrNotLeader))
+
+	p, err = m.Invite(p.ID, "alice", "bob")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, p.Invited, ElementsAre("bob"))
+
+	p, err = m.Join(p.ID, "bob")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, p.Members, ElementsAre("alice", "bob"))
+	ExpectThat(t, p.Invited, Empty())
+
+	found, ok := m.ForPlayer("bob")
+	ExpectEq(t, ok, true)
+	ExpectEq(t, found.ID, p.ID)
+}
+
+func TestCreate_AlreadyInParty(t *testing.T) {
+	m := NewManager()
+	_, err := m.Create("alice")
+	AssertThat(t, err, Nil())
+	_, err = m.Create("alice")
+	ExpectThat(t, err, ErrorIs(ErrInParty))
+}
+
+func TestInvite_Full(t *testing.T) {
+	m := NewManager()
+	p, err := m.Create("a")
+	AssertThat(t, err, Nil())
+	for _, id := range []string{"b", "c", "d"} {
+		_, err := m.Invite(p.ID, "a", id)
+		AssertThat(t, err, Nil())
+	}
+	_, err = m.Invite(p.ID, "a", "e")
+	ExpectThat(t, err, ErrorIs(ErrPartyFull))
+
+	_, err = m.Invite(p.ID, "a", "a")
+	ExpectThat(t, err, ErrorIs(ErrInviteSelf))
+}
+
+func TestLeave(t *testing.T) {
+	m := NewManager()
+	p, err := m.Create("alice")
+	AssertThat(t, err, Nil())
+	_, err = m.Invite(p.ID, "alice", "bob")
+	AssertThat(t, err, Nil())
+	_, err = m.Join(p.ID, "bob")
+	AssertThat(t, err, Nil())
+
+	// Leadership passes to the next member.
+	_, err = m.Leave("alice")
+	AssertThat(t, err, Nil())
+	p, err = m.Get(p.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, p.Leader, "bob")
+	ExpectThat(t, p.Members, ElementsAre("bob"))
+
+	// The last member leaving disbands the party.
+	_, err = m.Leave("bob")
+	AssertThat(t, err, Nil())
+	_, err = m.Get(p.ID)
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+
+	_, err = m.Leave("bob")
+	ExpectThat(t, err, ErrorIs(ErrNotInParty))
+}
@@ -0,0 +1,135 @@
+// Package penalty holds back players who keep backing out of matches, by
+// making them wait longer after each offense before they may queue again.
+package penalty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPenalized is returned for players who may not queue yet.
+var ErrPenalized = errors.New("player is serving a queue penalty")
+
+// Policy sets how a game mode penalizes dodging.
+type Policy struct {
+	// How long a player must wait before queueing again after each offense.
+	// The first offense costs Delays[0], the second Delays[1], and so on;
+	// offenses past the end cost the last delay.
+	Delays []time.Duration
+
+	// How long an offense counts against a player. A player who goes this
+	// long without an offense starts over from the first delay. Zero means
+	// offenses are never forgotten.
+	Decay time.Duration
+
+	// Leaving a table within this long of being seated is an offense. Zero
+	// means leaving a table is never penalized.
+	AbandonWithin time.Duration
+}
+
+// delay returns the wait owed for a player's nth offense, counting from 1.
+func (p Policy) delay(n int) time.Duration {
+	if len(p.Delays) == 0 {
+		return 0
+	}
+	return p.Delays[min(n, len(p.Delays))-1]
+}
+
+// Record is a player's offenses in one game mode.
+type Record struct {
+	PlayerID string
+	GameMode string
+
+	// Offenses that still count against the player, and when the latest
+	// happened.
+	Offenses    int
+	LastOffense time.Time
+
+	// When the player may queue again.
+	Until time.Time
+}
+
+// Manager records offenses and checks whether players may queue. It is safe
+// for concurrent use.
+type Manager struct {
+	store    Store
+	policies map[string]Policy
+	now      func() time.Time
+}
+
+// NewManager returns a Manager that keeps records in store and penalizes
+// dodging in the game modes in policies. Other game modes are never
+// penalized.
+func NewManager(store Store, policies map[string]Policy) *Manager {
+	return &Manager{store: store, policies: policies, now: time.Now}
+}
+
+// Check returns ErrPenalized if any of the players may not queue for the
+// game mode yet.
+func (m *Manager) Check(ctx context.Context, gameMode string, playerIDs ...string) error {
+	if _, ok := m.policies[gameMode]; !ok {
+		return nil
+	}
+	now := m.now()
+	for _, id := range playerIDs {
+		r, err := m.store.GetRecord(ctx, id, gameMode)
+		if err != nil {
+			return err
+		}
+		if now.Before(r.Until) {
+			return fmt.Errorf("%w: %s may queue for %s again in %s", ErrPenalized, id, gameMode, r.Until.Sub(now).Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// Dodged records an offense for each player who backed out of a match
+// during its ready check.
+func (m *Manager) Dodged(ctx context.Context, gameMode string, playerIDs ...string) error {
+	var errs []error
+	for _, id := range playerIDs {
+		_, err := m.offend(ctx, gameMode, id)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Left records an offense for a player who left a table seated at the given
+// time, if they left soon enough after sitting down for it to count as
+// abandoning it. It reports whether it did.
+func (m *Manager) Left(ctx context.Context, gameMode, playerID string, seatedAt time.Time) (bool, error) {
+	p, ok := m.policies[gameMode]
+	if !ok || p.AbandonWithin <= 0 || m.now().Sub(seatedAt) > p.AbandonWithin {
+		return false, nil
+	}
+	return m.offend(ctx, gameMode, playerID)
+}
+
+// Get returns the player's record in a game mode.
+func (m *Manager) Get(ctx context.Context, playerID, gameMode string) (Record, error) {
+	return m.store.GetRecord(ctx, playerID, gameMode)
+}
+
+// offend counts an offense against the player and extends their wait.
+func (m *Manager) offend(ctx context.Context, gameMode, playerID string) (bool, error) {
+	p, ok := m.policies[gameMode]
+	if !ok {
+		return false, nil
+	}
+	r, err := m.store.GetRecord(ctx, playerID, gameMode)
+	if err != nil {
+		return false, err
+	}
+	now := m.now()
+	if p.Decay > 0 && now.Sub(r.LastOffense) > p.Decay {
+		r.Offenses = 0
+	}
+	r.Offenses++
+	r.LastOffense = now
+	if until := now.Add(p.delay(r.Offenses)); until.After(r.Until) {
+		r.Until = until
+	}
+	return true, m.store.SaveRecord(ctx, r)
+}
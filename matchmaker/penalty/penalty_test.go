@@ -0,0 +1,67 @@
+package penalty
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+var ctx = context.Background()
+
+func TestDodged(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := NewManager(NewMemStore(), map[string]Policy{
+		"holdem": {Delays: []time.Duration{time.Minute, 5 * time.Minute}, Decay: time.Hour},
+	})
+	m.now = func() time.Time { return now }
+
+	AssertThat(t, m.Dodged(ctx, "holdem", "alice"), Nil())
+	ExpectThat(t, m.Check(ctx, "holdem", "bob", "alice"), ErrorIs(ErrPenalized))
+	ExpectThat(t, m.Check(ctx, "holdem", "bob"), Nil())
+
+	// Penalties only apply in their game mode, and run out.
+	AssertThat(t, m.Dodged(ctx, "omaha", "alice"), Nil())
+	ExpectThat(t, m.Check(ctx, "omaha", "alice"), Nil())
+	now = now.Add(time.Minute + time.Second)
+	ExpectThat(t, m.Check(ctx, "holdem", "alice"), Nil())
+
+	// Repeat offenses cost more, up to the last delay.
+	AssertThat(t, m.Dodged(ctx, "holdem", "alice"), Nil())
+	AssertThat(t, m.Dodged(ctx, "holdem", "alice"), Nil())
+	r, err := m.Get(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.Offenses, 3)
+	ExpectEq(t, r.Until, now.Add(5*time.Minute))
+
+	// Old offenses are forgotten.
+	now = now.Add(2 * time.Hour)
+	AssertThat(t, m.Dodged(ctx, "holdem", "alice"), Nil())
+	r, err = m.Get(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.Offenses, 1)
+	ExpectEq(t, r.Until, now.Add(time.Minute))
+}
+
+func TestLeft(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := NewManager(NewMemStore(), map[string]Policy{
+		"holdem": {Delays: []time.Duration{time.Minute}, AbandonWithin: 2 * time.Minute},
+		"omaha":  {Delays: []time.Duration{time.Minute}},
+	})
+	m.now = func() time.Time { return now }
+
+	ok, err := m.Left(ctx, "holdem", "alice", now.Add(-3*time.Minute))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, ok, false)
+	ok, err = m.Left(ctx, "omaha", "alice", now)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, ok, false)
+	ExpectThat(t, m.Check(ctx, "holdem", "alice"), Nil())
+
+	ok, err = m.Left(ctx, "holdem", "alice", now.Add(-time.Minute))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, ok, true)
+	ExpectThat(t, m.Check(ctx, "holdem", "alice"), ErrorIs(ErrPenalized))
+}
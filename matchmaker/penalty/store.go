@@ -0,0 +1,44 @@
+package penalty
+
+import (
+	"context"
+	"sync"
+)
+
+// Store persists players' offense records.
+type Store interface {
+	// GetRecord returns the player's record in a game mode. A player with no
+	// offenses gets an empty record.
+	GetRecord(ctx context.Context, playerID, gameMode string) (Record, error)
+
+	// SaveRecord replaces the player's record in its game mode.
+	SaveRecord(ctx context.Context, r Record) error
+}
+
+// MemStore is an in-memory Store, for development and tests.
+type MemStore struct {
+	mu      sync.Mutex
+	records map[[2]string]Record // player ID and game mode -> record
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{records: map[[2]string]Record{}}
+}
+
+func (s *MemStore) GetRecord(ctx context.Context, playerID, gameMode string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.records[[2]string{playerID, gameMode}]
+	if !ok {
+		return Record{PlayerID: playerID, GameMode: gameMode}, nil
+	}
+	return r, nil
+}
+
+func (s *MemStore) SaveRecord(ctx context.Context, r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[[2]string{r.PlayerID, r.GameMode}] = r
+	return nil
+}
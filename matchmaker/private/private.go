@@ -0,0 +1,189 @@
+// Package private manages private tables, which friends join by sharing a
+// short code instead of going through matchmaking.
+package private
+
+import (
+	"crypto/rand"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+const (
+	// Number of characters in a join code.
+	codeLength = 6
+
+	// Characters join codes are drawn from, leaving out ones that are easily
+	// confused with each other, like O and 0.
+	codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+	// MaxSeats is the largest private table allowed.
+	MaxSeats = 10
+)
+
+var (
+	ErrNotFound     = errors.New("private table not found")
+	ErrTableFull    = errors.New("private table is full")
+	ErrSeated       = errors.New("player is already at a private table")
+	ErrNotSeated    = errors.New("player is not at a private table")
+	ErrInvalidSeats = errors.New("private tables seat between 2 and 10 players")
+)
+
+// Table is a snapshot of a private table.
+type Table struct {
+	ID string
+
+	// Code that other players use to join.
+	Code string
+
+	// The player who created the table.
+	Host string
+
+	GameMode string
+	Config   *pb.TableConfig
+	Seats    int
+
+	// Players at the table, in the order they joined, starting with the
+	// host.
+	Players []string
+
+	CreatedAt time.Time
+}
+
+// Full reports whether every seat at the table is taken.
+func (t Table) Full() bool {
+	return len(t.Players) >= t.Seats
+}
+
+// Manager tracks all private tables. It is safe for concurrent use.
+type Manager struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	tables   map[string]*Table // code -> table
+	byPlayer map[string]string // player ID -> code
+}
+
+// NewManager returns a Manager with no tables.
+func NewManager() *Manager {
+	return &Manager{
+		now:      time.Now,
+		tables:   map[string]*Table{},
+		byPlayer: map[string]string{},
+	}
+}
+
+// Create opens a private table hosted by the player, who takes the first
+// seat.
+func (m *Manager) Create(host, gameMode string, cfg *pb.TableConfig, seats int) (Table, error) {
+	if seats < 2 || seats > MaxSeats {
+		return Table{}, ErrInvalidSeats
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.byPlayer[host]; ok {
+		return Table{}, ErrSeated
+	}
+	code := newCode()
+	for m.tables[code] != nil {
+		code = newCode()
+	}
+	t := &Table{
+		ID:        queue.NewID(),
+		Code:      code,
+		Host:      host,
+		GameMode:  gameMode,
+		Config:    cfg,
+		Seats:     seats,
+		Players:   []string{host},
+		CreatedAt: m.now(),
+	}
+	m.tables[code] = t
+	m.byPlayer[host] = code
+	return clone(t), nil
+}
+
+// Get returns the table with the given join code. Codes are not case
+// sensitive.
+func (m *Manager) Get(code string) (Table, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tables[strings.ToUpper(code)]
+	if !ok {
+		return Table{}, ErrNotFound
+	}
+	return clone(t), nil
+}
+
+// ForPlayer returns the table the player is seated at, if any.
+func (m *Manager) ForPlayer(playerID string) (Table, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code, ok := m.byPlayer[playerID]
+	if !ok {
+		return Table{}, false
+	}
+	return clone(m.tables[code]), true
+}
+
+// Join seats the player at the table with the given join code.
+func (m *Manager) Join(code, playerID string) (Table, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.tables[strings.ToUpper(code)]
+	if !ok {
+		return Table{}, ErrNotFound
+	}
+	if _, ok := m.byPlayer[playerID]; ok {
+		return Table{}, ErrSeated
+	}
+	if len(t.Players) >= t.Seats {
+		return Table{}, ErrTableFull
+	}
+	t.Players = append(t.Players, playerID)
+	m.byPlayer[playerID] = t.Code
+	return clone(t), nil
+}
+
+// Leave removes the player from their table. A table with no players left is
+// closed, and its code may be reused.
+func (m *Manager) Leave(playerID string) (Table, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code, ok := m.byPlayer[playerID]
+	if !ok {
+		return Table{}, ErrNotSeated
+	}
+	t := m.tables[code]
+	delete(m.byPlayer, playerID)
+	t.Players = slices.DeleteFunc(t.Players, func(s string) bool { return s == playerID })
+	if len(t.Players) == 0 {
+		delete(m.tables, code)
+	}
+	return clone(t), nil
+}
+
+func newCode() string {
+	var b [codeLength]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b[:])
+}
+
+func clone(t *Table) Table {
+	c := *t
+	c.Players = slices.Clone(t.Players)
+	return c
+}
@@ -0,0 +1,72 @@
+package private
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestCreateAndJoin(t *testing.T) {
+	m := NewManager()
+	tb, err := m.Create("alice", "holdem", nil, 3)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, len(tb.Code), codeLength)
+	ExpectThat(t, tb.Players, ElementsAre("alice"))
+
+	// Codes are not case sensitive.
+	tb, err = m.Join(strings.ToLower(tb.Code), "bob")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, tb.Players, ElementsAre("alice", "bob"))
+
+	_, err = m.Join(tb.Code, "bob")
+	ExpectThat(t, err, ErrorIs(ErrSeated))
+	_, err = m.Create("bob", "holdem", nil, 2)
+	ExpectThat(t, err, ErrorIs(ErrSeated))
+
+	tb, err = m.Join(tb.Code, "carol")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, tb.Full(), true)
+	_, err = m.Join(tb.Code, "dave")
+	ExpectThat(t, err, ErrorIs(ErrTableFull))
+
+	found, ok := m.ForPlayer("carol")
+	AssertEq(t, ok, true)
+	ExpectEq(t, found.ID, tb.ID)
+}
+
+func TestCreate_InvalidSeats(t *testing.T) {
+	m := NewManager()
+	_, err := m.Create("alice", "holdem", nil, 1)
+	ExpectThat(t, err, ErrorIs(ErrInvalidSeats))
+	_, err = m.Create("alice", "holdem", nil, MaxSeats+1)
+	ExpectThat(t, err, ErrorIs(ErrInvalidSeats))
+	_, err = m.Create("alice", "holdem", nil, MaxSeats)
+	ExpectThat(t, err, Nil())
+}
+
+func TestJoin_NotFound(t *testing.T) {
+	m := NewManager()
+	_, err := m.Join("ABCDEF", "alice")
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+}
+
+func TestLeave(t *testing.T) {
+	m := NewManager()
+	tb, err := m.Create("alice", "holdem", nil, 2)
+	AssertThat(t, err, Nil())
+	_, err = m.Join(tb.Code, "bob")
+	AssertThat(t, err, Nil())
+
+	tb, err = m.Leave("alice")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, tb.Players, ElementsAre("bob"))
+	_, err = m.Leave("alice")
+	ExpectThat(t, err, ErrorIs(ErrNotSeated))
+
+	// The table closes once everyone has left.
+	_, err = m.Leave("bob")
+	AssertThat(t, err, Nil())
+	_, err = m.Get(tb.Code)
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+}
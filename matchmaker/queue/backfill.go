@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNoBackfill is returned when a backfill ID does not refer to an open
+// backfill request.
+var ErrNoBackfill = errors.New("backfill not found")
+
+// Backfill is a request from a running table for players to fill its empty
+// seats. Open backfills are served before new tables are formed, so that
+// games in progress are not left short-handed.
+type Backfill struct {
+	ID       string
+	TableID  string
+	GameMode string
+
+	// If set, players are only sent to the table if they can play in this
+	// region.
+	Region string
+
+	// Seats still open at the table.
+	Seats int
+
+	CreatedAt time.Time
+}
+
+// RequestBackfill opens a backfill request for seats at a table. A table has
+// at most one open request; requesting again replaces the previous one's
+// region and seat count.
+func (q *Queue) RequestBackfill(tableID, gameMode, region string, seats int) (Backfill, error) {
+	if seats < 1 {
+		return Backfill{}, errors.New("backfill must request at least one seat")
+	}
+	var b *Backfill
+	err := q.update(context.Background(), func() error {
+		for _, open := range q.backfills {
+			if open.TableID == tableID {
+				open.GameMode, open.Region, open.Seats = gameMode, region, seats
+				b = open
+				return nil
+			}
+		}
+		b = &Backfill{
+			ID:        NewID(),
+			TableID:   tableID,
+			GameMode:  gameMode,
+			Region:    region,
+			Seats:     seats,
+			CreatedAt: q.now(),
+		}
+		q.backfills = append(q.backfills, b)
+		return nil
+	})
+	if err != nil {
+		return Backfill{}, err
+	}
+	return *b, nil
+}
+
+// CancelBackfill closes a backfill request.
+func (q *Queue) CancelBackfill(id string) error {
+	return q.update(context.Background(), func() error {
+		for i, b := range q.backfills {
+			if b.ID == id {
+				q.backfills = append(q.backfills[:i], q.backfills[i+1:]...)
+				return nil
+			}
+		}
+		return ErrNoBackfill
+	})
+}
+
+// Backfills returns the open backfill requests, oldest first.
+func (q *Queue) Backfills() []Backfill {
+	var open []Backfill
+	q.view(context.Background(), func() {
+		for _, b := range q.backfills {
+			open = append(open, *b)
+		}
+	})
+	return open
+}
+
+// backfillLocked fills as many of b's seats as it can from waiting tickets,
+// which must already be in match order. Ratings are not considered, since
+// the players at the table are unknown to the queue.
+func (q *Queue) backfillLocked(b *Backfill, waiting []*Ticket, matched map[*Ticket]bool) []*Ticket {
+	var group []*Ticket
+	for _, t := range waiting {
+		if b.Seats == 0 {
+			break
+		}
+		if matched[t] || t.Size() > b.Seats || !q.playsInLocked(t, b.Region) {
+			continue
+		}
+		group = append(group, t)
+		b.Seats -= t.Size()
+	}
+	return group
+}
+
+// playsInLocked reports whether t may be seated in region, which is true of
+// any region for tickets without latency reports.
+func (q *Queue) playsInLocked(t *Ticket, region string) bool {
+	allowed := q.allowedRegionsLocked(t)
+	if region == "" || allowed == nil {
+		return true
+	}
+	_, ok := allowed[region]
+	return ok
+}
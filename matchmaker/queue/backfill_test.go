@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestRequestBackfill(t *testing.T) {
+	q := New()
+	_, err := q.RequestBackfill("table1", "holdem", "", 0)
+	ExpectThat(t, err, Not(Nil()))
+
+	b, err := q.RequestBackfill("table1", "holdem", "", 2)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, b.Seats, 2)
+
+	// Requesting again for the same table updates the open request.
+	again, err := q.RequestBackfill("table1", "holdem", "", 3)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, again.ID, b.ID)
+	AssertThat(t, q.Backfills(), Len(1))
+	ExpectEq(t, q.Backfills()[0].Seats, 3)
+
+	ExpectThat(t, q.CancelBackfill(b.ID), Nil())
+	ExpectThat(t, q.CancelBackfill(b.ID), ErrorIs(ErrNoBackfill))
+	ExpectThat(t, q.Backfills(), Empty())
+}
+
+func TestFormMatches_Backfill(t *testing.T) {
+	q := New()
+	b, err := q.RequestBackfill("table1", "holdem", "", 2)
+	AssertThat(t, err, Nil())
+	a, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+
+	// A backfill takes players even when it can't fill every seat.
+	matches := formMatches(t, q, 6)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].TableID, "table1")
+	ExpectEq(t, matches[0].BackfillID, b.ID)
+	ExpectEq(t, matches[0].Tickets[0].ID, a.ID)
+	AssertThat(t, q.Backfills(), Len(1))
+	ExpectEq(t, q.Backfills()[0].Seats, 1)
+
+	// Parties that don't fit are left for new tables, and the request closes
+	// once its seats are filled.
+	_, err = q.EnqueueParty(ctx, "p", []string{"b", "c"}, "holdem")
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "d", "holdem")
+	AssertThat(t, err, Nil())
+	matches = formMatches(t, q, 6)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].Players(), []string{"d"})
+	ExpectThat(t, q.Backfills(), Empty())
+	ExpectEq(t, q.Len(), 1)
+}
+
+func TestFormMatches_BackfillRegion(t *testing.T) {
+	q := New(WithMaxRTT(50 * ms))
+	_, err := q.RequestBackfill("table1", "holdem", "eu-west", 1)
+	AssertThat(t, err, Nil())
+	enqueueWithLatency(t, q, "a", Latencies{"us-east": 20 * ms, "eu-west": 100 * ms})
+	ExpectThat(t, formMatches(t, q, 6), Empty())
+
+	enqueueWithLatency(t, q, "b", Latencies{"eu-west": 30 * ms})
+	matches := formMatches(t, q, 6)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].Players(), []string{"b"})
+	ExpectEq(t, matches[0].Region, "eu-west")
+}
@@ -0,0 +1,34 @@
+package queue
+
+import (
+	"time"
+)
+
+// BotPolicy lets a game mode's tables start short of players, with bots
+// seated in the empty places.
+type BotPolicy struct {
+	// How long the longest-waiting ticket in a match must have waited
+	// before the match may be completed with bots.
+	After time.Duration
+
+	// Fewest human players a table may start with. Zero means one.
+	MinHumans int
+}
+
+// WithBots allows matches in the game modes in policies to be completed with
+// bots once their players have waited long enough. Without it, matches only
+// form when every seat can be taken by a player.
+func WithBots(policies map[string]BotPolicy) Option {
+	return func(q *Queue) { q.bots = policies }
+}
+
+// botsLocked returns the number of bots that may complete a match of seats
+// players, anchored by its longest-waiting ticket, at a table for size. It
+// returns zero if the match must wait for more players.
+func (q *Queue) botsLocked(anchor *Ticket, seats, size int, now time.Time) int {
+	p, ok := q.bots[anchor.GameMode]
+	if !ok || now.Sub(anchor.CreatedAt) < p.After || seats < max(p.MinHumans, 1) {
+		return 0
+	}
+	return size - seats
+}
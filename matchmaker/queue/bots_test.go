@@ -0,0 +1,51 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestFormMatches_Bots(t *testing.T) {
+	now := time.Unix(1000, 0)
+	q := New(WithBots(map[string]BotPolicy{"holdem": {After: time.Minute, MinHumans: 2}}))
+	q.now = func() time.Time { return now }
+
+	for _, p := range []string{"a", "b"} {
+		_, err := q.Enqueue(ctx, p, "holdem")
+		AssertThat(t, err, Nil())
+	}
+	_, err := q.Enqueue(ctx, "x", "omaha")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, formMatches(t, q, 6), Empty())
+
+	// Once the players have waited long enough, bots take the empty seats.
+	now = now.Add(time.Minute)
+	matches := formMatches(t, q, 6)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].GameMode, "holdem")
+	ExpectThat(t, matches[0].Players(), ElementsAre("a", "b"))
+	ExpectEq(t, matches[0].Bots, 4)
+
+	// Game modes without a policy keep waiting for players.
+	ExpectEq(t, q.Len(), 1)
+}
+
+func TestFormMatches_BotsMinHumans(t *testing.T) {
+	now := time.Unix(1000, 0)
+	q := New(WithBots(map[string]BotPolicy{"holdem": {After: time.Minute, MinHumans: 2}}))
+	q.now = func() time.Time { return now }
+
+	_, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	now = now.Add(time.Hour)
+	ExpectThat(t, formMatches(t, q, 6), Empty())
+
+	// Full tables never need bots.
+	_, err = q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].Bots, 0)
+}
@@ -0,0 +1,26 @@
+package queue
+
+import (
+	"time"
+)
+
+// RegionFallback lets tickets that have waited too long in their own regions
+// be matched into neighboring ones, so that players in quiet regions are not
+// left queued indefinitely.
+type RegionFallback struct {
+	// How long a ticket must wait before it may fall back.
+	After time.Duration
+
+	// Neighbors maps each region to the regions a ticket allowed there may
+	// fall back to, with the penalty added to its round-trip time when
+	// choosing between regions. Larger penalties make a neighbor less
+	// preferred.
+	Neighbors map[string]map[string]time.Duration
+}
+
+// WithRegionFallback allows tickets that have waited past f.After to play in
+// the neighbors of the regions their latency reports allow. Without it,
+// tickets only play in regions they measured, within the maximum RTT.
+func WithRegionFallback(f RegionFallback) Option {
+	return func(q *Queue) { q.fallback = f }
+}
@@ -0,0 +1,52 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestFormMatches_RegionFallback(t *testing.T) {
+	now := time.Unix(1000, 0)
+	q := New(WithMaxRTT(50*ms), WithRegionFallback(RegionFallback{
+		After: time.Minute,
+		Neighbors: map[string]map[string]time.Duration{
+			"us-east": {"eu-west": 60 * ms},
+			"eu-west": {"us-east": 60 * ms},
+		},
+	}))
+	q.now = func() time.Time { return now }
+
+	enqueueWithLatency(t, q, "a", Latencies{"us-east": 20 * ms, "eu-west": 150 * ms})
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+
+	// A newly queued player can't reach the first player's region.
+	now = now.Add(time.Minute)
+	enqueueWithLatency(t, q, "b", Latencies{"us-east": 150 * ms, "eu-west": 20 * ms})
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+
+	// Only the player who has waited long enough falls back.
+	ExpectEq(t, matches[0].Region, "eu-west")
+}
+
+func TestFormMatches_RegionFallbackUnmeasured(t *testing.T) {
+	now := time.Unix(1000, 0)
+	q := New(WithRegionFallback(RegionFallback{
+		After:     time.Minute,
+		Neighbors: map[string]map[string]time.Duration{"us-east": {"us-west": 30 * ms}},
+	}))
+	q.now = func() time.Time { return now }
+
+	enqueueWithLatency(t, q, "a", Latencies{"us-east": 20 * ms})
+	enqueueWithLatency(t, q, "b", Latencies{"us-west": 10 * ms})
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+
+	// Neighbors count as measured at the home region's time plus the
+	// penalty.
+	now = now.Add(time.Minute)
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].Region, "us-west")
+}
@@ -0,0 +1,160 @@
+package queue
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// Latencies maps region names to a player's measured round-trip time to the
+// game servers in that region.
+type Latencies map[string]time.Duration
+
+// ReportLatency records a member's round-trip times to each region. Reports
+// replace any earlier report from the same player.
+func (q *Queue) ReportLatency(ticketID, playerID string, rtts Latencies) error {
+	return q.update(context.Background(), func() error {
+		idx := q.indexLocked(ticketID)
+		if idx < 0 || !q.tickets[idx].Has(playerID) {
+			return ErrNotFound
+		}
+		if q.pings[ticketID] == nil {
+			q.pings[ticketID] = map[string]Latencies{}
+		}
+		q.pings[ticketID][playerID] = maps.Clone(rtts)
+		return nil
+	})
+}
+
+// Latency returns the ticket's round-trip time to each region, which is the
+// worst time reported by any of its members. Regions that only some members
+// measured are omitted. It returns nil if no member has reported.
+func (q *Queue) Latency(ticketID string) (l Latencies) {
+	q.view(context.Background(), func() { l = q.latencyLocked(ticketID) })
+	return l
+}
+
+func (q *Queue) latencyLocked(ticketID string) Latencies {
+	var worst Latencies
+	for _, rtts := range q.pings[ticketID] {
+		worst = worstOf(worst, rtts)
+	}
+	return worst
+}
+
+// worstOf combines two sets of latencies, keeping the regions present in
+// both with the larger of the two times. A nil a is treated as matching b.
+func worstOf(a, b Latencies) Latencies {
+	if a == nil {
+		return maps.Clone(b)
+	}
+	for region, rtt := range a {
+		other, ok := b[region]
+		if !ok {
+			delete(a, region)
+		} else if other > rtt {
+			a[region] = other
+		}
+	}
+	return a
+}
+
+// regionLocked picks the region to host a group of tickets: the one with the
+// lowest worst-case round-trip time among the tickets that have reported
+// latency, which must all be allowed to play there. Tickets without reports
+// are assumed to play well anywhere, and a group with no reports at all gets
+// an empty region. It reports false if no region qualifies.
+func (q *Queue) regionLocked(group []*Ticket) (string, time.Duration, bool) {
+	var shared Latencies
+	reported := false
+	for _, t := range group {
+		if allowed := q.allowedRegionsLocked(t); allowed != nil {
+			shared = worstOf(shared, allowed)
+			reported = true
+		}
+	}
+	if !reported {
+		return "", 0, true
+	}
+
+	best, bestRTT, found := "", time.Duration(0), false
+	for _, region := range slices.Sorted(maps.Keys(shared)) {
+		if rtt := shared[region]; !found || rtt < bestRTT {
+			best, bestRTT, found = region, rtt, true
+		}
+	}
+	return best, bestRTT, found
+}
+
+// allowedRegionsLocked returns the regions a ticket may play in, with the
+// round-trip time to charge it for each, or nil if it has not reported
+// latency.
+//
+// With a maximum RTT configured, no ticket is placed in a region slower than
+// the maximum, unless even its fastest region is slower; then it is held to
+// that region's time instead. A ticket that has waited past the region
+// fallback deadline may also play in the neighbors of its allowed regions,
+// charged the RTT of the region it falls back from plus the neighbor's
+// penalty.
+func (q *Queue) allowedRegionsLocked(t *Ticket) Latencies {
+	rtts := q.latencyLocked(t.ID)
+	if rtts == nil || q.maxRTT <= 0 && q.fallback.Neighbors == nil {
+		return rtts
+	}
+
+	allowed := rtts
+	if q.maxRTT > 0 {
+		limit := max(q.maxRTT, slices.Min(slices.Collect(maps.Values(rtts))))
+		allowed = Latencies{}
+		for region, rtt := range rtts {
+			if rtt <= limit {
+				allowed[region] = rtt
+			}
+		}
+	}
+	if q.fallback.Neighbors == nil || q.now().Sub(t.CreatedAt) < q.fallback.After {
+		return allowed
+	}
+
+	fallbacks := Latencies{}
+	for region, rtt := range allowed {
+		for neighbor, penalty := range q.fallback.Neighbors[region] {
+			if _, ok := allowed[neighbor]; ok {
+				continue
+			}
+			if cost, ok := fallbacks[neighbor]; !ok || rtt+penalty < cost {
+				fallbacks[neighbor] = rtt + penalty
+			}
+		}
+	}
+	maps.Copy(allowed, fallbacks)
+	return allowed
+}
+
+// byLatencyLocked orders candidates for joining anchor's match so that
+// those with the lowest mutual latency to the anchor come first. Candidates
+// that cannot be compared, because either side has not reported, keep their
+// queue order after the comparable ones.
+func (q *Queue) byLatencyLocked(anchor *Ticket, candidates []*Ticket) []*Ticket {
+	if q.latencyLocked(anchor.ID) == nil {
+		return candidates
+	}
+	cost := map[*Ticket]time.Duration{}
+	for _, t := range candidates {
+		cost[t] = time.Duration(math.MaxInt64)
+		if q.latencyLocked(t.ID) == nil {
+			continue
+		}
+		if _, rtt, ok := q.regionLocked([]*Ticket{anchor, t}); ok {
+			cost[t] = rtt
+		}
+	}
+	sorted := slices.Clone(candidates)
+	slices.SortStableFunc(sorted, func(a, b *Ticket) int {
+		return cmp.Compare(cost[a], cost[b])
+	})
+	return sorted
+}
@@ -0,0 +1,93 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+const ms = time.Millisecond
+
+func enqueueWithLatency(t *testing.T, q *Queue, player string, rtts Latencies) *Ticket {
+	t.Helper()
+	tk, err := q.Enqueue(ctx, player, "holdem")
+	AssertThat(t, err, Nil())
+	if rtts != nil {
+		AssertThat(t, q.ReportLatency(tk.ID, player, rtts), Nil())
+	}
+	return tk
+}
+
+func TestReportLatency(t *testing.T) {
+	q := New()
+	tk, err := q.EnqueueParty(ctx, "p", []string{"a", "b"}, "holdem")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, q.Latency(tk.ID), Nil())
+
+	AssertThat(t, q.ReportLatency(tk.ID, "a", Latencies{"us-east": 20 * ms, "eu-west": 90 * ms}), Nil())
+	AssertThat(t, q.ReportLatency(tk.ID, "b", Latencies{"us-east": 40 * ms, "us-west": 30 * ms}), Nil())
+
+	// The ticket gets the worst time of its members, in regions they all measured.
+	ExpectEq(t, q.Latency(tk.ID), Latencies{"us-east": 40 * ms})
+
+	// A new report replaces the member's old one.
+	AssertThat(t, q.ReportLatency(tk.ID, "b", Latencies{"us-east": 10 * ms}), Nil())
+	ExpectEq(t, q.Latency(tk.ID), Latencies{"us-east": 20 * ms})
+
+	ExpectThat(t, q.ReportLatency(tk.ID, "c", Latencies{}), ErrorIs(ErrNotFound))
+	ExpectThat(t, q.ReportLatency("bogus", "a", Latencies{}), ErrorIs(ErrNotFound))
+
+	_, err = q.Cancel(ctx, tk.ID)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, q.Latency(tk.ID), Nil())
+}
+
+func TestFormMatches_PrefersLowLatency(t *testing.T) {
+	q := New()
+	a := enqueueWithLatency(t, q, "a", Latencies{"us-east": 20 * ms, "eu-west": 100 * ms})
+	enqueueWithLatency(t, q, "b", Latencies{"us-east": 120 * ms, "eu-west": 30 * ms})
+	c := enqueueWithLatency(t, q, "c", Latencies{"us-east": 25 * ms, "eu-west": 110 * ms})
+
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].Tickets[0].ID, a.ID)
+	ExpectEq(t, matches[0].Tickets[1].ID, c.ID)
+	ExpectEq(t, matches[0].Region, "us-east")
+}
+
+func TestFormMatches_MaxRTT(t *testing.T) {
+	q := New(WithMaxRTT(50 * ms))
+	enqueueWithLatency(t, q, "a", Latencies{"us-east": 20 * ms, "eu-west": 100 * ms})
+	enqueueWithLatency(t, q, "b", Latencies{"us-east": 120 * ms, "eu-west": 30 * ms})
+
+	// The best shared region is too slow for one of the players.
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+
+	// Players without reports can play anywhere.
+	enqueueWithLatency(t, q, "c", nil)
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].Region, "us-east")
+}
+
+func TestFormMatches_MaxRTTSlowPlayer(t *testing.T) {
+	q := New(WithMaxRTT(50 * ms))
+	enqueueWithLatency(t, q, "a", Latencies{"us-east": 80 * ms, "eu-west": 150 * ms})
+	enqueueWithLatency(t, q, "b", Latencies{"us-east": 20 * ms, "eu-west": 30 * ms})
+
+	// "a" has no region under the limit, so may still play in their fastest.
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].Region, "us-east")
+}
+
+func TestFormMatches_NoLatencyReports(t *testing.T) {
+	q := New(WithMaxRTT(50 * ms))
+	enqueueWithLatency(t, q, "a", nil)
+	enqueueWithLatency(t, q, "b", nil)
+
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].Region, "")
+}
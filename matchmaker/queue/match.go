@@ -0,0 +1,294 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jfmatt/snapfold/lib/tracing"
+)
+
+// Match is a group of tickets to be seated at the same table.
+type Match struct {
+	ID        string
+	GameMode  string
+	Tickets   []*Ticket
+	CreatedAt time.Time
+
+	// The region whose servers should host the match, chosen from the
+	// players' latency reports. Empty if no player reported latency.
+	Region string
+
+	// Set when the match fills seats at a running table in response to a
+	// backfill request, rather than starting a new table.
+	TableID    string
+	BackfillID string
+
+	// Number of empty seats to fill with bots.
+	Bots int
+}
+
+// Players returns every player in the match, in ticket order.
+func (m *Match) Players() []string {
+	var players []string
+	for _, t := range m.Tickets {
+		players = append(players, t.Members...)
+	}
+	return players
+}
+
+// RatingWindow bounds the rating difference between players in a match. The
+// window for a ticket starts at Initial and widens according to Curve for
+// every second the ticket has waited, up to Max.
+type RatingWindow struct {
+	Initial float64
+	Growth  float64
+	Curve   Curve
+
+	// Upper bound on the window. Zero means the window grows without bound.
+	Max float64
+}
+
+// Curve is the shape of a rating window's growth over time.
+type Curve int
+
+const (
+	// The window grows by Growth rating points per second.
+	CurveLinear Curve = iota
+
+	// The window grows by a factor of 1 + Growth per second.
+	CurveExponential
+)
+
+// Width returns the window for a ticket that has waited for the given time.
+// The zero RatingWindow is unbounded.
+func (w RatingWindow) Width(wait time.Duration) float64 {
+	if w == (RatingWindow{}) {
+		return math.Inf(1)
+	}
+	width := w.Initial + w.Growth*wait.Seconds()
+	if w.Curve == CurveExponential {
+		width = w.Initial * math.Pow(1+w.Growth, wait.Seconds())
+	}
+	if w.Max > 0 && width > w.Max {
+		width = w.Max
+	}
+	return width
+}
+
+// FormMatches first fills open backfill requests, then groups the remaining
+// waiting tickets into matches of exactly size players for every game mode
+// with enough compatible players queued.
+//
+// Tickets are considered in priority order, and by age within a priority.
+// The first ticket in that order anchors each new match, and is joined by
+// the tickets that fit in the remaining seats, whose ratings fall within the
+// window of every ticket already in the match, and which share a region with
+// it. Tickets with the lowest mutual latency to the anchor are preferred.
+// Party tickets are never split. If the queue allows bots in the game mode
+// and the anchor has waited long enough, a match short of players is formed
+// anyway, with bots in the empty seats.
+//
+// Matched tickets leave the queue, and their watchers receive a final update
+// carrying the match. Matches are returned even if recording the tickets'
+// new state fails.
+//
+// If the queue has a ready check, new tables are instead held until their
+// players accept. FormMatches then fails the checks whose deadline has
+// passed, and returns the matches accepted since it last ran alongside any
+// that filled backfill requests.
+func (q *Queue) FormMatches(ctx context.Context, size int) ([]*Match, error) {
+	var matches []*Match
+	var updates []Update
+	var dodges []dodge
+	var now time.Time
+	err := q.update(ctx, func() error {
+		now = q.now()
+		updates, dodges = q.expireReadyChecksLocked(now)
+		formed := q.formMatchesLocked(size, now)
+		for _, m := range formed {
+			for _, t := range m.Tickets {
+				updates = append(updates, Update{Ticket: t, Match: m})
+			}
+		}
+		matches = append(q.seated, formed...)
+		q.seated = nil
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, errors.Join(q.saveAll(ctx, updates, now), q.reportDodges(ctx, dodges))
+}
+
+func (q *Queue) formMatchesLocked(size int, now time.Time) []*Match {
+	byMode := map[string][]*Ticket{}
+	var modes []string
+	for _, t := range q.orderedLocked(q.tickets, now) {
+		if _, ok := byMode[t.GameMode]; !ok {
+			modes = append(modes, t.GameMode)
+		}
+		byMode[t.GameMode] = append(byMode[t.GameMode], t)
+	}
+
+	matched := map[*Ticket]bool{}
+	var matches []*Match
+	take := func(mode, region string, group []*Ticket) *Match {
+		for _, t := range group {
+			matched[t] = true
+			q.recordWaitLocked(mode, region, now.Sub(t.CreatedAt))
+		}
+		m := &Match{
+			ID:        NewID(),
+			GameMode:  mode,
+			Tickets:   group,
+			CreatedAt: now,
+			Region:    region,
+		}
+		matches = append(matches, m)
+		return m
+	}
+
+	open := q.backfills[:0]
+	for _, b := range q.backfills {
+		if group := q.backfillLocked(b, byMode[b.GameMode], matched); len(group) > 0 {
+			m := take(b.GameMode, b.Region, group)
+			m.TableID, m.BackfillID = b.TableID, b.ID
+		}
+		if b.Seats > 0 {
+			open = append(open, b)
+		}
+	}
+	clear(q.backfills[len(open):])
+	q.backfills = open
+
+	for _, mode := range modes {
+		waiting := byMode[mode]
+		for i, anchor := range waiting {
+			if matched[anchor] || anchor.Size() > size {
+				continue
+			}
+			group := []*Ticket{anchor}
+			seats := anchor.Size()
+			for _, t := range q.byLatencyLocked(anchor, waiting[i+1:]) {
+				if seats == size {
+					break
+				}
+				if !matched[t] && seats+t.Size() <= size && q.compatibleLocked(t, group, now) {
+					group = append(group, t)
+					seats += t.Size()
+				}
+			}
+			bots := 0
+			if seats < size {
+				if bots = q.botsLocked(anchor, seats, size, now); bots == 0 {
+					continue
+				}
+			}
+			region, _, _ := q.regionLocked(group)
+			take(mode, region, group).Bots = bots
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	remaining := q.tickets[:0]
+	for _, t := range q.tickets {
+		if !matched[t] {
+			remaining = append(remaining, t)
+		}
+	}
+	clear(q.tickets[len(remaining):])
+	q.tickets = remaining
+
+	seated := matches[:0]
+	for _, m := range matches {
+		if q.readyTimeout > 0 && m.TableID == "" {
+			q.startReadyCheckLocked(m, now)
+			continue
+		}
+		for _, t := range m.Tickets {
+			q.finishLocked(Update{Ticket: t, Match: m})
+		}
+		seated = append(seated, m)
+	}
+	q.notifyLocked()
+	return seated
+}
+
+// DefaultInterval is how often Run forms matches if given no interval.
+const DefaultInterval = time.Second
+
+// Run expires stale tickets and forms matches of size players every interval
+// until ctx is done, passing each match to onMatch. A non-positive interval
+// means DefaultInterval. Errors recording ticket states are passed to
+// onError, and do not stop the loop.
+//
+// Each pass is traced, and each match is handed to onMatch with a span of
+// its own, linked to the requests that created its tickets.
+func (q *Queue) Run(ctx context.Context, interval time.Duration, size int, onMatch func(ctx context.Context, m *Match), onError func(error)) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			q.pass(ctx, size, onMatch, onError)
+		}
+	}
+}
+
+// pass is one pass of Run.
+func (q *Queue) pass(ctx context.Context, size int, onMatch func(context.Context, *Match), onError func(error)) {
+	tracer := otel.Tracer("github.com/jfmatt/snapfold/matchmaker/queue")
+	ctx, span := tracer.Start(ctx, "queue.pass")
+	defer span.End()
+	if _, err := q.Expire(ctx); err != nil {
+		onError(err)
+	}
+	matches, err := q.FormMatches(ctx, size)
+	span.SetAttributes(attribute.Int("snapfold.matches", len(matches)))
+	for _, m := range matches {
+		var links []trace.Link
+		for _, t := range m.Tickets {
+			if l, ok := tracing.Link(t.Traceparent); ok {
+				links = append(links, l)
+			}
+		}
+		mctx, mspan := tracer.Start(ctx, "queue.match", trace.WithLinks(links...), trace.WithAttributes(
+			attribute.String("snapfold.match_id", m.ID),
+			attribute.String("snapfold.game_mode", m.GameMode),
+		))
+		onMatch(mctx, m)
+		mspan.End()
+	}
+	if err != nil {
+		onError(err)
+	}
+}
+
+// compatibleLocked reports whether t may join a group, which requires every
+// pair of players to be within both players' rating windows and a region to
+// be acceptable to all of them.
+func (q *Queue) compatibleLocked(t *Ticket, group []*Ticket, now time.Time) bool {
+	width := q.window.Width(now.Sub(t.CreatedAt))
+	for _, other := range group {
+		diff := math.Abs(t.Rating - other.Rating)
+		if diff > width || diff > q.window.Width(now.Sub(other.CreatedAt)) {
+			return false
+		}
+	}
+	_, _, ok := q.regionLocked(append(slices.Clone(group), t))
+	return ok
+}
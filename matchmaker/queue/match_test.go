@@ -0,0 +1,298 @@
+package queue
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func formMatches(t *testing.T, q *Queue, size int) []*Match {
+	t.Helper()
+	matches, err := q.FormMatches(ctx, size)
+	AssertThat(t, err, Nil())
+	return matches
+}
+
+func TestFormMatches(t *testing.T) {
+	q := New()
+	var ids []string
+	for _, p := range []string{"a", "b", "c", "d", "e"} {
+		tk, err := q.Enqueue(ctx, p, "holdem")
+		AssertThat(t, err, Nil())
+		ids = append(ids, tk.ID)
+	}
+	_, err := q.Enqueue(ctx, "x", "omaha")
+	AssertThat(t, err, Nil())
+
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(2))
+	ExpectEq(t, matches[0].GameMode, "holdem")
+	ExpectEq(t, matches[0].Tickets[0].ID, ids[0])
+	ExpectEq(t, matches[0].Tickets[1].ID, ids[1])
+	ExpectEq(t, matches[1].Tickets[0].ID, ids[2])
+
+	// "e" and the lone omaha player are left over.
+	ExpectEq(t, q.Len(), 2)
+	_, pos, err := q.Get(ids[4])
+	ExpectThat(t, err, Nil())
+	ExpectEq(t, pos, 0)
+
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+}
+
+func TestFormMatches_NotifiesWatchers(t *testing.T) {
+	q := New()
+	a, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+
+	updates, stop, err := q.Watch(a.ID)
+	AssertThat(t, err, Nil())
+	defer stop()
+	<-updates
+
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+
+	u := <-updates
+	AssertThat(t, u.Match, Not(Nil()))
+	ExpectEq(t, u.Match.ID, matches[0].ID)
+
+	_, open := <-updates
+	ExpectEq(t, open, false)
+}
+
+func TestEstimatedWait(t *testing.T) {
+	now := time.Unix(1000, 0)
+	q := New()
+	q.now = func() time.Time { return now }
+
+	_, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	now = now.Add(10 * time.Second)
+	_, err = q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+	now = now.Add(10 * time.Second)
+	AssertThat(t, formMatches(t, q, 2), Len(1))
+
+	c, err := q.Enqueue(ctx, "c", "holdem")
+	AssertThat(t, err, Nil())
+	updates, stop, err := q.Watch(c.ID)
+	AssertThat(t, err, Nil())
+	defer stop()
+
+	// Waits were 20s and 10s; the median falls at the top of the 5-10s
+	// bucket.
+	u := <-updates
+	ExpectEq(t, u.EstimatedWait, 10*time.Second)
+}
+
+func TestRun(t *testing.T) {
+	q := New()
+	_, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	found := make(chan *Match, 1)
+	go q.Run(ctx, time.Millisecond, 2, func(_ context.Context, m *Match) { found <- m }, func(err error) { t.Error(err) })
+
+	m := <-found
+	ExpectThat(t, m.Tickets, Len(2))
+}
+
+func TestRun_NoInterval(t *testing.T) {
+	q := New()
+	for _, id := range []string{"a", "b"} {
+		_, err := q.Enqueue(ctx, id, "holdem")
+		AssertThat(t, err, Nil())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	found := make(chan *Match, 1)
+	go q.Run(ctx, 0, 2, func(_ context.Context, m *Match) { found <- m }, func(err error) { t.Error(err) })
+
+	select {
+	case m := <-found:
+		ExpectThat(t, m.Tickets, Len(2))
+	case <-time.After(5 * DefaultInterval):
+		t.Fatal("no match formed")
+	}
+}
+
+func TestRun_Traces(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(prev)
+
+	q := New()
+	var requests []trace.SpanID
+	for _, id := range []string{"a", "b"} {
+		ctx, span := otel.Tracer("test").Start(context.Background(), "enqueue")
+		_, err := q.Enqueue(ctx, id, "holdem")
+		AssertThat(t, err, Nil())
+		span.End()
+		requests = append(requests, span.SpanContext().SpanID())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	found := make(chan trace.SpanContext, 1)
+	go q.Run(ctx, time.Millisecond, 2, func(ctx context.Context, m *Match) {
+		found <- trace.SpanContextFromContext(ctx)
+	}, func(err error) { t.Error(err) })
+	sc := <-found
+	cancel()
+	ExpectEq(t, sc.IsValid(), true)
+
+	// The match's span ends once onMatch returns.
+	var match sdktrace.ReadOnlySpan
+	for match == nil {
+		for _, s := range rec.Ended() {
+			if s.SpanContext().SpanID() == sc.SpanID() {
+				match = s
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ExpectEq(t, match.Name(), "queue.match")
+	var linked []trace.SpanID
+	for _, l := range match.Links() {
+		linked = append(linked, l.SpanContext.SpanID())
+	}
+	ExpectThat(t, linked, ElementsAre(requests[0], requests[1]))
+}
+
+func TestFormMatches_RatingWindow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	ratings := map[string]float64{"a": 1500, "b": 1900, "c": 1550}
+	q := New(
+		WithRatings(func(_ context.Context, id string) (float64, error) { return ratings[id], nil }),
+		WithRatingWindow(RatingWindow{Initial: 100, Growth: 10, Max: 500}),
+	)
+	q.now = func() time.Time { return now }
+
+	a, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, a.Rating, 1500.0)
+	b, err := q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+
+	// a and b are 400 apart, and need to wait 30s to widen their windows.
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+	now = now.Add(29 * time.Second)
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+
+	// c is close enough to match a right away, skipping over b.
+	c, err := q.Enqueue(ctx, "c", "holdem")
+	AssertThat(t, err, Nil())
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].Tickets[0].ID, a.ID)
+	ExpectEq(t, matches[0].Tickets[1].ID, c.ID)
+
+	_, _, err = q.Get(b.ID)
+	ExpectThat(t, err, Nil())
+}
+
+func TestFormMatches_WindowsAreMutual(t *testing.T) {
+	now := time.Unix(1000, 0)
+	ratings := map[string]float64{"a": 1500, "b": 1800}
+	q := New(
+		WithRatings(func(_ context.Context, id string) (float64, error) { return ratings[id], nil }),
+		WithRatingWindow(RatingWindow{Initial: 100, Growth: 10}),
+	)
+	q.now = func() time.Time { return now }
+
+	_, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	now = now.Add(60 * time.Second)
+	_, err = q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+
+	// a's window has grown to 700, but b's is still 100.
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+	now = now.Add(20 * time.Second)
+	ExpectThat(t, formMatches(t, q, 2), Len(1))
+}
+
+func TestSetThresholds(t *testing.T) {
+	ratings := map[string]float64{"a": 1500, "b": 1900}
+	q := New(
+		WithRatings(func(_ context.Context, id string) (float64, error) { return ratings[id], nil }),
+		WithRatingWindow(RatingWindow{Initial: 100}),
+		WithTicketTTL(time.Minute),
+	)
+	for _, id := range []string{"a", "b"} {
+		_, err := q.Enqueue(ctx, id, "holdem")
+		AssertThat(t, err, Nil())
+	}
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+
+	// Widening the window matches the tickets already waiting.
+	th := q.Thresholds()
+	ExpectEq(t, th.TicketTTL, time.Minute)
+	th.RatingWindow.Initial = 500
+	q.SetThresholds(th)
+	ExpectEq(t, q.Thresholds().RatingWindow.Initial, 500.0)
+	ExpectThat(t, formMatches(t, q, 2), Len(1))
+}
+
+func TestRatingWindow_Width(t *testing.T) {
+	ExpectEq(t, RatingWindow{}.Width(time.Hour), math.Inf(1))
+	w := RatingWindow{Initial: 50, Growth: 5, Max: 200}
+	ExpectEq(t, w.Width(0), 50.0)
+	ExpectEq(t, w.Width(10*time.Second), 100.0)
+	ExpectEq(t, w.Width(time.Hour), 200.0)
+
+	w = RatingWindow{Initial: 50, Growth: 1, Curve: CurveExponential, Max: 300}
+	ExpectEq(t, w.Width(0), 50.0)
+	ExpectEq(t, w.Width(2*time.Second), 200.0)
+	ExpectEq(t, w.Width(time.Minute), 300.0)
+}
+
+func TestFormMatches_Parties(t *testing.T) {
+	q := New()
+	trio, err := q.EnqueueParty(ctx, "p1", []string{"a", "b", "c"}, "holdem")
+	AssertThat(t, err, Nil())
+	duo, err := q.EnqueueParty(ctx, "p2", []string{"d", "e"}, "holdem")
+	AssertThat(t, err, Nil())
+	solo, err := q.Enqueue(ctx, "f", "holdem")
+	AssertThat(t, err, Nil())
+
+	// The duo doesn't fit alongside the trio at a four-seat table, so the
+	// solo player fills the last seat.
+	matches := formMatches(t, q, 4)
+	AssertThat(t, matches, Len(1))
+	ExpectThat(t, matches[0].Tickets, ElementsAre(trio, solo))
+	ExpectThat(t, matches[0].Players(), ElementsAre("a", "b", "c", "f"))
+
+	_, _, err = q.Get(duo.ID)
+	ExpectThat(t, err, Nil())
+}
+
+func TestFormMatches_PartyLargerThanTable(t *testing.T) {
+	q := New()
+	_, err := q.EnqueueParty(ctx, "p1", []string{"a", "b", "c"}, "holdem")
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "d", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "e", "holdem")
+	AssertThat(t, err, Nil())
+
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+	ExpectThat(t, matches[0].Players(), ElementsAre("d", "e"))
+}
@@ -0,0 +1,227 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Pool is shared storage for a queue's state: its tickets, their latency
+// reports, open backfill requests, and matches waiting to be seated. Queues
+// that share a pool, such as those of several matchmaker replicas, act as
+// one queue, and any of them may match any ticket.
+//
+// The state is stored as an opaque encoding chosen by the queue.
+type Pool interface {
+	// Load returns the current state, or nil if nothing has been saved.
+	Load(ctx context.Context) ([]byte, error)
+
+	// Update passes the current state to fn and saves the state fn returns,
+	// with no other update in between. fn is called exactly once. If it
+	// returns an error, nothing is saved and Update returns the error.
+	Update(ctx context.Context, fn func(state []byte) ([]byte, error)) error
+}
+
+// WithPool keeps the queue's state in p rather than in memory, so that it
+// can be shared with other queues. Each queue still keeps its own watchers
+// and wait time history. Watchers learn of changes made through other
+// queues the next time their queue reads the pool, which Run does every
+// interval.
+func WithPool(p Pool) Option {
+	return func(q *Queue) { q.pool = p }
+}
+
+// How long a queue with a pool remembers the final update of a ticket that
+// left the queue, so that other queues can deliver it to their watchers.
+const finishedRetention = time.Minute
+
+// state is the part of a queue that is kept in its pool.
+type state struct {
+	tickets []*Ticket
+
+	// Latency reports per ticket ID, by reporting member.
+	pings map[string]map[string]Latencies
+
+	// Open requests to fill seats at running tables, oldest first.
+	backfills []*Backfill
+
+	// Matches waiting for their players to accept, by match ID, and matches
+	// that have been accepted since FormMatches last returned.
+	checks map[string]*ReadyCheck
+	seated []*Match
+
+	// Final updates of tickets that recently left the queue, by ticket ID.
+	// Only kept if the queue has a pool.
+	finished map[string]finished
+}
+
+type finished struct {
+	Update Update
+	At     time.Time
+}
+
+func newState() state {
+	return state{
+		pings:    map[string]map[string]Latencies{},
+		checks:   map[string]*ReadyCheck{},
+		finished: map[string]finished{},
+	}
+}
+
+// stateJSON is the encoding of a state in a pool.
+type stateJSON struct {
+	Tickets   []*Ticket                       `json:"tickets,omitempty"`
+	Pings     map[string]map[string]Latencies `json:"pings,omitempty"`
+	Backfills []*Backfill                     `json:"backfills,omitempty"`
+	Checks    map[string]*ReadyCheck          `json:"checks,omitempty"`
+	Seated    []*Match                        `json:"seated,omitempty"`
+	Finished  map[string]finished             `json:"finished,omitempty"`
+}
+
+func (s *state) marshal() ([]byte, error) {
+	return json.Marshal(stateJSON{
+		Tickets:   s.tickets,
+		Pings:     s.pings,
+		Backfills: s.backfills,
+		Checks:    s.checks,
+		Seated:    s.seated,
+		Finished:  s.finished,
+	})
+}
+
+func (s *state) unmarshal(b []byte) error {
+	var j stateJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*s = newState()
+	s.tickets, s.backfills, s.seated = j.Tickets, j.Backfills, j.Seated
+	if j.Pings != nil {
+		s.pings = j.Pings
+	}
+	if j.Checks != nil {
+		s.checks = j.Checks
+	}
+	if j.Finished != nil {
+		s.finished = j.Finished
+	}
+	return nil
+}
+
+// update calls fn with the queue locked. If the queue has a pool, the pool
+// is held while fn runs, the queue's state is brought up to date with it
+// first, and the state fn leaves is saved to it afterwards unless fn fails.
+func (q *Queue) update(ctx context.Context, fn func() error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pool == nil {
+		return fn()
+	}
+	err := q.pool.Update(ctx, func(b []byte) ([]byte, error) {
+		if err := q.loadLocked(b); err != nil {
+			return nil, err
+		}
+		if err := fn(); err != nil {
+			return nil, err
+		}
+		now := q.now()
+		for id, f := range q.finished {
+			if now.Sub(f.At) > finishedRetention {
+				delete(q.finished, id)
+			}
+		}
+		b, err := q.state.marshal()
+		if err != nil {
+			return nil, err
+		}
+		q.loaded = b
+		return b, nil
+	})
+	if err != nil {
+		// The pool and the queue's state may no longer agree.
+		q.synced = false
+	}
+	return err
+}
+
+// view calls fn with the queue locked, after bringing the queue's state up
+// to date with its pool if it has one. If reading the pool fails, fn still
+// runs, on the state as last read, and the error is returned.
+func (q *Queue) view(ctx context.Context, fn func()) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var err error
+	if q.pool != nil {
+		var b []byte
+		if b, err = q.pool.Load(ctx); err == nil {
+			err = q.loadLocked(b)
+		}
+	}
+	fn()
+	return err
+}
+
+// loadLocked replaces the queue's state with one read from its pool, and
+// tells watchers about any changes made by other queues.
+func (q *Queue) loadLocked(b []byte) error {
+	if q.synced && bytes.Equal(b, q.loaded) {
+		return nil
+	}
+	s := newState()
+	if b != nil {
+		if err := s.unmarshal(b); err != nil {
+			return err
+		}
+	}
+	q.state = s
+	q.loaded, q.synced = slices.Clone(b), true
+
+	for id := range q.watchers {
+		if idx := q.indexLocked(id); idx >= 0 {
+			u := q.updateLocked(idx)
+			for _, ch := range q.watchers[id] {
+				send(ch, u)
+			}
+		} else if c, t := q.readyCheckForLocked(id); c != nil {
+			u := q.readyCheckUpdate(c, t)
+			for _, ch := range q.watchers[id] {
+				send(ch, u)
+			}
+		} else if f, ok := q.finished[id]; ok {
+			q.finishLocked(f.Update)
+		}
+	}
+	return nil
+}
+
+// MemPool is a Pool in memory, for running several queues in one process,
+// as in development and tests.
+type MemPool struct {
+	mu    sync.Mutex
+	state []byte
+}
+
+// NewMemPool returns an empty MemPool.
+func NewMemPool() *MemPool {
+	return &MemPool{}
+}
+
+func (p *MemPool) Load(ctx context.Context) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.state), nil
+}
+
+func (p *MemPool) Update(ctx context.Context, fn func(state []byte) ([]byte, error)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, err := fn(slices.Clone(p.state))
+	if err != nil {
+		return err
+	}
+	p.state = b
+	return nil
+}
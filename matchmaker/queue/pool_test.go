@@ -0,0 +1,86 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+// replicas returns queues that share a pool and a ticket store.
+func replicas(n int, opts ...Option) []*Queue {
+	pool, store := NewMemPool(), NewMemTicketStore()
+	var qs []*Queue
+	for range n {
+		qs = append(qs, New(append(opts, WithPool(pool), WithTicketStore(store))...))
+	}
+	return qs
+}
+
+func TestPool_SharedTickets(t *testing.T) {
+	qs := replicas(2)
+	a, err := qs[0].Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+
+	// Either replica sees the ticket, and players can't queue twice.
+	_, pos, err := qs[1].Get(a.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, pos, 0)
+	_, err = qs[1].Enqueue(ctx, "alice", "holdem")
+	ExpectThat(t, err, ErrorIs(ErrAlreadyQueued))
+
+	b, err := qs[1].Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, qs[0].Len(), 2)
+
+	_, err = qs[0].Cancel(ctx, b.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, qs[1].Len(), 1)
+}
+
+func TestPool_MatchAcrossReplicas(t *testing.T) {
+	qs := replicas(2)
+	a, err := qs[0].Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = qs[1].Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+	updates, stop, err := qs[0].Watch(a.ID)
+	AssertThat(t, err, Nil())
+	defer stop()
+	<-updates
+
+	matches := formMatches(t, qs[1], 2)
+	AssertThat(t, matches, Len(1))
+	ExpectThat(t, formMatches(t, qs[0], 2), Empty())
+
+	// The watcher hears about the match once its replica reads the pool.
+	u, ok := <-updates
+	AssertEq(t, ok, true)
+	AssertThat(t, u.Match, Not(Nil()))
+	ExpectEq(t, u.Match.ID, matches[0].ID)
+	_, ok = <-updates
+	ExpectEq(t, ok, false)
+
+	r, _, err := qs[0].Status(ctx, a.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.State, StateMatched)
+}
+
+func TestPool_ReadyCheck(t *testing.T) {
+	qs := replicas(2, WithReadyCheck(10*time.Second))
+	_, err := qs[0].Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	b, err := qs[1].Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, formMatches(t, qs[0], 2), Empty())
+
+	id := readyCheckID(t, qs[1], b.ID)
+	AssertThat(t, qs[0].Accept(ctx, id, "alice"), Nil())
+	AssertThat(t, qs[1].Accept(ctx, id, "bob"), Nil())
+
+	// Whichever replica forms matches next returns the accepted match.
+	matches := formMatches(t, qs[1], 2)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].ID, id)
+	ExpectThat(t, formMatches(t, qs[0], 2), Empty())
+}
@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Priority orders tickets within a game mode. Tickets with a higher priority
+// are matched ahead of older tickets with a lower one.
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+
+	// Players overflowing from a full tournament into regular play.
+	PriorityTournament
+
+	// Players returned to the queue after their match fell through, such as
+	// when an opponent disconnected before play began.
+	PriorityRequeue
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityNormal:
+		return "normal"
+	case PriorityTournament:
+		return "tournament"
+	case PriorityRequeue:
+		return "requeue"
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+// ParsePriority returns the Priority with the given name.
+func ParsePriority(s string) (Priority, error) {
+	for p := PriorityNormal; p <= PriorityRequeue; p++ {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown priority %q", s)
+}
+
+// TicketOption configures a ticket as it is enqueued.
+type TicketOption func(*Ticket)
+
+// WithPriority enqueues a ticket at priority p rather than PriorityNormal.
+func WithPriority(p Priority) TicketOption {
+	return func(t *Ticket) { t.Priority = p }
+}
+
+// WithPriorityAging protects low-priority tickets from starvation by raising
+// a ticket's effective priority one level for every d it has waited. Without
+// it, a steady stream of high-priority tickets can hold back lower ones
+// indefinitely.
+func WithPriorityAging(d time.Duration) Option {
+	return func(q *Queue) { q.aging = d }
+}
+
+// effectivePriority returns t's priority after aging.
+func (q *Queue) effectivePriority(t *Ticket, now time.Time) Priority {
+	if q.aging <= 0 {
+		return t.Priority
+	}
+	return t.Priority + Priority(now.Sub(t.CreatedAt)/q.aging)
+}
+
+// compareLocked orders a before b if it has a higher effective priority, or
+// an equal one and was enqueued first.
+func (q *Queue) compareLocked(a, b *Ticket, now time.Time) int {
+	if c := cmp.Compare(q.effectivePriority(b, now), q.effectivePriority(a, now)); c != 0 {
+		return c
+	}
+	return a.CreatedAt.Compare(b.CreatedAt)
+}
+
+// orderedLocked returns tickets sorted in the order they should be matched.
+func (q *Queue) orderedLocked(tickets []*Ticket, now time.Time) []*Ticket {
+	sorted := slices.Clone(tickets)
+	slices.SortStableFunc(sorted, func(a, b *Ticket) int {
+		return q.compareLocked(a, b, now)
+	})
+	return sorted
+}
@@ -0,0 +1,68 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestPriority_String(t *testing.T) {
+	for _, p := range []Priority{PriorityNormal, PriorityTournament, PriorityRequeue} {
+		parsed, err := ParsePriority(p.String())
+		ExpectThat(t, err, Nil())
+		ExpectEq(t, parsed, p)
+	}
+	_, err := ParsePriority("vip")
+	ExpectThat(t, err, Not(Nil()))
+}
+
+func TestFormMatches_Priority(t *testing.T) {
+	q := New()
+	now := time.Unix(1000, 0)
+	q.now = func() time.Time { return now }
+
+	a, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	now = now.Add(time.Second)
+	b, err := q.Enqueue(ctx, "b", "holdem", WithPriority(PriorityTournament))
+	AssertThat(t, err, Nil())
+	now = now.Add(time.Second)
+	c, err := q.Enqueue(ctx, "c", "holdem", WithPriority(PriorityRequeue))
+	AssertThat(t, err, Nil())
+
+	// Positions follow priority, not arrival.
+	for want, tk := range []*Ticket{c, b, a} {
+		_, pos, err := q.Get(tk.ID)
+		AssertThat(t, err, Nil())
+		ExpectEq(t, pos, want)
+	}
+
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].Tickets[0].ID, c.ID)
+	ExpectEq(t, matches[0].Tickets[1].ID, b.ID)
+}
+
+func TestFormMatches_PriorityAging(t *testing.T) {
+	q := New(WithPriorityAging(30 * time.Second))
+	now := time.Unix(1000, 0)
+	q.now = func() time.Time { return now }
+
+	a, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	now = now.Add(time.Minute)
+	_, err = q.Enqueue(ctx, "b", "holdem", WithPriority(PriorityTournament))
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "c", "holdem", WithPriority(PriorityTournament))
+	AssertThat(t, err, Nil())
+
+	// After a minute "a" has aged past the tournament tier.
+	_, pos, err := q.Get(a.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, pos, 0)
+
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].Tickets[0].ID, a.ID)
+}
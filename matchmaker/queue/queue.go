@@ -0,0 +1,595 @@
+// Package queue holds players who are waiting to be matched into a table.
+package queue
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jfmatt/snapfold/lib/tracing"
+)
+
+var (
+	// ErrNotFound is returned when a ticket ID does not refer to a queued
+	// ticket.
+	ErrNotFound = errors.New("ticket not found")
+
+	// ErrAlreadyQueued is returned when a player tries to enqueue while they
+	// already hold a ticket.
+	ErrAlreadyQueued = errors.New("player is already queued")
+)
+
+// Ticket is a request to be matched into a game, on behalf of a single
+// player or a party that must be seated together.
+type Ticket struct {
+	ID string
+
+	// The player who created the ticket. For a party, this is the leader.
+	PlayerID string
+
+	// Set if the ticket was created for a party.
+	PartyID string
+
+	// Every player on the ticket, including PlayerID.
+	Members []string
+
+	GameMode  string
+	CreatedAt time.Time
+
+	// The average skill rating of the members when the ticket was created.
+	Rating float64
+
+	Priority Priority
+
+	// The W3C traceparent of the request that created the ticket, if it
+	// was traced, which the span of the match it joins links to.
+	Traceparent string
+}
+
+// Size returns the number of seats the ticket needs.
+func (t *Ticket) Size() int {
+	return len(t.Members)
+}
+
+// Has reports whether the player is one of the ticket's members.
+func (t *Ticket) Has(playerID string) bool {
+	return slices.Contains(t.Members, playerID)
+}
+
+// Update describes the status of a ticket at a point in time.
+type Update struct {
+	Ticket *Ticket
+
+	// Zero-based position among tickets for the same game mode.
+	Position int
+
+	// Expected time until the ticket is matched: the median wait of recent
+	// matches in the same game mode. Zero if there is no history to estimate
+	// from.
+	EstimatedWait time.Duration
+
+	// Set if the ticket has left the queue because it was canceled. This is
+	// always the last update sent to a watcher.
+	Canceled bool
+
+	// Set if the ticket has left the queue because it was matched. This is
+	// always the last update sent to a watcher.
+	Match *Match
+
+	// Set if the ticket has left the queue because it waited longer than the
+	// queue's ticket TTL. This is always the last update sent to a watcher.
+	Expired bool
+
+	// Set while the ticket's match waits for its players to accept it. If
+	// the ready check fails, the ticket is either canceled or sent back to
+	// the queue, and updates resume accordingly.
+	ReadyCheck *ReadyCheck
+}
+
+// State returns the state of the ticket as of the update.
+func (u Update) State() State {
+	switch {
+	case u.Canceled:
+		return StateCanceled
+	case u.Match != nil:
+		return StateMatched
+	case u.Expired:
+		return StateExpired
+	}
+	return StateQueued
+}
+
+// record returns the Record describing the ticket as of the update.
+func (u Update) record(now time.Time) Record {
+	r := Record{Ticket: *u.Ticket, State: u.State(), UpdatedAt: now}
+	if u.Match != nil {
+		r.MatchID = u.Match.ID
+	}
+	return r
+}
+
+// Queue is a FIFO of tickets, safe for concurrent use.
+type Queue struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	watchers map[string][]chan Update
+
+	// The tickets and matches in play, and, if they are kept in a pool, the
+	// encoding last read from or written to it.
+	state
+	pool   Pool
+	loaded []byte
+	synced bool
+
+	// Recent times from enqueue to match, per game mode and region.
+	waits      map[waitKey]*rollingHistogram
+	waitWindow time.Duration
+
+	ratings  RatingFunc
+	window   RatingWindow
+	maxRTT   time.Duration
+	aging    time.Duration
+	bots     map[string]BotPolicy
+	fallback RegionFallback
+
+	store TicketStore
+	ttl   time.Duration
+
+	readyTimeout time.Duration
+	dodged       DodgeFunc
+}
+
+// RatingFunc looks up a player's current skill rating.
+type RatingFunc func(ctx context.Context, playerID string) (float64, error)
+
+// Option configures a Queue.
+type Option func(*Queue)
+
+// WithRatings sets the source of player ratings, which are recorded on each
+// ticket at enqueue time. Without it, every player is rated 0.
+func WithRatings(f RatingFunc) Option {
+	return func(q *Queue) { q.ratings = f }
+}
+
+// WithRatingWindow restricts matches to players whose ratings are within the
+// window of each other. Without it, ratings are ignored when matching.
+func WithRatingWindow(w RatingWindow) Option {
+	return func(q *Queue) { q.window = w }
+}
+
+// WithMaxRTT restricts matches to regions where every player has reported a
+// round-trip time of at most d, except that players with no region that fast
+// may play in their fastest one. Without it, the region with the lowest
+// round-trip time is still preferred, but any region is allowed.
+func WithMaxRTT(d time.Duration) Option {
+	return func(q *Queue) { q.maxRTT = d }
+}
+
+// WithTicketStore sets where ticket states are recorded. Without it, they
+// are kept in a MemTicketStore.
+func WithTicketStore(s TicketStore) Option {
+	return func(q *Queue) { q.store = s }
+}
+
+// WithTicketTTL expires tickets that have waited longer than d without being
+// matched. Without it, tickets wait until they are matched or canceled.
+func WithTicketTTL(d time.Duration) Option {
+	return func(q *Queue) { q.ttl = d }
+}
+
+// New returns an empty queue.
+func New(opts ...Option) *Queue {
+	q := &Queue{
+		now:        time.Now,
+		watchers:   map[string][]chan Update{},
+		state:      newState(),
+		waits:      map[waitKey]*rollingHistogram{},
+		waitWindow: defaultWaitWindow,
+		store:      NewMemTicketStore(),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// Thresholds are the queue's matching settings that may change while it
+// runs, as set by the options of the same names. Zero values turn each off.
+type Thresholds struct {
+	RatingWindow   RatingWindow
+	MaxRTT         time.Duration
+	RegionFallback RegionFallback
+	TicketTTL      time.Duration
+	PriorityAging  time.Duration
+}
+
+// Thresholds returns the queue's matching settings.
+func (q *Queue) Thresholds() Thresholds {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return Thresholds{
+		RatingWindow:   q.window,
+		MaxRTT:         q.maxRTT,
+		RegionFallback: q.fallback,
+		TicketTTL:      q.ttl,
+		PriorityAging:  q.aging,
+	}
+}
+
+// SetThresholds changes the queue's matching settings. They apply from the
+// next pass over the queue, to the tickets already waiting as well as new
+// ones.
+func (q *Queue) SetThresholds(t Thresholds) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.window = t.RatingWindow
+	q.maxRTT = t.MaxRTT
+	q.fallback = t.RegionFallback
+	q.ttl = t.TicketTTL
+	q.aging = t.PriorityAging
+}
+
+// Enqueue adds a ticket for the player to the back of the queue.
+func (q *Queue) Enqueue(ctx context.Context, playerID, gameMode string, opts ...TicketOption) (*Ticket, error) {
+	return q.EnqueueParty(ctx, "", []string{playerID}, gameMode, opts...)
+}
+
+// EnqueueParty adds a single ticket for a group of players who will be
+// matched into the same game. The first member is recorded as the ticket's
+// owner.
+func (q *Queue) EnqueueParty(ctx context.Context, partyID string, members []string, gameMode string, opts ...TicketOption) (*Ticket, error) {
+	if len(members) == 0 {
+		return nil, errors.New("ticket must have at least one member")
+	}
+
+	var rating float64
+	if q.ratings != nil {
+		for _, id := range members {
+			r, err := q.ratings(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			rating += r
+		}
+		rating /= float64(len(members))
+	}
+
+	t := &Ticket{
+		ID:          NewID(),
+		PlayerID:    members[0],
+		PartyID:     partyID,
+		Members:     slices.Clone(members),
+		GameMode:    gameMode,
+		Rating:      rating,
+		Traceparent: tracing.Traceparent(ctx),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	err := q.update(ctx, func() error {
+		for _, id := range members {
+			if _, ok := q.ticketForLocked(id); ok {
+				return ErrAlreadyQueued
+			}
+		}
+		t.CreatedAt = q.now()
+		q.tickets = append(q.tickets, t)
+		q.notifyLocked()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.store.SaveTicket(ctx, Record{Ticket: *t, State: StateQueued, UpdatedAt: t.CreatedAt})
+	if err != nil {
+		// The ticket can't be looked up later, so don't leave it queued.
+		q.update(ctx, func() error {
+			q.removeLocked(Update{Ticket: t, Canceled: true})
+			return nil
+		})
+		return nil, err
+	}
+	return t, nil
+}
+
+// Cancel removes a ticket from the queue. Watchers of the ticket receive a
+// final update with Canceled set.
+//
+// Canceling a ticket that is already canceled succeeds without effect, so
+// that clients may safely retry. Canceling a ticket that was matched or has
+// expired fails with ErrClosed. Canceling a ticket whose match awaits a
+// ready check declines the match.
+func (q *Queue) Cancel(ctx context.Context, id string) (*Ticket, error) {
+	var t *Ticket
+	var updates []Update
+	var dodges []dodge
+	var now time.Time
+	err := q.update(ctx, func() error {
+		now = q.now()
+		if idx := q.indexLocked(id); idx >= 0 {
+			u := Update{Ticket: q.tickets[idx], Canceled: true}
+			q.removeLocked(u)
+			t, updates = u.Ticket, []Update{u}
+		} else if c, held := q.readyCheckForLocked(id); c != nil {
+			dodges = []dodge{{match: c.Match, players: c.pending(held)}}
+			updates = q.failReadyCheckLocked(c, func(other *Ticket) bool { return other == held })
+			t = held
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if t != nil {
+		return t, errors.Join(q.saveAll(ctx, updates, now), q.reportDodges(ctx, dodges))
+	}
+
+	r, err := q.store.GetTicket(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch r.State {
+	case StateCanceled:
+		return &r.Ticket, nil
+	case StateQueued:
+		// The record outlived the queue, such as across a restart.
+		r.State, r.UpdatedAt = StateCanceled, q.now()
+		return &r.Ticket, q.store.SaveTicket(ctx, r)
+	}
+	return nil, fmt.Errorf("%w: ticket was %s", ErrClosed, r.State)
+}
+
+// Status returns the record of a ticket, whether or not it is still queued,
+// along with its position if it is.
+func (q *Queue) Status(ctx context.Context, id string) (Record, int, error) {
+	var r Record
+	var pos int
+	err := q.view(ctx, func() {
+		if idx := q.indexLocked(id); idx >= 0 {
+			t := q.tickets[idx]
+			r, pos = Record{Ticket: *t, State: StateQueued, UpdatedAt: t.CreatedAt}, q.positionLocked(idx)
+		} else if c, t := q.readyCheckForLocked(id); c != nil {
+			r = Record{Ticket: *t, State: StateQueued, UpdatedAt: c.Match.CreatedAt}
+		}
+	})
+	if err != nil || r.State != 0 {
+		return r, pos, err
+	}
+
+	r, err = q.store.GetTicket(ctx, id)
+	return r, 0, err
+}
+
+// Expire removes tickets that have waited longer than the queue's ticket
+// TTL. Their watchers receive a final update with Expired set.
+func (q *Queue) Expire(ctx context.Context) ([]*Ticket, error) {
+	if q.Thresholds().TicketTTL <= 0 {
+		return nil, nil
+	}
+	var expired []Update
+	var now time.Time
+	err := q.update(ctx, func() error {
+		now = q.now()
+		for _, t := range q.tickets {
+			if q.ttl > 0 && now.Sub(t.CreatedAt) > q.ttl {
+				expired = append(expired, Update{Ticket: t, Expired: true})
+			}
+		}
+		for _, u := range expired {
+			q.removeLocked(u)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var tickets []*Ticket
+	for _, u := range expired {
+		tickets = append(tickets, u.Ticket)
+	}
+	return tickets, q.saveAll(ctx, expired, now)
+}
+
+// Watch subscribes to updates for a ticket. The current status is delivered
+// immediately. Only the most recent update is buffered, so slow readers skip
+// intermediate positions rather than blocking the queue.
+//
+// The returned channel is closed when the ticket leaves the queue or when
+// the returned stop function is called.
+func (q *Queue) Watch(id string) (<-chan Update, func(), error) {
+	ch := make(chan Update, 1)
+	err := q.view(context.Background(), func() {
+		if idx := q.indexLocked(id); idx >= 0 {
+			ch <- q.updateLocked(idx)
+		} else if c, t := q.readyCheckForLocked(id); c != nil {
+			ch <- q.readyCheckUpdate(c, t)
+		} else {
+			return
+		}
+		q.watchers[id] = append(q.watchers[id], ch)
+	})
+	if len(ch) == 0 {
+		return nil, nil, cmp.Or(err, ErrNotFound)
+	}
+
+	stop := func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		watchers := q.watchers[id]
+		for i, w := range watchers {
+			if w == ch {
+				q.watchers[id] = append(watchers[:i], watchers[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+		if len(q.watchers[id]) == 0 {
+			delete(q.watchers, id)
+		}
+	}
+	return ch, stop, nil
+}
+
+// Get returns the ticket with the given ID along with its zero-based
+// position among tickets for the same game mode, in the order they will be
+// matched.
+func (q *Queue) Get(id string) (*Ticket, int, error) {
+	var t *Ticket
+	var pos int
+	err := q.view(context.Background(), func() {
+		if idx := q.indexLocked(id); idx >= 0 {
+			t, pos = q.tickets[idx], q.positionLocked(idx)
+		}
+	})
+	if t == nil {
+		return nil, 0, cmp.Or(err, ErrNotFound)
+	}
+	return t, pos, nil
+}
+
+// TicketFor returns the queued ticket that the player is a member of,
+// including one whose match awaits a ready check.
+func (q *Queue) TicketFor(playerID string) (t *Ticket, ok bool) {
+	q.view(context.Background(), func() { t, ok = q.ticketForLocked(playerID) })
+	return t, ok
+}
+
+func (q *Queue) ticketForLocked(playerID string) (*Ticket, bool) {
+	for _, t := range q.tickets {
+		if t.Has(playerID) {
+			return t, true
+		}
+	}
+	for _, c := range q.checks {
+		for _, t := range c.Match.Tickets {
+			if t.Has(playerID) {
+				return t, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Len returns the number of queued tickets across all game modes.
+func (q *Queue) Len() (n int) {
+	q.view(context.Background(), func() { n = len(q.tickets) })
+	return n
+}
+
+// Depths returns the number of queued tickets for each game mode with any.
+func (q *Queue) Depths() map[string]int {
+	depths := map[string]int{}
+	q.view(context.Background(), func() {
+		for _, t := range q.tickets {
+			depths[t.GameMode]++
+		}
+	})
+	return depths
+}
+
+// removeLocked takes a ticket out of the queue, sending u to its watchers as
+// their final update.
+func (q *Queue) removeLocked(u Update) {
+	idx := q.indexLocked(u.Ticket.ID)
+	if idx < 0 {
+		return
+	}
+	q.tickets = append(q.tickets[:idx], q.tickets[idx+1:]...)
+	q.finishLocked(u)
+	q.notifyLocked()
+}
+
+func (q *Queue) indexLocked(id string) int {
+	for i, t := range q.tickets {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *Queue) positionLocked(idx int) int {
+	t, now := q.tickets[idx], q.now()
+	pos := 0
+	for i, other := range q.tickets {
+		if i == idx || other.GameMode != t.GameMode {
+			continue
+		}
+		if c := q.compareLocked(other, t, now); c < 0 || (c == 0 && i < idx) {
+			pos++
+		}
+	}
+	return pos
+}
+
+func (q *Queue) updateLocked(idx int) Update {
+	pos := q.positionLocked(idx)
+	t := q.tickets[idx]
+	return Update{
+		Ticket:        t,
+		Position:      pos,
+		EstimatedWait: q.estimateLocked(t.GameMode),
+	}
+}
+
+// finishLocked sends a final update to the watchers of a ticket that has
+// left the queue, closes their channels, and forgets its latency reports.
+func (q *Queue) finishLocked(u Update) {
+	if q.pool != nil {
+		q.finished[u.Ticket.ID] = finished{Update: u, At: q.now()}
+	}
+	for _, ch := range q.watchers[u.Ticket.ID] {
+		send(ch, u)
+		close(ch)
+	}
+	delete(q.watchers, u.Ticket.ID)
+	delete(q.pings, u.Ticket.ID)
+}
+
+// notifyLocked sends the current position of every watched ticket to its
+// watchers.
+func (q *Queue) notifyLocked() {
+	for i, t := range q.tickets {
+		watchers := q.watchers[t.ID]
+		if len(watchers) == 0 {
+			continue
+		}
+		u := q.updateLocked(i)
+		for _, ch := range watchers {
+			send(ch, u)
+		}
+	}
+}
+
+// send delivers u to a watcher channel with a buffer of one, replacing any
+// update the watcher has not yet consumed. Callers must hold the queue lock,
+// which guarantees they are the only sender.
+func send(ch chan Update, u Update) {
+	select {
+	case ch <- u:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	ch <- u
+}
+
+// NewID returns a random 128-bit identifier, hex encoded.
+func NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,147 @@
+package queue
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+var ctx = context.Background()
+
+func TestEnqueue(t *testing.T) {
+	q := New()
+	a, err := q.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	b, err := q.Enqueue(ctx, "bob", "omaha")
+	AssertThat(t, err, Nil())
+	c, err := q.Enqueue(ctx, "carol", "holdem")
+	AssertThat(t, err, Nil())
+
+	ExpectEq(t, q.Len(), 3)
+	ExpectEq(t, q.Depths(), map[string]int{"holdem": 2, "omaha": 1})
+	ExpectThat(t, a.ID, Not(Eq(c.ID)))
+
+	_, pos, err := q.Get(c.ID)
+	ExpectThat(t, err, Nil())
+	ExpectEq(t, pos, 1)
+
+	_, pos, err = q.Get(b.ID)
+	ExpectThat(t, err, Nil())
+	ExpectEq(t, pos, 0)
+}
+
+func TestEnqueue_AlreadyQueued(t *testing.T) {
+	q := New()
+	_, err := q.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+
+	_, err = q.Enqueue(ctx, "alice", "omaha")
+	ExpectThat(t, err, ErrorIs(ErrAlreadyQueued))
+	ExpectEq(t, q.Len(), 1)
+}
+
+func TestGet_NotFound(t *testing.T) {
+	_, _, err := New().Get("nope")
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+}
+
+func TestCancel(t *testing.T) {
+	q := New()
+	a, err := q.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	b, err := q.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+
+	_, err = q.Cancel(ctx, a.ID)
+	ExpectThat(t, err, Nil())
+	ExpectEq(t, q.Len(), 1)
+
+	_, pos, err := q.Get(b.ID)
+	ExpectThat(t, err, Nil())
+	ExpectEq(t, pos, 0)
+
+	// Canceling again is harmless.
+	_, err = q.Cancel(ctx, a.ID)
+	ExpectThat(t, err, Nil())
+	_, err = q.Cancel(ctx, "bogus")
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+
+	// The player may queue again once canceled.
+	_, err = q.Enqueue(ctx, "alice", "holdem")
+	ExpectThat(t, err, Nil())
+}
+
+func TestWatch(t *testing.T) {
+	q := New()
+	a, err := q.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+	b, err := q.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+
+	updates, stop, err := q.Watch(b.ID)
+	AssertThat(t, err, Nil())
+	defer stop()
+
+	u := <-updates
+	ExpectEq(t, u.Ticket.ID, b.ID)
+	ExpectEq(t, u.Position, 1)
+
+	_, err = q.Cancel(ctx, a.ID)
+	AssertThat(t, err, Nil())
+	u = <-updates
+	ExpectEq(t, u.Position, 0)
+	ExpectEq(t, u.Canceled, false)
+
+	_, err = q.Cancel(ctx, b.ID)
+	AssertThat(t, err, Nil())
+	u = <-updates
+	ExpectEq(t, u.Canceled, true)
+
+	_, open := <-updates
+	ExpectEq(t, open, false)
+}
+
+func TestWatch_Stop(t *testing.T) {
+	q := New()
+	a, err := q.Enqueue(ctx, "alice", "holdem")
+	AssertThat(t, err, Nil())
+
+	updates, stop, err := q.Watch(a.ID)
+	AssertThat(t, err, Nil())
+	<-updates
+	stop()
+
+	_, open := <-updates
+	ExpectEq(t, open, false)
+
+	// Canceling after the watcher has stopped must not touch its channel.
+	_, err = q.Cancel(ctx, a.ID)
+	ExpectThat(t, err, Nil())
+
+	_, _, err = q.Watch(a.ID)
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+}
+
+func TestEnqueueParty(t *testing.T) {
+	ratings := map[string]float64{"a": 1400, "b": 1600}
+	q := New(WithRatings(func(_ context.Context, id string) (float64, error) { return ratings[id], nil }))
+
+	tk, err := q.EnqueueParty(ctx, "p1", []string{"a", "b"}, "holdem")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, tk.PlayerID, "a")
+	ExpectEq(t, tk.PartyID, "p1")
+	ExpectEq(t, tk.Size(), 2)
+	ExpectEq(t, tk.Rating, 1500.0)
+
+	// Members can't queue separately.
+	_, err = q.Enqueue(ctx, "b", "holdem")
+	ExpectThat(t, err, ErrorIs(ErrAlreadyQueued))
+
+	found, ok := q.TicketFor("b")
+	ExpectEq(t, ok, true)
+	ExpectEq(t, found, tk)
+
+	_, ok = q.TicketFor("c")
+	ExpectEq(t, ok, false)
+}
@@ -0,0 +1,238 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// ErrNoReadyCheck is returned when a player accepts or declines a match that
+// is not waiting on them.
+var ErrNoReadyCheck = errors.New("no ready check for match")
+
+// WithReadyCheck makes every player confirm a new match within d before it
+// is seated. If anyone declines or fails to answer in time, their tickets
+// are removed as canceled and the rest return to the queue at
+// PriorityRequeue. Matches that fill backfill requests are seated at once.
+// Without it, matches are seated as soon as they form.
+func WithReadyCheck(d time.Duration) Option {
+	return func(q *Queue) { q.readyTimeout = d }
+}
+
+// DodgeFunc is told of players who backed out of a match during its ready
+// check.
+type DodgeFunc func(ctx context.Context, m *Match, playerIDs []string) error
+
+// WithDodgeFunc sets a function to call with the players who back out of
+// each failed ready check: a player who declines, the members of a ticket
+// canceled before they all accepted, and the players who had not accepted
+// by the deadline. Errors it returns are returned alongside those from
+// recording ticket states.
+func WithDodgeFunc(f DodgeFunc) Option {
+	return func(q *Queue) { q.dodged = f }
+}
+
+// ReadyCheck is a match waiting for its players to accept it.
+type ReadyCheck struct {
+	Match *Match
+
+	// When players who have not accepted are treated as declining.
+	Deadline time.Time
+
+	// Players who have accepted so far.
+	Accepted []string
+}
+
+// accepted reports whether every member of t has accepted.
+func (c *ReadyCheck) accepted(t *Ticket) bool {
+	return len(c.pending(t)) == 0
+}
+
+// pending returns the members of t who have not accepted.
+func (c *ReadyCheck) pending(t *Ticket) []string {
+	var ids []string
+	for _, id := range t.Members {
+		if !slices.Contains(c.Accepted, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// dodge is a failed ready check and the players who backed out of it.
+type dodge struct {
+	match   *Match
+	players []string
+}
+
+// Accept records that a player is ready to play a match awaiting its ready
+// check. Once every player has accepted, the match is seated: its watchers
+// receive a final update carrying it, and it is returned by the next call to
+// FormMatches.
+func (q *Queue) Accept(ctx context.Context, matchID, playerID string) error {
+	var updates []Update
+	var now time.Time
+	err := q.update(ctx, func() error {
+		now = q.now()
+		c, err := q.readyCheckLocked(matchID, playerID, now)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(c.Accepted, playerID) {
+			c.Accepted = append(c.Accepted, playerID)
+		}
+		if len(c.Accepted) < len(c.Match.Players()) {
+			q.notifyReadyCheckLocked(c)
+			return nil
+		}
+
+		delete(q.checks, matchID)
+		q.seated = append(q.seated, c.Match)
+		for _, t := range c.Match.Tickets {
+			u := Update{Ticket: t, Match: c.Match}
+			q.finishLocked(u)
+			updates = append(updates, u)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return q.saveAll(ctx, updates, now)
+}
+
+// Decline abandons a match awaiting its ready check. The ticket holding the
+// player is canceled, and the match's other tickets return to the queue.
+func (q *Queue) Decline(ctx context.Context, matchID, playerID string) error {
+	var updates []Update
+	var dodges []dodge
+	var now time.Time
+	err := q.update(ctx, func() error {
+		now = q.now()
+		c, err := q.readyCheckLocked(matchID, playerID, now)
+		if err != nil {
+			return err
+		}
+		updates = q.failReadyCheckLocked(c, func(t *Ticket) bool { return t.Has(playerID) })
+		dodges = []dodge{{match: c.Match, players: []string{playerID}}}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return errors.Join(q.saveAll(ctx, updates, now), q.reportDodges(ctx, dodges))
+}
+
+// readyCheckLocked returns the open ready check for a match that includes
+// the player.
+func (q *Queue) readyCheckLocked(matchID, playerID string, now time.Time) (*ReadyCheck, error) {
+	c := q.checks[matchID]
+	if c == nil || !slices.Contains(c.Match.Players(), playerID) || now.After(c.Deadline) {
+		return nil, ErrNoReadyCheck
+	}
+	return c, nil
+}
+
+// readyCheckForLocked returns the open ready check holding a ticket.
+func (q *Queue) readyCheckForLocked(ticketID string) (*ReadyCheck, *Ticket) {
+	for _, c := range q.checks {
+		for _, t := range c.Match.Tickets {
+			if t.ID == ticketID {
+				return c, t
+			}
+		}
+	}
+	return nil, nil
+}
+
+// startReadyCheckLocked holds a newly formed match until its players accept.
+func (q *Queue) startReadyCheckLocked(m *Match, now time.Time) {
+	c := &ReadyCheck{Match: m, Deadline: now.Add(q.readyTimeout)}
+	q.checks[m.ID] = c
+	q.notifyReadyCheckLocked(c)
+}
+
+// expireReadyChecksLocked fails every ready check whose deadline has passed,
+// canceling the tickets of players who did not accept.
+func (q *Queue) expireReadyChecksLocked(now time.Time) ([]Update, []dodge) {
+	var updates []Update
+	var dodges []dodge
+	for _, c := range q.checks {
+		if !now.After(c.Deadline) {
+			continue
+		}
+		d := dodge{match: c.Match}
+		for _, t := range c.Match.Tickets {
+			d.players = append(d.players, c.pending(t)...)
+		}
+		updates = append(updates, q.failReadyCheckLocked(c, func(t *Ticket) bool { return !c.accepted(t) })...)
+		dodges = append(dodges, d)
+	}
+	return updates, dodges
+}
+
+// failReadyCheckLocked abandons a ready check, canceling the tickets chosen
+// by declined and returning the others to the front of the queue. It
+// returns the new state of every ticket in the match.
+func (q *Queue) failReadyCheckLocked(c *ReadyCheck, declined func(*Ticket) bool) []Update {
+	delete(q.checks, c.Match.ID)
+	var updates, requeued []Update
+	var tickets []*Ticket
+	for _, t := range c.Match.Tickets {
+		if declined(t) {
+			u := Update{Ticket: t, Canceled: true}
+			q.finishLocked(u)
+			updates = append(updates, u)
+			continue
+		}
+		// Watchers may still be reading the matched ticket, so requeue a copy.
+		requeue := *t
+		requeue.Priority = PriorityRequeue
+		tickets = append(tickets, &requeue)
+		requeued = append(requeued, Update{Ticket: &requeue})
+	}
+	q.tickets = append(tickets, q.tickets...)
+	q.notifyLocked()
+	return append(updates, requeued...)
+}
+
+// notifyReadyCheckLocked sends the state of a ready check to the watchers of
+// every ticket in its match.
+func (q *Queue) notifyReadyCheckLocked(c *ReadyCheck) {
+	for _, t := range c.Match.Tickets {
+		u := q.readyCheckUpdate(c, t)
+		for _, ch := range q.watchers[t.ID] {
+			send(ch, u)
+		}
+	}
+}
+
+func (q *Queue) readyCheckUpdate(c *ReadyCheck, t *Ticket) Update {
+	snapshot := *c
+	snapshot.Accepted = slices.Clone(c.Accepted)
+	return Update{Ticket: t, ReadyCheck: &snapshot}
+}
+
+// reportDodges passes each dodge to the queue's DodgeFunc, if it has one.
+func (q *Queue) reportDodges(ctx context.Context, dodges []dodge) error {
+	if q.dodged == nil {
+		return nil
+	}
+	var errs []error
+	for _, d := range dodges {
+		if len(d.players) > 0 {
+			errs = append(errs, q.dodged(ctx, d.match, d.players))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// saveAll records the state of each ticket in updates.
+func (q *Queue) saveAll(ctx context.Context, updates []Update, now time.Time) error {
+	var errs []error
+	for _, u := range updates {
+		errs = append(errs, q.store.SaveTicket(ctx, u.record(now)))
+	}
+	return errors.Join(errs...)
+}
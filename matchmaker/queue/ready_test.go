@@ -0,0 +1,191 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+// readyCheckID returns the ID of the match awaiting a ready check that holds
+// a ticket.
+func readyCheckID(t *testing.T, q *Queue, ticketID string) string {
+	t.Helper()
+	var c *ReadyCheck
+	q.view(ctx, func() { c, _ = q.readyCheckForLocked(ticketID) })
+	AssertThat(t, c, Not(Nil()))
+	return c.Match.ID
+}
+
+func TestReadyCheck_AllAccept(t *testing.T) {
+	q := New(WithReadyCheck(10 * time.Second))
+	a, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+	updates, stop, err := q.Watch(a.ID)
+	AssertThat(t, err, Nil())
+	defer stop()
+	<-updates
+
+	// The match is held until everyone accepts.
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+	ExpectEq(t, q.Len(), 0)
+	u := <-updates
+	AssertThat(t, u.ReadyCheck, Not(Nil()))
+	id := u.ReadyCheck.Match.ID
+
+	// Players can't queue again while the check is open.
+	_, err = q.Enqueue(ctx, "a", "holdem")
+	ExpectThat(t, err, ErrorIs(ErrAlreadyQueued))
+
+	AssertThat(t, q.Accept(ctx, id, "a"), Nil())
+	ExpectThat(t, (<-updates).ReadyCheck.Accepted, ElementsAre("a"))
+	ExpectThat(t, q.Accept(ctx, id, "c"), ErrorIs(ErrNoReadyCheck))
+	AssertThat(t, q.Accept(ctx, id, "b"), Nil())
+	ExpectEq(t, (<-updates).Match.ID, id)
+
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].ID, id)
+	r, _, err := q.Status(ctx, a.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.State, StateMatched)
+
+	// Matches are returned only once.
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+}
+
+func TestReadyCheck_Decline(t *testing.T) {
+	q := New(WithReadyCheck(10 * time.Second))
+	a, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	b, err := q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+	c, err := q.Enqueue(ctx, "c", "holdem")
+	AssertThat(t, err, Nil())
+
+	rec, _, err := q.Status(ctx, b.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, rec.State, StateQueued)
+	id := readyCheckID(t, q, a.ID)
+
+	AssertThat(t, q.Accept(ctx, id, "b"), Nil())
+	AssertThat(t, q.Decline(ctx, id, "a"), Nil())
+
+	// The decliner is out, and the other player goes back ahead of newer
+	// tickets.
+	rec, _, err = q.Status(ctx, a.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, rec.State, StateCanceled)
+	tk, pos, err := q.Get(b.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, tk.Priority, PriorityRequeue)
+	ExpectEq(t, pos, 0)
+	_, pos, err = q.Get(c.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, pos, 1)
+
+	ExpectThat(t, q.Accept(ctx, id, "b"), ErrorIs(ErrNoReadyCheck))
+}
+
+func TestReadyCheck_Timeout(t *testing.T) {
+	now := time.Unix(1000, 0)
+	q := New(WithReadyCheck(10 * time.Second))
+	q.now = func() time.Time { return now }
+	a, err := q.EnqueueParty(ctx, "p", []string{"a1", "a2"}, "holdem")
+	AssertThat(t, err, Nil())
+	b, err := q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, formMatches(t, q, 3), Empty())
+	tk, ok := q.TicketFor("b")
+	AssertEq(t, ok, true)
+	ExpectEq(t, tk.ID, b.ID)
+
+	id := readyCheckID(t, q, b.ID)
+	AssertThat(t, q.Accept(ctx, id, "a1"), Nil())
+	AssertThat(t, q.Accept(ctx, id, "b"), Nil())
+
+	// A party is only ready once all its members accept.
+	now = now.Add(11 * time.Second)
+	ExpectThat(t, q.Accept(ctx, id, "a2"), ErrorIs(ErrNoReadyCheck))
+	ExpectThat(t, formMatches(t, q, 3), Empty())
+	rec, _, err := q.Status(ctx, a.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, rec.State, StateCanceled)
+	_, _, err = q.Get(b.ID)
+	ExpectThat(t, err, Nil())
+}
+
+func TestReadyCheck_Cancel(t *testing.T) {
+	q := New(WithReadyCheck(10 * time.Second))
+	a, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	b, err := q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+
+	// Canceling a ticket declines its match.
+	_, err = q.Cancel(ctx, a.ID)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, q.checks, Empty())
+	_, _, err = q.Get(b.ID)
+	ExpectThat(t, err, Nil())
+}
+
+func TestReadyCheck_Backfill(t *testing.T) {
+	q := New(WithReadyCheck(10 * time.Second))
+	_, err := q.RequestBackfill("table1", "holdem", "", 1)
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+
+	// Backfills skip the ready check.
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].TableID, "table1")
+}
+
+func TestReadyCheck_Dodges(t *testing.T) {
+	now := time.Unix(1000, 0)
+	dodged := map[string][]string{}
+	q := New(WithReadyCheck(10*time.Second), WithDodgeFunc(func(ctx context.Context, m *Match, playerIDs []string) error {
+		dodged[m.ID] = append(dodged[m.ID], playerIDs...)
+		return nil
+	}))
+	q.now = func() time.Time { return now }
+
+	// A player who declines is reported even if they accepted first.
+	a, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+	id := readyCheckID(t, q, a.ID)
+	AssertThat(t, q.Accept(ctx, id, "a"), Nil())
+	AssertThat(t, q.Decline(ctx, id, "a"), Nil())
+	ExpectThat(t, dodged[id], ElementsAre("a"))
+
+	// Canceling reports the ticket's members who had not accepted.
+	c, err := q.EnqueueParty(ctx, "p", []string{"c1", "c2"}, "holdem")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, formMatches(t, q, 3), Empty())
+	id = readyCheckID(t, q, c.ID)
+	AssertThat(t, q.Accept(ctx, id, "c1"), Nil())
+	_, err = q.Cancel(ctx, c.ID)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, dodged[id], ElementsAre("c2"))
+
+	// Letting the check time out reports everyone who had not accepted.
+	_, err = q.Enqueue(ctx, "d", "holdem")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+	b, _ := q.TicketFor("b")
+	id = readyCheckID(t, q, b.ID)
+	AssertThat(t, q.Accept(ctx, id, "d"), Nil())
+	now = now.Add(11 * time.Second)
+	ExpectThat(t, formMatches(t, q, 2), Empty())
+	ExpectThat(t, dodged[id], ElementsAre("b"))
+}
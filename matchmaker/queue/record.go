@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned when acting on a ticket that has already left the
+// queue in a way that does not allow the action.
+var ErrClosed = errors.New("ticket is closed")
+
+// State is a stage in a ticket's life. Tickets begin queued and move to
+// exactly one of the other states, after which they never change.
+type State int
+
+const (
+	StateQueued State = iota + 1
+	StateCanceled
+	StateMatched
+	StateExpired
+)
+
+func (s State) String() string {
+	switch s {
+	case StateQueued:
+		return "queued"
+	case StateCanceled:
+		return "canceled"
+	case StateMatched:
+		return "matched"
+	case StateExpired:
+		return "expired"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// ParseState returns the State with the given name.
+func ParseState(s string) (State, error) {
+	for st := StateQueued; st <= StateExpired; st++ {
+		if st.String() == s {
+			return st, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown ticket state %q", s)
+}
+
+// Record is the lasting state of a ticket, kept after it leaves the queue.
+type Record struct {
+	Ticket Ticket
+	State  State
+
+	// Set if the ticket was matched.
+	MatchID string
+
+	UpdatedAt time.Time
+}
+
+// TicketStore records the state of every ticket, so that tickets can still
+// be looked up after they leave the queue.
+type TicketStore interface {
+	// SaveTicket records a ticket's state. Once a ticket has been saved in a
+	// final state, later saves of it are ignored, which keeps retried
+	// transitions harmless.
+	SaveTicket(ctx context.Context, r Record) error
+
+	// GetTicket returns the record of a ticket, or ErrNotFound.
+	GetTicket(ctx context.Context, id string) (Record, error)
+}
+
+// How long MemTicketStore remembers tickets after they leave the queue.
+const memTicketRetention = time.Hour
+
+// MemTicketStore is a TicketStore that keeps records in memory. Records of
+// tickets that have left the queue are forgotten after an hour.
+type MemTicketStore struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	records map[string]Record
+	pruned  time.Time
+}
+
+// NewMemTicketStore returns an empty MemTicketStore.
+func NewMemTicketStore() *MemTicketStore {
+	return &MemTicketStore{now: time.Now, records: map[string]Record{}}
+}
+
+func (s *MemTicketStore) SaveTicket(ctx context.Context, r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := s.now(); now.Sub(s.pruned) > time.Minute {
+		for id, old := range s.records {
+			if old.State != StateQueued && now.Sub(old.UpdatedAt) > memTicketRetention {
+				delete(s.records, id)
+			}
+		}
+		s.pruned = now
+	}
+	if old, ok := s.records[r.Ticket.ID]; ok && old.State != StateQueued {
+		return nil
+	}
+	s.records[r.Ticket.ID] = r
+	return nil
+}
+
+func (s *MemTicketStore) GetTicket(ctx context.Context, id string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.records[id]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	return r, nil
+}
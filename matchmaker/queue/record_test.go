@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestState_String(t *testing.T) {
+	for _, s := range []State{StateQueued, StateCanceled, StateMatched, StateExpired} {
+		parsed, err := ParseState(s.String())
+		ExpectThat(t, err, Nil())
+		ExpectEq(t, parsed, s)
+	}
+	_, err := ParseState("lost")
+	ExpectThat(t, err, Not(Nil()))
+}
+
+func TestStatus(t *testing.T) {
+	q := New()
+	a, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	b, err := q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+
+	r, pos, err := q.Status(ctx, b.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.State, StateQueued)
+	ExpectEq(t, pos, 1)
+
+	// Matched tickets can still be looked up, but not canceled.
+	matches := formMatches(t, q, 2)
+	AssertThat(t, matches, Len(1))
+	r, _, err = q.Status(ctx, a.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.State, StateMatched)
+	ExpectEq(t, r.MatchID, matches[0].ID)
+
+	_, err = q.Cancel(ctx, a.ID)
+	ExpectThat(t, err, ErrorIs(ErrClosed))
+
+	_, _, err = q.Status(ctx, "bogus")
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+}
+
+func TestExpire(t *testing.T) {
+	now := time.Unix(1000, 0)
+	q := New(WithTicketTTL(time.Minute))
+	q.now = func() time.Time { return now }
+
+	a, err := q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	now = now.Add(30 * time.Second)
+	b, err := q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+	updates, stop, err := q.Watch(a.ID)
+	AssertThat(t, err, Nil())
+	defer stop()
+	<-updates
+
+	now = now.Add(31 * time.Second)
+	expired, err := q.Expire(ctx)
+	AssertThat(t, err, Nil())
+	AssertThat(t, expired, Len(1))
+	ExpectEq(t, expired[0].ID, a.ID)
+	ExpectEq(t, (<-updates).Expired, true)
+
+	r, _, err := q.Status(ctx, a.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.State, StateExpired)
+	_, _, err = q.Get(b.ID)
+	ExpectThat(t, err, Nil())
+}
+
+func TestMemTicketStore_FinalStateSticks(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewMemTicketStore()
+	s.now = func() time.Time { return now }
+	tk := Ticket{ID: "t1"}
+	AssertThat(t, s.SaveTicket(ctx, Record{Ticket: tk, State: StateQueued, UpdatedAt: now}), Nil())
+	AssertThat(t, s.SaveTicket(ctx, Record{Ticket: tk, State: StateMatched, MatchID: "m1", UpdatedAt: now}), Nil())
+	AssertThat(t, s.SaveTicket(ctx, Record{Ticket: tk, State: StateCanceled, UpdatedAt: now}), Nil())
+
+	r, err := s.GetTicket(ctx, "t1")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.State, StateMatched)
+	ExpectEq(t, r.MatchID, "m1")
+
+	// Closed tickets are eventually forgotten.
+	now = now.Add(2 * time.Hour)
+	AssertThat(t, s.SaveTicket(ctx, Record{Ticket: Ticket{ID: "t2"}, State: StateQueued, UpdatedAt: now}), Nil())
+	_, err = s.GetTicket(ctx, "t1")
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+}
+
+type failingStore struct{ *MemTicketStore }
+
+func (*failingStore) SaveTicket(context.Context, Record) error {
+	return errors.New("database is down")
+}
+
+func TestEnqueue_StoreFailure(t *testing.T) {
+	q := New(WithTicketStore(&failingStore{NewMemTicketStore()}))
+	_, err := q.Enqueue(ctx, "a", "holdem")
+	ExpectThat(t, err, Not(Nil()))
+	ExpectEq(t, q.Len(), 0)
+}
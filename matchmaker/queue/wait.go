@@ -0,0 +1,184 @@
+package queue
+
+import (
+	"time"
+)
+
+// Upper bounds of the buckets in wait time histograms. Waits longer than the
+// last bound are counted in a final overflow bucket.
+var waitBuckets = []time.Duration{
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	15 * time.Second,
+	20 * time.Second,
+	30 * time.Second,
+	45 * time.Second,
+	time.Minute,
+	90 * time.Second,
+	2 * time.Minute,
+	3 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	20 * time.Minute,
+}
+
+const (
+	// How far back wait times are remembered, unless configured otherwise.
+	defaultWaitWindow = 15 * time.Minute
+
+	// Number of slices a wait window is divided into. Samples expire a slice
+	// at a time.
+	waitSlots = 15
+)
+
+// WithWaitWindow sets how far back the queue looks at completed matches
+// when estimating wait times. Shorter windows react faster to changes in
+// traffic but are noisier. The default is 15 minutes.
+func WithWaitWindow(d time.Duration) Option {
+	return func(q *Queue) { q.waitWindow = d }
+}
+
+// WaitEstimate summarizes how long recent tickets waited to be matched.
+type WaitEstimate struct {
+	GameMode string
+
+	// Empty for an estimate across all regions.
+	Region string
+
+	// Median and 90th percentile waits.
+	Median time.Duration
+	P90    time.Duration
+
+	// Number of matched tickets the estimate is based on. The durations are
+	// zero if there are none.
+	Samples int
+}
+
+// EstimateWait returns the distribution of recent wait times for a game mode
+// in a region. An empty region covers matches in every region, including
+// those formed without latency reports.
+func (q *Queue) EstimateWait(gameMode, region string) WaitEstimate {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.estimateWaitLocked(gameMode, region)
+}
+
+func (q *Queue) estimateWaitLocked(gameMode, region string) WaitEstimate {
+	e := WaitEstimate{GameMode: gameMode, Region: region}
+	h := q.waits[waitKey{gameMode, region}]
+	if h == nil {
+		return e
+	}
+	counts := h.counts(q.now())
+	for _, c := range counts {
+		e.Samples += c
+	}
+	e.Median = quantile(counts, e.Samples, 0.5)
+	e.P90 = quantile(counts, e.Samples, 0.9)
+	return e
+}
+
+type waitKey struct {
+	gameMode, region string
+}
+
+// recordWaitLocked adds the wait of a matched ticket to the histograms for
+// its game mode, overall and in the match's region.
+func (q *Queue) recordWaitLocked(gameMode, region string, wait time.Duration) {
+	keys := []waitKey{{gameMode, ""}}
+	if region != "" {
+		keys = append(keys, waitKey{gameMode, region})
+	}
+	now := q.now()
+	for _, k := range keys {
+		h := q.waits[k]
+		if h == nil {
+			h = newRollingHistogram(q.waitWindow)
+			q.waits[k] = h
+		}
+		h.add(now, wait)
+	}
+}
+
+// estimateLocked guesses how long a ticket will wait, using the median wait
+// of recent matches in its game mode.
+func (q *Queue) estimateLocked(gameMode string) time.Duration {
+	return q.estimateWaitLocked(gameMode, "").Median
+}
+
+// rollingHistogram counts wait times over a sliding window. The window is
+// split into slots, each holding the counts for samples recorded during its
+// span; a slot is cleared when the window moves past it.
+type rollingHistogram struct {
+	slotWidth time.Duration
+	slots     [waitSlots]struct {
+		start  time.Time
+		counts []int // per bucket, plus overflow
+	}
+}
+
+func newRollingHistogram(window time.Duration) *rollingHistogram {
+	return &rollingHistogram{slotWidth: max(window/waitSlots, time.Nanosecond)}
+}
+
+func (h *rollingHistogram) add(now time.Time, wait time.Duration) {
+	start := now.Truncate(h.slotWidth)
+	slot := &h.slots[int(start.UnixNano()/int64(h.slotWidth))%waitSlots]
+	if slot.counts == nil || !slot.start.Equal(start) {
+		slot.start = start
+		slot.counts = make([]int, len(waitBuckets)+1)
+	}
+	bucket := len(waitBuckets)
+	for i, bound := range waitBuckets {
+		if wait <= bound {
+			bucket = i
+			break
+		}
+	}
+	slot.counts[bucket]++
+}
+
+// counts returns the number of samples in each bucket, across slots still
+// within the window.
+func (h *rollingHistogram) counts(now time.Time) []int {
+	counts := make([]int, len(waitBuckets)+1)
+	oldest := now.Truncate(h.slotWidth).Add(-h.slotWidth * (waitSlots - 1))
+	for _, slot := range h.slots {
+		if slot.counts == nil || slot.start.Before(oldest) {
+			continue
+		}
+		for i, c := range slot.counts {
+			counts[i] += c
+		}
+	}
+	return counts
+}
+
+// quantile estimates the q-th quantile of total samples from bucket counts,
+// interpolating linearly within the bucket it falls in. Samples in the
+// overflow bucket are assumed to equal the largest bound.
+func quantile(counts []int, total int, q float64) time.Duration {
+	if total == 0 {
+		return 0
+	}
+	rank := q * float64(total)
+	seen := 0
+	for i, c := range counts {
+		if c == 0 || float64(seen+c) < rank {
+			seen += c
+			continue
+		}
+		if i == len(waitBuckets) {
+			break
+		}
+		var lower time.Duration
+		if i > 0 {
+			lower = waitBuckets[i-1]
+		}
+		frac := (rank - float64(seen)) / float64(c)
+		return lower + time.Duration(frac*float64(waitBuckets[i]-lower))
+	}
+	return waitBuckets[len(waitBuckets)-1]
+}
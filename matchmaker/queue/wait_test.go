@@ -0,0 +1,52 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestQuantile(t *testing.T) {
+	counts := make([]int, len(waitBuckets)+1)
+	ExpectEq(t, quantile(counts, 0, 0.5), time.Duration(0))
+
+	// Four samples in the 10-15s bucket.
+	counts[4] = 4
+	ExpectEq(t, quantile(counts, 4, 0.5), 12500*time.Millisecond)
+	ExpectEq(t, quantile(counts, 4, 1), 15*time.Second)
+
+	// Overflowing samples are reported at the largest bound.
+	counts[len(waitBuckets)] = 100
+	ExpectEq(t, quantile(counts, 104, 0.9), 20*time.Minute)
+}
+
+func TestEstimateWait(t *testing.T) {
+	now := time.Unix(1000, 0)
+	q := New(WithWaitWindow(10 * time.Minute))
+	q.now = func() time.Time { return now }
+	ExpectEq(t, q.EstimateWait("holdem", ""), WaitEstimate{GameMode: "holdem"})
+
+	enqueueWithLatency(t, q, "a", Latencies{"us-east": 20 * ms})
+	enqueueWithLatency(t, q, "b", Latencies{"us-east": 20 * ms})
+	now = now.Add(8 * time.Second)
+	enqueueWithLatency(t, q, "c", nil)
+	enqueueWithLatency(t, q, "d", nil)
+	now = now.Add(4 * time.Second)
+	AssertThat(t, formMatches(t, q, 2), Len(2))
+
+	all := q.EstimateWait("holdem", "")
+	ExpectEq(t, all.Samples, 4)
+	// Two waits of 4s in the 2-5s bucket and two of 12s in the 10-15s bucket.
+	ExpectEq(t, all.Median, 5*time.Second)
+	ExpectEq(t, all.P90, 14*time.Second)
+
+	east := q.EstimateWait("holdem", "us-east")
+	ExpectEq(t, east.Samples, 2)
+	ExpectEq(t, east.Median, 12500*time.Millisecond)
+	ExpectEq(t, q.EstimateWait("holdem", "eu-west").Samples, 0)
+
+	// Samples age out of the window.
+	now = now.Add(11 * time.Minute)
+	ExpectEq(t, q.EstimateWait("holdem", "").Samples, 0)
+}
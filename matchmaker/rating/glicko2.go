@@ -0,0 +1,117 @@
+// Package rating implements Glicko-2 player ratings.
+//
+// See http://www.glicko.net/glicko/glicko2.pdf for the algorithm.
+package rating
+
+import "math"
+
+const (
+	// Conversion factor between the Glicko and Glicko-2 scales.
+	scale = 173.7178
+
+	// System constant constraining the change in volatility over time.
+	tau = 0.5
+
+	// Convergence tolerance for the volatility iteration.
+	epsilon = 0.000001
+)
+
+// Rating is a player's skill estimate on the Glicko scale.
+type Rating struct {
+	// Estimated skill. New players start at 1500.
+	Rating float64
+
+	// Uncertainty in Rating; roughly one standard deviation.
+	Deviation float64
+
+	// Expected degree of fluctuation in the player's performance.
+	Volatility float64
+}
+
+// Default returns the rating assigned to a player with no history.
+func Default() Rating {
+	return Rating{Rating: 1500, Deviation: 350, Volatility: 0.06}
+}
+
+// Result is the outcome of one game against a single opponent.
+type Result struct {
+	Opponent Rating
+
+	// 1 for a win, 0.5 for a draw, 0 for a loss.
+	Score float64
+}
+
+// Update returns r adjusted for a rating period in which the player had the
+// given results. A period with no results only increases the deviation.
+func Update(r Rating, results []Result) Rating {
+	mu := (r.Rating - 1500) / scale
+	phi := r.Deviation / scale
+	sigma := r.Volatility
+
+	if len(results) == 0 {
+		return Rating{
+			Rating:     r.Rating,
+			Deviation:  math.Sqrt(phi*phi+sigma*sigma) * scale,
+			Volatility: sigma,
+		}
+	}
+
+	var vInv, sum float64
+	for _, res := range results {
+		muJ := (res.Opponent.Rating - 1500) / scale
+		phiJ := res.Opponent.Deviation / scale
+		g := 1 / math.Sqrt(1+3*phiJ*phiJ/(math.Pi*math.Pi))
+		e := 1 / (1 + math.Exp(-g*(mu-muJ)))
+		vInv += g * g * e * (1 - e)
+		sum += g * (res.Score - e)
+	}
+	v := 1 / vInv
+	delta := v * sum
+
+	sigma = newVolatility(phi, sigma, v, delta)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu += phi * phi * sum
+
+	return Rating{
+		Rating:     mu*scale + 1500,
+		Deviation:  phi * scale,
+		Volatility: sigma,
+	}
+}
+
+// newVolatility solves for the updated volatility using the Illinois
+// algorithm, per step 5 of the Glicko-2 paper.
+func newVolatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
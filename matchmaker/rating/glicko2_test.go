@@ -0,0 +1,37 @@
+package rating
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func near(t *testing.T, got, want, tolerance float64) {
+	t.Helper()
+	ExpectThat(t, math.Abs(got-want), Lt(tolerance))
+}
+
+// The worked example from the Glicko-2 paper.
+func TestUpdate_PaperExample(t *testing.T) {
+	r := Update(Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}, []Result{
+		{Opponent: Rating{Rating: 1400, Deviation: 30}, Score: 1},
+		{Opponent: Rating{Rating: 1550, Deviation: 100}, Score: 0},
+		{Opponent: Rating{Rating: 1700, Deviation: 300}, Score: 0},
+	})
+	near(t, r.Rating, 1464.06, 0.01)
+	near(t, r.Deviation, 151.52, 0.01)
+	near(t, r.Volatility, 0.05999, 0.00001)
+}
+
+func TestUpdate_NoGames(t *testing.T) {
+	r := Update(Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}, nil)
+	ExpectEq(t, r.Rating, 1500.0)
+	ExpectThat(t, r.Deviation, Gt(200.0))
+}
+
+func TestUpdate_WinRaisesRating(t *testing.T) {
+	r := Update(Default(), []Result{{Opponent: Default(), Score: 1}})
+	ExpectThat(t, r.Rating, Gt(1500.0))
+	ExpectThat(t, r.Deviation, Lt(350.0))
+}
@@ -0,0 +1,97 @@
+package rating
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// Store persists player ratings.
+type Store interface {
+	// Get returns the ratings for the given players. Players without a stored
+	// rating are assigned Default().
+	Get(ctx context.Context, playerIDs ...string) (map[string]Rating, error)
+
+	// Put saves the given ratings, replacing any existing values.
+	Put(ctx context.Context, ratings map[string]Rating) error
+
+	// All returns every stored rating, by player.
+	All(ctx context.Context) (map[string]Rating, error)
+}
+
+// MemStore is an in-memory Store, for development and tests.
+type MemStore struct {
+	mu      sync.Mutex
+	ratings map[string]Rating
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{ratings: map[string]Rating{}}
+}
+
+func (s *MemStore) Get(ctx context.Context, playerIDs ...string) (map[string]Rating, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]Rating, len(playerIDs))
+	for _, id := range playerIDs {
+		r, ok := s.ratings[id]
+		if !ok {
+			r = Default()
+		}
+		out[id] = r
+	}
+	return out, nil
+}
+
+func (s *MemStore) Put(ctx context.Context, ratings map[string]Rating) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, r := range ratings {
+		s.ratings[id] = r
+	}
+	return nil
+}
+
+func (s *MemStore) All(ctx context.Context) (map[string]Rating, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.ratings), nil
+}
+
+// RecordMatch updates the ratings of everyone in a finished match. places
+// maps each player to their finishing position, where 1 is the winner and
+// tied players share a position.
+//
+// A multi-player match is rated as if each player played a separate game
+// against every other player, winning against those who finished below them.
+// All updates are computed from the pre-match ratings.
+func RecordMatch(ctx context.Context, store Store, places map[string]int) error {
+	ids := make([]string, 0, len(places))
+	for id := range places {
+		ids = append(ids, id)
+	}
+	before, err := store.Get(ctx, ids...)
+	if err != nil {
+		return err
+	}
+
+	after := make(map[string]Rating, len(ids))
+	for _, id := range ids {
+		var results []Result
+		for _, other := range ids {
+			if other == id {
+				continue
+			}
+			score := 0.5
+			if places[id] < places[other] {
+				score = 1
+			} else if places[id] > places[other] {
+				score = 0
+			}
+			results = append(results, Result{Opponent: before[other], Score: score})
+		}
+		after[id] = Update(before[id], results)
+	}
+	return store.Put(ctx, after)
+}
@@ -0,0 +1,45 @@
+package rating
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestMemStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemStore()
+
+	got, err := s.Get(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got["alice"], Default())
+
+	want := Rating{Rating: 1600, Deviation: 100, Volatility: 0.06}
+	AssertThat(t, s.Put(ctx, map[string]Rating{"alice": want}), Nil())
+	got, err = s.Get(ctx, "alice", "bob")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got["alice"], want)
+	ExpectEq(t, got["bob"], Default())
+
+	// Only stored ratings are listed.
+	all, err := s.All(ctx)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, len(all), 1)
+	ExpectEq(t, all["alice"], want)
+}
+
+func TestRecordMatch(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemStore()
+
+	err := RecordMatch(ctx, s, map[string]int{"alice": 1, "bob": 2, "carol": 2, "dave": 4})
+	AssertThat(t, err, Nil())
+
+	got, err := s.Get(ctx, "alice", "bob", "carol", "dave")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, got["alice"].Rating, Gt(got["bob"].Rating))
+	ExpectEq(t, got["bob"].Rating, got["carol"].Rating)
+	ExpectThat(t, got["carol"].Rating, Gt(got["dave"].Rating))
+	ExpectThat(t, got["dave"].Rating, Lt(1500.0))
+}
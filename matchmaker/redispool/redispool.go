@@ -0,0 +1,109 @@
+// Package redispool keeps the matchmaking queue's shared state in Redis, so
+// that several matchmaker replicas can serve one queue.
+package redispool
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+// ErrLockLost is returned when an update takes so long that its lock
+// expires, in which case its changes are discarded.
+var ErrLockLost = errors.New("lost the queue lock before saving")
+
+const (
+	// How long a replica may hold the lock. Updates are expected to take
+	// milliseconds; this only bounds how long a crashed replica can stall the
+	// others.
+	lockTTL = 10 * time.Second
+
+	// How often to retry taking a held lock.
+	lockRetry = 5 * time.Millisecond
+)
+
+var (
+	// Saves the state and releases the lock, if the lock is still ours.
+	saveScript = redis.NewScript(`
+if redis.call("get", KEYS[2]) == ARGV[1] then
+	redis.call("set", KEYS[1], ARGV[2])
+	redis.call("del", KEYS[2])
+	return 1
+end
+return 0`)
+
+	// Releases the lock, if it is still ours.
+	unlockScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0`)
+)
+
+// Pool is a queue.Pool stored in Redis. Updates are serialized by a lock
+// kept alongside the state.
+type Pool struct {
+	client  redis.UniversalClient
+	key     string
+	lockKey string
+}
+
+// New returns a Pool stored in client under keys starting with prefix.
+// Both keys share a hash tag, so the pool also works with Redis Cluster.
+func New(client redis.UniversalClient, prefix string) *Pool {
+	tag := "{" + prefix + "}"
+	return &Pool{client: client, key: tag + ":state", lockKey: tag + ":lock"}
+}
+
+var _ queue.Pool = (*Pool)(nil)
+
+func (p *Pool) Load(ctx context.Context) ([]byte, error) {
+	b, err := p.client.Get(ctx, p.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// Update waits until no other replica holds the pool's lock, then takes it
+// for the duration of fn.
+func (p *Pool) Update(ctx context.Context, fn func(state []byte) ([]byte, error)) error {
+	token := queue.NewID()
+	for {
+		ok, err := p.client.SetNX(ctx, p.lockKey, token, lockTTL).Result()
+		if err != nil {
+			return err
+		}
+		if ok {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockRetry):
+		}
+	}
+	// Release the lock even if ctx ends while it is held.
+	unlockCtx := context.WithoutCancel(ctx)
+
+	state, err := p.Load(ctx)
+	if err == nil {
+		state, err = fn(state)
+	}
+	if err != nil {
+		unlockScript.Run(unlockCtx, p.client, []string{p.lockKey}, token)
+		return err
+	}
+	saved, err := saveScript.Run(unlockCtx, p.client, []string{p.key, p.lockKey}, token, state).Int()
+	if err != nil {
+		return err
+	}
+	if saved == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
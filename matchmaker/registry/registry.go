@@ -0,0 +1,236 @@
+// Package registry keeps named game configs, TableConfigs and MatchRules,
+// in the matchmaker's store, so that game servers and operators can fetch
+// them by name rather than bundling config files. Every change to a config
+// gives it a new revision, which clients use to tell whether their copy is
+// current and to avoid overwriting each other's changes.
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/matchmaker/rules"
+)
+
+var (
+	ErrNotFound      = errors.New("config not found")
+	ErrInvalidName   = errors.New("invalid config name")
+	ErrInvalidKind   = errors.New("invalid config kind")
+	ErrInvalidConfig = errors.New("invalid config")
+	ErrConflict      = errors.New("config has changed since the revision given")
+)
+
+// Kind is the type of a config.
+type Kind string
+
+const (
+	KindTableConfig Kind = "table-configs"
+	KindMatchRules  Kind = "match-rules"
+)
+
+// ParseKind returns the kind with the name, or ErrInvalidKind.
+func ParseKind(name string) (Kind, error) {
+	switch k := Kind(name); k {
+	case KindTableConfig, KindMatchRules:
+		return k, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidKind, name)
+}
+
+// Names are short and safe to put in URLs and file names.
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Entry is one stored config.
+type Entry struct {
+	Kind Kind
+	Name string
+
+	// The config, as protojson.
+	Config []byte
+
+	// 1 when the config was first stored, and one more for each change.
+	Revision  int64
+	UpdatedAt time.Time
+}
+
+// ETag returns the entity tag of the entry's revision, for HTTP caching.
+func (e Entry) ETag() string {
+	return fmt.Sprintf(`"%d"`, e.Revision)
+}
+
+// Registry stores configs and checks them on the way in. It is safe for
+// concurrent use.
+type Registry struct {
+	store Store
+	now   func() time.Time
+}
+
+// New returns a Registry that keeps configs in store.
+func New(store Store) *Registry {
+	return &Registry{store: store, now: time.Now}
+}
+
+// Get returns the config of the kind with the name, or ErrNotFound.
+func (r *Registry) Get(ctx context.Context, kind Kind, name string) (Entry, error) {
+	return r.store.GetConfig(ctx, kind, name)
+}
+
+// TableConfig returns the TableConfig with the name, upgraded to the
+// current schema.
+func (r *Registry) TableConfig(ctx context.Context, name string) (*pb.TableConfig, Entry, error) {
+	e, err := r.Get(ctx, KindTableConfig, name)
+	if err != nil {
+		return nil, Entry{}, err
+	}
+	cfg, err := unmarshalTableConfig(e.Config)
+	if err != nil {
+		return nil, Entry{}, fmt.Errorf("table config %s: %w", name, err)
+	}
+	return cfg, e, nil
+}
+
+// MatchRules returns the MatchRules with the name.
+func (r *Registry) MatchRules(ctx context.Context, name string) (*pb.MatchRules, Entry, error) {
+	e, err := r.Get(ctx, KindMatchRules, name)
+	if err != nil {
+		return nil, Entry{}, err
+	}
+	mr := &pb.MatchRules{}
+	if err := protojson.Unmarshal(e.Config, mr); err != nil {
+		return nil, Entry{}, fmt.Errorf("match rules %s: %w", name, err)
+	}
+	return mr, e, nil
+}
+
+// List returns every config of the kind, by name.
+func (r *Registry) List(ctx context.Context, kind Kind) ([]Entry, error) {
+	return r.store.ListConfigs(ctx, kind)
+}
+
+// Put checks a config of the kind, given as protojson, and stores it under
+// the name. If revision is not zero, the config is only stored if the one
+// it replaces is at that revision, or ErrConflict is returned.
+func (r *Registry) Put(ctx context.Context, kind Kind, name string, config []byte, revision int64) (Entry, error) {
+	e, err := r.Prepare(ctx, kind, name, config, revision)
+	if err != nil {
+		return Entry{}, err
+	}
+	return e, r.Save(ctx, e)
+}
+
+// Prepare checks a config as Put does, and returns the entry Put would
+// store, without storing it.
+func (r *Registry) Prepare(ctx context.Context, kind Kind, name string, config []byte, revision int64) (Entry, error) {
+	if !validName.MatchString(name) {
+		return Entry{}, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	var err error
+	switch kind {
+	case KindTableConfig:
+		config, err = checkTableConfig(config)
+	case KindMatchRules:
+		config, err = checkMatchRules(config)
+	default:
+		err = fmt.Errorf("%w: %q", ErrInvalidKind, kind)
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+
+	cur, err := r.store.GetConfig(ctx, kind, name)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return Entry{}, err
+	}
+	if revision != 0 && revision != cur.Revision {
+		return Entry{}, fmt.Errorf("%w: %s is at revision %d", ErrConflict, name, cur.Revision)
+	}
+	e := Entry{
+		Kind:      kind,
+		Name:      name,
+		Config:    config,
+		Revision:  cur.Revision + 1,
+		UpdatedAt: r.now(),
+	}
+	return e, nil
+}
+
+// Save stores an entry made by Prepare. It fails with ErrConflict if the
+// config has changed since.
+func (r *Registry) Save(ctx context.Context, e Entry) error {
+	cur, err := r.store.GetConfig(ctx, e.Kind, e.Name)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if cur.Revision != e.Revision-1 {
+		return fmt.Errorf("%w: %s is at revision %d", ErrConflict, e.Name, cur.Revision)
+	}
+	return r.store.SaveConfig(ctx, e)
+}
+
+// PutTableConfig stores a TableConfig under the name. See Put.
+func (r *Registry) PutTableConfig(ctx context.Context, name string, cfg *pb.TableConfig, revision int64) (Entry, error) {
+	b, err := protojson.Marshal(gamedefio.Stamped(cfg))
+	if err != nil {
+		return Entry{}, err
+	}
+	return r.Put(ctx, KindTableConfig, name, b, revision)
+}
+
+// PutMatchRules stores MatchRules under the name. See Put.
+func (r *Registry) PutMatchRules(ctx context.Context, name string, mr *pb.MatchRules, revision int64) (Entry, error) {
+	b, err := protojson.Marshal(mr)
+	if err != nil {
+		return Entry{}, err
+	}
+	return r.Put(ctx, KindMatchRules, name, b, revision)
+}
+
+// Delete removes the config of the kind with the name, or returns
+// ErrNotFound.
+func (r *Registry) Delete(ctx context.Context, kind Kind, name string) error {
+	return r.store.DeleteConfig(ctx, kind, name)
+}
+
+func unmarshalTableConfig(b []byte) (*pb.TableConfig, error) {
+	b, err := gamedefio.UpgradeJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &pb.TableConfig{}
+	if err := protojson.Unmarshal(b, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// checkTableConfig parses and validates a TableConfig, and returns it as it
+// should be stored: upgraded, with its defaults filled in.
+func checkTableConfig(b []byte) ([]byte, error) {
+	cfg, err := unmarshalTableConfig(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	gamedefio.SetDefaults(cfg)
+	if err := gamedefio.Validate(cfg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return protojson.Marshal(cfg)
+}
+
+func checkMatchRules(b []byte) ([]byte, error) {
+	mr := &pb.MatchRules{}
+	if err := protojson.Unmarshal(b, mr); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	if err := rules.Validate(mr); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return protojson.Marshal(mr)
+}
@@ -0,0 +1,136 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+)
+
+var ctx = context.Background()
+
+const holdem = `{"blinds": {"blindLevels": [{"units": "1"}, {"units": "2"}]}}`
+
+func TestPutAndGet(t *testing.T) {
+	r := New(NewMemStore())
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	e, err := r.Put(ctx, KindTableConfig, "holdem", []byte(`{"standardGameId": "holdem", "blinds": {"blindLevels": [{"units": "1"}, {"units": "2"}]}}`), 0)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, e.Revision, int64(1))
+	ExpectEq(t, e.ETag(), `"1"`)
+	ExpectEq(t, e.UpdatedAt, now)
+
+	// Stored configs are upgraded and have their defaults filled in.
+	cfg, got, err := r.TableConfig(ctx, "holdem")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got.Revision, int64(1))
+	ExpectEq(t, cfg.GetStandardGameId(), "holdem")
+	ExpectEq(t, cfg.GetVariant(), pb.TableConfig_HOLDEM)
+	ExpectEq(t, cfg.GetSchemaVersion(), int32(1))
+
+	cfg.SetStandardGameId("omaha")
+	e, err = r.PutTableConfig(ctx, "holdem", cfg, 1)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, e.Revision, int64(2))
+	cfg, _, err = r.TableConfig(ctx, "holdem")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, cfg.GetStandardGameId(), "omaha")
+
+	_, _, err = r.TableConfig(ctx, "omaha")
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+	_, _, err = r.MatchRules(ctx, "holdem")
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+}
+
+func TestPut_Conflict(t *testing.T) {
+	r := New(NewMemStore())
+	_, err := r.Put(ctx, KindTableConfig, "holdem", []byte(holdem), 0)
+	AssertThat(t, err, Nil())
+	_, err = r.Put(ctx, KindTableConfig, "holdem", []byte(holdem), 1)
+	AssertThat(t, err, Nil())
+
+	_, err = r.Put(ctx, KindTableConfig, "holdem", []byte(holdem), 1)
+	ExpectThat(t, err, ErrorIs(ErrConflict))
+	_, err = r.Put(ctx, KindTableConfig, "omaha", []byte(holdem), 1)
+	ExpectThat(t, err, ErrorIs(ErrConflict))
+	// Without a revision, the latest is replaced.
+	e, err := r.Put(ctx, KindTableConfig, "holdem", []byte(holdem), 0)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, e.Revision, int64(3))
+}
+
+func TestPrepare(t *testing.T) {
+	r := New(NewMemStore())
+	e, err := r.Prepare(ctx, KindTableConfig, "holdem", []byte(holdem), 0)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, e.Revision, int64(1))
+	_, err = r.Get(ctx, KindTableConfig, "holdem")
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+	_, err = r.Prepare(ctx, KindTableConfig, "holdem", []byte(holdem), 1)
+	ExpectThat(t, err, ErrorIs(ErrConflict))
+
+	AssertThat(t, r.Save(ctx, e), Nil())
+	got, err := r.Get(ctx, KindTableConfig, "holdem")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got.Revision, int64(1))
+
+	// Entries prepared against an older revision aren't saved.
+	ExpectThat(t, r.Save(ctx, e), ErrorIs(ErrConflict))
+}
+
+func TestPut_Invalid(t *testing.T) {
+	r := New(NewMemStore())
+	for _, tc := range []struct {
+		desc   string
+		kind   Kind
+		name   string
+		config string
+		want   error
+	}{
+		{"capitals", KindTableConfig, "Hold'em", `{}`, ErrInvalidName},
+		{"no name", KindTableConfig, "", `{}`, ErrInvalidName},
+		{"unknown kind", "widgets", "holdem", `{}`, ErrInvalidKind},
+		{"malformed", KindTableConfig, "holdem", `not json`, ErrInvalidConfig},
+		{"negative blind", KindTableConfig, "holdem", `{"blinds": {"blindLevels": [{"units": "-1"}]}}`, ErrInvalidConfig},
+		{"newer schema", KindTableConfig, "holdem", `{"schemaVersion": 99}`, ErrInvalidConfig},
+		{"unknown field", KindMatchRules, "ranked", `{"noSuchField": 1}`, ErrInvalidConfig},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := r.Put(ctx, tc.kind, tc.name, []byte(tc.config), 0)
+			ExpectThat(t, err, ErrorIs(tc.want))
+		})
+	}
+	entries, err := r.List(ctx, KindTableConfig)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, entries, Empty())
+}
+
+func TestListAndDelete(t *testing.T) {
+	r := New(NewMemStore())
+	for _, name := range []string{"omaha", "holdem"} {
+		_, err := r.Put(ctx, KindTableConfig, name, []byte(holdem), 0)
+		AssertThat(t, err, Nil())
+	}
+	_, err := r.PutMatchRules(ctx, "ranked", pb.MatchRules_builder{TeamSize: proto.Int32(1), Teams: proto.Int32(2)}.Build(), 0)
+	AssertThat(t, err, Nil())
+
+	entries, err := r.List(ctx, KindTableConfig)
+	AssertThat(t, err, Nil())
+	AssertThat(t, entries, Len(2))
+	ExpectEq(t, entries[0].Name, "holdem")
+	ExpectEq(t, entries[1].Name, "omaha")
+
+	AssertThat(t, r.Delete(ctx, KindTableConfig, "holdem"), Nil())
+	ExpectThat(t, r.Delete(ctx, KindTableConfig, "holdem"), ErrorIs(ErrNotFound))
+	// Names are per kind.
+	ExpectThat(t, r.Delete(ctx, KindTableConfig, "ranked"), ErrorIs(ErrNotFound))
+	entries, err = r.List(ctx, KindTableConfig)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, entries, Len(1))
+}
@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+)
+
+// Store persists configs.
+type Store interface {
+	// GetConfig returns the config of the kind with the name, or
+	// ErrNotFound.
+	GetConfig(ctx context.Context, kind Kind, name string) (Entry, error)
+
+	// SaveConfig records a config. It returns ErrConflict unless the
+	// config it replaces is at the revision before e's, or there is none
+	// and e is at revision 1.
+	SaveConfig(ctx context.Context, e Entry) error
+
+	// ListConfigs returns every config of the kind, by name.
+	ListConfigs(ctx context.Context, kind Kind) ([]Entry, error)
+
+	// DeleteConfig removes a config, or returns ErrNotFound.
+	DeleteConfig(ctx context.Context, kind Kind, name string) error
+}
+
+type key struct {
+	kind Kind
+	name string
+}
+
+// MemStore is an in-memory Store, for development and tests.
+type MemStore struct {
+	mu      sync.Mutex
+	configs map[key]Entry
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{configs: map[key]Entry{}}
+}
+
+func (s *MemStore) GetConfig(ctx context.Context, kind Kind, name string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.configs[key{kind, name}]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	return e, nil
+}
+
+func (s *MemStore) SaveConfig(ctx context.Context, e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := key{e.Kind, e.Name}
+	if s.configs[k].Revision != e.Revision-1 {
+		return ErrConflict
+	}
+	s.configs[k] = e
+	return nil
+}
+
+func (s *MemStore) ListConfigs(ctx context.Context, kind Kind) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entries []Entry
+	for _, e := range s.configs {
+		if e.Kind == kind {
+			entries = append(entries, e)
+		}
+	}
+	slices.SortFunc(entries, func(a, b Entry) int { return cmp.Compare(a.Name, b.Name) })
+	return entries, nil
+}
+
+func (s *MemStore) DeleteConfig(ctx context.Context, kind Kind, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := key{kind, name}
+	if _, ok := s.configs[k]; !ok {
+		return ErrNotFound
+	}
+	delete(s.configs, k)
+	return nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/config"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/lib/log"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/registry"
+	"github.com/jfmatt/snapfold/matchmaker/rules"
+)
+
+// reloadFlags returns the serve flags that take effect without a restart
+// when they change in the config file. The queue's thresholds are among
+// them unless a match rules file replaces them.
+func reloadFlags(flags *ServeArgs) []string {
+	live := []string{"log-level", "priority-aging"}
+	if flags.MatchRules == "" {
+		live = append(live, "rating-window.initial", "rating-window.growth", "rating-window.max",
+			"ticket-ttl", "max-rtt", "region-fallback.after")
+	}
+	return live
+}
+
+// reloader applies the changes to the matchmaker's settings that are safe
+// to make while it runs: the log level, the queue's thresholds, and game
+// modes' rake and feature flags. Each change it applies is logged by the
+// audit component; other changes are logged as needing a restart.
+type reloader struct {
+	args    ServeArgs
+	fs      *pflag.FlagSet
+	watcher *config.Watcher
+	level   *slog.LevelVar
+	queue   *queue.Queue
+	lobby   *lobby.Lobby
+	configs *registry.Registry
+
+	// The match rules and game modes in effect, with the game modes'
+	// feature flags unresolved.
+	rules *pb.MatchRules
+	modes map[string]*pb.TableConfig
+
+	// Changes that need a restart and have been logged, so that they are
+	// only logged once.
+	pendingRules *pb.MatchRules
+	pendingModes map[string]*pb.TableConfig
+
+	log   *slog.Logger
+	audit *slog.Logger
+}
+
+// Run checks for changes every interval until ctx is done.
+func (r *reloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reload(ctx)
+		}
+	}
+}
+
+func (r *reloader) reload(ctx context.Context) {
+	if err := r.reloadFlags(); err != nil {
+		r.log.Error("rereading config file", log.Err(err))
+	}
+	if err := r.reloadRules(); err != nil {
+		r.log.Error("rereading match rules", log.Err(err))
+	}
+	if err := r.reloadGameModes(ctx); err != nil {
+		r.log.Error("rereading game modes", log.Err(err))
+	}
+}
+
+// record writes the audit log entry for an applied change.
+func (r *reloader) record(setting, old, new, source string) {
+	r.audit.Info("applied setting", slog.String("setting", setting), slog.String("old", old),
+		slog.String("new", new), slog.String("source", source))
+}
+
+func (r *reloader) reloadFlags() error {
+	applied, restart, err := r.watcher.Reload()
+	for _, c := range restart {
+		r.log.Warn("setting changed in the config file takes effect on restart", slog.String("setting", c.Flag),
+			slog.String("old", c.Old), slog.String("new", c.New))
+	}
+	for _, c := range applied {
+		if err := r.setFlag(c.Flag); err != nil {
+			r.log.Error("applying setting", slog.String("setting", c.Flag), log.Err(err))
+			continue
+		}
+		r.record(c.Flag, c.Old, c.New, r.watcher.Path())
+	}
+	return err
+}
+
+// setFlag reads one of reloadFlags' flags into the reloader's args.
+func (r *reloader) setFlag(name string) error {
+	var err error
+	switch name {
+	case "log-level":
+		r.args.LogLevel, _ = r.fs.GetString(name)
+		var level slog.Level
+		if level, err = log.ParseLevel(r.args.LogLevel); err == nil {
+			r.level.Set(level)
+		}
+	case "priority-aging":
+		r.args.PriorityAging, err = r.fs.GetDuration(name)
+	case "rating-window.initial":
+		r.args.RatingWindow.Initial, err = r.fs.GetFloat64(name)
+	case "rating-window.growth":
+		r.args.RatingWindow.Growth, err = r.fs.GetFloat64(name)
+	case "rating-window.max":
+		r.args.RatingWindow.Max, err = r.fs.GetFloat64(name)
+	case "ticket-ttl":
+		r.args.TicketTTL, err = r.fs.GetDuration(name)
+	case "max-rtt":
+		r.args.MaxRTT, err = r.fs.GetDuration(name)
+	case "region-fallback.after":
+		r.args.RegionFallback.After, err = r.fs.GetDuration(name)
+	default:
+		err = fmt.Errorf("%s can't change without a restart", name)
+	}
+	return err
+}
+
+// reloadRules sets the queue's thresholds from the match rules file, or
+// the flags, if they changed. The table size can only change on restart,
+// since game modes were checked against it.
+func (r *reloader) reloadRules() error {
+	next, err := loadMatchRules(&r.args)
+	if err != nil {
+		return err
+	}
+	aging := r.queue.Thresholds().PriorityAging
+	if proto.Equal(next, r.rules) && r.args.PriorityAging == aging {
+		return nil
+	}
+	if rules.TableSize(next) != rules.TableSize(r.rules) {
+		if !proto.Equal(next, r.pendingRules) {
+			r.pendingRules = next
+			r.log.Warn("changing the table size takes effect on restart; keeping the current match rules",
+				slog.Int("old", rules.TableSize(r.rules)), slog.Int("new", rules.TableSize(next)))
+		}
+		return nil
+	}
+
+	// Flags changed in the config file were recorded as they were read.
+	if r.args.MatchRules != "" {
+		for _, c := range gamedefio.Diff(r.rules, next) {
+			r.record("match-rules."+c.Field, c.Old, c.New, r.args.MatchRules)
+		}
+	}
+	t := rules.Thresholds(next)
+	t.PriorityAging = r.args.PriorityAging
+	r.queue.SetThresholds(t)
+	r.rules, r.pendingRules = next, nil
+	return nil
+}
+
+// reloadGameModes applies the game modes whose only changes are to their
+// rake or feature flags. Other changes, and adding or removing game modes,
+// take effect on restart.
+func (r *reloader) reloadGameModes(ctx context.Context) error {
+	modes, err := gatherGameModes(ctx, &r.args, r.configs)
+	if err != nil {
+		return err
+	}
+	names := slices.Sorted(maps.Keys(modes))
+	for name := range r.modes {
+		if _, ok := modes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		old, next := r.modes[name], modes[name]
+		source := r.gameModeSource(name)
+		if old == nil || next == nil {
+			if p, ok := r.pendingModes[name]; !ok || !proto.Equal(p, next) {
+				r.pendingModes[name] = next
+				r.log.Warn("adding or removing a game mode takes effect on restart",
+					slog.String("game_mode", name), slog.String("source", source))
+			}
+			continue
+		}
+
+		changes := gamedefio.Diff(old, next)
+		if len(changes) == 0 {
+			delete(r.pendingModes, name)
+			continue
+		}
+		if slices.ContainsFunc(changes, needsRestart) {
+			if p, ok := r.pendingModes[name]; !ok || !proto.Equal(p, next) {
+				r.pendingModes[name] = next
+				var fields []string
+				for _, c := range changes {
+					fields = append(fields, c.Field)
+				}
+				r.log.Warn("game mode changes other than rake and feature flags take effect on restart",
+					slog.String("game_mode", name), slog.String("source", source), slog.Any("fields", fields))
+			}
+			continue
+		}
+
+		if err := r.lobby.SetTableConfig(name, gamedefio.Resolve(next, r.args.Environment)); err != nil {
+			return err
+		}
+		r.modes[name] = next
+		delete(r.pendingModes, name)
+		for _, c := range changes {
+			r.record("game-mode."+name+"."+c.Field, c.Old, c.New, source)
+		}
+	}
+	return nil
+}
+
+// gameModeSource names where a game mode is read from: its file or preset,
+// or else the registry, since the built-in presets don't change.
+func (r *reloader) gameModeSource(name string) string {
+	if path, ok := r.args.GameModes[name]; ok {
+		return path
+	}
+	return "registry"
+}
+
+// needsRestart reports whether a game mode change is to anything but its
+// rake and feature flags, which only apply to tables created afterwards.
+func needsRestart(c gamedefio.Change) bool {
+	for _, field := range []string{"rake", "features"} {
+		if c.Field == field || strings.HasPrefix(c.Field, field+".") {
+			return false
+		}
+	}
+	return true
+}
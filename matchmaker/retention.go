@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/history"
+)
+
+type HandRetentionArgs struct {
+	Keep           time.Duration `flag:"keep,default=2160h,help=How long to keep players' hand histories; 0 to keep them forever"`
+	Interval       time.Duration `flag:"interval,default=1h,help=How often to prune hand histories and, in Postgres, make partitions for the coming week's"`
+	ArchiveCommand string        `flag:"archive-command,help=Shell command to archive hand histories before they are pruned; it reads them as JSON lines on stdin and gets the time range they were played in as $SNAPFOLD_HANDS_FROM and $SNAPFOLD_HANDS_TO, and hands it fails to archive are kept"`
+}
+
+// archivedHand is how an archive command is given a hand.
+type archivedHand struct {
+	Number   uint64    `json:"number"`
+	TableID  string    `json:"table_id"`
+	PlayerID string    `json:"player_id"`
+	PlayedAt time.Time `json:"played_at"`
+	Text     string    `json:"text"`
+}
+
+// archiveCommand returns an ArchiveFunc that runs a shell command per
+// batch, or nil if command is empty.
+func archiveCommand(command string) history.ArchiveFunc {
+	if command == "" {
+		return nil
+	}
+	return func(ctx context.Context, from, to time.Time, hands iter.Seq2[history.Hand, error]) error {
+		var fromEnv string
+		if !from.IsZero() {
+			fromEnv = from.UTC().Format(time.RFC3339)
+		}
+		c := exec.CommandContext(ctx, "sh", "-c", command)
+		c.Env = append(os.Environ(), "SNAPFOLD_HANDS_FROM="+fromEnv, "SNAPFOLD_HANDS_TO="+to.UTC().Format(time.RFC3339))
+		c.Stdout, c.Stderr = os.Stderr, os.Stderr
+		stdin, err := c.StdinPipe()
+		if err != nil {
+			return err
+		}
+		if err := c.Start(); err != nil {
+			return err
+		}
+		writeErr := writeHands(stdin, hands)
+		stdin.Close()
+		if err := c.Wait(); err != nil {
+			return fmt.Errorf("archive command: %w", err)
+		}
+		return writeErr
+	}
+}
+
+func writeHands(w io.Writer, hands iter.Seq2[history.Hand, error]) error {
+	enc := json.NewEncoder(w)
+	for h, err := range hands {
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(archivedHand{Number: h.Number, TableID: h.TableID, PlayerID: h.PlayerID, PlayedAt: h.PlayedAt, Text: h.Text}); err != nil {
+			return fmt.Errorf("archive command: %w", err)
+		}
+	}
+	return nil
+}
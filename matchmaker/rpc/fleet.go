@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/matchmaker/fleet"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+)
+
+// FleetService implements pb.FleetServiceServer, registering game servers
+// with a fleet.
+type FleetService struct {
+	pb.UnimplementedFleetServiceServer
+
+	fleet *fleet.Fleet
+	lobby *lobby.Lobby
+}
+
+// NewFleetService returns a FleetService that registers servers with f, and
+// sends them each match's game mode configuration from l.
+func NewFleetService(f *fleet.Fleet, l *lobby.Lobby) *FleetService {
+	return &FleetService{fleet: f, lobby: l}
+}
+
+func (s *FleetService) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	if req.GetServerId() == "" || req.GetAddress() == "" {
+		return nil, status.Error(codes.InvalidArgument, "server_id and address are required")
+	}
+	if req.GetCapacity() < 0 || req.GetTables() < 0 || req.GetPlayers() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "capacity, tables and players must not be negative")
+	}
+
+	assigned := s.fleet.Heartbeat(fleet.Server{
+		ID:       req.GetServerId(),
+		Address:  req.GetAddress(),
+		Region:   req.GetRegion(),
+		Capacity: int(req.GetCapacity()),
+		Tables:   int(req.GetTables()),
+		Players:  int(req.GetPlayers()),
+		Draining: req.GetDraining(),
+	})
+	var assignments []*pb.MatchAssignment
+	for _, a := range assigned {
+		m := a.Match
+		b := pb.MatchAssignment_builder{
+			MatchId:   proto.String(m.ID),
+			GameMode:  proto.String(m.GameMode),
+			PlayerIds: m.Players(),
+		}
+		if a.Traceparent != "" {
+			b.Traceparent = proto.String(a.Traceparent)
+		}
+		if m.Bots > 0 {
+			b.Bots = proto.Int32(int32(m.Bots))
+		}
+		// Matches only form for known game modes, but one may have been
+		// removed from the config since.
+		if cfg, err := s.lobby.TableConfig(m.GameMode); err == nil {
+			b.Config = cfg
+		}
+		assignments = append(assignments, b.Build())
+	}
+	return pb.HeartbeatResponse_builder{Assignments: assignments}.Build(), nil
+}
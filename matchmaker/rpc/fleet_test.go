@@ -0,0 +1,108 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/matchmaker/apikey"
+	"github.com/jfmatt/snapfold/matchmaker/fleet"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func startFleetServer(t *testing.T, f *fleet.Fleet, sessions *session.Store, gameModes map[string]*pb.TableConfig, opts ...ServerOption) pb.FleetServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := NewServer(lobby.New(queue.New(), party.NewManager(), gameModes), sessions, append(opts, WithFleet(f, "secret"))...)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	AssertThat(t, err, Nil())
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewFleetServiceClient(conn)
+}
+
+func TestHeartbeat(t *testing.T) {
+	f := fleet.NewFleet(time.Minute)
+	sessions := session.NewStore()
+	gameModes := map[string]*pb.TableConfig{"holdem": pb.TableConfig_builder{NoSpectators: proto.Bool(true)}.Build()}
+	client := startFleetServer(t, f, sessions, gameModes)
+	ctx := withToken("secret")
+	req := pb.HeartbeatRequest_builder{
+		ServerId: proto.String("s1"),
+		Address:  proto.String("10.0.0.1:7000"),
+		Capacity: proto.Int32(4),
+		Players:  proto.Int32(3),
+	}.Build()
+
+	// Players can't register servers.
+	_, err := client.Heartbeat(withToken(sessions.Create("alice")), req)
+	ExpectEq(t, status.Code(err), codes.Unauthenticated)
+	_, err = client.Heartbeat(ctx, &pb.HeartbeatRequest{})
+	ExpectEq(t, status.Code(err), codes.InvalidArgument)
+
+	resp, err := client.Heartbeat(ctx, req)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, resp.GetAssignments(), Empty())
+	AssertThat(t, f.Servers(), Len(1))
+	ExpectEq(t, f.Servers()[0].Players, 3)
+
+	m := &queue.Match{ID: "m1", GameMode: "holdem", Tickets: []*queue.Ticket{{Members: []string{"alice", "bob"}}}}
+	alloc, err := f.Allocate(ctx, m)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, alloc.Address, "10.0.0.1:7000")
+
+	resp, err = client.Heartbeat(ctx, req)
+	AssertThat(t, err, Nil())
+	AssertThat(t, resp.GetAssignments(), Len(1))
+	ExpectEq(t, resp.GetAssignments()[0].GetMatchId(), "m1")
+	ExpectThat(t, resp.GetAssignments()[0].GetPlayerIds(), ElementsAre("alice", "bob"))
+	ExpectEq(t, resp.GetAssignments()[0].GetConfig().GetNoSpectators(), true)
+
+	// A draining server is given no more matches.
+	req.SetDraining(true)
+	_, err = client.Heartbeat(ctx, req)
+	AssertThat(t, err, Nil())
+	_, err = f.Allocate(ctx, &queue.Match{ID: "m2", GameMode: "holdem"})
+	ExpectThat(t, err, ErrorIs(fleet.ErrNoCapacity))
+}
+
+func TestHeartbeat_APIKey(t *testing.T) {
+	keys := apikey.NewManager(apikey.NewMemStore())
+	client := startFleetServer(t, fleet.NewFleet(time.Minute), session.NewStore(), nil, WithAPIKeys(keys))
+	_, fleetKey, err := keys.Issue(context.Background(), "game servers", apikey.ScopeFleet)
+	AssertThat(t, err, Nil())
+	_, matchesKey, err := keys.Issue(context.Background(), "results", apikey.ScopeMatches)
+	AssertThat(t, err, Nil())
+	req := pb.HeartbeatRequest_builder{
+		ServerId: proto.String("s1"),
+		Address:  proto.String("10.0.0.1:7000"),
+		Capacity: proto.Int32(4),
+	}.Build()
+
+	_, err = client.Heartbeat(withToken(fleetKey), req)
+	ExpectThat(t, err, Nil())
+	_, err = client.Heartbeat(withToken(matchesKey), req)
+	ExpectEq(t, status.Code(err), codes.PermissionDenied)
+	_, err = client.Heartbeat(withToken("sfk_bogus_key"), req)
+	ExpectEq(t, status.Code(err), codes.Unauthenticated)
+}
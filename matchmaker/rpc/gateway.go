@@ -0,0 +1,233 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/jfmatt/snapfold/lib/protocol"
+	"github.com/jfmatt/snapfold/lib/tracing"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+// maxGatewayRequest is the largest request body the gateway reads.
+const maxGatewayRequest = 1 << 20
+
+// NewGateway returns an HTTP handler that serves the same services as
+// NewServer, with the same authentication, to clients that speak JSON
+// rather than gRPC, such as browsers.
+//
+// Each method is at POST /rpc/{service}/{method}, such as
+// /rpc/snapfold.gamedef.MatchmakerService/Enqueue. The request body is the
+// request message in the protobuf JSON mapping, or empty for an empty
+// message, and the Authorization and Snapfold-Protocol headers carry what
+// the metadata of the same names would. A unary method replies with its
+// response message; a server-streaming method replies with a
+// text/event-stream of them, one per data event. Errors reply with
+// {"error": message} and the HTTP status closest to the gRPC code, or, once
+// a stream has started, end it with an error event. Clients too old for the
+// server get 426 Upgrade Required, as from the HTTP API.
+func NewGateway(l *lobby.Lobby, sessions *session.Store, opts ...ServerOption) http.Handler {
+	auth, services := newServices(l, sessions, opts)
+	g := &gateway{auth: auth, methods: map[string]gatewayMethod{}}
+	for _, s := range services {
+		for i := range s.desc.Methods {
+			m := &s.desc.Methods[i]
+			g.methods["/"+s.desc.ServiceName+"/"+m.MethodName] = gatewayMethod{impl: s.impl, unary: m}
+		}
+		for i := range s.desc.Streams {
+			// Clients can't stream requests over a single HTTP request.
+			if m := &s.desc.Streams[i]; !m.ClientStreams {
+				g.methods["/"+s.desc.ServiceName+"/"+m.StreamName] = gatewayMethod{impl: s.impl, stream: m}
+			}
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /rpc/{service}/{method}", g.serve)
+	return protocol.Handler(tracing.Handler(mux))
+}
+
+type gateway struct {
+	auth authenticator
+
+	// Keyed by full method name, such as
+	// /snapfold.gamedef.MatchmakerService/Enqueue.
+	methods map[string]gatewayMethod
+}
+
+// gatewayMethod is a method the gateway serves: unary or server-streaming.
+type gatewayMethod struct {
+	impl   any
+	unary  *grpc.MethodDesc
+	stream *grpc.StreamDesc
+}
+
+func (g *gateway) serve(w http.ResponseWriter, r *http.Request) {
+	fullMethod := "/" + r.PathValue("service") + "/" + r.PathValue("method")
+	m, ok := g.methods[fullMethod]
+	if !ok {
+		writeGatewayError(w, status.Errorf(codes.Unimplemented, "unknown method %s", fullMethod))
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxGatewayRequest))
+	if err != nil {
+		writeGatewayError(w, status.Error(codes.InvalidArgument, err.Error()))
+		return
+	}
+	md := metadata.MD{}
+	for _, h := range []string{"Authorization", protocol.Header} {
+		if v := r.Header.Values(h); len(v) > 0 {
+			md.Set(h, v...)
+		}
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+
+	if m.stream != nil {
+		ss := &eventStream{ctx: ctx, w: w, body: body}
+		info := &grpc.StreamServerInfo{FullMethod: fullMethod, IsServerStream: true}
+		if err := g.auth.stream(m.impl, ss, info, m.stream.Handler); err != nil {
+			ss.fail(err)
+		}
+		return
+	}
+	resp, err := m.unary.Handler(m.impl, ctx, func(req any) error {
+		return decodeRequest(body, req)
+	}, g.auth.unary)
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	out, err := protojson.Marshal(resp.(proto.Message))
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// decodeRequest decodes a request message from its JSON mapping. An empty
+// body is an empty message.
+func decodeRequest(body []byte, req any) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if err := protojson.Unmarshal(body, req.(proto.Message)); err != nil {
+		return status.Errorf(codes.InvalidArgument, "decoding request: %v", err)
+	}
+	return nil
+}
+
+// writeGatewayError replies with err's message and the HTTP status for its
+// gRPC code.
+func writeGatewayError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(st.Code()))
+	json.NewEncoder(w).Encode(map[string]string{"error": st.Message()})
+}
+
+// httpStatus returns the HTTP status that best matches a gRPC code.
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Client Closed Request, as nginx reports it.
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// eventStream is the server side of a server-streaming call made through the
+// gateway. It receives the one request message from the request body and
+// sends each response message as a server-sent event.
+type eventStream struct {
+	ctx  context.Context
+	w    http.ResponseWriter
+	body []byte
+
+	received bool
+	started  bool
+}
+
+func (s *eventStream) Context() context.Context { return s.ctx }
+
+// Headers and trailers have nowhere to go: the response's headers are sent
+// with the first message.
+func (s *eventStream) SetHeader(metadata.MD) error  { return nil }
+func (s *eventStream) SendHeader(metadata.MD) error { return nil }
+func (s *eventStream) SetTrailer(metadata.MD)       {}
+
+func (s *eventStream) RecvMsg(m any) error {
+	if s.received {
+		return io.EOF
+	}
+	s.received = true
+	return decodeRequest(s.body, m)
+}
+
+func (s *eventStream) SendMsg(m any) error {
+	out, err := protojson.Marshal(m.(proto.Message))
+	if err != nil {
+		return err
+	}
+	s.start()
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", out); err != nil {
+		return err
+	}
+	return http.NewResponseController(s.w).Flush()
+}
+
+func (s *eventStream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.WriteHeader(http.StatusOK)
+}
+
+// fail ends the call with err: as an ordinary error reply if nothing has
+// been sent, and otherwise as an error event.
+func (s *eventStream) fail(err error) {
+	if !s.started {
+		writeGatewayError(s.w, err)
+		return
+	}
+	out, _ := json.Marshal(map[string]string{"error": status.Convert(err).Message()})
+	fmt.Fprintf(s.w, "event: error\ndata: %s\n\n", out)
+	http.NewResponseController(s.w).Flush()
+}
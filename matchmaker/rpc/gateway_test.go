@@ -0,0 +1,141 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+// post calls a matchmaker method through the gateway at url.
+func post(t *testing.T, url, method, token, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url+"/rpc/snapfold.gamedef.MatchmakerService/"+method, strings.NewReader(body))
+	AssertThat(t, err, Nil())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	AssertThat(t, err, Nil())
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decode(t *testing.T, resp *http.Response, m proto.Message) {
+	t.Helper()
+	body, err := io.ReadAll(resp.Body)
+	AssertThat(t, err, Nil())
+	AssertThat(t, protojson.Unmarshal(body, m), Nil())
+}
+
+func errorOf(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	var body struct{ Error string }
+	AssertThat(t, json.NewDecoder(resp.Body).Decode(&body), Nil())
+	return body.Error
+}
+
+func TestGateway_Unary(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewGateway(lobby.New(q, party.NewManager(), nil), sessions))
+	defer srv.Close()
+	token := sessions.Create("alice")
+
+	resp := post(t, srv.URL, "Enqueue", token, `{"gameMode": "holdem"}`)
+	AssertEq(t, resp.StatusCode, http.StatusOK)
+	ExpectEq(t, resp.Header.Get("Content-Type"), "application/json")
+	var enqueued pb.EnqueueResponse
+	decode(t, resp, &enqueued)
+	ExpectEq(t, enqueued.GetTicket().GetPlayerId(), "alice")
+	ExpectEq(t, q.Len(), 1)
+
+	// Field names in the proto's own spelling are accepted too.
+	resp = post(t, srv.URL, "CancelTicket", token, `{"ticket_id": "`+enqueued.GetTicket().GetId()+`"}`)
+	ExpectEq(t, resp.StatusCode, http.StatusOK)
+	ExpectEq(t, q.Len(), 0)
+
+	// An empty body is an empty request.
+	resp = post(t, srv.URL, "ListRegions", token, "")
+	ExpectEq(t, resp.StatusCode, http.StatusOK)
+}
+
+func TestGateway_Errors(t *testing.T) {
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewGateway(lobby.New(queue.New(), party.NewManager(), nil), sessions))
+	defer srv.Close()
+	token := sessions.Create("alice")
+
+	for _, tc := range []struct {
+		name, method, token, body string
+		status                    int
+		error                     string
+	}{
+		{"no token", "Enqueue", "", `{"gameMode": "holdem"}`, http.StatusUnauthorized, "missing bearer token"},
+		{"bad token", "Enqueue", "nope", `{"gameMode": "holdem"}`, http.StatusUnauthorized, "invalid session"},
+		{"invalid argument", "Enqueue", token, `{}`, http.StatusBadRequest, "game_mode is required"},
+		{"not found", "GetTicket", token, `{"ticketId": "t-missing"}`, http.StatusNotFound, ""},
+		{"unknown method", "Explode", token, `{}`, http.StatusNotImplemented, "unknown method /snapfold.gamedef.MatchmakerService/Explode"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := post(t, srv.URL, tc.method, tc.token, tc.body)
+			ExpectEq(t, resp.StatusCode, tc.status)
+			if msg := errorOf(t, resp); tc.error != "" {
+				ExpectEq(t, msg, tc.error)
+			}
+		})
+	}
+
+	resp := post(t, srv.URL, "Enqueue", token, `{"gameMode": `)
+	ExpectEq(t, resp.StatusCode, http.StatusBadRequest)
+	ExpectThat(t, errorOf(t, resp), HasSubstr("decoding request"))
+}
+
+func TestGateway_Stream(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	srv := httptest.NewServer(NewGateway(lobby.New(q, party.NewManager(), nil), sessions))
+	defer srv.Close()
+	token := sessions.Create("alice")
+
+	resp := post(t, srv.URL, "Enqueue", token, `{"gameMode": "holdem"}`)
+	var enqueued pb.EnqueueResponse
+	decode(t, resp, &enqueued)
+	id := enqueued.GetTicket().GetId()
+
+	resp = post(t, srv.URL, "WatchTicket", token, `{"ticketId": "`+id+`"}`)
+	AssertEq(t, resp.StatusCode, http.StatusOK)
+	ExpectEq(t, resp.Header.Get("Content-Type"), "text/event-stream")
+	events := bufio.NewScanner(resp.Body)
+	next := func() *pb.TicketUpdate {
+		t.Helper()
+		for events.Scan() {
+			if data, ok := strings.CutPrefix(events.Text(), "data: "); ok {
+				var u pb.TicketUpdate
+				AssertThat(t, protojson.Unmarshal([]byte(data), &u), Nil())
+				return &u
+			}
+		}
+		t.Fatalf("stream ended: %v", events.Err())
+		return nil
+	}
+	ExpectEq(t, next().GetState(), pb.TicketState_QUEUED)
+
+	_, err := q.Cancel(context.Background(), id)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, next().GetState(), pb.TicketState_CANCELED)
+}
@@ -0,0 +1,583 @@
+// Package rpc implements the matchmaker's gRPC interface.
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/idempotency"
+	"github.com/jfmatt/snapfold/lib/protocol"
+	"github.com/jfmatt/snapfold/lib/tracing"
+	"github.com/jfmatt/snapfold/matchmaker/apikey"
+	"github.com/jfmatt/snapfold/matchmaker/fleet"
+	"github.com/jfmatt/snapfold/matchmaker/history"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/paging"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/penalty"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/seat"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+// Service implements pb.MatchmakerServiceServer on top of a lobby.
+type Service struct {
+	pb.UnimplementedMatchmakerServiceServer
+
+	lobby       *lobby.Lobby
+	queue       *queue.Queue
+	idempotency *idempotency.Keeper
+}
+
+// NewService returns a Service that places players into l's queue.
+func NewService(l *lobby.Lobby) *Service {
+	return &Service{
+		lobby:       l,
+		queue:       l.Queue(),
+		idempotency: idempotency.New(idempotency.NewMemStore(), idempotency.DefaultTTL),
+	}
+}
+
+// ServerOption configures the server returned by NewServer.
+type ServerOption func(*serverConfig)
+
+type serverConfig struct {
+	fleet         *fleet.Fleet
+	internalToken string
+	apiKeys       *apikey.Manager
+	idempotency   *idempotency.Keeper
+}
+
+// WithFleet registers the fleet service, through which game servers holding
+// internalToken, or an API key with the fleet scope, join f.
+func WithFleet(f *fleet.Fleet, internalToken string) ServerOption {
+	return func(c *serverConfig) {
+		c.fleet = f
+		c.internalToken = internalToken
+	}
+}
+
+// WithAPIKeys accepts keys issued by keys on calls to internal services.
+func WithAPIKeys(keys *apikey.Manager) ServerOption {
+	return func(c *serverConfig) { c.apiKeys = keys }
+}
+
+// WithIdempotency remembers the idempotency keys of calls to Enqueue in k,
+// rather than in memory, which only suits a single matchmaker.
+func WithIdempotency(k *idempotency.Keeper) ServerOption {
+	return func(c *serverConfig) { c.idempotency = k }
+}
+
+// NewServer returns a gRPC server with the matchmaker service registered and
+// session authentication applied to every call.
+func NewServer(l *lobby.Lobby, sessions *session.Store, opts ...ServerOption) *grpc.Server {
+	auth, services := newServices(l, sessions, opts)
+	srv := grpc.NewServer(
+		grpc.StatsHandler(tracing.ServerHandler()),
+		grpc.UnaryInterceptor(auth.unary),
+		grpc.StreamInterceptor(auth.stream),
+	)
+	for _, s := range services {
+		srv.RegisterService(s.desc, s.impl)
+	}
+	return srv
+}
+
+// service is a service implementation and its description.
+type service struct {
+	desc *grpc.ServiceDesc
+	impl any
+}
+
+// newServices returns the services that opts configure and how to
+// authenticate calls to them.
+func newServices(l *lobby.Lobby, sessions *session.Store, opts []ServerOption) (authenticator, []service) {
+	var cfg serverConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	auth := authenticator{sessions: sessions, internalToken: cfg.internalToken, apiKeys: cfg.apiKeys}
+	svc := NewService(l)
+	if cfg.idempotency != nil {
+		svc.idempotency = cfg.idempotency
+	}
+	services := []service{{&pb.MatchmakerService_ServiceDesc, svc}}
+	if cfg.fleet != nil {
+		services = append(services, service{&pb.FleetService_ServiceDesc, NewFleetService(cfg.fleet, l)})
+	}
+	return auth, services
+}
+
+func (s *Service) Enqueue(ctx context.Context, req *pb.EnqueueRequest) (*pb.EnqueueResponse, error) {
+	playerID, _ := session.PlayerFrom(ctx)
+	if req.GetGameMode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "game_mode is required")
+	}
+
+	unkeyed := proto.CloneOf(req)
+	unkeyed.ClearIdempotencyKey()
+	fingerprint, err := proto.MarshalOptions{Deterministic: true}.Marshal(unkeyed)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	b, _, err := s.idempotency.Do(ctx, playerID, req.GetIdempotencyKey(), idempotency.Fingerprint([]byte("Enqueue"), fingerprint), func() ([]byte, bool, error) {
+		t, err := s.lobby.Enqueue(ctx, playerID, req.GetGameMode())
+		if err != nil {
+			return nil, false, err
+		}
+		_, pos, _ := s.queue.Get(t.ID)
+		b, err := proto.Marshal(pb.EnqueueResponse_builder{
+			Ticket:   ticketProto(t),
+			Position: proto.Int32(int32(pos)),
+		}.Build())
+		return b, true, err
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	resp := &pb.EnqueueResponse{}
+	if err := proto.Unmarshal(b, resp); err != nil {
+		return nil, statusError(err)
+	}
+	return resp, nil
+}
+
+func (s *Service) AcceptMatch(ctx context.Context, req *pb.AcceptMatchRequest) (*pb.AcceptMatchResponse, error) {
+	playerID, _ := session.PlayerFrom(ctx)
+	if err := s.queue.Accept(ctx, req.GetMatchId(), playerID); err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.AcceptMatchResponse{}, nil
+}
+
+func (s *Service) DeclineMatch(ctx context.Context, req *pb.DeclineMatchRequest) (*pb.DeclineMatchResponse, error) {
+	playerID, _ := session.PlayerFrom(ctx)
+	if err := s.queue.Decline(ctx, req.GetMatchId(), playerID); err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.DeclineMatchResponse{}, nil
+}
+
+func (s *Service) Rejoin(ctx context.Context, req *pb.RejoinRequest) (*pb.RejoinResponse, error) {
+	playerID, _ := session.PlayerFrom(ctx)
+	st, err := s.lobby.Seats().Rejoin(ctx, playerID)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	b := pb.Seat_builder{
+		TableId:  proto.String(st.TableID),
+		MatchId:  proto.String(st.MatchID),
+		GameMode: proto.String(st.GameMode),
+	}
+	if st.Region != "" {
+		b.Region = proto.String(st.Region)
+	}
+	if st.Address != "" {
+		b.ServerAddress = proto.String(st.Address)
+	}
+	return pb.RejoinResponse_builder{Seat: b.Build()}.Build(), nil
+}
+
+func (s *Service) CancelTicket(ctx context.Context, req *pb.CancelTicketRequest) (*pb.CancelTicketResponse, error) {
+	playerID, _ := session.PlayerFrom(ctx)
+	t, err := s.lobby.Cancel(ctx, playerID, req.GetTicketId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return pb.CancelTicketResponse_builder{Ticket: ticketProto(t)}.Build(), nil
+}
+
+func (s *Service) GetTicket(ctx context.Context, req *pb.GetTicketRequest) (*pb.TicketUpdate, error) {
+	playerID, _ := session.PlayerFrom(ctx)
+	rec, pos, err := s.lobby.Ticket(ctx, playerID, req.GetTicketId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return recordProto(rec, pos), nil
+}
+
+func (s *Service) WatchTicket(req *pb.WatchTicketRequest, stream grpc.ServerStreamingServer[pb.TicketUpdate]) error {
+	ctx := stream.Context()
+	playerID, _ := session.PlayerFrom(ctx)
+	rec, _, err := s.lobby.Ticket(ctx, playerID, req.GetTicketId())
+	if err != nil {
+		return statusError(err)
+	}
+	if rec.State != queue.StateQueued {
+		// Report how the search ended rather than streaming nothing.
+		return stream.Send(recordProto(rec, 0))
+	}
+	updates, stop, err := s.queue.Watch(req.GetTicketId())
+	if err != nil {
+		return statusError(err)
+	}
+	defer stop()
+	hello := protocol.FromContext(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case u, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			if u.ReadyCheck != nil && !hello.Has(protocol.ReadyChecks) {
+				// The client can't answer ready checks, so they are
+				// accepted for it. If that fails, the ready check runs out
+				// as if it had been ignored.
+				if !slices.Contains(u.ReadyCheck.Accepted, playerID) {
+					s.queue.Accept(ctx, u.ReadyCheck.Match.ID, playerID)
+				}
+				continue
+			}
+			if err := stream.Send(updateProto(u)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Service) ListRegions(ctx context.Context, req *pb.ListRegionsRequest) (*pb.ListRegionsResponse, error) {
+	regions := s.lobby.Regions()
+	var resp []*pb.Region
+	for _, name := range slices.Sorted(maps.Keys(regions)) {
+		resp = append(resp, pb.Region_builder{
+			Name:         proto.String(name),
+			ProbeAddress: proto.String(regions[name]),
+		}.Build())
+	}
+	return pb.ListRegionsResponse_builder{Regions: resp}.Build(), nil
+}
+
+func (s *Service) ReportLatency(ctx context.Context, req *pb.ReportLatencyRequest) (*pb.ReportLatencyResponse, error) {
+	playerID, _ := session.PlayerFrom(ctx)
+	if len(req.GetRtts()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "rtts is required")
+	}
+	rtts := queue.Latencies{}
+	for name, rtt := range req.GetRtts() {
+		rtts[name] = rtt.AsDuration()
+	}
+	if err := s.lobby.ReportLatency(ctx, playerID, req.GetTicketId(), rtts); err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.ReportLatencyResponse{}, nil
+}
+
+func (s *Service) GetWaitEstimate(ctx context.Context, req *pb.GetWaitEstimateRequest) (*pb.WaitEstimate, error) {
+	if req.GetGameMode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "game_mode is required")
+	}
+	e, err := s.lobby.EstimateWait(req.GetGameMode(), req.GetRegion())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	b := pb.WaitEstimate_builder{
+		GameMode: proto.String(e.GameMode),
+		Samples:  proto.Int32(int32(e.Samples)),
+	}
+	if e.Region != "" {
+		b.Region = proto.String(e.Region)
+	}
+	if e.Samples > 0 {
+		b.Median = durationpb.New(e.Median)
+		b.P90 = durationpb.New(e.P90)
+	}
+	return b.Build(), nil
+}
+
+func (s *Service) ListMatches(ctx context.Context, req *pb.ListMatchesRequest) (*pb.ListMatchesResponse, error) {
+	playerID, _ := session.PlayerFrom(ctx)
+	r, err := history.Matches.Parse(int(req.GetPageSize()), req.GetPageToken(), req.GetSort(),
+		map[string]string{"game_mode": req.GetGameMode()})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	p, err := s.lobby.History().List(ctx, playerID, r)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	b := pb.ListMatchesResponse_builder{}
+	for _, m := range p.Items {
+		b.Matches = append(b.Matches, matchProto(m))
+	}
+	if p.NextToken != "" {
+		b.NextPageToken = proto.String(p.NextToken)
+	}
+	return b.Build(), nil
+}
+
+func (s *Service) GetMatch(ctx context.Context, req *pb.GetMatchRequest) (*pb.Match, error) {
+	m, err := s.lobby.History().Get(ctx, req.GetMatchId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return matchProto(m), nil
+}
+
+// statusError converts an error from the matchmaking packages to a gRPC
+// status error with a matching code.
+func statusError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, queue.ErrNotFound),
+		errors.Is(err, queue.ErrNoReadyCheck),
+		errors.Is(err, party.ErrNotFound),
+		errors.Is(err, party.ErrNotInParty),
+		errors.Is(err, seat.ErrNotSeated),
+		errors.Is(err, history.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, queue.ErrAlreadyQueued),
+		errors.Is(err, party.ErrInParty):
+		code = codes.AlreadyExists
+	case errors.Is(err, queue.ErrClosed),
+		errors.Is(err, seat.ErrGraceExpired),
+		errors.Is(err, penalty.ErrPenalized):
+		code = codes.FailedPrecondition
+	case errors.Is(err, party.ErrNotLeader),
+		errors.Is(err, party.ErrNotInvited):
+		code = codes.PermissionDenied
+	case errors.Is(err, lobby.ErrUnknownGameMode),
+		errors.Is(err, lobby.ErrPartyTooLarge),
+		errors.Is(err, lobby.ErrUnknownRegion),
+		errors.Is(err, lobby.ErrInvalidLatency),
+		errors.Is(err, party.ErrPartyFull),
+		errors.Is(err, party.ErrInviteSelf),
+		errors.Is(err, paging.ErrInvalidToken),
+		errors.Is(err, paging.ErrInvalidSize),
+		errors.Is(err, paging.ErrInvalidSort),
+		errors.Is(err, idempotency.ErrMismatch),
+		errors.Is(err, idempotency.ErrInvalidKey):
+		code = codes.InvalidArgument
+	case errors.Is(err, idempotency.ErrInProgress):
+		code = codes.Aborted
+	}
+	return status.Error(code, err.Error())
+}
+
+func matchProto(m history.Match) *pb.Match {
+	b := pb.Match_builder{
+		Id:          proto.String(m.ID),
+		GameMode:    proto.String(m.GameMode),
+		PlayerIds:   m.Players,
+		TableConfig: m.Config,
+		CreatedAt:   timestamppb.New(m.CreatedAt),
+	}
+	if m.Region != "" {
+		b.Region = proto.String(m.Region)
+	}
+	if m.TableID != "" {
+		b.TableId = proto.String(m.TableID)
+	}
+	if m.Bots > 0 {
+		b.Bots = proto.Int32(int32(m.Bots))
+	}
+	if m.Ended() {
+		b.EndedAt = timestamppb.New(m.EndedAt)
+		b.Places = map[string]int32{}
+		for id, place := range m.Places {
+			b.Places[id] = int32(place)
+		}
+	}
+	return b.Build()
+}
+
+func ticketProto(t *queue.Ticket) *pb.Ticket {
+	b := pb.Ticket_builder{
+		Id:        proto.String(t.ID),
+		PlayerId:  proto.String(t.PlayerID),
+		MemberIds: t.Members,
+		GameMode:  proto.String(t.GameMode),
+		CreatedAt: timestamppb.New(t.CreatedAt),
+	}
+	if t.PartyID != "" {
+		b.PartyId = proto.String(t.PartyID)
+	}
+	if t.Priority != queue.PriorityNormal {
+		b.Priority = pb.TicketPriority(t.Priority).Enum()
+	}
+	return b.Build()
+}
+
+// recordProto describes a ticket's state as looked up, rather than as
+// streamed by the queue.
+func recordProto(r queue.Record, pos int) *pb.TicketUpdate {
+	b := pb.TicketUpdate_builder{
+		Ticket: ticketProto(&r.Ticket),
+		State:  pb.TicketState(r.State).Enum(),
+	}
+	switch r.State {
+	case queue.StateQueued:
+		b.Position = proto.Int32(int32(pos))
+	case queue.StateMatched:
+		b.MatchId = proto.String(r.MatchID)
+	}
+	return b.Build()
+}
+
+func updateProto(u queue.Update) *pb.TicketUpdate {
+	b := pb.TicketUpdate_builder{
+		Ticket: ticketProto(u.Ticket),
+		State:  pb.TicketState(u.State()).Enum(),
+	}
+	switch {
+	case u.Canceled, u.Expired:
+	case u.ReadyCheck != nil:
+		b.State = pb.TicketState_READY_CHECK.Enum()
+		b.MatchId = proto.String(u.ReadyCheck.Match.ID)
+		b.AcceptDeadline = timestamppb.New(u.ReadyCheck.Deadline)
+		b.AcceptedPlayerIds = u.ReadyCheck.Accepted
+	case u.Match != nil:
+		b.MatchId = proto.String(u.Match.ID)
+		if u.Match.Region != "" {
+			b.Region = proto.String(u.Match.Region)
+		}
+		if u.Match.TableID != "" {
+			b.TableId = proto.String(u.Match.TableID)
+		}
+		if u.Match.Bots > 0 {
+			b.Bots = proto.Int32(int32(u.Match.Bots))
+		}
+	default:
+		b.Position = proto.Int32(int32(u.Position))
+		if u.EstimatedWait > 0 {
+			b.EstimatedWait = durationpb.New(u.EstimatedWait)
+		}
+	}
+	return b.Build()
+}
+
+// authenticator checks the bearer token on every call: a session token for
+// the matchmaker service, or the internal token or an API key for services
+// that other snapfold services call.
+type authenticator struct {
+	sessions      *session.Store
+	internalToken string
+	apiKeys       *apikey.Manager
+	idempotency   *idempotency.Keeper
+}
+
+// authenticate resolves the bearer token in the incoming metadata to a
+// player and returns a context carrying the player ID. Calls to internal
+// services must carry the internal token or an API key with the fleet scope
+// instead, and their context is returned unchanged.
+func (a authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	internal := strings.HasPrefix(method, "/"+pb.FleetService_ServiceDesc.ServiceName+"/")
+	for _, header := range md.Get("authorization") {
+		token, ok := session.BearerToken(header)
+		if !ok {
+			continue
+		}
+		if internal {
+			return ctx, a.authenticateService(ctx, token, apikey.ScopeFleet)
+		}
+		if ctx, ok := a.sessions.Authenticate(ctx, token); ok {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid session")
+	}
+	return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+}
+
+// authenticateService checks that token is the internal token or an API key
+// with the scope.
+func (a authenticator) authenticateService(ctx context.Context, token string, scope apikey.Scope) error {
+	if a.internalToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.internalToken)) == 1 {
+		return nil
+	}
+	if a.apiKeys == nil {
+		return status.Error(codes.Unauthenticated, "invalid internal token")
+	}
+	_, err := a.apiKeys.Verify(ctx, token, scope)
+	switch {
+	case errors.Is(err, apikey.ErrInvalidKey):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, apikey.ErrScope):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case err != nil:
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// negotiate checks the protocol version in the incoming metadata and
+// returns a context carrying the client's Hello.
+func negotiate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var hello string
+	if v := md.Get(protocol.Header); len(v) > 0 {
+		hello = v[0]
+	}
+	h, err := protocol.Negotiate(hello)
+	switch {
+	case errors.Is(err, protocol.ErrUnsupported):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return protocol.NewContext(ctx, h), nil
+}
+
+// serverHello is the header every reply carries.
+var serverHello = metadata.Pairs(protocol.Header, strconv.Itoa(protocol.Version))
+
+func (a authenticator) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	grpc.SetHeader(ctx, serverHello)
+	ctx, err := negotiate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// stream authenticates a streaming call and ends it if its session is
+// revoked.
+func (a authenticator) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ss.SetHeader(serverHello)
+	ctx, err := negotiate(ss.Context())
+	if err != nil {
+		return err
+	}
+	ctx, err = a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return err
+	}
+	ctx, stop := a.sessions.Watch(ctx)
+	defer stop()
+	err = handler(srv, &authedStream{ServerStream: ss, ctx: ctx})
+	if errors.Is(context.Cause(ctx), session.ErrRevoked) {
+		return status.Error(codes.Unauthenticated, session.ErrRevoked.Error())
+	}
+	return err
+}
+
+// authedStream overrides the context of a server stream with one carrying
+// the authenticated player.
+type authedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,420 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/matchmaker/lobby"
+	"github.com/jfmatt/snapfold/matchmaker/party"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+func startServer(t *testing.T, l *lobby.Lobby, sessions *session.Store) pb.MatchmakerServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := NewServer(l, sessions)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	AssertThat(t, err, Nil())
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewMatchmakerServiceClient(conn)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestEnqueueAndCancel(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	client := startServer(t, lobby.New(q, party.NewManager(), nil), sessions)
+	ctx := withToken(sessions.Create("alice"))
+
+	resp, err := client.Enqueue(ctx, pb.EnqueueRequest_builder{GameMode: proto.String("holdem")}.Build())
+	AssertThat(t, err, Nil())
+	ExpectEq(t, resp.GetTicket().GetPlayerId(), "alice")
+	ExpectEq(t, resp.GetPosition(), int32(0))
+	ExpectEq(t, q.Len(), 1)
+
+	_, err = client.Enqueue(ctx, pb.EnqueueRequest_builder{GameMode: proto.String("holdem")}.Build())
+	ExpectEq(t, status.Code(err), codes.AlreadyExists)
+
+	// Other players can't cancel the ticket.
+	other := withToken(sessions.Create("bob"))
+	_, err = client.CancelTicket(other, pb.CancelTicketRequest_builder{TicketId: proto.String(resp.GetTicket().GetId())}.Build())
+	ExpectEq(t, status.Code(err), codes.NotFound)
+
+	_, err = client.CancelTicket(ctx, pb.CancelTicketRequest_builder{TicketId: proto.String(resp.GetTicket().GetId())}.Build())
+	ExpectThat(t, err, Nil())
+	ExpectEq(t, q.Len(), 0)
+
+	// Canceling again succeeds.
+	_, err = client.CancelTicket(ctx, pb.CancelTicketRequest_builder{TicketId: proto.String(resp.GetTicket().GetId())}.Build())
+	ExpectThat(t, err, Nil())
+
+	u, err := client.GetTicket(ctx, pb.GetTicketRequest_builder{TicketId: proto.String(resp.GetTicket().GetId())}.Build())
+	AssertThat(t, err, Nil())
+	ExpectEq(t, u.GetState(), pb.TicketState_CANCELED)
+}
+
+func TestGetTicket_Matched(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	client := startServer(t, lobby.New(q, party.NewManager(), nil), sessions)
+	ctx := withToken(sessions.Create("alice"))
+
+	resp, err := client.Enqueue(ctx, pb.EnqueueRequest_builder{GameMode: proto.String("holdem")}.Build())
+	AssertThat(t, err, Nil())
+	id := proto.String(resp.GetTicket().GetId())
+	_, err = q.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+
+	u, err := client.GetTicket(ctx, pb.GetTicketRequest_builder{TicketId: id}.Build())
+	AssertThat(t, err, Nil())
+	ExpectEq(t, u.GetState(), pb.TicketState_MATCHED)
+	ExpectEq(t, u.GetMatchId(), matches[0].ID)
+
+	_, err = client.CancelTicket(ctx, pb.CancelTicketRequest_builder{TicketId: id}.Build())
+	ExpectEq(t, status.Code(err), codes.FailedPrecondition)
+
+	// Watching a closed ticket reports its final state and ends.
+	stream, err := client.WatchTicket(ctx, pb.WatchTicketRequest_builder{TicketId: id}.Build())
+	AssertThat(t, err, Nil())
+	u, err = stream.Recv()
+	AssertThat(t, err, Nil())
+	ExpectEq(t, u.GetState(), pb.TicketState_MATCHED)
+	_, err = stream.Recv()
+	ExpectEq(t, err, io.EOF)
+}
+
+func TestEnqueue_Party(t *testing.T) {
+	parties := party.NewManager()
+	sessions := session.NewStore()
+	client := startServer(t, lobby.New(queue.New(), parties, nil), sessions)
+	p, err := parties.Create("alice")
+	AssertThat(t, err, Nil())
+	_, err = parties.Invite(p.ID, "alice", "bob")
+	AssertThat(t, err, Nil())
+	_, err = parties.Join(p.ID, "bob")
+	AssertThat(t, err, Nil())
+
+	req := pb.EnqueueRequest_builder{GameMode: proto.String("holdem")}.Build()
+	_, err = client.Enqueue(withToken(sessions.Create("bob")), req)
+	ExpectEq(t, status.Code(err), codes.PermissionDenied)
+
+	resp, err := client.Enqueue(withToken(sessions.Create("alice")), req)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, resp.GetTicket().GetPartyId(), p.ID)
+	ExpectThat(t, resp.GetTicket().GetMemberIds(), ElementsAre("alice", "bob"))
+}
+
+func TestEnqueue_IdempotencyKey(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	client := startServer(t, lobby.New(q, party.NewManager(), nil), sessions)
+	ctx := withToken(sessions.Create("alice"))
+
+	req := pb.EnqueueRequest_builder{GameMode: proto.String("holdem"), IdempotencyKey: proto.String("k1")}.Build()
+	first, err := client.Enqueue(ctx, req)
+	AssertThat(t, err, Nil())
+	retried, err := client.Enqueue(ctx, req)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, retried.GetTicket().GetId(), first.GetTicket().GetId())
+	ExpectEq(t, q.Len(), 1)
+
+	_, err = client.Enqueue(ctx, pb.EnqueueRequest_builder{GameMode: proto.String("omaha"), IdempotencyKey: proto.String("k1")}.Build())
+	ExpectEq(t, status.Code(err), codes.InvalidArgument)
+}
+
+func TestEnqueue_UnknownGameMode(t *testing.T) {
+	sessions := session.NewStore()
+	client := startServer(t, lobby.New(queue.New(), party.NewManager(), map[string]*pb.TableConfig{"holdem": {}}), sessions)
+
+	_, err := client.Enqueue(withToken(sessions.Create("alice")), pb.EnqueueRequest_builder{GameMode: proto.String("omaha")}.Build())
+	ExpectEq(t, status.Code(err), codes.InvalidArgument)
+}
+
+func TestWatchTicket(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	client := startServer(t, lobby.New(q, party.NewManager(), nil), sessions)
+	ctx := withToken(sessions.Create("alice"))
+
+	resp, err := client.Enqueue(ctx, pb.EnqueueRequest_builder{GameMode: proto.String("holdem")}.Build())
+	AssertThat(t, err, Nil())
+	id := resp.GetTicket().GetId()
+
+	stream, err := client.WatchTicket(ctx, pb.WatchTicketRequest_builder{TicketId: proto.String(id)}.Build())
+	AssertThat(t, err, Nil())
+
+	u, err := stream.Recv()
+	AssertThat(t, err, Nil())
+	ExpectEq(t, u.GetState(), pb.TicketState_QUEUED)
+
+	_, err = q.Cancel(ctx, id)
+	AssertThat(t, err, Nil())
+
+	u, err = stream.Recv()
+	AssertThat(t, err, Nil())
+	ExpectEq(t, u.GetState(), pb.TicketState_CANCELED)
+}
+
+func TestWatchTicket_SessionRevoked(t *testing.T) {
+	sessions := session.NewStore()
+	client := startServer(t, lobby.New(queue.New(), party.NewManager(), nil), sessions)
+	tokens, err := sessions.Login(context.Background(), "alice")
+	AssertThat(t, err, Nil())
+	ctx := withToken(tokens.Access)
+
+	resp, err := client.Enqueue(ctx, pb.EnqueueRequest_builder{GameMode: proto.String("holdem")}.Build())
+	AssertThat(t, err, Nil())
+	stream, err := client.WatchTicket(ctx, pb.WatchTicketRequest_builder{TicketId: proto.String(resp.GetTicket().GetId())}.Build())
+	AssertThat(t, err, Nil())
+	_, err = stream.Recv()
+	AssertThat(t, err, Nil())
+
+	AssertThat(t, sessions.LogoutAll(context.Background(), "alice"), Nil())
+	_, err = stream.Recv()
+	ExpectEq(t, status.Code(err), codes.Unauthenticated)
+	_, err = client.Enqueue(ctx, pb.EnqueueRequest_builder{GameMode: proto.String("holdem")}.Build())
+	ExpectEq(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestWatchTicket_Matched(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	client := startServer(t, lobby.New(q, party.NewManager(), nil), sessions)
+	ctx := withToken(sessions.Create("alice"))
+
+	resp, err := client.Enqueue(ctx, pb.EnqueueRequest_builder{GameMode: proto.String("holdem")}.Build())
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+
+	stream, err := client.WatchTicket(ctx, pb.WatchTicketRequest_builder{TicketId: proto.String(resp.GetTicket().GetId())}.Build())
+	AssertThat(t, err, Nil())
+	_, err = stream.Recv()
+	AssertThat(t, err, Nil())
+
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+
+	u, err := stream.Recv()
+	AssertThat(t, err, Nil())
+	ExpectEq(t, u.GetState(), pb.TicketState_MATCHED)
+	ExpectEq(t, u.GetMatchId(), matches[0].ID)
+}
+
+func TestReadyCheck(t *testing.T) {
+	q := queue.New(queue.WithReadyCheck(time.Minute))
+	sessions := session.NewStore()
+	client := startServer(t, lobby.New(q, party.NewManager(), nil), sessions)
+	ctx := withToken(sessions.Create("alice"))
+
+	resp, err := client.Enqueue(ctx, pb.EnqueueRequest_builder{GameMode: proto.String("holdem")}.Build())
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "bob", "holdem")
+	AssertThat(t, err, Nil())
+
+	stream, err := client.WatchTicket(ctx, pb.WatchTicketRequest_builder{TicketId: proto.String(resp.GetTicket().GetId())}.Build())
+	AssertThat(t, err, Nil())
+	_, err = stream.Recv()
+	AssertThat(t, err, Nil())
+
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, matches, Empty())
+	u, err := stream.Recv()
+	AssertThat(t, err, Nil())
+	ExpectEq(t, u.GetState(), pb.TicketState_READY_CHECK)
+	ExpectEq(t, u.HasAcceptDeadline(), true)
+	matchID := proto.String(u.GetMatchId())
+
+	_, err = client.AcceptMatch(ctx, pb.AcceptMatchRequest_builder{MatchId: matchID}.Build())
+	AssertThat(t, err, Nil())
+	u, err = stream.Recv()
+	AssertThat(t, err, Nil())
+	ExpectThat(t, u.GetAcceptedPlayerIds(), ElementsAre("alice"))
+
+	// Bob declines, so Alice goes back to the queue.
+	bob := withToken(sessions.Create("bob"))
+	_, err = client.DeclineMatch(bob, pb.DeclineMatchRequest_builder{MatchId: matchID}.Build())
+	AssertThat(t, err, Nil())
+	u, err = stream.Recv()
+	AssertThat(t, err, Nil())
+	ExpectEq(t, u.GetState(), pb.TicketState_QUEUED)
+	ExpectEq(t, u.GetTicket().GetPriority(), pb.TicketPriority_REQUEUE)
+
+	_, err = client.AcceptMatch(ctx, pb.AcceptMatchRequest_builder{MatchId: matchID}.Build())
+	ExpectEq(t, status.Code(err), codes.NotFound)
+}
+
+func TestRejoin(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	l := lobby.New(q, party.NewManager(), nil)
+	client := startServer(t, l, sessions)
+	ctx := withToken(sessions.Create("alice"))
+
+	_, err := client.Rejoin(ctx, &pb.RejoinRequest{})
+	ExpectEq(t, status.Code(err), codes.NotFound)
+
+	for _, p := range []string{"alice", "bob"} {
+		_, err := q.Enqueue(ctx, p, "holdem")
+		AssertThat(t, err, Nil())
+	}
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+	AssertThat(t, l.Seats().Assign(ctx, matches[0], "10.0.0.1:7000"), Nil())
+	_, err = l.Seats().Disconnect(ctx, matches[0].ID, "alice")
+	AssertThat(t, err, Nil())
+
+	resp, err := client.Rejoin(ctx, &pb.RejoinRequest{})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, resp.GetSeat().GetTableId(), matches[0].ID)
+	ExpectEq(t, resp.GetSeat().GetGameMode(), "holdem")
+	ExpectEq(t, resp.GetSeat().GetServerAddress(), "10.0.0.1:7000")
+}
+
+func TestRegionsAndLatency(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	l := lobby.New(q, party.NewManager(), nil, lobby.WithRegions(map[string]string{"us-east": "use.example.com:7000"}))
+	client := startServer(t, l, sessions)
+	ctx := withToken(sessions.Create("alice"))
+
+	regions, err := client.ListRegions(ctx, &pb.ListRegionsRequest{})
+	AssertThat(t, err, Nil())
+	AssertThat(t, regions.GetRegions(), Len(1))
+	ExpectEq(t, regions.GetRegions()[0].GetName(), "us-east")
+	ExpectEq(t, regions.GetRegions()[0].GetProbeAddress(), "use.example.com:7000")
+
+	resp, err := client.Enqueue(ctx, pb.EnqueueRequest_builder{GameMode: proto.String("holdem")}.Build())
+	AssertThat(t, err, Nil())
+	id := resp.GetTicket().GetId()
+
+	_, err = client.ReportLatency(ctx, pb.ReportLatencyRequest_builder{
+		TicketId: proto.String(id),
+		Rtts:     map[string]*durationpb.Duration{"mars": durationpb.New(time.Millisecond)},
+	}.Build())
+	ExpectEq(t, status.Code(err), codes.InvalidArgument)
+
+	_, err = client.ReportLatency(ctx, pb.ReportLatencyRequest_builder{
+		TicketId: proto.String(id),
+		Rtts:     map[string]*durationpb.Duration{"us-east": durationpb.New(20 * time.Millisecond)},
+	}.Build())
+	AssertThat(t, err, Nil())
+	ExpectEq(t, q.Latency(id), queue.Latencies{"us-east": 20 * time.Millisecond})
+}
+
+func TestGetWaitEstimate(t *testing.T) {
+	q := queue.New()
+	sessions := session.NewStore()
+	client := startServer(t, lobby.New(q, party.NewManager(), nil), sessions)
+	ctx := withToken(sessions.Create("alice"))
+
+	_, err := client.GetWaitEstimate(ctx, &pb.GetWaitEstimateRequest{})
+	ExpectEq(t, status.Code(err), codes.InvalidArgument)
+
+	req := pb.GetWaitEstimateRequest_builder{GameMode: proto.String("holdem")}.Build()
+	e, err := client.GetWaitEstimate(ctx, req)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, e.GetSamples(), int32(0))
+	ExpectEq(t, e.HasMedian(), false)
+
+	_, err = q.Enqueue(ctx, "a", "holdem")
+	AssertThat(t, err, Nil())
+	_, err = q.Enqueue(ctx, "b", "holdem")
+	AssertThat(t, err, Nil())
+	matches, err := q.FormMatches(ctx, 2)
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+
+	e, err = client.GetWaitEstimate(ctx, req)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, e.GetSamples(), int32(2))
+	ExpectEq(t, e.HasMedian(), true)
+}
+
+func TestMatchHistory(t *testing.T) {
+	q := queue.New()
+	l := lobby.New(q, party.NewManager(), nil)
+	sessions := session.NewStore()
+	client := startServer(t, l, sessions)
+	ctx := withToken(sessions.Create("alice"))
+
+	var ids []string
+	for range 3 {
+		_, err := q.Enqueue(ctx, "alice", "holdem")
+		AssertThat(t, err, Nil())
+		_, err = q.Enqueue(ctx, "bob", "holdem")
+		AssertThat(t, err, Nil())
+		matches, err := q.FormMatches(ctx, 2)
+		AssertThat(t, err, Nil())
+		AssertThat(t, matches, Len(1))
+		AssertThat(t, l.RecordMatch(ctx, matches[0]), Nil())
+		ids = append(ids, matches[0].ID)
+	}
+	AssertThat(t, l.History().Finish(ctx, ids[2], map[string]int{"alice": 2, "bob": 1}), Nil())
+
+	resp, err := client.ListMatches(ctx, pb.ListMatchesRequest_builder{PageSize: proto.Int32(2)}.Build())
+	AssertThat(t, err, Nil())
+	AssertThat(t, resp.GetMatches(), Len(2))
+	ExpectEq(t, resp.GetMatches()[0].GetId(), ids[2])
+	ExpectEq(t, resp.GetMatches()[0].GetPlaces()["bob"], int32(1))
+	ExpectEq(t, resp.GetMatches()[1].HasEndedAt(), false)
+	resp, err = client.ListMatches(ctx, pb.ListMatchesRequest_builder{
+		PageSize:  proto.Int32(2),
+		PageToken: proto.String(resp.GetNextPageToken()),
+	}.Build())
+	AssertThat(t, err, Nil())
+	AssertThat(t, resp.GetMatches(), Len(1))
+	ExpectEq(t, resp.GetMatches()[0].GetId(), ids[0])
+	ExpectEq(t, resp.HasNextPageToken(), false)
+
+	_, err = client.ListMatches(ctx, pb.ListMatchesRequest_builder{PageToken: proto.String("bogus")}.Build())
+	ExpectEq(t, status.Code(err), codes.InvalidArgument)
+
+	m, err := client.GetMatch(ctx, pb.GetMatchRequest_builder{MatchId: proto.String(ids[1])}.Build())
+	AssertThat(t, err, Nil())
+	ExpectThat(t, m.GetPlayerIds(), ElementsAre("alice", "bob"))
+	_, err = client.GetMatch(ctx, pb.GetMatchRequest_builder{MatchId: proto.String("nope")}.Build())
+	ExpectEq(t, status.Code(err), codes.NotFound)
+}
+
+func TestUnauthenticated(t *testing.T) {
+	client := startServer(t, lobby.New(queue.New(), party.NewManager(), nil), session.NewStore())
+
+	_, err := client.Enqueue(context.Background(), pb.EnqueueRequest_builder{GameMode: proto.String("holdem")}.Build())
+	ExpectEq(t, status.Code(err), codes.Unauthenticated)
+
+	_, err = client.Enqueue(withToken("bogus"), pb.EnqueueRequest_builder{GameMode: proto.String("holdem")}.Build())
+	ExpectEq(t, status.Code(err), codes.Unauthenticated)
+}
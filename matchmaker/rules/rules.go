@@ -0,0 +1,126 @@
+// Package rules turns the MatchRules config into queue settings.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/protobuf/encoding/prototext"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+// ErrInvalid is returned for rules that the matchmaker cannot follow.
+var ErrInvalid = errors.New("invalid match rules")
+
+// Load reads MatchRules from a textproto file and validates them.
+func Load(path string) (*pb.MatchRules, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &pb.MatchRules{}
+	if err := prototext.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := Validate(r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Validate checks that r is complete and consistent.
+func Validate(r *pb.MatchRules) error {
+	if r.GetTeamSize() < 1 || r.GetTeams() < 1 {
+		return fmt.Errorf("%w: team_size and teams must be positive", ErrInvalid)
+	}
+	if r.GetMaxWait().AsDuration() < 0 {
+		return fmt.Errorf("%w: max_wait is negative", ErrInvalid)
+	}
+
+	if r.HasRatingWindow() {
+		w := r.GetRatingWindow()
+		if w.GetInitial() < 0 || w.GetRate() < 0 || w.GetMax() < 0 {
+			return fmt.Errorf("%w: rating_window values must not be negative", ErrInvalid)
+		}
+		if w.GetMax() > 0 && w.GetMax() < w.GetInitial() {
+			return fmt.Errorf("%w: rating_window max is below initial", ErrInvalid)
+		}
+		switch w.GetGrowth() {
+		case pb.RatingWindow_UNKNOWN, pb.RatingWindow_LINEAR:
+		case pb.RatingWindow_EXPONENTIAL:
+			if w.GetInitial() == 0 {
+				return fmt.Errorf("%w: exponential rating_window needs a positive initial window", ErrInvalid)
+			}
+		default:
+			return fmt.Errorf("%w: unknown rating_window growth %v", ErrInvalid, w.GetGrowth())
+		}
+	}
+
+	p := r.GetRegionPolicy()
+	if p.GetMaxRtt().AsDuration() < 0 || p.GetFallbackAfter().AsDuration() < 0 {
+		return fmt.Errorf("%w: region_policy durations must not be negative", ErrInvalid)
+	}
+	for _, n := range p.GetNeighbors() {
+		if n.GetA() == "" || n.GetB() == "" || n.GetA() == n.GetB() {
+			return fmt.Errorf("%w: neighbors must name two different regions", ErrInvalid)
+		}
+		if n.GetPenalty().AsDuration() < 0 {
+			return fmt.Errorf("%w: penalty between %s and %s is negative", ErrInvalid, n.GetA(), n.GetB())
+		}
+	}
+	return nil
+}
+
+// TableSize returns the number of players seated at each table.
+func TableSize(r *pb.MatchRules) int {
+	return int(r.GetTeamSize() * r.GetTeams())
+}
+
+// QueueOptions returns the queue settings that carry out r, which must be
+// valid.
+func QueueOptions(r *pb.MatchRules) []queue.Option {
+	t := Thresholds(r)
+	return []queue.Option{
+		queue.WithTicketTTL(t.TicketTTL),
+		queue.WithRatingWindow(t.RatingWindow),
+		queue.WithMaxRTT(t.MaxRTT),
+		queue.WithRegionFallback(t.RegionFallback),
+	}
+}
+
+// Thresholds returns the queue thresholds that carry out r, which must be
+// valid, for changing a running queue's. r sets no priority aging.
+func Thresholds(r *pb.MatchRules) queue.Thresholds {
+	t := queue.Thresholds{TicketTTL: r.GetMaxWait().AsDuration()}
+
+	if r.HasRatingWindow() {
+		w := r.GetRatingWindow()
+		t.RatingWindow = queue.RatingWindow{Initial: w.GetInitial(), Growth: w.GetRate(), Max: w.GetMax()}
+		if w.GetGrowth() == pb.RatingWindow_EXPONENTIAL {
+			t.RatingWindow.Curve = queue.CurveExponential
+		}
+	}
+
+	p := r.GetRegionPolicy()
+	t.MaxRTT = p.GetMaxRtt().AsDuration()
+	if len(p.GetNeighbors()) > 0 {
+		neighbors := map[string]map[string]time.Duration{}
+		for _, n := range p.GetNeighbors() {
+			for _, edge := range [][2]string{{n.GetA(), n.GetB()}, {n.GetB(), n.GetA()}} {
+				if neighbors[edge[0]] == nil {
+					neighbors[edge[0]] = map[string]time.Duration{}
+				}
+				neighbors[edge[0]][edge[1]] = n.GetPenalty().AsDuration()
+			}
+		}
+		t.RegionFallback = queue.RegionFallback{
+			After:     p.GetFallbackAfter().AsDuration(),
+			Neighbors: neighbors,
+		}
+	}
+	return t
+}
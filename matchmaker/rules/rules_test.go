@@ -0,0 +1,106 @@
+package rules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"google.golang.org/protobuf/encoding/prototext"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+var ctx = context.Background()
+
+func parse(t *testing.T, text string) *pb.MatchRules {
+	t.Helper()
+	r := &pb.MatchRules{}
+	AssertThat(t, prototext.Unmarshal([]byte(text), r), Nil())
+	return r
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.textproto")
+	AssertThat(t, os.WriteFile(path, []byte(`
+		team_size: 1
+		teams: 6
+		rating_window { initial: 100 growth: GROWTH_EXPONENTIAL rate: 0.05 max: 400 }
+		max_wait { seconds: 600 }
+		region_policy {
+			max_rtt { nanos: 80000000 }
+			fallback_after { seconds: 60 }
+			neighbors { a: "us-east" b: "us-west" penalty { nanos: 40000000 } }
+		}
+	`), 0o644), Nil())
+
+	r, err := Load(path)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, TableSize(r), 6)
+
+	q := queue.New(QueueOptions(r)...)
+	ExpectThat(t, q, Not(Nil()))
+
+	AssertThat(t, os.WriteFile(path, []byte(`teams: 6`), 0o644), Nil())
+	_, err = Load(path)
+	ExpectThat(t, err, ErrorIs(ErrInvalid))
+}
+
+func TestValidate(t *testing.T) {
+	for _, text := range []string{
+		`team_size: 0 teams: 6`,
+		`team_size: 1 teams: 6 max_wait { seconds: -1 }`,
+		`team_size: 1 teams: 6 rating_window { initial: -5 }`,
+		`team_size: 1 teams: 6 rating_window { initial: 100 max: 50 }`,
+		`team_size: 1 teams: 6 rating_window { growth: GROWTH_EXPONENTIAL rate: 0.1 }`,
+		`team_size: 1 teams: 6 region_policy { max_rtt { seconds: -1 } }`,
+		`team_size: 1 teams: 6 region_policy { neighbors { a: "us-east" b: "us-east" } }`,
+		`team_size: 1 teams: 6 region_policy { neighbors { a: "us-east" b: "eu-west" penalty { seconds: -1 } } }`,
+	} {
+		ExpectThat(t, Validate(parse(t, text)), ErrorIs(ErrInvalid))
+	}
+	ExpectThat(t, Validate(parse(t, `team_size: 2 teams: 2`)), Nil())
+}
+
+func TestQueueOptions(t *testing.T) {
+	r := parse(t, `
+		team_size: 1
+		teams: 2
+		region_policy {
+			neighbors { a: "us-east" b: "us-west" penalty { nanos: 30000000 } }
+		}
+	`)
+	AssertThat(t, Validate(r), Nil())
+	q := queue.New(QueueOptions(r)...)
+
+	// The players measured different regions, but may fall back to each
+	// other's at once.
+	for _, p := range []struct{ player, region string }{{"a", "us-east"}, {"b", "us-west"}} {
+		tk, err := q.Enqueue(ctx, p.player, "holdem")
+		AssertThat(t, err, Nil())
+		AssertThat(t, q.ReportLatency(tk.ID, p.player, queue.Latencies{p.region: 10 * time.Millisecond}), Nil())
+	}
+	matches, err := q.FormMatches(ctx, TableSize(r))
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].Region, "us-east")
+}
+
+func TestThresholds(t *testing.T) {
+	r := parse(t, `
+		team_size: 1
+		teams: 2
+		max_wait { seconds: 60 }
+		rating_window { initial: 100 rate: 10 max: 400 growth: GROWTH_EXPONENTIAL }
+		region_policy { max_rtt { nanos: 80000000 } }
+	`)
+	AssertThat(t, Validate(r), Nil())
+	th := Thresholds(r)
+	ExpectEq(t, th.TicketTTL, time.Minute)
+	ExpectEq(t, th.MaxRTT, 80*time.Millisecond)
+	ExpectEq(t, th.RatingWindow, queue.RatingWindow{Initial: 100, Growth: 10, Max: 400, Curve: queue.CurveExponential})
+	ExpectThat(t, th.RegionFallback.Neighbors, Nil())
+}
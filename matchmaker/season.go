@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jfmatt/flagr"
+	"github.com/spf13/cobra"
+
+	"github.com/jfmatt/snapfold/matchmaker/season"
+	"github.com/jfmatt/snapfold/matchmaker/store"
+)
+
+func SeasonCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "season",
+		Short: "Open, close and inspect ranked seasons",
+	}
+
+	openCmd := &cobra.Command{
+		Use:   "open",
+		Short: "Start a new season, softly resetting every rating",
+	}
+	openCmd.RunE = flagr.Run(openCmd, OpenSeason)
+	c.AddCommand(openCmd)
+
+	closeCmd := &cobra.Command{
+		Use:   "close",
+		Short: "End the open season and hand out its rewards",
+	}
+	closeCmd.RunE = flagr.Run(closeCmd, CloseSeason)
+	c.AddCommand(closeCmd)
+
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the current season",
+	}
+	showCmd.RunE = flagr.Run(showCmd, ShowSeason)
+	c.AddCommand(showCmd)
+
+	return c
+}
+
+type OpenSeasonArgs struct {
+	Dsn  string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	Name string `flag:"name,required,help=Name of the season shown to players"`
+	Ends string `flag:"ends,help=Date the season is scheduled to end, as YYYY-MM-DD; for display only"`
+
+	Keep      float64 `flag:"keep,default=0.5,help=Fraction of each player's distance from the target rating they keep; 0 resets everyone to the target"`
+	Target    float64 `flag:"target,help=Rating players are pulled toward; 0 for the rating of a new player"`
+	Deviation float64 `flag:"deviation,default=200,help=Rating deviations below this are raised to it, so ratings settle quickly; 0 to leave them"`
+}
+
+func OpenSeason(flags *OpenSeasonArgs, cmd *cobra.Command, args []string) error {
+	var endsAt time.Time
+	if flags.Ends != "" {
+		var err error
+		if endsAt, err = time.Parse(time.DateOnly, flags.Ends); err != nil {
+			return fmt.Errorf("ends: %w", err)
+		}
+	}
+	db, err := store.Open(cmd.Context(), flags.Dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m := season.NewManager(store.NewSeasons(db), store.NewRatings(db))
+	s, err := m.Open(cmd.Context(), flags.Name, endsAt, season.Reset{
+		Keep:      flags.Keep,
+		Target:    flags.Target,
+		Deviation: flags.Deviation,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "opened season %d %q\n", s.Number, s.Name)
+	return nil
+}
+
+type CloseSeasonArgs struct {
+	Dsn            string        `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	RewardsWebhook string        `flag:"rewards-webhook,help=URL to POST the final standings to so that rewards can be handed out; none are if unset"`
+	Timeout        time.Duration `flag:"timeout,default=30s,help=How long to wait for the rewards webhook"`
+}
+
+func CloseSeason(flags *CloseSeasonArgs, cmd *cobra.Command, args []string) error {
+	db, err := store.Open(cmd.Context(), flags.Dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var opts []season.Option
+	if flags.RewardsWebhook != "" {
+		opts = append(opts, season.WithRewards(season.Webhook(flags.RewardsWebhook, &http.Client{Timeout: flags.Timeout})))
+	}
+	m := season.NewManager(store.NewSeasons(db), store.NewRatings(db), opts...)
+	s, err := m.Close(cmd.Context())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "closed season %d %q\n", s.Number, s.Name)
+	return nil
+}
+
+type ShowSeasonArgs struct {
+	Dsn string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	Top int    `flag:"top,default=10,help=Number of players to list from a closed season's final standings"`
+}
+
+func ShowSeason(flags *ShowSeasonArgs, cmd *cobra.Command, args []string) error {
+	db, err := store.Open(cmd.Context(), flags.Dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m := season.NewManager(store.NewSeasons(db), store.NewRatings(db))
+	s, err := m.Current(cmd.Context())
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "season %d %q, started %s\n", s.Number, s.Name, s.StartedAt.Format(time.DateOnly))
+	if !s.EndsAt.IsZero() {
+		fmt.Fprintf(out, "scheduled to end %s\n", s.EndsAt.Format(time.DateOnly))
+	}
+	if s.Open() {
+		fmt.Fprintln(out, "open")
+		return nil
+	}
+	fmt.Fprintf(out, "closed %s\n", s.ClosedAt.Format(time.DateOnly))
+	standings, err := m.Standings(cmd.Context(), s.Number)
+	if err != nil {
+		return err
+	}
+	for _, st := range standings[:min(flags.Top, len(standings))] {
+		fmt.Fprintf(out, "%4d  %-24s %7.1f\n", st.Rank, st.PlayerID, st.Rating.Rating)
+	}
+	return nil
+}
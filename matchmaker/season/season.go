@@ -0,0 +1,244 @@
+// Package season organizes ranked play into seasons. Opening a season pulls
+// every player's rating part of the way back toward the middle, so that
+// everyone has to prove themselves again, and closing one records the final
+// standings and hands them to a rewards hook.
+package season
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/paging"
+	"github.com/jfmatt/snapfold/matchmaker/rating"
+)
+
+var (
+	ErrNoSeason     = errors.New("no season is open")
+	ErrSeasonOpen   = errors.New("a season is already open")
+	ErrInvalidReset = errors.New("invalid rating reset")
+)
+
+// Season is one period of ranked play.
+type Season struct {
+	// 1 for the first season, and one more for each after it.
+	Number int
+	Name   string
+
+	StartedAt time.Time
+
+	// When the season is scheduled to end, for display. Seasons only end
+	// when they are closed. Zero if no end is scheduled.
+	EndsAt time.Time
+
+	// When the season was closed. Zero while it is open.
+	ClosedAt time.Time
+
+	// The reset applied to ratings when the season opened.
+	Reset Reset
+}
+
+// Open reports whether the season is still in play.
+func (s Season) Open() bool {
+	return s.ClosedAt.IsZero()
+}
+
+// Reset pulls ratings toward a common value at the start of a season.
+type Reset struct {
+	// Fraction of each player's distance from Target that they keep: 0 puts
+	// everyone at Target, and 1 leaves ratings where they are.
+	Keep float64
+
+	// The rating players are pulled toward. Zero means the rating of a new
+	// player.
+	Target float64
+
+	// Deviations below this are raised to it, so that ratings move quickly
+	// early in the season. Zero leaves deviations alone.
+	Deviation float64
+}
+
+// Validate checks that r can be applied.
+func (r Reset) Validate() error {
+	if r.Keep < 0 || r.Keep > 1 {
+		return fmt.Errorf("%w: keep must be between 0 and 1", ErrInvalidReset)
+	}
+	if r.Target < 0 || r.Deviation < 0 {
+		return fmt.Errorf("%w: target and deviation must not be negative", ErrInvalidReset)
+	}
+	return nil
+}
+
+// Apply returns x after the reset.
+func (r Reset) Apply(x rating.Rating) rating.Rating {
+	target := cmp.Or(r.Target, rating.Default().Rating)
+	x.Rating = target + r.Keep*(x.Rating-target)
+	x.Deviation = max(x.Deviation, r.Deviation)
+	return x
+}
+
+// Standing is a player's final place in a season, by rating.
+type Standing struct {
+	// 1 for the highest rated player. Players with equal ratings share a
+	// rank.
+	Rank     int
+	PlayerID string
+	Rating   rating.Rating
+}
+
+// RewardFunc hands out end-of-season rewards from the final standings of a
+// season being closed, which are sorted by rank. If it fails, the season
+// stays open and closing it may be retried, so it should tolerate being
+// called more than once for the same season.
+type RewardFunc func(ctx context.Context, s Season, standings []Standing) error
+
+// Manager opens and closes seasons. Only one season may be open at a time.
+type Manager struct {
+	store   Store
+	ratings rating.Store
+	rewards RewardFunc
+	now     func() time.Time
+}
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithRewards sets the hook called with the final standings of each season
+// as it closes.
+func WithRewards(f RewardFunc) Option {
+	return func(m *Manager) { m.rewards = f }
+}
+
+// NewManager returns a Manager that keeps seasons in store and resets the
+// ratings in ratings.
+func NewManager(store Store, ratings rating.Store, opts ...Option) *Manager {
+	m := &Manager{store: store, ratings: ratings, now: time.Now}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Current returns the latest season, which may have been closed, or
+// ErrNoSeason if there has never been one.
+func (m *Manager) Current(ctx context.Context) (Season, error) {
+	return m.store.LatestSeason(ctx)
+}
+
+// Open starts a new season, applying reset to every player's rating. It
+// fails with ErrSeasonOpen if the previous season has not been closed.
+func (m *Manager) Open(ctx context.Context, name string, endsAt time.Time, reset Reset) (Season, error) {
+	if err := reset.Validate(); err != nil {
+		return Season{}, err
+	}
+	prev, err := m.store.LatestSeason(ctx)
+	switch {
+	case errors.Is(err, ErrNoSeason):
+	case err != nil:
+		return Season{}, err
+	case prev.Open():
+		return Season{}, ErrSeasonOpen
+	}
+
+	ratings, err := m.ratings.All(ctx)
+	if err != nil {
+		return Season{}, err
+	}
+	for id, r := range ratings {
+		ratings[id] = reset.Apply(r)
+	}
+	if err := m.ratings.Put(ctx, ratings); err != nil {
+		return Season{}, err
+	}
+
+	s := Season{
+		Number:    prev.Number + 1,
+		Name:      name,
+		StartedAt: m.now(),
+		EndsAt:    endsAt,
+		Reset:     reset,
+	}
+	return s, m.store.SaveSeason(ctx, s)
+}
+
+// Close ends the open season, recording its final standings and passing
+// them to the rewards hook. It fails with ErrNoSeason if no season is open.
+func (m *Manager) Close(ctx context.Context) (Season, error) {
+	s, err := m.store.LatestSeason(ctx)
+	if err != nil {
+		return Season{}, err
+	}
+	if !s.Open() {
+		return Season{}, ErrNoSeason
+	}
+
+	ratings, err := m.ratings.All(ctx)
+	if err != nil {
+		return Season{}, err
+	}
+	standings := Rank(ratings)
+	if err := m.store.SaveStandings(ctx, s.Number, standings); err != nil {
+		return Season{}, err
+	}
+	if m.rewards != nil {
+		if err := m.rewards(ctx, s, standings); err != nil {
+			return Season{}, err
+		}
+	}
+	s.ClosedAt = m.now()
+	return s, m.store.SaveSeason(ctx, s)
+}
+
+// Standings returns the final standings of a closed season.
+func (m *Manager) Standings(ctx context.Context, number int) ([]Standing, error) {
+	return m.store.Standings(ctx, number)
+}
+
+// Leaderboard is how a season's standings may be listed: by rank, highest
+// first unless sorted by -rank.
+var Leaderboard = paging.Spec{
+	Default: paging.Sort{Field: "rank"},
+	Fields:  []string{"rank"},
+}
+
+// LeaderboardPage returns a page of the final standings of a closed season,
+// for a request made with the Leaderboard spec.
+func (m *Manager) LeaderboardPage(ctx context.Context, number int, r paging.Request) (paging.Page[Standing], error) {
+	standings, err := m.store.Standings(ctx, number)
+	if err != nil {
+		return paging.Page[Standing]{}, err
+	}
+	if r.After != nil && len(r.After) != 2 {
+		return paging.Page[Standing]{}, paging.ErrInvalidToken
+	}
+	return paging.Slice(r, standings, func(st Standing) []string {
+		return []string{strconv.Itoa(st.Rank), st.PlayerID}
+	}, func(st Standing, after []string) int {
+		return cmp.Or(cmp.Compare(int64(st.Rank), paging.Int(after[0])), cmp.Compare(st.PlayerID, after[1]))
+	}), nil
+}
+
+// Rank orders players by rating, highest first.
+func Rank(ratings map[string]rating.Rating) []Standing {
+	standings := make([]Standing, 0, len(ratings))
+	for id, r := range ratings {
+		standings = append(standings, Standing{PlayerID: id, Rating: r})
+	}
+	slices.SortFunc(standings, func(a, b Standing) int {
+		if c := cmp.Compare(b.Rating.Rating, a.Rating.Rating); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.PlayerID, b.PlayerID)
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+		if i > 0 && standings[i].Rating.Rating == standings[i-1].Rating.Rating {
+			standings[i].Rank = standings[i-1].Rank
+		}
+	}
+	return standings
+}
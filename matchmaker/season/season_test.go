@@ -0,0 +1,119 @@
+package season
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/rating"
+)
+
+var ctx = context.Background()
+
+func TestReset(t *testing.T) {
+	r := Reset{Keep: 0.5, Deviation: 200}
+	got := r.Apply(rating.Rating{Rating: 1900, Deviation: 60, Volatility: 0.06})
+	ExpectEq(t, got, rating.Rating{Rating: 1700, Deviation: 200, Volatility: 0.06})
+	got = Reset{Keep: 0, Target: 1200}.Apply(rating.Rating{Rating: 1000, Deviation: 300})
+	ExpectEq(t, got, rating.Rating{Rating: 1200, Deviation: 300})
+
+	ExpectThat(t, Reset{Keep: 1.5}.Validate(), ErrorIs(ErrInvalidReset))
+	ExpectThat(t, Reset{Deviation: -1}.Validate(), ErrorIs(ErrInvalidReset))
+}
+
+func TestOpenAndClose(t *testing.T) {
+	ratings := rating.NewMemStore()
+	AssertThat(t, ratings.Put(ctx, map[string]rating.Rating{
+		"alice": {Rating: 1800, Deviation: 50},
+		"bob":   {Rating: 1400, Deviation: 50},
+		"carol": {Rating: 1400, Deviation: 50},
+	}), Nil())
+	var rewarded []Standing
+	m := NewManager(NewMemStore(), ratings, WithRewards(func(ctx context.Context, s Season, standings []Standing) error {
+		rewarded = standings
+		return nil
+	}))
+
+	_, err := m.Current(ctx)
+	ExpectThat(t, err, ErrorIs(ErrNoSeason))
+	_, err = m.Close(ctx)
+	ExpectThat(t, err, ErrorIs(ErrNoSeason))
+
+	s, err := m.Open(ctx, "Spring", time.Time{}, Reset{Keep: 0.5})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.Number, 1)
+	ExpectEq(t, s.Open(), true)
+	got, err := ratings.Get(ctx, "alice", "bob")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got["alice"].Rating, 1650.0)
+	ExpectEq(t, got["bob"].Rating, 1450.0)
+
+	_, err = m.Open(ctx, "Summer", time.Time{}, Reset{})
+	ExpectThat(t, err, ErrorIs(ErrSeasonOpen))
+
+	s, err = m.Close(ctx)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.Open(), false)
+	AssertThat(t, rewarded, Len(3))
+	ExpectEq(t, rewarded[0].PlayerID, "alice")
+	ExpectEq(t, rewarded[1].Rank, 2)
+	ExpectEq(t, rewarded[2].Rank, 2)
+	standings, err := m.Standings(ctx, 1)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, standings, Len(3))
+
+	s, err = m.Open(ctx, "Summer", time.Time{}, Reset{Keep: 1})
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.Number, 2)
+}
+
+func TestClose_RewardsFail(t *testing.T) {
+	fail := true
+	m := NewManager(NewMemStore(), rating.NewMemStore(), WithRewards(func(ctx context.Context, s Season, standings []Standing) error {
+		if fail {
+			return errors.New("rewards service down")
+		}
+		return nil
+	}))
+	_, err := m.Open(ctx, "Spring", time.Time{}, Reset{})
+	AssertThat(t, err, Nil())
+
+	// The season stays open until rewards are handed out.
+	_, err = m.Close(ctx)
+	ExpectThat(t, err, Not(Nil()))
+	s, err := m.Current(ctx)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.Open(), true)
+
+	fail = false
+	s, err = m.Close(ctx)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.Open(), false)
+}
+
+func TestWebhook(t *testing.T) {
+	var got webhookRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		if got.Season.Number > 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	hook := Webhook(srv.URL, srv.Client())
+
+	standings := []Standing{{Rank: 1, PlayerID: "alice", Rating: rating.Rating{Rating: 1700}}}
+	AssertThat(t, hook(ctx, Season{Number: 1, Name: "Spring"}, standings), Nil())
+	ExpectEq(t, got.Season.Name, "Spring")
+	ExpectThat(t, got.Season.EndsAt, Nil())
+	AssertThat(t, got.Standings, Len(1))
+	ExpectEq(t, got.Standings[0].Rating, 1700.0)
+
+	ExpectThat(t, hook(ctx, Season{Number: 2}, nil), Not(Nil()))
+}
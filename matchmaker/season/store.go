@@ -0,0 +1,69 @@
+package season
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Store persists seasons and their final standings.
+type Store interface {
+	// LatestSeason returns the season with the highest number, or
+	// ErrNoSeason if there are none.
+	LatestSeason(ctx context.Context) (Season, error)
+
+	// SaveSeason records a season, replacing any earlier record of it.
+	SaveSeason(ctx context.Context, s Season) error
+
+	// SaveStandings records the final standings of a season, replacing any
+	// recorded before.
+	SaveStandings(ctx context.Context, number int, standings []Standing) error
+
+	// Standings returns the standings recorded for a season, by rank.
+	Standings(ctx context.Context, number int) ([]Standing, error)
+}
+
+// MemStore is an in-memory Store, for development and tests.
+type MemStore struct {
+	mu        sync.Mutex
+	seasons   []Season // by number
+	standings map[int][]Standing
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{standings: map[int][]Standing{}}
+}
+
+func (s *MemStore) LatestSeason(ctx context.Context) (Season, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.seasons) == 0 {
+		return Season{}, ErrNoSeason
+	}
+	return s.seasons[len(s.seasons)-1], nil
+}
+
+func (s *MemStore) SaveSeason(ctx context.Context, season Season) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := season.Number - 1; i < len(s.seasons) {
+		s.seasons[i] = season
+	} else {
+		s.seasons = append(s.seasons, season)
+	}
+	return nil
+}
+
+func (s *MemStore) SaveStandings(ctx context.Context, number int, standings []Standing) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.standings[number] = slices.Clone(standings)
+	return nil
+}
+
+func (s *MemStore) Standings(ctx context.Context, number int) ([]Standing, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.standings[number]), nil
+}
@@ -0,0 +1,64 @@
+package season
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type webhookSeason struct {
+	Number    int        `json:"number"`
+	Name      string     `json:"name"`
+	StartedAt time.Time  `json:"started_at"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+}
+
+type webhookStanding struct {
+	Rank     int     `json:"rank"`
+	PlayerID string  `json:"player_id"`
+	Rating   float64 `json:"rating"`
+}
+
+type webhookRequest struct {
+	Season    webhookSeason     `json:"season"`
+	Standings []webhookStanding `json:"standings"`
+}
+
+// Webhook returns a RewardFunc that POSTs each closing season and its
+// standings as JSON to url, for a service that hands out the rewards. Any
+// status other than 2xx is an error.
+func Webhook(url string, client *http.Client) RewardFunc {
+	return func(ctx context.Context, s Season, standings []Standing) error {
+		req := webhookRequest{
+			Season:    webhookSeason{Number: s.Number, Name: s.Name, StartedAt: s.StartedAt},
+			Standings: make([]webhookStanding, 0, len(standings)),
+		}
+		if !s.EndsAt.IsZero() {
+			req.Season.EndsAt = &s.EndsAt
+		}
+		for _, st := range standings {
+			req.Standings = append(req.Standings, webhookStanding{Rank: st.Rank, PlayerID: st.PlayerID, Rating: st.Rating.Rating})
+		}
+		body, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(httpReq)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("rewards webhook: %s", resp.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+// Package seat tracks where players are seated in matches that are in play,
+// so that a player who loses their connection mid-hand can find their way
+// back to their table.
+package seat
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+var (
+	ErrNotSeated    = errors.New("player is not seated at a table")
+	ErrGraceExpired = errors.New("rejoin grace window has passed")
+)
+
+// Seat is a player's place at a table in play.
+type Seat struct {
+	PlayerID string
+
+	// The table the player sits at. For matches that fill seats at an
+	// existing table, this is that table's ID; otherwise it is the match ID.
+	TableID string
+
+	// The match that seated the player.
+	MatchID string
+
+	GameMode string
+	Region   string
+
+	// host:port of the game server hosting the table. Empty if unknown.
+	Address string
+
+	SeatedAt time.Time
+
+	// When the game server reported the player disconnected. Zero while they
+	// are connected.
+	DisconnectedAt time.Time
+}
+
+// Disconnected reports whether the player has lost their connection to the
+// table.
+func (s Seat) Disconnected() bool {
+	return !s.DisconnectedAt.IsZero()
+}
+
+// Manager hands out and looks up seats. It is safe for concurrent use.
+type Manager struct {
+	store Store
+	grace time.Duration
+	now   func() time.Time
+}
+
+// NewManager returns a Manager that keeps seats in store. Players who
+// disconnect may rejoin their table for up to grace afterwards.
+func NewManager(store Store, grace time.Duration) *Manager {
+	return &Manager{store: store, grace: grace, now: time.Now}
+}
+
+// Grace returns how long disconnected players have to rejoin.
+func (m *Manager) Grace() time.Duration {
+	return m.grace
+}
+
+// Assign seats every player in a newly seated match at the game server with
+// the given address, moving them from any table they were at before.
+func (m *Manager) Assign(ctx context.Context, match *queue.Match, address string) error {
+	tableID := match.TableID
+	if tableID == "" {
+		tableID = match.ID
+	}
+	now := m.now()
+	var errs []error
+	for _, id := range match.Players() {
+		errs = append(errs, m.store.SaveSeat(ctx, Seat{
+			PlayerID: id,
+			TableID:  tableID,
+			MatchID:  match.ID,
+			GameMode: match.GameMode,
+			Region:   match.Region,
+			Address:  address,
+			SeatedAt: now,
+		}))
+	}
+	return errors.Join(errs...)
+}
+
+// Disconnect records that a player has lost their connection to a table,
+// starting their grace window. Reporting a player who is already
+// disconnected does not restart it.
+func (m *Manager) Disconnect(ctx context.Context, tableID, playerID string) (Seat, error) {
+	s, err := m.seatAt(ctx, tableID, playerID)
+	if err != nil {
+		return Seat{}, err
+	}
+	if s.Disconnected() {
+		return s, nil
+	}
+	s.DisconnectedAt = m.now()
+	return s, m.store.SaveSeat(ctx, s)
+}
+
+// Rejoin returns the player's seat so that they can reconnect to its table,
+// and marks them connected again. A player who was disconnected for longer
+// than the grace window has lost their seat and gets ErrGraceExpired.
+func (m *Manager) Rejoin(ctx context.Context, playerID string) (Seat, error) {
+	s, err := m.store.GetSeat(ctx, playerID)
+	if err != nil {
+		return Seat{}, err
+	}
+	if !s.Disconnected() {
+		return s, nil
+	}
+	if m.now().Sub(s.DisconnectedAt) > m.grace {
+		if err := m.store.DeleteSeat(ctx, playerID); err != nil {
+			return Seat{}, err
+		}
+		return Seat{}, ErrGraceExpired
+	}
+	s.DisconnectedAt = time.Time{}
+	return s, m.store.SaveSeat(ctx, s)
+}
+
+// Release frees a player's seat once they have left the table for good, and
+// returns the seat as it was.
+func (m *Manager) Release(ctx context.Context, tableID, playerID string) (Seat, error) {
+	s, err := m.seatAt(ctx, tableID, playerID)
+	if err != nil {
+		return Seat{}, err
+	}
+	return s, m.store.DeleteSeat(ctx, playerID)
+}
+
+// CloseTable frees every seat at a table that has finished.
+func (m *Manager) CloseTable(ctx context.Context, tableID string) error {
+	return m.store.DeleteTable(ctx, tableID)
+}
+
+// seatAt returns the player's seat if it is at the given table.
+func (m *Manager) seatAt(ctx context.Context, tableID, playerID string) (Seat, error) {
+	s, err := m.store.GetSeat(ctx, playerID)
+	if err != nil {
+		return Seat{}, err
+	}
+	if s.TableID != tableID {
+		return Seat{}, ErrNotSeated
+	}
+	return s, nil
+}
@@ -0,0 +1,94 @@
+package seat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+var ctx = context.Background()
+
+func newMatch(id, tableID string, players ...string) *queue.Match {
+	m := &queue.Match{ID: id, TableID: tableID, GameMode: "holdem"}
+	for _, p := range players {
+		m.Tickets = append(m.Tickets, &queue.Ticket{PlayerID: p, Members: []string{p}})
+	}
+	return m
+}
+
+func TestRejoin(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := NewManager(NewMemStore(), time.Minute)
+	m.now = func() time.Time { return now }
+	AssertThat(t, m.Assign(ctx, newMatch("m1", "", "alice", "bob"), "10.0.0.1:7000"), Nil())
+
+	// Connected players can always look up their seat.
+	s, err := m.Rejoin(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.TableID, "m1")
+	ExpectEq(t, s.Address, "10.0.0.1:7000")
+	_, err = m.Rejoin(ctx, "carol")
+	ExpectThat(t, err, ErrorIs(ErrNotSeated))
+
+	_, err = m.Disconnect(ctx, "m2", "alice")
+	ExpectThat(t, err, ErrorIs(ErrNotSeated))
+	s, err = m.Disconnect(ctx, "m1", "alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.Disconnected(), true)
+
+	now = now.Add(30 * time.Second)
+	s, err = m.Rejoin(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.Disconnected(), false)
+
+	// Past the grace window the seat is gone.
+	_, err = m.Disconnect(ctx, "m1", "bob")
+	AssertThat(t, err, Nil())
+	now = now.Add(2 * time.Minute)
+	_, err = m.Rejoin(ctx, "bob")
+	ExpectThat(t, err, ErrorIs(ErrGraceExpired))
+	_, err = m.Rejoin(ctx, "bob")
+	ExpectThat(t, err, ErrorIs(ErrNotSeated))
+}
+
+func TestDisconnect_KeepsFirstTime(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := NewManager(NewMemStore(), time.Minute)
+	m.now = func() time.Time { return now }
+	AssertThat(t, m.Assign(ctx, newMatch("m1", "", "alice"), ""), Nil())
+
+	first, err := m.Disconnect(ctx, "m1", "alice")
+	AssertThat(t, err, Nil())
+	now = now.Add(30 * time.Second)
+	s, err := m.Disconnect(ctx, "m1", "alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.DisconnectedAt, first.DisconnectedAt)
+}
+
+func TestBackfillSeats(t *testing.T) {
+	m := NewManager(NewMemStore(), time.Minute)
+	AssertThat(t, m.Assign(ctx, newMatch("m1", "", "alice"), ""), Nil())
+	AssertThat(t, m.Assign(ctx, newMatch("m2", "table1", "bob"), ""), Nil())
+
+	// Backfilled players sit at the existing table.
+	s, err := m.Rejoin(ctx, "bob")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.TableID, "table1")
+	ExpectEq(t, s.MatchID, "m2")
+
+	_, err = m.Release(ctx, "m1", "bob")
+	ExpectThat(t, err, ErrorIs(ErrNotSeated))
+	s, err = m.Release(ctx, "table1", "bob")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, s.MatchID, "m2")
+	_, err = m.Rejoin(ctx, "bob")
+	ExpectThat(t, err, ErrorIs(ErrNotSeated))
+
+	AssertThat(t, m.CloseTable(ctx, "m1"), Nil())
+	_, err = m.Rejoin(ctx, "alice")
+	ExpectThat(t, err, ErrorIs(ErrNotSeated))
+}
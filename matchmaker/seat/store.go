@@ -0,0 +1,69 @@
+package seat
+
+import (
+	"context"
+	"sync"
+)
+
+// Store persists seats, so that players can rejoin their tables after the
+// matchmaker restarts.
+type Store interface {
+	// SaveSeat records a player's seat, replacing any earlier one.
+	SaveSeat(ctx context.Context, s Seat) error
+
+	// GetSeat returns the player's seat, or ErrNotSeated.
+	GetSeat(ctx context.Context, playerID string) (Seat, error)
+
+	// DeleteSeat forgets the player's seat. Deleting a seat that does not
+	// exist is not an error.
+	DeleteSeat(ctx context.Context, playerID string) error
+
+	// DeleteTable forgets every seat at a table.
+	DeleteTable(ctx context.Context, tableID string) error
+}
+
+// MemStore is an in-memory Store, for development and tests.
+type MemStore struct {
+	mu    sync.Mutex
+	seats map[string]Seat // player ID -> seat
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{seats: map[string]Seat{}}
+}
+
+func (s *MemStore) SaveSeat(ctx context.Context, seat Seat) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seats[seat.PlayerID] = seat
+	return nil
+}
+
+func (s *MemStore) GetSeat(ctx context.Context, playerID string) (Seat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seat, ok := s.seats[playerID]
+	if !ok {
+		return Seat{}, ErrNotSeated
+	}
+	return seat, nil
+}
+
+func (s *MemStore) DeleteSeat(ctx context.Context, playerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.seats, playerID)
+	return nil
+}
+
+func (s *MemStore) DeleteTable(ctx context.Context, tableID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, seat := range s.seats {
+		if seat.TableID == tableID {
+			delete(s.seats, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jfmatt/flagr"
+	"github.com/spf13/cobra"
+
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/history"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+	"github.com/jfmatt/snapfold/matchmaker/rating"
+	"github.com/jfmatt/snapfold/matchmaker/registry"
+	"github.com/jfmatt/snapfold/matchmaker/store"
+)
+
+func SeedCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "seed",
+		Short: "Fill a freshly migrated database with sample data for development",
+		Long: `Fills a freshly migrated database with sample data, so that the API and
+gocli have something to work with: an admin account named admin, a moderator
+named mod, and players named player1, player2 and so on, all with verified
+example.com emails and the password --password; every built-in preset as a
+TableConfig in the config registry; and a week of finished matches between
+the players at those presets, with the ratings they earned.
+
+Serve with --registry-game-modes to offer the presets as game modes.`,
+	}
+	c.RunE = flagr.Run(c, Seed)
+	return c
+}
+
+type SeedArgs struct {
+	Dsn      string `flag:"dsn,required,help=Postgres connection string, or sqlite:PATH for a SQLite database"`
+	Password string `flag:"password,default=snapfold-dev,help=Password of every seeded account"`
+	Players  int    `flag:"players,default=12,help=Number of player accounts to create"`
+	Matches  int    `flag:"matches,default=40,help=Number of finished matches to record between them"`
+	Seed     uint64 `flag:"seed,default=1,help=Seed for the random matches, so that the same flags seed the same data"`
+}
+
+func Seed(flags *SeedArgs, cmd *cobra.Command, args []string) error {
+	if flags.Players < 2 {
+		return errors.New("--players must be at least 2, to have anyone to play")
+	}
+	ctx := cmd.Context()
+	db, err := store.Open(ctx, flags.Dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	accounts := store.NewAccounts(db)
+	m := account.NewManager(accounts)
+	create := func(username string, role account.Role) error {
+		a, err := m.Register(ctx, username, username+"@example.com", flags.Password)
+		if errors.Is(err, account.ErrUsernameTaken) {
+			return fmt.Errorf("%w; seed only a fresh database", err)
+		}
+		if err != nil {
+			return err
+		}
+		a.EmailVerified = true
+		a.Role = role
+		return accounts.UpdateAccount(ctx, a)
+	}
+	if err := create("admin", account.RoleAdmin); err != nil {
+		return err
+	}
+	if err := create("mod", account.RoleModerator); err != nil {
+		return err
+	}
+	players := make([]string, flags.Players)
+	for i := range players {
+		players[i] = fmt.Sprintf("player%d", i+1)
+		if err := create(players[i], account.RolePlayer); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "created admin, mod and %d players with password %q\n", len(players), flags.Password)
+
+	r := registry.New(store.NewConfigs(db))
+	for _, p := range gamedefio.Presets {
+		cfg, err := p.Config()
+		if err != nil {
+			return err
+		}
+		if _, err := r.PutTableConfig(ctx, p.Name, cfg, 0); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "stored %d table configs\n", len(gamedefio.Presets))
+
+	matches := store.NewMatches(db)
+	ratings := store.NewRatings(db)
+	rng := rand.New(rand.NewPCG(flags.Seed, flags.Seed))
+	// Matches are spread over the last week, oldest first, so that ratings
+	// are earned in the order the matches were played.
+	start := time.Now().Add(-7 * 24 * time.Hour).Truncate(time.Minute)
+	for i := range flags.Matches {
+		p := gamedefio.Presets[rng.IntN(len(gamedefio.Presets))]
+		cfg, err := p.Config()
+		if err != nil {
+			return err
+		}
+		seated := rng.Perm(len(players))[:min(p.Seats, len(players))]
+		match := history.Match{
+			ID:        queue.NewID(),
+			GameMode:  p.Name,
+			Region:    "local",
+			Config:    cfg,
+			CreatedAt: start.Add(time.Duration(i) * 7 * 24 * time.Hour / time.Duration(flags.Matches)),
+		}
+		finish := rng.Perm(len(seated))
+		places := map[string]int{}
+		for k, j := range seated {
+			match.Players = append(match.Players, players[j])
+			places[players[j]] = finish[k] + 1
+		}
+		if err := matches.SaveMatch(ctx, match); err != nil {
+			return err
+		}
+		endedAt := match.CreatedAt.Add(time.Duration(10+rng.IntN(50)) * time.Minute)
+		if err := matches.SaveResult(ctx, match.ID, places, endedAt); err != nil {
+			return err
+		}
+		if err := rating.RecordMatch(ctx, ratings, places); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "recorded %d matches\n", flags.Matches)
+	return nil
+}
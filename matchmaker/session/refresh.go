@@ -0,0 +1,184 @@
+package session
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// RefreshToken is a stored refresh token.
+type RefreshToken struct {
+	// SHA-256 of the token, hex encoded.
+	ID       string
+	PlayerID string
+
+	// Shared by every token descended from the same login, so that they can
+	// be revoked together.
+	Family string
+
+	ExpiresAt time.Time
+
+	// When the token was exchanged or revoked. Zero while it is usable.
+	RevokedAt time.Time
+}
+
+// RefreshStore persists refresh tokens and the sessions they belong to.
+type RefreshStore interface {
+	SaveRefreshToken(ctx context.Context, t RefreshToken) error
+
+	// RevokeRefreshToken revokes a token and returns it as it was before,
+	// so that callers can tell whether it had already been revoked. It
+	// returns ErrInvalidToken if there is no such token.
+	RevokeRefreshToken(ctx context.Context, id string, at time.Time) (RefreshToken, error)
+
+	// RevokeFamily revokes the session with the family as its ID and every
+	// token in the family that is not yet revoked.
+	RevokeFamily(ctx context.Context, family string, at time.Time) error
+
+	// RevokePlayer revokes every session and token of a player that is not
+	// yet revoked.
+	RevokePlayer(ctx context.Context, playerID string, at time.Time) error
+
+	// SaveSession records a new session.
+	SaveSession(ctx context.Context, s Session) error
+
+	// TouchSession records that a session was refreshed.
+	TouchSession(ctx context.Context, id string, at, expiresAt time.Time) error
+
+	// GetSession returns a session, or ErrNotFound.
+	GetSession(ctx context.Context, id string) (Session, error)
+
+	// ListSessions returns a player's sessions that are not revoked, oldest
+	// first.
+	ListSessions(ctx context.Context, playerID string) ([]Session, error)
+
+	// RevokedSessions returns the IDs of sessions revoked at or after since.
+	RevokedSessions(ctx context.Context, since time.Time) ([]string, error)
+}
+
+// MemRefreshStore is an in-memory RefreshStore, for development and tests.
+type MemRefreshStore struct {
+	mu       sync.Mutex
+	tokens   map[string]RefreshToken // ID -> token
+	sessions map[string]Session      // ID -> session
+}
+
+// NewMemRefreshStore returns an empty MemRefreshStore.
+func NewMemRefreshStore() *MemRefreshStore {
+	return &MemRefreshStore{tokens: map[string]RefreshToken{}, sessions: map[string]Session{}}
+}
+
+func (s *MemRefreshStore) SaveRefreshToken(ctx context.Context, t RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[t.ID] = t
+	return nil
+}
+
+func (s *MemRefreshStore) RevokeRefreshToken(ctx context.Context, id string, at time.Time) (RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[id]
+	if !ok {
+		return RefreshToken{}, ErrInvalidToken
+	}
+	if t.RevokedAt.IsZero() {
+		revoked := t
+		revoked.RevokedAt = at
+		s.tokens[id] = revoked
+	}
+	return t, nil
+}
+
+func (s *MemRefreshStore) RevokeFamily(ctx context.Context, family string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess, ok := s.sessions[family]; ok && sess.RevokedAt.IsZero() {
+		sess.RevokedAt = at
+		s.sessions[family] = sess
+	}
+	for id, t := range s.tokens {
+		if t.Family == family && t.RevokedAt.IsZero() {
+			t.RevokedAt = at
+			s.tokens[id] = t
+		}
+	}
+	return nil
+}
+
+func (s *MemRefreshStore) RevokePlayer(ctx context.Context, playerID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sess := range s.sessions {
+		if sess.PlayerID == playerID && sess.RevokedAt.IsZero() {
+			sess.RevokedAt = at
+			s.sessions[id] = sess
+		}
+	}
+	for id, t := range s.tokens {
+		if t.PlayerID == playerID && t.RevokedAt.IsZero() {
+			t.RevokedAt = at
+			s.tokens[id] = t
+		}
+	}
+	return nil
+}
+
+func (s *MemRefreshStore) SaveSession(ctx context.Context, sess Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[sess.ID] = sess
+	return nil
+}
+
+func (s *MemRefreshStore) TouchSession(ctx context.Context, id string, at, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	sess.RefreshedAt, sess.ExpiresAt = at, expiresAt
+	s.sessions[id] = sess
+	return nil
+}
+
+func (s *MemRefreshStore) GetSession(ctx context.Context, id string) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+	return sess, nil
+}
+
+func (s *MemRefreshStore) ListSessions(ctx context.Context, playerID string) ([]Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sessions []Session
+	for _, sess := range s.sessions {
+		if sess.PlayerID == playerID && sess.RevokedAt.IsZero() {
+			sessions = append(sessions, sess)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return sessions, nil
+}
+
+func (s *MemRefreshStore) RevokedSessions(ctx context.Context, since time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	for _, id := range slices.Sorted(maps.Keys(s.sessions)) {
+		if at := s.sessions[id].RevokedAt; !at.IsZero() && !at.Before(since) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,165 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+// Session is one login of a player. It lasts across refreshes until it is
+// revoked or goes unrefreshed until ExpiresAt.
+type Session struct {
+	// Shared by every refresh token issued to the session, as their Family.
+	ID       string
+	PlayerID string
+
+	CreatedAt   time.Time
+	RefreshedAt time.Time
+
+	// When the session's latest refresh token expires.
+	ExpiresAt time.Time
+
+	// When the session was revoked. Zero while it is active.
+	RevokedAt time.Time
+}
+
+// Sessions returns a player's active sessions, oldest first.
+func (s *Store) Sessions(ctx context.Context, playerID string) ([]Session, error) {
+	all, err := s.refresh.ListSessions(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	var active []Session
+	for _, sess := range all {
+		if now.Before(sess.ExpiresAt) {
+			active = append(active, sess)
+		}
+	}
+	return active, nil
+}
+
+// Revoke ends one of a player's sessions. It returns ErrNotFound if the
+// player has no such session.
+func (s *Store) Revoke(ctx context.Context, playerID, sessionID string) error {
+	sess, err := s.refresh.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if sess.PlayerID != playerID {
+		return ErrNotFound
+	}
+	return s.revokeFamily(ctx, sessionID, s.now())
+}
+
+// Watch returns a context derived from ctx that is canceled, with cause
+// ErrRevoked, when the session ctx was authenticated with is revoked, so
+// that long-lived streams end with their session. Call stop to release it.
+//
+// Revocations on this replica take effect at once; those on others take
+// effect when Sync next runs.
+func (s *Store) Watch(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	id, ok := SessionFrom(ctx)
+	if !ok {
+		return ctx, func() { cancel(nil) }
+	}
+	w := &watch{cancel: cancel}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, revoked := s.revoked[id]; revoked {
+		cancel(ErrRevoked)
+		return ctx, func() {}
+	}
+	if s.watches[id] == nil {
+		s.watches[id] = map[*watch]struct{}{}
+	}
+	s.watches[id][w] = struct{}{}
+	return ctx, func() {
+		s.mu.Lock()
+		delete(s.watches[id], w)
+		if len(s.watches[id]) == 0 {
+			delete(s.watches, id)
+		}
+		s.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// RevokedSessions returns the IDs of sessions revoked at or after since, by
+// any replica.
+func (s *Store) RevokedSessions(ctx context.Context, since time.Time) ([]string, error) {
+	return s.refresh.RevokedSessions(ctx, since)
+}
+
+// Sync learns of sessions revoked by other replicas, or elsewhere if the
+// store was made WithRevocations, so that their access tokens stop working
+// here too and their streams end.
+func (s *Store) Sync(ctx context.Context) error {
+	// Access tokens of sessions revoked longer ago than this have expired.
+	ids, err := s.revocations.RevokedSessions(ctx, s.now().Add(-s.accessTTL))
+	if err != nil {
+		return err
+	}
+	s.forget(ids...)
+	return nil
+}
+
+// Run calls Sync every interval until ctx is done. Errors are passed to
+// onError, and do not stop the loop.
+func (s *Store) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	if err := s.Sync(ctx); err != nil {
+		onError(err)
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			if err := s.Sync(ctx); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (s *Store) revokeFamily(ctx context.Context, family string, at time.Time) error {
+	if err := s.refresh.RevokeFamily(ctx, family, at); err != nil {
+		return err
+	}
+	s.forget(family)
+	return nil
+}
+
+// forget rejects the sessions' access tokens from now until they expire,
+// and ends their streams.
+func (s *Store) forget(ids ...string) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, until := range s.revoked {
+		if !now.Before(until) {
+			delete(s.revoked, id)
+		}
+	}
+	for _, id := range ids {
+		if _, ok := s.revoked[id]; !ok {
+			s.revoked[id] = now.Add(s.accessTTL)
+		}
+		for w := range s.watches[id] {
+			w.cancel(ErrRevoked)
+		}
+		delete(s.watches, id)
+	}
+}
+
+func (s *Store) isRevoked(id string) bool {
+	if id == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	until, ok := s.revoked[id]
+	return ok && s.now().Before(until)
+}
@@ -0,0 +1,136 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestSessions(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewStore()
+	s.now = func() time.Time { return now }
+
+	phone, err := s.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	now = now.Add(time.Minute)
+	laptop, err := s.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	_, err = s.Login(ctx, "bob")
+	AssertThat(t, err, Nil())
+
+	now = now.Add(time.Minute)
+	laptop, err = s.Refresh(ctx, laptop.Refresh)
+	AssertThat(t, err, Nil())
+
+	sessions, err := s.Sessions(ctx, "alice")
+	AssertThat(t, err, Nil())
+	AssertThat(t, sessions, Len(2))
+	ExpectEq(t, sessions[0].CreatedAt, time.Unix(1000, 0))
+	ExpectEq(t, sessions[1].RefreshedAt, now)
+
+	authed, ok := s.Authenticate(ctx, laptop.Access)
+	AssertEq(t, ok, true)
+	id, ok := SessionFrom(authed)
+	AssertEq(t, ok, true)
+	ExpectEq(t, id, sessions[1].ID)
+	playerID, _ := PlayerFrom(authed)
+	ExpectEq(t, playerID, "alice")
+
+	// Players can only revoke their own sessions.
+	ExpectThat(t, s.Revoke(ctx, "bob", id), ErrorIs(ErrNotFound))
+	ExpectThat(t, s.Revoke(ctx, "alice", "bogus"), ErrorIs(ErrNotFound))
+
+	AssertThat(t, s.Revoke(ctx, "alice", id), Nil())
+	_, ok = s.Lookup(laptop.Access)
+	ExpectEq(t, ok, false)
+	_, err = s.Refresh(ctx, laptop.Refresh)
+	ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+	_, ok = s.Lookup(phone.Access)
+	ExpectEq(t, ok, true)
+	sessions, err = s.Sessions(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, sessions, Len(1))
+
+	AssertThat(t, s.LogoutAll(ctx, "alice"), Nil())
+	_, ok = s.Lookup(phone.Access)
+	ExpectEq(t, ok, false)
+	sessions, err = s.Sessions(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, sessions, Empty())
+}
+
+func TestWatch(t *testing.T) {
+	s := NewStore()
+	tokens, err := s.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	authed, _ := s.Authenticate(ctx, tokens.Access)
+
+	watched, stop := s.Watch(authed)
+	defer stop()
+	kept, stopKept := s.Watch(authed)
+	stopKept()
+	ExpectThat(t, context.Cause(kept), Eq(context.Canceled))
+
+	AssertThat(t, s.Logout(ctx, tokens.Refresh), Nil())
+	<-watched.Done()
+	ExpectThat(t, context.Cause(watched), ErrorIs(ErrRevoked))
+
+	// Watching a revoked session ends at once.
+	late, stop := s.Watch(authed)
+	defer stop()
+	ExpectThat(t, context.Cause(late), ErrorIs(ErrRevoked))
+
+	// Tokens without a session are never revoked.
+	plain, _ := s.Authenticate(ctx, s.Create("alice"))
+	unwatched, stop := s.Watch(plain)
+	defer stop()
+	AssertThat(t, s.LogoutAll(ctx, "alice"), Nil())
+	ExpectThat(t, unwatched.Err(), Nil())
+}
+
+func TestSync(t *testing.T) {
+	// Two replicas sharing a key and a store.
+	refresh := NewMemRefreshStore()
+	a := NewStore(WithKey([]byte("key")), WithRefreshStore(refresh))
+	b := NewStore(WithKey([]byte("key")), WithRefreshStore(refresh))
+
+	tokens, err := a.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	authed, ok := b.Authenticate(ctx, tokens.Access)
+	AssertEq(t, ok, true)
+	watched, stop := b.Watch(authed)
+	defer stop()
+
+	AssertThat(t, a.LogoutAll(ctx, "alice"), Nil())
+	_, ok = a.Lookup(tokens.Access)
+	ExpectEq(t, ok, false)
+	_, ok = b.Lookup(tokens.Access)
+	ExpectEq(t, ok, true)
+
+	AssertThat(t, b.Sync(ctx), Nil())
+	_, ok = b.Lookup(tokens.Access)
+	ExpectEq(t, ok, false)
+	ExpectThat(t, context.Cause(watched), ErrorIs(ErrRevoked))
+}
+
+func TestSync_WithRevocations(t *testing.T) {
+	// A matchmaker, and a game server that only checks its tokens.
+	mm := NewStore(WithKey([]byte("key")))
+	gs := NewStore(WithKey([]byte("key")), WithRevocations(mm))
+
+	tokens, err := mm.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	authed, ok := gs.Authenticate(ctx, tokens.Access)
+	AssertEq(t, ok, true)
+	watched, stop := gs.Watch(authed)
+	defer stop()
+
+	AssertThat(t, mm.Logout(ctx, tokens.Refresh), Nil())
+	AssertThat(t, gs.Sync(ctx), Nil())
+	_, ok = gs.Lookup(tokens.Access)
+	ExpectEq(t, ok, false)
+	ExpectThat(t, context.Cause(watched), ErrorIs(ErrRevoked))
+}
@@ -0,0 +1,339 @@
+// Package session issues and checks the tokens that logged-in players
+// present. Access tokens are short-lived JWTs, checked without any shared
+// state but a list of recently revoked sessions; refresh tokens are
+// long-lived, kept in a RefreshStore, and replaced each time they are used.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+var (
+	// ErrInvalidToken is returned for refresh tokens that are unknown,
+	// expired or revoked.
+	ErrInvalidToken = errors.New("invalid or expired refresh token")
+
+	ErrNotFound = errors.New("session not found")
+
+	// ErrRevoked is the cause of contexts from Watch that are canceled
+	// because their session was revoked.
+	ErrRevoked = errors.New("session revoked")
+)
+
+const (
+	DefaultAccessTTL  = 15 * time.Minute
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+
+	issuer = "snapfold"
+)
+
+// Tokens are issued at login and on refresh.
+type Tokens struct {
+	PlayerID string
+
+	// Presented as a bearer token on every request.
+	Access    string
+	ExpiresAt time.Time
+
+	// Exchanged for new Tokens once Access expires. It may only be used
+	// once.
+	Refresh string
+}
+
+// Store issues and verifies session tokens. It is safe for concurrent use.
+type Store struct {
+	key         []byte
+	refresh     RefreshStore
+	revocations Revocations
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+	now         func() time.Time
+
+	mu sync.Mutex
+	// Sessions revoked recently enough that access tokens issued to them
+	// may not have expired, and when they will have.
+	revoked map[string]time.Time
+	// Contexts to cancel when a session is revoked, by session ID.
+	watches map[string]map[*watch]struct{}
+}
+
+type watch struct {
+	cancel context.CancelCauseFunc
+}
+
+// claims are carried by access tokens.
+type claims struct {
+	jwt.RegisteredClaims
+
+	// The session the token was issued to. Empty for tokens from Create,
+	// which cannot be revoked.
+	Session string `json:"sid,omitempty"`
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithKey sets the key access tokens are signed with. Every replica must
+// share it. By default a random key is generated, so tokens only work with
+// the replica that issued them and do not survive a restart.
+func WithKey(key []byte) Option {
+	return func(s *Store) { s.key = key }
+}
+
+// WithRefreshStore sets where refresh tokens are kept. Defaults to a
+// MemRefreshStore.
+func WithRefreshStore(rs RefreshStore) Option {
+	return func(s *Store) { s.refresh = rs }
+}
+
+// Revocations reports the sessions revoked at or after since. Every
+// RefreshStore does, as does a Store.
+type Revocations interface {
+	RevokedSessions(ctx context.Context, since time.Time) ([]string, error)
+}
+
+// WithRevocations has Sync learn of revoked sessions from r rather than the
+// refresh store, for stores that only check tokens another service issues,
+// such as a game server's, which learns of them from the matchmaker.
+func WithRevocations(r Revocations) Option {
+	return func(s *Store) { s.revocations = r }
+}
+
+// WithTTLs sets how long access and refresh tokens last. Non-positive TTLs
+// keep their defaults, DefaultAccessTTL and DefaultRefreshTTL.
+func WithTTLs(access, refresh time.Duration) Option {
+	return func(s *Store) {
+		if access > 0 {
+			s.accessTTL = access
+		}
+		if refresh > 0 {
+			s.refreshTTL = refresh
+		}
+	}
+}
+
+// NewStore returns a session store.
+func NewStore(opts ...Option) *Store {
+	s := &Store{
+		accessTTL:  DefaultAccessTTL,
+		refreshTTL: DefaultRefreshTTL,
+		now:        time.Now,
+		revoked:    map[string]time.Time{},
+		watches:    map[string]map[*watch]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.key == nil {
+		s.key = make([]byte, 32)
+		if _, err := rand.Read(s.key); err != nil {
+			panic(err)
+		}
+	}
+	if s.refresh == nil {
+		s.refresh = NewMemRefreshStore()
+	}
+	if s.revocations == nil {
+		s.revocations = s.refresh
+	}
+	return s
+}
+
+// Create returns an access token for the player, without a refresh token
+// or a session that could be revoked.
+func (s *Store) Create(playerID string) string {
+	token, _ := s.access(playerID, "")
+	return token
+}
+
+// Login starts a new session for the player.
+func (s *Store) Login(ctx context.Context, playerID string) (Tokens, error) {
+	now := s.now()
+	sess := Session{
+		ID:          queue.NewID(),
+		PlayerID:    playerID,
+		CreatedAt:   now,
+		RefreshedAt: now,
+		ExpiresAt:   now.Add(s.refreshTTL),
+	}
+	if err := s.refresh.SaveSession(ctx, sess); err != nil {
+		return Tokens{}, err
+	}
+	return s.issue(ctx, sess)
+}
+
+// Refresh exchanges a refresh token for new tokens, revoking it. Presenting
+// a refresh token that was already exchanged means it has leaked, so every
+// token descended from the same login is revoked and ErrInvalidToken is
+// returned.
+func (s *Store) Refresh(ctx context.Context, refreshToken string) (Tokens, error) {
+	now := s.now()
+	t, err := s.refresh.RevokeRefreshToken(ctx, hashToken(refreshToken), now)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if !t.RevokedAt.IsZero() {
+		if err := s.revokeFamily(ctx, t.Family, now); err != nil {
+			return Tokens{}, err
+		}
+		return Tokens{}, ErrInvalidToken
+	}
+	if !now.Before(t.ExpiresAt) {
+		return Tokens{}, ErrInvalidToken
+	}
+	sess := Session{
+		ID:          t.Family,
+		PlayerID:    t.PlayerID,
+		RefreshedAt: now,
+		ExpiresAt:   now.Add(s.refreshTTL),
+	}
+	if err := s.refresh.TouchSession(ctx, sess.ID, sess.RefreshedAt, sess.ExpiresAt); err != nil {
+		return Tokens{}, err
+	}
+	return s.issue(ctx, sess)
+}
+
+// Logout ends the session a refresh token belongs to.
+func (s *Store) Logout(ctx context.Context, refreshToken string) error {
+	now := s.now()
+	t, err := s.refresh.RevokeRefreshToken(ctx, hashToken(refreshToken), now)
+	if err != nil {
+		return err
+	}
+	return s.revokeFamily(ctx, t.Family, now)
+}
+
+// LogoutAll ends every session of a player, such as after their password
+// changes.
+func (s *Store) LogoutAll(ctx context.Context, playerID string) error {
+	sessions, err := s.refresh.ListSessions(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	if err := s.refresh.RevokePlayer(ctx, playerID, s.now()); err != nil {
+		return err
+	}
+	ids := make([]string, len(sessions))
+	for i, sess := range sessions {
+		ids[i] = sess.ID
+	}
+	s.forget(ids...)
+	return nil
+}
+
+// Lookup returns the player ID for a valid access token.
+func (s *Store) Lookup(token string) (string, bool) {
+	c, ok := s.parse(token)
+	return c.Subject, ok
+}
+
+// Authenticate checks an access token and returns ctx carrying the player
+// and session it was issued to, for PlayerFrom and SessionFrom.
+func (s *Store) Authenticate(ctx context.Context, token string) (context.Context, bool) {
+	c, ok := s.parse(token)
+	if !ok {
+		return nil, false
+	}
+	ctx = WithPlayer(ctx, c.Subject)
+	if c.Session != "" {
+		ctx = context.WithValue(ctx, sessionKey{}, c.Session)
+	}
+	return ctx, true
+}
+
+func (s *Store) parse(token string) (claims, bool) {
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, func(*jwt.Token) (any, error) {
+		return s.key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(s.now),
+	)
+	if err != nil || c.Subject == "" || s.isRevoked(c.Session) {
+		return claims{}, false
+	}
+	return c, true
+}
+
+func (s *Store) issue(ctx context.Context, sess Session) (Tokens, error) {
+	access, expiresAt := s.access(sess.PlayerID, sess.ID)
+	refresh := queue.NewID()
+	err := s.refresh.SaveRefreshToken(ctx, RefreshToken{
+		ID:        hashToken(refresh),
+		PlayerID:  sess.PlayerID,
+		Family:    sess.ID,
+		ExpiresAt: sess.ExpiresAt,
+	})
+	if err != nil {
+		return Tokens{}, err
+	}
+	return Tokens{PlayerID: sess.PlayerID, Access: access, ExpiresAt: expiresAt, Refresh: refresh}, nil
+}
+
+func (s *Store) access(playerID, sessionID string) (string, time.Time) {
+	now := s.now()
+	expiresAt := now.Add(s.accessTTL)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   playerID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Session: sessionID,
+	}).SignedString(s.key)
+	if err != nil {
+		// Signing with HMAC only fails for keys of the wrong type.
+		panic(err)
+	}
+	return token, expiresAt
+}
+
+// hashToken returns the ID a refresh token is stored under, so that the
+// store never holds a usable token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer <token>"
+// header value.
+func BearerToken(header string) (string, bool) {
+	return strings.CutPrefix(header, "Bearer ")
+}
+
+type (
+	playerKey  struct{}
+	sessionKey struct{}
+)
+
+// WithPlayer returns a context carrying the authenticated player ID.
+func WithPlayer(ctx context.Context, playerID string) context.Context {
+	return context.WithValue(ctx, playerKey{}, playerID)
+}
+
+// PlayerFrom returns the authenticated player ID stored by WithPlayer.
+func PlayerFrom(ctx context.Context) (string, bool) {
+	playerID, ok := ctx.Value(playerKey{}).(string)
+	return playerID, ok
+}
+
+// SessionFrom returns the ID of the session stored by Authenticate. There is
+// none for tokens from Create.
+func SessionFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sessionKey{}).(string)
+	return id, ok
+}
@@ -0,0 +1,142 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+var ctx = context.Background()
+
+func TestStore(t *testing.T) {
+	s := NewStore()
+	token := s.Create("alice")
+
+	playerID, ok := s.Lookup(token)
+	ExpectEq(t, ok, true)
+	ExpectEq(t, playerID, "alice")
+
+	_, ok = s.Lookup("bogus")
+	ExpectEq(t, ok, false)
+
+	// Tokens signed with another key are rejected.
+	_, ok = NewStore().Lookup(token)
+	ExpectEq(t, ok, false)
+	playerID, ok = NewStore(WithKey(s.key)).Lookup(token)
+	ExpectEq(t, ok, true)
+	ExpectEq(t, playerID, "alice")
+}
+
+func TestStore_AccessExpires(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewStore(WithTTLs(time.Minute, time.Hour))
+	s.now = func() time.Time { return now }
+
+	tokens, err := s.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, tokens.PlayerID, "alice")
+	ExpectEq(t, tokens.ExpiresAt, now.Add(time.Minute))
+	_, ok := s.Lookup(tokens.Access)
+	ExpectEq(t, ok, true)
+
+	now = now.Add(time.Minute)
+	_, ok = s.Lookup(tokens.Access)
+	ExpectEq(t, ok, false)
+
+	tokens, err = s.Refresh(ctx, tokens.Refresh)
+	AssertThat(t, err, Nil())
+	playerID, ok := s.Lookup(tokens.Access)
+	ExpectEq(t, ok, true)
+	ExpectEq(t, playerID, "alice")
+
+	// Refresh tokens expire too.
+	now = now.Add(time.Hour)
+	_, err = s.Refresh(ctx, tokens.Refresh)
+	ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+}
+
+func TestWithTTLs_Unset(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewStore(WithTTLs(0, 0))
+	s.now = func() time.Time { return now }
+
+	tokens, err := s.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, tokens.ExpiresAt, now.Add(DefaultAccessTTL))
+	_, ok := s.Lookup(tokens.Access)
+	ExpectEq(t, ok, true)
+	_, err = s.Refresh(ctx, tokens.Refresh)
+	ExpectThat(t, err, Nil())
+}
+
+func TestStore_RefreshRotates(t *testing.T) {
+	s := NewStore()
+	first, err := s.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	second, err := s.Refresh(ctx, first.Refresh)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, second.Refresh, Not(Eq(first.Refresh)))
+
+	// Reusing a refresh token revokes the whole session.
+	_, err = s.Refresh(ctx, first.Refresh)
+	ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+	_, err = s.Refresh(ctx, second.Refresh)
+	ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+
+	_, err = s.Refresh(ctx, "bogus")
+	ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+}
+
+func TestStore_Logout(t *testing.T) {
+	s := NewStore()
+	tokens, err := s.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	other, err := s.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+
+	AssertThat(t, s.Logout(ctx, tokens.Refresh), Nil())
+	_, err = s.Refresh(ctx, tokens.Refresh)
+	ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+
+	// Other logins are unaffected.
+	_, err = s.Refresh(ctx, other.Refresh)
+	ExpectThat(t, err, Nil())
+}
+
+func TestStore_LogoutAll(t *testing.T) {
+	s := NewStore()
+	first, err := s.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	second, err := s.Login(ctx, "alice")
+	AssertThat(t, err, Nil())
+	bob, err := s.Login(ctx, "bob")
+	AssertThat(t, err, Nil())
+
+	AssertThat(t, s.LogoutAll(ctx, "alice"), Nil())
+	_, err = s.Refresh(ctx, first.Refresh)
+	ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+	_, err = s.Refresh(ctx, second.Refresh)
+	ExpectThat(t, err, ErrorIs(ErrInvalidToken))
+	_, err = s.Refresh(ctx, bob.Refresh)
+	ExpectThat(t, err, Nil())
+}
+
+func TestBearerToken(t *testing.T) {
+	token, ok := BearerToken("Bearer abc")
+	ExpectEq(t, ok, true)
+	ExpectEq(t, token, "abc")
+
+	_, ok = BearerToken("Basic abc")
+	ExpectEq(t, ok, false)
+}
+
+func TestPlayerContext(t *testing.T) {
+	_, ok := PlayerFrom(context.Background())
+	ExpectEq(t, ok, false)
+
+	playerID, ok := PlayerFrom(WithPlayer(context.Background(), "alice"))
+	ExpectEq(t, ok, true)
+	ExpectEq(t, playerID, "alice")
+}
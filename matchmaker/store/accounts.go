@@ -0,0 +1,265 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jfmatt/snapfold/matchmaker/account"
+)
+
+// Accounts is an account.Store backed by the accounts table.
+type Accounts struct {
+	db *DB
+}
+
+// NewAccounts returns an account store using db.
+func NewAccounts(db *DB) *Accounts {
+	return &Accounts{db: db}
+}
+
+func (s *Accounts) CreateAccount(ctx context.Context, a account.Account) error {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO accounts (username, email, email_verified, password_hash, guest, role, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (username) DO NOTHING`,
+		a.Username, a.Email, a.EmailVerified, a.PasswordHash, a.Guest, a.Role, a.CreatedAt)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return account.ErrUsernameTaken
+	}
+	return nil
+}
+
+// banColumns are the accounts columns that hold a ban, read by banScan.
+const banColumns = "banned_at, banned_until, banned_by, ban_reason"
+
+// banScan holds the ban columns of a row as they are scanned.
+type banScan struct {
+	at, until  sql.NullTime
+	by, reason string
+}
+
+func (b *banScan) dest() []any {
+	return []any{&b.at, &b.until, &b.by, &b.reason}
+}
+
+// ban returns the ban scanned, or nil if the account has none.
+func (b *banScan) ban() *account.Ban {
+	if !b.at.Valid {
+		return nil
+	}
+	return &account.Ban{Reason: b.reason, By: b.by, At: b.at.Time, Until: b.until.Time}
+}
+
+// banValues returns the values of the ban columns for a ban.
+func banValues(b *account.Ban) []any {
+	if b == nil {
+		return []any{nil, nil, "", ""}
+	}
+	until := sql.NullTime{Time: b.Until, Valid: !b.Until.IsZero()}
+	return []any{b.At, until, b.By, b.Reason}
+}
+
+func (s *Accounts) GetAccount(ctx context.Context, username string) (account.Account, error) {
+	a := account.Account{Username: username}
+	var ban banScan
+	err := s.db.QueryRowContext(ctx, `
+		SELECT email, email_verified, password_hash, guest, role, created_at, `+banColumns+`
+		FROM accounts WHERE username = $1`, username).Scan(
+		append([]any{&a.Email, &a.EmailVerified, &a.PasswordHash, &a.Guest, &a.Role, &a.CreatedAt}, ban.dest()...)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return account.Account{}, account.ErrNotFound
+	}
+	if err != nil {
+		return account.Account{}, err
+	}
+	a.Ban = ban.ban()
+	return a, nil
+}
+
+func (s *Accounts) UpdateAccount(ctx context.Context, a account.Account) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE accounts SET email = $2, email_verified = $3, password_hash = $4, guest = $5, role = $6,
+			banned_at = $7, banned_until = $8, banned_by = $9, ban_reason = $10
+		WHERE username = $1`,
+		append([]any{a.Username, a.Email, a.EmailVerified, a.PasswordHash, a.Guest, a.Role}, banValues(a.Ban)...)...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return account.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Accounts) GetAccountsByEmail(ctx context.Context, email string) ([]account.Account, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT username, email_verified, password_hash, guest, role, created_at, `+banColumns+`
+		FROM accounts WHERE email = $1`, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var accounts []account.Account
+	for rows.Next() {
+		a := account.Account{Email: email}
+		var ban banScan
+		if err := rows.Scan(append([]any{&a.Username, &a.EmailVerified, &a.PasswordHash, &a.Guest, &a.Role, &a.CreatedAt}, ban.dest()...)...); err != nil {
+			return nil, err
+		}
+		a.Ban = ban.ban()
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+func (s *Accounts) LinkIdentity(ctx context.Context, l account.Link) error {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO identities (provider, subject, username, linked_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (provider, subject) DO NOTHING`,
+		l.Provider, l.Subject, l.Username, l.LinkedAt)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return account.ErrIdentityLinked
+	}
+	return nil
+}
+
+func (s *Accounts) GetIdentity(ctx context.Context, provider, subject string) (string, error) {
+	var username string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT username FROM identities WHERE provider = $1 AND subject = $2`, provider, subject).Scan(&username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", account.ErrNotFound
+	}
+	return username, err
+}
+
+func (s *Accounts) ListIdentities(ctx context.Context, username string) ([]account.Link, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT provider, subject, linked_at FROM identities
+		WHERE username = $1
+		ORDER BY linked_at, provider`, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var links []account.Link
+	for rows.Next() {
+		l := account.Link{Username: username}
+		if err := rows.Scan(&l.Provider, &l.Subject, &l.LinkedAt); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+func (s *Accounts) UnlinkIdentity(ctx context.Context, username, provider string) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM identities WHERE username = $1 AND provider = $2`, username, provider)
+	return err
+}
+
+func (s *Accounts) SaveVerification(ctx context.Context, v account.Verification) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO email_verifications (username, email, token_hash, sent_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (username) DO UPDATE SET
+			email = excluded.email,
+			token_hash = excluded.token_hash,
+			sent_at = excluded.sent_at,
+			expires_at = excluded.expires_at`,
+		v.Username, v.Email, v.TokenHash, v.SentAt, v.ExpiresAt)
+	return err
+}
+
+func (s *Accounts) GetVerification(ctx context.Context, username string) (account.Verification, error) {
+	v := account.Verification{Username: username}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT email, token_hash, sent_at, expires_at
+		FROM email_verifications WHERE username = $1`, username).Scan(
+		&v.Email, &v.TokenHash, &v.SentAt, &v.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return account.Verification{}, account.ErrNotFound
+	}
+	if err != nil {
+		return account.Verification{}, err
+	}
+	return v, nil
+}
+
+func (s *Accounts) TakeVerification(ctx context.Context, tokenHash string) (account.Verification, error) {
+	v := account.Verification{TokenHash: tokenHash}
+	err := s.db.QueryRowContext(ctx, `
+		DELETE FROM email_verifications WHERE token_hash = $1
+		RETURNING username, email, sent_at, expires_at`, tokenHash).Scan(
+		&v.Username, &v.Email, &v.SentAt, &v.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return account.Verification{}, account.ErrNotFound
+	}
+	if err != nil {
+		return account.Verification{}, err
+	}
+	return v, nil
+}
+
+func (s *Accounts) SaveReset(ctx context.Context, r account.Reset) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO password_resets (username, token_hash, sent_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (username) DO UPDATE SET
+			token_hash = excluded.token_hash,
+			sent_at = excluded.sent_at,
+			expires_at = excluded.expires_at`,
+		r.Username, r.TokenHash, r.SentAt, r.ExpiresAt)
+	return err
+}
+
+func (s *Accounts) GetReset(ctx context.Context, username string) (account.Reset, error) {
+	r := account.Reset{Username: username}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT token_hash, sent_at, expires_at
+		FROM password_resets WHERE username = $1`, username).Scan(
+		&r.TokenHash, &r.SentAt, &r.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return account.Reset{}, account.ErrNotFound
+	}
+	if err != nil {
+		return account.Reset{}, err
+	}
+	return r, nil
+}
+
+func (s *Accounts) TakeReset(ctx context.Context, tokenHash string) (account.Reset, error) {
+	r := account.Reset{TokenHash: tokenHash}
+	err := s.db.QueryRowContext(ctx, `
+		DELETE FROM password_resets WHERE token_hash = $1
+		RETURNING username, sent_at, expires_at`, tokenHash).Scan(
+		&r.Username, &r.SentAt, &r.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return account.Reset{}, account.ErrNotFound
+	}
+	if err != nil {
+		return account.Reset{}, err
+	}
+	return r, nil
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/jfmatt/snapfold/matchmaker/apikey"
+)
+
+// APIKeys is an apikey.Store backed by the api_keys table.
+type APIKeys struct {
+	db *DB
+}
+
+// NewAPIKeys returns an API key store using db.
+func NewAPIKeys(db *DB) *APIKeys {
+	return &APIKeys{db: db}
+}
+
+const apiKeyColumns = `id, name, scopes, secret_hash, created_at, revoked_at`
+
+func (s *APIKeys) SaveKey(ctx context.Context, k apikey.Key) error {
+	scopes, err := json.Marshal(k.Scopes)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO api_keys (`+apiKeyColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		k.ID, k.Name, string(scopes), k.SecretHash, k.CreatedAt, nullTime(k.RevokedAt))
+	return err
+}
+
+func (s *APIKeys) GetKey(ctx context.Context, id string) (apikey.Key, error) {
+	k, err := scanAPIKey(s.db.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return apikey.Key{}, apikey.ErrNotFound
+	}
+	return k, err
+}
+
+func (s *APIKeys) UpdateKey(ctx context.Context, k apikey.Key) error {
+	scopes, err := json.Marshal(k.Scopes)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET name = $2, scopes = $3, secret_hash = $4, revoked_at = $5
+		WHERE id = $1`,
+		k.ID, k.Name, string(scopes), k.SecretHash, nullTime(k.RevokedAt))
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return apikey.ErrNotFound
+	}
+	return nil
+}
+
+func (s *APIKeys) ListKeys(ctx context.Context) ([]apikey.Key, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []apikey.Key
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func scanAPIKey(row interface{ Scan(...any) error }) (apikey.Key, error) {
+	var k apikey.Key
+	var scopes []byte
+	var revokedAt sql.NullTime
+	if err := row.Scan(&k.ID, &k.Name, &scopes, &k.SecretHash, &k.CreatedAt, &revokedAt); err != nil {
+		return apikey.Key{}, err
+	}
+	if err := json.Unmarshal(scopes, &k.Scopes); err != nil {
+		return apikey.Key{}, err
+	}
+	k.RevokedAt = revokedAt.Time
+	return k, nil
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+)
+
+// AuditLog is an audit.Store backed by the audit_log table, which refuses
+// updates and deletes.
+type AuditLog struct {
+	db *DB
+}
+
+// NewAuditLog returns an audit log store using db.
+func NewAuditLog(db *DB) *AuditLog {
+	return &AuditLog{db: db}
+}
+
+const auditColumns = `id, time, actor, action, target, before, after`
+
+func (s *AuditLog) Append(ctx context.Context, e audit.Entry) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO audit_log (`+auditColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		e.ID, e.Time, e.Actor, string(e.Action), e.Target, nullJSON(e.Before), nullJSON(e.After))
+	return err
+}
+
+func (s *AuditLog) List(ctx context.Context, q audit.Query) ([]audit.Entry, error) {
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE TRUE`
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if q.Actor != "" {
+		query += ` AND actor = ` + arg(q.Actor)
+	}
+	if q.Action != "" {
+		query += ` AND action = ` + arg(string(q.Action))
+	}
+	if q.Target != "" {
+		query += ` AND target = ` + arg(q.Target)
+	}
+	op, order := "<", "DESC"
+	if q.OldestFirst {
+		op, order = ">", "ASC"
+	}
+	if q.AfterID != "" {
+		query += fmt.Sprintf(` AND (time, id) %s (%s, %s)`, op, arg(q.AfterTime), arg(q.AfterID))
+	}
+	query += fmt.Sprintf(` ORDER BY time %s, id %s LIMIT %d`, order, order, q.Limit)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []audit.Entry
+	for rows.Next() {
+		var e audit.Entry
+		var action string
+		var before, after sql.NullString
+		if err := rows.Scan(&e.ID, &e.Time, &e.Actor, &action, &e.Target, &before, &after); err != nil {
+			return nil, err
+		}
+		e.Action = audit.Action(action)
+		if before.Valid {
+			e.Before = []byte(before.String)
+		}
+		if after.Valid {
+			e.After = []byte(after.String)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// nullJSON returns NULL for a missing JSON value.
+func nullJSON(b []byte) sql.NullString {
+	return sql.NullString{String: string(b), Valid: b != nil}
+}
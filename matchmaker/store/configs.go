@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jfmatt/snapfold/matchmaker/registry"
+)
+
+// Configs is a registry.Store backed by the configs table.
+type Configs struct {
+	db *DB
+}
+
+// NewConfigs returns a config registry store using db.
+func NewConfigs(db *DB) *Configs {
+	return &Configs{db: db}
+}
+
+const configColumns = `kind, name, config, revision, updated_at`
+
+func (s *Configs) GetConfig(ctx context.Context, kind registry.Kind, name string) (registry.Entry, error) {
+	e, err := scanConfig(s.db.QueryRowContext(ctx, `
+		SELECT `+configColumns+` FROM configs WHERE kind = $1 AND name = $2`, string(kind), name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return registry.Entry{}, registry.ErrNotFound
+	}
+	return e, err
+}
+
+func (s *Configs) SaveConfig(ctx context.Context, e registry.Entry) error {
+	// The update only applies to the revision before e's, so that two
+	// writers can't both replace the same revision.
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO configs (`+configColumns+`)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (kind, name) DO UPDATE SET
+			config = excluded.config,
+			revision = excluded.revision,
+			updated_at = excluded.updated_at
+		WHERE configs.revision = excluded.revision - 1`,
+		string(e.Kind), e.Name, string(e.Config), e.Revision, e.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return registry.ErrConflict
+	}
+	return nil
+}
+
+func (s *Configs) ListConfigs(ctx context.Context, kind registry.Kind) ([]registry.Entry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+configColumns+` FROM configs WHERE kind = $1 ORDER BY name`, string(kind))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []registry.Entry
+	for rows.Next() {
+		e, err := scanConfig(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *Configs) DeleteConfig(ctx context.Context, kind registry.Kind, name string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM configs WHERE kind = $1 AND name = $2`, string(kind), name)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return registry.ErrNotFound
+	}
+	return nil
+}
+
+func scanConfig(row interface{ Scan(...any) error }) (registry.Entry, error) {
+	var e registry.Entry
+	if err := row.Scan(&e.Kind, &e.Name, &e.Config, &e.Revision, &e.UpdatedAt); err != nil {
+		return registry.Entry{}, err
+	}
+	return e, nil
+}
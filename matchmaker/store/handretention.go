@@ -0,0 +1,194 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+	"strings"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/history"
+)
+
+// In Postgres, hand histories are partitioned by the UTC day they were
+// played, in tables named hand_histories_YYYYMMDD, so that retention drops
+// a day's hands at once rather than deleting them row by row. PruneHands
+// makes the partitions for the coming week; hands played on a day without
+// one, and those from before partitioning, are kept in
+// hand_histories_default, which is pruned row by row like SQLite's table.
+
+const (
+	handPartitionPrefix = "hand_histories_"
+	handPartitionFormat = "20060102"
+	handDefaultTable    = "hand_histories_default"
+
+	// Days ahead PruneHands makes partitions for, so that hands keep going
+	// to them if it fails to run for a while.
+	handPartitionsAhead = 7
+
+	// Key of the Postgres advisory lock held while pruning, so that only
+	// one replica prunes at a time.
+	handsLock = 0x68616e6473 // "hands"
+)
+
+func (s *Matches) PruneHands(ctx context.Context, before time.Time, archive history.ArchiveFunc) error {
+	if s.db.Dialect == SQLite {
+		return pruneHandRows(ctx, s.db, "hand_histories", before, archive)
+	}
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, handsLock).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		// Another replica is pruning.
+		return nil
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, handsLock)
+
+	days, err := handPartitions(ctx, conn)
+	if err != nil {
+		return err
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for i := range handPartitionsAhead + 1 {
+		day := today.AddDate(0, 0, i)
+		if !days[day] {
+			if err := createHandPartition(ctx, conn, day); err != nil {
+				return err
+			}
+		}
+	}
+	if before.IsZero() {
+		return nil
+	}
+	for day := range days {
+		end := day.AddDate(0, 0, 1)
+		if end.After(before) {
+			continue
+		}
+		table := handPartitionPrefix + day.Format(handPartitionFormat)
+		if archive != nil {
+			if err := archive(ctx, day, end, scanHands(ctx, conn, `SELECT `+handColumns+` FROM `+table+` ORDER BY `+handOrder)); err != nil {
+				return err
+			}
+		}
+		if _, err := conn.ExecContext(ctx, `DROP TABLE `+table); err != nil {
+			return err
+		}
+	}
+	return pruneHandRows(ctx, conn, handDefaultTable, before, archive)
+}
+
+const (
+	handColumns = `hand_number, player_id, table_id, played_at, text`
+	handOrder   = `played_at, hand_number, player_id`
+)
+
+// handPartitions returns the days hand_histories has partitions for.
+func handPartitions(ctx context.Context, q queryer) (map[time.Time]bool, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'hand_histories'::regclass`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	days := map[time.Time]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if day, err := time.Parse(handPartitionFormat, strings.TrimPrefix(name, handPartitionPrefix)); err == nil {
+			days[day] = true
+		}
+	}
+	return days, rows.Err()
+}
+
+// createHandPartition makes the partition for a day, moving any of its
+// hands out of the default partition, which may not overlap it.
+func createHandPartition(ctx context.Context, conn *sql.Conn, day time.Time) error {
+	table := handPartitionPrefix + day.Format(handPartitionFormat)
+	end := day.AddDate(0, 0, 1)
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE `+table+` (LIKE hand_histories INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		WITH moved AS (
+			DELETE FROM `+handDefaultTable+` WHERE played_at >= $1 AND played_at < $2
+			RETURNING `+handColumns+`
+		)
+		INSERT INTO `+table+` (`+handColumns+`) SELECT `+handColumns+` FROM moved`,
+		day, end)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `ALTER TABLE hand_histories ATTACH PARTITION `+table+
+		` FOR VALUES FROM ('`+day.Format(time.RFC3339)+`') TO ('`+end.Format(time.RFC3339)+`')`)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// pruneHandRows archives and deletes the hands in table played before
+// before, as one batch.
+func pruneHandRows(ctx context.Context, q queryer, table string, before time.Time, archive history.ArchiveFunc) error {
+	if before.IsZero() {
+		return nil
+	}
+	if archive != nil {
+		var found bool
+		if err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+table+` WHERE played_at < $1)`, before).Scan(&found); err != nil {
+			return err
+		}
+		if !found {
+			return nil
+		}
+		hands := scanHands(ctx, q, `SELECT `+handColumns+` FROM `+table+` WHERE played_at < $1 ORDER BY `+handOrder, before)
+		if err := archive(ctx, time.Time{}, before, hands); err != nil {
+			return err
+		}
+	}
+	_, err := q.ExecContext(ctx, `DELETE FROM `+table+` WHERE played_at < $1`, before)
+	return err
+}
+
+// scanHands returns an iterator over the hands a query returns.
+func scanHands(ctx context.Context, q queryer, query string, args ...any) iter.Seq2[history.Hand, error] {
+	return func(yield func(history.Hand, error) bool) {
+		rows, err := q.QueryContext(ctx, query, args...)
+		if err != nil {
+			yield(history.Hand{}, err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var h history.Hand
+			var number int64
+			if err := rows.Scan(&number, &h.PlayerID, &h.TableID, &h.PlayedAt, &h.Text); err != nil {
+				yield(history.Hand{}, err)
+				return
+			}
+			h.Number = uint64(number)
+			if !yield(h, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(history.Hand{}, err)
+		}
+	}
+}
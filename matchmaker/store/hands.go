@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/history"
+)
+
+func (s *Matches) SaveHands(ctx context.Context, hands []history.Hand) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, h := range hands {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO hand_histories (hand_number, player_id, table_id, played_at, text)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (hand_number, player_id, played_at) DO UPDATE
+			SET table_id = EXCLUDED.table_id, text = EXCLUDED.text`,
+			int64(h.Number), h.PlayerID, h.TableID, h.PlayedAt, h.Text)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Matches) ListHands(ctx context.Context, playerID string, since time.Time, limit int) ([]history.Hand, error) {
+	// Players download hands they have finished playing, so a few seconds
+	// behind loses at most the last one.
+	rows, err := s.db.read(ctx, 5*time.Second, `
+		SELECT hand_number, player_id, table_id, played_at, text FROM hand_histories
+		WHERE player_id = $1 AND played_at >= $2
+		ORDER BY played_at, hand_number
+		LIMIT `+strconv.Itoa(limit),
+		playerID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hands []history.Hand
+	for rows.Next() {
+		var h history.Hand
+		var number int64
+		if err := rows.Scan(&number, &h.PlayerID, &h.TableID, &h.PlayedAt, &h.Text); err != nil {
+			return nil, err
+		}
+		h.Number = uint64(number)
+		hands = append(hands, h)
+	}
+	return hands, rows.Err()
+}
+
+func (s *Matches) SaveRake(ctx context.Context, r history.Rake) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO hand_rake (hand_number, table_id, played_at, amount)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (hand_number) DO UPDATE
+		SET table_id = EXCLUDED.table_id, played_at = EXCLUDED.played_at, amount = EXCLUDED.amount`,
+		int64(r.Number), r.TableID, r.PlayedAt, r.Amount)
+	return err
+}
+
+func (s *Matches) SumRake(ctx context.Context, since, until time.Time) (map[string]int64, error) {
+	// Rake reports cover whole days, and are read by admins well after.
+	rows, err := s.db.read(ctx, time.Minute, `
+		SELECT table_id, SUM(amount) FROM hand_rake
+		WHERE played_at >= $1 AND played_at < $2 AND amount > 0
+		GROUP BY table_id`,
+		since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tables := map[string]int64{}
+	for rows.Next() {
+		var id string
+		var amount int64
+		if err := rows.Scan(&id, &amount); err != nil {
+			return nil, err
+		}
+		tables[id] = amount
+	}
+	return tables, rows.Err()
+}
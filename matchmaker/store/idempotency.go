@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jfmatt/snapfold/lib/idempotency"
+)
+
+// IdempotencyKeys is an idempotency.Store backed by the idempotency_keys
+// table, so that a retry is recognized by whichever matchmaker it reaches.
+type IdempotencyKeys struct {
+	db *DB
+}
+
+// NewIdempotencyKeys returns an idempotency key store using db.
+func NewIdempotencyKeys(db *DB) *IdempotencyKeys {
+	return &IdempotencyKeys{db: db}
+}
+
+func (s *IdempotencyKeys) Reserve(ctx context.Context, r idempotency.Record, notBefore time.Time) (idempotency.Record, bool, error) {
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE scope = $1 AND idempotency_key = $2
+		AND (created_at <= $3 OR (NOT done AND lease_until <= $4))`,
+		r.Scope, r.Key, notBefore, r.CreatedAt); err != nil {
+		return idempotency.Record{}, false, err
+	}
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (scope, idempotency_key, fingerprint, created_at, lease_until)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (scope, idempotency_key) DO NOTHING`,
+		r.Scope, r.Key, r.Fingerprint, r.CreatedAt, nullTime(r.LeaseUntil))
+	if err != nil {
+		return idempotency.Record{}, false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 1 {
+		return idempotency.Record{}, err == nil, err
+	}
+
+	existing := idempotency.Record{Scope: r.Scope, Key: r.Key}
+	var leaseUntil sql.NullTime
+	err = s.db.QueryRowContext(ctx, `
+		SELECT fingerprint, done, response, created_at, lease_until FROM idempotency_keys
+		WHERE scope = $1 AND idempotency_key = $2`,
+		r.Scope, r.Key).Scan(&existing.Fingerprint, &existing.Done, &existing.Response, &existing.CreatedAt, &leaseUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The request that held the key failed and released it just now.
+		return idempotency.Record{}, false, idempotency.ErrInProgress
+	}
+	existing.LeaseUntil = leaseUntil.Time
+	return existing, false, err
+}
+
+func (s *IdempotencyKeys) Complete(ctx context.Context, scope, key string, response []byte) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET done = true, response = $3
+		WHERE scope = $1 AND idempotency_key = $2`,
+		scope, key, response)
+	return err
+}
+
+func (s *IdempotencyKeys) Release(ctx context.Context, scope, key string) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE scope = $1 AND idempotency_key = $2`,
+		scope, key)
+	return err
+}
+
+func (s *IdempotencyKeys) DeleteBefore(ctx context.Context, t time.Time) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE created_at < $1`, t)
+	return err
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jfmatt/snapfold/lib/metrics"
+	"github.com/jfmatt/snapfold/lib/tracing"
+)
+
+// Instrument records how long each statement run on db takes in latency,
+// labeled by op: exec or query. Queries are timed until their results are
+// ready to read. Statements in transactions aren't timed.
+func (db *DB) Instrument(latency *metrics.Histogram) {
+	db.latency = latency
+}
+
+// ExecContext runs a statement on the primary, timing and tracing it.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startStatement(ctx, "exec", query)
+	defer db.latency.Since(time.Now(), "exec")
+	res, err := db.DB.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return res, err
+}
+
+// QueryContext runs a query on the primary, timing and tracing it.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startStatement(ctx, "query", query)
+	defer db.latency.Since(time.Now(), "query")
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+// QueryRowContext runs a query for one row on the primary, timing and
+// tracing it.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startStatement(ctx, "query", query)
+	defer db.latency.Since(time.Now(), "query")
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	tracing.End(span, row.Err())
+	return row
+}
+
+// read runs a query whose results may be up to staleness behind the
+// primary, on the reader, timing and tracing it.
+func (db *DB) read(ctx context.Context, staleness time.Duration, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startStatement(ctx, "query", query)
+	defer db.latency.Since(time.Now(), "query")
+	rows, err := db.reader(staleness).QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+// startStatement starts the span of a statement outside a transaction.
+func startStatement(ctx context.Context, op, query string) (context.Context, trace.Span) {
+	return otel.Tracer("github.com/jfmatt/snapfold/matchmaker/store").Start(ctx, "db."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBOperationName(op), semconv.DBQueryText(query)))
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/jfmatt/snapfold/lib/metrics"
+)
+
+func TestInstrument(t *testing.T) {
+	db := openTest(t)
+	reg := metrics.NewRegistry()
+	db.Instrument(reg.Histogram("statement_seconds", "Statements.", metrics.DefaultBuckets, "op"))
+
+	_, err := db.ExecContext(ctx, `CREATE TABLE t (n INTEGER)`)
+	AssertThat(t, err, Nil())
+	var n int
+	AssertThat(t, db.QueryRowContext(ctx, `SELECT COUNT(*) FROM t`).Scan(&n), Nil())
+	rows, err := db.read(ctx, time.Minute, `SELECT n FROM t`)
+	AssertThat(t, err, Nil())
+	rows.Close()
+
+	var b strings.Builder
+	_, err = reg.WriteTo(&b)
+	AssertThat(t, err, Nil())
+	lines := strings.Split(b.String(), "\n")
+	ExpectThat(t, lines, Contains(`statement_seconds_count{op="exec"} 1`))
+	ExpectThat(t, lines, Contains(`statement_seconds_count{op="query"} 2`))
+}
+
+func TestTrace(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(prev)
+
+	db := openTest(t)
+	ctx, root := otel.Tracer("test").Start(ctx, "root")
+	_, err := db.ExecContext(ctx, `CREATE TABLE t (n INTEGER)`)
+	AssertThat(t, err, Nil())
+	_, err = db.QueryContext(ctx, `SELECT missing FROM t`)
+	ExpectThat(t, err, Not(Nil()))
+	root.End()
+
+	var names []string
+	for _, s := range rec.Ended() {
+		if s.Parent().SpanID() == root.SpanContext().SpanID() {
+			names = append(names, s.Name()+" "+s.Status().Code.String())
+		}
+	}
+	ExpectThat(t, names, ElementsAre("db.exec Unset", "db.query Error"))
+}
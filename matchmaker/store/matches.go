@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/jfmatt/snapfold/gamedef"
+	"github.com/jfmatt/snapfold/lib/gamedefio"
+	"github.com/jfmatt/snapfold/matchmaker/history"
+)
+
+// Matches is a history.Store backed by the matches and hand_histories
+// tables.
+type Matches struct {
+	db *DB
+}
+
+// NewMatches returns a match history store using db.
+func NewMatches(db *DB) *Matches {
+	return &Matches{db: db}
+}
+
+const matchColumns = `id, game_mode, region, table_id, players, bots, config, created_at, places, ended_at`
+
+func (s *Matches) SaveMatch(ctx context.Context, m history.Match) error {
+	players, err := json.Marshal(m.Players)
+	if err != nil {
+		return err
+	}
+	var config sql.NullString
+	if m.Config != nil {
+		b, err := protojson.Marshal(gamedefio.Stamped(m.Config))
+		if err != nil {
+			return err
+		}
+		config = sql.NullString{String: string(b), Valid: true}
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO matches (id, game_mode, region, table_id, players, bots, config, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO NOTHING`,
+		m.ID, m.GameMode, m.Region, m.TableID, string(players), m.Bots, config, m.CreatedAt)
+	return err
+}
+
+func (s *Matches) SaveResult(ctx context.Context, matchID string, places map[string]int, endedAt time.Time) error {
+	b, err := json.Marshal(places)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE matches SET places = $2, ended_at = $3
+		WHERE id = $1 AND ended_at IS NULL`,
+		matchID, string(b), endedAt)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	// Nothing was updated: either the match is unknown or it already ended.
+	if _, err := s.GetMatch(ctx, matchID); err != nil {
+		return err
+	}
+	return history.ErrReported
+}
+
+func (s *Matches) GetMatch(ctx context.Context, matchID string) (history.Match, error) {
+	m, err := scanMatch(s.db.QueryRowContext(ctx, `SELECT `+matchColumns+` FROM matches WHERE id = $1`, matchID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return history.Match{}, history.ErrNotFound
+	}
+	return m, err
+}
+
+func (s *Matches) ListMatches(ctx context.Context, playerID string, q history.Query) ([]history.Match, error) {
+	query := `SELECT ` + matchColumns + ` FROM matches WHERE players ? $1`
+	if s.db.Dialect == SQLite {
+		query = `SELECT ` + matchColumns + ` FROM matches
+			WHERE EXISTS (SELECT 1 FROM json_each(players) WHERE value = $1)`
+	}
+	args := []any{playerID}
+	if q.GameMode != "" {
+		args = append(args, q.GameMode)
+		query += fmt.Sprintf(` AND game_mode = $%d`, len(args))
+	}
+	op, order := "<", "DESC"
+	if q.OldestFirst {
+		op, order = ">", "ASC"
+	}
+	if q.After.ID != "" {
+		args = append(args, q.After.CreatedAt, q.After.ID)
+		query += fmt.Sprintf(` AND (created_at, id) %s ($%d, $%d)`, op, len(args)-1, len(args))
+	}
+	query += fmt.Sprintf(` ORDER BY created_at %s, id %s LIMIT %d`, order, order, q.Limit)
+	// Players look back over matches that formed or ended at least seconds
+	// ago. GetMatch stays on the primary, as results are checked against it.
+	rows, err := s.db.read(ctx, 5*time.Second, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var matches []history.Match
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+func scanMatch(row interface{ Scan(...any) error }) (history.Match, error) {
+	var m history.Match
+	var players, config, places []byte
+	var endedAt sql.NullTime
+	err := row.Scan(&m.ID, &m.GameMode, &m.Region, &m.TableID, &players, &m.Bots, &config,
+		&m.CreatedAt, &places, &endedAt)
+	if err != nil {
+		return history.Match{}, err
+	}
+	if err := json.Unmarshal(players, &m.Players); err != nil {
+		return history.Match{}, err
+	}
+	if config != nil {
+		// Configs are upgraded as they are read, so that matches stored
+		// before a schema change can still be read after it.
+		config, err := gamedefio.UpgradeJSON(config)
+		if err != nil {
+			return history.Match{}, err
+		}
+		m.Config = &pb.TableConfig{}
+		if err := protojson.Unmarshal(config, m.Config); err != nil {
+			return history.Match{}, err
+		}
+	}
+	if places != nil {
+		if err := json.Unmarshal(places, &m.Places); err != nil {
+			return history.Match{}, err
+		}
+	}
+	m.EndedAt = endedAt.Time
+	return m, nil
+}
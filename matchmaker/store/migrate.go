@@ -0,0 +1,349 @@
+package store
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Each migration is a pair of files, NNNN_name.up.sql and
+// NNNN_name.down.sql, where NNNN is its version, in the directory for each
+// dialect. SQLite's migrations start from the schema as it was at version
+// 21, when SQLite was first supported; later migrations are written for both
+// dialects with the same version.
+//
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrations embed.FS
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var (
+	sqlComment    = regexp.MustCompile(`--[^\n]*`)
+	dropClause    = regexp.MustCompile(`(?i)\bDROP\s+(\w+)`)
+	deletesRows   = regexp.MustCompile(`(?i)\b(TRUNCATE|DELETE\s+FROM)\b`)
+	changesType   = regexp.MustCompile(`(?i)\bALTER\s+(COLUMN\s+)?\w+\s+(SET\s+DATA\s+)?TYPE\b`)
+	keepsDataDrop = []string{"CONSTRAINT", "DEFAULT", "EXPRESSION", "FUNCTION", "IDENTITY", "INDEX", "NOT", "TRIGGER", "VIEW"}
+)
+
+// A Migration is a versioned change to the schema, with the SQL that makes
+// it and the SQL that undoes it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Destructive reports whether a migration script may lose data: whether it
+// drops tables, columns or schemas, deletes rows, or changes columns'
+// types. Dropping indexes, constraints, defaults and the like doesn't
+// count.
+func Destructive(script string) bool {
+	script = sqlComment.ReplaceAllString(script, "")
+	if deletesRows.MatchString(script) || changesType.MatchString(script) {
+		return true
+	}
+	for _, m := range dropClause.FindAllStringSubmatch(script, -1) {
+		// Anything else dropped is a table, schema or column, which
+		// ALTER TABLE ... DROP may name without saying COLUMN.
+		if !slices.Contains(keepsDataDrop, strings.ToUpper(m[1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrationState is a migration and whether it has been applied.
+type MigrationState struct {
+	Migration
+
+	// When the migration was applied, or zero if it is pending.
+	AppliedAt time.Time
+
+	// Set for migrations the database has applied that this build doesn't
+	// know, because a newer one applied them. Their SQL is empty.
+	Unknown bool
+}
+
+// Migrations returns the embedded migrations for a dialect, in version
+// order.
+func Migrations(dialect Dialect) ([]Migration, error) {
+	names, err := fs.Glob(migrations, "migrations/"+string(dialect)+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, name := range names {
+		match := migrationFile.FindStringSubmatch(path.Base(name))
+		if match == nil {
+			return nil, fmt.Errorf("%s: migrations are named NNNN_name.up.sql or NNNN_name.down.sql", name)
+		}
+		version, _ := strconv.Atoi(match[1])
+		script, err := migrations.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("%s: migration %d is also named %s", name, version, m.Name)
+		}
+		if match[3] == "up" {
+			m.Up = string(script)
+		} else {
+			m.Down = string(script)
+		}
+	}
+	var all []Migration
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %s needs both an up and a down script", m)
+		}
+		all = append(all, *m)
+	}
+	slices.SortFunc(all, func(a, b Migration) int { return a.Version - b.Version })
+	return all, nil
+}
+
+// MigrationStatus returns every migration, embedded or applied, in version
+// order.
+func MigrationStatus(ctx context.Context, db *DB) ([]MigrationState, error) {
+	all, err := Migrations(db.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	var states []MigrationState
+	for _, m := range all {
+		s := MigrationState{Migration: m}
+		if a, ok := applied[m.Version]; ok {
+			s.AppliedAt = a.AppliedAt
+			delete(applied, m.Version)
+		}
+		states = append(states, s)
+	}
+	for _, a := range applied {
+		states = append(states, a)
+	}
+	slices.SortFunc(states, func(a, b MigrationState) int { return a.Version - b.Version })
+	return states, nil
+}
+
+// PlanUp returns the pending migrations MigrateUp would apply, in the
+// order it would apply them: all of them, or those up to and including
+// version to if it isn't 0.
+func PlanUp(ctx context.Context, db *DB, to int) ([]Migration, error) {
+	states, err := MigrationStatus(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(states, to); err != nil {
+		return nil, err
+	}
+	var plan []Migration
+	for _, s := range states {
+		if s.AppliedAt.IsZero() && (to == 0 || s.Version <= to) {
+			plan = append(plan, s.Migration)
+		}
+	}
+	return plan, nil
+}
+
+// MigrateUp applies the migrations PlanUp plans, each in a transaction with
+// its record in schema_migrations, and returns those it applied.
+// Migrations from before versioning are idempotent, so a database they were
+// applied to without being recorded is brought up to date safely. It waits
+// for any other migration of the database to finish first, and plans once
+// it has, so that replicas deployed together apply each migration once.
+func MigrateUp(ctx context.Context, db *DB, to int) ([]Migration, error) {
+	unlock, err := lockMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := createMigrationsTable(ctx, db); err != nil {
+		return nil, err
+	}
+	plan, err := PlanUp(ctx, db, to)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, m := range plan {
+		err := inTx(ctx, db, m.Up, `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`, m.Version, m.Name, time.Now())
+		if err != nil {
+			return done, fmt.Errorf("%s: %w", m, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// PlanDown returns the applied migrations MigrateDown would revert, newest
+// first: those after version to, or if to is negative, the steps most
+// recently applied. It refuses to plan reverting migrations this build
+// doesn't know.
+func PlanDown(ctx context.Context, db *DB, steps, to int) ([]Migration, error) {
+	states, err := MigrationStatus(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if to > 0 {
+		if err := checkVersion(states, to); err != nil {
+			return nil, err
+		}
+	}
+	var plan []Migration
+	for _, s := range slices.Backward(states) {
+		if to < 0 && len(plan) == steps || to >= 0 && s.Version <= to {
+			break
+		}
+		if s.AppliedAt.IsZero() {
+			continue
+		}
+		if s.Unknown {
+			return nil, fmt.Errorf("migration %s was applied by a newer build, which must revert it", s.Migration)
+		}
+		plan = append(plan, s.Migration)
+	}
+	return plan, nil
+}
+
+// MigrateDown reverts the migrations PlanDown plans, each in a transaction
+// with the removal of its record, and returns those it reverted. Like
+// MigrateUp, it waits for any other migration to finish first.
+func MigrateDown(ctx context.Context, db *DB, steps, to int) ([]Migration, error) {
+	unlock, err := lockMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	plan, err := PlanDown(ctx, db, steps, to)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, m := range plan {
+		if err := inTx(ctx, db, m.Down, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+			return done, fmt.Errorf("%s: %w", m, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// checkVersion returns an error unless version is 0 or a migration's.
+func checkVersion(states []MigrationState, version int) error {
+	if version == 0 || slices.ContainsFunc(states, func(s MigrationState) bool { return s.Version == version }) {
+		return nil
+	}
+	return fmt.Errorf("there is no migration %d", version)
+}
+
+// migrationLock is the key of the Postgres advisory lock held while
+// migrating, shared by every matchmaker build.
+const migrationLock = 0x736e6170666f6c64 // "snapfold"
+
+// lockMigrations waits until no one else is migrating the database, and
+// returns a func that lets them. In Postgres it holds an advisory lock on a
+// connection of its own, so the lock is released if the process dies.
+// SQLite databases are local to one matchmaker, and aren't locked.
+func lockMigrations(ctx context.Context, db *DB) (unlock func(), err error) {
+	if db.Dialect == SQLite {
+		return func() {}, nil
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLock); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("waiting for another migration to finish: %w", err)
+	}
+	return func() {
+		// Unlock even if ctx is done: closing conn only returns it to the
+		// pool, where its session would go on holding the lock.
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLock)
+		conn.Close()
+	}, nil
+}
+
+func createMigrationsTable(ctx context.Context, db *DB) error {
+	// SQLite's driver only reads TIMESTAMP columns as times.
+	timestamp := "TIMESTAMPTZ"
+	if db.Dialect == SQLite {
+		timestamp = "TIMESTAMP"
+	}
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at `+timestamp+` NOT NULL
+		)`)
+	return err
+}
+
+// appliedMigrations returns the migrations recorded in schema_migrations,
+// if it exists, by version. Their SQL is empty and they are marked Unknown
+// until matched with the embedded ones.
+func appliedMigrations(ctx context.Context, db *DB) (map[int]MigrationState, error) {
+	applied := map[int]MigrationState{}
+	query := `SELECT to_regclass('schema_migrations') IS NOT NULL`
+	if db.Dialect == SQLite {
+		query = `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`
+	}
+	var exists bool
+	if err := db.QueryRowContext(ctx, query).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return applied, nil
+	}
+	rows, err := db.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		s := MigrationState{Unknown: true}
+		if err := rows.Scan(&s.Version, &s.Name, &s.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[s.Version] = s
+	}
+	return applied, rows.Err()
+}
+
+// inTx runs a migration script and then the statement recording it, in one
+// transaction.
+func inTx(ctx context.Context, db *DB, script, record string, args ...any) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS ratings (
+    player_id  TEXT PRIMARY KEY,
+    rating     DOUBLE PRECISION NOT NULL,
+    deviation  DOUBLE PRECISION NOT NULL,
+    volatility DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS tickets (
+    id         TEXT PRIMARY KEY,
+    player_id  TEXT NOT NULL,
+    party_id   TEXT NOT NULL DEFAULT '',
+    members    JSONB NOT NULL,
+    game_mode  TEXT NOT NULL,
+    priority   TEXT NOT NULL,
+    rating     DOUBLE PRECISION NOT NULL,
+    state      TEXT NOT NULL,
+    match_id   TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS tickets_player_id ON tickets (player_id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS seats (
+    player_id       TEXT PRIMARY KEY,
+    table_id        TEXT NOT NULL,
+    match_id        TEXT NOT NULL,
+    game_mode       TEXT NOT NULL,
+    region          TEXT NOT NULL DEFAULT '',
+    seated_at       TIMESTAMPTZ NOT NULL,
+    disconnected_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS seats_table_id ON seats (table_id);
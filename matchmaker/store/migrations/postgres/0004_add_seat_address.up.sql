@@ -0,0 +1 @@
+ALTER TABLE seats ADD COLUMN IF NOT EXISTS address TEXT NOT NULL DEFAULT '';
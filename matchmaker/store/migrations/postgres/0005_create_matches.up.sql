@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS matches (
+    id         TEXT PRIMARY KEY,
+    game_mode  TEXT NOT NULL,
+    region     TEXT NOT NULL DEFAULT '',
+    table_id   TEXT NOT NULL DEFAULT '',
+    players    JSONB NOT NULL,
+    bots       INTEGER NOT NULL DEFAULT 0,
+    config     JSONB,
+    created_at TIMESTAMPTZ NOT NULL,
+    places     JSONB,
+    ended_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS matches_players ON matches USING GIN (players);
+CREATE INDEX IF NOT EXISTS matches_created_at ON matches (created_at DESC, id DESC);
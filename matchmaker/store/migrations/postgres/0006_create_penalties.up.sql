@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS penalties (
+    player_id    TEXT NOT NULL,
+    game_mode    TEXT NOT NULL,
+    offenses     INTEGER NOT NULL,
+    last_offense TIMESTAMPTZ NOT NULL,
+    until        TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (player_id, game_mode)
+);
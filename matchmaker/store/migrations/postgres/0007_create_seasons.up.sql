@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS seasons (
+    number          INTEGER PRIMARY KEY,
+    name            TEXT NOT NULL,
+    started_at      TIMESTAMPTZ NOT NULL,
+    ends_at         TIMESTAMPTZ,
+    closed_at       TIMESTAMPTZ,
+    reset_keep      DOUBLE PRECISION NOT NULL,
+    reset_target    DOUBLE PRECISION NOT NULL,
+    reset_deviation DOUBLE PRECISION NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS season_standings (
+    season     INTEGER NOT NULL REFERENCES seasons (number),
+    player_id  TEXT NOT NULL,
+    rank       INTEGER NOT NULL,
+    rating     DOUBLE PRECISION NOT NULL,
+    deviation  DOUBLE PRECISION NOT NULL,
+    volatility DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (season, player_id)
+);
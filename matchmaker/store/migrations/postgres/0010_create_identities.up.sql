@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS identities (
+    provider TEXT NOT NULL,
+    subject  TEXT NOT NULL,
+    username TEXT NOT NULL REFERENCES accounts (username),
+    PRIMARY KEY (provider, subject)
+);
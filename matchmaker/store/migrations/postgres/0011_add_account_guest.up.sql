@@ -0,0 +1 @@
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS guest BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS email_verifications;
+
+ALTER TABLE accounts DROP COLUMN IF EXISTS email_verified;
+ALTER TABLE accounts DROP COLUMN IF EXISTS email;
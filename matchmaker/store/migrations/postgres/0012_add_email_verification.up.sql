@@ -0,0 +1,10 @@
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS email TEXT NOT NULL DEFAULT '';
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+    username   TEXT PRIMARY KEY REFERENCES accounts (username),
+    email      TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    sent_at    TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS refresh_tokens_player_id;
+DROP INDEX IF EXISTS accounts_email;
+
+DROP TABLE IF EXISTS password_resets;
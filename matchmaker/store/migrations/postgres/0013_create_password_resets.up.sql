@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS password_resets (
+    username   TEXT PRIMARY KEY REFERENCES accounts (username),
+    token_hash TEXT NOT NULL UNIQUE,
+    sent_at    TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS accounts_email ON accounts (email);
+CREATE INDEX IF NOT EXISTS refresh_tokens_player_id ON refresh_tokens (player_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS identities_username_provider;
+
+ALTER TABLE identities DROP COLUMN IF EXISTS linked_at;
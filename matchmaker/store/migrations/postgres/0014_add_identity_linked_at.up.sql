@@ -0,0 +1,4 @@
+ALTER TABLE identities ADD COLUMN IF NOT EXISTS linked_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- An account may have one identity from each provider.
+CREATE UNIQUE INDEX IF NOT EXISTS identities_username_provider ON identities (username, provider);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id           TEXT PRIMARY KEY,
+    player_id    TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL,
+    refreshed_at TIMESTAMPTZ NOT NULL,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS sessions_player_id ON sessions (player_id);
+CREATE INDEX IF NOT EXISTS sessions_revoked_at ON sessions (revoked_at);
+
+-- Sessions started before they were tracked, so that they can be listed
+-- and revoked too. When they started is unknown, so they are dated from
+-- this migration.
+INSERT INTO sessions (id, player_id, created_at, refreshed_at, expires_at)
+SELECT family, player_id, now(), now(), max(expires_at)
+FROM refresh_tokens
+WHERE revoked_at IS NULL
+GROUP BY family, player_id
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS hand_histories (
+    hand_number BIGINT NOT NULL,
+    player_id   TEXT NOT NULL,
+    table_id    TEXT NOT NULL,
+    played_at   TIMESTAMPTZ NOT NULL,
+    text        TEXT NOT NULL,
+    PRIMARY KEY (hand_number, player_id)
+);
+
+CREATE INDEX IF NOT EXISTS hand_histories_player_played_at ON hand_histories (player_id, played_at, hand_number);
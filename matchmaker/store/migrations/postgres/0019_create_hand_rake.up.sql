@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS hand_rake (
+    hand_number BIGINT PRIMARY KEY,
+    table_id    TEXT NOT NULL,
+    played_at   TIMESTAMPTZ NOT NULL,
+    amount      BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS hand_rake_played_at ON hand_rake (played_at);
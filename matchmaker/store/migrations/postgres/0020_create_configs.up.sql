@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS configs (
+    kind       TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    config     JSONB NOT NULL,
+    revision   BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (kind, name)
+);
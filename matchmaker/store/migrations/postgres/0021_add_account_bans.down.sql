@@ -0,0 +1,4 @@
+ALTER TABLE accounts DROP COLUMN IF EXISTS ban_reason;
+ALTER TABLE accounts DROP COLUMN IF EXISTS banned_by;
+ALTER TABLE accounts DROP COLUMN IF EXISTS banned_until;
+ALTER TABLE accounts DROP COLUMN IF EXISTS banned_at;
@@ -0,0 +1,19 @@
+CREATE TABLE hand_histories_unpartitioned (
+    hand_number BIGINT NOT NULL,
+    player_id   TEXT NOT NULL,
+    table_id    TEXT NOT NULL,
+    played_at   TIMESTAMPTZ NOT NULL,
+    text        TEXT NOT NULL,
+    CONSTRAINT hand_histories_unpartitioned_pkey PRIMARY KEY (hand_number, player_id)
+);
+
+-- A hand recorded again at a different time has two histories per player
+-- now; the latest is kept.
+INSERT INTO hand_histories_unpartitioned
+SELECT DISTINCT ON (hand_number, player_id) * FROM hand_histories
+ORDER BY hand_number, player_id, played_at DESC;
+
+DROP TABLE hand_histories;
+ALTER TABLE hand_histories_unpartitioned RENAME TO hand_histories;
+ALTER TABLE hand_histories RENAME CONSTRAINT hand_histories_unpartitioned_pkey TO hand_histories_pkey;
+CREATE INDEX hand_histories_player_played_at ON hand_histories (player_id, played_at, hand_number);
@@ -0,0 +1,24 @@
+-- Hand histories are partitioned by the day they were played, so that
+-- retention drops a day at a time. The matchmaker makes each day's
+-- partition ahead of time; hands from before partitioning, or played on a
+-- day without a partition, go to the default one. A partitioned table's
+-- primary key must include played_at.
+ALTER TABLE hand_histories RENAME TO hand_histories_unpartitioned;
+ALTER TABLE hand_histories_unpartitioned RENAME CONSTRAINT hand_histories_pkey TO hand_histories_unpartitioned_pkey;
+ALTER INDEX hand_histories_player_played_at RENAME TO hand_histories_unpartitioned_player_played_at;
+
+CREATE TABLE hand_histories (
+    hand_number BIGINT NOT NULL,
+    player_id   TEXT NOT NULL,
+    table_id    TEXT NOT NULL,
+    played_at   TIMESTAMPTZ NOT NULL,
+    text        TEXT NOT NULL,
+    PRIMARY KEY (hand_number, player_id, played_at)
+) PARTITION BY RANGE (played_at);
+
+CREATE INDEX hand_histories_player_played_at ON hand_histories (player_id, played_at, hand_number);
+
+CREATE TABLE hand_histories_default PARTITION OF hand_histories DEFAULT;
+
+INSERT INTO hand_histories SELECT * FROM hand_histories_unpartitioned;
+DROP TABLE hand_histories_unpartitioned;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id     TEXT PRIMARY KEY,
+    time   TIMESTAMPTZ NOT NULL,
+    actor  TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target TEXT NOT NULL,
+    before JSONB,
+    after  JSONB
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_time ON audit_log (time, id);
+CREATE INDEX IF NOT EXISTS audit_log_actor ON audit_log (actor, time, id);
+CREATE INDEX IF NOT EXISTS audit_log_target ON audit_log (target, time, id);
+
+-- The log is append-only.
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
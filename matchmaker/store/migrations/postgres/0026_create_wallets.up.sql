@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS wallets (
+    player_id TEXT PRIMARY KEY,
+    balance   BIGINT NOT NULL CHECK (balance >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    player_id TEXT NOT NULL,
+    kind      TEXT NOT NULL,
+    ref       TEXT NOT NULL,
+    amount    BIGINT NOT NULL,
+    at        TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (player_id, kind, ref)
+);
@@ -0,0 +1,17 @@
+DROP TABLE configs;
+DROP TABLE hand_rake;
+DROP TABLE hand_histories;
+DROP TABLE sessions;
+DROP TABLE api_keys;
+DROP TABLE password_resets;
+DROP TABLE email_verifications;
+DROP TABLE identities;
+DROP TABLE refresh_tokens;
+DROP TABLE accounts;
+DROP TABLE season_standings;
+DROP TABLE seasons;
+DROP TABLE penalties;
+DROP TABLE matches;
+DROP TABLE seats;
+DROP TABLE tickets;
+DROP TABLE ratings;
@@ -0,0 +1,187 @@
+-- The schema as the Postgres migrations up to this version leave it.
+-- Times are TIMESTAMP, which the driver reads as times, and JSON is TEXT.
+
+CREATE TABLE ratings (
+    player_id  TEXT PRIMARY KEY,
+    rating     DOUBLE PRECISION NOT NULL,
+    deviation  DOUBLE PRECISION NOT NULL,
+    volatility DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE tickets (
+    id         TEXT PRIMARY KEY,
+    player_id  TEXT NOT NULL,
+    party_id   TEXT NOT NULL DEFAULT '',
+    members    TEXT NOT NULL,
+    game_mode  TEXT NOT NULL,
+    priority   TEXT NOT NULL,
+    rating     DOUBLE PRECISION NOT NULL,
+    state      TEXT NOT NULL,
+    match_id   TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX tickets_player_id ON tickets (player_id);
+
+CREATE TABLE seats (
+    player_id       TEXT PRIMARY KEY,
+    table_id        TEXT NOT NULL,
+    match_id        TEXT NOT NULL,
+    game_mode       TEXT NOT NULL,
+    region          TEXT NOT NULL DEFAULT '',
+    address         TEXT NOT NULL DEFAULT '',
+    seated_at       TIMESTAMP NOT NULL,
+    disconnected_at TIMESTAMP
+);
+
+CREATE INDEX seats_table_id ON seats (table_id);
+
+CREATE TABLE matches (
+    id         TEXT PRIMARY KEY,
+    game_mode  TEXT NOT NULL,
+    region     TEXT NOT NULL DEFAULT '',
+    table_id   TEXT NOT NULL DEFAULT '',
+    players    TEXT NOT NULL,
+    bots       INTEGER NOT NULL DEFAULT 0,
+    config     TEXT,
+    created_at TIMESTAMP NOT NULL,
+    places     TEXT,
+    ended_at   TIMESTAMP
+);
+
+CREATE INDEX matches_created_at ON matches (created_at DESC, id DESC);
+
+CREATE TABLE penalties (
+    player_id    TEXT NOT NULL,
+    game_mode    TEXT NOT NULL,
+    offenses     INTEGER NOT NULL,
+    last_offense TIMESTAMP NOT NULL,
+    until        TIMESTAMP NOT NULL,
+    PRIMARY KEY (player_id, game_mode)
+);
+
+CREATE TABLE seasons (
+    number          INTEGER PRIMARY KEY,
+    name            TEXT NOT NULL,
+    started_at      TIMESTAMP NOT NULL,
+    ends_at         TIMESTAMP,
+    closed_at       TIMESTAMP,
+    reset_keep      DOUBLE PRECISION NOT NULL,
+    reset_target    DOUBLE PRECISION NOT NULL,
+    reset_deviation DOUBLE PRECISION NOT NULL
+);
+
+CREATE TABLE season_standings (
+    season     INTEGER NOT NULL REFERENCES seasons (number),
+    player_id  TEXT NOT NULL,
+    rank       INTEGER NOT NULL,
+    rating     DOUBLE PRECISION NOT NULL,
+    deviation  DOUBLE PRECISION NOT NULL,
+    volatility DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (season, player_id)
+);
+
+CREATE TABLE accounts (
+    username       TEXT PRIMARY KEY,
+    password_hash  TEXT NOT NULL,
+    created_at     TIMESTAMP NOT NULL,
+    guest          BOOLEAN NOT NULL DEFAULT false,
+    email          TEXT NOT NULL DEFAULT '',
+    email_verified BOOLEAN NOT NULL DEFAULT false,
+    role           TEXT NOT NULL DEFAULT 'player',
+    banned_at      TIMESTAMP,
+    banned_until   TIMESTAMP,
+    banned_by      TEXT NOT NULL DEFAULT '',
+    ban_reason     TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX accounts_email ON accounts (email);
+
+CREATE TABLE refresh_tokens (
+    id         TEXT PRIMARY KEY,
+    player_id  TEXT NOT NULL,
+    family     TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX refresh_tokens_family ON refresh_tokens (family);
+CREATE INDEX refresh_tokens_player_id ON refresh_tokens (player_id);
+
+CREATE TABLE identities (
+    provider  TEXT NOT NULL,
+    subject   TEXT NOT NULL,
+    username  TEXT NOT NULL REFERENCES accounts (username),
+    linked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, subject)
+);
+
+-- An account may have one identity from each provider.
+CREATE UNIQUE INDEX identities_username_provider ON identities (username, provider);
+
+CREATE TABLE email_verifications (
+    username   TEXT PRIMARY KEY REFERENCES accounts (username),
+    email      TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    sent_at    TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE password_resets (
+    username   TEXT PRIMARY KEY REFERENCES accounts (username),
+    token_hash TEXT NOT NULL UNIQUE,
+    sent_at    TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE api_keys (
+    id          TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    scopes      TEXT NOT NULL,
+    secret_hash TEXT NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    revoked_at  TIMESTAMP
+);
+
+CREATE TABLE sessions (
+    id           TEXT PRIMARY KEY,
+    player_id    TEXT NOT NULL,
+    created_at   TIMESTAMP NOT NULL,
+    refreshed_at TIMESTAMP NOT NULL,
+    expires_at   TIMESTAMP NOT NULL,
+    revoked_at   TIMESTAMP
+);
+
+CREATE INDEX sessions_player_id ON sessions (player_id);
+CREATE INDEX sessions_revoked_at ON sessions (revoked_at);
+
+CREATE TABLE hand_histories (
+    hand_number BIGINT NOT NULL,
+    player_id   TEXT NOT NULL,
+    table_id    TEXT NOT NULL,
+    played_at   TIMESTAMP NOT NULL,
+    text        TEXT NOT NULL,
+    PRIMARY KEY (hand_number, player_id)
+);
+
+CREATE INDEX hand_histories_player_played_at ON hand_histories (player_id, played_at, hand_number);
+
+CREATE TABLE hand_rake (
+    hand_number BIGINT PRIMARY KEY,
+    table_id    TEXT NOT NULL,
+    played_at   TIMESTAMP NOT NULL,
+    amount      BIGINT NOT NULL
+);
+
+CREATE INDEX hand_rake_played_at ON hand_rake (played_at);
+
+CREATE TABLE configs (
+    kind       TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    config     TEXT NOT NULL,
+    revision   BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (kind, name)
+);
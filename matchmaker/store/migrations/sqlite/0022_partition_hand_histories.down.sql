@@ -0,0 +1,15 @@
+CREATE TABLE hand_histories_old (
+    hand_number BIGINT NOT NULL,
+    player_id   TEXT NOT NULL,
+    table_id    TEXT NOT NULL,
+    played_at   TIMESTAMP NOT NULL,
+    text        TEXT NOT NULL,
+    PRIMARY KEY (hand_number, player_id)
+);
+
+-- A hand recorded again at a different time has two histories per player
+-- now; the latest is kept.
+INSERT OR REPLACE INTO hand_histories_old SELECT * FROM hand_histories ORDER BY played_at;
+DROP TABLE hand_histories;
+ALTER TABLE hand_histories_old RENAME TO hand_histories;
+CREATE INDEX hand_histories_player_played_at ON hand_histories (player_id, played_at, hand_number);
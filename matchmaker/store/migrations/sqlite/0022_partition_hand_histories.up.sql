@@ -0,0 +1,16 @@
+-- SQLite has no partitions, and hand histories are pruned row by row, but
+-- the primary key includes played_at as in Postgres, where a partitioned
+-- table's must.
+CREATE TABLE hand_histories_new (
+    hand_number BIGINT NOT NULL,
+    player_id   TEXT NOT NULL,
+    table_id    TEXT NOT NULL,
+    played_at   TIMESTAMP NOT NULL,
+    text        TEXT NOT NULL,
+    PRIMARY KEY (hand_number, player_id, played_at)
+);
+
+INSERT INTO hand_histories_new SELECT * FROM hand_histories;
+DROP TABLE hand_histories;
+ALTER TABLE hand_histories_new RENAME TO hand_histories;
+CREATE INDEX hand_histories_player_played_at ON hand_histories (player_id, played_at, hand_number);
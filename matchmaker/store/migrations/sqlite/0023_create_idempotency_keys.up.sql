@@ -0,0 +1,11 @@
+CREATE TABLE idempotency_keys (
+    scope           TEXT NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    fingerprint     TEXT NOT NULL,
+    done            BOOLEAN NOT NULL DEFAULT false,
+    response        BLOB,
+    created_at      TIMESTAMP NOT NULL,
+    PRIMARY KEY (scope, idempotency_key)
+);
+
+CREATE INDEX idempotency_keys_created_at ON idempotency_keys (created_at);
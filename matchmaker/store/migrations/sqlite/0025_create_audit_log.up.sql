@@ -0,0 +1,24 @@
+CREATE TABLE audit_log (
+    id     TEXT PRIMARY KEY,
+    time   TIMESTAMP NOT NULL,
+    actor  TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target TEXT NOT NULL,
+    before TEXT,
+    after  TEXT
+);
+
+CREATE INDEX audit_log_time ON audit_log (time, id);
+CREATE INDEX audit_log_actor ON audit_log (actor, time, id);
+CREATE INDEX audit_log_target ON audit_log (target, time, id);
+
+-- The log is append-only.
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
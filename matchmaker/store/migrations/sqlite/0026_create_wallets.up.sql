@@ -0,0 +1,13 @@
+CREATE TABLE wallets (
+    player_id TEXT PRIMARY KEY,
+    balance   INTEGER NOT NULL CHECK (balance >= 0)
+);
+
+CREATE TABLE wallet_transactions (
+    player_id TEXT NOT NULL,
+    kind      TEXT NOT NULL,
+    ref       TEXT NOT NULL,
+    amount    INTEGER NOT NULL,
+    at        TIMESTAMP NOT NULL,
+    PRIMARY KEY (player_id, kind, ref)
+);
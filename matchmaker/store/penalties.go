@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jfmatt/snapfold/matchmaker/penalty"
+)
+
+// Penalties is a penalty.Store backed by the penalties table.
+type Penalties struct {
+	db *DB
+}
+
+// NewPenalties returns a penalty store using db.
+func NewPenalties(db *DB) *Penalties {
+	return &Penalties{db: db}
+}
+
+func (s *Penalties) GetRecord(ctx context.Context, playerID, gameMode string) (penalty.Record, error) {
+	r := penalty.Record{PlayerID: playerID, GameMode: gameMode}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT offenses, last_offense, until
+		FROM penalties WHERE player_id = $1 AND game_mode = $2`, playerID, gameMode).Scan(
+		&r.Offenses, &r.LastOffense, &r.Until)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return penalty.Record{}, err
+	}
+	return r, nil
+}
+
+func (s *Penalties) SaveRecord(ctx context.Context, r penalty.Record) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO penalties (player_id, game_mode, offenses, last_offense, until)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (player_id, game_mode) DO UPDATE SET
+			offenses = excluded.offenses,
+			last_offense = excluded.last_offense,
+			until = excluded.until`,
+		r.PlayerID, r.GameMode, r.Offenses, r.LastOffense, r.Until)
+	return err
+}
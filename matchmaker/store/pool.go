@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Pool sizes a DB's connection pool. Zero fields leave database/sql's
+// defaults: no limit on open connections or their age, and two idle ones.
+type Pool struct {
+	MaxOpen     int
+	MaxIdle     int
+	MaxLifetime time.Duration
+	MaxIdleTime time.Duration
+}
+
+// Configure applies p to the pool, and to the replica's if there is one.
+// SQLite databases keep their one connection whatever MaxOpen is.
+func (db *DB) Configure(p Pool) {
+	if db.replica != nil {
+		configure(db.replica, p)
+	}
+	if db.Dialect == SQLite {
+		p.MaxOpen = 0
+	}
+	configure(db.DB, p)
+}
+
+func configure(db *sql.DB, p Pool) {
+	if p.MaxOpen > 0 {
+		db.SetMaxOpenConns(p.MaxOpen)
+	}
+	if p.MaxIdle > 0 {
+		db.SetMaxIdleConns(p.MaxIdle)
+	}
+	if p.MaxLifetime > 0 {
+		db.SetConnMaxLifetime(p.MaxLifetime)
+	}
+	if p.MaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(p.MaxIdleTime)
+	}
+}
+
+// Health tracks whether a DB can be reached, from pings made by Run.
+type Health struct {
+	db *DB
+
+	mu        sync.Mutex
+	err       error
+	checkedAt time.Time
+	failures  int64
+}
+
+// NewHealth returns a health checker for db, which is taken to be healthy
+// until a check fails.
+func NewHealth(db *DB) *Health {
+	return &Health{db: db}
+}
+
+// Check pings the database, waiting at most timeout, and records the
+// result. If there is a replica, it also measures the replica's lag; a
+// replica that can't be reached doesn't fail the check, but reads stop
+// going to it until it can.
+func (h *Health) Check(ctx context.Context, timeout time.Duration) error {
+	if h.db.replica != nil {
+		lagCtx, cancel := context.WithTimeout(ctx, timeout)
+		h.db.measureLag(lagCtx)
+		cancel()
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := h.db.PingContext(ctx)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.err, h.checkedAt = err, time.Now()
+	if err != nil {
+		h.failures++
+	}
+	return err
+}
+
+// Err returns the error of the last check, or nil if it succeeded.
+func (h *Health) Err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+// HealthStats is what a Health has seen.
+type HealthStats struct {
+	// The error of the last check, or nil if it succeeded.
+	Err error
+
+	// When the last check was made, or zero if none has been.
+	CheckedAt time.Time
+
+	// Checks that have failed.
+	Failures int64
+}
+
+// Stats returns what h has seen.
+func (h *Health) Stats() HealthStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return HealthStats{Err: h.err, CheckedAt: h.checkedAt, Failures: h.failures}
+}
+
+// Run checks the database every interval until ctx is done, calling
+// onChange with the result whenever the database becomes unreachable or
+// reachable again.
+func (h *Health) Run(ctx context.Context, interval, timeout time.Duration, onChange func(error)) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			was := h.Err()
+			err := h.Check(ctx, timeout)
+			if ctx.Err() != nil {
+				return
+			}
+			if (err == nil) != (was == nil) {
+				onChange(err)
+			}
+		}
+	}
+}
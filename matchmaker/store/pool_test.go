@@ -0,0 +1,40 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestConfigure_SQLite(t *testing.T) {
+	db := openTest(t)
+	db.Configure(Pool{MaxOpen: 20, MaxIdle: 10, MaxLifetime: time.Hour})
+	ExpectEq(t, db.Stats().MaxOpenConnections, 1)
+}
+
+func TestHealth(t *testing.T) {
+	db := openTest(t)
+	h := NewHealth(db)
+	ExpectThat(t, h.Check(ctx, time.Second), Nil())
+	ExpectThat(t, h.Err(), Nil())
+	ExpectEq(t, h.Stats().CheckedAt.IsZero(), false)
+
+	db.Close()
+	ExpectThat(t, h.Check(ctx, time.Second), Not(Nil()))
+	ExpectThat(t, h.Err(), Not(Nil()))
+	ExpectThat(t, h.Check(ctx, time.Second), Not(Nil()))
+	ExpectEq(t, h.Stats().Failures, int64(2))
+}
+
+func TestHealth_Run(t *testing.T) {
+	db := openTest(t)
+	h := NewHealth(db)
+	changes := make(chan error, 1)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go h.Run(ctx, time.Millisecond, time.Second, func(err error) { changes <- err })
+	db.Close()
+	ExpectThat(t, <-changes, Not(Nil()))
+}
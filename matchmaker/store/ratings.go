@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jfmatt/snapfold/matchmaker/rating"
+)
+
+// Ratings is a rating.Store backed by the ratings table.
+type Ratings struct {
+	db *DB
+}
+
+// NewRatings returns a rating store using db.
+func NewRatings(db *DB) *Ratings {
+	return &Ratings{db: db}
+}
+
+func (s *Ratings) Get(ctx context.Context, playerIDs ...string) (map[string]rating.Rating, error) {
+	out := make(map[string]rating.Rating, len(playerIDs))
+	for _, id := range playerIDs {
+		out[id] = rating.Default()
+	}
+
+	query := `SELECT player_id, rating, deviation, volatility FROM ratings WHERE player_id = ANY($1)`
+	args := []any{playerIDs}
+	if s.db.Dialect == SQLite {
+		query = `SELECT player_id, rating, deviation, volatility FROM ratings
+			WHERE player_id IN (SELECT value FROM json_each($1))`
+		b, err := json.Marshal(playerIDs)
+		if err != nil {
+			return nil, err
+		}
+		args = []any{string(b)}
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var r rating.Rating
+		if err := rows.Scan(&id, &r.Rating, &r.Deviation, &r.Volatility); err != nil {
+			return nil, err
+		}
+		out[id] = r
+	}
+	return out, rows.Err()
+}
+
+func (s *Ratings) Put(ctx context.Context, ratings map[string]rating.Rating) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for id, r := range ratings {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO ratings (player_id, rating, deviation, volatility, updated_at)
+			VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+			ON CONFLICT (player_id) DO UPDATE SET
+				rating = excluded.rating,
+				deviation = excluded.deviation,
+				volatility = excluded.volatility,
+				updated_at = excluded.updated_at`,
+			id, r.Rating, r.Deviation, r.Volatility)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Ratings) All(ctx context.Context) (map[string]rating.Rating, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT player_id, rating, deviation, volatility FROM ratings`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]rating.Rating{}
+	for rows.Next() {
+		var id string
+		var r rating.Rating
+		if err := rows.Scan(&id, &r.Rating, &r.Deviation, &r.Volatility); err != nil {
+			return nil, err
+		}
+		out[id] = r
+	}
+	return out, rows.Err()
+}
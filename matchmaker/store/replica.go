@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// A DB may have a read replica, to take reads off the primary. Each read
+// that may go to it says how stale its results may be, and goes to the
+// replica only while the replica's lag, as last measured, is within that;
+// other reads, writes and transactions always go to the primary. Reads that
+// gate a write or authenticate someone must see every committed write, and
+// stay on the primary.
+
+// OpenReplica connects to a read replica of the primary at dsn and measures
+// how far behind it is. Only Postgres databases have replicas.
+func (db *DB) OpenReplica(ctx context.Context, dsn string) error {
+	if db.Dialect != Postgres {
+		return errors.New("only Postgres databases can have read replicas")
+	}
+	if _, ok := sqlitePath(dsn); ok {
+		return errors.New("a Postgres database's replica must be Postgres too")
+	}
+	replica, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return err
+	}
+	if err := replica.PingContext(ctx); err != nil {
+		replica.Close()
+		return err
+	}
+	db.replica = replica
+	db.lag.Store(-1)
+	return db.measureLag(ctx)
+}
+
+// Close closes the database and its replica, if it has one.
+func (db *DB) Close() error {
+	err := db.DB.Close()
+	if db.replica != nil {
+		err = errors.Join(err, db.replica.Close())
+	}
+	return err
+}
+
+// ReplicaLag returns how far behind the primary the replica was when last
+// measured, or false if there is no replica or the measurement failed.
+func (db *DB) ReplicaLag() (time.Duration, bool) {
+	if db.replica == nil {
+		return 0, false
+	}
+	lag := time.Duration(db.lag.Load())
+	return lag, lag >= 0
+}
+
+// measureLag records how far behind the primary the replica is. A replica
+// that has replayed everything it has received counts as caught up, however
+// long ago the primary last wrote. Until a measurement succeeds, reads stay
+// on the primary.
+func (db *DB) measureLag(ctx context.Context) error {
+	var seconds float64
+	err := db.replica.QueryRowContext(ctx, `
+		SELECT (CASE
+			WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+		END)::float8`).Scan(&seconds)
+	if err != nil {
+		db.lag.Store(-1)
+		return err
+	}
+	db.lag.Store(int64(seconds * float64(time.Second)))
+	return nil
+}
+
+// reader returns where to send a read whose results may be up to
+// staleness behind the primary.
+func (db *DB) reader(staleness time.Duration) *sql.DB {
+	if lag, ok := db.ReplicaLag(); ok && lag <= staleness {
+		return db.replica
+	}
+	return db.DB
+}
@@ -0,0 +1,32 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+)
+
+func TestReader(t *testing.T) {
+	db := openTest(t)
+	ExpectEq(t, db.reader(time.Hour), db.DB)
+
+	replica := openTest(t)
+	db.replica = replica.DB
+	db.lag.Store(-1)
+	ExpectEq(t, db.reader(time.Hour), db.DB)
+
+	db.lag.Store(int64(2 * time.Second))
+	lag, ok := db.ReplicaLag()
+	ExpectEq(t, ok, true)
+	ExpectEq(t, lag, 2*time.Second)
+	ExpectEq(t, db.reader(time.Second), db.DB)
+	ExpectEq(t, db.reader(5*time.Second), replica.DB)
+}
+
+func TestOpenReplica_SQLite(t *testing.T) {
+	db := openTest(t)
+	ExpectThat(t, db.OpenReplica(ctx, "sqlite::memory:"), Not(Nil()))
+	_, ok := db.ReplicaLag()
+	ExpectEq(t, ok, false)
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/season"
+)
+
+// Seasons is a season.Store backed by the seasons and season_standings
+// tables.
+type Seasons struct {
+	db *DB
+}
+
+// NewSeasons returns a season store using db.
+func NewSeasons(db *DB) *Seasons {
+	return &Seasons{db: db}
+}
+
+func (s *Seasons) LatestSeason(ctx context.Context) (season.Season, error) {
+	var st season.Season
+	var endsAt, closedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT number, name, started_at, ends_at, closed_at, reset_keep, reset_target, reset_deviation
+		FROM seasons ORDER BY number DESC LIMIT 1`).Scan(
+		&st.Number, &st.Name, &st.StartedAt, &endsAt, &closedAt,
+		&st.Reset.Keep, &st.Reset.Target, &st.Reset.Deviation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return season.Season{}, season.ErrNoSeason
+	}
+	if err != nil {
+		return season.Season{}, err
+	}
+	st.EndsAt, st.ClosedAt = endsAt.Time, closedAt.Time
+	return st, nil
+}
+
+func (s *Seasons) SaveSeason(ctx context.Context, st season.Season) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO seasons (number, name, started_at, ends_at, closed_at, reset_keep, reset_target, reset_deviation)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (number) DO UPDATE SET
+			name = excluded.name,
+			started_at = excluded.started_at,
+			ends_at = excluded.ends_at,
+			closed_at = excluded.closed_at,
+			reset_keep = excluded.reset_keep,
+			reset_target = excluded.reset_target,
+			reset_deviation = excluded.reset_deviation`,
+		st.Number, st.Name, st.StartedAt, nullTime(st.EndsAt), nullTime(st.ClosedAt),
+		st.Reset.Keep, st.Reset.Target, st.Reset.Deviation)
+	return err
+}
+
+func (s *Seasons) SaveStandings(ctx context.Context, number int, standings []season.Standing) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM season_standings WHERE season = $1`, number); err != nil {
+		return err
+	}
+	for _, st := range standings {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO season_standings (season, player_id, rank, rating, deviation, volatility)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			number, st.PlayerID, st.Rank, st.Rating.Rating, st.Rating.Deviation, st.Rating.Volatility)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Seasons) Standings(ctx context.Context, number int) ([]season.Standing, error) {
+	// Standings are written once, when their season ends, and are read as
+	// a leaderboard; a minute behind only delays a new one appearing.
+	rows, err := s.db.read(ctx, time.Minute, `
+		SELECT player_id, rank, rating, deviation, volatility
+		FROM season_standings WHERE season = $1 ORDER BY rank, player_id`, number)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var standings []season.Standing
+	for rows.Next() {
+		var st season.Standing
+		if err := rows.Scan(&st.PlayerID, &st.Rank, &st.Rating.Rating, &st.Rating.Deviation, &st.Rating.Volatility); err != nil {
+			return nil, err
+		}
+		standings = append(standings, st)
+	}
+	return standings, rows.Err()
+}
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jfmatt/snapfold/matchmaker/seat"
+)
+
+// Seats is a seat.Store backed by the seats table.
+type Seats struct {
+	db *DB
+}
+
+// NewSeats returns a seat store using db.
+func NewSeats(db *DB) *Seats {
+	return &Seats{db: db}
+}
+
+func (s *Seats) SaveSeat(ctx context.Context, st seat.Seat) error {
+	var disconnectedAt sql.NullTime
+	if st.Disconnected() {
+		disconnectedAt = sql.NullTime{Time: st.DisconnectedAt, Valid: true}
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO seats (player_id, table_id, match_id, game_mode, region, address, seated_at, disconnected_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (player_id) DO UPDATE SET
+			table_id = excluded.table_id,
+			match_id = excluded.match_id,
+			game_mode = excluded.game_mode,
+			region = excluded.region,
+			address = excluded.address,
+			seated_at = excluded.seated_at,
+			disconnected_at = excluded.disconnected_at`,
+		st.PlayerID, st.TableID, st.MatchID, st.GameMode, st.Region, st.Address, st.SeatedAt, disconnectedAt)
+	return err
+}
+
+func (s *Seats) GetSeat(ctx context.Context, playerID string) (seat.Seat, error) {
+	var st seat.Seat
+	var disconnectedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT player_id, table_id, match_id, game_mode, region, address, seated_at, disconnected_at
+		FROM seats WHERE player_id = $1`, playerID).Scan(
+		&st.PlayerID, &st.TableID, &st.MatchID, &st.GameMode, &st.Region, &st.Address, &st.SeatedAt, &disconnectedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return seat.Seat{}, seat.ErrNotSeated
+	}
+	if err != nil {
+		return seat.Seat{}, err
+	}
+	st.DisconnectedAt = disconnectedAt.Time
+	return st, nil
+}
+
+func (s *Seats) DeleteSeat(ctx context.Context, playerID string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM seats WHERE player_id = $1`, playerID)
+	return err
+}
+
+func (s *Seats) DeleteTable(ctx context.Context, tableID string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM seats WHERE table_id = $1`, tableID)
+	return err
+}
@@ -0,0 +1,217 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/session"
+)
+
+// RefreshTokens is a session.RefreshStore backed by the refresh_tokens and
+// sessions tables.
+type RefreshTokens struct {
+	db *DB
+}
+
+// NewRefreshTokens returns a refresh token store using db.
+func NewRefreshTokens(db *DB) *RefreshTokens {
+	return &RefreshTokens{db: db}
+}
+
+func (s *RefreshTokens) SaveRefreshToken(ctx context.Context, t session.RefreshToken) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (id, player_id, family, expires_at, revoked_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		t.ID, t.PlayerID, t.Family, t.ExpiresAt, nullTime(t.RevokedAt))
+	return err
+}
+
+func (s *RefreshTokens) RevokeRefreshToken(ctx context.Context, id string, at time.Time) (session.RefreshToken, error) {
+	if s.db.Dialect == SQLite {
+		return s.revokeRefreshTokenSQLite(ctx, id, at)
+	}
+	t := session.RefreshToken{ID: id}
+	var revokedAt sql.NullTime
+	// The subquery locks the row and reads revoked_at as it was before the
+	// update, so that of two concurrent refreshes only one sees it unset.
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE refresh_tokens t SET revoked_at = COALESCE(t.revoked_at, $2)
+		FROM (SELECT id, revoked_at FROM refresh_tokens WHERE id = $1 FOR UPDATE) old
+		WHERE t.id = old.id
+		RETURNING t.player_id, t.family, t.expires_at, old.revoked_at`, id, at).Scan(
+		&t.PlayerID, &t.Family, &t.ExpiresAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session.RefreshToken{}, session.ErrInvalidToken
+	}
+	if err != nil {
+		return session.RefreshToken{}, err
+	}
+	t.RevokedAt = revokedAt.Time
+	return t, nil
+}
+
+// revokeRefreshTokenSQLite is RevokeRefreshToken for SQLite, which has no
+// row locks: its one connection serializes the read and the update instead.
+func (s *RefreshTokens) revokeRefreshTokenSQLite(ctx context.Context, id string, at time.Time) (session.RefreshToken, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return session.RefreshToken{}, err
+	}
+	defer tx.Rollback()
+	t := session.RefreshToken{ID: id}
+	var revokedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT player_id, family, expires_at, revoked_at FROM refresh_tokens WHERE id = $1`, id).Scan(
+		&t.PlayerID, &t.Family, &t.ExpiresAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session.RefreshToken{}, session.ErrInvalidToken
+	}
+	if err != nil {
+		return session.RefreshToken{}, err
+	}
+	if !revokedAt.Valid {
+		if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = $2 WHERE id = $1`, id, at); err != nil {
+			return session.RefreshToken{}, err
+		}
+	}
+	t.RevokedAt = revokedAt.Time
+	return t, tx.Commit()
+}
+
+func (s *RefreshTokens) RevokeFamily(ctx context.Context, family string, at time.Time) error {
+	if s.db.Dialect == SQLite {
+		return s.revokeSQLite(ctx, "id", "family", family, at)
+	}
+	_, err := s.db.ExecContext(ctx, `
+		WITH revoked AS (
+			UPDATE sessions SET revoked_at = $2
+			WHERE id = $1 AND revoked_at IS NULL
+		)
+		UPDATE refresh_tokens SET revoked_at = $2
+		WHERE family = $1 AND revoked_at IS NULL`, family, at)
+	return err
+}
+
+func (s *RefreshTokens) RevokePlayer(ctx context.Context, playerID string, at time.Time) error {
+	if s.db.Dialect == SQLite {
+		return s.revokeSQLite(ctx, "player_id", "player_id", playerID, at)
+	}
+	_, err := s.db.ExecContext(ctx, `
+		WITH revoked AS (
+			UPDATE sessions SET revoked_at = $2
+			WHERE player_id = $1 AND revoked_at IS NULL
+		)
+		UPDATE refresh_tokens SET revoked_at = $2
+		WHERE player_id = $1 AND revoked_at IS NULL`, playerID, at)
+	return err
+}
+
+// revokeSQLite revokes the sessions whose sessionColumn is value and the
+// refresh tokens whose tokenColumn is, in a transaction, as SQLite can't
+// update in a WITH clause.
+func (s *RefreshTokens) revokeSQLite(ctx context.Context, sessionColumn, tokenColumn, value string, at time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, `
+		UPDATE sessions SET revoked_at = $2
+		WHERE `+sessionColumn+` = $1 AND revoked_at IS NULL`, value, at)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = $2
+		WHERE `+tokenColumn+` = $1 AND revoked_at IS NULL`, value, at)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *RefreshTokens) SaveSession(ctx context.Context, sess session.Session) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sessions (id, player_id, created_at, refreshed_at, expires_at, revoked_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		sess.ID, sess.PlayerID, sess.CreatedAt, sess.RefreshedAt, sess.ExpiresAt, nullTime(sess.RevokedAt))
+	return err
+}
+
+func (s *RefreshTokens) TouchSession(ctx context.Context, id string, at, expiresAt time.Time) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE sessions SET refreshed_at = $2, expires_at = $3
+		WHERE id = $1`, id, at, expiresAt)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return session.ErrNotFound
+	}
+	return nil
+}
+
+const sessionColumns = `id, player_id, created_at, refreshed_at, expires_at, revoked_at`
+
+func (s *RefreshTokens) GetSession(ctx context.Context, id string) (session.Session, error) {
+	sess, err := scanSession(s.db.QueryRowContext(ctx, `
+		SELECT `+sessionColumns+` FROM sessions WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return session.Session{}, session.ErrNotFound
+	}
+	return sess, err
+}
+
+func (s *RefreshTokens) ListSessions(ctx context.Context, playerID string) ([]session.Session, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+sessionColumns+` FROM sessions
+		WHERE player_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at, id`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sessions []session.Session
+	for rows.Next() {
+		sess, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *RefreshTokens) RevokedSessions(ctx context.Context, since time.Time) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id FROM sessions WHERE revoked_at >= $1`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func scanSession(row interface{ Scan(...any) error }) (session.Session, error) {
+	var sess session.Session
+	var revokedAt sql.NullTime
+	if err := row.Scan(&sess.ID, &sess.PlayerID, &sess.CreatedAt, &sess.RefreshedAt, &sess.ExpiresAt, &revokedAt); err != nil {
+		return session.Session{}, err
+	}
+	sess.RevokedAt = revokedAt.Time
+	return sess, nil
+}
@@ -0,0 +1,86 @@
+// Package store persists matchmaker state in Postgres, or for local
+// development and tests, in SQLite.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+
+	"github.com/jfmatt/snapfold/lib/metrics"
+)
+
+// Dialect is the kind of database a DB is.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// DB is a database connection and the dialect of SQL it speaks. Queries
+// are written for Postgres, and only those SQLite can't run have a variant
+// for it.
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+
+	// The read replica, if there is one, and its lag in nanoseconds when
+	// last measured, or -1 if that failed.
+	replica *sql.DB
+	lag     atomic.Int64
+
+	// Times statements, if set.
+	latency *metrics.Histogram
+}
+
+// Open connects to the database at dsn and checks that it is reachable.
+// DSNs starting with "sqlite:" name a SQLite database file, as in
+// "sqlite:snapfold.db" or "sqlite:///var/lib/snapfold.db"; any other DSN is
+// a Postgres connection string.
+func Open(ctx context.Context, dsn string) (*DB, error) {
+	db := &DB{Dialect: Postgres}
+	var err error
+	if path, ok := sqlitePath(dsn); ok {
+		db.Dialect = SQLite
+		db.DB, err = sql.Open("sqlite", path)
+		// SQLite allows one writer at a time, so the pool is limited to
+		// one connection rather than have writers fail as busy.
+		db.SetMaxOpenConns(1)
+	} else {
+		db.DB, err = sql.Open("pgx", dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// sqlitePath returns the driver DSN for a sqlite: DSN, with the options the
+// store relies on: foreign keys enforced and times written in a format that
+// sorts as text.
+func sqlitePath(dsn string) (string, bool) {
+	rest, ok := strings.CutPrefix(dsn, "sqlite:")
+	if !ok {
+		return "", false
+	}
+	rest = strings.TrimPrefix(rest, "//")
+	path, query, _ := strings.Cut(rest, "?")
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		// Leave the malformed query for the driver to report.
+		return rest, true
+	}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Set("_time_format", "sqlite")
+	return path + "?" + q.Encode(), true
+}
@@ -0,0 +1,445 @@
+package store
+
+import (
+	"context"
+	"iter"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/lib/idempotency"
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/audit"
+	"github.com/jfmatt/snapfold/matchmaker/history"
+	"github.com/jfmatt/snapfold/matchmaker/rating"
+	"github.com/jfmatt/snapfold/matchmaker/session"
+	"github.com/jfmatt/snapfold/matchmaker/wallet"
+	"github.com/jfmatt/snapfold/matchmaker/webhook"
+)
+
+var ctx = context.Background()
+
+// openTest returns a migrated in-memory SQLite database.
+func openTest(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(ctx, "sqlite::memory:")
+	AssertThat(t, err, Nil())
+	t.Cleanup(func() { db.Close() })
+	_, err = MigrateUp(ctx, db, 0)
+	AssertThat(t, err, Nil())
+	return db
+}
+
+func TestSQLitePath(t *testing.T) {
+	path, ok := sqlitePath("sqlite:///var/lib/snapfold.db")
+	ExpectEq(t, ok, true)
+	ExpectEq(t, path, "/var/lib/snapfold.db?_pragma=foreign_keys%281%29&_time_format=sqlite")
+	path, ok = sqlitePath("sqlite:snapfold.db?_pragma=busy_timeout(5000)")
+	ExpectEq(t, ok, true)
+	ExpectEq(t, path, "snapfold.db?_pragma=busy_timeout%285000%29&_pragma=foreign_keys%281%29&_time_format=sqlite")
+	_, ok = sqlitePath("postgres://localhost/snapfold")
+	ExpectEq(t, ok, false)
+}
+
+func TestMigrate_SQLite(t *testing.T) {
+	db, err := Open(ctx, "sqlite::memory:")
+	AssertThat(t, err, Nil())
+	defer db.Close()
+
+	plan, err := PlanUp(ctx, db, 0)
+	AssertThat(t, err, Nil())
+	AssertThat(t, plan, Not(Empty()))
+	applied, err := MigrateUp(ctx, db, 0)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, len(applied), len(plan))
+	applied, err = MigrateUp(ctx, db, 0)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, applied, Empty())
+
+	states, err := MigrationStatus(ctx, db)
+	AssertThat(t, err, Nil())
+	for _, s := range states {
+		ExpectEq(t, s.AppliedAt.IsZero(), false)
+	}
+
+	reverted, err := MigrateDown(ctx, db, 0, 0)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, len(reverted), len(plan))
+	_, err = MigrateUp(ctx, db, 0)
+	ExpectThat(t, err, Nil())
+}
+
+func TestMigrations(t *testing.T) {
+	for _, d := range []Dialect{Postgres, SQLite} {
+		all, err := Migrations(d)
+		AssertThat(t, err, Nil())
+		AssertThat(t, all, Not(Empty()))
+	}
+}
+
+func TestAccounts_SQLite(t *testing.T) {
+	s := NewAccounts(openTest(t))
+	now := time.Now().Truncate(time.Second)
+	a := account.Account{Username: "alice", Email: "alice@example.com", PasswordHash: "hash", Role: account.RolePlayer, CreatedAt: now}
+	AssertThat(t, s.CreateAccount(ctx, a), Nil())
+	ExpectThat(t, s.CreateAccount(ctx, a), ErrorIs(account.ErrUsernameTaken))
+
+	a.Ban = &account.Ban{Reason: "collusion", By: "bob", At: now, Until: now.Add(time.Hour)}
+	AssertThat(t, s.UpdateAccount(ctx, a), Nil())
+	got, err := s.GetAccount(ctx, "alice")
+	AssertThat(t, err, Nil())
+	AssertThat(t, got.Ban, Not(Nil()))
+	ExpectEq(t, got.Ban.Reason, "collusion")
+	ExpectEq(t, got.Ban.Until.Equal(now.Add(time.Hour)), true)
+	ExpectEq(t, got.CreatedAt.Equal(now), true)
+
+	AssertThat(t, s.SaveReset(ctx, account.Reset{Username: "alice", TokenHash: "h", SentAt: now, ExpiresAt: now.Add(time.Hour)}), Nil())
+	r, err := s.TakeReset(ctx, "h")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, r.Username, "alice")
+	_, err = s.TakeReset(ctx, "h")
+	ExpectThat(t, err, ErrorIs(account.ErrNotFound))
+}
+
+func TestRefreshTokens_SQLite(t *testing.T) {
+	s := NewRefreshTokens(openTest(t))
+	now := time.Now()
+	AssertThat(t, s.SaveSession(ctx, session.Session{ID: "f", PlayerID: "alice", CreatedAt: now, RefreshedAt: now, ExpiresAt: now.Add(time.Hour)}), Nil())
+	AssertThat(t, s.SaveRefreshToken(ctx, session.RefreshToken{ID: "t1", PlayerID: "alice", Family: "f", ExpiresAt: now.Add(time.Hour)}), Nil())
+
+	tok, err := s.RevokeRefreshToken(ctx, "t1", now)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, tok.Family, "f")
+	ExpectEq(t, tok.RevokedAt.IsZero(), true)
+	// Only the first revocation sees the token unrevoked.
+	tok, err = s.RevokeRefreshToken(ctx, "t1", now.Add(time.Second))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, tok.RevokedAt.IsZero(), false)
+	_, err = s.RevokeRefreshToken(ctx, "t2", now)
+	ExpectThat(t, err, ErrorIs(session.ErrInvalidToken))
+
+	sessions, err := s.ListSessions(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, sessions, Len(1))
+	AssertThat(t, s.RevokePlayer(ctx, "alice", now), Nil())
+	sessions, err = s.ListSessions(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, sessions, Empty())
+	revoked, err := s.RevokedSessions(ctx, now.Add(-time.Minute))
+	AssertThat(t, err, Nil())
+	ExpectThat(t, revoked, ElementsAre("f"))
+}
+
+func TestMatches_SQLite(t *testing.T) {
+	s := NewMatches(openTest(t))
+	now := time.Now()
+	for i, players := range [][]string{{"alice", "bob"}, {"bob", "carol"}, {"alice", "carol"}} {
+		m := history.Match{ID: string(rune('a' + i)), GameMode: "holdem", Players: players, CreatedAt: now.Add(time.Duration(i) * time.Minute)}
+		AssertThat(t, s.SaveMatch(ctx, m), Nil())
+	}
+	matches, err := s.ListMatches(ctx, "alice", history.Query{Limit: 10})
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(2))
+	ExpectEq(t, matches[0].ID, "c")
+	ExpectEq(t, matches[1].ID, "a")
+
+	matches, err = s.ListMatches(ctx, "alice", history.Query{After: history.Cursor{CreatedAt: matches[0].CreatedAt, ID: "c"}, Limit: 10})
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].ID, "a")
+
+	matches, err = s.ListMatches(ctx, "carol", history.Query{OldestFirst: true, After: history.Cursor{CreatedAt: now.Add(time.Minute), ID: "b"}, Limit: 10})
+	AssertThat(t, err, Nil())
+	AssertThat(t, matches, Len(1))
+	ExpectEq(t, matches[0].ID, "c")
+
+	matches, err = s.ListMatches(ctx, "alice", history.Query{GameMode: "omaha", Limit: 10})
+	AssertThat(t, err, Nil())
+	ExpectThat(t, matches, Empty())
+}
+
+func TestRatings_SQLite(t *testing.T) {
+	s := NewRatings(openTest(t))
+	AssertThat(t, s.Put(ctx, map[string]rating.Rating{"alice": {Rating: 1600, Deviation: 100, Volatility: 0.06}}), Nil())
+	got, err := s.Get(ctx, "alice", "bob")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got["alice"].Rating, 1600.0)
+	ExpectEq(t, got["bob"], rating.Default())
+}
+
+func TestVerifySchema_SQLite(t *testing.T) {
+	db := openTest(t)
+	drift, err := VerifySchema(ctx, db)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, drift, Empty())
+
+	_, err = db.ExecContext(ctx, `
+		DROP INDEX seats_table_id;
+		CREATE INDEX tickets_created_at ON tickets (created_at);`)
+	AssertThat(t, err, Nil())
+	drift, err = VerifySchema(ctx, db)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, drift, ElementsAre(
+		Drift{Object: "index seats_table_id", Want: "CREATE INDEX seats_table_id ON seats (table_id)"},
+		Drift{Object: "index tickets_created_at", Got: "CREATE INDEX tickets_created_at ON tickets (created_at)"},
+	))
+
+	// With the drift undone and a migration reverted, the schema is the
+	// one the remaining migrations make.
+	_, err = db.ExecContext(ctx, `
+		DROP INDEX tickets_created_at;
+		CREATE INDEX seats_table_id ON seats (table_id);`)
+	AssertThat(t, err, Nil())
+	_, err = MigrateDown(ctx, db, 1, -1)
+	AssertThat(t, err, Nil())
+	drift, err = VerifySchema(ctx, db)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, drift, Empty())
+}
+
+func TestDestructive(t *testing.T) {
+	for _, tc := range []struct {
+		script string
+		want   bool
+	}{
+		{`CREATE TABLE t (id TEXT PRIMARY KEY);`, false},
+		{`ALTER TABLE t ADD COLUMN n INTEGER NOT NULL DEFAULT 0;`, false},
+		{`DROP INDEX IF EXISTS t_n; ALTER TABLE t DROP CONSTRAINT t_n_key;`, false},
+		{`ALTER TABLE t ALTER COLUMN n DROP DEFAULT, ALTER n DROP NOT NULL;`, false},
+		{"-- DROP TABLE t would lose everything.\nSELECT 1;", false},
+		{`DROP TABLE IF EXISTS t;`, true},
+		{`ALTER TABLE t DROP COLUMN n;`, true},
+		{`ALTER TABLE t DROP n;`, true},
+		{`DELETE FROM t WHERE n = 0;`, true},
+		{`TRUNCATE t;`, true},
+		{`ALTER TABLE t ALTER COLUMN n TYPE BIGINT;`, true},
+		{`ALTER TABLE t ALTER n SET DATA TYPE SMALLINT;`, true},
+	} {
+		t.Run(tc.script, func(t *testing.T) {
+			ExpectEq(t, Destructive(tc.script), tc.want)
+		})
+	}
+}
+
+func TestPruneHands_SQLite(t *testing.T) {
+	s := NewMatches(openTest(t))
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	AssertThat(t, s.SaveHands(ctx, []history.Hand{
+		{Number: 1, TableID: "t1", PlayerID: "alice", PlayedAt: t0, Text: "hand 1"},
+		{Number: 2, TableID: "t1", PlayerID: "alice", PlayedAt: t0.Add(time.Hour), Text: "hand 2"},
+	}), Nil())
+
+	AssertThat(t, s.PruneHands(ctx, time.Time{}, nil), Nil())
+	hands, err := s.ListHands(ctx, "alice", time.Time{}, 10)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, hands, Len(2))
+
+	var archived []uint64
+	err = s.PruneHands(ctx, t0.Add(time.Minute), func(ctx context.Context, from, to time.Time, hands iter.Seq2[history.Hand, error]) error {
+		for h, err := range hands {
+			AssertThat(t, err, Nil())
+			archived = append(archived, h.Number)
+		}
+		return nil
+	})
+	AssertThat(t, err, Nil())
+	ExpectThat(t, archived, ElementsAre(uint64(1)))
+	hands, err = s.ListHands(ctx, "alice", time.Time{}, 10)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, hands, Len(1))
+	ExpectEq(t, hands[0].Number, uint64(2))
+}
+
+func TestIdempotencyKeys_SQLite(t *testing.T) {
+	s := NewIdempotencyKeys(openTest(t))
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := idempotency.Record{Scope: "alice", Key: "k1", Fingerprint: "f1", CreatedAt: t0}
+
+	_, reserved, err := s.Reserve(ctx, r, t0.Add(-time.Hour))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, reserved, true)
+	got, reserved, err := s.Reserve(ctx, r, t0.Add(-time.Hour))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, reserved, false)
+	ExpectEq(t, got.Done, false)
+
+	AssertThat(t, s.Complete(ctx, "alice", "k1", []byte("response")), Nil())
+	got, _, err = s.Reserve(ctx, r, t0.Add(-time.Hour))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got.Fingerprint, "f1")
+	ExpectEq(t, got.Done, true)
+	ExpectEq(t, string(got.Response), "response")
+
+	// Keys are scoped.
+	_, reserved, err = s.Reserve(ctx, idempotency.Record{Scope: "bob", Key: "k1", CreatedAt: t0}, t0.Add(-time.Hour))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, reserved, true)
+
+	// Expired keys are reused.
+	_, reserved, err = s.Reserve(ctx, idempotency.Record{Scope: "alice", Key: "k1", CreatedAt: t0.Add(time.Hour)}, t0)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, reserved, true)
+
+	// So are keys whose lease ran out before they were done, but not
+	// those done.
+	leased := idempotency.Record{Scope: "carol", Key: "k1", Fingerprint: "f1", CreatedAt: t0, LeaseUntil: t0.Add(time.Minute)}
+	_, reserved, err = s.Reserve(ctx, leased, t0.Add(-time.Hour))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, reserved, true)
+	leased.CreatedAt = t0.Add(30 * time.Second)
+	_, reserved, err = s.Reserve(ctx, leased, t0.Add(-time.Hour))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, reserved, false)
+	leased.CreatedAt, leased.LeaseUntil = t0.Add(time.Minute), t0.Add(2*time.Minute)
+	_, reserved, err = s.Reserve(ctx, leased, t0.Add(-time.Hour))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, reserved, true)
+	AssertThat(t, s.Complete(ctx, "carol", "k1", []byte("response")), Nil())
+	leased.CreatedAt = t0.Add(time.Hour)
+	got, reserved, err = s.Reserve(ctx, leased, t0.Add(-time.Hour))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, reserved, false)
+	ExpectEq(t, got.Done, true)
+
+	AssertThat(t, s.Release(ctx, "alice", "k1"), Nil())
+	AssertThat(t, s.DeleteBefore(ctx, t0.Add(time.Minute)), Nil())
+	_, reserved, err = s.Reserve(ctx, idempotency.Record{Scope: "bob", Key: "k1", CreatedAt: t0}, t0.Add(-time.Hour))
+	AssertThat(t, err, Nil())
+	ExpectEq(t, reserved, true)
+}
+
+func TestWebhooks_SQLite(t *testing.T) {
+	s := NewWebhooks(openTest(t))
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	h := webhook.Hook{ID: "wh1", URL: "https://example.com/hook", Events: []webhook.Event{webhook.MatchCreated}, Secret: "s", CreatedAt: t0}
+	AssertThat(t, s.SaveHook(ctx, h), Nil())
+	got, err := s.GetHook(ctx, "wh1")
+	AssertThat(t, err, Nil())
+	ExpectThat(t, got.Events, ElementsAre(webhook.MatchCreated))
+	_, err = s.GetHook(ctx, "wh2")
+	ExpectThat(t, err, ErrorIs(webhook.ErrNotFound))
+
+	var ds []webhook.Delivery
+	for i, id := range []string{"d1", "d2", "d3"} {
+		ds = append(ds, webhook.Delivery{
+			ID:            id,
+			HookID:        "wh1",
+			Event:         webhook.MatchCreated,
+			Payload:       []byte(`{}`),
+			CreatedAt:     t0.Add(time.Duration(i) * time.Second),
+			State:         webhook.Pending,
+			NextAttemptAt: t0.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	AssertThat(t, s.SaveDeliveries(ctx, ds), Nil())
+
+	// Claimed deliveries aren't claimed again until their lease runs out.
+	claimed, err := s.ClaimDeliveries(ctx, t0.Add(time.Minute), t0.Add(time.Hour), 10)
+	AssertThat(t, err, Nil())
+	AssertThat(t, claimed, Len(2))
+	ExpectEq(t, claimed[0].ID, "d1")
+	claimed, err = s.ClaimDeliveries(ctx, t0.Add(2*time.Minute), t0.Add(time.Hour), 10)
+	AssertThat(t, err, Nil())
+	AssertThat(t, claimed, Len(1))
+	ExpectEq(t, claimed[0].ID, "d3")
+
+	d := claimed[0]
+	d.State, d.Attempts, d.LastStatus, d.DeliveredAt = webhook.Delivered, 1, 204, t0.Add(2*time.Minute)
+	AssertThat(t, s.UpdateDelivery(ctx, d), Nil())
+	log, err := s.ListDeliveries(ctx, "wh1", webhook.Query{Limit: 2})
+	AssertThat(t, err, Nil())
+	AssertThat(t, log, Len(2))
+	ExpectEq(t, log[0].ID, "d3")
+	ExpectEq(t, log[0].State, webhook.Delivered)
+	ExpectEq(t, log[0].LastStatus, 204)
+	log, err = s.ListDeliveries(ctx, "wh1", webhook.Query{OldestFirst: true, AfterCreatedAt: log[1].CreatedAt, AfterID: log[1].ID, Limit: 2})
+	AssertThat(t, err, Nil())
+	AssertThat(t, log, Len(1))
+	ExpectEq(t, log[0].ID, "d3")
+
+	AssertThat(t, s.DeleteHook(ctx, "wh1"), Nil())
+	ExpectThat(t, s.DeleteHook(ctx, "wh1"), ErrorIs(webhook.ErrNotFound))
+}
+
+func TestAuditLog_SQLite(t *testing.T) {
+	db := openTest(t)
+	s := NewAuditLog(db)
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, e := range []audit.Entry{
+		{ID: "a1", Actor: "alice", Action: audit.RoleSet, Target: "bob", Before: []byte(`"player"`), After: []byte(`"admin"`)},
+		{ID: "a2", Actor: "alice", Action: audit.PasswordResetSent, Target: "carol"},
+		{ID: "a3", Actor: "bob", Action: audit.AccountBanned, Target: "carol", After: []byte(`{"reason":"spam"}`)},
+	} {
+		e.Time = t0.Add(time.Duration(i) * time.Minute)
+		AssertThat(t, s.Append(ctx, e), Nil())
+	}
+
+	log, err := s.List(ctx, audit.Query{Limit: 2})
+	AssertThat(t, err, Nil())
+	AssertThat(t, log, Len(2))
+	ExpectEq(t, log[0].ID, "a3")
+	ExpectEq(t, log[0].Action, audit.AccountBanned)
+	ExpectEq(t, string(log[0].After), `{"reason":"spam"}`)
+	ExpectThat(t, log[0].Before, Nil())
+	log, err = s.List(ctx, audit.Query{AfterTime: log[1].Time, AfterID: log[1].ID, Limit: 2})
+	AssertThat(t, err, Nil())
+	AssertThat(t, log, Len(1))
+	ExpectEq(t, log[0].ID, "a1")
+	ExpectEq(t, string(log[0].Before), `"player"`)
+
+	log, err = s.List(ctx, audit.Query{Actor: "alice", Target: "carol", OldestFirst: true, Limit: 10})
+	AssertThat(t, err, Nil())
+	AssertThat(t, log, Len(1))
+	ExpectEq(t, log[0].ID, "a2")
+	log, err = s.List(ctx, audit.Query{Action: audit.AccountBanned, Limit: 10})
+	AssertThat(t, err, Nil())
+	AssertThat(t, log, Len(1))
+	ExpectEq(t, log[0].ID, "a3")
+
+	// Entries can't be changed or removed.
+	_, err = db.ExecContext(ctx, `UPDATE audit_log SET actor = 'mallory'`)
+	ExpectThat(t, err, ErrorMessage(HasSubstr("append-only")))
+	_, err = db.ExecContext(ctx, `DELETE FROM audit_log WHERE id = 'a1'`)
+	ExpectThat(t, err, ErrorMessage(HasSubstr("append-only")))
+}
+
+func TestWallets_SQLite(t *testing.T) {
+	w := wallet.New(NewWallets(openTest(t)))
+	balance, err := w.Balance(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, balance, int64(0))
+
+	balance, err = w.Deposit(ctx, "alice", 5000, "d1")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, balance, int64(5000))
+	for range 2 {
+		balance, err = w.Debit(ctx, "alice", 2000, "m1/1")
+		AssertThat(t, err, Nil())
+		ExpectEq(t, balance, int64(3000))
+	}
+	_, err = w.Debit(ctx, "alice", 4000, "m1/2")
+	ExpectThat(t, err, ErrorIs(wallet.ErrInsufficientFunds))
+	_, err = w.Debit(ctx, "bob", 1, "m1/3")
+	ExpectThat(t, err, ErrorIs(wallet.ErrInsufficientFunds))
+
+	// The refused debit wasn't recorded, so it can be retried once the
+	// player can afford it.
+	_, err = w.Deposit(ctx, "alice", 1000, "d2")
+	AssertThat(t, err, Nil())
+	balance, err = w.Debit(ctx, "alice", 4000, "m1/2")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, balance, int64(0))
+	balance, err = w.Credit(ctx, "alice", 4000, "m1/2")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, balance, int64(4000))
+
+	// Credits only give back debits, of what was debited.
+	_, err = w.Credit(ctx, "alice", 5000, "m1/3")
+	ExpectThat(t, err, ErrorIs(wallet.ErrNoDebit))
+	_, err = w.Credit(ctx, "alice", 1000, "m1/2")
+	ExpectThat(t, err, ErrorIs(wallet.ErrNoDebit))
+	balance, err = w.Balance(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, balance, int64(4000))
+}
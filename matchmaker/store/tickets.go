@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+// Tickets is a queue.TicketStore backed by the tickets table.
+type Tickets struct {
+	db *DB
+}
+
+// NewTickets returns a ticket store using db.
+func NewTickets(db *DB) *Tickets {
+	return &Tickets{db: db}
+}
+
+func (s *Tickets) SaveTicket(ctx context.Context, r queue.Record) error {
+	members, err := json.Marshal(r.Ticket.Members)
+	if err != nil {
+		return err
+	}
+	// Only queued tickets may change state, so a late or repeated save can
+	// never undo a cancellation or a match.
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO tickets (id, player_id, party_id, members, game_mode, priority, rating,
+			state, match_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (id) DO UPDATE SET
+			state = excluded.state,
+			match_id = excluded.match_id,
+			updated_at = excluded.updated_at
+		WHERE tickets.state = 'queued'`,
+		r.Ticket.ID, r.Ticket.PlayerID, r.Ticket.PartyID, string(members), r.Ticket.GameMode,
+		r.Ticket.Priority.String(), r.Ticket.Rating, r.State.String(), r.MatchID,
+		r.Ticket.CreatedAt, r.UpdatedAt)
+	return err
+}
+
+func (s *Tickets) GetTicket(ctx context.Context, id string) (queue.Record, error) {
+	var r queue.Record
+	var members []byte
+	var priority, state string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, player_id, party_id, members, game_mode, priority, rating,
+			state, match_id, created_at, updated_at
+		FROM tickets WHERE id = $1`, id).Scan(
+		&r.Ticket.ID, &r.Ticket.PlayerID, &r.Ticket.PartyID, &members, &r.Ticket.GameMode,
+		&priority, &r.Ticket.Rating, &state, &r.MatchID, &r.Ticket.CreatedAt, &r.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return queue.Record{}, queue.ErrNotFound
+	}
+	if err != nil {
+		return queue.Record{}, err
+	}
+	if err := json.Unmarshal(members, &r.Ticket.Members); err != nil {
+		return queue.Record{}, err
+	}
+	if r.Ticket.Priority, err = queue.ParsePriority(priority); err != nil {
+		return queue.Record{}, err
+	}
+	if r.State, err = queue.ParseState(state); err != nil {
+		return queue.Record{}, err
+	}
+	return r, nil
+}
@@ -0,0 +1,210 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Drift is a difference between a database's schema and the schema its
+// applied migrations make.
+type Drift struct {
+	// The object that differs, such as "column accounts.email" or
+	// "index identities_username_provider".
+	Object string
+
+	// Its definition as the migrations make it and as the database has it.
+	// Empty where it doesn't exist.
+	Want, Got string
+}
+
+func (d Drift) String() string {
+	switch {
+	case d.Got == "":
+		return fmt.Sprintf("missing %s: %s", d.Object, d.Want)
+	case d.Want == "":
+		return fmt.Sprintf("unexpected %s: %s", d.Object, d.Got)
+	default:
+		return fmt.Sprintf("changed %s: want %s, got %s", d.Object, d.Want, d.Got)
+	}
+}
+
+// VerifySchema compares db's schema with the one the migrations it has
+// applied make, and returns how they differ, by object. The expected
+// schema is made by replaying the migrations: in Postgres, in a scratch
+// schema in a transaction that is rolled back, so the user needs to be able
+// to create schemas; in SQLite, in an in-memory database. Only tables,
+// columns, constraints and indexes are compared, and schema_migrations is
+// ignored.
+func VerifySchema(ctx context.Context, db *DB) ([]Drift, error) {
+	states, err := MigrationStatus(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	var applied []Migration
+	for _, s := range states {
+		if s.Unknown {
+			return nil, fmt.Errorf("migration %s was applied by a newer build, which must verify the schema", s.Migration)
+		}
+		if !s.AppliedAt.IsZero() {
+			applied = append(applied, s.Migration)
+		}
+	}
+	got, err := schemaOf(ctx, db.Dialect, db)
+	if err != nil {
+		return nil, err
+	}
+	want, err := expectedSchema(ctx, db, applied)
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []Drift
+	for object, w := range want {
+		if g := got[object]; g != w {
+			drift = append(drift, Drift{Object: object, Want: w, Got: g})
+		}
+	}
+	for object, g := range got {
+		if _, ok := want[object]; !ok {
+			drift = append(drift, Drift{Object: object, Got: g})
+		}
+	}
+	slices.SortFunc(drift, func(a, b Drift) int { return cmp.Compare(a.Object, b.Object) })
+	return drift, nil
+}
+
+// expectedSchema replays migrations on an empty database of db's dialect
+// and returns the schema they make.
+func expectedSchema(ctx context.Context, db *DB, migrations []Migration) (map[string]string, error) {
+	if db.Dialect == SQLite {
+		scratch, err := Open(ctx, "sqlite::memory:")
+		if err != nil {
+			return nil, err
+		}
+		defer scratch.Close()
+		for _, m := range migrations {
+			if _, err := scratch.ExecContext(ctx, m.Up); err != nil {
+				return nil, fmt.Errorf("replaying %s: %w", m, err)
+			}
+		}
+		return schemaOf(ctx, SQLite, scratch)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	scratch := "snapfold_verify_" + strings.ToLower(rand.Text())
+	if _, err := tx.ExecContext(ctx, `CREATE SCHEMA `+scratch); err != nil {
+		return nil, fmt.Errorf("creating a scratch schema: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `SET LOCAL search_path = `+scratch); err != nil {
+		return nil, err
+	}
+	for _, m := range migrations {
+		if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+			return nil, fmt.Errorf("replaying %s: %w", m, err)
+		}
+	}
+	return schemaOf(ctx, Postgres, tx)
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// schemaOf describes the objects in the current schema, keyed by kind and
+// name. Postgres definitions name other objects as seen from the schema,
+// so that the same objects in different schemas are described alike.
+func schemaOf(ctx context.Context, dialect Dialect, q queryer) (map[string]string, error) {
+	schema := map[string]string{}
+	add := func(query string, describe func(cols []string) (string, string)) error {
+		rows, err := q.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		names, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+		n := len(names)
+		for rows.Next() {
+			cols := make([]string, n)
+			dest := make([]any, n)
+			for i := range cols {
+				dest[i] = &cols[i]
+			}
+			if err := rows.Scan(dest...); err != nil {
+				return err
+			}
+			object, def := describe(cols)
+			schema[object] = def
+		}
+		return rows.Err()
+	}
+
+	if dialect == SQLite {
+		// SQLite keeps each object's definition as the SQL that made it,
+		// amended by ALTER TABLE, so replaying the migrations reproduces it.
+		// Renaming a table quotes its name, which reverting a migration
+		// may do where replaying doesn't.
+		err := add(`
+			SELECT type, name, sql FROM sqlite_master
+			WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+				AND tbl_name <> 'schema_migrations'`,
+			func(c []string) (string, string) {
+				return c[0] + " " + c[1], strings.Join(strings.Fields(strings.ReplaceAll(c[2], `"`, "")), " ")
+			})
+		return schema, err
+	}
+
+	var current string
+	if err := q.QueryRowContext(ctx, `SELECT current_schema()`).Scan(&current); err != nil {
+		return nil, err
+	}
+	// Partitions are made as they are needed, so neither they nor their
+	// constraints and indexes are compared.
+	err := add(`
+		SELECT t.relname, a.attname, format_type(a.atttypid, a.atttypmod),
+			CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END,
+			COALESCE(' DEFAULT ' || pg_get_expr(d.adbin, d.adrelid), '')
+		FROM pg_attribute a
+		JOIN pg_class t ON t.oid = a.attrelid
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE t.relnamespace = (SELECT oid FROM pg_namespace WHERE nspname = current_schema()) AND t.relkind IN ('r', 'p')
+			AND t.relname <> 'schema_migrations' AND NOT t.relispartition AND a.attnum > 0 AND NOT a.attisdropped`,
+		func(c []string) (string, string) { return "column " + c[0] + "." + c[1], c[2] + c[3] + c[4] })
+	if err != nil {
+		return nil, err
+	}
+	err = add(`
+		SELECT t.relname, c.conname, pg_get_constraintdef(c.oid)
+		FROM pg_constraint c
+		JOIN pg_class t ON t.oid = c.conrelid
+		WHERE t.relnamespace = (SELECT oid FROM pg_namespace WHERE nspname = current_schema()) AND t.relname <> 'schema_migrations' AND NOT t.relispartition`,
+		func(c []string) (string, string) { return "constraint " + c[0] + "." + c[1], c[2] })
+	if err != nil {
+		return nil, err
+	}
+	// Index definitions always name their table's schema.
+	err = add(`
+		SELECT i.relname, pg_get_indexdef(i.oid)
+		FROM pg_index x
+		JOIN pg_class i ON i.oid = x.indexrelid
+		JOIN pg_class t ON t.oid = x.indrelid
+		WHERE t.relnamespace = (SELECT oid FROM pg_namespace WHERE nspname = current_schema()) AND t.relname <> 'schema_migrations' AND NOT t.relispartition`,
+		func(c []string) (string, string) {
+			def := strings.Replace(c[1], " ON "+current+".", " ON ", 1)
+			return "index " + c[0], strings.Replace(def, " ON ONLY "+current+".", " ON ONLY ", 1)
+		})
+	return schema, err
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jfmatt/snapfold/matchmaker/wallet"
+)
+
+// Wallets is a wallet.Store backed by the wallets table, and the
+// wallet_transactions table that makes transactions idempotent.
+type Wallets struct {
+	db *DB
+}
+
+// NewWallets returns a wallet store using db.
+func NewWallets(db *DB) *Wallets {
+	return &Wallets{db: db}
+}
+
+func (s *Wallets) Apply(ctx context.Context, tx wallet.Transaction) (int64, error) {
+	t, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer t.Rollback()
+	if tx.Kind == wallet.Credit {
+		var debit int64
+		err := t.QueryRowContext(ctx, `
+			SELECT amount FROM wallet_transactions
+			WHERE player_id = $1 AND kind = $2 AND ref = $3`,
+			tx.PlayerID, string(wallet.Debit), tx.Ref).Scan(&debit)
+		if errors.Is(err, sql.ErrNoRows) || err == nil && debit != tx.Amount {
+			return 0, wallet.ErrNoDebit
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	res, err := t.ExecContext(ctx, `
+		INSERT INTO wallet_transactions (player_id, kind, ref, amount, at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (player_id, kind, ref) DO NOTHING`,
+		tx.PlayerID, string(tx.Kind), tx.Ref, tx.Amount, tx.At)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		// Already applied.
+		var balance int64
+		err := t.QueryRowContext(ctx, `SELECT balance FROM wallets WHERE player_id = $1`, tx.PlayerID).Scan(&balance)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+		return balance, nil
+	}
+	var balance int64
+	if tx.Kind == wallet.Debit {
+		err = t.QueryRowContext(ctx, `
+			UPDATE wallets SET balance = balance - $2
+			WHERE player_id = $1 AND balance >= $2
+			RETURNING balance`,
+			tx.PlayerID, tx.Amount).Scan(&balance)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, wallet.ErrInsufficientFunds
+		}
+	} else {
+		err = t.QueryRowContext(ctx, `
+			INSERT INTO wallets (player_id, balance) VALUES ($1, $2)
+			ON CONFLICT (player_id) DO UPDATE SET balance = wallets.balance + excluded.balance
+			RETURNING balance`,
+			tx.PlayerID, tx.Amount).Scan(&balance)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return balance, t.Commit()
+}
+
+func (s *Wallets) Balance(ctx context.Context, playerID string) (int64, error) {
+	var balance int64
+	err := s.db.QueryRowContext(ctx, `SELECT balance FROM wallets WHERE player_id = $1`, playerID).Scan(&balance)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	return balance, nil
+}
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/webhook"
+)
+
+// Webhooks is a webhook.Store backed by the webhooks and
+// webhook_deliveries tables, so that deliveries survive restarts and are
+// shared by every matchmaker.
+type Webhooks struct {
+	db *DB
+}
+
+// NewWebhooks returns a webhook store using db.
+func NewWebhooks(db *DB) *Webhooks {
+	return &Webhooks{db: db}
+}
+
+const (
+	webhookColumns  = `id, url, events, secret, created_at`
+	deliveryColumns = `id, hook_id, event, payload, created_at, state, attempts, next_attempt_at,
+		last_status, last_error, delivered_at`
+)
+
+func (s *Webhooks) SaveHook(ctx context.Context, h webhook.Hook) error {
+	events, err := json.Marshal(h.Events)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO webhooks (`+webhookColumns+`) VALUES ($1, $2, $3, $4, $5)`,
+		h.ID, h.URL, string(events), h.Secret, h.CreatedAt)
+	return err
+}
+
+func (s *Webhooks) GetHook(ctx context.Context, id string) (webhook.Hook, error) {
+	h, err := scanWebhook(s.db.QueryRowContext(ctx, `
+		SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhook.Hook{}, webhook.ErrNotFound
+	}
+	return h, err
+}
+
+func (s *Webhooks) DeleteHook(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return webhook.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Webhooks) ListHooks(ctx context.Context) ([]webhook.Hook, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+webhookColumns+` FROM webhooks ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hooks []webhook.Hook
+	for rows.Next() {
+		h, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, h)
+	}
+	return hooks, rows.Err()
+}
+
+func scanWebhook(row interface{ Scan(...any) error }) (webhook.Hook, error) {
+	var h webhook.Hook
+	var events []byte
+	if err := row.Scan(&h.ID, &h.URL, &events, &h.Secret, &h.CreatedAt); err != nil {
+		return webhook.Hook{}, err
+	}
+	if err := json.Unmarshal(events, &h.Events); err != nil {
+		return webhook.Hook{}, err
+	}
+	return h, nil
+}
+
+func (s *Webhooks) SaveDeliveries(ctx context.Context, ds []webhook.Delivery) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, d := range ds {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO webhook_deliveries (`+deliveryColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+			d.ID, d.HookID, string(d.Event), d.Payload, d.CreatedAt, string(d.State), d.Attempts,
+			nullTime(d.NextAttemptAt), d.LastStatus, d.LastError, nullTime(d.DeliveredAt))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Webhooks) UpdateDelivery(ctx context.Context, d webhook.Delivery) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET state = $2, attempts = $3, next_attempt_at = $4,
+			last_status = $5, last_error = $6, delivered_at = $7
+		WHERE id = $1`,
+		d.ID, string(d.State), d.Attempts, nullTime(d.NextAttemptAt), d.LastStatus, d.LastError, nullTime(d.DeliveredAt))
+	return err
+}
+
+func (s *Webhooks) ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]webhook.Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE state = 'pending' AND next_attempt_at <= $1
+		ORDER BY next_attempt_at, id LIMIT `+fmt.Sprint(limit), now)
+	if err != nil {
+		return nil, err
+	}
+	due, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Another matchmaker may have claimed some of them since, in which case
+	// their next attempt is no longer due.
+	var claimed []webhook.Delivery
+	for _, d := range due {
+		res, err := s.db.ExecContext(ctx, `
+			UPDATE webhook_deliveries SET next_attempt_at = $2
+			WHERE id = $1 AND state = 'pending' AND next_attempt_at <= $3`,
+			d.ID, leaseUntil, now)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 1 {
+			d.NextAttemptAt = leaseUntil
+			claimed = append(claimed, d)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *Webhooks) ListDeliveries(ctx context.Context, hookID string, q webhook.Query) ([]webhook.Delivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE hook_id = $1`
+	args := []any{hookID}
+	op, order := "<", "DESC"
+	if q.OldestFirst {
+		op, order = ">", "ASC"
+	}
+	if q.AfterID != "" {
+		query += fmt.Sprintf(` AND (created_at, id) %s ($2, $3)`, op)
+		args = append(args, q.AfterCreatedAt, q.AfterID)
+	}
+	query += fmt.Sprintf(` ORDER BY created_at %s, id %s LIMIT %d`, order, order, q.Limit)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+func scanDeliveries(rows *sql.Rows) ([]webhook.Delivery, error) {
+	defer rows.Close()
+	var ds []webhook.Delivery
+	for rows.Next() {
+		var d webhook.Delivery
+		var event, state string
+		var nextAttemptAt, deliveredAt sql.NullTime
+		err := rows.Scan(&d.ID, &d.HookID, &event, &d.Payload, &d.CreatedAt, &state, &d.Attempts, &nextAttemptAt,
+			&d.LastStatus, &d.LastError, &deliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		d.Event, d.State = webhook.Event(event), webhook.State(state)
+		d.NextAttemptAt, d.DeliveredAt = nextAttemptAt.Time, deliveredAt.Time
+		ds = append(ds, d)
+	}
+	return ds, rows.Err()
+}
@@ -0,0 +1,48 @@
+package wallet
+
+import (
+	"context"
+	"sync"
+)
+
+// MemStore is an in-memory Store, for development and tests.
+type MemStore struct {
+	mu       sync.Mutex
+	balances map[string]int64
+	applied  map[[3]string]int64 // player ID, kind and ref -> amount
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{balances: map[string]int64{}, applied: map[[3]string]int64{}}
+}
+
+func (s *MemStore) Apply(ctx context.Context, tx Transaction) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := [3]string{tx.PlayerID, string(tx.Kind), tx.Ref}
+	if tx.Kind == Credit {
+		if debit, ok := s.applied[[3]string{tx.PlayerID, string(Debit), tx.Ref}]; !ok || debit != tx.Amount {
+			return 0, ErrNoDebit
+		}
+	}
+	if _, ok := s.applied[key]; ok {
+		return s.balances[tx.PlayerID], nil
+	}
+	change := tx.Amount
+	if tx.Kind == Debit {
+		if s.balances[tx.PlayerID] < tx.Amount {
+			return 0, ErrInsufficientFunds
+		}
+		change = -tx.Amount
+	}
+	s.applied[key] = tx.Amount
+	s.balances[tx.PlayerID] += change
+	return s.balances[tx.PlayerID], nil
+}
+
+func (s *MemStore) Balance(ctx context.Context, playerID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.balances[playerID], nil
+}
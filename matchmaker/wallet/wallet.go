@@ -0,0 +1,125 @@
+// Package wallet keeps players' money while it is away from the tables.
+// Game servers debit a player's wallet for the chips they buy, and credit
+// it with debits whose chips could not be added; admins deposit into it.
+//
+// Each transaction carries a ref, so that one retried after a timeout is
+// only applied once.
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrInvalidAmount     = errors.New("amount must be positive")
+	ErrInvalidRef        = errors.New("transaction ref must be set")
+	ErrNoDebit           = errors.New("no debit of that amount with that ref")
+)
+
+// Kind is a kind of transaction.
+type Kind string
+
+const (
+	// Money was added by an admin.
+	Deposit Kind = "deposit"
+
+	// Money was taken to buy chips.
+	Debit Kind = "debit"
+
+	// A debit was given back.
+	Credit Kind = "credit"
+)
+
+// Transaction is a change to a player's balance.
+type Transaction struct {
+	PlayerID string
+	Kind     Kind
+
+	// How much was added or, for a debit, taken. Always positive.
+	Amount int64
+
+	// Identifies the transaction among the player's of its kind. A credit
+	// has the ref of the debit it gives back.
+	Ref string
+
+	At time.Time
+}
+
+// Store persists balances and the transactions that made them.
+type Store interface {
+	// Apply records a transaction and changes the player's balance by it,
+	// returning the balance after. If the player already has a transaction
+	// of its kind with its ref, nothing changes and the current balance is
+	// returned. A debit of more than the balance returns
+	// ErrInsufficientFunds, and a credit without a debit of the same amount
+	// and ref ErrNoDebit.
+	Apply(ctx context.Context, tx Transaction) (int64, error)
+
+	// Balance returns the player's balance, which is zero for a player with
+	// no transactions.
+	Balance(ctx context.Context, playerID string) (int64, error)
+}
+
+// Wallets applies transactions to players' wallets. It is safe for
+// concurrent use.
+type Wallets struct {
+	store Store
+	now   func() time.Time
+}
+
+// New returns Wallets kept in store.
+func New(store Store) *Wallets {
+	return &Wallets{store: store, now: time.Now}
+}
+
+// Balance returns the money in a player's wallet.
+func (w *Wallets) Balance(ctx context.Context, playerID string) (int64, error) {
+	return w.store.Balance(ctx, playerID)
+}
+
+// Deposit adds money to a player's wallet, and returns their balance.
+func (w *Wallets) Deposit(ctx context.Context, playerID string, amount int64, ref string) (int64, error) {
+	return w.apply(ctx, playerID, Deposit, amount, ref)
+}
+
+// Debit takes money from a player's wallet, and returns their balance. It
+// returns ErrInsufficientFunds if they can't afford it.
+func (w *Wallets) Debit(ctx context.Context, playerID string, amount int64, ref string) (int64, error) {
+	return w.apply(ctx, playerID, Debit, amount, ref)
+}
+
+// Credit gives back money debited with ref, and returns the player's
+// balance. It returns ErrNoDebit unless the player was debited amount with
+// ref.
+func (w *Wallets) Credit(ctx context.Context, playerID string, amount int64, ref string) (int64, error) {
+	return w.apply(ctx, playerID, Credit, amount, ref)
+}
+
+// Check returns why a transaction of amount with ref would be refused
+// whatever the balance, or nil.
+func Check(amount int64, ref string) error {
+	if amount <= 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidAmount, amount)
+	}
+	if ref == "" {
+		return ErrInvalidRef
+	}
+	return nil
+}
+
+func (w *Wallets) apply(ctx context.Context, playerID string, kind Kind, amount int64, ref string) (int64, error) {
+	if err := Check(amount, ref); err != nil {
+		return 0, err
+	}
+	return w.store.Apply(ctx, Transaction{
+		PlayerID: playerID,
+		Kind:     kind,
+		Amount:   amount,
+		Ref:      ref,
+		At:       w.now(),
+	})
+}
@@ -0,0 +1,58 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/jfmatt/gotest"
+)
+
+var ctx = context.Background()
+
+func TestWallets(t *testing.T) {
+	w := New(NewMemStore())
+	balance, err := w.Balance(ctx, "alice")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, balance, int64(0))
+
+	balance, err = w.Deposit(ctx, "alice", 5000, "d1")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, balance, int64(5000))
+	balance, err = w.Debit(ctx, "alice", 2000, "m1/1")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, balance, int64(3000))
+
+	// A retried debit is only taken once.
+	balance, err = w.Debit(ctx, "alice", 2000, "m1/1")
+	AssertThat(t, err, Nil())
+	ExpectEq(t, balance, int64(3000))
+
+	_, err = w.Debit(ctx, "alice", 4000, "m1/2")
+	ExpectThat(t, err, ErrorIs(ErrInsufficientFunds))
+	_, err = w.Debit(ctx, "bob", 1, "m1/3")
+	ExpectThat(t, err, ErrorIs(ErrInsufficientFunds))
+
+	// A credit has the ref of the debit it gives back, and is also only
+	// given once.
+	for range 2 {
+		balance, err = w.Credit(ctx, "alice", 2000, "m1/1")
+		AssertThat(t, err, Nil())
+		ExpectEq(t, balance, int64(5000))
+	}
+
+	// Nor can a credit give back more than was debited, or money that
+	// wasn't.
+	_, err = w.Credit(ctx, "alice", 3000, "m1/1")
+	ExpectThat(t, err, ErrorIs(ErrNoDebit))
+	_, err = w.Credit(ctx, "alice", 2000, "m1/5")
+	ExpectThat(t, err, ErrorIs(ErrNoDebit))
+	_, err = w.Credit(ctx, "bob", 2000, "m1/1")
+	ExpectThat(t, err, ErrorIs(ErrNoDebit))
+
+	_, err = w.Deposit(ctx, "alice", 0, "d2")
+	ExpectThat(t, err, ErrorIs(ErrInvalidAmount))
+	_, err = w.Debit(ctx, "alice", -1, "m1/4")
+	ExpectThat(t, err, ErrorIs(ErrInvalidAmount))
+	_, err = w.Credit(ctx, "alice", 1, "")
+	ExpectThat(t, err, ErrorIs(ErrInvalidRef))
+}
@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Query picks the deliveries ListDeliveries returns.
+type Query struct {
+	// Whether the log runs from oldest to newest, rather than newest to
+	// oldest.
+	OldestFirst bool
+
+	// Only deliveries after this one in the log, if set. Deliveries are
+	// ordered by when they were created and then by ID.
+	AfterCreatedAt time.Time
+	AfterID        string
+
+	Limit int
+}
+
+// includes reports whether d is after q's cursor.
+func (q Query) includes(d Delivery) bool {
+	if q.AfterID == "" {
+		return true
+	}
+	c := cmp.Or(d.CreatedAt.Compare(q.AfterCreatedAt), strings.Compare(d.ID, q.AfterID))
+	if q.OldestFirst {
+		return c > 0
+	}
+	return c < 0
+}
+
+// Store persists webhooks and their deliveries.
+type Store interface {
+	// SaveHook records a new webhook.
+	SaveHook(ctx context.Context, h Hook) error
+
+	// GetHook returns the webhook with the ID, or ErrNotFound.
+	GetHook(ctx context.Context, id string) (Hook, error)
+
+	// DeleteHook removes a webhook, or returns ErrNotFound.
+	DeleteHook(ctx context.Context, id string) error
+
+	// ListHooks returns every webhook, oldest first.
+	ListHooks(ctx context.Context) ([]Hook, error)
+
+	// SaveDeliveries records new deliveries.
+	SaveDeliveries(ctx context.Context, ds []Delivery) error
+
+	// UpdateDelivery replaces the record of a delivery.
+	UpdateDelivery(ctx context.Context, d Delivery) error
+
+	// ClaimDeliveries returns up to limit pending deliveries due by now,
+	// and puts their next attempt off until leaseUntil, so that nobody
+	// else claims them meanwhile.
+	ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Delivery, error)
+
+	// ListDeliveries returns up to q.Limit of a webhook's deliveries, in
+	// q's order.
+	ListDeliveries(ctx context.Context, hookID string, q Query) ([]Delivery, error)
+}
+
+// MemStore is an in-memory Store, for development and tests.
+type MemStore struct {
+	mu         sync.Mutex
+	hooks      map[string]Hook
+	deliveries map[string]Delivery
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{hooks: map[string]Hook{}, deliveries: map[string]Delivery{}}
+}
+
+func (s *MemStore) SaveHook(ctx context.Context, h Hook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h.Events = slices.Clone(h.Events)
+	s.hooks[h.ID] = h
+	return nil
+}
+
+func (s *MemStore) GetHook(ctx context.Context, id string) (Hook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.hooks[id]
+	if !ok {
+		return Hook{}, ErrNotFound
+	}
+	return h, nil
+}
+
+func (s *MemStore) DeleteHook(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.hooks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.hooks, id)
+	return nil
+}
+
+func (s *MemStore) ListHooks(ctx context.Context) ([]Hook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.SortedFunc(maps.Values(s.hooks), func(a, b Hook) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	}), nil
+}
+
+func (s *MemStore) SaveDeliveries(ctx context.Context, ds []Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range ds {
+		s.deliveries[d.ID] = d
+	}
+	return nil
+}
+
+func (s *MemStore) UpdateDelivery(ctx context.Context, d Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[d.ID] = d
+	return nil
+}
+
+func (s *MemStore) ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []Delivery
+	for _, d := range s.deliveries {
+		if d.State == Pending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	slices.SortFunc(due, func(a, b Delivery) int {
+		return cmp.Or(a.NextAttemptAt.Compare(b.NextAttemptAt), cmp.Compare(a.ID, b.ID))
+	})
+	due = due[:min(len(due), limit)]
+	for _, d := range due {
+		d.NextAttemptAt = leaseUntil
+		s.deliveries[d.ID] = d
+	}
+	return due, nil
+}
+
+func (s *MemStore) ListDeliveries(ctx context.Context, hookID string, q Query) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ds []Delivery
+	for _, d := range s.deliveries {
+		if d.HookID == hookID && q.includes(d) {
+			ds = append(ds, d)
+		}
+	}
+	slices.SortFunc(ds, func(a, b Delivery) int {
+		c := cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+		if q.OldestFirst {
+			return c
+		}
+		return -c
+	})
+	return ds[:min(len(ds), q.Limit)], nil
+}
@@ -0,0 +1,515 @@
+// Package webhook tells operators' services, such as Discord bots and
+// analytics pipelines, about matches forming and finishing and players being
+// banned, by POSTing each event as JSON to the URLs registered for it.
+//
+// Requests are signed with the webhook's secret, so that receivers can tell
+// they came from the matchmaker. Deliveries that fail are retried with
+// exponential backoff, and every attempt is logged, so that operators can
+// see why a receiver is missing events.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/history"
+	"github.com/jfmatt/snapfold/matchmaker/paging"
+	"github.com/jfmatt/snapfold/matchmaker/queue"
+)
+
+var (
+	ErrNotFound     = errors.New("webhook not found")
+	ErrInvalidURL   = errors.New("invalid webhook url")
+	ErrInvalidEvent = errors.New("invalid webhook event")
+	ErrSignature    = errors.New("invalid webhook signature")
+)
+
+// Event is a kind of event that webhooks may be registered for.
+type Event string
+
+const (
+	// A match formed, or players were added to a running table.
+	MatchCreated Event = "match.created"
+
+	// A game server reported a match's results.
+	MatchCompleted Event = "match.completed"
+
+	// A moderator banned a player.
+	PlayerBanned Event = "player.banned"
+)
+
+// Events lists every event.
+var Events = []Event{MatchCreated, MatchCompleted, PlayerBanned}
+
+// ParseEvent returns the event with the name, or ErrInvalidEvent.
+func ParseEvent(name string) (Event, error) {
+	e := Event(name)
+	if !slices.Contains(Events, e) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidEvent, name)
+	}
+	return e, nil
+}
+
+// Headers set on every delivery.
+const (
+	// The event's kind.
+	EventHeader = "Snapfold-Event"
+
+	// The delivery's ID, which stays the same across retries, so receivers
+	// can drop duplicates.
+	DeliveryHeader = "Snapfold-Delivery"
+
+	// When the request was signed and its signature, as
+	// t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">.
+	SignatureHeader = "Snapfold-Signature"
+)
+
+// Hook is a registered webhook.
+type Hook struct {
+	ID     string
+	URL    string
+	Events []Event
+
+	// Signs deliveries. Receivers are given it when the hook is registered.
+	Secret string
+
+	CreatedAt time.Time
+}
+
+// Wants reports whether the hook is registered for e.
+func (h Hook) Wants(e Event) bool {
+	return slices.Contains(h.Events, e)
+}
+
+// State is how far a delivery has got.
+type State string
+
+const (
+	Pending   State = "pending"
+	Delivered State = "delivered"
+	Failed    State = "failed"
+)
+
+// Delivery is an event on its way to a hook, and the log of its attempts.
+type Delivery struct {
+	ID     string
+	HookID string
+	Event  Event
+
+	// The request body.
+	Payload []byte
+
+	CreatedAt time.Time
+	State     State
+	Attempts  int
+
+	// When the delivery is next attempted, while it is pending.
+	NextAttemptAt time.Time
+
+	// The outcome of the last attempt: the status the hook replied with,
+	// which is 0 if it didn't, and why the attempt failed, if it did.
+	LastStatus int
+	LastError  string
+
+	DeliveredAt time.Time
+}
+
+// Defaults for retrying deliveries, which span about two hours.
+const (
+	defaultAttempts = 8
+	defaultBackoff  = time.Minute
+	maxBackoff      = time.Hour
+
+	// How long a matchmaker has to make an attempt before others may make
+	// it too.
+	lease = time.Minute
+
+	// How many deliveries are attempted at once.
+	batch = 32
+)
+
+// Manager registers webhooks and delivers events to them. It is safe for
+// concurrent use.
+type Manager struct {
+	store    Store
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+	now      func() time.Time
+
+	// Wakes Run when events are published.
+	wake chan struct{}
+}
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithClient sets the client deliveries are sent with. Without it, each
+// attempt has ten seconds.
+func WithClient(c *http.Client) Option {
+	return func(m *Manager) { m.client = c }
+}
+
+// WithRetries sets how many times a delivery is attempted before it is
+// given up on, and how long to wait before the first retry, which doubles
+// with each retry after, up to an hour.
+func WithRetries(attempts int, backoff time.Duration) Option {
+	return func(m *Manager) { m.attempts, m.backoff = attempts, backoff }
+}
+
+// NewManager returns a Manager that keeps webhooks and deliveries in store.
+func NewManager(store Store, opts ...Option) *Manager {
+	m := &Manager{
+		store:    store,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		attempts: defaultAttempts,
+		backoff:  defaultBackoff,
+		now:      time.Now,
+		wake:     make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Register adds a webhook that is sent events of the given kinds. The hook
+// it returns holds the secret its deliveries are signed with.
+func (m *Manager) Register(ctx context.Context, rawURL string, events ...Event) (Hook, error) {
+	h, err := m.NewHook(rawURL, events...)
+	if err != nil {
+		return Hook{}, err
+	}
+	return h, m.Save(ctx, h)
+}
+
+// NewHook returns a webhook that would be sent events of the given kinds,
+// without adding it. It is sent nothing until it is added with Save.
+func (m *Manager) NewHook(rawURL string, events ...Event) (Hook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Hook{}, fmt.Errorf("%w: %q", ErrInvalidURL, rawURL)
+	}
+	if len(events) == 0 {
+		return Hook{}, fmt.Errorf("%w: a webhook needs at least one event", ErrInvalidEvent)
+	}
+	for _, e := range events {
+		if _, err := ParseEvent(string(e)); err != nil {
+			return Hook{}, err
+		}
+	}
+	h := Hook{
+		ID:        "wh_" + strings.ToLower(rand.Text()[:12]),
+		URL:       rawURL,
+		Events:    slices.Compact(slices.Sorted(slices.Values(events))),
+		Secret:    "whsec_" + strings.ToLower(rand.Text()),
+		CreatedAt: m.now(),
+	}
+	return h, nil
+}
+
+// Save adds a webhook made by NewHook.
+func (m *Manager) Save(ctx context.Context, h Hook) error {
+	return m.store.SaveHook(ctx, h)
+}
+
+// Delete removes a webhook. Its pending deliveries are given up on.
+func (m *Manager) Delete(ctx context.Context, id string) error {
+	return m.store.DeleteHook(ctx, id)
+}
+
+// List returns every webhook, oldest first.
+func (m *Manager) List(ctx context.Context) ([]Hook, error) {
+	return m.store.ListHooks(ctx)
+}
+
+// Get returns the webhook with the ID.
+func (m *Manager) Get(ctx context.Context, id string) (Hook, error) {
+	return m.store.GetHook(ctx, id)
+}
+
+// envelope is the body of every delivery.
+type envelope struct {
+	ID        string    `json:"id"`
+	Type      Event     `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Publish queues an event, with data as its JSON payload, for delivery to
+// every webhook registered for it.
+func (m *Manager) Publish(ctx context.Context, e Event, data any) error {
+	hooks, err := m.store.ListHooks(ctx)
+	if err != nil {
+		return err
+	}
+	now := m.now()
+	body, err := json.Marshal(envelope{ID: "ev_" + strings.ToLower(rand.Text()[:16]), Type: e, CreatedAt: now, Data: data})
+	if err != nil {
+		return err
+	}
+	var deliveries []Delivery
+	for _, h := range hooks {
+		if h.Wants(e) {
+			deliveries = append(deliveries, Delivery{
+				ID:            "whd_" + strings.ToLower(rand.Text()[:16]),
+				HookID:        h.ID,
+				Event:         e,
+				Payload:       body,
+				CreatedAt:     now,
+				State:         Pending,
+				NextAttemptAt: now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := m.store.SaveDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+type matchData struct {
+	MatchID   string         `json:"match_id"`
+	GameMode  string         `json:"game_mode"`
+	Region    string         `json:"region,omitempty"`
+	TableID   string         `json:"table_id,omitempty"`
+	Players   []string       `json:"players"`
+	Bots      int            `json:"bots,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	Places    map[string]int `json:"places,omitempty"`
+	EndedAt   *time.Time     `json:"ended_at,omitempty"`
+}
+
+// PublishMatchCreated publishes a MatchCreated event for a newly formed
+// match.
+func (m *Manager) PublishMatchCreated(ctx context.Context, match *queue.Match) error {
+	return m.Publish(ctx, MatchCreated, matchData{
+		MatchID:   match.ID,
+		GameMode:  match.GameMode,
+		Region:    match.Region,
+		TableID:   match.TableID,
+		Players:   match.Players(),
+		Bots:      match.Bots,
+		CreatedAt: match.CreatedAt,
+	})
+}
+
+// PublishMatchCompleted publishes a MatchCompleted event for a match whose
+// results have been recorded.
+func (m *Manager) PublishMatchCompleted(ctx context.Context, match history.Match) error {
+	return m.Publish(ctx, MatchCompleted, matchData{
+		MatchID:   match.ID,
+		GameMode:  match.GameMode,
+		Region:    match.Region,
+		TableID:   match.TableID,
+		Players:   match.Players,
+		Bots:      match.Bots,
+		CreatedAt: match.CreatedAt,
+		Places:    match.Places,
+		EndedAt:   &match.EndedAt,
+	})
+}
+
+type banData struct {
+	PlayerID string     `json:"player_id"`
+	Reason   string     `json:"reason"`
+	BannedAt time.Time  `json:"banned_at"`
+	Until    *time.Time `json:"until,omitempty"`
+}
+
+// PublishPlayerBanned publishes a PlayerBanned event for an account that
+// has just been banned.
+func (m *Manager) PublishPlayerBanned(ctx context.Context, a account.Account) error {
+	if a.Ban == nil {
+		return nil
+	}
+	data := banData{PlayerID: a.Username, Reason: a.Ban.Reason, BannedAt: a.Ban.At}
+	if !a.Ban.Until.IsZero() {
+		data.Until = &a.Ban.Until
+	}
+	return m.Publish(ctx, PlayerBanned, data)
+}
+
+// DeliveryLog is how a webhook's deliveries may be listed: by when they
+// were created, newest first unless sorted by created_at.
+var DeliveryLog = paging.Spec{
+	Default: paging.Sort{Field: "created_at", Desc: true},
+	Fields:  []string{"created_at"},
+}
+
+// Deliveries returns a page of a webhook's delivery log, for a request made
+// with the DeliveryLog spec.
+func (m *Manager) Deliveries(ctx context.Context, hookID string, r paging.Request) (paging.Page[Delivery], error) {
+	if _, err := m.store.GetHook(ctx, hookID); err != nil {
+		return paging.Page[Delivery]{}, err
+	}
+	q := Query{OldestFirst: !r.Sort.Desc, Limit: r.Limit()}
+	if r.After != nil {
+		if len(r.After) != 2 {
+			return paging.Page[Delivery]{}, paging.ErrInvalidToken
+		}
+		q.AfterCreatedAt, q.AfterID = time.Unix(0, paging.Int(r.After[0])), r.After[1]
+	}
+	ds, err := m.store.ListDeliveries(ctx, hookID, q)
+	if err != nil {
+		return paging.Page[Delivery]{}, err
+	}
+	return paging.Finish(r, ds, func(d Delivery) []string {
+		return []string{strconv.FormatInt(d.CreatedAt.UnixNano(), 10), d.ID}
+	}), nil
+}
+
+// Run attempts deliveries as they fall due, until ctx is done. It checks
+// for them every interval, and as soon as events are published here.
+// Matchmakers sharing a store may each run it; every attempt is made by one
+// of them.
+func (m *Manager) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := m.deliverDue(ctx); err != nil && onError != nil {
+			onError(fmt.Errorf("delivering webhooks: %w", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		case <-m.wake:
+		}
+	}
+}
+
+// deliverDue attempts every delivery that is due.
+func (m *Manager) deliverDue(ctx context.Context) error {
+	for {
+		now := m.now()
+		due, err := m.store.ClaimDeliveries(ctx, now, now.Add(lease), batch)
+		if err != nil {
+			return err
+		}
+		var wg sync.WaitGroup
+		errs := make([]error, len(due))
+		for i, d := range due {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = m.attempt(ctx, d)
+			}()
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil || len(due) < batch {
+			return err
+		}
+	}
+}
+
+// attempt sends a delivery and logs the outcome.
+func (m *Manager) attempt(ctx context.Context, d Delivery) error {
+	h, err := m.store.GetHook(ctx, d.HookID)
+	if errors.Is(err, ErrNotFound) {
+		d.State, d.NextAttemptAt, d.LastError = Failed, time.Time{}, "webhook was deleted"
+		return m.store.UpdateDelivery(ctx, d)
+	}
+	if err != nil {
+		return err
+	}
+
+	d.Attempts++
+	d.LastStatus, err = m.send(ctx, h, d)
+	now := m.now()
+	switch {
+	case err == nil:
+		d.State, d.NextAttemptAt, d.LastError, d.DeliveredAt = Delivered, time.Time{}, "", now
+	case d.Attempts >= m.attempts:
+		d.State, d.NextAttemptAt, d.LastError = Failed, time.Time{}, err.Error()
+	default:
+		d.NextAttemptAt, d.LastError = now.Add(min(m.backoff<<(d.Attempts-1), maxBackoff)), err.Error()
+	}
+	return m.store.UpdateDelivery(ctx, d)
+}
+
+// send POSTs a delivery to its hook, returning the status it replied with.
+// Any status other than 2xx is an error.
+func (m *Manager) send(ctx context.Context, h Hook, d Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(d.Event))
+	req.Header.Set(DeliveryHeader, d.ID)
+	req.Header.Set(SignatureHeader, Sign(h.Secret, m.now(), d.Payload))
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, fmt.Errorf("webhook replied %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the value of the SignatureHeader for a body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + signature(secret, ts, body)
+}
+
+func signature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the SignatureHeader of a delivery received at now, for
+// receivers written in Go. Signatures older than tolerance are rejected,
+// so that a captured request can't be replayed later.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts, sig string
+	for part := range strings.SplitSeq(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	secs, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return fmt.Errorf("%w: malformed", ErrSignature)
+	}
+	if age := now.Sub(time.Unix(secs, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: signed %v ago", ErrSignature, age.Round(time.Second))
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, ts, body))) {
+		return ErrSignature
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/jfmatt/gotest"
+
+	"github.com/jfmatt/snapfold/matchmaker/account"
+	"github.com/jfmatt/snapfold/matchmaker/paging"
+)
+
+var ctx = context.Background()
+
+func TestRegister(t *testing.T) {
+	m := NewManager(NewMemStore())
+	_, err := m.Register(ctx, "ftp://example.com", MatchCreated)
+	ExpectThat(t, err, ErrorIs(ErrInvalidURL))
+	_, err = m.Register(ctx, "https://example.com/hook")
+	ExpectThat(t, err, ErrorIs(ErrInvalidEvent))
+	_, err = m.Register(ctx, "https://example.com/hook", "match.exploded")
+	ExpectThat(t, err, ErrorIs(ErrInvalidEvent))
+
+	h, err := m.Register(ctx, "https://example.com/hook", PlayerBanned, MatchCreated, MatchCreated)
+	AssertThat(t, err, Nil())
+	ExpectThat(t, h.Events, ElementsAre(MatchCreated, PlayerBanned))
+	ExpectEq(t, h.Secret != "", true)
+
+	hooks, err := m.List(ctx)
+	AssertThat(t, err, Nil())
+	AssertThat(t, hooks, Len(1))
+	AssertThat(t, m.Delete(ctx, h.ID), Nil())
+	ExpectThat(t, m.Delete(ctx, h.ID), ErrorIs(ErrNotFound))
+}
+
+func TestNewHook(t *testing.T) {
+	m := NewManager(NewMemStore())
+	_, err := m.NewHook("https://example.com/hook")
+	ExpectThat(t, err, ErrorIs(ErrInvalidEvent))
+	h, err := m.NewHook("https://example.com/hook", MatchCreated)
+	AssertThat(t, err, Nil())
+	_, err = m.Get(ctx, h.ID)
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+
+	AssertThat(t, m.Save(ctx, h), Nil())
+	got, err := m.Get(ctx, h.ID)
+	AssertThat(t, err, Nil())
+	ExpectEq(t, got.URL, "https://example.com/hook")
+}
+
+func TestDeliver(t *testing.T) {
+	var secret string
+	var got envelope
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ExpectThat(t, Verify(secret, r.Header.Get(SignatureHeader), body, time.Now(), time.Minute), Nil())
+		ExpectEq(t, r.Header.Get(EventHeader), string(PlayerBanned))
+		json.Unmarshal(body, &got)
+	}))
+	defer srv.Close()
+	m := NewManager(NewMemStore(), WithClient(srv.Client()))
+	h, err := m.Register(ctx, srv.URL, PlayerBanned)
+	AssertThat(t, err, Nil())
+	secret = h.Secret
+
+	// Only hooks registered for an event are sent it.
+	AssertThat(t, m.Publish(ctx, MatchCreated, nil), Nil())
+	AssertThat(t, m.PublishPlayerBanned(ctx, account.Account{Username: "alice", Ban: &account.Ban{Reason: "collusion", At: time.Now()}}), Nil())
+	AssertThat(t, m.deliverDue(ctx), Nil())
+	ExpectEq(t, got.Type, PlayerBanned)
+	ExpectEq(t, got.Data.(map[string]any)["player_id"], "alice")
+
+	r, err := DeliveryLog.Parse(0, "", "", nil)
+	AssertThat(t, err, Nil())
+	p, err := m.Deliveries(ctx, h.ID, r)
+	AssertThat(t, err, Nil())
+	AssertThat(t, p.Items, Len(1))
+	ExpectEq(t, p.Items[0].State, Delivered)
+	ExpectEq(t, p.Items[0].Attempts, 1)
+	ExpectEq(t, p.Items[0].LastStatus, http.StatusOK)
+
+	_, err = m.Deliveries(ctx, "nope", r)
+	ExpectThat(t, err, ErrorIs(ErrNotFound))
+}
+
+func TestDeliver_Retries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	m := NewManager(NewMemStore(), WithClient(srv.Client()), WithRetries(3, time.Minute))
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	h, err := m.Register(ctx, srv.URL, MatchCreated)
+	AssertThat(t, err, Nil())
+	AssertThat(t, m.Publish(ctx, MatchCreated, map[string]string{"match_id": "m1"}), Nil())
+
+	deliveries := func() []Delivery {
+		t.Helper()
+		p, err := m.Deliveries(ctx, h.ID, paging.Request{Size: 10, Sort: DeliveryLog.Default})
+		AssertThat(t, err, Nil())
+		AssertThat(t, p.Items, Len(1))
+		return p.Items
+	}
+
+	AssertThat(t, m.deliverDue(ctx), Nil())
+	d := deliveries()[0]
+	ExpectEq(t, d.State, Pending)
+	ExpectEq(t, d.LastStatus, http.StatusBadGateway)
+	ExpectEq(t, d.NextAttemptAt, now.Add(time.Minute))
+
+	// Nothing is sent before the retry is due, and retries back off.
+	AssertThat(t, m.deliverDue(ctx), Nil())
+	ExpectEq(t, calls.Load(), int32(1))
+	now = now.Add(time.Minute)
+	AssertThat(t, m.deliverDue(ctx), Nil())
+	ExpectEq(t, deliveries()[0].NextAttemptAt, now.Add(2*time.Minute))
+
+	now = now.Add(2 * time.Minute)
+	AssertThat(t, m.deliverDue(ctx), Nil())
+	d = deliveries()[0]
+	ExpectEq(t, d.State, Failed)
+	ExpectEq(t, d.Attempts, 3)
+	ExpectThat(t, d.LastError, HasSubstr("502"))
+	ExpectEq(t, calls.Load(), int32(3))
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	body := []byte(`{"type":"match.created"}`)
+	sig := Sign("whsec_x", now, body)
+
+	ExpectThat(t, Verify("whsec_x", sig, body, now.Add(time.Minute), 5*time.Minute), Nil())
+	ExpectThat(t, Verify("whsec_y", sig, body, now, 5*time.Minute), ErrorIs(ErrSignature))
+	ExpectThat(t, Verify("whsec_x", sig, []byte(`{}`), now, 5*time.Minute), ErrorIs(ErrSignature))
+	ExpectThat(t, Verify("whsec_x", sig, body, now.Add(time.Hour), 5*time.Minute), ErrorIs(ErrSignature))
+	ExpectThat(t, Verify("whsec_x", "v1=abc", body, now, 5*time.Minute), ErrorIs(ErrSignature))
+}